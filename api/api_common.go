@@ -18,7 +18,16 @@ type Common interface {
 	// MethodGroup: Auth
 
 	AuthVerify(ctx context.Context, token string) ([]auth.Permission, error)
+	// AuthNew creates a token with the given permissions; the method scopes
+	// among them (see apistruct.MethodScope) restrict the token to the
+	// methods they match
 	AuthNew(ctx context.Context, perms []auth.Permission) ([]byte, error)
+	// AuthUsage returns the number of calls, the bytes transferred and the
+	// execution time of the API calls per token since the node started
+	AuthUsage(ctx context.Context) ([]TokenUsage, error)
+	// AuthConnections returns the open websocket connections to the API, and
+	// their subscriptions
+	AuthConnections(ctx context.Context) ([]APIConnection, error)
 
 	// MethodGroup: Net
 
@@ -52,6 +61,10 @@ type Common interface {
 	// Version provides information about API provider
 	Version(context.Context) (Version, error)
 
+	// Discover returns the version of the API, its methods with the
+	// permission each requires, and the permissions of the token of the call
+	Discover(context.Context) (APICapabilities, error)
+
 	LogList(context.Context) ([]string, error)
 	LogSetLevel(context.Context, string, string) error
 
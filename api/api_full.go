@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -43,6 +44,21 @@ type FullNode interface {
 	// First message is guaranteed to be of len == 1, and type == 'current'.
 	ChainNotify(context.Context) (<-chan []*HeadChange, error)
 
+	// ChainNotifyFrom is like ChainNotify, but delivers head changes as
+	// entries of the node's persistent head change journal, each with a
+	// cursor. Given the cursor of the last entry a subscriber processed, it
+	// first replays every head change recorded since, including across node
+	// restarts, so that no reverts are missed. A zero cursor starts from the
+	// current head. The journal keeps the latest 10000 entries.
+	ChainNotifyFrom(ctx context.Context, cursor uint64) (<-chan *HeadChanges, error)
+
+	// ChainNotifySince is like ChainNotifyFrom, but resumes from the last
+	// tipset a subscriber processed instead of a cursor: the first entry
+	// reverts and applies the tipsets between it and the current head, at
+	// most finality deep, or is the current head when the subscriber is at
+	// it. An empty tipset key starts from the current head.
+	ChainNotifySince(ctx context.Context, tsk types.TipSetKey) (<-chan *HeadChanges, error)
+
 	// ChainHead returns the current head of the chain.
 	ChainHead(context.Context) (*types.TipSet, error)
 
@@ -124,6 +140,71 @@ type FullNode interface {
 	// If oldmsgskip is set, messages from before the requested roots are also not included.
 	ChainExport(ctx context.Context, nroots abi.ChainEpoch, oldmsgskip bool, tsk types.TipSetKey) (<-chan []byte, error)
 
+	// ChainExportIncremental returns a stream of bytes with a CAR dump of the
+	// objects in the snapshot at the given tipset which are not part of the
+	// snapshot at the base tipset, which must be an ancestor of it. Both
+	// snapshots are assumed to use the same 'nroots' and 'oldmsgskip'.
+	ChainExportIncremental(ctx context.Context, nroots abi.ChainEpoch, oldmsgskip bool, base types.TipSetKey, tsk types.TipSetKey) (<-chan []byte, error)
+
+	// ChainExportRange returns a stream of bytes with a CAR dump of the chain
+	// data between the start and end epochs (inclusive), walking back from the
+	// tipset at 'end' on the chain of the given tipset. Which objects are
+	// included is selected by opts; the CAR roots are the tipset at 'end'.
+	ChainExportRange(ctx context.Context, tsk types.TipSetKey, start, end abi.ChainEpoch, opts ChainExportRangeOpts) (<-chan []byte, error)
+
+	// ChainPrune deletes state trees older than 'keep' epochs behind the
+	// current head from the chain blockstore. Block headers, messages and
+	// receipts are preserved, as are the genesis state and any objects still
+	// referenced by recent state trees. 'keep' must be at least a finality.
+	// No state is computed while an old state tree is deleted, and the state
+	// caches are emptied afterwards.
+	// If dryRun is set, nothing is deleted and the result reports the space
+	// that would be reclaimed.
+	ChainPrune(ctx context.Context, keep abi.ChainEpoch, dryRun bool) (*ChainPruneResult, error)
+
+	// ChainStat returns statistics of the chain blockstore, such as the hit
+	// rate of its cache.
+	ChainStat(ctx context.Context) (*ChainStat, error)
+
+	// ChainCheckBlockstore re-hashes every block in the chain blockstore and
+	// reports the blocks whose data doesn't match their CID. If repair is
+	// set, corrupt blocks are deleted and fetched again from the network.
+	ChainCheckBlockstore(ctx context.Context, repair bool) (*ChainCheckResult, error)
+
+	// ChainGetReorgs returns the reorgs recorded in the node's reorg journal
+	// whose new head is at or above the given height, oldest first.
+	ChainGetReorgs(ctx context.Context, since abi.ChainEpoch) ([]*ChainReorg, error)
+
+	// ChainReorgNotify returns a channel which receives a journal entry for
+	// every reorg the node goes through. Head changes which only extend the
+	// current chain are not reported.
+	ChainReorgNotify(ctx context.Context) (<-chan *ChainReorg, error)
+
+	// ChainBlockstoreGC runs value log garbage collection, and optionally
+	// compaction, on the datastores backing the chain blockstore without
+	// stopping the node. The returned channel reports progress and is closed
+	// when GC finishes; if GC fails, the last message carries the error.
+	ChainBlockstoreGC(ctx context.Context, opts BlockstoreGCOpts) (<-chan BlockstoreGCProgress, error)
+
+	// ChainReindexHeights drops the persistent height index used by
+	// ChainGetTipSetByHeight, and rebuilds it for the chain of the given
+	// tipset down to genesis.
+	ChainReindexHeights(ctx context.Context, tsk types.TipSetKey) error
+
+	// ChainIndexMessages records the messages executed between the 'from'
+	// and 'to' epochs (inclusive) of the current chain in the message index
+	// used by StateSearchMsg and StateGetReceipt, and returns the number of
+	// messages indexed. New messages are indexed during sync when the
+	// message index is enabled in the Chainstore config.
+	ChainIndexMessages(ctx context.Context, from, to abi.ChainEpoch) (int, error)
+
+	// ChainBackfillMessageIndex indexes the messages like ChainIndexMessages
+	// in the background, reporting progress on the returned channel, which is
+	// closed when the backfill stops. Progress is checkpointed, so that an
+	// interrupted backfill of the same range resumes where it stopped unless
+	// Restart is set.
+	ChainBackfillMessageIndex(ctx context.Context, opts IndexBackfillOpts) (<-chan IndexBackfillProgress, error)
+
 	// MethodGroup: Beacon
 	// The Beacon method group contains methods for interacting with the random beacon (DRAND)
 
@@ -132,6 +213,10 @@ type FullNode interface {
 	// becomes available
 	BeaconGetEntry(ctx context.Context, epoch abi.ChainEpoch) (*types.BeaconEntry, error)
 
+	// BeaconSchedule returns the drand chains used by the network, with the
+	// epochs they are used from.
+	BeaconSchedule(ctx context.Context) ([]BeaconInfo, error)
+
 	// GasEstimateFeeCap estimates gas fee cap
 	GasEstimateFeeCap(context.Context, *types.Message, int64, types.TipSetKey) (types.BigInt, error)
 
@@ -145,6 +230,14 @@ type FullNode interface {
 	GasEstimateGasPremium(_ context.Context, nblocksincl uint64,
 		sender address.Address, gaslimit int64, tsk types.TipSetKey) (types.BigInt, error)
 
+	// GasEstimateFeeForecast projects the base fee over the next `nepochs`
+	// epochs from the fullness of the blocks of the last `lookback` epochs,
+	// and returns the percentiles of the gas premiums of the messages
+	// included in them. A fee cap of the projected base fee at epoch N plus a
+	// high percentile premium gets a message included within about N epochs;
+	// lower premiums get it included eventually.
+	GasEstimateFeeForecast(ctx context.Context, nepochs, lookback uint64, tsk types.TipSetKey) (*FeeForecast, error)
+
 	// GasEstimateMessageGas estimates gas values for unset message gas fields
 	GasEstimateMessageGas(context.Context, *types.Message, *MessageSendSpec, types.TipSetKey) (*types.Message, error)
 
@@ -180,9 +273,31 @@ type FullNode interface {
 	// the reason.
 	SyncCheckBad(ctx context.Context, bcid cid.Cid) (string, error)
 
+	// SyncListBad returns the blocks in the bad block cache with the reasons
+	// they were marked bad.
+	SyncListBad(ctx context.Context) ([]BadBlock, error)
+
+	// SyncBlockPeer stops the node from syncing to the heads of the peer and
+	// from fetching chain data from it, e.g. when it keeps serving a bad fork.
+	// Blocked peers aren't persisted across restarts.
+	SyncBlockPeer(ctx context.Context, p peer.ID, reason string) error
+
+	// SyncUnblockPeer lets the node sync from a blocked peer again.
+	SyncUnblockPeer(ctx context.Context, p peer.ID) error
+
+	// SyncBlockedPeers lists the peers blocked with SyncBlockPeer.
+	SyncBlockedPeers(ctx context.Context) ([]BlockedPeer, error)
+
 	// SyncValidateTipset indicates whether the provided tipset is valid or not
 	SyncValidateTipset(ctx context.Context, tsk types.TipSetKey) (bool, error)
 
+	// SyncValidateBlockHeader performs all checks on the given block header
+	// which don't need its messages or the execution of its parent tipset:
+	// sanity, timestamp, parent weight, base fee, miner eligibility, ticket,
+	// election proof, beacon entries, winning PoSt and signature. The parent
+	// tipset must be known to the node.
+	SyncValidateBlockHeader(ctx context.Context, h *types.BlockHeader) (*BlockHeaderValidation, error)
+
 	// MethodGroup: Mpool
 	// The Mpool methods are for interacting with the message pool. The message pool
 	// manages all incoming and outgoing 'messages' going over the network.
@@ -197,6 +312,10 @@ type FullNode interface {
 	MpoolPush(context.Context, *types.SignedMessage) (cid.Cid, error)
 
 	// MpoolPushUntrusted pushes a signed message to mempool from untrusted sources.
+	// The message is checked like messages from the network: the signature, the
+	// balance of the sender, that the nonce doesn't leave a gap and the mpool
+	// limits for untrusted actors, including MinUntrustedGasPremium. It isn't
+	// republished by this node.
 	MpoolPushUntrusted(context.Context, *types.SignedMessage) (cid.Cid, error)
 
 	// MpoolPushMessage atomically assigns a nonce, signs, and pushes a message
@@ -207,14 +326,61 @@ type FullNode interface {
 	// based on current chain conditions
 	MpoolPushMessage(ctx context.Context, msg *types.Message, spec *MessageSendSpec) (*types.SignedMessage, error)
 
+	// MpoolBatchPush pushes the signed messages to mempool as a unit: either
+	// all of them are added, or none. The messages can't replace pending
+	// messages, and the ones of a sender must be in nonce order.
+	MpoolBatchPush(context.Context, []*types.SignedMessage) ([]cid.Cid, error)
+
+	// MpoolBatchPushMessage is like MpoolPushMessage for messages from the
+	// same sender: it assigns them consecutive nonces, in order, signs and
+	// pushes them as a unit, failing without pushing any message if one of
+	// them can't be pushed.
+	MpoolBatchPushMessage(ctx context.Context, msgs []*types.Message, spec *MessageSendSpec) ([]*types.SignedMessage, error)
+
+	// MpoolReplace replaces the pending message from the address with the
+	// nonce by the same message with the gas values of the spec, signs it
+	// with the local wallet and pushes it. Gas values left zero are kept for
+	// the limit, and estimated for the premium and fee cap; the estimated
+	// premium is at least the minimum the message pool accepts to replace
+	// the message, capped by the MaxFee of the spec.
+	MpoolReplace(ctx context.Context, from address.Address, nonce uint64, spec *MpoolReplaceSpec) (*types.SignedMessage, error)
+
 	// MpoolGetNonce gets next nonce for the specified sender.
 	// Note that this method may not be atomic. Use MpoolPushMessage instead.
 	MpoolGetNonce(context.Context, address.Address) (uint64, error)
+	// MpoolSub returns a channel of the changes to the mpool: the messages added
+	// to it, and the ones removed from it along with the reason of the removal.
 	MpoolSub(context.Context) (<-chan MpoolUpdate, error)
+	// MpoolSubAddress is like MpoolSub, but only returns the updates of the
+	// messages sent from or to one of the addresses. The ID and key addresses
+	// of actors existing at the head are both matched.
+	MpoolSubAddress(context.Context, []address.Address) (<-chan MpoolUpdate, error)
+
+	// MpoolSchedule schedules the message to be pushed like with
+	// MpoolPushMessage once the schedule is met, and returns its schedule ID.
+	// Scheduled messages are kept across restarts of the node.
+	MpoolSchedule(ctx context.Context, msg *types.Message, spec *MessageSendSpec, schedule MessageSchedule) (uint64, error)
+	// MpoolScheduleSigned schedules the signed message to be pushed once the
+	// schedule is met, and returns its schedule ID.
+	MpoolScheduleSigned(ctx context.Context, smsg *types.SignedMessage, schedule MessageSchedule) (uint64, error)
+	// MpoolScheduled returns the scheduled messages, pending or not.
+	MpoolScheduled(context.Context) ([]ScheduledMessage, error)
+	// MpoolScheduledCancel cancels the pending scheduled message, or removes
+	// the message pushed, or failed to be pushed, from the scheduled messages.
+	MpoolScheduledCancel(ctx context.Context, id uint64) error
 
 	// MpoolClear clears pending messages from the mpool
 	MpoolClear(context.Context, bool) error
 
+	// MpoolLocalMessages returns the messages pushed through this node. They
+	// are persisted and republished, also across restarts, until they are
+	// included in the chain or abandoned.
+	MpoolLocalMessages(context.Context) ([]*types.SignedMessage, error)
+	// MpoolAbandon stops republishing the local message with the given CID
+	// and removes it from the mpool. Pending messages from the same sender
+	// with higher nonces can't be included until the nonce is used again.
+	MpoolAbandon(context.Context, cid.Cid) error
+
 	// MpoolGetConfig returns (a copy of) the current mpool config
 	MpoolGetConfig(context.Context) (*types.MpoolConfig, error)
 	// MpoolSetConfig sets the mpool config to (a copy of) the supplied config
@@ -225,6 +391,25 @@ type FullNode interface {
 	MinerGetBaseInfo(context.Context, address.Address, abi.ChainEpoch, types.TipSetKey) (*MiningBaseInfo, error)
 	MinerCreateBlock(context.Context, *BlockTemplate) (*types.BlockMsg, error)
 
+	// The MinerWithdrawBalance, MinerChangeWorker, MinerConfirmChangeWorker and
+	// MinerSetControlAddresses methods send a miner admin message as the owner of the miner. If the
+	// owner is a multisig, the message is proposed to it from 'from' instead,
+	// or if an identical proposal is already pending, the proposal is approved.
+	// Otherwise 'from' may be left undefined, and must be the owner if set.
+
+	// MinerWithdrawBalance withdraws the given amount from the available
+	// balance of the miner to its owner.
+	MinerWithdrawBalance(ctx context.Context, maddr address.Address, amount abi.TokenAmount, from address.Address) (*MinerOwnerMessage, error)
+	// MinerChangeWorker requests a change of the worker key of the miner,
+	// keeping its control addresses.
+	MinerChangeWorker(ctx context.Context, maddr address.Address, newWorker address.Address, from address.Address) (*MinerOwnerMessage, error)
+	// MinerConfirmChangeWorker confirms the pending worker key change of the
+	// miner, replacing the worker key. It can only be sent once the change is
+	// effective, at the WorkerChangeEpoch of the miner info.
+	MinerConfirmChangeWorker(ctx context.Context, maddr address.Address, from address.Address) (*MinerOwnerMessage, error)
+	// MinerSetControlAddresses replaces the control addresses of the miner.
+	MinerSetControlAddresses(ctx context.Context, maddr address.Address, addrs []address.Address, from address.Address) (*MinerOwnerMessage, error)
+
 	// // UX ?
 
 	// MethodGroup: Wallet
@@ -235,6 +420,10 @@ type FullNode interface {
 	WalletNew(context.Context, types.KeyType) (address.Address, error)
 	// WalletHas indicates whether the given address is in the wallet.
 	WalletHas(context.Context, address.Address) (bool, error)
+	// WalletBackend returns the wallet backend holding the key of the given
+	// address: "local", "remote" or "ledger", or an empty string when no
+	// backend has it.
+	WalletBackend(context.Context, address.Address) (string, error)
 	// WalletList lists all the addresses in the wallet.
 	WalletList(context.Context) ([]address.Address, error)
 	// WalletBalance returns the balance of the given address at the current head of the chain.
@@ -243,6 +432,10 @@ type FullNode interface {
 	WalletSign(context.Context, address.Address, []byte) (*crypto.Signature, error)
 	// WalletSignMessage signs the given message using the given address.
 	WalletSignMessage(context.Context, address.Address, *types.Message) (*types.SignedMessage, error)
+	// WalletSignAggregate signs the given messages with the BLS keys of their
+	// senders, and returns the aggregate of the signatures, like the
+	// BLSAggregate of a block.
+	WalletSignAggregate(context.Context, []*types.Message) (*crypto.Signature, error)
 	// WalletVerify takes an address, a signature, and some bytes, and indicates whether the signature is valid.
 	// The address does not have to be in the wallet.
 	WalletVerify(context.Context, address.Address, []byte, *crypto.Signature) (bool, error)
@@ -258,6 +451,30 @@ type FullNode interface {
 	WalletDelete(context.Context, address.Address) error
 	// WalletValidateAddress validates whether a given string can be decoded as a well-formed address
 	WalletValidateAddress(context.Context, string) (address.Address, error)
+	// WalletEncrypt encrypts the keys of the local keystore with a key derived
+	// from the given passphrase; the keys added later are encrypted too. The
+	// wallet is locked afterwards.
+	WalletEncrypt(context.Context, string) error
+	// WalletUnlock decrypts the keys of the local keystore with the given
+	// passphrase, until WalletLock is called or the timeout elapses. A zero
+	// timeout keeps the wallet unlocked.
+	WalletUnlock(ctx context.Context, passphrase string, timeout time.Duration) error
+	// WalletLock forgets the decrypted keys of the local keystore, the
+	// operations which need signing fail until it's unlocked again.
+	WalletLock(context.Context) error
+	// WalletNewHD derives a key of the given type from the mnemonic of the
+	// wallet, at the first index with no key in the wallet. A mnemonic is
+	// generated if the wallet doesn't have one yet.
+	WalletNewHD(context.Context, types.KeyType) (address.Address, error)
+	// WalletDeriveHD derives the key of the given type at the given index from
+	// the mnemonic of the wallet, and adds it to the wallet.
+	WalletDeriveHD(context.Context, types.KeyType, uint64) (address.Address, error)
+	// WalletImportMnemonic sets the BIP39 mnemonic the keys are derived from;
+	// the wallet must not have one yet.
+	WalletImportMnemonic(context.Context, string) error
+	// WalletExportMnemonic returns the mnemonic of the wallet, with the
+	// derivation paths of its keys.
+	WalletExportMnemonic(context.Context) (*HDExport, error)
 
 	// Other
 
@@ -271,6 +488,13 @@ type FullNode interface {
 	ClientRemoveImport(ctx context.Context, importID multistore.StoreID) error
 	// ClientStartDeal proposes a deal with a miner.
 	ClientStartDeal(ctx context.Context, params *StartDealParams) (*cid.Cid, error)
+	// ClientAuthorizeDelegate signs, with the key of the funder address, an
+	// authorization for the delegate address to make storage deals on behalf
+	// of the funder until the expiration epoch. The delegate signs its deal
+	// proposals with its own key and pays them from its market escrow; the
+	// providers requiring it verify the authorization against the key of the
+	// funder in the chain state.
+	ClientAuthorizeDelegate(ctx context.Context, funder, delegate address.Address, expiration abi.ChainEpoch) (*SignedDealAuthorization, error)
 	// ClientGetDealInfo returns the latest information about a given deal.
 	ClientGetDealInfo(context.Context, cid.Cid) (*DealInfo, error)
 	// ClientListDeals returns information about the deals made by the local client.
@@ -322,13 +546,47 @@ type FullNode interface {
 	StateCall(context.Context, *types.Message, types.TipSetKey) (*InvocResult, error)
 	// StateReplay replays a given message, assuming it was included in a block in the specified tipset.
 	// If no tipset key is provided, the appropriate tipset is looked up.
+	// The execution trace of the result includes the subcalls of the message and their gas charges.
 	StateReplay(context.Context, types.TipSetKey, cid.Cid) (*InvocResult, error)
+	// StateReplayGasProfile replays a given message like StateReplay, and
+	// returns its gas charges, including the ones of its subcalls, aggregated
+	// by charge point.
+	StateReplayGasProfile(context.Context, types.TipSetKey, cid.Cid) (*GasProfile, error)
+	// StateReplayGasPprof replays a given message like StateReplay, and
+	// returns its gas charges, including the ones of its subcalls, as a gzipped
+	// pprof profile of the gas used by call stack, with the actors methods and
+	// the charge points as frames.
+	StateReplayGasPprof(context.Context, types.TipSetKey, cid.Cid) ([]byte, error)
 	// StateGetActor returns the indicated actor's nonce and balance.
 	StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error)
 	// StateReadState returns the indicated actor's state.
 	StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*ActorState, error)
+	// StateDecodeParams decodes the params of a call of the given method of the
+	// indicated actor, with the params type of the method in the actors version
+	// of the actor.
+	StateDecodeParams(ctx context.Context, toAddr address.Address, method abi.MethodNum, params []byte, tsk types.TipSetKey) (interface{}, error)
+	// StateDecodeReturn decodes the return value of a call of the given method
+	// of the indicated actor, like StateDecodeParams.
+	StateDecodeReturn(ctx context.Context, toAddr address.Address, method abi.MethodNum, ret []byte, tsk types.TipSetKey) (interface{}, error)
+	// StateDecodeActorState decodes the given state head, e.g. a past head of an
+	// actor, with the state type of the given actor code.
+	StateDecodeActorState(ctx context.Context, code cid.Cid, head cid.Cid) (interface{}, error)
+	// StateGetProof returns a merkle proof of the value at the given path in
+	// the indicated actor's state, relative to the parent state root of the
+	// tipset. The path uses the syntax of ChainGetNode, starting at the actor
+	// state head. The proof contains every IPLD block visited to reach the
+	// value, from the state root down, so that it can be verified against a
+	// block header without trusting the node.
+	StateGetProof(ctx context.Context, actor address.Address, path string, tsk types.TipSetKey) (*StateProof, error)
 	// StateListMessages looks back and returns all messages with a matching to or from address, stopping at the given height.
 	StateListMessages(ctx context.Context, match *MessageMatch, tsk types.TipSetKey, toht abi.ChainEpoch) ([]cid.Cid, error)
+	// StateListMessagesFor returns the messages sent or received by the
+	// address and executed on the chain of the given tipset, newest first,
+	// with their receipts. It skips the first offset messages and returns at
+	// most limit ones, all of them when zero. It relies on the message index of
+	// the node, which covers the chain synced since it was enabled; older
+	// ranges are indexed with ChainIndexMessages.
+	StateListMessagesFor(ctx context.Context, addr address.Address, tsk types.TipSetKey, offset, limit uint64) ([]*AddrMessage, error)
 
 	// StateNetworkName returns the name of the network the node is synced to
 	StateNetworkName(context.Context) (dtypes.NetworkName, error)
@@ -371,6 +629,18 @@ type FullNode interface {
 	StateSectorPartition(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tok types.TipSetKey) (*miner.SectorLocation, error)
 	// StateSearchMsg searches for a message in the chain, and returns its receipt and the tipset where it was executed
 	StateSearchMsg(context.Context, cid.Cid) (*MsgLookup, error)
+	// StateSearchMsgBounded looks back up to limit epochs in the chain for a message, and returns its receipt and the
+	// tipset where it was executed, or null if it wasn't found; use LookbackNoLimit (-1) to search the whole chain.
+	//
+	// If allowReplaced is set, a message from the same sender with the same nonce and call, e.g. the message with its
+	// gas values bumped, is accepted in place of the message; the message executed is returned in the lookup. If
+	// another message from the sender with the same nonce was executed, the message was replaced and can't be executed
+	// anymore, and an error naming the message executed is returned.
+	StateSearchMsgBounded(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*MsgLookup, error)
+	// StateMsgGasCost searches for a message in the chain, and returns how the fees it paid were
+	// split between burning, the miner and the refund to the sender, computed from its receipt and
+	// the base fee it was executed with. Returns null if the message isn't on chain.
+	StateMsgGasCost(context.Context, cid.Cid) (*MsgGasCost, error)
 	// StateWaitMsg looks back in the chain for a message. If not found, it blocks until the
 	// message arrives on chain, and gets to the indicated confidence depth.
 	StateWaitMsg(ctx context.Context, cid cid.Cid, confidence uint64) (*MsgLookup, error)
@@ -378,6 +648,18 @@ type FullNode interface {
 	// If not found, it blocks until the message arrives on chain, and gets to the
 	// indicated confidence depth.
 	StateWaitMsgLimited(ctx context.Context, cid cid.Cid, confidence uint64, limit abi.ChainEpoch) (*MsgLookup, error)
+	// StateWaitMsgBounded looks back up to limit epochs in the chain for a message, use LookbackNoLimit (-1)
+	// to search the whole chain. If not found, it blocks until the message arrives on chain, and gets
+	// to the indicated confidence depth. Replaced messages are handled as by StateSearchMsgBounded: waiting
+	// for a message which was replaced fails instead of blocking forever.
+	StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*MsgLookup, error)
+	// StateWatchMsgs returns a channel which receives an event when a watched
+	// message gets to the confidence of the watch, i.e. the tipset executing it
+	// has that many tipsets on top of it, and another when the tipset is
+	// reverted. Messages are confirmed again if they are re-included after a
+	// revert. A watched message already on chain is reported when it gets to
+	// the confidence, or right away if it's already deeper.
+	StateWatchMsgs(context.Context, MsgWatch) (<-chan *MsgWatchEvent, error)
 	// StateListMiners returns the addresses of every miner that has claimed power in the Power Actor
 	StateListMiners(context.Context, types.TipSetKey) ([]address.Address, error)
 	// StateListActors returns the addresses of every actor in the state
@@ -388,6 +670,10 @@ type FullNode interface {
 	StateMarketParticipants(context.Context, types.TipSetKey) (map[string]MarketBalance, error)
 	// StateMarketDeals returns information about every deal in the Storage Market
 	StateMarketDeals(context.Context, types.TipSetKey) (map[string]MarketDeal, error)
+	// StateMarketDealsPage returns the deals in the Storage Market with IDs from
+	// start, at most limit of them (no limit when 0), to page through the deals
+	// by their IDs.
+	StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]MarketDeal, error)
 	// StateMarketStorageDeal returns information about the indicated deal
 	StateMarketStorageDeal(context.Context, abi.DealID, types.TipSetKey) (*MarketDeal, error)
 	// StateLookupID retrieves the ID address of the given address
@@ -397,12 +683,30 @@ type FullNode interface {
 	// StateChangedActors returns all the actors whose states change between the two given state CIDs
 	// TODO: Should this take tipset keys instead?
 	StateChangedActors(context.Context, cid.Cid, cid.Cid) (map[string]types.Actor, error)
+	// StateDiff returns the difference between the states computed by the two
+	// given tipsets. If actor is the empty address, it lists the actors
+	// created, deleted and modified; otherwise it returns the changes of the
+	// fields of the state of the actor, and the entries added, removed and
+	// modified in the collections of the state, e.g. the sectors of a miner.
+	StateDiff(ctx context.Context, from, to types.TipSetKey, actor address.Address) (*StateDiff, error)
+	// StateWatchPaths returns a channel which receives the changes to the
+	// given actor state paths every time the chain head changes. Values are
+	// compared between the parent states of the previous and new heads, so
+	// reverts are reported as changes too.
+	StateWatchPaths(context.Context, []StateWatchPath) (<-chan []*StateWatchChange, error)
+	// StateSubscribeChanges returns a channel which receives the changes of
+	// actor states matching the filter, for every tipset applied or reverted
+	// by the chain head changes. A change of a tipset is the difference
+	// between the states computed by its parent and by it, so reverted
+	// tipsets are reported with the changes they had applied.
+	StateSubscribeChanges(context.Context, StateChangeFilter) (<-chan []*StateChangeEvent, error)
 	// StateGetReceipt returns the message receipt for the given message
 	StateGetReceipt(context.Context, cid.Cid, types.TipSetKey) (*types.MessageReceipt, error)
 	// StateMinerSectorCount returns the number of sectors in a miner's sector set and proving set
 	StateMinerSectorCount(context.Context, address.Address, types.TipSetKey) (MinerSectors, error)
 	// StateCompute is a flexible command that applies the given messages on the given tipset.
 	// The messages are run as though the VM were at the provided height.
+	// The trace holds the execution traces of the tipset messages followed by the given ones.
 	StateCompute(context.Context, abi.ChainEpoch, []*types.Message, types.TipSetKey) (*ComputeStateOutput, error)
 	// StateVerifierStatus returns the data cap for the given address.
 	// Returns nil if there is no entry in the data cap table for the
@@ -421,11 +725,19 @@ type FullNode interface {
 	// StateCirculatingSupply returns the exact circulating supply of Filecoin at the given tipset.
 	// This is not used anywhere in the protocol itself, and is only for external consumption.
 	StateCirculatingSupply(context.Context, types.TipSetKey) (abi.TokenAmount, error)
-	// StateVMCirculatingSupplyInternal returns an approximation of the circulating supply of Filecoin at the given tipset.
+	// StateVMCirculatingSupply returns an approximation of the circulating supply of Filecoin at the given tipset,
+	// along with the amounts it is computed from: vested, mined, disbursed from the reserve, burnt,
+	// and locked in the market and by miners' pledges.
 	// This is the value reported by the runtime interface to actors code.
+	StateVMCirculatingSupply(context.Context, types.TipSetKey) (CirculatingSupply, error)
+	// StateVMCirculatingSupplyInternal is the former name of StateVMCirculatingSupply.
+	// Deprecated: use StateVMCirculatingSupply.
 	StateVMCirculatingSupplyInternal(context.Context, types.TipSetKey) (CirculatingSupply, error)
 	// StateNetworkVersion returns the network version at the given tipset
 	StateNetworkVersion(context.Context, types.TipSetKey) (network.Version, error)
+	// StateActorCodeCIDs returns the code CIDs of the builtin actors at the
+	// given network version, keyed by actor name
+	StateActorCodeCIDs(context.Context, network.Version) (map[string]cid.Cid, error)
 
 	// MethodGroup: Msig
 	// The Msig methods are used to interact with multisig wallets on the
@@ -553,6 +865,21 @@ type Import struct {
 	FilePath string
 }
 
+// DealAuthorization authorizes the Delegate address to make storage deals as
+// a client on behalf of the Funder address, until the Expiration epoch.
+type DealAuthorization struct {
+	Funder     address.Address
+	Delegate   address.Address
+	Expiration abi.ChainEpoch
+}
+
+// SignedDealAuthorization is a DealAuthorization signed by the key of its
+// Funder.
+type SignedDealAuthorization struct {
+	DealAuthorization
+	Signature crypto.Signature
+}
+
 type DealInfo struct {
 	ProposalCid cid.Cid
 	State       storagemarket.StorageDealStatus
@@ -570,8 +897,15 @@ type DealInfo struct {
 
 	CreationTime time.Time
 	Verified     bool
+
+	// Rejection is set if the provider rejected the deal
+	Rejection *DealRejection
 }
 
+// LookbackNoLimit is the lookback limit of StateSearchMsgBounded and
+// StateWaitMsgBounded to search the whole chain.
+const LookbackNoLimit = abi.ChainEpoch(-1)
+
 type MsgLookup struct {
 	Message   cid.Cid // Can be different than requested, in case it was replaced, but only gas values changed
 	Receipt   types.MessageReceipt
@@ -580,6 +914,75 @@ type MsgLookup struct {
 	Height    abi.ChainEpoch
 }
 
+// MsgWatch selects the messages watched by StateWatchMsgs: either the message
+// with the given CID, or all the messages sent to an actor, optionally only
+// those calling a method. When watching a recipient, a single message is
+// reported per tipset.
+type MsgWatch struct {
+	Message cid.Cid
+
+	// To is the recipient of the watched messages, as set in the messages
+	// or its ID address, when Message is undefined
+	To     address.Address
+	Method *abi.MethodNum
+
+	// Confidence is the number of tipsets on top of the tipset executing a
+	// message before it is confirmed
+	Confidence uint64
+	// Timeout, when not zero, is the height at which a timeout event is sent
+	// if no message was confirmed yet
+	Timeout abi.ChainEpoch
+}
+
+// Types of the MsgWatchEvents.
+const (
+	MsgConfirmed = "confirmed"
+	MsgReverted  = "reverted"
+	MsgTimeout   = "timeout"
+)
+
+type MsgWatchEvent struct {
+	Type string
+
+	// Message and Receipt are not set on timeouts
+	Message cid.Cid
+	Receipt *types.MessageReceipt
+
+	// TipSet is the tipset which executed the message, the reverted tipset
+	// for reverts
+	TipSet types.TipSetKey
+	Height abi.ChainEpoch
+	// Head is the height of the chain head when the message was confirmed
+	// or timed out; not set for reverts
+	Head abi.ChainEpoch
+}
+
+// AddrMessage is a message sent or received by an address, with its
+// execution.
+type AddrMessage struct {
+	Cid     cid.Cid
+	Message *types.Message
+	Receipt *types.MessageReceipt
+	// TipSet is the tipset which executed the message
+	TipSet types.TipSetKey
+	Height abi.ChainEpoch
+}
+
+// HDExport is the backup of the hierarchical deterministic keys of a
+// wallet: the BIP39 mnemonic they are derived from, and the derivation
+// paths of the addresses in use.
+type HDExport struct {
+	Mnemonic string
+	Keys     []HDKey
+}
+
+type HDKey struct {
+	Address address.Address
+	Type    types.KeyType
+	Index   uint64
+	Path    string // like m/44'/461'/0'/0/0
+}
+
 type MsgGasCost struct {
 	Message            cid.Cid // Can be different than requested, in case it was replaced, but only gas values changed
 	GasUsed            abi.TokenAmount
@@ -610,6 +1013,68 @@ type ActorState struct {
 	State   interface{}
 }
 
+// Kinds of the changes of actors in a StateDiff.
+const (
+	ActorCreated  = "created"
+	ActorDeleted  = "deleted"
+	ActorModified = "modified"
+)
+
+// StateDiff is the difference between the states of two tipsets, returned by
+// StateDiff. Actors is set when diffing all the actors, Actor when diffing a
+// single actor.
+type StateDiff struct {
+	From, To cid.Cid
+
+	Actors []ActorChange
+	Actor  *ActorStateDiff
+}
+
+type ActorChange struct {
+	Address address.Address
+	// Name is the name of the actor code, e.g. "fil/2/storageminer"
+	Name   string
+	Change string
+	// From is nil for created actors, To for deleted actors
+	From, To *types.Actor
+}
+
+// ActorStateDiff is the difference between two states of an actor: the fields
+// of its state whose value changed, and the entries added, removed and
+// modified in the collections (HAMTs and AMTs) of its state, decoded with the
+// actor abstraction of their version.
+type ActorStateDiff struct {
+	ActorChange
+
+	Fields      []FieldChange
+	Collections []CollectionDiff
+}
+
+type FieldChange struct {
+	Field    string
+	From, To json.RawMessage
+}
+
+// CollectionDiff lists the changes of a collection of an actor state, e.g. the
+// sectors of a miner, by key; only the modifications the actor abstraction
+// tracks are listed, e.g. the expiration of the sectors.
+type CollectionDiff struct {
+	Name     string
+	Added    []CollectionEntry
+	Removed  []CollectionEntry
+	Modified []CollectionChange
+}
+
+type CollectionEntry struct {
+	Key   string
+	Value interface{}
+}
+
+type CollectionChange struct {
+	Key      string
+	From, To interface{}
+}
+
 type PCHDir int
 
 const (
@@ -769,6 +1234,83 @@ type IpldObject struct {
 	Obj interface{}
 }
 
+// StateChangeFilter selects the state changes sent by StateSubscribeChanges;
+// all of its set fields must match.
+type StateChangeFilter struct {
+	// Actors are the actors whose changes are sent; when empty, all the
+	// actors changed by a tipset are matched, which requires diffing the whole
+	// state trees unless Methods or a singleton Predicate is set.
+	Actors []address.Address
+	// Methods, when set, only matches the actors which were sent a message
+	// calling one of these methods by the changing tipset.
+	Methods []abi.MethodNum
+	// MinHeight and MaxHeight bound the heights of the matched tipsets; zero
+	// values don't bound the range.
+	MinHeight abi.ChainEpoch
+	MaxHeight abi.ChainEpoch
+	// Predicate is the name of a predicate of the chain/events/state package
+	// the changes must match, e.g. "deal-state-changed" or
+	// "miner-sectors-changed"; the data it returns is set on the change.
+	Predicate string
+}
+
+// Types of the StateChangeEvents.
+const (
+	StateChangeApply  = "apply"
+	StateChangeRevert = "revert"
+)
+
+type StateChangeEvent struct {
+	Type   string
+	TipSet types.TipSetKey
+	Height abi.ChainEpoch
+
+	// Actor is the ID address of the changed actor; From is nil for created
+	// actors, To for deleted actors
+	Actor    address.Address
+	From, To *types.Actor
+
+	// Messages are the messages to the actor executed by the tipset, matching
+	// the Methods of the filter if set
+	Messages []cid.Cid
+	// Data is the change returned by the predicate of the filter
+	Data interface{}
+}
+
+type StateWatchPath struct {
+	Actor address.Address
+	// Path points into the actor's state using the same syntax as
+	// ChainGetNode, for example "1/@Ha:f01234"; empty for the whole state
+	Path string
+	// Diff, when set, treats the value at Path as a HAMT or AMT root and
+	// reports changes per entry instead of the whole value. It is one of
+	// "@H" (raw keys), "@Ha" (address keys), "@Hi" (int keys), "@Hu" (uint
+	// keys) or "@A" (AMT).
+	Diff string
+}
+
+type StateWatchChange struct {
+	// Watch is the index of the changed path in the StateWatchPaths request
+	Watch  int
+	TipSet types.TipSetKey
+	Height abi.ChainEpoch
+
+	// Old and New are the values at the watched path, nil if the actor or
+	// path didn't exist; not set when the path is diffed
+	Old *IpldObject
+	New *IpldObject
+
+	// Entries lists the changed entries when the path is diffed
+	Entries []StateWatchEntry
+}
+
+type StateWatchEntry struct {
+	Key string
+	// Old is nil for added entries, New is nil for removed ones
+	Old interface{}
+	New interface{}
+}
+
 type ActiveSync struct {
 	Base   *types.TipSet
 	Target *types.TipSet
@@ -779,12 +1321,69 @@ type ActiveSync struct {
 	Start   time.Time
 	End     time.Time
 	Message string
+
+	// Headers are collected from the target down to the base, then the
+	// messages of the tipsets are fetched and the tipsets validated from the
+	// base up
+	HeadersFetched   uint64
+	MessagesFetched  uint64
+	TipSetsValidated uint64
+	// Stages is the time spent in each stage so far
+	Stages []SyncStageTime
+
+	// ValidationRate is the number of tipsets fetched and validated per second
+	// while syncing messages
+	ValidationRate float64
+	// ETA is the estimated time left to reach the target at ValidationRate;
+	// zero when unknown, e.g. while collecting headers
+	ETA time.Duration
+	// WeightDiff is the parent weight of the target minus the parent weight
+	// of the current head
+	WeightDiff types.BigInt
+}
+
+type SyncStageTime struct {
+	Stage   SyncStateStage
+	Elapsed time.Duration
 }
 
 type SyncState struct {
 	ActiveSyncs []ActiveSync
 
 	VMApplied uint64
+
+	// Trusted is set in the lightweight sync mode, which doesn't validate
+	// the chain up to a trusted checkpoint
+	Trusted *TrustedCheckpoint
+}
+
+type TrustedCheckpoint struct {
+	TipSet types.TipSetKey
+	Height abi.ChainEpoch
+	// Reached is whether the local chain got to the checkpoint yet
+	Reached bool
+}
+
+type BeaconInfo struct {
+	// Start is the first epoch the beacon is used at
+	Start abi.ChainEpoch
+
+	// ChainHash is the hash of the drand chain info, identifying it
+	ChainHash   string
+	Period      time.Duration
+	GenesisTime int64
+	Servers     []string
+}
+
+type BadBlock struct {
+	Block  cid.Cid
+	Reason string
+}
+
+type BlockedPeer struct {
+	ID     peer.ID
+	Reason string
+	Since  time.Time
 }
 
 type SyncStateStage int
@@ -797,6 +1396,7 @@ const (
 	StageSyncComplete
 	StageSyncErrored
 	StageFetchingMessages
+	StageFetchingState
 )
 
 func (v SyncStateStage) String() string {
@@ -813,6 +1413,8 @@ func (v SyncStateStage) String() string {
 		return "error"
 	case StageFetchingMessages:
 		return "fetching messages"
+	case StageFetchingState:
+		return "fetching state"
 	default:
 		return fmt.Sprintf("<unknown: %d>", v)
 	}
@@ -825,9 +1427,156 @@ const (
 	MpoolRemove
 )
 
+// MpoolRemoveReason tells why a message was removed from the mpool.
+type MpoolRemoveReason int
+
+const (
+	// MpoolRemoveNone is the reason of MpoolAdd updates.
+	MpoolRemoveNone MpoolRemoveReason = iota
+	// MpoolRemoveIncluded is set when the message, or another message of the
+	// sender with the same nonce, was included in the chain.
+	MpoolRemoveIncluded
+	// MpoolRemoveReplaced is set when the message was replaced by a message
+	// with the same nonce paying a higher gas premium.
+	MpoolRemoveReplaced
+	// MpoolRemoveEvicted is set when the message was pruned from a full mpool.
+	MpoolRemoveEvicted
+	// MpoolRemoveAbandoned is set when the local message was abandoned with
+	// MpoolAbandon.
+	MpoolRemoveAbandoned
+)
+
+func (r MpoolRemoveReason) String() string {
+	switch r {
+	case MpoolRemoveNone:
+		return "none"
+	case MpoolRemoveIncluded:
+		return "included"
+	case MpoolRemoveReplaced:
+		return "replaced"
+	case MpoolRemoveEvicted:
+		return "evicted"
+	case MpoolRemoveAbandoned:
+		return "abandoned"
+	default:
+		return fmt.Sprintf("<unknown: %d>", r)
+	}
+}
+
 type MpoolUpdate struct {
 	Type    MpoolChange
 	Message *types.SignedMessage
+	// Reason is set for MpoolRemove updates
+	Reason MpoolRemoveReason
+}
+
+// MessageSchedule is the condition for pushing a scheduled message; all of its
+// set fields must be met.
+type MessageSchedule struct {
+	// AtEpoch is the first epoch the message is pushed at
+	AtEpoch abi.ChainEpoch
+	// MaxBaseFee defers the message until the base fee is at most this value
+	MaxBaseFee abi.TokenAmount
+}
+
+type ScheduledMessageState int
+
+const (
+	ScheduledPending ScheduledMessageState = iota
+	ScheduledPushed
+	ScheduledFailed
+	// ScheduledPushing is the state of a message signed and being pushed,
+	// which is pushed again if the node stopped meanwhile
+	ScheduledPushing
+)
+
+func (s ScheduledMessageState) String() string {
+	switch s {
+	case ScheduledPending:
+		return "pending"
+	case ScheduledPushed:
+		return "pushed"
+	case ScheduledFailed:
+		return "failed"
+	case ScheduledPushing:
+		return "pushing"
+	default:
+		return fmt.Sprintf("<unknown: %d>", s)
+	}
+}
+
+// ScheduledMessage is a message pushed by the node once its schedule is met.
+type ScheduledMessage struct {
+	ID       uint64
+	Schedule MessageSchedule
+
+	// Message is pushed like with MpoolPushMessage, using Spec, unless the
+	// message was signed beforehand
+	Message *types.Message
+	Spec    *MessageSendSpec
+
+	// Signed is the message signed beforehand, or Message once signed
+	Signed *types.SignedMessage
+
+	State ScheduledMessageState
+	Error string
+
+	// Finished is the epoch the message was pushed or failed at; finished
+	// messages are forgotten a day later
+	Finished abi.ChainEpoch
+}
+
+// FeeForecast is the base fee projection and the premium statistics returned
+// by GasEstimateFeeForecast.
+type FeeForecast struct {
+	// Height of the tipset the forecast starts from
+	Height abi.ChainEpoch
+	// Epochs and Blocks sampled for the statistics
+	Epochs uint64
+	Blocks int
+	// Fullness is the mean ratio of the gas limit of the messages of a
+	// sampled block to the block gas target
+	Fullness float64
+	// BaseFees holds the projected base fee of each of the next epochs, the
+	// first one being the base fee of the child of the tipset
+	BaseFees []types.BigInt
+	// Premiums holds the gas premiums of the sampled messages at
+	// FeeForecastPercentiles, weighted by gas limit; it is empty if no
+	// messages were included
+	Premiums []PremiumPercentile
+}
+
+// FeeForecastPercentiles are the percentiles of the gas premiums returned by
+// GasEstimateFeeForecast.
+var FeeForecastPercentiles = []int{10, 25, 50, 75, 90}
+
+type PremiumPercentile struct {
+	Percentile int
+	// GasPremium at or below which the premiums of Percentile percent of the
+	// sampled gas were
+	GasPremium types.BigInt
+}
+
+// GasProfile aggregates the gas charges of a message execution by charge
+// point.
+type GasProfile struct {
+	MsgCid  cid.Cid
+	GasUsed int64
+	// Charges holds an entry per charge point, most expensive first
+	Charges []GasProfileEntry
+}
+
+type GasProfileEntry struct {
+	// Name of the charge point, as in the gas charges of execution traces
+	Name       string
+	Count      int64
+	TotalGas   int64
+	ComputeGas int64
+	StorageGas int64
+	// Bytes is the size of the data charged for, for IPLD gets and puts and
+	// hashing
+	Bytes     int64
+	TimeTaken time.Duration
 }
 
 type ComputeStateOutput struct {
@@ -840,12 +1589,19 @@ type DealCollateralBounds struct {
 	Max abi.TokenAmount
 }
 
+// CirculatingSupply is the breakdown of the circulating supply computed by
+// the VM: FilCirculating = FilVested + FilMined + FilReserveDisbursed -
+// FilBurnt - FilLocked, floored at zero.
 type CirculatingSupply struct {
-	FilVested      abi.TokenAmount
-	FilMined       abi.TokenAmount
-	FilBurnt       abi.TokenAmount
-	FilLocked      abi.TokenAmount
-	FilCirculating abi.TokenAmount
+	FilVested           abi.TokenAmount
+	FilMined            abi.TokenAmount
+	FilReserveDisbursed abi.TokenAmount
+	FilBurnt            abi.TokenAmount
+	// FilLocked is the sum of FilMarketLocked and FilPowerLocked
+	FilLocked       abi.TokenAmount
+	FilMarketLocked abi.TokenAmount // deal collateral and client escrow locked in the market actor
+	FilPowerLocked  abi.TokenAmount // pledge collateral of miners, tracked by the power actor
+	FilCirculating  abi.TokenAmount
 }
 
 type MiningBaseInfo struct {
@@ -885,6 +1641,188 @@ type HeadChange struct {
 	Val  *types.TipSet
 }
 
+// HeadChanges is an entry of the node's head change journal.
+type HeadChanges struct {
+	// Cursor is the position of the entry in the journal; pass it to
+	// ChainNotifyFrom to resume after this entry
+	Cursor uint64
+	// Changes are the reverted tipsets, newest first, followed by the
+	// applied tipsets, oldest first
+	Changes []*HeadChange
+	// Truncated is set on replayed entries which applied more tipsets than
+	// the journal keeps; only the newest applied tipsets are included
+	Truncated bool
+}
+
+type ChainReorg struct {
+	// Seq is the position of the reorg in the reorg journal
+	Seq uint64
+	// Timestamp is the unix time at which the reorg was recorded
+	Timestamp uint64
+	// Depth is the number of tipsets reverted
+	Depth int
+
+	From       types.TipSetKey
+	FromHeight abi.ChainEpoch
+	To         types.TipSetKey
+	ToHeight   abi.ChainEpoch
+	// ForkHeight is the height of the common ancestor of the old and new heads
+	ForkHeight abi.ChainEpoch
+
+	// DroppedBlocks lists reverted blocks which are not part of the new chain
+	DroppedBlocks []cid.Cid
+	// DroppedMessages lists messages included in reverted blocks which are not
+	// included again in the applied tipsets
+	DroppedMessages []cid.Cid
+}
+
+type BlockstoreGCOpts struct {
+	// DiscardRatio is the fraction of a value log file which must be garbage
+	// before the file is rewritten; zero uses the default
+	DiscardRatio float64
+	// Compact also flattens the LSM tree after value log GC
+	Compact bool
+}
+
+type IndexBackfillOpts struct {
+	From abi.ChainEpoch
+	// To is the last epoch to index; if negative, the range of the
+	// interrupted backfill starting at From is resumed, or the head is used
+	To abi.ChainEpoch
+	// MaxTipSetsPerSecond bounds the indexing rate; zero doesn't bound it
+	MaxTipSetsPerSecond float64
+	// Restart ignores the checkpoint of an interrupted backfill
+	Restart bool
+}
+
+type IndexBackfillProgress struct {
+	From abi.ChainEpoch
+	To   abi.ChainEpoch
+	// Next is the highest epoch which isn't indexed yet; the range is
+	// indexed from the top down
+	Next abi.ChainEpoch
+	// Resumed is set when an interrupted backfill was resumed
+	Resumed bool
+
+	// TipSets and Messages are the numbers indexed so far by this call
+	TipSets  int
+	Messages int
+
+	Done  bool
+	Error string
+}
+
+type BlockstoreGCProgress struct {
+	Namespace string
+	// Stage is one of "start", "gc", "compact" or "done"
+	Stage string
+	// Rewrites is the number of value log files rewritten so far
+	Rewrites int
+
+	LSMSize  int64
+	VlogSize int64
+
+	Error string
+}
+
+type ChainPruneResult struct {
+	// DryRun is set when nothing was actually deleted
+	DryRun bool
+	// Boundary is the height at and below which state trees were pruned
+	Boundary abi.ChainEpoch
+	// StateRoots is the number of state trees that were pruned
+	StateRoots int
+	// Objects and Bytes describe the deleted (or deletable) state objects
+	Objects uint64
+	Bytes   uint64
+}
+
+type ChainStat struct {
+	// BlockstoreCache is nil if the chain blockstore isn't cached
+	BlockstoreCache *BlockstoreCacheStat
+}
+
+type BlockstoreCacheStat struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+
+	// Entries and Bytes describe the cached blocks
+	Entries int
+	Bytes   uint64
+	// Capacity is the maximum size of the cached blocks in bytes
+	Capacity uint64
+}
+
+type ChainCheckResult struct {
+	// Checked is the number of blocks which were re-hashed
+	Checked int
+	// Corrupt lists the blocks whose data didn't match their CID
+	Corrupt []cid.Cid
+	// Repaired lists the corrupt blocks which were fetched again
+	Repaired []cid.Cid
+}
+
+type BlockHeaderValidation struct {
+	Block cid.Cid
+	// Valid is set if all checks passed
+	Valid  bool
+	Checks []BlockHeaderCheck
+}
+
+type BlockHeaderCheck struct {
+	Name string
+	// Error is empty if the check passed
+	Error string
+}
+
+type StateProof struct {
+	// StateRoot is the parent state root of the requested tipset
+	StateRoot cid.Cid
+	// Actor is the ID address of the actor, which is the key proven in the
+	// actors HAMT
+	Actor address.Address
+	// Head is the actor state head
+	Head cid.Cid
+	// Path is the proven path relative to Head
+	Path string
+	// Value is the CID of the IPLD node the path resolves to
+	Value cid.Cid
+	// Blocks are the IPLD blocks visited from StateRoot to Value, in the order
+	// they were visited
+	Blocks []ProofBlock
+}
+
+type ProofBlock struct {
+	Cid  cid.Cid
+	Data []byte
+}
+
+type ChainExportRangeOpts struct {
+	// Headers includes the block headers
+	Headers bool
+	// Messages includes the messages included in each block
+	Messages bool
+	// Receipts includes the receipts of the parent tipsets' messages
+	Receipts bool
+	// StateRoots includes the full parent state trees
+	StateRoots bool
+}
+
+// MinerOwnerMessage describes a message sent by MinerWithdrawBalance,
+// MinerChangeWorker, MinerConfirmChangeWorker or MinerSetControlAddresses.
+type MinerOwnerMessage struct {
+	// Message is the CID of the pushed message
+	Message cid.Cid
+	Owner   address.Address
+	// Multisig is set if the owner is a multisig, and Message proposes or
+	// approves a multisig transaction
+	Multisig bool
+	// Approved is set if Message approves the pending transaction TxID
+	Approved bool
+	TxID     int64
+}
+
 type MsigProposeResponse int
 
 const (
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/ipfs/go-cid"
@@ -14,6 +15,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/extern/sector-storage/fsutil"
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
 	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
 	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 )
@@ -37,6 +39,12 @@ type StorageMiner interface {
 	// List all staged sectors
 	SectorsList(context.Context) ([]abi.SectorNumber, error)
 
+	// SectorsListWithDetails returns the sectors matching the filter with
+	// their sealing state, deals and on-chain info, all joined against the
+	// chain state of the current head in one call. Sector logs aren't
+	// included, use SectorsStatus for them.
+	SectorsListWithDetails(ctx context.Context, filter SectorsFilter) ([]SectorDetails, error)
+
 	SectorsRefs(context.Context) (map[string][]SealedRef, error)
 
 	// SectorStartSealing can be called on sectors in Empty or WaitDeals states
@@ -68,6 +76,15 @@ type StorageMiner interface {
 	// SealingSchedDiag dumps internal sealing scheduler state
 	SealingSchedDiag(context.Context) (interface{}, error)
 
+	// SealingSchedPause stops the scheduler from assigning tasks of the given
+	// types, or of all types if none are given, to workers. Tasks already
+	// running are finished; new ones stay queued until resumed.
+	SealingSchedPause(context.Context, []sealtasks.TaskType) error
+	// SealingSchedResume resumes assigning tasks of the given types, or of all
+	// types if none are given; resuming some types doesn't lift a pause of all
+	// types.
+	SealingSchedResume(context.Context, []sealtasks.TaskType) error
+
 	stores.SectorIndex
 
 	MarketImportDealData(ctx context.Context, propcid cid.Cid, path string) error
@@ -94,6 +111,17 @@ type StorageMiner interface {
 	DealsSetConsiderOfflineStorageDeals(context.Context, bool) error
 	DealsConsiderOfflineRetrievalDeals(context.Context) (bool, error)
 	DealsSetConsiderOfflineRetrievalDeals(context.Context, bool) error
+	// DealsSLAReports returns the service level reports of the deals of the
+	// miner for the last count periods, most recent first, starting with the
+	// report of the current period. A count of 0 returns all the reports kept.
+	DealsSLAReports(ctx context.Context, count int) ([]DealSLAReport, error)
+	// DealsImportAuthorization verifies a deal authorization against the key
+	// of its funder in the chain state and keeps it, for the deals from its
+	// delegate to be accepted when the funder is one of the AuthorizedFunders
+	// of the miner config.
+	DealsImportAuthorization(ctx context.Context, auth SignedDealAuthorization) error
+	// DealsListAuthorizations lists the deal authorizations imported.
+	DealsListAuthorizations(ctx context.Context) ([]SignedDealAuthorization, error)
 
 	StorageAddLocal(ctx context.Context, path string) error
 
@@ -102,11 +130,37 @@ type StorageMiner interface {
 	PiecesGetPieceInfo(ctx context.Context, pieceCid cid.Cid) (*piecestore.PieceInfo, error)
 	PiecesGetCIDInfo(ctx context.Context, payloadCid cid.Cid) (*piecestore.CIDInfo, error)
 
+	// MarketVerifyPiece re-reads every stored copy of the piece, unsealing
+	// sectors which don't have an unsealed copy, and checks that the
+	// recomputed CommP matches the piece CID.
+	MarketVerifyPiece(ctx context.Context, pieceCid cid.Cid) (*PieceVerification, error)
+
 	// CreateBackup creates node backup onder the specified file name. The
 	// method requires that the lotus-miner is running with the
 	// LOTUS_BACKUP_BASE_PATH environment variable set to some path, and that
 	// the path specified when calling CreateBackup is within the base path
 	CreateBackup(ctx context.Context, fpath string) error
+
+	// ConfigGet returns a section of the miner config encoded in JSON. The
+	// section is a dot-separated path of field names, e.g. "Dealmaking" or
+	// "Sealing.MaxSealingSectors", matched case-insensitively; the empty
+	// section is the whole config. FIL amounts are encoded in attoFIL.
+	ConfigGet(ctx context.Context, section string) (json.RawMessage, error)
+	// ConfigSet sets a section of the miner config from its JSON encoding, as
+	// returned by ConfigGet, and saves the config. Fields missing from the
+	// JSON object keep their values; unknown fields and invalid values are
+	// rejected, leaving the config unchanged. The result tells whether some of
+	// the settings changed only apply after restarting the miner.
+	ConfigSet(ctx context.Context, section string, value json.RawMessage) (*ConfigSetResult, error)
+}
+
+// ConfigSetResult describes the changes made by ConfigSet.
+type ConfigSetResult struct {
+	// Changed lists the settings changed, as dot-separated paths
+	Changed []string
+	// RestartRequired is true if some of the changed settings only apply
+	// after restarting the miner
+	RestartRequired bool
 }
 
 type SealRes struct {
@@ -139,6 +193,11 @@ type SectorInfo struct {
 	Retries      uint64
 	ToUpgrade    bool
 
+	// Last epochs the precommit and the prove commit of the sector can land
+	// on chain at, 0 until known
+	TicketExpiration abi.ChainEpoch
+	SeedExpiration   abi.ChainEpoch
+
 	LastErr string
 
 	Log []SectorLog
@@ -157,6 +216,24 @@ type SectorInfo struct {
 	Early abi.ChainEpoch
 }
 
+// SectorsFilter selects the sectors returned by SectorsListWithDetails. Empty
+// fields match all sectors.
+type SectorsFilter struct {
+	States  []SectorState
+	Sectors []abi.SectorNumber
+	// WithDeals only matches sectors containing deals
+	WithDeals bool
+}
+
+type SectorDetails struct {
+	SectorInfo
+
+	// OnChain is whether the sector is in the miner actor state
+	OnChain bool
+	// Active is whether the sector is being proven and isn't faulty
+	Active bool
+}
+
 type SealedRef struct {
 	SectorID abi.SectorNumber
 	Offset   abi.PaddedPieceSize
@@ -167,6 +244,77 @@ type SealedRefs struct {
 	Refs []SealedRef
 }
 
+// PieceVerification is the result of recomputing the CommP of a piece.
+type PieceVerification struct {
+	PieceCID cid.Cid
+	// Valid is set if every copy of the piece matches its CID
+	Valid  bool
+	Copies []PieceCopyVerification
+}
+
+// PieceCopyVerification is the result of checking one copy of a piece.
+type PieceCopyVerification struct {
+	DealID   abi.DealID
+	SectorID abi.SectorNumber
+	Offset   abi.PaddedPieceSize
+	Length   abi.PaddedPieceSize
+
+	// CommP is the recomputed piece commitment; it's undefined if reading
+	// the copy failed
+	CommP cid.Cid
+	Match bool
+	Error string
+}
+
+// DealSLAReport aggregates the service levels of the storage and retrieval
+// deals of the miner over a period. Each deal is accounted in the period in
+// which the measured step completed.
+type DealSLAReport struct {
+	Start time.Time
+	End   time.Time
+	// InProgress is set for the report of the current period
+	InProgress bool
+
+	// Ingest is the time taken by the data transfers of online storage deals
+	Ingest DurationStats
+	// Activation is the time from a storage deal proposal being received to
+	// the deal being active on chain
+	Activation DurationStats
+	// ActivationMargin is the number of epochs left between the activation
+	// of the deals and the start epoch of their proposal
+	ActivationMargin EpochStats
+	// DealsActivatedLate is the number of deals activated after the start
+	// epoch of their proposal
+	DealsActivatedLate int
+	DealsFailed        int
+
+	// RetrievalResponse is the time from a retrieval deal proposal being
+	// received to the miner responding to it
+	RetrievalResponse DurationStats
+	// RetrievalCompletion is the time from a retrieval deal proposal being
+	// received to the retrieval being completed
+	RetrievalCompletion DurationStats
+	RetrievalsFailed    int
+}
+
+// DurationStats summarizes the distribution of a set of durations.
+type DurationStats struct {
+	Count  int
+	Min    time.Duration
+	Median time.Duration
+	P90    time.Duration
+	Max    time.Duration
+}
+
+// EpochStats summarizes the distribution of a set of epoch counts.
+type EpochStats struct {
+	Count  int
+	Min    abi.ChainEpoch
+	Median abi.ChainEpoch
+	P90    abi.ChainEpoch
+	Max    abi.ChainEpoch
+}
+
 type SealTicket struct {
 	Value abi.SealRandomness
 	Epoch abi.ChainEpoch
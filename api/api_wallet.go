@@ -23,6 +23,9 @@ const (
 	// Signing a deal proposal. signing raw cbor proposal bytes (MsgMeta.Extra is empty)
 	MTDealProposal = "dealproposal"
 
+	// Signing a deal authorization. signing the bytes returned by dealauth.SigningBytes (MsgMeta.Extra is empty)
+	MTDealAuthorization = "dealauthorization"
+
 	// TODO: Deals, Vouchers, VRF
 )
 
@@ -0,0 +1,407 @@
+// Package apiconns tracks the websocket connections to the API and their
+// subscriptions, the calls returning channels, and bounds them, so that a
+// slow consumer can't make the node buffer its notifications without limit.
+package apiconns
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/api/apiusage"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+var log = logging.Logger("apiconns")
+
+// Policy is what is done when the buffer of a subscription is full.
+type Policy string
+
+const (
+	// DropOldest drops the oldest buffered value of the subscription.
+	DropOldest Policy = "drop-oldest"
+	// Disconnect closes the connection of the subscription.
+	Disconnect Policy = "disconnect"
+)
+
+// ParsePolicy parses a policy name.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case DropOldest, Disconnect:
+		return p, nil
+	default:
+		return "", xerrors.Errorf("unknown subscription overflow policy '%s', expected '%s' or '%s'", s, DropOldest, Disconnect)
+	}
+}
+
+// ErrTooManySubscriptions is returned by the calls exceeding the limit of
+// subscriptions of their connection.
+var ErrTooManySubscriptions = xerrors.New("too many subscriptions on the connection")
+
+// Limits bounds the subscriptions of the connections.
+type Limits struct {
+	// MaxSubscriptions is the number of subscriptions a connection can have
+	// at once, zero for no limit
+	MaxSubscriptions int
+	// Buffer is the number of values buffered for each subscription while
+	// its consumer is slow
+	Buffer int
+	// Overflow is what is done when the buffer of a subscription is full
+	Overflow Policy
+}
+
+// DefaultLimits are the limits of the registries until they are set.
+var DefaultLimits = Limits{
+	Buffer:   1024,
+	Overflow: Disconnect,
+}
+
+type connKey struct{}
+
+type conn struct {
+	id     uint64
+	remote string
+	token  string
+	opened time.Time
+
+	lk      sync.Mutex
+	netConn net.Conn
+	subs    map[uint64]*subscription
+}
+
+type subscription struct {
+	id      uint64
+	method  string
+	started time.Time
+
+	lk       sync.Mutex
+	buffered int
+	dropped  uint64
+}
+
+// close closes the network connection of c, once the websocket handshake
+// hijacked it.
+func (c *conn) close() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if c.netConn != nil {
+		_ = c.netConn.Close()
+	}
+}
+
+// Registry tracks the websocket connections served by its Handler, and the
+// subscriptions made on them through the API returned by LimitFullAPI and
+// LimitStorageMinerAPI.
+type Registry struct {
+	lk     sync.Mutex
+	limits Limits
+	nextID uint64
+	conns  map[uint64]*conn
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		limits: DefaultLimits,
+		conns:  map[uint64]*conn{},
+	}
+}
+
+// SetLimits sets the limits of the subscriptions made from now on.
+func (r *Registry) SetLimits(l Limits) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.limits = l
+}
+
+func (r *Registry) getLimits() Limits {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return r.limits
+}
+
+func (r *Registry) newID() uint64 {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.nextID++
+	return r.nextID
+}
+
+// Connections returns the open connections, the oldest first.
+func (r *Registry) Connections() []api.APIConnection {
+	r.lk.Lock()
+	conns := make([]*conn, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.lk.Unlock()
+
+	out := make([]api.APIConnection, 0, len(conns))
+	for _, c := range conns {
+		ac := api.APIConnection{
+			ID:         c.id,
+			RemoteAddr: c.remote,
+			TokenID:    c.token,
+			Opened:     c.opened,
+		}
+
+		c.lk.Lock()
+		for _, s := range c.subs {
+			s.lk.Lock()
+			ac.Subscriptions = append(ac.Subscriptions, api.APISubscription{
+				ID:       s.id,
+				Method:   s.method,
+				Started:  s.started,
+				Buffered: s.buffered,
+				Dropped:  s.dropped,
+			})
+			s.lk.Unlock()
+		}
+		c.lk.Unlock()
+
+		sort.Slice(ac.Subscriptions, func(i, j int) bool {
+			return ac.Subscriptions[i].ID < ac.Subscriptions[j].ID
+		})
+		out = append(out, ac)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Handler tracks the websocket connections served by next, while they are
+// open, and passes them to the calls through the request context. It should
+// be wrapped by the Handler of an apiusage.Tracker for the connections to be
+// attributed to their token.
+func (r *Registry) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !websocket.IsWebSocketUpgrade(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		c := r.open(req.Context(), req.RemoteAddr)
+		defer r.remove(c)
+
+		ctx := context.WithValue(req.Context(), connKey{}, c)
+		next.ServeHTTP(&hijackWriter{ResponseWriter: w, c: c}, req.WithContext(ctx))
+	})
+}
+
+// TrackConn tracks conn, a connection of a transport other than http, until
+// the returned function is called, and passes it to the calls made on it
+// through the returned context. Like for Handler, the connection is
+// attributed to the token passed through ctx by an apiusage.Tracker.
+func (r *Registry) TrackConn(ctx context.Context, nc net.Conn) (context.Context, func()) {
+	c := r.open(ctx, nc.RemoteAddr().String())
+	c.netConn = nc
+	return context.WithValue(ctx, connKey{}, c), func() {
+		r.remove(c)
+	}
+}
+
+func (r *Registry) open(ctx context.Context, remote string) *conn {
+	token, _ := apiusage.TokenIDFromContext(ctx)
+	c := &conn{
+		id:     r.newID(),
+		remote: remote,
+		token:  token,
+		opened: time.Now(),
+		subs:   map[uint64]*subscription{},
+	}
+
+	r.lk.Lock()
+	r.conns[c.id] = c
+	r.lk.Unlock()
+	return c
+}
+
+func (r *Registry) remove(c *conn) {
+	r.lk.Lock()
+	delete(r.conns, c.id)
+	r.lk.Unlock()
+}
+
+// hijackWriter records the network connection of a websocket, to be closed
+// by the Disconnect policy.
+type hijackWriter struct {
+	http.ResponseWriter
+	c *conn
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	nc, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.c.lk.Lock()
+	w.c.netConn = nc
+	w.c.lk.Unlock()
+	return nc, brw, nil
+}
+
+// LimitFullAPI returns a FullNode API bounding the subscriptions made through
+// it on the connections handled by the Handler of r.
+func LimitFullAPI(a api.FullNode, r *Registry) api.FullNode {
+	var out apistruct.FullNodeStruct
+	proxy(r, a, &out.Internal)
+	proxy(r, a, &out.CommonStruct.Internal)
+	return &out
+}
+
+// LimitStorageMinerAPI is like LimitFullAPI for the StorageMiner API.
+func LimitStorageMinerAPI(a api.StorageMiner, r *Registry) api.StorageMiner {
+	var out apistruct.StorageMinerStruct
+	proxy(r, a, &out.Internal)
+	proxy(r, a, &out.CommonStruct.Internal)
+	return &out
+}
+
+func proxy(r *Registry, in interface{}, out interface{}) {
+	apistruct.Proxy(in, out, func(m apistruct.ProxyMethod, next apistruct.ProxyCall) apistruct.ProxyCall {
+		ft := m.Type
+		if ft.NumOut() != 2 || ft.Out(0).Kind() != reflect.Chan {
+			return next
+		}
+
+		return func(args []reflect.Value) []reflect.Value {
+			c, ok := args[0].Interface().(context.Context).Value(connKey{}).(*conn)
+			if !ok {
+				return next(args)
+			}
+			return r.subscribe(c, m.Name, ft, args, next)
+		}
+	})
+}
+
+// subscribe makes the call of a method returning a channel on the connection
+// c, and relays the values of the channel through a bounded buffer.
+func (r *Registry) subscribe(c *conn, method string, ft reflect.Type, args []reflect.Value, call apistruct.ProxyCall) []reflect.Value {
+	limits := r.getLimits()
+
+	s := &subscription{
+		id:      r.newID(),
+		method:  method,
+		started: time.Now(),
+	}
+	c.lk.Lock()
+	if limits.MaxSubscriptions > 0 && len(c.subs) >= limits.MaxSubscriptions {
+		c.lk.Unlock()
+		return apistruct.ErrorResults(ft, ErrTooManySubscriptions)
+	}
+	c.subs[s.id] = s
+	c.lk.Unlock()
+
+	ctx, cancel := context.WithCancel(args[0].Interface().(context.Context))
+	args[0] = reflect.ValueOf(ctx)
+
+	res := call(args)
+	if !res[1].IsNil() || res[0].IsNil() {
+		cancel()
+		c.remove(s.id)
+		return res
+	}
+
+	in := res[0]
+	out := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, ft.Out(0).Elem()), 0)
+	go func() {
+		defer c.remove(s.id)
+		defer cancel()
+		relay(ctx, in, out, s, limits, c)
+	}()
+
+	res[0] = reflect.New(ft.Out(0)).Elem()
+	res[0].Set(out)
+	return res
+}
+
+func (c *conn) remove(id uint64) {
+	c.lk.Lock()
+	delete(c.subs, id)
+	c.lk.Unlock()
+}
+
+// relay passes the values of in to out, buffering at most limits.Buffer of
+// them, until in is closed or ctx is done. It closes out when it returns.
+func relay(ctx context.Context, in, out reflect.Value, s *subscription, limits Limits, c *conn) {
+	defer out.Close()
+
+	var queue []reflect.Value
+	setBuffered := func() {
+		s.lk.Lock()
+		s.buffered = len(queue)
+		s.lk.Unlock()
+	}
+
+	recv := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: in}
+	done := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+	for {
+		cases := []reflect.SelectCase{done, recv}
+		if len(queue) > 0 {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: out, Send: queue[0]})
+		}
+
+		chosen, v, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			go drain(in)
+			return
+		case 1:
+			if !ok {
+				// the values left are still sent
+				for _, v := range queue {
+					send := reflect.SelectCase{Dir: reflect.SelectSend, Chan: out, Send: v}
+					if chosen, _, _ := reflect.Select([]reflect.SelectCase{done, send}); chosen == 0 {
+						return
+					}
+				}
+				return
+			}
+
+			if limits.Buffer > 0 && len(queue) >= limits.Buffer {
+				if limits.Overflow == Disconnect {
+					reqid.Logger(ctx, log).Warnw("closing API connection on subscription overflow", "connection", c.id, "remote", c.remote, "method", s.method, "buffered", len(queue))
+					c.close()
+					go drain(in)
+					return
+				}
+				queue[0] = reflect.Value{}
+				queue = queue[1:]
+				s.lk.Lock()
+				s.dropped++
+				s.lk.Unlock()
+			}
+			queue = append(queue, v)
+		case 2:
+			queue[0] = reflect.Value{}
+			queue = queue[1:]
+		}
+		setBuffered()
+	}
+}
+
+// drain reads in until it is closed, for the producer not to block once the
+// subscription is over.
+func drain(in reflect.Value) {
+	for {
+		if _, ok := in.Recv(); !ok {
+			return
+		}
+	}
+}
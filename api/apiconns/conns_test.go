@@ -0,0 +1,87 @@
+package apiconns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testAPI struct {
+	ch chan int
+}
+
+func (a *testAPI) Sub(ctx context.Context) (<-chan int, error) {
+	return a.ch, nil
+}
+
+type testStruct struct {
+	Internal struct {
+		Sub func(ctx context.Context) (<-chan int, error)
+	}
+}
+
+func newTest(t *testing.T, limits Limits) (*Registry, *testAPI, *testStruct, context.Context) {
+	r := NewRegistry()
+	r.SetLimits(limits)
+	a := &testAPI{ch: make(chan int)}
+	var s testStruct
+	proxy(r, a, &s.Internal)
+
+	c := &conn{id: r.newID(), subs: map[uint64]*subscription{}}
+	r.conns[c.id] = c
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return r, a, &s, context.WithValue(ctx, connKey{}, c)
+}
+
+func TestDropOldest(t *testing.T) {
+	r, a, s, ctx := newTest(t, Limits{Buffer: 2, Overflow: DropOldest})
+
+	ch, err := s.Internal.Sub(ctx)
+	require.NoError(t, err)
+
+	// the consumer doesn't read while the values are sent
+	for i := 0; i < 5; i++ {
+		a.ch <- i
+	}
+	require.Eventually(t, func() bool {
+		subs := r.Connections()[0].Subscriptions
+		return len(subs) == 1 && subs[0].Dropped == 3
+	}, time.Second, 10*time.Millisecond)
+
+	close(a.ch)
+	var got []int
+	for v := range ch {
+		got = append(got, v)
+	}
+	require.Equal(t, []int{3, 4}, got)
+
+	require.Eventually(t, func() bool {
+		return len(r.Connections()[0].Subscriptions) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDisconnect(t *testing.T) {
+	_, a, s, ctx := newTest(t, Limits{Buffer: 1, Overflow: Disconnect})
+
+	ch, err := s.Internal.Sub(ctx)
+	require.NoError(t, err)
+
+	a.ch <- 1
+	a.ch <- 2
+	// the subscription is closed, and the producer isn't blocked
+	a.ch <- 3
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+func TestMaxSubscriptions(t *testing.T) {
+	_, _, s, ctx := newTest(t, Limits{MaxSubscriptions: 1, Buffer: 1, Overflow: DropOldest})
+
+	_, err := s.Internal.Sub(ctx)
+	require.NoError(t, err)
+	_, err = s.Internal.Sub(ctx)
+	require.Equal(t, ErrTooManySubscriptions, err)
+}
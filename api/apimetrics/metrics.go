@@ -0,0 +1,155 @@
+// Package apimetrics records the metrics of the API calls by method, and logs
+// the slow calls, so that operators can tell which methods, and which tokens,
+// load the node.
+package apimetrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	logging "github.com/ipfs/go-log/v2"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/api/apiusage"
+	"github.com/filecoin-project/lotus/lib/reqid"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+var log = logging.Logger("apimetrics")
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// MetricedFullAPI returns a FullNode API recording the count, errors and
+// duration of the calls made through it, and logging the calls taking longer
+// than slow, when it isn't zero.
+func MetricedFullAPI(a api.FullNode, slow time.Duration) api.FullNode {
+	var out apistruct.FullNodeStruct
+	proxy(a, &out.Internal, slow)
+	proxy(a, &out.CommonStruct.Internal, slow)
+	return &out
+}
+
+// MetricedStorageMinerAPI is like MetricedFullAPI for the StorageMiner API.
+func MetricedStorageMinerAPI(a api.StorageMiner, slow time.Duration) api.StorageMiner {
+	var out apistruct.StorageMinerStruct
+	proxy(a, &out.Internal, slow)
+	proxy(a, &out.CommonStruct.Internal, slow)
+	return &out
+}
+
+func proxy(in interface{}, out interface{}, slow time.Duration) {
+	apistruct.Proxy(in, out, func(m apistruct.ProxyMethod, next apistruct.ProxyCall) apistruct.ProxyCall {
+		returnsErr := m.Type.NumOut() > 0 && m.Type.Out(m.Type.NumOut()-1) == errorType
+
+		return func(args []reflect.Value) []reflect.Value {
+			ctx := args[0].Interface().(context.Context)
+			start := time.Now()
+
+			out := next(args)
+
+			var err error
+			if returnsErr {
+				err, _ = out[len(out)-1].Interface().(error)
+			}
+			record(ctx, m.Name, start, err, slow)
+			return out
+		}
+	})
+}
+
+func record(ctx context.Context, method string, start time.Time, err error, slow time.Duration) {
+	d := time.Since(start)
+
+	mctx, _ := tag.New(ctx, tag.Upsert(metrics.APIMethod, method))
+	stats.Record(mctx, metrics.APIRequests.M(1), metrics.APIRequestDuration.M(metrics.SinceInMilliseconds(start)))
+	if err != nil {
+		stats.Record(mctx, metrics.APIRequestErrors.M(1))
+	}
+
+	if slow > 0 && d > slow {
+		token, _ := apiusage.TokenIDFromContext(ctx)
+		reqid.Logger(ctx, log).Warnw("slow API call", "method", method, "took", d, "token", token, "error", err)
+	}
+}
+
+// Handler records the size of the requests and responses to next by method,
+// for the calls made over HTTP; the websocket connections aren't accounted.
+// The calls of methods which aren't methods of a, an API struct like
+// &apistruct.FullNodeStruct{}, are recorded as "unknown", and the batches of
+// calls as "batch".
+func Handler(a interface{}, next http.Handler) http.Handler {
+	known := map[string]bool{}
+	for _, m := range apistruct.Methods(a) {
+		known[m.Name] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		cw := &countingWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+
+		ctx, _ := tag.New(r.Context(), tag.Upsert(metrics.APIMethod, requestMethod(body, known)))
+		stats.Record(ctx, metrics.APIRequestBytes.M(int64(len(body))), metrics.APIResponseBytes.M(cw.n))
+	})
+}
+
+func requestMethod(body []byte, known map[string]bool) string {
+	if b := bytes.TrimLeft(body, " \t\r\n"); len(b) > 0 && b[0] == '[' {
+		return "batch"
+	}
+
+	var req struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "unknown"
+	}
+
+	// the methods are served in the Filecoin namespace
+	method := req.Method
+	if i := strings.LastIndexByte(method, '.'); i >= 0 {
+		method = method[i+1:]
+	}
+	if !known[method] {
+		return "unknown"
+	}
+	return method
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,47 @@
+package apimetrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+func TestRequestMethod(t *testing.T) {
+	known := map[string]bool{"ChainHead": true}
+
+	require.Equal(t, "ChainHead", requestMethod([]byte(`{"jsonrpc": "2.0", "method": "Filecoin.ChainHead", "params": [], "id": 1}`), known))
+	require.Equal(t, "unknown", requestMethod([]byte(`{"jsonrpc": "2.0", "method": "Filecoin.Random", "params": [], "id": 1}`), known))
+	require.Equal(t, "unknown", requestMethod([]byte(`not json`), known))
+	require.Equal(t, "batch", requestMethod([]byte(` [{"method": "Filecoin.ChainHead"}]`), known))
+}
+
+func TestMetricedAPI(t *testing.T) {
+	require.NoError(t, view.Register(metrics.APIRequestsView, metrics.APIRequestErrorsView))
+	defer view.Unregister(metrics.APIRequestsView, metrics.APIRequestErrorsView)
+
+	var in apistruct.FullNodeStruct
+	in.Internal.ChainHead = func(ctx context.Context) (*types.TipSet, error) {
+		return nil, xerrors.New("no head")
+	}
+
+	a := MetricedFullAPI(&in, 0)
+	for i := 0; i < 2; i++ {
+		_, err := a.ChainHead(context.Background())
+		require.Error(t, err)
+	}
+
+	for _, v := range []*view.View{metrics.APIRequestsView, metrics.APIRequestErrorsView} {
+		rows, err := view.RetrieveData(v.Name)
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.Equal(t, "ChainHead", rows[0].Tags[0].Value)
+		require.Equal(t, int64(2), rows[0].Data.(*view.CountData).Value)
+	}
+}
@@ -0,0 +1,155 @@
+// Package apirecord records the calls made to an API for replaying them
+// against another node later, e.g. to find regressions between versions.
+package apirecord
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+var log = logging.Logger("apirecord")
+
+// Entry is a recorded API call.
+type Entry struct {
+	// Seq is the order in which the calls were made. Entries are written when
+	// the call returns, so they can be out of order in the file.
+	Seq      uint64
+	Time     time.Time
+	Duration time.Duration
+
+	Method string
+	Perm   string
+
+	Params []json.RawMessage `json:",omitempty"`
+	Result json.RawMessage   `json:",omitempty"`
+	Error  string            `json:",omitempty"`
+
+	// Redacted is set for admin methods, whose params and results can hold
+	// keys and tokens and aren't recorded
+	Redacted bool `json:",omitempty"`
+	// Stream is set for methods returning a channel, whose results aren't
+	// recorded
+	Stream bool `json:",omitempty"`
+}
+
+// Recorder writes the calls made through the APIs returned by RecordFullAPI
+// to a file, one JSON entry per line.
+type Recorder struct {
+	seq uint64
+
+	lk  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// NewRecorder creates a recorder appending to the file at path.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, xerrors.Errorf("opening record file: %w", err)
+	}
+
+	return &Recorder{
+		f:   f,
+		enc: json.NewEncoder(f),
+	}, nil
+}
+
+func (r *Recorder) Close() error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return r.f.Close()
+}
+
+func (r *Recorder) write(ctx context.Context, e *Entry) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		reqid.Logger(ctx, log).Errorw("recording api call", "method", e.Method, "error", err)
+	}
+}
+
+// RecordFullAPI returns a FullNode API recording the calls made through it
+// to a.
+func RecordFullAPI(a api.FullNode, r *Recorder) api.FullNode {
+	var out apistruct.FullNodeStruct
+	proxy(r, a, &out.Internal)
+	proxy(r, a, &out.CommonStruct.Internal)
+	return &out
+}
+
+func proxy(r *Recorder, in interface{}, out interface{}) {
+	apistruct.Proxy(in, out, func(m apistruct.ProxyMethod, next apistruct.ProxyCall) apistruct.ProxyCall {
+		return func(args []reflect.Value) []reflect.Value {
+			e := &Entry{
+				Seq:      atomic.AddUint64(&r.seq, 1),
+				Time:     time.Now(),
+				Method:   m.Name,
+				Perm:     string(m.Perm),
+				Redacted: m.Perm == apistruct.PermAdmin,
+			}
+
+			res := next(args)
+			e.Duration = time.Since(e.Time)
+
+			ctx := args[0].Interface().(context.Context)
+			if !e.Redacted {
+				if err := e.setParams(args[1:]); err != nil {
+					reqid.Logger(ctx, log).Warnw("recording api call params", "method", m.Name, "error", err)
+				}
+			}
+			if err := e.setResults(res); err != nil {
+				reqid.Logger(ctx, log).Warnw("recording api call result", "method", m.Name, "error", err)
+			}
+
+			r.write(ctx, e)
+			return res
+		}
+	})
+}
+
+func (e *Entry) setParams(params []reflect.Value) error {
+	for _, p := range params {
+		b, err := json.Marshal(p.Interface())
+		if err != nil {
+			return err
+		}
+		e.Params = append(e.Params, b)
+	}
+	return nil
+}
+
+// setResults records the results of a method, which are an optional value
+// and an error.
+func (e *Entry) setResults(res []reflect.Value) error {
+	if errv := res[len(res)-1]; !errv.IsNil() {
+		e.Error = errv.Interface().(error).Error()
+	}
+	if len(res) < 2 || e.Error != "" || e.Redacted {
+		return nil
+	}
+
+	if res[0].Kind() == reflect.Chan {
+		e.Stream = true
+		return nil
+	}
+
+	b, err := json.Marshal(res[0].Interface())
+	if err != nil {
+		return err
+	}
+	e.Result = b
+	return nil
+}
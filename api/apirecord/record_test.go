@@ -0,0 +1,82 @@
+package apirecord
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func fakeNode(nv network.Version) *apistruct.FullNodeStruct {
+	var a apistruct.FullNodeStruct
+	a.Internal.StateNetworkVersion = func(ctx context.Context, tsk types.TipSetKey) (network.Version, error) {
+		return nv, nil
+	}
+	a.Internal.WalletExport = func(ctx context.Context, addr address.Address) (*types.KeyInfo, error) {
+		return &types.KeyInfo{PrivateKey: []byte("secret")}, nil
+	}
+	a.Internal.ChainNotify = func(ctx context.Context) (<-chan []*api.HeadChange, error) {
+		return make(chan []*api.HeadChange), nil
+	}
+	return &a
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "apirecord")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	path := filepath.Join(dir, "record.jsonl")
+
+	rec, err := NewRecorder(path)
+	require.NoError(t, err)
+
+	a := RecordFullAPI(fakeNode(network.Version4), rec)
+	_, err = a.StateNetworkVersion(ctx, types.EmptyTSK)
+	require.NoError(t, err)
+	addr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	_, err = a.WalletExport(ctx, addr)
+	require.NoError(t, err)
+	_, err = a.ChainNotify(ctx)
+	require.NoError(t, err)
+	require.NoError(t, rec.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+	entries, err := ReadEntries(f)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	nv, export, notify := entries[0], entries[1], entries[2]
+	require.Equal(t, "StateNetworkVersion", nv.Method)
+	require.True(t, nv.Replayable())
+	require.Equal(t, "4", string(nv.Result))
+
+	require.True(t, export.Redacted)
+	require.Empty(t, export.Params)
+	require.Empty(t, export.Result)
+	require.False(t, export.Replayable())
+
+	require.True(t, notify.Stream)
+	require.False(t, notify.Replayable())
+
+	got, err := Replay(ctx, fakeNode(network.Version4), nv)
+	require.NoError(t, err)
+	require.True(t, Equal(&nv, got))
+
+	got, err = Replay(ctx, fakeNode(network.Version5), nv)
+	require.NoError(t, err)
+	require.False(t, Equal(&nv, got))
+}
@@ -0,0 +1,99 @@
+package apirecord
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+// ReadEntries reads the entries written by a recorder, ordered by the time
+// the calls were made.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var out []Entry
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var e Entry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("reading entry %d: %w", len(out), err)
+		}
+		out = append(out, e)
+	}
+
+	// the recorder is appended to across daemon runs, which restart the
+	// sequence numbers
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
+
+// Replayable returns whether the call can be replayed: only calls of read
+// methods whose params and results were recorded are, as the others would
+// change the state of the node replayed against.
+func (e *Entry) Replayable() bool {
+	return e.Perm == string(apistruct.PermRead) && !e.Redacted && !e.Stream
+}
+
+// Replay makes the recorded call to the API a, which is usually an RPC
+// client of another node, and returns the entry of the new call.
+func Replay(ctx context.Context, a interface{}, e Entry) (*Entry, error) {
+	fn := reflect.ValueOf(a).MethodByName(e.Method)
+	if !fn.IsValid() {
+		return nil, xerrors.Errorf("method %s not found", e.Method)
+	}
+
+	ft := fn.Type()
+	if ft.NumIn() != len(e.Params)+1 {
+		return nil, xerrors.Errorf("method %s takes %d params, %d were recorded", e.Method, ft.NumIn()-1, len(e.Params))
+	}
+
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	for i, p := range e.Params {
+		v := reflect.New(ft.In(i + 1))
+		if err := json.Unmarshal(p, v.Interface()); err != nil {
+			return nil, xerrors.Errorf("decoding param %d of %s: %w", i, e.Method, err)
+		}
+		args = append(args, v.Elem())
+	}
+
+	out := &Entry{
+		Seq:    e.Seq,
+		Time:   time.Now(),
+		Method: e.Method,
+		Perm:   e.Perm,
+		Params: e.Params,
+	}
+	res := call(fn, args)
+	out.Duration = time.Since(out.Time)
+	if err := out.setResults(res); err != nil {
+		reqid.Logger(ctx, log).Warnw("recording api call result", "method", out.Method, "error", err)
+	}
+	return out, nil
+}
+
+// Equal returns whether two calls returned the same result or error.
+func Equal(a, b *Entry) bool {
+	return a.Error == b.Error && bytes.Equal(a.Result, b.Result)
+}
+
+func call(fn reflect.Value, args []reflect.Value) []reflect.Value {
+	if fn.Type().IsVariadic() {
+		return fn.CallSlice(args)
+	}
+	return fn.Call(args)
+}
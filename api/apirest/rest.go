@@ -0,0 +1,358 @@
+// Package apirest serves the common read-only chain queries of the full node
+// API as plain HTTP GET requests returning JSON, for the integrations which
+// can't speak JSON-RPC or websockets.
+package apirest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+var log = logging.Logger("apirest")
+
+// Prefix is the path the REST API is served under.
+const Prefix = "/v1/"
+
+const (
+	// DefaultPageSize and MaxPageSize are the default and maximum limit of
+	// the paginated results
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+
+	immutableCache = "public, max-age=31536000, immutable"
+)
+
+// MessageEntry is an entry of the messages of a block.
+type MessageEntry struct {
+	Cid     cid.Cid
+	Message *types.Message
+}
+
+type handler struct {
+	api api.FullNode
+}
+
+// NewHandler serves the queries on the API, which should be a permissioned
+// one:
+//
+//	GET /v1/tipset/head
+//	GET /v1/tipset/{height}
+//	GET /v1/block/{cid}
+//	GET /v1/block/{cid}/messages?offset=0&limit=100
+//	GET /v1/actor/{address}[?height=]
+//	GET /v1/message/{cid}
+//	GET /v1/message/{cid}/receipt
+//
+// The objects identified by their CID, and the tipsets past finality, are
+// served with an ETag to be cached by the clients. The paginated results set
+// the X-Total-Count and Link headers.
+func NewHandler(a api.FullNode) http.Handler {
+	h := &handler{api: a}
+
+	r := mux.NewRouter()
+	sr := r.PathPrefix(strings.TrimSuffix(Prefix, "/")).Methods(http.MethodGet).Subrouter()
+	sr.HandleFunc("/tipset/head", h.head)
+	sr.HandleFunc("/tipset/{height:[0-9]+}", h.tipset)
+	sr.HandleFunc("/block/{cid}", h.block)
+	sr.HandleFunc("/block/{cid}/messages", h.blockMessages)
+	sr.HandleFunc("/actor/{address}", h.actor)
+	sr.HandleFunc("/message/{cid}", h.message)
+	sr.HandleFunc("/message/{cid}/receipt", h.receipt)
+	return r
+}
+
+// httpError is an error answered with its status code.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: xerrors.Errorf(format, args...)}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var herr *httpError
+	switch {
+	case xerrors.As(err, &herr):
+		status = herr.status
+	case xerrors.Is(err, types.ErrActorNotFound), xerrors.Is(err, blockstore.ErrNotFound):
+		status = http.StatusNotFound
+	}
+	if status == http.StatusInternalServerError {
+		reqid.Logger(r.Context(), log).Warnf("rest request failed: %s", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct{ Error string }{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		reqid.Logger(r.Context(), log).Warnf("writing rest response: %s", err)
+	}
+}
+
+// notModified sets the ETag of an immutable response, and returns whether the
+// client already has it.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	etag = `"` + etag + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", immutableCache)
+
+	for _, m := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if m = strings.TrimSpace(m); m == etag || m == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func cidVar(r *http.Request) (cid.Cid, error) {
+	c, err := cid.Decode(mux.Vars(r)["cid"])
+	if err != nil {
+		return cid.Undef, badRequest("invalid cid: %w", err)
+	}
+	return c, nil
+}
+
+func (h *handler) head(w http.ResponseWriter, r *http.Request) {
+	ts, err := h.api.ChainHead(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, r, ts)
+}
+
+// final returns whether the tipset can't be reverted anymore.
+func (h *handler) final(r *http.Request, ts *types.TipSet) (bool, error) {
+	head, err := h.api.ChainHead(r.Context())
+	if err != nil {
+		return false, err
+	}
+	return ts.Height() <= head.Height()-build.Finality, nil
+}
+
+func (h *handler) tipset(w http.ResponseWriter, r *http.Request) {
+	height, err := strconv.ParseInt(mux.Vars(r)["height"], 10, 64)
+	if err != nil {
+		writeError(w, r, badRequest("invalid height: %w", err))
+		return
+	}
+
+	ts, err := h.api.ChainGetTipSetByHeight(r.Context(), abi.ChainEpoch(height), types.EmptyTSK)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	final, err := h.final(r, ts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if final && notModified(w, r, ts.Key().String()) {
+		return
+	}
+	writeJSON(w, r, ts)
+}
+
+func (h *handler) block(w http.ResponseWriter, r *http.Request) {
+	c, err := cidVar(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	blk, err := h.api.ChainGetBlock(r.Context(), c)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if notModified(w, r, c.String()) {
+		return
+	}
+	writeJSON(w, r, blk)
+}
+
+// page returns the offset and limit of a paginated request.
+func page(r *http.Request) (int, int, error) {
+	offset, limit := 0, DefaultPageSize
+	q := r.URL.Query()
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, 0, badRequest("invalid offset '%s'", s)
+		}
+		offset = n
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > MaxPageSize {
+			return 0, 0, badRequest("invalid limit '%s', it must be between 1 and %d", s, MaxPageSize)
+		}
+		limit = n
+	}
+	return offset, limit, nil
+}
+
+// setPageHeaders sets the X-Total-Count header, and the Link header to the
+// next page, if any.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, offset, limit, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if offset+limit >= total {
+		return
+	}
+
+	q := r.URL.Query()
+	q.Set("offset", strconv.Itoa(offset+limit))
+	q.Set("limit", strconv.Itoa(limit))
+	next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
+
+func (h *handler) blockMessages(w http.ResponseWriter, r *http.Request) {
+	c, err := cidVar(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	offset, limit, err := page(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	msgs, err := h.api.ChainGetBlockMessages(r.Context(), c)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// the cids are those of the bls messages, then of the secpk ones
+	entries := make([]MessageEntry, 0, len(msgs.Cids))
+	for i, mc := range msgs.Cids {
+		e := MessageEntry{Cid: mc}
+		if i < len(msgs.BlsMessages) {
+			e.Message = msgs.BlsMessages[i]
+		} else {
+			e.Message = &msgs.SecpkMessages[i-len(msgs.BlsMessages)].Message
+		}
+		entries = append(entries, e)
+	}
+
+	setPageHeaders(w, r, offset, limit, len(entries))
+	if notModified(w, r, fmt.Sprintf("%s-%d-%d", c, offset, limit)) {
+		return
+	}
+
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	end := offset + limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+	writeJSON(w, r, entries[offset:end])
+}
+
+func (h *handler) actor(w http.ResponseWriter, r *http.Request) {
+	addr, err := address.NewFromString(mux.Vars(r)["address"])
+	if err != nil {
+		writeError(w, r, badRequest("invalid address: %w", err))
+		return
+	}
+
+	tsk := types.EmptyTSK
+	if s := r.URL.Query().Get("height"); s != "" {
+		height, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			writeError(w, r, badRequest("invalid height: %w", err))
+			return
+		}
+		ts, err := h.api.ChainGetTipSetByHeight(r.Context(), abi.ChainEpoch(height), types.EmptyTSK)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		tsk = ts.Key()
+	}
+
+	act, err := h.api.StateGetActor(r.Context(), addr, tsk)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, r, act)
+}
+
+func (h *handler) message(w http.ResponseWriter, r *http.Request) {
+	c, err := cidVar(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	msg, err := h.api.ChainGetMessage(r.Context(), c)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if notModified(w, r, c.String()) {
+		return
+	}
+	writeJSON(w, r, msg)
+}
+
+func (h *handler) receipt(w http.ResponseWriter, r *http.Request) {
+	c, err := cidVar(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lookup, err := h.api.StateSearchMsg(r.Context(), c)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if lookup == nil {
+		writeError(w, r, &httpError{status: http.StatusNotFound, err: xerrors.Errorf("message %s not found on chain", c)})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, r, lookup)
+}
@@ -0,0 +1,126 @@
+package apirest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func testMessage(nonce uint64) *types.Message {
+	a, _ := address.NewIDAddress(100)
+	return &types.Message{
+		From:       a,
+		To:         a,
+		Nonce:      nonce,
+		Value:      types.NewInt(0),
+		GasFeeCap:  types.NewInt(0),
+		GasPremium: types.NewInt(0),
+	}
+}
+
+func testHandler() http.Handler {
+	var a apistruct.FullNodeStruct
+	a.Internal.ChainGetMessage = func(ctx context.Context, c cid.Cid) (*types.Message, error) {
+		if m := testMessage(1); c == m.Cid() {
+			return m, nil
+		}
+		return nil, xerrors.Errorf("loading message: %w", blockstore.ErrNotFound)
+	}
+	a.Internal.ChainGetBlock = func(ctx context.Context, c cid.Cid) (*types.BlockHeader, error) {
+		return nil, xerrors.Errorf("loading block: %w", blockstore.ErrNotFound)
+	}
+	a.Internal.ChainGetBlockMessages = func(ctx context.Context, c cid.Cid) (*api.BlockMessages, error) {
+		out := &api.BlockMessages{}
+		for i := uint64(0); i < 3; i++ {
+			m := testMessage(i)
+			out.BlsMessages = append(out.BlsMessages, m)
+			out.Cids = append(out.Cids, m.Cid())
+		}
+		return out, nil
+	}
+	a.Internal.StateGetActor = func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+		return nil, xerrors.Errorf("loading actor: %w", types.ErrActorNotFound)
+	}
+	return NewHandler(&a)
+}
+
+func get(h http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMessageETag(t *testing.T) {
+	h := testHandler()
+	c := testMessage(1).Cid()
+
+	w := get(h, "/v1/message/"+c.String(), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.Equal(t, `"`+c.String()+`"`, etag)
+
+	var msg types.Message
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	require.Equal(t, c, msg.Cid())
+
+	w = get(h, "/v1/message/"+c.String(), http.Header{"If-None-Match": {etag}})
+	require.Equal(t, http.StatusNotModified, w.Code)
+
+	w = get(h, "/v1/message/notacid", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// unknown objects aren't reported as not modified
+	unknown := testMessage(2).Cid()
+	w = get(h, "/v1/message/"+unknown.String(), http.Header{"If-None-Match": {`"` + unknown.String() + `"`}})
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Empty(t, w.Header().Get("ETag"))
+
+	w = get(h, "/v1/block/"+unknown.String(), http.Header{"If-None-Match": {`"` + unknown.String() + `"`}})
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBlockMessagesPages(t *testing.T) {
+	h := testHandler()
+	c := testMessage(0).Cid()
+
+	w := get(h, "/v1/block/"+c.String()+"/messages?limit=2", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "3", w.Header().Get("X-Total-Count"))
+	require.Equal(t, `</v1/block/`+c.String()+`/messages?limit=2&offset=2>; rel="next"`, w.Header().Get("Link"))
+
+	var entries []MessageEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(1), entries[1].Message.Nonce)
+
+	w = get(h, "/v1/block/"+c.String()+"/messages?limit=2&offset=2", nil)
+	require.Equal(t, "", w.Header().Get("Link"))
+	entries = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+
+	w = get(h, "/v1/block/"+c.String()+"/messages?limit=0", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestActorNotFound(t *testing.T) {
+	w := get(testHandler(), "/v1/actor/t0100", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
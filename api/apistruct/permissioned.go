@@ -1,6 +1,13 @@
 package apistruct
 
 import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
 	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/filecoin-project/lotus/api"
 )
@@ -17,10 +24,88 @@ const (
 var AllPermissions = []auth.Permission{PermRead, PermWrite, PermSign, PermAdmin}
 var DefaultPerms = []auth.Permission{PermRead}
 
+// PermScoped is granted to the tokens restricted to some methods: on top of
+// the permissions the methods require, they can only call the methods one of
+// their MethodScope permissions allows.
+const PermScoped auth.Permission = "scoped"
+
+const methodScopePrefix = "method:"
+
+// MethodScope is the permission of a scoped token allowing it to call a
+// method, or the methods starting with a prefix when the pattern ends with
+// '*', like 'State*'.
+func MethodScope(pattern string) auth.Permission {
+	return auth.Permission(methodScopePrefix + pattern)
+}
+
+// SplitScopes splits the method scopes out of perms, returning the other
+// permissions and the patterns of the scopes.
+func SplitScopes(perms []auth.Permission) ([]auth.Permission, []string) {
+	var out []auth.Permission
+	var patterns []string
+	for _, p := range perms {
+		switch {
+		case strings.HasPrefix(string(p), methodScopePrefix):
+			patterns = append(patterns, strings.TrimPrefix(string(p), methodScopePrefix))
+		case p != PermScoped:
+			out = append(out, p)
+		}
+	}
+	return out, patterns
+}
+
+// Permissions returns the permissions of a token allowing it the perms, and
+// restricting it to the methods matching the patterns of the method scopes
+// when there are any.
+func Permissions(allow []auth.Permission, methods []string) []auth.Permission {
+	if len(methods) == 0 {
+		return allow
+	}
+
+	perms := append([]auth.Permission{PermScoped}, allow...)
+	for _, m := range methods {
+		perms = append(perms, MethodScope(m))
+	}
+	return perms
+}
+
+// ScopeAllows returns whether the method scopes of the caller allow it to call
+// the method; callers with no scopes can call any method.
+func ScopeAllows(ctx context.Context, method string) bool {
+	if !auth.HasPerm(ctx, nil, PermScoped) {
+		return true
+	}
+	if auth.HasPerm(ctx, nil, MethodScope(method)) {
+		return true
+	}
+	for i := 0; i <= len(method); i++ {
+		if auth.HasPerm(ctx, nil, MethodScope(method[:i]+"*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedProxy wraps the methods of the Internal struct of an API struct with
+// a check of the method scopes of the caller.
+func scopedProxy(internal interface{}) {
+	Wrap(internal, func(m ProxyMethod, next ProxyCall) ProxyCall {
+		return func(args []reflect.Value) []reflect.Value {
+			ctx := args[0].Interface().(context.Context)
+			if ScopeAllows(ctx, m.Name) {
+				return next(args)
+			}
+			return ErrorResults(m.Type, xerrors.Errorf("missing scope to call %s", m.Name))
+		}
+	})
+}
+
 func PermissionedStorMinerAPI(a api.StorageMiner) api.StorageMiner {
 	var out StorageMinerStruct
 	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.Internal)
 	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.CommonStruct.Internal)
+	scopedProxy(&out.Internal)
+	scopedProxy(&out.CommonStruct.Internal)
 	return &out
 }
 
@@ -28,17 +113,50 @@ func PermissionedFullAPI(a api.FullNode) api.FullNode {
 	var out FullNodeStruct
 	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.Internal)
 	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.CommonStruct.Internal)
+	scopedProxy(&out.Internal)
+	scopedProxy(&out.CommonStruct.Internal)
 	return &out
 }
 
 func PermissionedWorkerAPI(a api.WorkerAPI) api.WorkerAPI {
 	var out WorkerStruct
 	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.Internal)
+	scopedProxy(&out.Internal)
 	return &out
 }
 
 func PermissionedWalletAPI(a api.WalletAPI) api.WalletAPI {
 	var out WalletStruct
 	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.Internal)
+	scopedProxy(&out.Internal)
 	return &out
 }
+
+// Methods returns the methods of an API struct of this package, like
+// &FullNodeStruct{}, with the permissions they require.
+func Methods(s interface{}) []api.APIMethod {
+	var out []api.APIMethod
+	collectMethods(reflect.TypeOf(s).Elem(), &out)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func collectMethods(t reflect.Type, out *[]api.APIMethod) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Name == "Internal":
+			for j := 0; j < f.Type.NumField(); j++ {
+				m := f.Type.Field(j)
+				*out = append(*out, api.APIMethod{
+					Name: m.Name,
+					Perm: auth.Permission(m.Tag.Get("perm")),
+				})
+			}
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			collectMethods(f.Type, out)
+		}
+	}
+}
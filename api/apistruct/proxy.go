@@ -0,0 +1,74 @@
+package apistruct
+
+import (
+	"reflect"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+)
+
+// ProxyMethod is a method of an API struct wrapped by Proxy or Wrap.
+type ProxyMethod struct {
+	Name string
+	Perm auth.Permission
+	Type reflect.Type
+}
+
+// ProxyCall makes the call of an API method, with the context of the call as
+// its first argument.
+type ProxyCall func(args []reflect.Value) []reflect.Value
+
+// ProxyWrapper returns the call of the method m, making the call with next.
+// It's called once per method, so it can return next as is for the methods it
+// doesn't wrap.
+type ProxyWrapper func(m ProxyMethod, next ProxyCall) ProxyCall
+
+// Proxy sets the methods of out, the Internal struct of an API struct, to call
+// the methods of in through wrap.
+func Proxy(in interface{}, out interface{}, wrap ProxyWrapper) {
+	rint := reflect.ValueOf(out).Elem()
+	ra := reflect.ValueOf(in)
+
+	for f := 0; f < rint.NumField(); f++ {
+		setWrapped(rint, f, ra.MethodByName(rint.Type().Field(f).Name), wrap)
+	}
+}
+
+// Wrap wraps the methods already set in internal, the Internal struct of an
+// API struct, with wrap.
+func Wrap(internal interface{}, wrap ProxyWrapper) {
+	rint := reflect.ValueOf(internal).Elem()
+
+	for f := 0; f < rint.NumField(); f++ {
+		if rint.Field(f).IsNil() {
+			continue
+		}
+		setWrapped(rint, f, reflect.ValueOf(rint.Field(f).Interface()), wrap)
+	}
+}
+
+func setWrapped(rint reflect.Value, f int, fn reflect.Value, wrap ProxyWrapper) {
+	field := rint.Type().Field(f)
+	m := ProxyMethod{
+		Name: field.Name,
+		Perm: auth.Permission(field.Tag.Get("perm")),
+		Type: field.Type,
+	}
+
+	call := wrap(m, func(args []reflect.Value) []reflect.Value {
+		if fn.Type().IsVariadic() {
+			return fn.CallSlice(args)
+		}
+		return fn.Call(args)
+	})
+	rint.Field(f).Set(reflect.MakeFunc(field.Type, call))
+}
+
+// ErrorResults returns the results of a method of type ft failing with err.
+func ErrorResults(ft reflect.Type, err error) []reflect.Value {
+	out := make([]reflect.Value, ft.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(ft.Out(i))
+	}
+	out[len(out)-1] = reflect.ValueOf(&err).Elem()
+	return out
+}
@@ -2,6 +2,7 @@ package apistruct
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"time"
 
@@ -45,8 +46,10 @@ var _ = AllPermissions
 
 type CommonStruct struct {
 	Internal struct {
-		AuthVerify func(ctx context.Context, token string) ([]auth.Permission, error) `perm:"read"`
-		AuthNew    func(ctx context.Context, perms []auth.Permission) ([]byte, error) `perm:"admin"`
+		AuthVerify      func(ctx context.Context, token string) ([]auth.Permission, error) `perm:"read"`
+		AuthNew         func(ctx context.Context, perms []auth.Permission) ([]byte, error) `perm:"admin"`
+		AuthUsage       func(ctx context.Context) ([]api.TokenUsage, error)                `perm:"admin"`
+		AuthConnections func(ctx context.Context) ([]api.APIConnection, error)             `perm:"admin"`
 
 		NetConnectedness            func(context.Context, peer.ID) (network.Connectedness, error)    `perm:"read"`
 		NetPeers                    func(context.Context) ([]peer.AddrInfo, error)                   `perm:"read"`
@@ -61,8 +64,9 @@ type CommonStruct struct {
 		NetBandwidthStatsByProtocol func(ctx context.Context) (map[protocol.ID]metrics.Stats, error) `perm:"read"`
 		NetAgentVersion             func(ctx context.Context, p peer.ID) (string, error)             `perm:"read"`
 
-		ID      func(context.Context) (peer.ID, error)     `perm:"read"`
-		Version func(context.Context) (api.Version, error) `perm:"read"`
+		ID       func(context.Context) (peer.ID, error)             `perm:"read"`
+		Version  func(context.Context) (api.Version, error)         `perm:"read"`
+		Discover func(context.Context) (api.APICapabilities, error) `perm:"read"`
 
 		LogList     func(context.Context) ([]string, error)     `perm:"write"`
 		LogSetLevel func(context.Context, string, string) error `perm:"write"`
@@ -77,69 +81,105 @@ type FullNodeStruct struct {
 	CommonStruct
 
 	Internal struct {
-		ChainNotify                   func(context.Context) (<-chan []*api.HeadChange, error)                                                            `perm:"read"`
-		ChainHead                     func(context.Context) (*types.TipSet, error)                                                                       `perm:"read"`
-		ChainGetRandomnessFromTickets func(context.Context, types.TipSetKey, crypto.DomainSeparationTag, abi.ChainEpoch, []byte) (abi.Randomness, error) `perm:"read"`
-		ChainGetRandomnessFromBeacon  func(context.Context, types.TipSetKey, crypto.DomainSeparationTag, abi.ChainEpoch, []byte) (abi.Randomness, error) `perm:"read"`
-		ChainGetBlock                 func(context.Context, cid.Cid) (*types.BlockHeader, error)                                                         `perm:"read"`
-		ChainGetTipSet                func(context.Context, types.TipSetKey) (*types.TipSet, error)                                                      `perm:"read"`
-		ChainGetBlockMessages         func(context.Context, cid.Cid) (*api.BlockMessages, error)                                                         `perm:"read"`
-		ChainGetParentReceipts        func(context.Context, cid.Cid) ([]*types.MessageReceipt, error)                                                    `perm:"read"`
-		ChainGetParentMessages        func(context.Context, cid.Cid) ([]api.Message, error)                                                              `perm:"read"`
-		ChainGetTipSetByHeight        func(context.Context, abi.ChainEpoch, types.TipSetKey) (*types.TipSet, error)                                      `perm:"read"`
-		ChainReadObj                  func(context.Context, cid.Cid) ([]byte, error)                                                                     `perm:"read"`
-		ChainDeleteObj                func(context.Context, cid.Cid) error                                                                               `perm:"admin"`
-		ChainHasObj                   func(context.Context, cid.Cid) (bool, error)                                                                       `perm:"read"`
-		ChainStatObj                  func(context.Context, cid.Cid, cid.Cid) (api.ObjStat, error)                                                       `perm:"read"`
-		ChainSetHead                  func(context.Context, types.TipSetKey) error                                                                       `perm:"admin"`
-		ChainGetGenesis               func(context.Context) (*types.TipSet, error)                                                                       `perm:"read"`
-		ChainTipSetWeight             func(context.Context, types.TipSetKey) (types.BigInt, error)                                                       `perm:"read"`
-		ChainGetNode                  func(ctx context.Context, p string) (*api.IpldObject, error)                                                       `perm:"read"`
-		ChainGetMessage               func(context.Context, cid.Cid) (*types.Message, error)                                                             `perm:"read"`
-		ChainGetPath                  func(context.Context, types.TipSetKey, types.TipSetKey) ([]*api.HeadChange, error)                                 `perm:"read"`
-		ChainExport                   func(context.Context, abi.ChainEpoch, bool, types.TipSetKey) (<-chan []byte, error)                                `perm:"read"`
+		ChainNotify                   func(context.Context) (<-chan []*api.HeadChange, error)                                                                 `perm:"read"`
+		ChainNotifyFrom               func(context.Context, uint64) (<-chan *api.HeadChanges, error)                                                          `perm:"read"`
+		ChainNotifySince              func(context.Context, types.TipSetKey) (<-chan *api.HeadChanges, error)                                                 `perm:"read"`
+		ChainHead                     func(context.Context) (*types.TipSet, error)                                                                            `perm:"read"`
+		ChainGetRandomnessFromTickets func(context.Context, types.TipSetKey, crypto.DomainSeparationTag, abi.ChainEpoch, []byte) (abi.Randomness, error)      `perm:"read"`
+		ChainGetRandomnessFromBeacon  func(context.Context, types.TipSetKey, crypto.DomainSeparationTag, abi.ChainEpoch, []byte) (abi.Randomness, error)      `perm:"read"`
+		ChainGetBlock                 func(context.Context, cid.Cid) (*types.BlockHeader, error)                                                              `perm:"read"`
+		ChainGetTipSet                func(context.Context, types.TipSetKey) (*types.TipSet, error)                                                           `perm:"read"`
+		ChainGetBlockMessages         func(context.Context, cid.Cid) (*api.BlockMessages, error)                                                              `perm:"read"`
+		ChainGetParentReceipts        func(context.Context, cid.Cid) ([]*types.MessageReceipt, error)                                                         `perm:"read"`
+		ChainGetParentMessages        func(context.Context, cid.Cid) ([]api.Message, error)                                                                   `perm:"read"`
+		ChainGetTipSetByHeight        func(context.Context, abi.ChainEpoch, types.TipSetKey) (*types.TipSet, error)                                           `perm:"read"`
+		ChainReadObj                  func(context.Context, cid.Cid) ([]byte, error)                                                                          `perm:"read"`
+		ChainDeleteObj                func(context.Context, cid.Cid) error                                                                                    `perm:"admin"`
+		ChainHasObj                   func(context.Context, cid.Cid) (bool, error)                                                                            `perm:"read"`
+		ChainStatObj                  func(context.Context, cid.Cid, cid.Cid) (api.ObjStat, error)                                                            `perm:"read"`
+		ChainSetHead                  func(context.Context, types.TipSetKey) error                                                                            `perm:"admin"`
+		ChainGetGenesis               func(context.Context) (*types.TipSet, error)                                                                            `perm:"read"`
+		ChainTipSetWeight             func(context.Context, types.TipSetKey) (types.BigInt, error)                                                            `perm:"read"`
+		ChainGetNode                  func(ctx context.Context, p string) (*api.IpldObject, error)                                                            `perm:"read"`
+		ChainGetMessage               func(context.Context, cid.Cid) (*types.Message, error)                                                                  `perm:"read"`
+		ChainGetPath                  func(context.Context, types.TipSetKey, types.TipSetKey) ([]*api.HeadChange, error)                                      `perm:"read"`
+		ChainExport                   func(context.Context, abi.ChainEpoch, bool, types.TipSetKey) (<-chan []byte, error)                                     `perm:"read"`
+		ChainExportIncremental        func(context.Context, abi.ChainEpoch, bool, types.TipSetKey, types.TipSetKey) (<-chan []byte, error)                    `perm:"read"`
+		ChainExportRange              func(context.Context, types.TipSetKey, abi.ChainEpoch, abi.ChainEpoch, api.ChainExportRangeOpts) (<-chan []byte, error) `perm:"read"`
+		ChainPrune                    func(context.Context, abi.ChainEpoch, bool) (*api.ChainPruneResult, error)                                              `perm:"admin"`
+		ChainStat                     func(context.Context) (*api.ChainStat, error)                                                                           `perm:"read"`
+		ChainCheckBlockstore          func(context.Context, bool) (*api.ChainCheckResult, error)                                                              `perm:"admin"`
+		ChainGetReorgs                func(context.Context, abi.ChainEpoch) ([]*api.ChainReorg, error)                                                        `perm:"read"`
+		ChainReorgNotify              func(context.Context) (<-chan *api.ChainReorg, error)                                                                   `perm:"read"`
+		ChainBlockstoreGC             func(context.Context, api.BlockstoreGCOpts) (<-chan api.BlockstoreGCProgress, error)                                    `perm:"admin"`
+		ChainIndexMessages            func(context.Context, abi.ChainEpoch, abi.ChainEpoch) (int, error)                                                      `perm:"admin"`
+		ChainBackfillMessageIndex     func(context.Context, api.IndexBackfillOpts) (<-chan api.IndexBackfillProgress, error)                                  `perm:"admin"`
+		ChainReindexHeights           func(context.Context, types.TipSetKey) error                                                                            `perm:"admin"`
 
 		BeaconGetEntry func(ctx context.Context, epoch abi.ChainEpoch) (*types.BeaconEntry, error) `perm:"read"`
-
-		GasEstimateGasPremium func(context.Context, uint64, address.Address, int64, types.TipSetKey) (types.BigInt, error)         `perm:"read"`
-		GasEstimateGasLimit   func(context.Context, *types.Message, types.TipSetKey) (int64, error)                                `perm:"read"`
-		GasEstimateFeeCap     func(context.Context, *types.Message, int64, types.TipSetKey) (types.BigInt, error)                  `perm:"read"`
-		GasEstimateMessageGas func(context.Context, *types.Message, *api.MessageSendSpec, types.TipSetKey) (*types.Message, error) `perm:"read"`
-
-		SyncState          func(context.Context) (*api.SyncState, error)                `perm:"read"`
-		SyncSubmitBlock    func(ctx context.Context, blk *types.BlockMsg) error         `perm:"write"`
-		SyncIncomingBlocks func(ctx context.Context) (<-chan *types.BlockHeader, error) `perm:"read"`
-		SyncCheckpoint     func(ctx context.Context, key types.TipSetKey) error         `perm:"admin"`
-		SyncMarkBad        func(ctx context.Context, bcid cid.Cid) error                `perm:"admin"`
-		SyncUnmarkBad      func(ctx context.Context, bcid cid.Cid) error                `perm:"admin"`
-		SyncUnmarkAllBad   func(ctx context.Context) error                              `perm:"admin"`
-		SyncCheckBad       func(ctx context.Context, bcid cid.Cid) (string, error)      `perm:"read"`
-		SyncValidateTipset func(ctx context.Context, tsk types.TipSetKey) (bool, error) `perm:"read"`
+		BeaconSchedule func(ctx context.Context) ([]api.BeaconInfo, error)                         `perm:"read"`
+
+		GasEstimateGasPremium  func(context.Context, uint64, address.Address, int64, types.TipSetKey) (types.BigInt, error)         `perm:"read"`
+		GasEstimateGasLimit    func(context.Context, *types.Message, types.TipSetKey) (int64, error)                                `perm:"read"`
+		GasEstimateFeeCap      func(context.Context, *types.Message, int64, types.TipSetKey) (types.BigInt, error)                  `perm:"read"`
+		GasEstimateMessageGas  func(context.Context, *types.Message, *api.MessageSendSpec, types.TipSetKey) (*types.Message, error) `perm:"read"`
+		GasEstimateFeeForecast func(context.Context, uint64, uint64, types.TipSetKey) (*api.FeeForecast, error)                     `perm:"read"`
+
+		SyncState               func(context.Context) (*api.SyncState, error)                                 `perm:"read"`
+		SyncSubmitBlock         func(ctx context.Context, blk *types.BlockMsg) error                          `perm:"write"`
+		SyncIncomingBlocks      func(ctx context.Context) (<-chan *types.BlockHeader, error)                  `perm:"read"`
+		SyncCheckpoint          func(ctx context.Context, key types.TipSetKey) error                          `perm:"admin"`
+		SyncMarkBad             func(ctx context.Context, bcid cid.Cid) error                                 `perm:"admin"`
+		SyncUnmarkBad           func(ctx context.Context, bcid cid.Cid) error                                 `perm:"admin"`
+		SyncUnmarkAllBad        func(ctx context.Context) error                                               `perm:"admin"`
+		SyncCheckBad            func(ctx context.Context, bcid cid.Cid) (string, error)                       `perm:"read"`
+		SyncListBad             func(ctx context.Context) ([]api.BadBlock, error)                             `perm:"read"`
+		SyncBlockPeer           func(ctx context.Context, p peer.ID, reason string) error                     `perm:"admin"`
+		SyncUnblockPeer         func(ctx context.Context, p peer.ID) error                                    `perm:"admin"`
+		SyncBlockedPeers        func(ctx context.Context) ([]api.BlockedPeer, error)                          `perm:"read"`
+		SyncValidateTipset      func(ctx context.Context, tsk types.TipSetKey) (bool, error)                  `perm:"read"`
+		SyncValidateBlockHeader func(context.Context, *types.BlockHeader) (*api.BlockHeaderValidation, error) `perm:"read"`
 
 		MpoolGetConfig func(context.Context) (*types.MpoolConfig, error) `perm:"read"`
 		MpoolSetConfig func(context.Context, *types.MpoolConfig) error   `perm:"write"`
 
 		MpoolSelect func(context.Context, types.TipSetKey, float64) ([]*types.SignedMessage, error) `perm:"read"`
 
-		MpoolPending func(context.Context, types.TipSetKey) ([]*types.SignedMessage, error) `perm:"read"`
-		MpoolClear   func(context.Context, bool) error                                      `perm:"write"`
+		MpoolPending       func(context.Context, types.TipSetKey) ([]*types.SignedMessage, error) `perm:"read"`
+		MpoolClear         func(context.Context, bool) error                                      `perm:"write"`
+		MpoolLocalMessages func(context.Context) ([]*types.SignedMessage, error)                  `perm:"read"`
+		MpoolAbandon       func(context.Context, cid.Cid) error                                   `perm:"write"`
 
 		MpoolPush          func(context.Context, *types.SignedMessage) (cid.Cid, error) `perm:"write"`
 		MpoolPushUntrusted func(context.Context, *types.SignedMessage) (cid.Cid, error) `perm:"write"`
 
-		MpoolPushMessage func(context.Context, *types.Message, *api.MessageSendSpec) (*types.SignedMessage, error) `perm:"sign"`
-		MpoolGetNonce    func(context.Context, address.Address) (uint64, error)                                    `perm:"read"`
-		MpoolSub         func(context.Context) (<-chan api.MpoolUpdate, error)                                     `perm:"read"`
-
-		MinerGetBaseInfo func(context.Context, address.Address, abi.ChainEpoch, types.TipSetKey) (*api.MiningBaseInfo, error) `perm:"read"`
-		MinerCreateBlock func(context.Context, *api.BlockTemplate) (*types.BlockMsg, error)                                   `perm:"write"`
+		MpoolBatchPush        func(context.Context, []*types.SignedMessage) ([]cid.Cid, error)                                            `perm:"write"`
+		MpoolBatchPushMessage func(ctx context.Context, msgs []*types.Message, spec *api.MessageSendSpec) ([]*types.SignedMessage, error) `perm:"sign"`
+		MpoolSchedule         func(context.Context, *types.Message, *api.MessageSendSpec, api.MessageSchedule) (uint64, error)            `perm:"sign"`
+		MpoolScheduleSigned   func(context.Context, *types.SignedMessage, api.MessageSchedule) (uint64, error)                            `perm:"write"`
+		MpoolScheduled        func(context.Context) ([]api.ScheduledMessage, error)                                                       `perm:"read"`
+		MpoolScheduledCancel  func(context.Context, uint64) error                                                                         `perm:"write"`
+		MpoolPushMessage      func(context.Context, *types.Message, *api.MessageSendSpec) (*types.SignedMessage, error)                   `perm:"sign"`
+		MpoolReplace          func(context.Context, address.Address, uint64, *api.MpoolReplaceSpec) (*types.SignedMessage, error)         `perm:"sign"`
+		MpoolGetNonce         func(context.Context, address.Address) (uint64, error)                                                      `perm:"read"`
+		MpoolSub              func(context.Context) (<-chan api.MpoolUpdate, error)                                                       `perm:"read"`
+		MpoolSubAddress       func(context.Context, []address.Address) (<-chan api.MpoolUpdate, error)                                    `perm:"read"`
+
+		MinerGetBaseInfo         func(context.Context, address.Address, abi.ChainEpoch, types.TipSetKey) (*api.MiningBaseInfo, error)       `perm:"read"`
+		MinerCreateBlock         func(context.Context, *api.BlockTemplate) (*types.BlockMsg, error)                                         `perm:"write"`
+		MinerWithdrawBalance     func(context.Context, address.Address, abi.TokenAmount, address.Address) (*api.MinerOwnerMessage, error)   `perm:"sign"`
+		MinerChangeWorker        func(context.Context, address.Address, address.Address, address.Address) (*api.MinerOwnerMessage, error)   `perm:"sign"`
+		MinerConfirmChangeWorker func(context.Context, address.Address, address.Address) (*api.MinerOwnerMessage, error)                    `perm:"sign"`
+		MinerSetControlAddresses func(context.Context, address.Address, []address.Address, address.Address) (*api.MinerOwnerMessage, error) `perm:"sign"`
 
 		WalletNew             func(context.Context, types.KeyType) (address.Address, error)                        `perm:"write"`
 		WalletHas             func(context.Context, address.Address) (bool, error)                                 `perm:"write"`
+		WalletBackend         func(context.Context, address.Address) (string, error)                               `perm:"write"`
 		WalletList            func(context.Context) ([]address.Address, error)                                     `perm:"write"`
 		WalletBalance         func(context.Context, address.Address) (types.BigInt, error)                         `perm:"read"`
 		WalletSign            func(context.Context, address.Address, []byte) (*crypto.Signature, error)            `perm:"sign"`
 		WalletSignMessage     func(context.Context, address.Address, *types.Message) (*types.SignedMessage, error) `perm:"sign"`
+		WalletSignAggregate   func(context.Context, []*types.Message) (*crypto.Signature, error)                   `perm:"sign"`
 		WalletVerify          func(context.Context, address.Address, []byte, *crypto.Signature) (bool, error)      `perm:"read"`
 		WalletDefaultAddress  func(context.Context) (address.Address, error)                                       `perm:"write"`
 		WalletSetDefault      func(context.Context, address.Address) error                                         `perm:"admin"`
@@ -147,27 +187,35 @@ type FullNodeStruct struct {
 		WalletImport          func(context.Context, *types.KeyInfo) (address.Address, error)                       `perm:"admin"`
 		WalletDelete          func(context.Context, address.Address) error                                         `perm:"write"`
 		WalletValidateAddress func(context.Context, string) (address.Address, error)                               `perm:"read"`
-
-		ClientImport                              func(ctx context.Context, ref api.FileRef) (*api.ImportRes, error)                                                `perm:"admin"`
-		ClientListImports                         func(ctx context.Context) ([]api.Import, error)                                                                   `perm:"write"`
-		ClientRemoveImport                        func(ctx context.Context, importID multistore.StoreID) error                                                      `perm:"admin"`
-		ClientHasLocal                            func(ctx context.Context, root cid.Cid) (bool, error)                                                             `perm:"write"`
-		ClientFindData                            func(ctx context.Context, root cid.Cid, piece *cid.Cid) ([]api.QueryOffer, error)                                 `perm:"read"`
-		ClientMinerQueryOffer                     func(ctx context.Context, miner address.Address, root cid.Cid, piece *cid.Cid) (api.QueryOffer, error)            `perm:"read"`
-		ClientStartDeal                           func(ctx context.Context, params *api.StartDealParams) (*cid.Cid, error)                                          `perm:"admin"`
-		ClientGetDealInfo                         func(context.Context, cid.Cid) (*api.DealInfo, error)                                                             `perm:"read"`
-		ClientListDeals                           func(ctx context.Context) ([]api.DealInfo, error)                                                                 `perm:"write"`
-		ClientGetDealUpdates                      func(ctx context.Context) (<-chan api.DealInfo, error)                                                            `perm:"read"`
-		ClientRetrieve                            func(ctx context.Context, order api.RetrievalOrder, ref *api.FileRef) error                                       `perm:"admin"`
-		ClientRetrieveWithEvents                  func(ctx context.Context, order api.RetrievalOrder, ref *api.FileRef) (<-chan marketevents.RetrievalEvent, error) `perm:"admin"`
-		ClientQueryAsk                            func(ctx context.Context, p peer.ID, miner address.Address) (*storagemarket.StorageAsk, error)                    `perm:"read"`
-		ClientCalcCommP                           func(ctx context.Context, inpath string) (*api.CommPRet, error)                                                   `perm:"read"`
-		ClientGenCar                              func(ctx context.Context, ref api.FileRef, outpath string) error                                                  `perm:"write"`
-		ClientDealSize                            func(ctx context.Context, root cid.Cid) (api.DataSize, error)                                                     `perm:"read"`
-		ClientListDataTransfers                   func(ctx context.Context) ([]api.DataTransferChannel, error)                                                      `perm:"write"`
-		ClientDataTransferUpdates                 func(ctx context.Context) (<-chan api.DataTransferChannel, error)                                                 `perm:"write"`
-		ClientRestartDataTransfer                 func(ctx context.Context, transferID datatransfer.TransferID, otherPeer peer.ID, isInitiator bool) error          `perm:"write"`
-		ClientRetrieveTryRestartInsufficientFunds func(ctx context.Context, paymentChannel address.Address) error                                                   `perm:"write"`
+		WalletEncrypt         func(context.Context, string) error                                                  `perm:"admin"`
+		WalletUnlock          func(context.Context, string, time.Duration) error                                   `perm:"admin"`
+		WalletLock            func(context.Context) error                                                          `perm:"write"`
+		WalletNewHD           func(context.Context, types.KeyType) (address.Address, error)                        `perm:"write"`
+		WalletDeriveHD        func(context.Context, types.KeyType, uint64) (address.Address, error)                `perm:"write"`
+		WalletImportMnemonic  func(context.Context, string) error                                                  `perm:"admin"`
+		WalletExportMnemonic  func(context.Context) (*api.HDExport, error)                                         `perm:"admin"`
+
+		ClientImport                              func(ctx context.Context, ref api.FileRef) (*api.ImportRes, error)                                                           `perm:"admin"`
+		ClientListImports                         func(ctx context.Context) ([]api.Import, error)                                                                              `perm:"write"`
+		ClientRemoveImport                        func(ctx context.Context, importID multistore.StoreID) error                                                                 `perm:"admin"`
+		ClientHasLocal                            func(ctx context.Context, root cid.Cid) (bool, error)                                                                        `perm:"write"`
+		ClientFindData                            func(ctx context.Context, root cid.Cid, piece *cid.Cid) ([]api.QueryOffer, error)                                            `perm:"read"`
+		ClientMinerQueryOffer                     func(ctx context.Context, miner address.Address, root cid.Cid, piece *cid.Cid) (api.QueryOffer, error)                       `perm:"read"`
+		ClientStartDeal                           func(ctx context.Context, params *api.StartDealParams) (*cid.Cid, error)                                                     `perm:"admin"`
+		ClientAuthorizeDelegate                   func(ctx context.Context, funder, delegate address.Address, expiration abi.ChainEpoch) (*api.SignedDealAuthorization, error) `perm:"sign"`
+		ClientGetDealInfo                         func(context.Context, cid.Cid) (*api.DealInfo, error)                                                                        `perm:"read"`
+		ClientListDeals                           func(ctx context.Context) ([]api.DealInfo, error)                                                                            `perm:"write"`
+		ClientGetDealUpdates                      func(ctx context.Context) (<-chan api.DealInfo, error)                                                                       `perm:"read"`
+		ClientRetrieve                            func(ctx context.Context, order api.RetrievalOrder, ref *api.FileRef) error                                                  `perm:"admin"`
+		ClientRetrieveWithEvents                  func(ctx context.Context, order api.RetrievalOrder, ref *api.FileRef) (<-chan marketevents.RetrievalEvent, error)            `perm:"admin"`
+		ClientQueryAsk                            func(ctx context.Context, p peer.ID, miner address.Address) (*storagemarket.StorageAsk, error)                               `perm:"read"`
+		ClientCalcCommP                           func(ctx context.Context, inpath string) (*api.CommPRet, error)                                                              `perm:"read"`
+		ClientGenCar                              func(ctx context.Context, ref api.FileRef, outpath string) error                                                             `perm:"write"`
+		ClientDealSize                            func(ctx context.Context, root cid.Cid) (api.DataSize, error)                                                                `perm:"read"`
+		ClientListDataTransfers                   func(ctx context.Context) ([]api.DataTransferChannel, error)                                                                 `perm:"write"`
+		ClientDataTransferUpdates                 func(ctx context.Context) (<-chan api.DataTransferChannel, error)                                                            `perm:"write"`
+		ClientRestartDataTransfer                 func(ctx context.Context, transferID datatransfer.TransferID, otherPeer peer.ID, isInitiator bool) error                     `perm:"write"`
+		ClientRetrieveTryRestartInsufficientFunds func(ctx context.Context, paymentChannel address.Address) error                                                              `perm:"write"`
 
 		StateNetworkName                   func(context.Context) (dtypes.NetworkName, error)                                                                   `perm:"read"`
 		StateMinerSectors                  func(context.Context, address.Address, *bitfield.BitField, types.TipSetKey) ([]*miner.SectorOnChainInfo, error)     `perm:"read"`
@@ -189,31 +237,48 @@ type FullNodeStruct struct {
 		StateSectorPartition               func(context.Context, address.Address, abi.SectorNumber, types.TipSetKey) (*miner.SectorLocation, error)            `perm:"read"`
 		StateCall                          func(context.Context, *types.Message, types.TipSetKey) (*api.InvocResult, error)                                    `perm:"read"`
 		StateReplay                        func(context.Context, types.TipSetKey, cid.Cid) (*api.InvocResult, error)                                           `perm:"read"`
+		StateReplayGasProfile              func(context.Context, types.TipSetKey, cid.Cid) (*api.GasProfile, error)                                            `perm:"read"`
+		StateReplayGasPprof                func(context.Context, types.TipSetKey, cid.Cid) ([]byte, error)                                                     `perm:"read"`
 		StateGetActor                      func(context.Context, address.Address, types.TipSetKey) (*types.Actor, error)                                       `perm:"read"`
+		StateGetProof                      func(context.Context, address.Address, string, types.TipSetKey) (*api.StateProof, error)                            `perm:"read"`
 		StateReadState                     func(context.Context, address.Address, types.TipSetKey) (*api.ActorState, error)                                    `perm:"read"`
-		StateWaitMsg                       func(ctx context.Context, cid cid.Cid, confidence uint64) (*api.MsgLookup, error)                                   `perm:"read"`
+		StateDecodeParams                  func(context.Context, address.Address, abi.MethodNum, []byte, types.TipSetKey) (interface{}, error)                 `perm:"read"`
+		StateDecodeReturn                  func(context.Context, address.Address, abi.MethodNum, []byte, types.TipSetKey) (interface{}, error)                 `perm:"read"`
+		StateDecodeActorState              func(context.Context, cid.Cid, cid.Cid) (interface{}, error)                                                        `perm:"read"`
+		StateWaitMsg                       func(context.Context, cid.Cid, uint64) (*api.MsgLookup, error)                                                      `perm:"read"`
 		StateWaitMsgLimited                func(context.Context, cid.Cid, uint64, abi.ChainEpoch) (*api.MsgLookup, error)                                      `perm:"read"`
+		StateWaitMsgBounded                func(context.Context, cid.Cid, uint64, abi.ChainEpoch, bool) (*api.MsgLookup, error)                                `perm:"read"`
+		StateWatchMsgs                     func(context.Context, api.MsgWatch) (<-chan *api.MsgWatchEvent, error)                                              `perm:"read"`
 		StateSearchMsg                     func(context.Context, cid.Cid) (*api.MsgLookup, error)                                                              `perm:"read"`
+		StateSearchMsgBounded              func(context.Context, cid.Cid, abi.ChainEpoch, bool) (*api.MsgLookup, error)                                        `perm:"read"`
+		StateMsgGasCost                    func(context.Context, cid.Cid) (*api.MsgGasCost, error)                                                             `perm:"read"`
 		StateListMiners                    func(context.Context, types.TipSetKey) ([]address.Address, error)                                                   `perm:"read"`
 		StateListActors                    func(context.Context, types.TipSetKey) ([]address.Address, error)                                                   `perm:"read"`
 		StateMarketBalance                 func(context.Context, address.Address, types.TipSetKey) (api.MarketBalance, error)                                  `perm:"read"`
 		StateMarketParticipants            func(context.Context, types.TipSetKey) (map[string]api.MarketBalance, error)                                        `perm:"read"`
 		StateMarketDeals                   func(context.Context, types.TipSetKey) (map[string]api.MarketDeal, error)                                           `perm:"read"`
+		StateMarketDealsPage               func(context.Context, abi.DealID, uint64, types.TipSetKey) (map[string]api.MarketDeal, error)                       `perm:"read"`
 		StateMarketStorageDeal             func(context.Context, abi.DealID, types.TipSetKey) (*api.MarketDeal, error)                                         `perm:"read"`
 		StateLookupID                      func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)                       `perm:"read"`
 		StateAccountKey                    func(context.Context, address.Address, types.TipSetKey) (address.Address, error)                                    `perm:"read"`
 		StateChangedActors                 func(context.Context, cid.Cid, cid.Cid) (map[string]types.Actor, error)                                             `perm:"read"`
+		StateDiff                          func(context.Context, types.TipSetKey, types.TipSetKey, address.Address) (*api.StateDiff, error)                    `perm:"read"`
+		StateWatchPaths                    func(context.Context, []api.StateWatchPath) (<-chan []*api.StateWatchChange, error)                                 `perm:"read"`
+		StateSubscribeChanges              func(context.Context, api.StateChangeFilter) (<-chan []*api.StateChangeEvent, error)                                `perm:"read"`
 		StateGetReceipt                    func(context.Context, cid.Cid, types.TipSetKey) (*types.MessageReceipt, error)                                      `perm:"read"`
 		StateMinerSectorCount              func(context.Context, address.Address, types.TipSetKey) (api.MinerSectors, error)                                   `perm:"read"`
 		StateListMessages                  func(ctx context.Context, match *api.MessageMatch, tsk types.TipSetKey, toht abi.ChainEpoch) ([]cid.Cid, error)     `perm:"read"`
+		StateListMessagesFor               func(context.Context, address.Address, types.TipSetKey, uint64, uint64) ([]*api.AddrMessage, error)                 `perm:"read"`
 		StateCompute                       func(context.Context, abi.ChainEpoch, []*types.Message, types.TipSetKey) (*api.ComputeStateOutput, error)           `perm:"read"`
 		StateVerifierStatus                func(context.Context, address.Address, types.TipSetKey) (*abi.StoragePower, error)                                  `perm:"read"`
 		StateVerifiedClientStatus          func(context.Context, address.Address, types.TipSetKey) (*abi.StoragePower, error)                                  `perm:"read"`
 		StateVerifiedRegistryRootKey       func(ctx context.Context, tsk types.TipSetKey) (address.Address, error)                                             `perm:"read"`
 		StateDealProviderCollateralBounds  func(context.Context, abi.PaddedPieceSize, bool, types.TipSetKey) (api.DealCollateralBounds, error)                 `perm:"read"`
 		StateCirculatingSupply             func(context.Context, types.TipSetKey) (abi.TokenAmount, error)                                                     `perm:"read"`
+		StateVMCirculatingSupply           func(context.Context, types.TipSetKey) (api.CirculatingSupply, error)                                               `perm:"read"`
 		StateVMCirculatingSupplyInternal   func(context.Context, types.TipSetKey) (api.CirculatingSupply, error)                                               `perm:"read"`
 		StateNetworkVersion                func(context.Context, types.TipSetKey) (stnetwork.Version, error)                                                   `perm:"read"`
+		StateActorCodeCIDs                 func(context.Context, stnetwork.Version) (map[string]cid.Cid, error)                                                `perm:"read"`
 
 		MsigGetAvailableBalance func(context.Context, address.Address, types.TipSetKey) (types.BigInt, error)                                                                    `perm:"read"`
 		MsigGetVestingSchedule  func(context.Context, address.Address, types.TipSetKey) (api.MsigVesting, error)                                                                 `perm:"read"`
@@ -284,6 +349,7 @@ type StorageMinerStruct struct {
 
 		SectorsStatus                 func(ctx context.Context, sid abi.SectorNumber, showOnChainInfo bool) (api.SectorInfo, error) `perm:"read"`
 		SectorsList                   func(context.Context) ([]abi.SectorNumber, error)                                             `perm:"read"`
+		SectorsListWithDetails        func(context.Context, api.SectorsFilter) ([]api.SectorDetails, error)                         `perm:"read"`
 		SectorsRefs                   func(context.Context) (map[string][]api.SealedRef, error)                                     `perm:"read"`
 		SectorStartSealing            func(context.Context, abi.SectorNumber) error                                                 `perm:"write"`
 		SectorSetSealDelay            func(context.Context, time.Duration) error                                                    `perm:"write"`
@@ -298,7 +364,9 @@ type StorageMinerStruct struct {
 		WorkerStats   func(context.Context) (map[uint64]storiface.WorkerStats, error) `perm:"admin"`
 		WorkerJobs    func(context.Context) (map[uint64][]storiface.WorkerJob, error) `perm:"admin"`
 
-		SealingSchedDiag func(context.Context) (interface{}, error) `perm:"admin"`
+		SealingSchedDiag   func(context.Context) (interface{}, error)        `perm:"admin"`
+		SealingSchedPause  func(context.Context, []sealtasks.TaskType) error `perm:"admin"`
+		SealingSchedResume func(context.Context, []sealtasks.TaskType) error `perm:"admin"`
 
 		StorageList          func(context.Context) (map[stores.ID][]stores.Decl, error)                                                                                    `perm:"admin"`
 		StorageLocal         func(context.Context) (map[stores.ID]string, error)                                                                                           `perm:"admin"`
@@ -323,17 +391,24 @@ type StorageMinerStruct struct {
 		DealsSetConsiderOfflineStorageDeals   func(context.Context, bool) error                                 `perm:"admin"`
 		DealsConsiderOfflineRetrievalDeals    func(context.Context) (bool, error)                               `perm:"read"`
 		DealsSetConsiderOfflineRetrievalDeals func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSLAReports                       func(context.Context, int) ([]api.DealSLAReport, error)           `perm:"read"`
+		DealsImportAuthorization              func(context.Context, api.SignedDealAuthorization) error          `perm:"admin"`
+		DealsListAuthorizations               func(context.Context) ([]api.SignedDealAuthorization, error)      `perm:"read"`
 		DealsPieceCidBlocklist                func(context.Context) ([]cid.Cid, error)                          `perm:"read"`
 		DealsSetPieceCidBlocklist             func(context.Context, []cid.Cid) error                            `perm:"admin"`
 
 		StorageAddLocal func(ctx context.Context, path string) error `perm:"admin"`
 
-		PiecesListPieces   func(ctx context.Context) ([]cid.Cid, error)                               `perm:"read"`
-		PiecesListCidInfos func(ctx context.Context) ([]cid.Cid, error)                               `perm:"read"`
-		PiecesGetPieceInfo func(ctx context.Context, pieceCid cid.Cid) (*piecestore.PieceInfo, error) `perm:"read"`
-		PiecesGetCIDInfo   func(ctx context.Context, payloadCid cid.Cid) (*piecestore.CIDInfo, error) `perm:"read"`
+		PiecesListPieces   func(ctx context.Context) ([]cid.Cid, error)                                `perm:"read"`
+		PiecesListCidInfos func(ctx context.Context) ([]cid.Cid, error)                                `perm:"read"`
+		PiecesGetPieceInfo func(ctx context.Context, pieceCid cid.Cid) (*piecestore.PieceInfo, error)  `perm:"read"`
+		PiecesGetCIDInfo   func(ctx context.Context, payloadCid cid.Cid) (*piecestore.CIDInfo, error)  `perm:"read"`
+		MarketVerifyPiece  func(ctx context.Context, pieceCid cid.Cid) (*api.PieceVerification, error) `perm:"admin"`
 
 		CreateBackup func(ctx context.Context, fpath string) error `perm:"admin"`
+
+		ConfigGet func(ctx context.Context, section string) (json.RawMessage, error)                             `perm:"admin"`
+		ConfigSet func(ctx context.Context, section string, value json.RawMessage) (*api.ConfigSetResult, error) `perm:"admin"`
 	}
 }
 
@@ -370,19 +445,38 @@ type WorkerStruct struct {
 type GatewayStruct struct {
 	Internal struct {
 		// TODO: does the gateway need perms?
-		ChainHasObj             func(context.Context, cid.Cid) (bool, error)
-		ChainGetTipSet          func(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error)
-		ChainGetTipSetByHeight  func(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
-		ChainHead               func(ctx context.Context) (*types.TipSet, error)
-		ChainReadObj            func(context.Context, cid.Cid) ([]byte, error)
-		GasEstimateMessageGas   func(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, tsk types.TipSetKey) (*types.Message, error)
-		MpoolPush               func(ctx context.Context, sm *types.SignedMessage) (cid.Cid, error)
-		MsigGetAvailableBalance func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (types.BigInt, error)
-		MsigGetVested           func(ctx context.Context, addr address.Address, start types.TipSetKey, end types.TipSetKey) (types.BigInt, error)
-		StateAccountKey         func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
-		StateGetActor           func(ctx context.Context, actor address.Address, ts types.TipSetKey) (*types.Actor, error)
-		StateLookupID           func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
-		StateWaitMsg            func(ctx context.Context, msg cid.Cid, confidence uint64) (*api.MsgLookup, error)
+		ChainHasObj                      func(context.Context, cid.Cid) (bool, error)
+		ChainGetTipSet                   func(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error)
+		ChainGetTipSetByHeight           func(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
+		ChainHead                        func(ctx context.Context) (*types.TipSet, error)
+		ChainReadObj                     func(context.Context, cid.Cid) ([]byte, error)
+		ChainGetNode                     func(ctx context.Context, p string) (*api.IpldObject, error)
+		ChainGetPath                     func(ctx context.Context, from types.TipSetKey, to types.TipSetKey) ([]*api.HeadChange, error)
+		GasEstimateMessageGas            func(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, tsk types.TipSetKey) (*types.Message, error)
+		MpoolPush                        func(ctx context.Context, sm *types.SignedMessage) (cid.Cid, error)
+		MsigGetAvailableBalance          func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (types.BigInt, error)
+		MsigGetVested                    func(ctx context.Context, addr address.Address, start types.TipSetKey, end types.TipSetKey) (types.BigInt, error)
+		StateAccountKey                  func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
+		StateGetActor                    func(ctx context.Context, actor address.Address, ts types.TipSetKey) (*types.Actor, error)
+		StateLookupID                    func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
+		StateWaitMsg                     func(ctx context.Context, msg cid.Cid, confidence uint64) (*api.MsgLookup, error)
+		StateWaitMsgBounded              func(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
+		StateReadState                   func(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error)
+		StateMinerPower                  func(context.Context, address.Address, types.TipSetKey) (*api.MinerPower, error)
+		StateMinerFaults                 func(context.Context, address.Address, types.TipSetKey) (bitfield.BitField, error)
+		StateMinerRecoveries             func(context.Context, address.Address, types.TipSetKey) (bitfield.BitField, error)
+		StateMinerInfo                   func(context.Context, address.Address, types.TipSetKey) (miner.MinerInfo, error)
+		StateMinerDeadlines              func(context.Context, address.Address, types.TipSetKey) ([]api.Deadline, error)
+		StateMinerAvailableBalance       func(context.Context, address.Address, types.TipSetKey) (types.BigInt, error)
+		StateMinerProvingDeadline        func(context.Context, address.Address, types.TipSetKey) (*dline.Info, error)
+		StateMinerSectors                func(context.Context, address.Address, *bitfield.BitField, types.TipSetKey) ([]*miner.SectorOnChainInfo, error)
+		StateMarketDealsPage             func(context.Context, abi.DealID, uint64, types.TipSetKey) (map[string]api.MarketDeal, error)
+		StateSearchMsg                   func(ctx context.Context, msg cid.Cid) (*api.MsgLookup, error)
+		StateSearchMsgBounded            func(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
+		StateCirculatingSupply           func(context.Context, types.TipSetKey) (abi.TokenAmount, error)
+		StateVMCirculatingSupply         func(context.Context, types.TipSetKey) (api.CirculatingSupply, error)
+		StateVMCirculatingSupplyInternal func(context.Context, types.TipSetKey) (api.CirculatingSupply, error)
+		WalletVerify                     func(context.Context, address.Address, []byte, *crypto.Signature) (bool, error)
 	}
 }
 
@@ -408,6 +502,14 @@ func (c *CommonStruct) AuthNew(ctx context.Context, perms []auth.Permission) ([]
 	return c.Internal.AuthNew(ctx, perms)
 }
 
+func (c *CommonStruct) AuthUsage(ctx context.Context) ([]api.TokenUsage, error) {
+	return c.Internal.AuthUsage(ctx)
+}
+
+func (c *CommonStruct) AuthConnections(ctx context.Context) ([]api.APIConnection, error) {
+	return c.Internal.AuthConnections(ctx)
+}
+
 func (c *CommonStruct) NetPubsubScores(ctx context.Context) ([]api.PubsubScore, error) {
 	return c.Internal.NetPubsubScores(ctx)
 }
@@ -466,6 +568,10 @@ func (c *CommonStruct) Version(ctx context.Context) (api.Version, error) {
 	return c.Internal.Version(ctx)
 }
 
+func (c *CommonStruct) Discover(ctx context.Context) (api.APICapabilities, error) {
+	return c.Internal.Discover(ctx)
+}
+
 func (c *CommonStruct) LogList(ctx context.Context) ([]string, error) {
 	return c.Internal.LogList(ctx)
 }
@@ -512,6 +618,10 @@ func (c *FullNodeStruct) ClientStartDeal(ctx context.Context, params *api.StartD
 	return c.Internal.ClientStartDeal(ctx, params)
 }
 
+func (c *FullNodeStruct) ClientAuthorizeDelegate(ctx context.Context, funder, delegate address.Address, expiration abi.ChainEpoch) (*api.SignedDealAuthorization, error) {
+	return c.Internal.ClientAuthorizeDelegate(ctx, funder, delegate, expiration)
+}
+
 func (c *FullNodeStruct) ClientGetDealInfo(ctx context.Context, deal cid.Cid) (*api.DealInfo, error) {
 	return c.Internal.ClientGetDealInfo(ctx, deal)
 }
@@ -571,6 +681,10 @@ func (c *FullNodeStruct) GasEstimateFeeCap(ctx context.Context, msg *types.Messa
 	return c.Internal.GasEstimateFeeCap(ctx, msg, maxqueueblks, tsk)
 }
 
+func (c *FullNodeStruct) GasEstimateFeeForecast(ctx context.Context, nepochs, lookback uint64, tsk types.TipSetKey) (*api.FeeForecast, error) {
+	return c.Internal.GasEstimateFeeForecast(ctx, nepochs, lookback, tsk)
+}
+
 func (c *FullNodeStruct) GasEstimateMessageGas(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, tsk types.TipSetKey) (*types.Message, error) {
 	return c.Internal.GasEstimateMessageGas(ctx, msg, spec, tsk)
 }
@@ -599,6 +713,14 @@ func (c *FullNodeStruct) MpoolClear(ctx context.Context, local bool) error {
 	return c.Internal.MpoolClear(ctx, local)
 }
 
+func (c *FullNodeStruct) MpoolLocalMessages(ctx context.Context) ([]*types.SignedMessage, error) {
+	return c.Internal.MpoolLocalMessages(ctx)
+}
+
+func (c *FullNodeStruct) MpoolAbandon(ctx context.Context, msg cid.Cid) error {
+	return c.Internal.MpoolAbandon(ctx, msg)
+}
+
 func (c *FullNodeStruct) MpoolPush(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
 	return c.Internal.MpoolPush(ctx, smsg)
 }
@@ -607,14 +729,46 @@ func (c *FullNodeStruct) MpoolPushUntrusted(ctx context.Context, smsg *types.Sig
 	return c.Internal.MpoolPushUntrusted(ctx, smsg)
 }
 
+func (c *FullNodeStruct) MpoolBatchPush(ctx context.Context, smsgs []*types.SignedMessage) ([]cid.Cid, error) {
+	return c.Internal.MpoolBatchPush(ctx, smsgs)
+}
+
+func (c *FullNodeStruct) MpoolBatchPushMessage(ctx context.Context, msgs []*types.Message, spec *api.MessageSendSpec) ([]*types.SignedMessage, error) {
+	return c.Internal.MpoolBatchPushMessage(ctx, msgs, spec)
+}
+
+func (c *FullNodeStruct) MpoolSchedule(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, schedule api.MessageSchedule) (uint64, error) {
+	return c.Internal.MpoolSchedule(ctx, msg, spec, schedule)
+}
+
+func (c *FullNodeStruct) MpoolScheduleSigned(ctx context.Context, smsg *types.SignedMessage, schedule api.MessageSchedule) (uint64, error) {
+	return c.Internal.MpoolScheduleSigned(ctx, smsg, schedule)
+}
+
+func (c *FullNodeStruct) MpoolScheduled(ctx context.Context) ([]api.ScheduledMessage, error) {
+	return c.Internal.MpoolScheduled(ctx)
+}
+
+func (c *FullNodeStruct) MpoolScheduledCancel(ctx context.Context, id uint64) error {
+	return c.Internal.MpoolScheduledCancel(ctx, id)
+}
+
 func (c *FullNodeStruct) MpoolPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (*types.SignedMessage, error) {
 	return c.Internal.MpoolPushMessage(ctx, msg, spec)
 }
 
+func (c *FullNodeStruct) MpoolReplace(ctx context.Context, from address.Address, nonce uint64, spec *api.MpoolReplaceSpec) (*types.SignedMessage, error) {
+	return c.Internal.MpoolReplace(ctx, from, nonce, spec)
+}
+
 func (c *FullNodeStruct) MpoolSub(ctx context.Context) (<-chan api.MpoolUpdate, error) {
 	return c.Internal.MpoolSub(ctx)
 }
 
+func (c *FullNodeStruct) MpoolSubAddress(ctx context.Context, addrs []address.Address) (<-chan api.MpoolUpdate, error) {
+	return c.Internal.MpoolSubAddress(ctx, addrs)
+}
+
 func (c *FullNodeStruct) MinerGetBaseInfo(ctx context.Context, maddr address.Address, epoch abi.ChainEpoch, tsk types.TipSetKey) (*api.MiningBaseInfo, error) {
 	return c.Internal.MinerGetBaseInfo(ctx, maddr, epoch, tsk)
 }
@@ -623,6 +777,22 @@ func (c *FullNodeStruct) MinerCreateBlock(ctx context.Context, bt *api.BlockTemp
 	return c.Internal.MinerCreateBlock(ctx, bt)
 }
 
+func (c *FullNodeStruct) MinerWithdrawBalance(ctx context.Context, maddr address.Address, amount abi.TokenAmount, from address.Address) (*api.MinerOwnerMessage, error) {
+	return c.Internal.MinerWithdrawBalance(ctx, maddr, amount, from)
+}
+
+func (c *FullNodeStruct) MinerChangeWorker(ctx context.Context, maddr address.Address, newWorker address.Address, from address.Address) (*api.MinerOwnerMessage, error) {
+	return c.Internal.MinerChangeWorker(ctx, maddr, newWorker, from)
+}
+
+func (c *FullNodeStruct) MinerConfirmChangeWorker(ctx context.Context, maddr address.Address, from address.Address) (*api.MinerOwnerMessage, error) {
+	return c.Internal.MinerConfirmChangeWorker(ctx, maddr, from)
+}
+
+func (c *FullNodeStruct) MinerSetControlAddresses(ctx context.Context, maddr address.Address, addrs []address.Address, from address.Address) (*api.MinerOwnerMessage, error) {
+	return c.Internal.MinerSetControlAddresses(ctx, maddr, addrs, from)
+}
+
 func (c *FullNodeStruct) ChainHead(ctx context.Context) (*types.TipSet, error) {
 	return c.Internal.ChainHead(ctx)
 }
@@ -647,6 +817,10 @@ func (c *FullNodeStruct) WalletHas(ctx context.Context, addr address.Address) (b
 	return c.Internal.WalletHas(ctx, addr)
 }
 
+func (c *FullNodeStruct) WalletBackend(ctx context.Context, addr address.Address) (string, error) {
+	return c.Internal.WalletBackend(ctx, addr)
+}
+
 func (c *FullNodeStruct) WalletList(ctx context.Context) ([]address.Address, error) {
 	return c.Internal.WalletList(ctx)
 }
@@ -663,6 +837,10 @@ func (c *FullNodeStruct) WalletSignMessage(ctx context.Context, k address.Addres
 	return c.Internal.WalletSignMessage(ctx, k, msg)
 }
 
+func (c *FullNodeStruct) WalletSignAggregate(ctx context.Context, msgs []*types.Message) (*crypto.Signature, error) {
+	return c.Internal.WalletSignAggregate(ctx, msgs)
+}
+
 func (c *FullNodeStruct) WalletVerify(ctx context.Context, k address.Address, msg []byte, sig *crypto.Signature) (bool, error) {
 	return c.Internal.WalletVerify(ctx, k, msg, sig)
 }
@@ -691,6 +869,34 @@ func (c *FullNodeStruct) WalletValidateAddress(ctx context.Context, str string)
 	return c.Internal.WalletValidateAddress(ctx, str)
 }
 
+func (c *FullNodeStruct) WalletEncrypt(ctx context.Context, passphrase string) error {
+	return c.Internal.WalletEncrypt(ctx, passphrase)
+}
+
+func (c *FullNodeStruct) WalletUnlock(ctx context.Context, passphrase string, timeout time.Duration) error {
+	return c.Internal.WalletUnlock(ctx, passphrase, timeout)
+}
+
+func (c *FullNodeStruct) WalletLock(ctx context.Context) error {
+	return c.Internal.WalletLock(ctx)
+}
+
+func (c *FullNodeStruct) WalletNewHD(ctx context.Context, typ types.KeyType) (address.Address, error) {
+	return c.Internal.WalletNewHD(ctx, typ)
+}
+
+func (c *FullNodeStruct) WalletDeriveHD(ctx context.Context, typ types.KeyType, index uint64) (address.Address, error) {
+	return c.Internal.WalletDeriveHD(ctx, typ, index)
+}
+
+func (c *FullNodeStruct) WalletImportMnemonic(ctx context.Context, mnemonic string) error {
+	return c.Internal.WalletImportMnemonic(ctx, mnemonic)
+}
+
+func (c *FullNodeStruct) WalletExportMnemonic(ctx context.Context) (*api.HDExport, error) {
+	return c.Internal.WalletExportMnemonic(ctx)
+}
+
 func (c *FullNodeStruct) MpoolGetNonce(ctx context.Context, addr address.Address) (uint64, error) {
 	return c.Internal.MpoolGetNonce(ctx, addr)
 }
@@ -719,6 +925,14 @@ func (c *FullNodeStruct) ChainNotify(ctx context.Context) (<-chan []*api.HeadCha
 	return c.Internal.ChainNotify(ctx)
 }
 
+func (c *FullNodeStruct) ChainNotifyFrom(ctx context.Context, cursor uint64) (<-chan *api.HeadChanges, error) {
+	return c.Internal.ChainNotifyFrom(ctx, cursor)
+}
+
+func (c *FullNodeStruct) ChainNotifySince(ctx context.Context, tsk types.TipSetKey) (<-chan *api.HeadChanges, error) {
+	return c.Internal.ChainNotifySince(ctx, tsk)
+}
+
 func (c *FullNodeStruct) ChainReadObj(ctx context.Context, obj cid.Cid) ([]byte, error) {
 	return c.Internal.ChainReadObj(ctx, obj)
 }
@@ -763,10 +977,58 @@ func (c *FullNodeStruct) ChainExport(ctx context.Context, nroots abi.ChainEpoch,
 	return c.Internal.ChainExport(ctx, nroots, iom, tsk)
 }
 
+func (c *FullNodeStruct) ChainExportIncremental(ctx context.Context, nroots abi.ChainEpoch, iom bool, base types.TipSetKey, tsk types.TipSetKey) (<-chan []byte, error) {
+	return c.Internal.ChainExportIncremental(ctx, nroots, iom, base, tsk)
+}
+
+func (c *FullNodeStruct) ChainExportRange(ctx context.Context, tsk types.TipSetKey, start, end abi.ChainEpoch, opts api.ChainExportRangeOpts) (<-chan []byte, error) {
+	return c.Internal.ChainExportRange(ctx, tsk, start, end, opts)
+}
+
+func (c *FullNodeStruct) ChainPrune(ctx context.Context, keep abi.ChainEpoch, dryRun bool) (*api.ChainPruneResult, error) {
+	return c.Internal.ChainPrune(ctx, keep, dryRun)
+}
+
+func (c *FullNodeStruct) ChainStat(ctx context.Context) (*api.ChainStat, error) {
+	return c.Internal.ChainStat(ctx)
+}
+
+func (c *FullNodeStruct) ChainCheckBlockstore(ctx context.Context, repair bool) (*api.ChainCheckResult, error) {
+	return c.Internal.ChainCheckBlockstore(ctx, repair)
+}
+
+func (c *FullNodeStruct) ChainGetReorgs(ctx context.Context, since abi.ChainEpoch) ([]*api.ChainReorg, error) {
+	return c.Internal.ChainGetReorgs(ctx, since)
+}
+
+func (c *FullNodeStruct) ChainReorgNotify(ctx context.Context) (<-chan *api.ChainReorg, error) {
+	return c.Internal.ChainReorgNotify(ctx)
+}
+
+func (c *FullNodeStruct) ChainBlockstoreGC(ctx context.Context, opts api.BlockstoreGCOpts) (<-chan api.BlockstoreGCProgress, error) {
+	return c.Internal.ChainBlockstoreGC(ctx, opts)
+}
+
+func (c *FullNodeStruct) ChainIndexMessages(ctx context.Context, from, to abi.ChainEpoch) (int, error) {
+	return c.Internal.ChainIndexMessages(ctx, from, to)
+}
+
+func (c *FullNodeStruct) ChainBackfillMessageIndex(ctx context.Context, opts api.IndexBackfillOpts) (<-chan api.IndexBackfillProgress, error) {
+	return c.Internal.ChainBackfillMessageIndex(ctx, opts)
+}
+
+func (c *FullNodeStruct) ChainReindexHeights(ctx context.Context, tsk types.TipSetKey) error {
+	return c.Internal.ChainReindexHeights(ctx, tsk)
+}
+
 func (c *FullNodeStruct) BeaconGetEntry(ctx context.Context, epoch abi.ChainEpoch) (*types.BeaconEntry, error) {
 	return c.Internal.BeaconGetEntry(ctx, epoch)
 }
 
+func (c *FullNodeStruct) BeaconSchedule(ctx context.Context) ([]api.BeaconInfo, error) {
+	return c.Internal.BeaconSchedule(ctx)
+}
+
 func (c *FullNodeStruct) SyncState(ctx context.Context) (*api.SyncState, error) {
 	return c.Internal.SyncState(ctx)
 }
@@ -799,10 +1061,30 @@ func (c *FullNodeStruct) SyncCheckBad(ctx context.Context, bcid cid.Cid) (string
 	return c.Internal.SyncCheckBad(ctx, bcid)
 }
 
+func (c *FullNodeStruct) SyncListBad(ctx context.Context) ([]api.BadBlock, error) {
+	return c.Internal.SyncListBad(ctx)
+}
+
+func (c *FullNodeStruct) SyncBlockPeer(ctx context.Context, p peer.ID, reason string) error {
+	return c.Internal.SyncBlockPeer(ctx, p, reason)
+}
+
+func (c *FullNodeStruct) SyncUnblockPeer(ctx context.Context, p peer.ID) error {
+	return c.Internal.SyncUnblockPeer(ctx, p)
+}
+
+func (c *FullNodeStruct) SyncBlockedPeers(ctx context.Context) ([]api.BlockedPeer, error) {
+	return c.Internal.SyncBlockedPeers(ctx)
+}
+
 func (c *FullNodeStruct) SyncValidateTipset(ctx context.Context, tsk types.TipSetKey) (bool, error) {
 	return c.Internal.SyncValidateTipset(ctx, tsk)
 }
 
+func (c *FullNodeStruct) SyncValidateBlockHeader(ctx context.Context, h *types.BlockHeader) (*api.BlockHeaderValidation, error) {
+	return c.Internal.SyncValidateBlockHeader(ctx, h)
+}
+
 func (c *FullNodeStruct) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
 	return c.Internal.StateNetworkName(ctx)
 }
@@ -883,14 +1165,38 @@ func (c *FullNodeStruct) StateReplay(ctx context.Context, tsk types.TipSetKey, m
 	return c.Internal.StateReplay(ctx, tsk, mc)
 }
 
+func (c *FullNodeStruct) StateReplayGasProfile(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) (*api.GasProfile, error) {
+	return c.Internal.StateReplayGasProfile(ctx, tsk, mc)
+}
+
+func (c *FullNodeStruct) StateReplayGasPprof(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) ([]byte, error) {
+	return c.Internal.StateReplayGasPprof(ctx, tsk, mc)
+}
+
 func (c *FullNodeStruct) StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error) {
 	return c.Internal.StateGetActor(ctx, actor, tsk)
 }
 
+func (c *FullNodeStruct) StateGetProof(ctx context.Context, actor address.Address, path string, tsk types.TipSetKey) (*api.StateProof, error) {
+	return c.Internal.StateGetProof(ctx, actor, path, tsk)
+}
+
 func (c *FullNodeStruct) StateReadState(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
 	return c.Internal.StateReadState(ctx, addr, tsk)
 }
 
+func (c *FullNodeStruct) StateDecodeParams(ctx context.Context, toAddr address.Address, method abi.MethodNum, params []byte, tsk types.TipSetKey) (interface{}, error) {
+	return c.Internal.StateDecodeParams(ctx, toAddr, method, params, tsk)
+}
+
+func (c *FullNodeStruct) StateDecodeReturn(ctx context.Context, toAddr address.Address, method abi.MethodNum, ret []byte, tsk types.TipSetKey) (interface{}, error) {
+	return c.Internal.StateDecodeReturn(ctx, toAddr, method, ret, tsk)
+}
+
+func (c *FullNodeStruct) StateDecodeActorState(ctx context.Context, code cid.Cid, head cid.Cid) (interface{}, error) {
+	return c.Internal.StateDecodeActorState(ctx, code, head)
+}
+
 func (c *FullNodeStruct) StateWaitMsg(ctx context.Context, msgc cid.Cid, confidence uint64) (*api.MsgLookup, error) {
 	return c.Internal.StateWaitMsg(ctx, msgc, confidence)
 }
@@ -899,10 +1205,26 @@ func (c *FullNodeStruct) StateWaitMsgLimited(ctx context.Context, msgc cid.Cid,
 	return c.Internal.StateWaitMsgLimited(ctx, msgc, confidence, limit)
 }
 
+func (c *FullNodeStruct) StateWaitMsgBounded(ctx context.Context, msgc cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return c.Internal.StateWaitMsgBounded(ctx, msgc, confidence, limit, allowReplaced)
+}
+
+func (c *FullNodeStruct) StateWatchMsgs(ctx context.Context, w api.MsgWatch) (<-chan *api.MsgWatchEvent, error) {
+	return c.Internal.StateWatchMsgs(ctx, w)
+}
+
 func (c *FullNodeStruct) StateSearchMsg(ctx context.Context, msgc cid.Cid) (*api.MsgLookup, error) {
 	return c.Internal.StateSearchMsg(ctx, msgc)
 }
 
+func (c *FullNodeStruct) StateSearchMsgBounded(ctx context.Context, msgc cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return c.Internal.StateSearchMsgBounded(ctx, msgc, limit, allowReplaced)
+}
+
+func (c *FullNodeStruct) StateMsgGasCost(ctx context.Context, msgc cid.Cid) (*api.MsgGasCost, error) {
+	return c.Internal.StateMsgGasCost(ctx, msgc)
+}
+
 func (c *FullNodeStruct) StateListMiners(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error) {
 	return c.Internal.StateListMiners(ctx, tsk)
 }
@@ -923,6 +1245,10 @@ func (c *FullNodeStruct) StateMarketDeals(ctx context.Context, tsk types.TipSetK
 	return c.Internal.StateMarketDeals(ctx, tsk)
 }
 
+func (c *FullNodeStruct) StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	return c.Internal.StateMarketDealsPage(ctx, start, limit, tsk)
+}
+
 func (c *FullNodeStruct) StateMarketStorageDeal(ctx context.Context, dealid abi.DealID, tsk types.TipSetKey) (*api.MarketDeal, error) {
 	return c.Internal.StateMarketStorageDeal(ctx, dealid, tsk)
 }
@@ -939,6 +1265,18 @@ func (c *FullNodeStruct) StateChangedActors(ctx context.Context, olnstate cid.Ci
 	return c.Internal.StateChangedActors(ctx, olnstate, newstate)
 }
 
+func (c *FullNodeStruct) StateDiff(ctx context.Context, from, to types.TipSetKey, actor address.Address) (*api.StateDiff, error) {
+	return c.Internal.StateDiff(ctx, from, to, actor)
+}
+
+func (c *FullNodeStruct) StateWatchPaths(ctx context.Context, paths []api.StateWatchPath) (<-chan []*api.StateWatchChange, error) {
+	return c.Internal.StateWatchPaths(ctx, paths)
+}
+
+func (c *FullNodeStruct) StateSubscribeChanges(ctx context.Context, filter api.StateChangeFilter) (<-chan []*api.StateChangeEvent, error) {
+	return c.Internal.StateSubscribeChanges(ctx, filter)
+}
+
 func (c *FullNodeStruct) StateGetReceipt(ctx context.Context, msg cid.Cid, tsk types.TipSetKey) (*types.MessageReceipt, error) {
 	return c.Internal.StateGetReceipt(ctx, msg, tsk)
 }
@@ -947,6 +1285,10 @@ func (c *FullNodeStruct) StateListMessages(ctx context.Context, match *api.Messa
 	return c.Internal.StateListMessages(ctx, match, tsk, toht)
 }
 
+func (c *FullNodeStruct) StateListMessagesFor(ctx context.Context, addr address.Address, tsk types.TipSetKey, offset, limit uint64) ([]*api.AddrMessage, error) {
+	return c.Internal.StateListMessagesFor(ctx, addr, tsk, offset, limit)
+}
+
 func (c *FullNodeStruct) StateCompute(ctx context.Context, height abi.ChainEpoch, msgs []*types.Message, tsk types.TipSetKey) (*api.ComputeStateOutput, error) {
 	return c.Internal.StateCompute(ctx, height, msgs, tsk)
 }
@@ -971,6 +1313,10 @@ func (c *FullNodeStruct) StateCirculatingSupply(ctx context.Context, tsk types.T
 	return c.Internal.StateCirculatingSupply(ctx, tsk)
 }
 
+func (c *FullNodeStruct) StateVMCirculatingSupply(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+	return c.Internal.StateVMCirculatingSupply(ctx, tsk)
+}
+
 func (c *FullNodeStruct) StateVMCirculatingSupplyInternal(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
 	return c.Internal.StateVMCirculatingSupplyInternal(ctx, tsk)
 }
@@ -979,6 +1325,10 @@ func (c *FullNodeStruct) StateNetworkVersion(ctx context.Context, tsk types.TipS
 	return c.Internal.StateNetworkVersion(ctx, tsk)
 }
 
+func (c *FullNodeStruct) StateActorCodeCIDs(ctx context.Context, nv stnetwork.Version) (map[string]cid.Cid, error) {
+	return c.Internal.StateActorCodeCIDs(ctx, nv)
+}
+
 func (c *FullNodeStruct) MsigGetAvailableBalance(ctx context.Context, a address.Address, tsk types.TipSetKey) (types.BigInt, error) {
 	return c.Internal.MsigGetAvailableBalance(ctx, a, tsk)
 }
@@ -1139,6 +1489,10 @@ func (c *StorageMinerStruct) SectorsList(ctx context.Context) ([]abi.SectorNumbe
 	return c.Internal.SectorsList(ctx)
 }
 
+func (c *StorageMinerStruct) SectorsListWithDetails(ctx context.Context, filter api.SectorsFilter) ([]api.SectorDetails, error) {
+	return c.Internal.SectorsListWithDetails(ctx, filter)
+}
+
 func (c *StorageMinerStruct) SectorsRefs(ctx context.Context) (map[string][]api.SealedRef, error) {
 	return c.Internal.SectorsRefs(ctx)
 }
@@ -1191,6 +1545,14 @@ func (c *StorageMinerStruct) SealingSchedDiag(ctx context.Context) (interface{},
 	return c.Internal.SealingSchedDiag(ctx)
 }
 
+func (c *StorageMinerStruct) SealingSchedPause(ctx context.Context, tasks []sealtasks.TaskType) error {
+	return c.Internal.SealingSchedPause(ctx, tasks)
+}
+
+func (c *StorageMinerStruct) SealingSchedResume(ctx context.Context, tasks []sealtasks.TaskType) error {
+	return c.Internal.SealingSchedResume(ctx, tasks)
+}
+
 func (c *StorageMinerStruct) StorageAttach(ctx context.Context, si stores.StorageInfo, st fsutil.FsStat) error {
 	return c.Internal.StorageAttach(ctx, si, st)
 }
@@ -1331,6 +1693,18 @@ func (c *StorageMinerStruct) DealsSetConsiderOfflineRetrievalDeals(ctx context.C
 	return c.Internal.DealsSetConsiderOfflineRetrievalDeals(ctx, b)
 }
 
+func (c *StorageMinerStruct) DealsSLAReports(ctx context.Context, count int) ([]api.DealSLAReport, error) {
+	return c.Internal.DealsSLAReports(ctx, count)
+}
+
+func (c *StorageMinerStruct) DealsImportAuthorization(ctx context.Context, auth api.SignedDealAuthorization) error {
+	return c.Internal.DealsImportAuthorization(ctx, auth)
+}
+
+func (c *StorageMinerStruct) DealsListAuthorizations(ctx context.Context) ([]api.SignedDealAuthorization, error) {
+	return c.Internal.DealsListAuthorizations(ctx)
+}
+
 func (c *StorageMinerStruct) StorageAddLocal(ctx context.Context, path string) error {
 	return c.Internal.StorageAddLocal(ctx, path)
 }
@@ -1351,10 +1725,22 @@ func (c *StorageMinerStruct) PiecesGetCIDInfo(ctx context.Context, payloadCid ci
 	return c.Internal.PiecesGetCIDInfo(ctx, payloadCid)
 }
 
+func (c *StorageMinerStruct) MarketVerifyPiece(ctx context.Context, pieceCid cid.Cid) (*api.PieceVerification, error) {
+	return c.Internal.MarketVerifyPiece(ctx, pieceCid)
+}
+
 func (c *StorageMinerStruct) CreateBackup(ctx context.Context, fpath string) error {
 	return c.Internal.CreateBackup(ctx, fpath)
 }
 
+func (c *StorageMinerStruct) ConfigGet(ctx context.Context, section string) (json.RawMessage, error) {
+	return c.Internal.ConfigGet(ctx, section)
+}
+
+func (c *StorageMinerStruct) ConfigSet(ctx context.Context, section string, value json.RawMessage) (*api.ConfigSetResult, error) {
+	return c.Internal.ConfigSet(ctx, section, value)
+}
+
 // WorkerStruct
 
 func (w *WorkerStruct) Version(ctx context.Context) (build.Version, error) {
@@ -1481,6 +1867,82 @@ func (g GatewayStruct) StateWaitMsg(ctx context.Context, msg cid.Cid, confidence
 	return g.Internal.StateWaitMsg(ctx, msg, confidence)
 }
 
+func (g GatewayStruct) StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return g.Internal.StateWaitMsgBounded(ctx, msg, confidence, limit, allowReplaced)
+}
+
+func (g GatewayStruct) ChainGetNode(ctx context.Context, p string) (*api.IpldObject, error) {
+	return g.Internal.ChainGetNode(ctx, p)
+}
+
+func (g GatewayStruct) ChainGetPath(ctx context.Context, from types.TipSetKey, to types.TipSetKey) ([]*api.HeadChange, error) {
+	return g.Internal.ChainGetPath(ctx, from, to)
+}
+
+func (g GatewayStruct) StateMinerSectors(ctx context.Context, m address.Address, filter *bitfield.BitField, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	return g.Internal.StateMinerSectors(ctx, m, filter, tsk)
+}
+
+func (g GatewayStruct) StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	return g.Internal.StateMarketDealsPage(ctx, start, limit, tsk)
+}
+
+func (g GatewayStruct) StateSearchMsg(ctx context.Context, msg cid.Cid) (*api.MsgLookup, error) {
+	return g.Internal.StateSearchMsg(ctx, msg)
+}
+
+func (g GatewayStruct) StateSearchMsgBounded(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return g.Internal.StateSearchMsgBounded(ctx, msg, limit, allowReplaced)
+}
+
+func (g GatewayStruct) StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
+	return g.Internal.StateReadState(ctx, actor, tsk)
+}
+
+func (g GatewayStruct) StateMinerPower(ctx context.Context, m address.Address, tsk types.TipSetKey) (*api.MinerPower, error) {
+	return g.Internal.StateMinerPower(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateMinerFaults(ctx context.Context, m address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	return g.Internal.StateMinerFaults(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateMinerRecoveries(ctx context.Context, m address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	return g.Internal.StateMinerRecoveries(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateMinerInfo(ctx context.Context, m address.Address, tsk types.TipSetKey) (miner.MinerInfo, error) {
+	return g.Internal.StateMinerInfo(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateMinerDeadlines(ctx context.Context, m address.Address, tsk types.TipSetKey) ([]api.Deadline, error) {
+	return g.Internal.StateMinerDeadlines(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateMinerAvailableBalance(ctx context.Context, m address.Address, tsk types.TipSetKey) (types.BigInt, error) {
+	return g.Internal.StateMinerAvailableBalance(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateMinerProvingDeadline(ctx context.Context, m address.Address, tsk types.TipSetKey) (*dline.Info, error) {
+	return g.Internal.StateMinerProvingDeadline(ctx, m, tsk)
+}
+
+func (g GatewayStruct) StateCirculatingSupply(ctx context.Context, tsk types.TipSetKey) (abi.TokenAmount, error) {
+	return g.Internal.StateCirculatingSupply(ctx, tsk)
+}
+
+func (g GatewayStruct) StateVMCirculatingSupply(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+	return g.Internal.StateVMCirculatingSupply(ctx, tsk)
+}
+
+func (g GatewayStruct) StateVMCirculatingSupplyInternal(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+	return g.Internal.StateVMCirculatingSupplyInternal(ctx, tsk)
+}
+
+func (g GatewayStruct) WalletVerify(ctx context.Context, k address.Address, msg []byte, sig *crypto.Signature) (bool, error) {
+	return g.Internal.WalletVerify(ctx, k, msg, sig)
+}
+
 func (c *WalletStruct) WalletNew(ctx context.Context, typ types.KeyType) (address.Address, error) {
 	return c.Internal.WalletNew(ctx, typ)
 }
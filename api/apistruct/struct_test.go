@@ -1,9 +1,66 @@
 package apistruct
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-jsonrpc/auth"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
 
 func TestPermTags(t *testing.T) {
 	_ = PermissionedFullAPI(&FullNodeStruct{})
 	_ = PermissionedStorMinerAPI(&StorageMinerStruct{})
 	_ = PermissionedWorkerAPI(&WorkerStruct{})
 }
+
+func TestMethods(t *testing.T) {
+	perms := map[string]auth.Permission{}
+	for _, m := range Methods(&FullNodeStruct{}) {
+		perms[m.Name] = m.Perm
+	}
+
+	// the methods of the embedded CommonStruct are included
+	if perms["Version"] != PermRead || perms["ChainHead"] != PermRead || perms["WalletSign"] != PermSign {
+		t.Fatalf("wrong method permissions: %v", perms)
+	}
+}
+
+func TestMethodScopes(t *testing.T) {
+	var s FullNodeStruct
+	s.Internal.ChainHead = func(context.Context) (*types.TipSet, error) {
+		return nil, nil
+	}
+	s.Internal.MpoolGetNonce = func(context.Context, address.Address) (uint64, error) {
+		return 1, nil
+	}
+	a := PermissionedFullAPI(&s)
+
+	// allowed returns whether the calls of ChainHead and MpoolGetNonce pass
+	allowed := func(perms ...auth.Permission) (bool, bool) {
+		ctx := auth.WithPerm(context.Background(), perms)
+		_, headErr := a.ChainHead(ctx)
+		_, nonceErr := a.MpoolGetNonce(ctx, address.Undef)
+		return headErr == nil, nonceErr == nil
+	}
+
+	if head, nonce := allowed(PermRead); !head || !nonce {
+		t.Fatal("unscoped tokens can call any method")
+	}
+	if head, nonce := allowed(PermRead, PermScoped, MethodScope("MpoolGetNonce")); head || !nonce {
+		t.Fatal("a token scoped to MpoolGetNonce can only call it")
+	}
+	if head, nonce := allowed(PermRead, PermScoped, MethodScope("Chain*")); !head || nonce {
+		t.Fatal("a token scoped to Chain* can only call the Chain methods")
+	}
+
+	// the permissions of the tokens with and without method scopes
+	if head, nonce := allowed(Permissions([]auth.Permission{PermRead}, nil)...); !head || !nonce {
+		t.Fatal("tokens without method scopes can call any method")
+	}
+	if head, nonce := allowed(Permissions([]auth.Permission{PermRead}, []string{"Chain*"})...); !head || nonce {
+		t.Fatal("tokens with method scopes can only call the methods they match")
+	}
+}
@@ -0,0 +1,244 @@
+// Package apiusage accounts the API usage per JWT token, so that operators of
+// nodes shared by several tenants can attribute the load to them.
+package apiusage
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+)
+
+// TokenID identifies the token in the usage without revealing it.
+func TokenID(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:8])
+}
+
+type tokenKey struct{}
+
+// TokenIDFromContext returns the TokenID of the token of the request handled
+// by the Handler of a Tracker, empty for requests without a valid token.
+func TokenIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tokenKey{}).(string)
+	return id, ok
+}
+
+// Tracker accounts the usage of the API per token. The bytes are accounted by
+// the http handler, the calls by the API returned by TrackFullAPI and
+// TrackStorageMinerAPI.
+type Tracker struct {
+	lk     sync.Mutex
+	tokens map[string]*api.TokenUsage
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		tokens: map[string]*api.TokenUsage{},
+	}
+}
+
+// Usage returns the usage of all tokens, the most used first.
+func (t *Tracker) Usage() []api.TokenUsage {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	out := make([]api.TokenUsage, 0, len(t.tokens))
+	for _, u := range t.tokens {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ExecTime != out[j].ExecTime {
+			return out[i].ExecTime > out[j].ExecTime
+		}
+		return out[i].TokenID < out[j].TokenID
+	})
+	return out
+}
+
+func (t *Tracker) update(id string, cb func(u *api.TokenUsage)) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	u, ok := t.tokens[id]
+	if !ok {
+		u = &api.TokenUsage{TokenID: id}
+		t.tokens[id] = u
+	}
+	cb(u)
+}
+
+func (t *Tracker) addBytes(id string, in, out int) {
+	t.update(id, func(u *api.TokenUsage) {
+		u.BytesIn += uint64(in)
+		u.BytesOut += uint64(out)
+	})
+}
+
+func (t *Tracker) addCall(id string, start time.Time) {
+	d := time.Since(start)
+	t.update(id, func(u *api.TokenUsage) {
+		u.Calls++
+		u.ExecTime += d
+		u.LastUsed = start
+	})
+}
+
+// Handler accounts the bytes of the requests to next, and passes the token
+// to the calls through the request context. Requests without a token which
+// verify are accounted together, with an empty token ID, so that random
+// tokens can't grow the accounting.
+func (t *Tracker) Handler(verify func(ctx context.Context, token string) ([]auth.Permission, error), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id string
+		if token := requestToken(r); token != "" {
+			if _, err := verify(r.Context(), token); err == nil {
+				id = TokenID(token)
+			}
+		}
+
+		if r.Body != nil {
+			r.Body = &countingBody{ReadCloser: r.Body, t: t, id: id}
+		}
+
+		ctx := context.WithValue(r.Context(), tokenKey{}, id)
+		next.ServeHTTP(&countingWriter{ResponseWriter: w, t: t, id: id}, r.WithContext(ctx))
+	})
+}
+
+// TrackConn accounts the bytes of conn, a connection of a transport other
+// than http, authenticated with token, and passes the token to the calls made
+// on it through the returned context. The token must have been verified.
+func (t *Tracker) TrackConn(ctx context.Context, conn net.Conn, token string) (context.Context, net.Conn) {
+	var id string
+	if token != "" {
+		id = TokenID(token)
+	}
+	return context.WithValue(ctx, tokenKey{}, id), &countingConn{Conn: conn, t: t, id: id}
+}
+
+// requestToken returns the token of the request the way auth.Handler reads it.
+func requestToken(r *http.Request) string {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		token = r.FormValue("token")
+		if token != "" {
+			token = "Bearer " + token
+		}
+	}
+
+	if !strings.HasPrefix(token, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(token, "Bearer ")
+}
+
+type countingBody struct {
+	io.ReadCloser
+	t  *Tracker
+	id string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.t.addBytes(b.id, n, 0)
+	return n, err
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	t  *Tracker
+	id string
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.t.addBytes(w.id, 0, n)
+	return n, err
+}
+
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack accounts the bytes of websocket connections.
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	if brw.Reader.Buffered() > 0 {
+		// the buffered data would be lost, don't account the connection
+		return conn, brw, nil
+	}
+
+	cc := &countingConn{Conn: conn, t: w.t, id: w.id}
+	return cc, bufio.NewReadWriter(bufio.NewReader(cc), bufio.NewWriter(cc)), nil
+}
+
+type countingConn struct {
+	net.Conn
+	t  *Tracker
+	id string
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.t.addBytes(c.id, n, 0)
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.t.addBytes(c.id, 0, n)
+	return n, err
+}
+
+// TrackFullAPI returns a FullNode API accounting the calls made through it
+// to the token of the request handled by the Handler of t.
+func TrackFullAPI(a api.FullNode, t *Tracker) api.FullNode {
+	var out apistruct.FullNodeStruct
+	proxy(t, a, &out.Internal)
+	proxy(t, a, &out.CommonStruct.Internal)
+	return &out
+}
+
+// TrackStorageMinerAPI is like TrackFullAPI for the StorageMiner API.
+func TrackStorageMinerAPI(a api.StorageMiner, t *Tracker) api.StorageMiner {
+	var out apistruct.StorageMinerStruct
+	proxy(t, a, &out.Internal)
+	proxy(t, a, &out.CommonStruct.Internal)
+	return &out
+}
+
+func proxy(t *Tracker, in interface{}, out interface{}) {
+	apistruct.Proxy(in, out, func(m apistruct.ProxyMethod, next apistruct.ProxyCall) apistruct.ProxyCall {
+		return func(args []reflect.Value) []reflect.Value {
+			id, ok := TokenIDFromContext(args[0].Interface().(context.Context))
+			if ok {
+				defer t.addCall(id, time.Now())
+			}
+			return next(args)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package apiusage
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestTracker(t *testing.T) {
+	var node apistruct.FullNodeStruct
+	node.Internal.StateNetworkVersion = func(ctx context.Context, tsk types.TipSetKey) (network.Version, error) {
+		return network.Version4, nil
+	}
+
+	tr := NewTracker()
+	a := TrackFullAPI(&node, tr)
+
+	verify := func(ctx context.Context, token string) ([]auth.Permission, error) {
+		if token != "good" {
+			return nil, xerrors.New("bad token")
+		}
+		return apistruct.AllPermissions, nil
+	}
+
+	h := tr.Handler(verify, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, err = a.StateNetworkVersion(r.Context(), types.EmptyTSK)
+		require.NoError(t, err)
+		_, _ = w.Write([]byte("response"))
+	}))
+
+	for _, token := range []string{"good", "good", "bad"} {
+		req := httptest.NewRequest("POST", "/rpc/v0", strings.NewReader("request"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// calls made without the handler aren't accounted
+	_, err := a.StateNetworkVersion(context.Background(), types.EmptyTSK)
+	require.NoError(t, err)
+
+	usage := tr.Usage()
+	require.Len(t, usage, 2)
+
+	byID := map[string]int{}
+	for i, u := range usage {
+		byID[u.TokenID] = i
+	}
+
+	good := usage[byID[TokenID("good")]]
+	require.Equal(t, uint64(2), good.Calls)
+	require.Equal(t, uint64(2*len("request")), good.BytesIn)
+	require.Equal(t, uint64(2*len("response")), good.BytesOut)
+	require.False(t, good.LastUsed.IsZero())
+
+	// invalid tokens are accounted as anonymous
+	anon := usage[byID[""]]
+	require.Equal(t, uint64(1), anon.Calls)
+}
@@ -7,10 +7,14 @@ import (
 	"path"
 	"time"
 
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+
 	"github.com/filecoin-project/go-jsonrpc"
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/lib/cborrpc"
 	"github.com/filecoin-project/lotus/lib/rpcenc"
 )
 
@@ -108,3 +112,25 @@ func NewWalletRPC(ctx context.Context, addr string, requestHeader http.Header) (
 
 	return &res, closer, err
 }
+
+// NewFullNodeCBORRPC creates a new full node client on the cbor rpc transport
+// listening on the address.
+func NewFullNodeCBORRPC(ctx context.Context, addr multiaddr.Multiaddr, token string) (api.FullNode, jsonrpc.ClientCloser, error) {
+	conn, err := manet.Dial(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res apistruct.FullNodeStruct
+	closer, err := cborrpc.NewMergeClient(ctx, conn, "Filecoin",
+		[]interface{}{
+			&res.CommonStruct.Internal,
+			&res.Internal,
+		}, token)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	return &res, jsonrpc.ClientCloser(closer), nil
+}
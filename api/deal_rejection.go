@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DealRejectionCode classifies why a provider rejected a storage deal.
+type DealRejectionCode string
+
+const (
+	DealRejectedPriceTooLow   DealRejectionCode = "price-too-low"
+	DealRejectedPieceTooLarge DealRejectionCode = "piece-too-large"
+	DealRejectedPieceTooSmall DealRejectionCode = "piece-too-small"
+	DealRejectedFilter        DealRejectionCode = "filter-rejected"
+	DealRejectedOutOfSpace    DealRejectionCode = "out-of-space"
+	DealRejectedNotAccepting  DealRejectionCode = "not-accepting"
+	DealRejectedBadStartEpoch DealRejectionCode = "bad-start-epoch"
+	DealRejectedBlocklisted   DealRejectionCode = "piece-blocklisted"
+	DealRejectedClientQuota   DealRejectionCode = "client-quota"
+	DealRejectedUnauthorized  DealRejectionCode = "client-unauthorized"
+	// DealRejectedOther is used for rejections without a recognisable code
+	DealRejectedOther DealRejectionCode = "other"
+)
+
+var dealRejectionCodes = []DealRejectionCode{
+	DealRejectedPriceTooLow,
+	DealRejectedPieceTooLarge,
+	DealRejectedPieceTooSmall,
+	DealRejectedFilter,
+	DealRejectedOutOfSpace,
+	DealRejectedNotAccepting,
+	DealRejectedBadStartEpoch,
+	DealRejectedBlocklisted,
+	DealRejectedClientQuota,
+	DealRejectedUnauthorized,
+}
+
+// checks done by the markets provider before the deal filter runs
+var marketsDealRejections = []struct {
+	match string
+	code  DealRejectionCode
+}{
+	{"storage price per epoch less than asking price", DealRejectedPriceTooLow},
+	{"piece size more than maximum", DealRejectedPieceTooLarge},
+	{"piece size less than minimum", DealRejectedPieceTooSmall},
+	{"custom deal decision logic", DealRejectedFilter},
+}
+
+// DealRejection is the reason a provider gave for rejecting a storage deal.
+type DealRejection struct {
+	Code   DealRejectionCode
+	Reason string
+}
+
+// FormatDealRejection formats the reason for rejecting a deal so that clients
+// can recover the code with ParseDealRejection. The reason travels to the
+// client as part of the deal's message.
+func FormatDealRejection(code DealRejectionCode, reason string) string {
+	return fmt.Sprintf("[%s] %s", code, reason)
+}
+
+// ParseDealRejection extracts the rejection from a deal message. It returns
+// nil if the message doesn't describe a rejection.
+func ParseDealRejection(msg string) *DealRejection {
+	for _, code := range dealRejectionCodes {
+		tag := "[" + string(code) + "] "
+		if i := strings.Index(msg, tag); i >= 0 {
+			return &DealRejection{Code: code, Reason: msg[i+len(tag):]}
+		}
+	}
+
+	for _, r := range marketsDealRejections {
+		if i := strings.Index(msg, r.match); i >= 0 {
+			return &DealRejection{Code: r.code, Reason: msg[i:]}
+		}
+	}
+
+	if strings.Contains(strings.ToLower(msg), "rejected") {
+		return &DealRejection{Code: DealRejectedOther, Reason: msg}
+	}
+
+	return nil
+}
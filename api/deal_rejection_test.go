@@ -0,0 +1,32 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDealRejection(t *testing.T) {
+	for msg, exp := range map[string]*DealRejection{
+		"":               nil,
+		"deal published": nil,
+		"deal rejected: storage price per epoch less than asking price: 1 < 2": {
+			Code:   DealRejectedPriceTooLow,
+			Reason: "storage price per epoch less than asking price: 1 < 2",
+		},
+		"deal rejected: deal rejected via custom deal decision logic: " + FormatDealRejection(DealRejectedClientQuota, "too many deals"): {
+			Code:   DealRejectedClientQuota,
+			Reason: "too many deals",
+		},
+		"deal rejected: deal rejected via custom deal decision logic: not today": {
+			Code:   DealRejectedFilter,
+			Reason: "custom deal decision logic: not today",
+		},
+		"proposal rejected: unknown": {
+			Code:   DealRejectedOther,
+			Reason: "proposal rejected: unknown",
+		},
+	} {
+		require.Equal(t, exp, ParseDealRejection(msg), msg)
+	}
+}
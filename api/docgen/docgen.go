@@ -58,6 +58,7 @@ func init() {
 	}
 
 	ExampleValues[reflect.TypeOf(c)] = c
+	addExample([]cid.Cid{c})
 
 	c2, err := cid.Decode("bafy2bzacebp3shtrn43k7g3unredz7fxn4gj533d3o43tqn2p2ipxxhrvchve")
 	if err != nil {
@@ -74,6 +75,7 @@ func init() {
 	}
 
 	ExampleValues[reflect.TypeOf(addr)] = addr
+	addExample([]address.Address{addr})
 
 	pid, err := peer.Decode("12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf")
 	if err != nil {
@@ -103,6 +105,8 @@ func init() {
 	addExample(abi.SectorNumber(9))
 	addExample(abi.SectorSize(32 * 1024 * 1024 * 1024))
 	addExample(api.MpoolChange(0))
+	addExample(api.MpoolRemoveNone)
+	addExample(api.ScheduledPending)
 	addExample(network.Connected)
 	addExample(dtypes.NetworkName("lotus"))
 	addExample(api.SyncStateStage(1))
@@ -120,6 +124,15 @@ func init() {
 		Msg:    exampleValue(reflect.TypeOf(&types.Message{}), nil).(*types.Message),
 		MsgRct: exampleValue(reflect.TypeOf(&types.MessageReceipt{}), nil).(*types.MessageReceipt),
 	})
+	addExample([]*types.Message{
+		exampleValue(reflect.TypeOf(&types.Message{}), nil).(*types.Message),
+	})
+	addExample([]*types.SignedMessage{
+		exampleValue(reflect.TypeOf(&types.SignedMessage{}), nil).(*types.SignedMessage),
+	})
+	addExample([]api.TokenUsage{
+		exampleValue(reflect.TypeOf(api.TokenUsage{}), nil).(api.TokenUsage),
+	})
 	addExample(map[string]types.Actor{
 		"t01236": exampleValue(reflect.TypeOf(types.Actor{}), nil).(types.Actor),
 	})
@@ -129,6 +142,9 @@ func init() {
 	addExample(map[string]api.MarketBalance{
 		"t026363": exampleValue(reflect.TypeOf(api.MarketBalance{}), nil).(api.MarketBalance),
 	})
+	addExample(map[string]cid.Cid{
+		"storageminer": c,
+	})
 	addExample(map[string]*pubsub.TopicScoreSnapshot{
 		"/blocks": {
 			TimeInMesh:               time.Minute,
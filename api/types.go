@@ -3,8 +3,10 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/ipfs/go-cid"
@@ -47,6 +49,67 @@ type PubsubScore struct {
 	Score *pubsub.PeerScoreSnapshot
 }
 
+// TokenUsage is the API usage of a JWT token since the node started
+type TokenUsage struct {
+	// TokenID identifies the token without revealing it, see apiusage.TokenID;
+	// it is empty for requests without a valid token
+	TokenID string
+
+	Calls    uint64
+	BytesIn  uint64
+	BytesOut uint64
+	// ExecTime is the cumulative execution time of the calls
+	ExecTime time.Duration
+	LastUsed time.Time
+}
+
+// APIConnection is a websocket connection to the API
+type APIConnection struct {
+	ID         uint64
+	RemoteAddr string
+	// TokenID identifies the token of the connection, see TokenUsage
+	TokenID       string
+	Opened        time.Time
+	Subscriptions []APISubscription
+}
+
+// APISubscription is a call of a method returning a channel, made on an
+// APIConnection
+type APISubscription struct {
+	ID      uint64
+	Method  string
+	Started time.Time
+	// Buffered is the number of values waiting for the consumer, and Dropped
+	// the number of values dropped when the buffer was full
+	Buffered int
+	Dropped  uint64
+}
+
+// APICapabilities describes the API of a node, for clients to find out what
+// they can call
+type APICapabilities struct {
+	// Version and APIVersion are the ones of Version, APIVersion split in
+	// Major, Minor and Patch
+	Version    string
+	APIVersion build.Version
+	Major      uint32
+	Minor      uint32
+	Patch      uint32
+
+	// Methods are the methods of the API, without the Filecoin. namespace
+	Methods []APIMethod
+	// Permissions are the permissions of the token of the call
+	Permissions []auth.Permission
+}
+
+type APIMethod struct {
+	Name string
+	// Perm is the permission required to call the method, and Allowed whether
+	// the token of the call has it
+	Perm    auth.Permission
+	Allowed bool
+}
+
 type MessageSendSpec struct {
 	MaxFee abi.TokenAmount
 }
@@ -64,6 +127,14 @@ func (ms *MessageSendSpec) Get() MessageSendSpec {
 	return *ms
 }
 
+type MpoolReplaceSpec struct {
+	GasLimit   int64
+	GasPremium abi.TokenAmount
+	GasFeeCap  abi.TokenAmount
+	// MaxFee bounds the fee of the replacement when its fee cap is estimated
+	MaxFee abi.TokenAmount
+}
+
 type DataTransferChannel struct {
 	TransferID  datatransfer.TransferID
 	Status      datatransfer.Status
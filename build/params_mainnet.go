@@ -11,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 
 	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
 )
@@ -20,6 +21,10 @@ var DrandSchedule = map[abi.ChainEpoch]DrandEnum{
 	UpgradeSmokeHeight: DrandMainnet,
 }
 
+// BuiltinCheckpoints are the checkpoints of the network enforced by default;
+// more can be added in the Sync.Checkpoints config.
+var BuiltinCheckpoints []dtypes.Checkpoint
+
 const UpgradeBreezeHeight = 41280
 const BreezeGasTampingDuration = 120
 
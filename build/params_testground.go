@@ -15,6 +15,7 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/builtin"
 
 	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 var (
@@ -91,6 +92,8 @@ var (
 		0: DrandMainnet,
 	}
 
+	BuiltinCheckpoints []dtypes.Checkpoint
+
 	NewestNetworkVersion       = network.Version5
 	ActorUpgradeNetworkVersion = network.Version4
 
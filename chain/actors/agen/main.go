@@ -0,0 +1,107 @@
+// agen generates the version specific wrappers of the actor states from
+// templates, see the README of chain/actors/builtin.
+//
+// It's meant to be run from the directory of the actor packages, with
+// go generate. Each template named like vN.go.template produces a file per
+// actors version, named with N replaced by the version (v0.go, v2.go, ...).
+// The template of a file may be overridden for a version by defining the
+// blocks it declares in a delta file named after the produced file, with a
+// .delta suffix (v0.go.delta).
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// versions are the supported versions of specs-actors, with the import path
+// of their actors packages.
+var versions = []struct {
+	Version int
+	Import  string
+}{
+	{0, "github.com/filecoin-project/specs-actors/actors/"},
+	{2, "github.com/filecoin-project/specs-actors/v2/actors/"},
+}
+
+const header = "// Code generated by chain/actors/agen. DO NOT EDIT.\n\n"
+
+func main() {
+	dir := "."
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	templates, err := filepath.Glob(filepath.Join(dir, "*", "*N.go.template"))
+	if err != nil {
+		fail(err)
+	}
+	if len(templates) == 0 {
+		fail(fmt.Errorf("no templates found in %s", dir))
+	}
+
+	for _, path := range templates {
+		if err := generate(path); err != nil {
+			fail(fmt.Errorf("%s: %w", path, err))
+		}
+	}
+}
+
+func generate(path string) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	base, err := template.New(filepath.Base(path)).Parse(string(src))
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		out := strings.Replace(strings.TrimSuffix(path, ".template"), "N.go", fmt.Sprintf("%d.go", v.Version), 1)
+
+		t, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		delta, err := ioutil.ReadFile(out + ".delta")
+		switch {
+		case err == nil:
+			if _, err := t.Parse(string(delta)); err != nil {
+				return fmt.Errorf("parsing delta of version %d: %w", v.Version, err)
+			}
+		case !os.IsNotExist(err):
+			return err
+		}
+
+		var b bytes.Buffer
+		b.WriteString(header)
+		if err := t.Execute(&b, map[string]interface{}{
+			"v":      v.Version,
+			"import": v.Import,
+		}); err != nil {
+			return fmt.Errorf("executing for version %d: %w", v.Version, err)
+		}
+
+		code, err := format.Source(b.Bytes())
+		if err != nil {
+			return fmt.Errorf("formatting version %d: %w", v.Version, err)
+		}
+		if err := ioutil.WriteFile(out, code, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/cbor"
 
+	"github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/actors/adt"
 	"github.com/filecoin-project/lotus/chain/types"
 
@@ -20,6 +21,8 @@ import (
 	smoothing0 "github.com/filecoin-project/specs-actors/actors/util/smoothing"
 )
 
+//go:generate go run ../agen
+
 var SystemActorAddr = builtin0.SystemActorAddr
 var BurntFundsActorAddr = builtin0.BurntFundsActorAddr
 var CronActorAddr = builtin0.CronActorAddr
@@ -93,6 +96,43 @@ func IsPaymentChannelActor(c cid.Cid) bool {
 	return c == builtin0.PaymentChannelActorCodeID || c == builtin2.PaymentChannelActorCodeID
 }
 
+// ActorCodeCIDs returns the code CIDs of the builtin actors of the actors
+// version, keyed by actor name.
+func ActorCodeCIDs(av actors.Version) (map[string]cid.Cid, error) {
+	switch av {
+	case actors.Version0:
+		return map[string]cid.Cid{
+			"system":           builtin0.SystemActorCodeID,
+			"init":             builtin0.InitActorCodeID,
+			"cron":             builtin0.CronActorCodeID,
+			"account":          builtin0.AccountActorCodeID,
+			"storagepower":     builtin0.StoragePowerActorCodeID,
+			"storageminer":     builtin0.StorageMinerActorCodeID,
+			"storagemarket":    builtin0.StorageMarketActorCodeID,
+			"paymentchannel":   builtin0.PaymentChannelActorCodeID,
+			"multisig":         builtin0.MultisigActorCodeID,
+			"reward":           builtin0.RewardActorCodeID,
+			"verifiedregistry": builtin0.VerifiedRegistryActorCodeID,
+		}, nil
+	case actors.Version2:
+		return map[string]cid.Cid{
+			"system":           builtin2.SystemActorCodeID,
+			"init":             builtin2.InitActorCodeID,
+			"cron":             builtin2.CronActorCodeID,
+			"account":          builtin2.AccountActorCodeID,
+			"storagepower":     builtin2.StoragePowerActorCodeID,
+			"storageminer":     builtin2.StorageMinerActorCodeID,
+			"storagemarket":    builtin2.StorageMarketActorCodeID,
+			"paymentchannel":   builtin2.PaymentChannelActorCodeID,
+			"multisig":         builtin2.MultisigActorCodeID,
+			"reward":           builtin2.RewardActorCodeID,
+			"verifiedregistry": builtin2.VerifiedRegistryActorCodeID,
+		}, nil
+	default:
+		return nil, xerrors.Errorf("unknown actors version %d", av)
+	}
+}
+
 func makeAddress(addr string) address.Address {
 	ret, err := address.NewFromString(addr)
 	if err != nil {
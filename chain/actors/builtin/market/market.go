@@ -45,6 +45,8 @@ type State interface {
 	EscrowTable() (BalanceTable, error)
 	LockedTable() (BalanceTable, error)
 	TotalLocked() (abi.TokenAmount, error)
+	// NextID is the ID of the next deal published; the deals have lower IDs
+	NextID() (abi.DealID, error)
 	StatesChanged(State) (bool, error)
 	States() (DealStates, error)
 	ProposalsChanged(State) (bool, error)
@@ -119,6 +121,13 @@ type DealStateChange struct {
 	To   *DealState
 }
 
+// DealProposalChange is a change in deal proposal from -> to
+type DealProposalChange struct {
+	ID   abi.DealID
+	From *DealProposal
+	To   *DealProposal
+}
+
 type DealProposalChanges struct {
 	Added   []ProposalIDState
 	Removed []ProposalIDState
@@ -89,6 +89,9 @@ type Deadline interface {
 	LoadPartition(idx uint64) (Partition, error)
 	ForEachPartition(cb func(idx uint64, part Partition) error) error
 	PostSubmissions() (bitfield.BitField, error)
+	// EarlyTerminations returns the partitions with early terminated sectors
+	// whose termination fees are yet to be processed.
+	EarlyTerminations() (bitfield.BitField, error)
 
 	PartitionsChanged(Deadline) (bool, error)
 }
@@ -99,6 +102,11 @@ type Partition interface {
 	RecoveringSectors() (bitfield.BitField, error)
 	LiveSectors() (bitfield.BitField, error)
 	ActiveSectors() (bitfield.BitField, error)
+
+	// ForEachExpiration calls cb with the sectors of the partition expiring
+	// at each epoch of its expiration queue, in order: the sectors expiring
+	// on time, and the faulty sectors expiring early.
+	ForEachExpiration(cb func(epoch abi.ChainEpoch, onTime, early bitfield.BitField) error) error
 }
 
 type SectorOnChainInfo struct {
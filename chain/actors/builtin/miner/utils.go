@@ -4,6 +4,8 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/dline"
 )
 
 func AllPartSectors(mas State, sget func(Partition) (bitfield.BitField, error)) (bitfield.BitField, error) {
@@ -26,3 +28,71 @@ func AllPartSectors(mas State, sget func(Partition) (bitfield.BitField, error))
 
 	return bitfield.MultiMerge(parts...)
 }
+
+// DeadlineIsMutable returns whether the partitions of the deadline can be
+// changed at the epoch. The miner actor refuses changes like compacting
+// partitions while the deadline is open, and during the challenge window
+// before it opens.
+func DeadlineIsMutable(provingPeriodStart abi.ChainEpoch, dlIdx uint64, currentEpoch abi.ChainEpoch) bool {
+	di := dline.NewInfo(provingPeriodStart, dlIdx, currentEpoch, WPoStPeriodDeadlines, WPoStProvingPeriod, WPoStChallengeWindow, WPoStChallengeLookback, FaultDeclarationCutoff).NextNotElapsed()
+	return currentEpoch < di.Open-WPoStChallengeWindow
+}
+
+// CompactablePartitions returns the partitions of the deadline worth
+// compacting: the ones with terminated sectors, except the ones with faults,
+// which the miner actor refuses to compact. Deadlines with early terminations
+// to process can't be compacted at all.
+func CompactablePartitions(dl Deadline) ([]uint64, error) {
+	et, err := dl.EarlyTerminations()
+	if err != nil {
+		return nil, xerrors.Errorf("getting early terminations: %w", err)
+	}
+	empty, err := et.IsEmpty()
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		return nil, xerrors.Errorf("the deadline has early terminations to process")
+	}
+
+	var out []uint64
+	err = dl.ForEachPartition(func(idx uint64, part Partition) error {
+		faults, err := part.FaultySectors()
+		if err != nil {
+			return xerrors.Errorf("getting faulty sectors (part %d): %w", idx, err)
+		}
+		nfaults, err := faults.Count()
+		if err != nil {
+			return err
+		}
+		if nfaults > 0 {
+			return nil
+		}
+
+		all, err := part.AllSectors()
+		if err != nil {
+			return xerrors.Errorf("getting sectors (part %d): %w", idx, err)
+		}
+		live, err := part.LiveSectors()
+		if err != nil {
+			return xerrors.Errorf("getting live sectors (part %d): %w", idx, err)
+		}
+		nall, err := all.Count()
+		if err != nil {
+			return err
+		}
+		nlive, err := live.Count()
+		if err != nil {
+			return err
+		}
+		if nall > nlive {
+			out = append(out, idx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -12,6 +12,7 @@ import (
 	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	miner2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/miner"
 	verifreg2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/verifreg"
+	"golang.org/x/xerrors"
 )
 
 const (
@@ -82,6 +83,22 @@ func GetMaxProveCommitDuration(ver actors.Version, t abi.RegisteredSealProof) ab
 	}
 }
 
+// GetMaxSectorExpirationExtension returns the most the expiration of a sector
+// can be after its activation.
+func GetMaxSectorExpirationExtension() abi.ChainEpoch {
+	return miner2.MaxSectorExpirationExtension
+}
+
+// GetSectorMaxLifetime returns the most the expiration of a sector sealed with
+// the proof can be after its activation.
+func GetSectorMaxLifetime(proof abi.RegisteredSealProof) (abi.ChainEpoch, error) {
+	p, ok := builtin2.SealProofPolicies[proof]
+	if !ok {
+		return 0, xerrors.Errorf("unsupported seal proof type %d", proof)
+	}
+	return p.SectorMaxLifetime, nil
+}
+
 func DealProviderCollateralBounds(
 	size abi.PaddedPieceSize, verified bool,
 	rawBytePower, qaPower, baselinePower abi.StoragePower,
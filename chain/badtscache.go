@@ -64,6 +64,18 @@ func (bts *BadBlockCache) Purge() {
 	bts.badBlocks.Purge()
 }
 
+// Entries returns the blocks in the cache with the reasons they were marked
+// bad, without affecting their eviction order.
+func (bts *BadBlockCache) Entries() map[cid.Cid]BadBlockReason {
+	out := map[cid.Cid]BadBlockReason{}
+	for _, k := range bts.badBlocks.Keys() {
+		if v, ok := bts.badBlocks.Peek(k); ok {
+			out[k.(cid.Cid)] = v.(BadBlockReason)
+		}
+	}
+	return out
+}
+
 func (bts *BadBlockCache) Has(c cid.Cid) (BadBlockReason, bool) {
 	rval, ok := bts.badBlocks.Get(c)
 	if !ok {
@@ -3,6 +3,8 @@ package drand
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"strconv"
 	"sync"
 	"time"
 
@@ -16,6 +18,8 @@ import (
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/xerrors"
 
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
 	logging "github.com/ipfs/go-log"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 
@@ -63,16 +67,21 @@ type DrandBeacon struct {
 
 	cacheLk    sync.Mutex
 	localCache map[uint64]types.BeaconEntry
+
+	// keeps the verified entries across restarts, nil if they aren't kept
+	ds datastore.Datastore
 }
 
-func NewDrandBeacon(genesisTs, interval uint64, ps *pubsub.PubSub, config dtypes.DrandConfig) (*DrandBeacon, error) {
+// NewDrandBeacon creates a beacon for the drand chain of the config. Entries
+// are persisted under the hash of the drand chain in ds, unless it is nil.
+func NewDrandBeacon(genesisTs, interval uint64, ps *pubsub.PubSub, config dtypes.DrandConfig, ds datastore.Datastore) (*DrandBeacon, error) {
 	if genesisTs == 0 {
 		panic("what are you doing this cant be zero")
 	}
 
-	drandChain, err := dchain.InfoFromJSON(bytes.NewReader([]byte(config.ChainInfoJSON)))
+	drandChain, err := ChainInfo(config)
 	if err != nil {
-		return nil, xerrors.Errorf("unable to unmarshal drand chain info: %w", err)
+		return nil, err
 	}
 
 	dlogger := dlog.NewKitLoggerFrom(kzap.NewZapSugarLogger(
@@ -110,6 +119,9 @@ func NewDrandBeacon(genesisTs, interval uint64, ps *pubsub.PubSub, config dtypes
 		client:     client,
 		localCache: make(map[uint64]types.BeaconEntry),
 	}
+	if ds != nil {
+		db.ds = namespace.Wrap(ds, datastore.NewKey("/drand/"+hex.EncodeToString(drandChain.Hash())))
+	}
 
 	db.pubkey = drandChain.PublicKey
 	db.interval = drandChain.Period
@@ -120,6 +132,15 @@ func NewDrandBeacon(genesisTs, interval uint64, ps *pubsub.PubSub, config dtypes
 	return db, nil
 }
 
+// ChainInfo returns the info of the drand chain of the config.
+func ChainInfo(config dtypes.DrandConfig) (*dchain.Info, error) {
+	info, err := dchain.InfoFromJSON(bytes.NewReader([]byte(config.ChainInfoJSON)))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal drand chain info: %w", err)
+	}
+	return info, nil
+}
+
 func (db *DrandBeacon) Entry(ctx context.Context, round uint64) <-chan beacon.Response {
 	out := make(chan beacon.Response, 1)
 	if round != 0 {
@@ -142,6 +163,8 @@ func (db *DrandBeacon) Entry(ctx context.Context, round uint64) <-chan beacon.Re
 		} else {
 			br.Entry.Round = resp.Round()
 			br.Entry.Data = resp.Signature()
+			// the client verified the entry against the chain info
+			db.cacheValue(br.Entry)
 		}
 		log.Infow("done fetching randomness", "round", round, "took", build.Clock.Since(start))
 		out <- br
@@ -152,20 +175,46 @@ func (db *DrandBeacon) Entry(ctx context.Context, round uint64) <-chan beacon.Re
 }
 func (db *DrandBeacon) cacheValue(e types.BeaconEntry) {
 	db.cacheLk.Lock()
-	defer db.cacheLk.Unlock()
 	db.localCache[e.Round] = e
+	db.cacheLk.Unlock()
+
+	if db.ds != nil {
+		if err := db.ds.Put(roundKey(e.Round), e.Data); err != nil {
+			log.Warnw("persisting beacon entry", "round", e.Round, "error", err)
+		}
+	}
 }
 
 func (db *DrandBeacon) getCachedValue(round uint64) *types.BeaconEntry {
 	db.cacheLk.Lock()
-	defer db.cacheLk.Unlock()
 	v, ok := db.localCache[round]
-	if !ok {
+	db.cacheLk.Unlock()
+	if ok {
+		return &v
+	}
+
+	if db.ds == nil {
+		return nil
+	}
+	data, err := db.ds.Get(roundKey(round))
+	if err != nil {
+		if err != datastore.ErrNotFound {
+			log.Warnw("loading persisted beacon entry", "round", round, "error", err)
+		}
 		return nil
 	}
+
+	v = types.BeaconEntry{Round: round, Data: data}
+	db.cacheLk.Lock()
+	db.localCache[round] = v
+	db.cacheLk.Unlock()
 	return &v
 }
 
+func roundKey(round uint64) datastore.Key {
+	return datastore.NewKey(strconv.FormatUint(round, 10))
+}
+
 func (db *DrandBeacon) VerifyEntry(curr types.BeaconEntry, prev types.BeaconEntry) error {
 	if prev.Round == 0 {
 		// TODO handle genesis better
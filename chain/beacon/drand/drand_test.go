@@ -6,9 +6,12 @@ import (
 
 	dchain "github.com/drand/drand/chain"
 	hclient "github.com/drand/drand/client/http"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
 )
 
 func TestPrintGroupInfo(t *testing.T) {
@@ -23,3 +26,18 @@ func TestPrintGroupInfo(t *testing.T) {
 	err = chain.ToJSON(os.Stdout)
 	assert.NoError(t, err)
 }
+
+func TestPersistedEntries(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	db := &DrandBeacon{localCache: map[uint64]types.BeaconEntry{}, ds: ds}
+	db.cacheValue(types.BeaconEntry{Round: 5, Data: []byte{1, 2, 3}})
+
+	// a restarted node loads the entry instead of fetching it again
+	restarted := &DrandBeacon{localCache: map[uint64]types.BeaconEntry{}, ds: ds}
+	be := restarted.getCachedValue(5)
+	if assert.NotNil(t, be) {
+		assert.Equal(t, types.BeaconEntry{Round: 5, Data: []byte{1, 2, 3}}, *be)
+	}
+	assert.Nil(t, restarted.getCachedValue(6))
+}
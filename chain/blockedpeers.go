@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// blockedPeers are the peers the syncer ignores the heads of, e.g. because
+// they keep serving a bad fork. The list isn't persisted.
+type blockedPeers struct {
+	lk    sync.Mutex
+	peers map[peer.ID]api.BlockedPeer
+}
+
+func newBlockedPeers() *blockedPeers {
+	return &blockedPeers{peers: map[peer.ID]api.BlockedPeer{}}
+}
+
+func (bp *blockedPeers) get(p peer.ID) (api.BlockedPeer, bool) {
+	bp.lk.Lock()
+	defer bp.lk.Unlock()
+	b, ok := bp.peers[p]
+	return b, ok
+}
+
+// BlockPeer stops the syncer from syncing to the heads of the peer and from
+// requesting chain data from it, until it is unblocked.
+func (syncer *Syncer) BlockPeer(p peer.ID, reason string) {
+	syncer.blocked.lk.Lock()
+	syncer.blocked.peers[p] = api.BlockedPeer{
+		ID:     p,
+		Reason: reason,
+		Since:  time.Now(),
+	}
+	syncer.blocked.lk.Unlock()
+
+	syncer.Exchange.RemovePeer(p)
+}
+
+// UnblockPeer lets the syncer sync from the peer again. It returns whether the
+// peer was blocked. The peer is used again once it informs the syncer of its
+// head.
+func (syncer *Syncer) UnblockPeer(p peer.ID) bool {
+	syncer.blocked.lk.Lock()
+	defer syncer.blocked.lk.Unlock()
+
+	_, ok := syncer.blocked.peers[p]
+	delete(syncer.blocked.peers, p)
+	return ok
+}
+
+// IsPeerBlocked returns whether the syncer ignores the peer.
+func (syncer *Syncer) IsPeerBlocked(p peer.ID) bool {
+	_, ok := syncer.blocked.get(p)
+	return ok
+}
+
+// BlockedPeers returns the blocked peers, the earliest blocked first.
+func (syncer *Syncer) BlockedPeers() []api.BlockedPeer {
+	syncer.blocked.lk.Lock()
+	out := make([]api.BlockedPeer, 0, len(syncer.blocked.peers))
+	for _, b := range syncer.blocked.peers {
+		out = append(out, b)
+	}
+	syncer.blocked.lk.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Since.Before(out[j].Since)
+	})
+	return out
+}
@@ -1,10 +1,13 @@
 package chain
 
 import (
+	"context"
 	"encoding/json"
+	"sort"
 
 	"github.com/filecoin-project/lotus/chain/types"
 
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/ipfs/go-datastore"
 	"golang.org/x/xerrors"
@@ -79,3 +82,117 @@ func (syncer *Syncer) GetCheckpoint() types.TipSetKey {
 	defer syncer.checkptLk.Unlock()
 	return syncer.checkpt
 }
+
+// ErrStaticCheckpoint is returned when a chain doesn't include a checkpoint
+// set with SetStaticCheckpoints.
+var ErrStaticCheckpoint = xerrors.New("chain conflicts with a static checkpoint")
+
+// SetStaticCheckpoints sets the tipsets which every synced chain must include
+// at their heights. Unlike the checkpoint set with SetCheckpoint, the tipsets
+// don't have to be synced yet, so they also protect the initial sync.
+func (syncer *Syncer) SetStaticCheckpoints(cps []dtypes.Checkpoint) error {
+	static := make([]staticCheckpoint, 0, len(cps))
+	for _, cp := range cps {
+		if len(cp.Blocks) == 0 {
+			return xerrors.Errorf("checkpoint at height %d has no blocks", cp.Height)
+		}
+		static = append(static, staticCheckpoint{
+			height: cp.Height,
+			key:    types.NewTipSetKey(cp.Blocks...),
+		})
+	}
+
+	syncer.checkptLk.Lock()
+	trusted := syncer.trusted
+	if trusted != nil {
+		static = append(static, *trusted)
+	}
+	sort.Slice(static, func(i, j int) bool {
+		return static[i].height < static[j].height
+	})
+	syncer.static = static
+	syncer.checkptLk.Unlock()
+
+	// the local chain may predate the checkpoints
+	head := syncer.ChainStore().GetHeaviestTipSet()
+	if head == nil {
+		return nil
+	}
+	for _, cp := range static {
+		if cp.height > head.Height() {
+			break
+		}
+		if trusted != nil && cp.height < trusted.height {
+			// the chain below the trusted checkpoint may not be local
+			continue
+		}
+		ts, err := syncer.ChainStore().GetTipsetByHeight(context.TODO(), cp.height, head, false)
+		if err != nil {
+			return xerrors.Errorf("loading local tipset at checkpoint height %d: %w", cp.height, err)
+		}
+		if ts.Key() != cp.key {
+			log.Errorw("the local chain conflicts with a checkpoint, it needs to be resynced", "height", cp.height,
+				"checkpoint", cp.key, "local", ts.Key())
+		}
+	}
+
+	return nil
+}
+
+type staticCheckpoint struct {
+	height abi.ChainEpoch
+	key    types.TipSetKey
+}
+
+// checkStaticCheckpoints checks that the chain segment, ordered from the head
+// down, includes the static checkpoints at the heights it covers.
+func (syncer *Syncer) checkStaticCheckpoints(chain []*types.TipSet) error {
+	syncer.checkptLk.Lock()
+	static := syncer.static
+	syncer.checkptLk.Unlock()
+
+	if len(static) == 0 || len(chain) == 0 {
+		return nil
+	}
+
+	// the parent of the segment is on the local chain
+	last := chain[len(chain)-1]
+	floor := abi.ChainEpoch(-1)
+	if last.Height() > 0 {
+		pts, err := syncer.store.LoadTipSet(last.Parents())
+		if err != nil {
+			return xerrors.Errorf("loading parent of synced chain: %w", err)
+		}
+		floor = pts.Height()
+	}
+
+	return checkCheckpoints(static, chain, floor)
+}
+
+// checkCheckpoints checks the chain segment against the sorted checkpoints;
+// floor is the height of the parent of the last tipset in the segment.
+func checkCheckpoints(static []staticCheckpoint, chain []*types.TipSet, floor abi.ChainEpoch) error {
+	for i, ts := range chain {
+		parentHeight := floor
+		if i+1 < len(chain) {
+			parentHeight = chain[i+1].Height()
+		}
+
+		for _, cp := range static {
+			if cp.height <= parentHeight {
+				continue
+			}
+			if cp.height > ts.Height() {
+				break
+			}
+
+			// the checkpoint is at or below ts, and above its parent
+			if ts.Height() != cp.height || ts.Key() != cp.key {
+				return xerrors.Errorf("tipset %s at height %d: %w (expected %s at height %d)", ts.Key(), ts.Height(),
+					ErrStaticCheckpoint, cp.key, cp.height)
+			}
+		}
+	}
+
+	return nil
+}
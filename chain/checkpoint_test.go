@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+func TestCheckCheckpoints(t *testing.T) {
+	// build a chain with a null round at height 3, ordered from the head down
+	mkChain := func(nonce uint64) []*types.TipSet {
+		var chain []*types.TipSet
+		cur := genTs
+		for i := 0; i < 5; i++ {
+			blk := mock.MkBlock(cur, 1, nonce)
+			if i == 2 {
+				blk.Height++
+			}
+			cur = mock.TipSet(blk)
+			chain = append([]*types.TipSet{cur}, chain...)
+		}
+		return chain
+	}
+
+	a := mkChain(1)
+	b := mkChain(2)
+	require.Equal(t, []int64{6, 5, 4, 2, 1}, heights(a))
+
+	at := func(chain []*types.TipSet, i int) staticCheckpoint {
+		return staticCheckpoint{height: chain[i].Height(), key: chain[i].Key()}
+	}
+
+	check := func(static []staticCheckpoint, chain []*types.TipSet) error {
+		return checkCheckpoints(static, chain, 0)
+	}
+
+	require.NoError(t, check(nil, a))
+	require.NoError(t, check([]staticCheckpoint{at(a, 3), at(a, 1)}, a))
+
+	err := check([]staticCheckpoint{at(a, 1)}, b)
+	require.True(t, xerrors.Is(err, ErrStaticCheckpoint), err)
+
+	// a checkpoint at the null round conflicts with the chain
+	err = check([]staticCheckpoint{{height: 3, key: a[2].Key()}}, a)
+	require.True(t, xerrors.Is(err, ErrStaticCheckpoint), err)
+
+	// checkpoints outside of the segment are ignored
+	require.NoError(t, check([]staticCheckpoint{at(a, 0)}, b[1:]))
+	require.NoError(t, checkCheckpoints([]staticCheckpoint{at(a, 4)}, b[:2], 4))
+}
+
+func heights(chain []*types.TipSet) []int64 {
+	out := make([]int64, len(chain))
+	for i, ts := range chain {
+		out[i] = int64(ts.Height())
+	}
+	return out
+}
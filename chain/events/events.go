@@ -51,11 +51,22 @@ type Events struct {
 	ready     sync.WaitGroup
 	readyOnce sync.Once
 
+	// set when the events are resumed from a cursor, see NewEventsWithCursor
+	cursor       *eventsCursor
+	cursorLoaded bool
+	missed       []*types.TipSet
+	resumed      chan struct{}
+	resumeOnce   sync.Once
+
 	heightEvents
 	*hcEvents
 }
 
 func NewEvents(ctx context.Context, api eventAPI) *Events {
+	return newEvents(ctx, api, nil)
+}
+
+func newEvents(ctx context.Context, api eventAPI, cursor *eventsCursor) *Events {
 	gcConfidence := 2 * build.ForkLengthThreshold
 
 	tsc := newTSCache(gcConfidence, api)
@@ -78,6 +89,11 @@ func NewEvents(ctx context.Context, api eventAPI) *Events {
 		hcEvents: newHCEvents(ctx, api, tsc, uint64(gcConfidence)),
 	}
 
+	if cursor != nil {
+		e.cursor = cursor
+		e.resumed = make(chan struct{})
+	}
+
 	e.ready.Add(1)
 
 	go e.listenHeadChanges(ctx)
@@ -128,16 +144,38 @@ func (e *Events) listenHeadChangesOnce(ctx context.Context) error {
 		return xerrors.Errorf("expected first head notification type to be 'current', was '%s'", cur[0].Type)
 	}
 
-	if err := e.tsc.add(cur[0].Val); err != nil {
+	first := cur[0].Val
+	if e.cursor != nil && !e.cursorLoaded {
+		e.cursorLoaded = true
+
+		start, missed, err := e.cursor.resume(ctx, e.api, first)
+		if err != nil {
+			log.Errorf("resuming events from cursor, starting from the current head: %s", err)
+		} else {
+			first = start
+			e.missed = missed
+		}
+	}
+
+	if err := e.tsc.add(first); err != nil {
 		log.Warn("tsc.add: adding current tipset failed: %w", err)
 	}
 
 	e.readyOnce.Do(func() {
-		e.lastTs = cur[0].Val
+		e.lastTs = first
 
 		e.ready.Done()
 	})
 
+	if e.resumed != nil {
+		// hold the new head changes until the missed ones are replayed
+		select {
+		case <-e.resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
 	for notif := range notifs {
 		var rev, app []*types.TipSet
 		for _, notif := range notif {
@@ -156,8 +194,8 @@ func (e *Events) listenHeadChangesOnce(ctx context.Context) error {
 		}
 
 		// sync with fake chainstore (for tests)
-		if fcs, ok := e.api.(interface{ notifDone() }); ok {
-			fcs.notifDone()
+		if fcs, ok := e.api.(interface{ NotifDone() }); ok {
+			fcs.NotifDone()
 		}
 	}
 
@@ -176,5 +214,63 @@ func (e *Events) headChange(rev, app []*types.TipSet) error {
 		return err
 	}
 
-	return e.processHeadChangeEvent(rev, app)
+	if err := e.processHeadChangeEvent(rev, app); err != nil {
+		return err
+	}
+
+	if e.cursor != nil {
+		if err := e.cursor.store(e.tsc, app, e.maxConfidence()); err != nil {
+			log.Errorf("storing events cursor: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// maxConfidence returns the highest confidence of the handlers which may
+// still be called.
+func (e *Events) maxConfidence() int {
+	var max int
+
+	e.heightEvents.lk.Lock()
+	for _, h := range e.heightTriggers {
+		if !h.called && h.confidence > max {
+			max = h.confidence
+		}
+	}
+	e.heightEvents.lk.Unlock()
+
+	e.hcEvents.lk.Lock()
+	for _, h := range e.triggers {
+		if !h.disabled && h.confidence > max {
+			max = h.confidence
+		}
+	}
+	e.hcEvents.lk.Unlock()
+
+	return max
+}
+
+// Resume replays the tipsets applied since the cursor of events created with
+// NewEventsWithCursor, and starts processing the new head changes. It must be
+// called once the handlers interested in the missed tipsets are registered;
+// it's a no-op for events without a cursor.
+func (e *Events) Resume() error {
+	if e.cursor == nil {
+		return nil
+	}
+
+	var err error
+	e.resumeOnce.Do(func() {
+		defer close(e.resumed)
+
+		if len(e.missed) == 0 {
+			return
+		}
+
+		log.Infof("replaying %d tipsets applied since the events cursor", len(e.missed))
+		err = e.headChange(nil, e.missed)
+		e.missed = nil
+	})
+	return err
 }
@@ -592,6 +592,11 @@ func (me *messageEvents) Called(check CheckFunc, msgHnd MsgHandler, rev RevertHa
 			panic("expected msg")
 		}
 
+		if msg == nil {
+			// timeout
+			return msgHnd(nil, nil, ts, height)
+		}
+
 		rec, err := me.cs.StateGetReceipt(me.ctx, msg.Cid(), ts.Key())
 		if err != nil {
 			return false, err
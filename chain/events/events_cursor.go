@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// NewEventsWithCursor creates events which persist the last processed tipset
+// in ds under key, so that the handlers registered on them can resume from it
+// after a restart instead of missing the triggers of the tipsets applied while
+// the node was down. The persisted tipset is the highest confidence of the
+// handlers below the head, so that the events still waiting for confidence
+// are seen again.
+//
+// The events start from the persisted tipset, or from the tipset a finality
+// below the head if it's older; the handlers registered on them see it as the
+// current tipset. The tipsets applied since are replayed when Resume is
+// called, which must be done after registering the handlers, and the new head
+// changes are held until then.
+func NewEventsWithCursor(ctx context.Context, api eventAPI, ds datastore.Datastore, key datastore.Key) *Events {
+	return newEvents(ctx, api, &eventsCursor{ds: ds, key: key})
+}
+
+type eventsCursor struct {
+	ds  datastore.Datastore
+	key datastore.Key
+}
+
+// resume returns the tipset to start the events from, and the tipsets applied
+// since, up to head, in order.
+func (c *eventsCursor) resume(ctx context.Context, api eventAPI, head *types.TipSet) (*types.TipSet, []*types.TipSet, error) {
+	b, err := c.ds.Get(c.key)
+	if err == datastore.ErrNotFound {
+		return head, nil, nil
+	}
+	if err != nil {
+		return nil, nil, xerrors.Errorf("loading events cursor: %w", err)
+	}
+
+	tsk, err := types.TipSetKeyFromBytes(b)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("decoding events cursor: %w", err)
+	}
+
+	from, err := api.ChainGetTipSet(ctx, tsk)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("loading events cursor tipset %s: %w", tsk, err)
+	}
+
+	if from.Height() < head.Height()-build.Finality {
+		log.Warnf("events cursor at height %d is more than a finality behind the head at %d, missed triggers below %d are lost", from.Height(), head.Height(), head.Height()-build.Finality)
+
+		from, err = api.ChainGetTipSetByHeight(ctx, head.Height()-build.Finality, head.Key())
+		if err != nil {
+			return nil, nil, xerrors.Errorf("loading tipset a finality below the head: %w", err)
+		}
+	}
+
+	// The cursor may have been reorged out of the chain, in which case we
+	// start from the common ancestor; the handlers are registered anew, so
+	// there is nothing to revert.
+	var missed []*types.TipSet
+	to := head
+	for !from.Equals(to) {
+		if from.Height() > to.Height() {
+			if from, err = api.ChainGetTipSet(ctx, from.Parents()); err != nil {
+				return nil, nil, xerrors.Errorf("walking back from the events cursor: %w", err)
+			}
+			continue
+		}
+
+		missed = append(missed, to)
+		if to, err = api.ChainGetTipSet(ctx, to.Parents()); err != nil {
+			return nil, nil, xerrors.Errorf("walking back from the head: %w", err)
+		}
+	}
+
+	for i, j := 0, len(missed)-1; i < j; i, j = i+1, j-1 {
+		missed[i], missed[j] = missed[j], missed[i]
+	}
+
+	return from, missed, nil
+}
+
+// store persists the tipset confidence epochs below the highest of the
+// applied tipsets, or the last non-null one before.
+func (c *eventsCursor) store(tsc *tipSetCache, app []*types.TipSet, confidence int) error {
+	last := app[0]
+	for _, ts := range app[1:] {
+		if ts.Height() > last.Height() {
+			last = ts
+		}
+	}
+
+	for h := last.Height() - abi.ChainEpoch(confidence); h < last.Height() && h >= 0; h-- {
+		ts, err := tsc.get(h)
+		if err != nil {
+			return xerrors.Errorf("loading tipset at %d: %w", h, err)
+		}
+		if ts != nil {
+			last = ts
+			break
+		}
+	}
+
+	return c.ds.Put(c.key, last.Key().Bytes())
+}
@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
 	"github.com/multiformats/go-multihash"
 	"github.com/stretchr/testify/require"
 
@@ -225,7 +227,7 @@ func (fcs *fakeCS) advance(rev, app int, msgs map[int]cid.Cid, nulls ...int) { /
 	fcs.sync.Unlock() //nolint:staticcheck
 }
 
-func (fcs *fakeCS) notifDone() {
+func (fcs *fakeCS) NotifDone() {
 	fcs.sync.Unlock()
 }
 
@@ -1323,3 +1325,150 @@ func TestStateChangedTimeout(t *testing.T) {
 	fcs.advance(0, 5, nil)
 	require.False(t, called)
 }
+
+func TestEventsCursor(t *testing.T) {
+	fcs := &fakeCS{
+		t: t,
+		h: 1,
+
+		msgs:    map[cid.Cid]fakeMsg{},
+		blkMsgs: map[cid.Cid]cid.Cid{},
+		tsc:     newTSCache(2*build.ForkLengthThreshold, nil),
+	}
+	require.NoError(t, fcs.tsc.add(fcs.makeTs(t, nil, 1, dummyCid)))
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	key := datastore.NewKey("/test/cursor")
+
+	requireCursor := func(h abi.ChainEpoch) {
+		b, err := ds.Get(key)
+		require.NoError(t, err)
+		ts, err := fcs.tsc.get(h)
+		require.NoError(t, err)
+		require.Equal(t, ts.Key().Bytes(), b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := NewEventsWithCursor(ctx, fcs, ds, key)
+	require.NoError(t, events.Resume())
+
+	fcs.advance(0, 3, nil) // H=4
+	requireCursor(4)
+	cancel()
+
+	// the chain advances while the events aren't running, with a message
+	// at H=5
+
+	t0123, err := address.NewFromString("t0123")
+	require.NoError(t, err)
+	mc := fcs.fakeMsgs(fakeMsg{
+		bmsgs: []*types.Message{
+			{To: t0123, From: t0123, Method: 5, Nonce: 1},
+		},
+	})
+	for ; fcs.h < 8; fcs.h++ {
+		best, err := fcs.tsc.best()
+		require.NoError(t, err)
+
+		ts := fcs.makeTs(t, best.Key().Cids(), fcs.h+1, mc)
+		require.NoError(t, fcs.tsc.add(ts))
+		if fcs.h+1 == 5 {
+			fcs.blkMsgs[ts.Blocks()[0].Cid()] = mc
+		}
+	}
+
+	events = NewEventsWithCursor(context.Background(), fcs, ds, key)
+
+	var heightApplied, msgApplied bool
+	err = events.ChainAt(func(_ context.Context, ts *types.TipSet, curH abi.ChainEpoch) error {
+		require.Equal(t, 6, int(ts.Height()))
+		require.Equal(t, 8, int(curH))
+		heightApplied = true
+		return nil
+	}, func(_ context.Context, ts *types.TipSet) error {
+		t.Fatal("unexpected revert")
+		return nil
+	}, 2, 6)
+	require.NoError(t, err)
+
+	err = events.Called(func(ts *types.TipSet) (d bool, m bool, e error) {
+		require.Equal(t, 4, int(ts.Height()))
+		return false, true, nil
+	}, func(msg *types.Message, rec *types.MessageReceipt, ts *types.TipSet, curH abi.ChainEpoch) (bool, error) {
+		require.Equal(t, uint64(1), msg.Nonce)
+		msgApplied = true
+		return true, nil
+	}, func(_ context.Context, ts *types.TipSet) error {
+		t.Fatal("unexpected revert")
+		return nil
+	}, 1, NoTimeout, matchAddrMethod(t0123, 5))
+	require.NoError(t, err)
+
+	require.False(t, heightApplied)
+	require.False(t, msgApplied)
+
+	require.NoError(t, events.Resume())
+	require.True(t, heightApplied)
+	require.True(t, msgApplied)
+	// the cursor stays the confidence of the message handler behind
+	requireCursor(7)
+
+	fcs.advance(0, 1, nil) // H=9
+	requireCursor(8)
+}
+
+func TestEventsCursorConfidence(t *testing.T) {
+	fcs := &fakeCS{
+		t: t,
+		h: 1,
+
+		msgs:    map[cid.Cid]fakeMsg{},
+		blkMsgs: map[cid.Cid]cid.Cid{},
+		tsc:     newTSCache(2*build.ForkLengthThreshold, nil),
+	}
+	require.NoError(t, fcs.tsc.add(fcs.makeTs(t, nil, 1, dummyCid)))
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	key := datastore.NewKey("/test/cursor")
+
+	t0123, err := address.NewFromString("t0123")
+	require.NoError(t, err)
+	mc := fcs.fakeMsgs(fakeMsg{
+		bmsgs: []*types.Message{
+			{To: t0123, From: t0123, Method: 5, Nonce: 1},
+		},
+	})
+
+	var applied int
+	register := func(events *Events) {
+		err := events.Called(func(ts *types.TipSet) (d bool, m bool, e error) {
+			return false, true, nil
+		}, func(msg *types.Message, rec *types.MessageReceipt, ts *types.TipSet, curH abi.ChainEpoch) (bool, error) {
+			applied++
+			return true, nil
+		}, func(_ context.Context, ts *types.TipSet) error {
+			t.Fatal("unexpected revert")
+			return nil
+		}, 3, NoTimeout, matchAddrMethod(t0123, 5))
+		require.NoError(t, err)
+		require.NoError(t, events.Resume())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	register(NewEventsWithCursor(ctx, fcs, ds, key))
+
+	fcs.advance(0, 3, nil)                    // H=4
+	fcs.advance(0, 2, map[int]cid.Cid{0: mc}) // H=6, message at H=5
+	require.Equal(t, 0, applied)
+	cancel()
+
+	// the events stop before the message reaches its confidence
+	for ; fcs.h < 10; fcs.h++ {
+		best, err := fcs.tsc.best()
+		require.NoError(t, err)
+		require.NoError(t, fcs.tsc.add(fcs.makeTs(t, best.Key().Cids(), fcs.h+1, dummyCid)))
+	}
+
+	register(NewEventsWithCursor(context.Background(), fcs, ds, key))
+	require.Equal(t, 1, applied)
+}
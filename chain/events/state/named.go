@@ -0,0 +1,78 @@
+package state
+
+import (
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	init_ "github.com/filecoin-project/lotus/chain/actors/builtin/init"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+)
+
+type namedPredicate struct {
+	// actor is the singleton actor the predicate applies to, undef when the
+	// predicate applies to any actor of its type
+	actor address.Address
+	build func(sp *StatePredicates, addr address.Address) DiffTipSetKeyFunc
+}
+
+// namedPredicates are the predicates which can be selected by name, e.g. by
+// subscribers of the API
+var namedPredicates = map[string]namedPredicate{
+	"deal-state-changed": {market.Address, func(sp *StatePredicates, _ address.Address) DiffTipSetKeyFunc {
+		return sp.OnStorageMarketActorChanged(sp.OnDealStateChanged(sp.OnDealStateAmtChanged()))
+	}},
+	"deal-proposal-changed": {market.Address, func(sp *StatePredicates, _ address.Address) DiffTipSetKeyFunc {
+		return sp.OnStorageMarketActorChanged(sp.OnDealProposalChanged(sp.OnDealProposalAmtChanged()))
+	}},
+	"total-power-changed": {power.Address, func(sp *StatePredicates, _ address.Address) DiffTipSetKeyFunc {
+		return sp.OnPowerActorChange(sp.OnTotalPowerChange())
+	}},
+	"address-map-changed": {init_.Address, func(sp *StatePredicates, _ address.Address) DiffTipSetKeyFunc {
+		return sp.OnInitActorChange(sp.OnAddressMapChange())
+	}},
+	"miner-sectors-changed": {address.Undef, func(sp *StatePredicates, addr address.Address) DiffTipSetKeyFunc {
+		return sp.OnMinerActorChange(addr, sp.OnMinerSectorChange())
+	}},
+	"miner-precommits-changed": {address.Undef, func(sp *StatePredicates, addr address.Address) DiffTipSetKeyFunc {
+		return sp.OnMinerActorChange(addr, sp.OnMinerPreCommitChange())
+	}},
+	"paych-to-send-changed": {address.Undef, func(sp *StatePredicates, addr address.Address) DiffTipSetKeyFunc {
+		return sp.OnPaymentChannelActorChanged(addr, sp.OnToSendAmountChanges())
+	}},
+}
+
+// PredicateNames returns the names of the predicates usable with Named.
+func PredicateNames() []string {
+	names := make([]string, 0, len(namedPredicates))
+	for name := range namedPredicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PredicateActor returns the singleton actor the named predicate applies to,
+// or address.Undef if it applies to any actor of its type.
+func PredicateActor(name string) (address.Address, error) {
+	p, ok := namedPredicates[name]
+	if !ok {
+		return address.Undef, xerrors.Errorf("unknown predicate %q", name)
+	}
+	return p.actor, nil
+}
+
+// Named returns the named predicate for the actor at addr.
+func (sp *StatePredicates) Named(name string, addr address.Address) (DiffTipSetKeyFunc, error) {
+	p, ok := namedPredicates[name]
+	if !ok {
+		return nil, xerrors.Errorf("unknown predicate %q", name)
+	}
+	if p.actor != address.Undef && p.actor != addr {
+		return nil, xerrors.Errorf("predicate %q only applies to actor %s", name, p.actor)
+	}
+	return p.build(sp, addr), nil
+}
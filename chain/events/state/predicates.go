@@ -17,6 +17,7 @@ import (
 	init_ "github.com/filecoin-project/lotus/chain/actors/builtin/init"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/paych"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
 	"github.com/filecoin-project/lotus/chain/types"
 )
 
@@ -255,6 +256,37 @@ func (sp *StatePredicates) DealStateChangedForIDs(dealIds []abi.DealID) DiffDeal
 	}
 }
 
+// ChangedProposals is a set of changes to deal proposals
+type ChangedProposals map[abi.DealID]market.DealProposalChange
+
+// DealProposalChangedForIDs detects changes in the deal proposal AMT for the
+// given deal IDs. Proposals are immutable, so they only change when they are
+// published or removed.
+func (sp *StatePredicates) DealProposalChangedForIDs(dealIds []abi.DealID) DiffDealProposalsFunc {
+	return func(ctx context.Context, oldDealProps, newDealProps market.DealProposals) (changed bool, user UserData, err error) {
+		changedProps := make(ChangedProposals)
+		for _, dealID := range dealIds {
+			oldProp, oldFound, err := oldDealProps.Get(dealID)
+			if err != nil {
+				return false, nil, err
+			}
+
+			newProp, newFound, err := newDealProps.Get(dealID)
+			if err != nil {
+				return false, nil, err
+			}
+
+			if oldFound != newFound {
+				changedProps[dealID] = market.DealProposalChange{ID: dealID, From: oldProp, To: newProp}
+			}
+		}
+		if len(changedProps) > 0 {
+			return true, changedProps, nil
+		}
+		return false, nil, nil
+	}
+}
+
 // ChangedBalances is a set of changes to deal state
 type ChangedBalances map[address.Address]BalanceChange
 
@@ -367,6 +399,135 @@ func (sp *StatePredicates) OnMinerPreCommitChange() DiffMinerActorStateFunc {
 	}
 }
 
+// ChangedSectors is a set of changes to sectors
+type ChangedSectors map[abi.SectorNumber]SectorChange
+
+// SectorChange is a change in sector from -> to, nil when the sector doesn't
+// exist
+type SectorChange struct {
+	From *miner.SectorOnChainInfo
+	To   *miner.SectorOnChainInfo
+}
+
+// SectorsChangedForNumbers detects changes of the given sectors, when they are
+// added, removed, extended or replaced
+func (sp *StatePredicates) SectorsChangedForNumbers(sectors []abi.SectorNumber) DiffMinerActorStateFunc {
+	return func(ctx context.Context, oldState, newState miner.State) (changed bool, user UserData, err error) {
+		changedSectors := make(ChangedSectors)
+		for _, num := range sectors {
+			oldSector, err := oldState.GetSector(num)
+			if err != nil {
+				return false, nil, err
+			}
+
+			newSector, err := newState.GetSector(num)
+			if err != nil {
+				return false, nil, err
+			}
+
+			existenceChanged := (oldSector == nil) != (newSector == nil)
+			valueChanged := oldSector != nil && newSector != nil &&
+				(oldSector.Expiration != newSector.Expiration ||
+					oldSector.Activation != newSector.Activation ||
+					!oldSector.SealedCID.Equals(newSector.SealedCID))
+			if existenceChanged || valueChanged {
+				changedSectors[num] = SectorChange{From: oldSector, To: newSector}
+			}
+		}
+		if len(changedSectors) > 0 {
+			return true, changedSectors, nil
+		}
+		return false, nil, nil
+	}
+}
+
+// DiffPowerActorStateFunc is function that compares two states for the power actor
+type DiffPowerActorStateFunc func(ctx context.Context, oldState power.State, newState power.State) (changed bool, user UserData, err error)
+
+// OnPowerActorChange calls diffPowerActorState when the state changes for the power actor
+func (sp *StatePredicates) OnPowerActorChange(diffPowerActorState DiffPowerActorStateFunc) DiffTipSetKeyFunc {
+	return sp.OnActorStateChanged(power.Address, func(ctx context.Context, oldActorState, newActorState *types.Actor) (changed bool, user UserData, err error) {
+		oldState, err := power.Load(adt.WrapStore(ctx, sp.cst), oldActorState)
+		if err != nil {
+			return false, nil, err
+		}
+		newState, err := power.Load(adt.WrapStore(ctx, sp.cst), newActorState)
+		if err != nil {
+			return false, nil, err
+		}
+		return diffPowerActorState(ctx, oldState, newState)
+	})
+}
+
+// PowerChange is a change in power claim from -> to
+type PowerChange struct {
+	From power.Claim
+	To   power.Claim
+}
+
+// ChangedPowers is a set of changes to miner power claims
+type ChangedPowers map[address.Address]PowerChange
+
+func claimChanged(from, to power.Claim) bool {
+	return !from.RawBytePower.Equals(to.RawBytePower) || !from.QualityAdjPower.Equals(to.QualityAdjPower)
+}
+
+// MinerPowerChangedForAddresses detects changes in the power claims of the
+// given miners; the claim of a miner without one is zero
+func (sp *StatePredicates) MinerPowerChangedForAddresses(getAddrs func() []address.Address) DiffPowerActorStateFunc {
+	return func(ctx context.Context, oldState, newState power.State) (changed bool, user UserData, err error) {
+		zero := power.Claim{RawBytePower: big.Zero(), QualityAdjPower: big.Zero()}
+
+		changedPowers := make(ChangedPowers)
+		for _, addr := range getAddrs() {
+			oldClaim, found, err := oldState.MinerPower(addr)
+			if err != nil {
+				return false, nil, err
+			}
+			if !found {
+				oldClaim = zero
+			}
+
+			newClaim, found, err := newState.MinerPower(addr)
+			if err != nil {
+				return false, nil, err
+			}
+			if !found {
+				newClaim = zero
+			}
+
+			if claimChanged(oldClaim, newClaim) {
+				changedPowers[addr] = PowerChange{From: oldClaim, To: newClaim}
+			}
+		}
+		if len(changedPowers) > 0 {
+			return true, changedPowers, nil
+		}
+		return false, nil, nil
+	}
+}
+
+// OnTotalPowerChange detects changes in the total power of the network, and
+// returns a *PowerChange
+func (sp *StatePredicates) OnTotalPowerChange() DiffPowerActorStateFunc {
+	return func(ctx context.Context, oldState, newState power.State) (changed bool, user UserData, err error) {
+		oldTotal, err := oldState.TotalPower()
+		if err != nil {
+			return false, nil, err
+		}
+
+		newTotal, err := newState.TotalPower()
+		if err != nil {
+			return false, nil, err
+		}
+
+		if !claimChanged(oldTotal, newTotal) {
+			return false, nil, nil
+		}
+		return true, &PowerChange{From: oldTotal, To: newTotal}, nil
+	}
+}
+
 // DiffPaymentChannelStateFunc is function that compares two states for the payment channel
 type DiffPaymentChannelStateFunc func(ctx context.Context, oldState paych.State, newState paych.State) (changed bool, user UserData, err error)
 
@@ -23,6 +23,7 @@ import (
 	market0 "github.com/filecoin-project/specs-actors/actors/builtin/market"
 
 	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	power0 "github.com/filecoin-project/specs-actors/actors/builtin/power"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 	tutils "github.com/filecoin-project/specs-actors/support/testing"
 
@@ -449,6 +450,150 @@ func TestMinerSectorChange(t *testing.T) {
 	require.Equal(t, si1Ext, sectorChanges.Extended[0].From)
 }
 
+func TestSectorsChangedForNumbers(t *testing.T) {
+	ctx := context.Background()
+	bs := bstore.NewTemporarySync()
+	store := adt.WrapStore(ctx, cbornode.NewCborStore(bs))
+
+	owner, worker := tutils.NewIDAddr(t, 0), tutils.NewIDAddr(t, 1)
+	si0 := newSectorOnChainInfo(0, tutils.MakeCID("0", &miner0.SealedCIDPrefix), big.NewInt(0), abi.ChainEpoch(0), abi.ChainEpoch(10))
+	si1 := newSectorOnChainInfo(1, tutils.MakeCID("1", &miner0.SealedCIDPrefix), big.NewInt(1), abi.ChainEpoch(1), abi.ChainEpoch(11))
+	si2 := newSectorOnChainInfo(2, tutils.MakeCID("2", &miner0.SealedCIDPrefix), big.NewInt(2), abi.ChainEpoch(2), abi.ChainEpoch(11))
+	oldMinerC := createMinerState(ctx, t, store, owner, worker, []miner.SectorOnChainInfo{si0, si1, si2})
+
+	// 0 delete
+	// 1 extend
+	// 2 same
+	si1Ext := si1
+	si1Ext.Expiration++
+	newMinerC := createMinerState(ctx, t, store, owner, worker, []miner.SectorOnChainInfo{si1Ext, si2})
+
+	minerAddr := tutils.NewIDAddr(t, 2)
+	oldState, err := mockTipset(minerAddr, 1)
+	require.NoError(t, err)
+	newState, err := mockTipset(minerAddr, 2)
+	require.NoError(t, err)
+
+	api := newMockAPI(bs)
+	api.setActor(oldState.Key(), &types.Actor{Head: oldMinerC, Code: builtin0.StorageMinerActorCodeID})
+	api.setActor(newState.Key(), &types.Actor{Head: newMinerC, Code: builtin0.StorageMinerActorCodeID})
+
+	preds := NewStatePredicates(api)
+
+	diffFn := preds.OnMinerActorChange(minerAddr, preds.SectorsChangedForNumbers([]abi.SectorNumber{0, 1, 2, 3}))
+	changed, val, err := diffFn(ctx, oldState.Key(), newState.Key())
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	changedSectors, ok := val.(ChangedSectors)
+	require.True(t, ok)
+	require.Len(t, changedSectors, 2)
+
+	require.Equal(t, si0, *changedSectors[0].From)
+	require.Nil(t, changedSectors[0].To)
+
+	require.Equal(t, si1, *changedSectors[1].From)
+	require.Equal(t, si1Ext, *changedSectors[1].To)
+
+	diffFn = preds.OnMinerActorChange(minerAddr, preds.SectorsChangedForNumbers([]abi.SectorNumber{2, 3}))
+	changed, val, err = diffFn(ctx, oldState.Key(), newState.Key())
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Nil(t, val)
+}
+
+func TestPowerChange(t *testing.T) {
+	ctx := context.Background()
+	bs := bstore.NewTemporarySync()
+	store := adt.WrapStore(ctx, cbornode.NewCborStore(bs))
+
+	minerA, minerB, minerC := tutils.NewIDAddr(t, 100), tutils.NewIDAddr(t, 101), tutils.NewIDAddr(t, 102)
+	claim := func(raw, qa int64) *power0.Claim {
+		return &power0.Claim{RawBytePower: big.NewInt(raw), QualityAdjPower: big.NewInt(qa)}
+	}
+
+	oldPowerC := createPowerState(ctx, t, store, map[address.Address]*power0.Claim{
+		minerA: claim(10, 10),
+		minerB: claim(20, 40),
+	})
+	newPowerC := createPowerState(ctx, t, store, map[address.Address]*power0.Claim{
+		minerA: claim(10, 10),
+		minerB: claim(30, 50),
+		minerC: claim(5, 5),
+	})
+
+	oldState, err := mockTipset(minerA, 1)
+	require.NoError(t, err)
+	newState, err := mockTipset(minerA, 2)
+	require.NoError(t, err)
+
+	api := newMockAPI(bs)
+	api.setActor(oldState.Key(), &types.Actor{Head: oldPowerC, Code: builtin0.StoragePowerActorCodeID})
+	api.setActor(newState.Key(), &types.Actor{Head: newPowerC, Code: builtin0.StoragePowerActorCodeID})
+
+	preds := NewStatePredicates(api)
+
+	diffFn := preds.OnPowerActorChange(preds.MinerPowerChangedForAddresses(func() []address.Address {
+		return []address.Address{minerA, minerB, minerC}
+	}))
+	changed, val, err := diffFn(ctx, oldState.Key(), newState.Key())
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	changedPowers, ok := val.(ChangedPowers)
+	require.True(t, ok)
+	require.Len(t, changedPowers, 2)
+
+	require.Equal(t, big.NewInt(20), changedPowers[minerB].From.RawBytePower)
+	require.Equal(t, big.NewInt(50), changedPowers[minerB].To.QualityAdjPower)
+
+	require.Equal(t, big.Zero(), changedPowers[minerC].From.RawBytePower)
+	require.Equal(t, big.NewInt(5), changedPowers[minerC].To.RawBytePower)
+
+	diffFn = preds.OnPowerActorChange(preds.OnTotalPowerChange())
+	changed, val, err = diffFn(ctx, oldState.Key(), newState.Key())
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	totalChange, ok := val.(*PowerChange)
+	require.True(t, ok)
+	require.Equal(t, big.NewInt(30), totalChange.From.RawBytePower)
+	require.Equal(t, big.NewInt(45), totalChange.To.RawBytePower)
+	require.Equal(t, big.NewInt(65), totalChange.To.QualityAdjPower)
+
+	changed, val, err = diffFn(ctx, oldState.Key(), oldState.Key())
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Nil(t, val)
+}
+
+func TestNamedPredicates(t *testing.T) {
+	preds := NewStatePredicates(newMockAPI(bstore.NewTemporarySync()))
+
+	for _, name := range PredicateNames() {
+		_, err := PredicateActor(name)
+		require.NoError(t, err)
+	}
+
+	actor, err := PredicateActor("deal-state-changed")
+	require.NoError(t, err)
+	require.Equal(t, market.Address, actor)
+
+	_, err = preds.Named("deal-state-changed", market.Address)
+	require.NoError(t, err)
+	_, err = preds.Named("deal-state-changed", tutils.NewIDAddr(t, 1000))
+	require.Error(t, err)
+
+	actor, err = PredicateActor("miner-sectors-changed")
+	require.NoError(t, err)
+	require.Equal(t, address.Undef, actor)
+	_, err = preds.Named("miner-sectors-changed", tutils.NewIDAddr(t, 1000))
+	require.NoError(t, err)
+
+	_, err = PredicateActor("unknown")
+	require.Error(t, err)
+}
+
 func mockTipset(minerAddr address.Address, timestamp uint64) (*types.TipSet, error) {
 	return types.NewTipSet([]*types.BlockHeader{{
 		Miner:                 minerAddr,
@@ -538,6 +683,30 @@ func createBalanceTable(ctx context.Context, t *testing.T, store adt.Store, bala
 	return [2]cid.Cid{escrowRootCid, lockedRootCid}
 }
 
+func createPowerState(ctx context.Context, t *testing.T, store adt.Store, claims map[address.Address]*power0.Claim) cid.Cid {
+	emptyMap, err := adt.MakeEmptyMap(store).Root()
+	require.NoError(t, err)
+	multiMap, err := adt.AsMultimap(store, emptyMap)
+	require.NoError(t, err)
+	emptyMultiMap, err := multiMap.Root()
+	require.NoError(t, err)
+
+	state := power0.ConstructState(emptyMap, emptyMultiMap)
+
+	claimsMap := adt.MakeEmptyMap(store)
+	for addr, claim := range claims {
+		require.NoError(t, claimsMap.Put(abi.AddrKey(addr), claim))
+		state.TotalRawBytePower = big.Add(state.TotalRawBytePower, claim.RawBytePower)
+		state.TotalQualityAdjPower = big.Add(state.TotalQualityAdjPower, claim.QualityAdjPower)
+	}
+	state.Claims, err = claimsMap.Root()
+	require.NoError(t, err)
+
+	stateC, err := store.Put(ctx, state)
+	require.NoError(t, err)
+	return stateC
+}
+
 func createMinerState(ctx context.Context, t *testing.T, store adt.Store, owner, worker address.Address, sectors []miner.SectorOnChainInfo) cid.Cid {
 	rootCid := createSectorsAMT(ctx, t, store, sectors)
 
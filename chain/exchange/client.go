@@ -21,6 +21,7 @@ import (
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/bandwidth"
 	incrt "github.com/filecoin-project/lotus/lib/increadtimeout"
 	"github.com/filecoin-project/lotus/lib/peermgr"
 )
@@ -35,16 +36,19 @@ type client struct {
 	host host.Host
 
 	peerTracker *bsPeerTracker
+
+	shaper *bandwidth.Shaper
 }
 
 var _ Client = (*client)(nil)
 
 // NewClient creates a new libp2p-based exchange.Client that uses the libp2p
 // ChainExhange protocol as the fetching mechanism.
-func NewClient(lc fx.Lifecycle, host host.Host, pmgr peermgr.MaybePeerMgr) Client {
+func NewClient(lc fx.Lifecycle, host host.Host, pmgr peermgr.MaybePeerMgr, bw BandwidthLimits) Client {
 	return &client{
 		host:        host,
 		peerTracker: newPeerTracker(lc, host, pmgr.Mgr),
+		shaper:      bw.Client,
 	}
 }
 
@@ -99,48 +103,110 @@ func (c *client) doRequest(
 		}
 	}
 
-	// Try the request for each peer in the list,
-	// return on the first successful response.
-	// FIXME: Doing this serially isn't great, but fetching in parallel
-	//  may not be a good idea either. Think about this more.
+	return c.requestFromPeers(ctx, req, peers, tipsets, singlePeer != nil)
+}
+
+// peerResult is the outcome of a request sent to a single peer.
+type peerResult struct {
+	peer peer.ID
+	res  *validatedResponse
+	err  error
+}
+
+// requestFromPeers sends the request to the peers in order, returning the
+// first valid response. A request that takes longer than the hedge delay is
+// raced against one to the next peer, so that a single slow peer doesn't
+// stall the sync; at most MaxParallelPeerRequests are in flight at once.
+// Failed requests are retried with the next peer right away.
+func (c *client) requestFromPeers(
+	ctx context.Context,
+	req *Request,
+	peers []peer.ID,
+	tipsets []*types.TipSet,
+	single bool,
+) (*validatedResponse, error) {
+	// Cancelling the context aborts the requests still in flight once a
+	// response was accepted.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	globalTime := build.Clock.Now()
 	// Global time used to track what is the expected time we will need to get
 	// a response if a client fails us.
-	for _, peer := range peers {
+	results := make(chan peerResult, len(peers))
+	next, inFlight := 0, 0
+	startNext := func() {
+		p := peers[next]
+		next++
+		inFlight++
+		go func() {
+			res, err := c.requestFromPeer(ctx, p, req, tipsets)
+			results <- peerResult{peer: p, res: res, err: err}
+		}()
+	}
+
+	startNext()
+	hedge := build.Clock.Timer(c.peerTracker.hedgeDelay())
+	defer hedge.Stop()
+
+	for inFlight > 0 {
 		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				c.peerTracker.logGlobalSuccess(build.Clock.Since(globalTime))
+				c.host.ConnManager().TagPeer(r.peer, "bsync", SuccessPeerTagValue)
+				return r.res, nil
+			}
+
+			if next < len(peers) {
+				startNext()
+			}
+		case <-hedge.C:
+			if next < len(peers) && inFlight < MaxParallelPeerRequests {
+				log.Debugw("request is slow, racing it against another peer", "peers", next)
+				startNext()
+			}
+			hedge.Reset(c.peerTracker.hedgeDelay())
 		case <-ctx.Done():
 			return nil, xerrors.Errorf("context cancelled: %w", ctx.Err())
-		default:
 		}
+	}
 
-		// Send request, read response.
-		res, err := c.sendRequestToPeer(ctx, peer, req)
-		if err != nil {
-			if !xerrors.Is(err, network.ErrNoConn) {
-				log.Warnf("could not send request to peer %s: %s",
-					peer.String(), err)
-			}
-			continue
-		}
+	errString := "doRequest failed for all peers"
+	if single {
+		errString = fmt.Sprintf("doRequest failed for single peer %s", peers[0])
+	}
+	return nil, xerrors.Errorf(errString)
+}
 
-		// Process and validate response.
-		validRes, err := c.processResponse(req, res, tipsets)
-		if err != nil {
-			log.Warnf("processing peer %s response failed: %s",
-				peer.String(), err)
-			continue
-		}
+// requestFromPeer sends the request to the peer and validates the response,
+// recording the outcome in the peer tracker. Requests aborted because the
+// context was cancelled aren't held against the peer.
+func (c *client) requestFromPeer(ctx context.Context, p peer.ID, req *Request, tipsets []*types.TipSet) (*validatedResponse, error) {
+	start := build.Clock.Now()
 
-		c.peerTracker.logGlobalSuccess(build.Clock.Since(globalTime))
-		c.host.ConnManager().TagPeer(peer, "bsync", SuccessPeerTagValue)
-		return validRes, nil
+	// Send request, read response.
+	res, err := c.sendRequestToPeer(ctx, p, req)
+	if err != nil {
+		if !xerrors.Is(err, network.ErrNoConn) && ctx.Err() == nil {
+			log.Warnf("could not send request to peer %s: %s",
+				p.String(), err)
+		}
+		return nil, err
 	}
 
-	errString := "doRequest failed for all peers"
-	if singlePeer != nil {
-		errString = fmt.Sprintf("doRequest failed for single peer %s", *singlePeer)
+	// Process and validate response.
+	validRes, err := c.processResponse(req, res, tipsets)
+	if err != nil {
+		log.Warnf("processing peer %s response failed: %s",
+			p.String(), err)
+		c.peerTracker.logInvalid(p, build.Clock.Since(start), req.Length)
+		return nil, err
 	}
-	return nil, xerrors.Errorf(errString)
+
+	c.peerTracker.logSuccess(p, build.Clock.Since(start), uint64(len(res.Chain)))
+	return validRes, nil
 }
 
 // Process and validate response. Check the status, the integrity of the
@@ -304,18 +370,84 @@ func (c *client) GetBlocks(ctx context.Context, tsk types.TipSetKey, count int)
 		)
 	}
 
-	req := &Request{
-		Head:    tsk.Cids(),
-		Length:  uint64(count),
-		Options: Headers,
+	if count <= SubRangeLength {
+		req := &Request{
+			Head:    tsk.Cids(),
+			Length:  uint64(count),
+			Options: Headers,
+		}
+
+		validRes, err := c.doRequest(ctx, req, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return validRes.tipsets, nil
 	}
 
-	validRes, err := c.doRequest(ctx, req, nil, nil)
-	if err != nil {
-		return nil, err
+	return c.getSubRanges(ctx, tsk, count)
+}
+
+// getSubRanges fetches a long range of headers as consecutive sub-ranges of
+// SubRangeLength tipsets, each starting at the parents of the last tipset of
+// the previous one. The requests start from a tipset key, so a sub-range can
+// only be requested once the previous one arrived; what splitting buys is
+// that the sub-ranges are spread across the best scored peers, and that a
+// sub-range which stalls is raced against other peers and re-requested on its
+// own, instead of the whole range.
+func (c *client) getSubRanges(ctx context.Context, tsk types.TipSetKey, count int) ([]*types.TipSet, error) {
+	out := make([]*types.TipSet, 0, count)
+	head := tsk.Cids()
+	for i := 0; len(out) < count; i++ {
+		peers := c.peerTracker.prefSortedPeers()
+		if len(peers) == 0 {
+			return nil, xerrors.Errorf("no peers available")
+		}
+
+		length := count - len(out)
+		if length > SubRangeLength {
+			length = SubRangeLength
+		}
+		req := &Request{
+			Head:    head,
+			Length:  uint64(length),
+			Options: Headers,
+		}
+
+		validRes, err := c.requestFromPeers(ctx, req, rotatePeers(peers, i), nil, false)
+		if err != nil {
+			if len(out) > 0 {
+				// like a partial response, return what was fetched so far
+				log.Warnw("fetching sub-range failed", "from", len(out), "length", length, "error", err)
+				return out, nil
+			}
+			return nil, err
+		}
+		out = append(out, validRes.tipsets...)
+
+		last := validRes.tipsets[len(validRes.tipsets)-1]
+		if last.Height() == 0 {
+			break
+		}
+		head = last.Parents().Cids()
+	}
+
+	return out, nil
+}
+
+// rotatePeers returns the peers starting with the i-th of the best scored
+// ones, so that consecutive sub-ranges are sent to different peers first.
+func rotatePeers(peers []peer.ID, i int) []peer.ID {
+	n := ShufflePeersPrefix
+	if len(peers) < n {
+		n = len(peers)
 	}
+	i %= n
 
-	return validRes.tipsets, nil
+	out := make([]peer.ID, 0, len(peers))
+	out = append(out, peers[i:n]...)
+	out = append(out, peers[:i]...)
+	return append(out, peers[n:]...)
 }
 
 // GetFullTipSet implements Client.GetFullTipSet(). Refer to the godocs there.
@@ -418,11 +550,23 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 		go helpers.FullClose(stream) //nolint:errcheck
 	}()
 
+	// Reset the stream when the request is abandoned, e.g. because another
+	// peer answered first, instead of waiting for the read deadline.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = stream.Reset()
+		case <-done:
+		}
+	}()
+
 	// Write request.
 	_ = stream.SetWriteDeadline(time.Now().Add(WriteReqDeadline))
 	if err := cborutil.WriteCborRPC(stream, req); err != nil {
 		_ = stream.SetWriteDeadline(time.Time{})
-		c.peerTracker.logFailure(peer, build.Clock.Since(connectionStart), req.Length)
+		c.logFailure(ctx, peer, build.Clock.Since(connectionStart), req.Length)
 		// FIXME: Should we also remove peer here?
 		return nil, err
 	}
@@ -431,11 +575,13 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 
 	// Read response.
 	var res Response
+	// the shaper wraps the timeout reader, so that time spent waiting for
+	// bandwidth doesn't count as the peer being slow
 	err = cborutil.ReadCborRPC(
-		bufio.NewReader(incrt.New(stream, ReadResMinSpeed, ReadResDeadline)),
+		bufio.NewReader(c.shaper.Reader(ctx, incrt.New(stream, ReadResMinSpeed, ReadResDeadline))),
 		&res)
 	if err != nil {
-		c.peerTracker.logFailure(peer, build.Clock.Since(connectionStart), req.Length)
+		c.logFailure(ctx, peer, build.Clock.Since(connectionStart), req.Length)
 		return nil, xerrors.Errorf("failed to read chainxchg response: %w", err)
 	}
 
@@ -449,12 +595,18 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 		)
 	}
 
-	c.peerTracker.logSuccess(peer, build.Clock.Since(connectionStart), uint64(len(res.Chain)))
-	// FIXME: We should really log a success only after we validate the response.
-	//  It might be a bit hard to do.
 	return &res, nil
 }
 
+// logFailure records a failed request in the peer tracker, unless it failed
+// because it was abandoned.
+func (c *client) logFailure(ctx context.Context, p peer.ID, dur time.Duration, reqSize uint64) {
+	if ctx.Err() != nil {
+		return
+	}
+	c.peerTracker.logFailure(p, dur, reqSize)
+}
+
 // AddPeer implements Client.AddPeer(). Refer to the godocs there.
 func (c *client) AddPeer(p peer.ID) {
 	c.peerTracker.addPeer(p)
@@ -0,0 +1,27 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatePeers(t *testing.T) {
+	var peers []peer.ID
+	for i := 0; i < ShufflePeersPrefix+2; i++ {
+		peers = append(peers, peer.ID(rune('a'+i)))
+	}
+
+	// the sub-ranges start with each of the best scored peers in turn, and the
+	// others come after them
+	for i := 0; i < 2*ShufflePeersPrefix; i++ {
+		rotated := rotatePeers(peers, i)
+		require.ElementsMatch(t, peers, rotated)
+		require.Equal(t, peers[i%ShufflePeersPrefix], rotated[0])
+		require.Equal(t, peers[ShufflePeersPrefix:], rotated[ShufflePeersPrefix:])
+	}
+
+	few := peers[:2]
+	require.Equal(t, []peer.ID{few[1], few[0]}, rotatePeers(few, 3))
+}
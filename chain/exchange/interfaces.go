@@ -8,8 +8,40 @@ import (
 
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/bandwidth"
 )
 
+// BandwidthLimits caps the throughput of the ChainExchange protocol, shared
+// by all streams. A nil shaper doesn't limit anything.
+type BandwidthLimits struct {
+	// Client shapes the responses read from peers, which carry most of the
+	// data of a sync
+	Client *bandwidth.Shaper
+	// Server shapes the responses served to peers
+	Server *bandwidth.Shaper
+}
+
+// ServerLimits bound what a single peer can take from the server, so that a
+// misbehaving peer can't saturate the disk reads of the node. Zero values
+// don't limit anything.
+type ServerLimits struct {
+	// MaxConcurrentRequests is the number of requests of a peer serviced at
+	// once; further requests get a GoAway response
+	MaxConcurrentRequests int
+	// PeerBandwidth caps the bytes per second served to each peer, in
+	// addition to the shared cap of BandwidthLimits.Server
+	PeerBandwidth int64
+	// MaxResponseBytes caps the size of a response; longer chain segments
+	// are returned partially. A response holds at least one tipset.
+	MaxResponseBytes int64
+}
+
+// DefaultServerLimits are the limits used unless configured otherwise.
+var DefaultServerLimits = ServerLimits{
+	MaxConcurrentRequests: 8,
+	MaxResponseBytes:      64 << 20,
+}
+
 // Server is the responder side of the ChainExchange protocol. It accepts
 // requests from clients and services them by returning the requested
 // chain data.
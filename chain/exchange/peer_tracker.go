@@ -17,8 +17,10 @@ import (
 )
 
 type peerStats struct {
-	successes   int
-	failures    int
+	successes int
+	failures  int
+	// invalid counts the responses which failed validation
+	invalid     int
 	firstSeen   time.Time
 	averageTime time.Duration
 }
@@ -74,8 +76,28 @@ const (
 	// newPeerMul is how much better than average is the new peer assumed to be
 	// less than one to encourouge trying new peers
 	newPeerMul = 0.9
+	// invalidMul is how many failures an invalid response counts as; peers
+	// serving bad data are worse than those timing out
+	invalidMul = 5
+	// hedgeDelayMul is how many times the average request time a request may
+	// take before it is raced against another peer
+	hedgeDelayMul = 2
 )
 
+// cost is the expected time of requesting a tipset from the peer, the
+// average time per tipset of its responses plus the average time of a request
+// for each of its failures. It handles the edge cases where not enough data
+// is available.
+func (bpt *bsPeerTracker) cost(pi *peerStats) float64 {
+	total := pi.successes + pi.failures + pi.invalid
+	if total == 0 {
+		return float64(bpt.avgGlobalTime) * newPeerMul
+	}
+
+	failRate := float64(pi.failures+invalidMul*pi.invalid) / float64(total)
+	return float64(pi.averageTime) + failRate*float64(bpt.avgGlobalTime)
+}
+
 func (bpt *bsPeerTracker) prefSortedPeers() []peer.ID {
 	// TODO: this could probably be cached, but as long as its not too many peers, fine for now
 	bpt.lk.Lock()
@@ -86,32 +108,8 @@ func (bpt *bsPeerTracker) prefSortedPeers() []peer.ID {
 	}
 
 	// sort by 'expected cost' of requesting data from that peer
-	// additionally handle edge cases where not enough data is available
 	sort.Slice(out, func(i, j int) bool {
-		pi := bpt.peers[out[i]]
-		pj := bpt.peers[out[j]]
-
-		var costI, costJ float64
-
-		getPeerInitLat := func(p peer.ID) float64 {
-			return float64(bpt.avgGlobalTime) * newPeerMul
-		}
-
-		if pi.successes+pi.failures > 0 {
-			failRateI := float64(pi.failures) / float64(pi.failures+pi.successes)
-			costI = float64(pi.averageTime) + failRateI*float64(bpt.avgGlobalTime)
-		} else {
-			costI = getPeerInitLat(out[i])
-		}
-
-		if pj.successes+pj.failures > 0 {
-			failRateJ := float64(pj.failures) / float64(pj.failures+pj.successes)
-			costJ = float64(pj.averageTime) + failRateJ*float64(bpt.avgGlobalTime)
-		} else {
-			costJ = getPeerInitLat(out[j])
-		}
-
-		return costI < costJ
+		return bpt.cost(bpt.peers[out[i]]) < bpt.cost(bpt.peers[out[j]])
 	})
 
 	return out
@@ -158,6 +156,9 @@ func (bpt *bsPeerTracker) logSuccess(p peer.ID, dur time.Duration, reqSize uint6
 	}
 
 	pi.successes++
+	if bpt.pmgr != nil {
+		bpt.pmgr.Reputation().RecordSuccess(p, dur, reqSize)
+	}
 	if reqSize == 0 {
 		reqSize = 1
 	}
@@ -176,12 +177,55 @@ func (bpt *bsPeerTracker) logFailure(p peer.ID, dur time.Duration, reqSize uint6
 	}
 
 	pi.failures++
+	if bpt.pmgr != nil {
+		bpt.pmgr.Reputation().RecordFailure(p)
+	}
 	if reqSize == 0 {
 		reqSize = 1
 	}
 	logTime(pi, dur/time.Duration(reqSize))
 }
 
+// logInvalid records a response which failed validation, both in the stats
+// used for picking peers and in the peer's long-term reputation.
+func (bpt *bsPeerTracker) logInvalid(p peer.ID, dur time.Duration, reqSize uint64) {
+	bpt.lk.Lock()
+	defer bpt.lk.Unlock()
+
+	if bpt.pmgr != nil {
+		bpt.pmgr.Reputation().RecordInvalid(p)
+	}
+
+	pi, ok := bpt.peers[p]
+	if !ok {
+		return
+	}
+
+	pi.invalid++
+	if reqSize == 0 {
+		reqSize = 1
+	}
+	logTime(pi, dur/time.Duration(reqSize))
+}
+
+// hedgeDelay returns how long a request may take before it is raced against
+// a request to another peer.
+func (bpt *bsPeerTracker) hedgeDelay() time.Duration {
+	bpt.lk.Lock()
+	defer bpt.lk.Unlock()
+
+	if bpt.avgGlobalTime == 0 {
+		// nothing to go by yet
+		return ReadResDeadline
+	}
+
+	d := bpt.avgGlobalTime * hedgeDelayMul
+	if d < HedgeDelayMin {
+		d = HedgeDelayMin
+	}
+	return d
+}
+
 func (bpt *bsPeerTracker) removePeer(p peer.ID) {
 	bpt.lk.Lock()
 	defer bpt.lk.Unlock()
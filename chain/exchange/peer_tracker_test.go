@@ -0,0 +1,45 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerTrackerSorting(t *testing.T) {
+	bpt := &bsPeerTracker{peers: make(map[peer.ID]*peerStats)}
+
+	fast, slow, invalid, fresh := peer.ID("fast"), peer.ID("slow"), peer.ID("invalid"), peer.ID("fresh")
+	for _, p := range []peer.ID{fast, slow, invalid, fresh} {
+		bpt.addPeer(p)
+	}
+
+	bpt.logGlobalSuccess(time.Second)
+	for i := 0; i < 20; i++ {
+		bpt.logSuccess(fast, 100*time.Millisecond, 100)
+		bpt.logSuccess(slow, 200*time.Second, 100)
+		bpt.logSuccess(invalid, 100*time.Millisecond, 100)
+	}
+	bpt.logInvalid(invalid, 100*time.Millisecond, 100)
+
+	require.Equal(t, []peer.ID{fast, invalid, fresh, slow}, bpt.prefSortedPeers())
+
+	// serving bad data repeatedly makes the peer worse than a fresh one
+	for i := 0; i < 4; i++ {
+		bpt.logInvalid(invalid, 100*time.Millisecond, 100)
+	}
+	require.Equal(t, []peer.ID{fast, fresh, invalid, slow}, bpt.prefSortedPeers())
+}
+
+func TestPeerTrackerHedgeDelay(t *testing.T) {
+	bpt := &bsPeerTracker{peers: make(map[peer.ID]*peerStats)}
+	require.Equal(t, ReadResDeadline, bpt.hedgeDelay())
+
+	bpt.logGlobalSuccess(100 * time.Millisecond)
+	require.Equal(t, HedgeDelayMin, bpt.hedgeDelay())
+
+	bpt.avgGlobalTime = 3 * time.Second
+	require.Equal(t, 6*time.Second, bpt.hedgeDelay())
+}
@@ -1,6 +1,7 @@
 package exchange
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/filecoin-project/lotus/build"
@@ -42,6 +43,16 @@ const (
 	ReadResMinSpeed     = 50 << 10
 	ShufflePeersPrefix  = 16
 	WriteResDeadline    = 60 * time.Second
+
+	// MaxParallelPeerRequests is the number of peers a slow request is sent
+	// to at once, see HedgeDelayMin
+	MaxParallelPeerRequests = 3
+	// HedgeDelayMin is the least time a request waits for a response before
+	// it is also sent to the next peer
+	HedgeDelayMin = 2 * time.Second
+	// SubRangeLength is the length of the sub-ranges that longer GetBlocks
+	// requests are split into
+	SubRangeLength = 100
 )
 
 // FIXME: Rename. Make private.
@@ -117,6 +128,25 @@ const (
 	BadRequest    = 204
 )
 
+func (s status) String() string {
+	switch s {
+	case Ok:
+		return "ok"
+	case Partial:
+		return "partial"
+	case NotFound:
+		return "not_found"
+	case GoAway:
+		return "go_away"
+	case InternalError:
+		return "internal_error"
+	case BadRequest:
+		return "bad_request"
+	default:
+		return fmt.Sprintf("unknown_%d", uint64(s))
+	}
+}
+
 // Convert status to internal error.
 func (res *Response) statusToError() error {
 	switch res.Status {
@@ -127,7 +157,7 @@ func (res *Response) statusToError() error {
 	case NotFound:
 		return xerrors.Errorf("not found")
 	case GoAway:
-		return xerrors.Errorf("not handling 'go away' chainxchg responses yet")
+		return xerrors.Errorf("block sync peer is busy: %s", res.ErrorMessage)
 	case InternalError:
 		return xerrors.Errorf("block sync peer errored: %s", res.ErrorMessage)
 	case BadRequest:
@@ -4,34 +4,65 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
 	"time"
 
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
 
 	cborutil "github.com/filecoin-project/go-cbor-util"
 
+	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/bandwidth"
+	"github.com/filecoin-project/lotus/metrics"
 
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/helpers"
 	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
+// peerIdleTimeout is how long the limiter of a peer without requests is
+// kept, so that a peer can't reset its bandwidth cap by reconnecting.
+const peerIdleTimeout = time.Minute
+
 // server implements exchange.Server. It services requests for the
 // libp2p ChainExchange protocol.
 type server struct {
 	cs *store.ChainStore
+
+	shaper *bandwidth.Shaper
+	limits ServerLimits
+
+	peersLk   sync.Mutex
+	peers     map[peer.ID]*peerLimiter
+	active    int64
+	lastSweep time.Time
+}
+
+// peerLimiter tracks the requests of a peer being serviced.
+type peerLimiter struct {
+	active   int
+	shaper   *bandwidth.Shaper
+	lastUsed time.Time
 }
 
 var _ Server = (*server)(nil)
 
 // NewServer creates a new libp2p-based exchange.Server. It services requests
 // for the libp2p ChainExchange protocol.
-func NewServer(cs *store.ChainStore) Server {
+func NewServer(cs *store.ChainStore, bw BandwidthLimits, limits ServerLimits) Server {
 	return &server{
-		cs: cs,
+		cs:     cs,
+		shaper: bw.Server,
+		limits: limits,
+		peers:  map[peer.ID]*peerLimiter{},
 	}
 }
 
@@ -49,23 +80,110 @@ func (s *server) HandleStream(stream inet.Stream) {
 		log.Warnf("failed to read block sync request: %s", err)
 		return
 	}
+	p := stream.Conn().RemotePeer()
 	log.Infow("block sync request",
-		"start", req.Head, "len", req.Length)
+		"start", req.Head, "len", req.Length, "peer", p)
 
-	resp, err := s.processRequest(ctx, &req)
-	if err != nil {
-		log.Warn("failed to process request: ", err)
-		return
+	start := build.Clock.Now()
+	pl, ok := s.acquire(p)
+	var resp *Response
+	if ok {
+		defer s.release(p)
+
+		var err error
+		resp, err = s.processRequest(ctx, &req)
+		if err != nil {
+			log.Warn("failed to process request: ", err)
+			return
+		}
+	} else {
+		log.Infow("turning away block sync request, too many concurrent requests from the peer", "peer", p)
+		resp = &Response{
+			Status:       GoAway,
+			ErrorMessage: fmt.Sprintf("too many concurrent requests (max %d)", s.limits.MaxConcurrentRequests),
+		}
+	}
+
+	var w io.Writer = stream
+	w = s.shaper.Writer(ctx, w)
+	if pl != nil {
+		w = pl.shaper.Writer(ctx, w)
 	}
+	cw := &countingWriter{w: w}
 
 	_ = stream.SetDeadline(time.Now().Add(WriteResDeadline))
-	if err := cborutil.WriteCborRPC(stream, resp); err != nil {
-		_ = stream.SetDeadline(time.Time{})
+	err := cborutil.WriteCborRPC(cw, resp)
+	_ = stream.SetDeadline(time.Time{})
+
+	tctx, _ := tag.New(ctx, tag.Upsert(metrics.Status, resp.Status.String()))
+	stats.Record(tctx, metrics.ChainExchangeServerRequests.M(1))
+	stats.Record(ctx, metrics.ChainExchangeServerBytes.M(cw.n),
+		metrics.ChainExchangeServerDuration.M(metrics.SinceInMilliseconds(start)))
+
+	if err != nil {
 		log.Warnw("failed to write back response for handle stream",
-			"err", err, "peer", stream.Conn().RemotePeer())
+			"err", err, "peer", p)
 		return
 	}
-	_ = stream.SetDeadline(time.Time{})
+}
+
+// acquire registers a request of the peer, returning false if the peer is at
+// its limit of concurrent requests. The limiter is nil when the request is
+// turned away.
+func (s *server) acquire(p peer.ID) (*peerLimiter, bool) {
+	s.peersLk.Lock()
+	defer s.peersLk.Unlock()
+
+	now := build.Clock.Now()
+	if now.Sub(s.lastSweep) > peerIdleTimeout {
+		for id, pl := range s.peers {
+			if pl.active == 0 && now.Sub(pl.lastUsed) > peerIdleTimeout {
+				delete(s.peers, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	pl, ok := s.peers[p]
+	if !ok {
+		pl = &peerLimiter{}
+		if s.limits.PeerBandwidth > 0 {
+			pl.shaper = bandwidth.NewShaper(s.limits.PeerBandwidth, nil)
+		}
+		s.peers[p] = pl
+	}
+	pl.lastUsed = now
+
+	if s.limits.MaxConcurrentRequests > 0 && pl.active >= s.limits.MaxConcurrentRequests {
+		return nil, false
+	}
+	pl.active++
+	s.active++
+	stats.Record(context.Background(), metrics.ChainExchangeServerActive.M(s.active))
+	return pl, true
+}
+
+func (s *server) release(p peer.ID) {
+	s.peersLk.Lock()
+	defer s.peersLk.Unlock()
+
+	if pl, ok := s.peers[p]; ok {
+		pl.active--
+		pl.lastUsed = build.Clock.Now()
+	}
+	s.active--
+	stats.Record(context.Background(), metrics.ChainExchangeServerActive.M(s.active))
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // Validate and service the request. We return either a protocol
@@ -135,7 +253,7 @@ func (s *server) serviceRequest(ctx context.Context, req *validatedRequest) (*Re
 	_, span := trace.StartSpan(ctx, "chainxchg.ServiceRequest")
 	defer span.End()
 
-	chain, err := collectChainSegment(s.cs, req)
+	chain, err := collectChainSegment(s.cs, req, s.limits.MaxResponseBytes)
 	if err != nil {
 		log.Warn("block sync request: collectChainSegment failed: ", err)
 		return &Response{
@@ -155,8 +273,11 @@ func (s *server) serviceRequest(ctx context.Context, req *validatedRequest) (*Re
 	}, nil
 }
 
-func collectChainSegment(cs *store.ChainStore, req *validatedRequest) ([]*BSTipSet, error) {
+// collectChainSegment loads the requested chain segment, stopping before
+// the response gets over maxBytes (0 = no limit).
+func collectChainSegment(cs *store.ChainStore, req *validatedRequest, maxBytes int64) ([]*BSTipSet, error) {
 	var bstips []*BSTipSet
+	var size int64
 
 	cur := req.head
 	for {
@@ -184,6 +305,16 @@ func collectChainSegment(cs *store.ChainStore, req *validatedRequest) ([]*BSTipS
 			bst.Messages.SecpkIncludes = smincl
 		}
 
+		if maxBytes > 0 {
+			cw := &countingWriter{w: ioutil.Discard}
+			if err := bst.MarshalCBOR(cw); err != nil {
+				return nil, xerrors.Errorf("sizing tipset: %w", err)
+			}
+			if size += cw.n; size > maxBytes && len(bstips) > 0 {
+				return bstips, nil
+			}
+		}
+
 		bstips = append(bstips, &bst)
 
 		// If we collected the length requested or if we reached the
@@ -0,0 +1,36 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerPeerLimits(t *testing.T) {
+	s := NewServer(nil, BandwidthLimits{}, ServerLimits{
+		MaxConcurrentRequests: 2,
+		PeerBandwidth:         1 << 20,
+	}).(*server)
+
+	a, b := peer.ID("a"), peer.ID("b")
+
+	pl, ok := s.acquire(a)
+	require.True(t, ok)
+	require.NotNil(t, pl.shaper)
+	_, ok = s.acquire(a)
+	require.True(t, ok)
+
+	// a is at its limit, b isn't
+	_, ok = s.acquire(a)
+	require.False(t, ok)
+	_, ok = s.acquire(b)
+	require.True(t, ok)
+	require.Equal(t, int64(3), s.active)
+
+	s.release(a)
+	again, ok := s.acquire(a)
+	require.True(t, ok)
+	// the bandwidth cap of a peer is kept across its requests
+	require.Equal(t, pl, again)
+}
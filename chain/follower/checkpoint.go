@@ -0,0 +1,92 @@
+package follower
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	dstore "github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// Checkpoint is the progress of a follower.
+type Checkpoint struct {
+	// Cursor is the head change journal cursor of the last fully processed
+	// journal entry
+	Cursor uint64
+	// TipSet is the last tipset passed to Apply, or the parent of the last
+	// reverted tipset
+	TipSet types.TipSetKey
+}
+
+// CheckpointStore persists the progress of a follower. Load returns nil when
+// nothing was saved yet.
+type CheckpointStore interface {
+	Load(ctx context.Context) (*Checkpoint, error)
+	Save(ctx context.Context, cp Checkpoint) error
+}
+
+type memoryCheckpoints struct {
+	lk sync.Mutex
+	cp *Checkpoint
+}
+
+// NewMemoryCheckpoints returns a CheckpointStore which doesn't persist the
+// progress across restarts of the process.
+func NewMemoryCheckpoints() CheckpointStore {
+	return &memoryCheckpoints{}
+}
+
+func (m *memoryCheckpoints) Load(context.Context) (*Checkpoint, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.cp == nil {
+		return nil, nil
+	}
+	cp := *m.cp
+	return &cp, nil
+}
+
+func (m *memoryCheckpoints) Save(_ context.Context, cp Checkpoint) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.cp = &cp
+	return nil
+}
+
+type datastoreCheckpoints struct {
+	ds  dstore.Datastore
+	key dstore.Key
+}
+
+// NewDatastoreCheckpoints returns a CheckpointStore keeping the progress
+// under the key in the datastore, e.g. next to the data of the indexer.
+func NewDatastoreCheckpoints(ds dstore.Datastore, key dstore.Key) CheckpointStore {
+	return &datastoreCheckpoints{ds: ds, key: key}
+}
+
+func (d *datastoreCheckpoints) Load(context.Context) (*Checkpoint, error) {
+	b, err := d.ds.Get(d.key)
+	if err == dstore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("reading checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, xerrors.Errorf("decoding checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func (d *datastoreCheckpoints) Save(_ context.Context, cp Checkpoint) error {
+	b, err := json.Marshal(&cp)
+	if err != nil {
+		return err
+	}
+	return d.ds.Put(d.key, b)
+}
@@ -0,0 +1,343 @@
+// Package follower follows the chain of a node for indexers, calling a
+// handler for every applied and reverted tipset, in order, and resuming
+// where it stopped after restarts and disconnections.
+package follower
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var log = logging.Logger("follower")
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// FollowerAPI is the part of the full node API the follower uses.
+type FollowerAPI interface {
+	ChainNotifyFrom(ctx context.Context, cursor uint64) (<-chan *api.HeadChanges, error)
+	ChainGetTipSet(context.Context, types.TipSetKey) (*types.TipSet, error)
+}
+
+// Handler processes the changes of the followed chain. Apply is called for
+// every tipset added to the chain, parents before children, and Revert for
+// every tipset removed from it, children before parents, so that the
+// tipsets the handler has seen always form a chain.
+//
+// The calls are made one at a time. A tipset whose call failed is passed to
+// the handler again when following resumes.
+type Handler interface {
+	Apply(ctx context.Context, ts *types.TipSet) error
+	Revert(ctx context.Context, ts *types.TipSet) error
+}
+
+// HandlerFuncs is a Handler calling the given functions; nil functions are
+// skipped.
+type HandlerFuncs struct {
+	ApplyFunc  func(ctx context.Context, ts *types.TipSet) error
+	RevertFunc func(ctx context.Context, ts *types.TipSet) error
+}
+
+func (h HandlerFuncs) Apply(ctx context.Context, ts *types.TipSet) error {
+	if h.ApplyFunc == nil {
+		return nil
+	}
+	return h.ApplyFunc(ctx, ts)
+}
+
+func (h HandlerFuncs) Revert(ctx context.Context, ts *types.TipSet) error {
+	if h.RevertFunc == nil {
+		return nil
+	}
+	return h.RevertFunc(ctx, ts)
+}
+
+// Follower follows the chain of a node.
+type Follower struct {
+	api     FollowerAPI
+	handler Handler
+	cps     CheckpointStore
+	start   types.TipSetKey
+
+	runLk sync.Mutex
+
+	lk     sync.Mutex
+	last   *types.TipSet
+	cursor uint64
+}
+
+type Option func(*Follower)
+
+// WithCheckpoints persists the progress of the follower, so that it resumes
+// where it stopped. By default the progress is kept in memory.
+func WithCheckpoints(cps CheckpointStore) Option {
+	return func(f *Follower) {
+		f.cps = cps
+	}
+}
+
+// WithStart makes the follower apply the tipsets from the given one up to
+// the head when it has no checkpoint, instead of starting at the head.
+func WithStart(tsk types.TipSetKey) Option {
+	return func(f *Follower) {
+		f.start = tsk
+	}
+}
+
+// New creates a follower calling the handler.
+func New(a FollowerAPI, h Handler, opts ...Option) *Follower {
+	f := &Follower{
+		api:     a,
+		handler: h,
+		cps:     NewMemoryCheckpoints(),
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+// Head returns the last tipset passed to Apply, or nil before the first.
+func (f *Follower) Head() *types.TipSet {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.last
+}
+
+// Run follows the chain until the context is cancelled or the handler fails.
+// It reconnects when the subscription ends, e.g. when the node restarts.
+func (f *Follower) Run(ctx context.Context) error {
+	f.runLk.Lock()
+	defer f.runLk.Unlock()
+
+	if err := f.restore(ctx); err != nil {
+		return err
+	}
+
+	backoff := minBackoff
+	for {
+		ch, err := f.api.ChainNotifyFrom(ctx, f.cursor)
+		if err != nil && f.cursor != 0 && f.Head() != nil && ctx.Err() == nil {
+			// the journal may no longer have the cursor; starting again from
+			// the head fills the gap from the last applied tipset
+			log.Warnw("resuming from the journal cursor failed, catching up from the last applied tipset", "cursor", f.cursor, "error", err)
+			f.cursor = 0
+			ch, err = f.api.ChainNotifyFrom(ctx, 0)
+		}
+
+		if err == nil {
+			backoff = minBackoff
+			if err := f.follow(ctx, ch); err != nil {
+				return err
+			}
+		} else {
+			log.Warnw("subscribing to head changes", "error", err)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		select {
+		case <-build.Clock.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (f *Follower) restore(ctx context.Context) error {
+	cp, err := f.cps.Load(ctx)
+	if err != nil {
+		return xerrors.Errorf("loading checkpoint: %w", err)
+	}
+
+	var last types.TipSetKey
+	if cp != nil {
+		f.cursor = cp.Cursor
+		last = cp.TipSet
+	}
+
+	if last == types.EmptyTSK && f.start != types.EmptyTSK {
+		start, err := f.api.ChainGetTipSet(ctx, f.start)
+		if err != nil {
+			return xerrors.Errorf("loading start tipset: %w", err)
+		}
+		// the start tipset is applied when catching up with the head
+		last = start.Parents()
+	}
+
+	if last == types.EmptyTSK {
+		return nil
+	}
+
+	ts, err := f.api.ChainGetTipSet(ctx, last)
+	if err != nil {
+		return xerrors.Errorf("loading last applied tipset: %w", err)
+	}
+	f.setLast(ts)
+	return nil
+}
+
+// follow processes the head changes until the channel is closed.
+func (f *Follower) follow(ctx context.Context, ch <-chan *api.HeadChanges) error {
+	for {
+		select {
+		case hc, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := f.process(ctx, hc); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (f *Follower) process(ctx context.Context, hc *api.HeadChanges) error {
+	for _, c := range hc.Changes {
+		switch c.Type {
+		case store.HCCurrent:
+			if err := f.moveTo(ctx, c.Val); err != nil {
+				return err
+			}
+		case store.HCApply:
+			seen, err := f.onChain(ctx, c.Val)
+			if err != nil {
+				return err
+			}
+			if seen {
+				continue
+			}
+			if err := f.moveTo(ctx, c.Val); err != nil {
+				return err
+			}
+		case store.HCRevert:
+			// reverts of tipsets which the handler didn't see are skipped
+			seen, err := f.onChain(ctx, c.Val)
+			if err != nil {
+				return err
+			}
+			if !seen {
+				continue
+			}
+			pts, err := f.api.ChainGetTipSet(ctx, c.Val.Parents())
+			if err != nil {
+				return xerrors.Errorf("loading parent of reverted tipset: %w", err)
+			}
+			if err := f.moveTo(ctx, pts); err != nil {
+				return err
+			}
+		}
+	}
+
+	f.cursor = hc.Cursor
+	return f.save(ctx)
+}
+
+// onChain returns whether ts is the last applied tipset or one of its
+// ancestors.
+func (f *Follower) onChain(ctx context.Context, ts *types.TipSet) (bool, error) {
+	cur := f.Head()
+	if cur == nil {
+		return false, nil
+	}
+
+	for cur.Height() > ts.Height() {
+		var err error
+		cur, err = f.api.ChainGetTipSet(ctx, cur.Parents())
+		if err != nil {
+			return false, xerrors.Errorf("loading tipset: %w", err)
+		}
+	}
+	return cur.Equals(ts), nil
+}
+
+// moveTo reverts the applied tipsets down to the common ancestor with
+// target, then applies the tipsets up to target.
+func (f *Follower) moveTo(ctx context.Context, target *types.TipSet) error {
+	from := f.Head()
+	if from == nil {
+		return f.apply(ctx, target)
+	}
+
+	var reverts, applies []*types.TipSet
+	for !from.Equals(target) {
+		var err error
+		if from.Height() >= target.Height() {
+			reverts = append(reverts, from)
+			from, err = f.api.ChainGetTipSet(ctx, from.Parents())
+		} else {
+			applies = append(applies, target)
+			target, err = f.api.ChainGetTipSet(ctx, target.Parents())
+		}
+		if err != nil {
+			return xerrors.Errorf("finding common ancestor: %w", err)
+		}
+	}
+
+	for _, ts := range reverts {
+		if err := f.handler.Revert(ctx, ts); err != nil {
+			return xerrors.Errorf("reverting tipset at %d: %w", ts.Height(), err)
+		}
+
+		pts, err := f.api.ChainGetTipSet(ctx, ts.Parents())
+		if err != nil {
+			return xerrors.Errorf("loading parent of reverted tipset: %w", err)
+		}
+		f.setLast(pts)
+		if err := f.save(ctx); err != nil {
+			return err
+		}
+	}
+
+	for i := len(applies) - 1; i >= 0; i-- {
+		if err := f.apply(ctx, applies[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Follower) apply(ctx context.Context, ts *types.TipSet) error {
+	if err := f.handler.Apply(ctx, ts); err != nil {
+		return xerrors.Errorf("applying tipset at %d: %w", ts.Height(), err)
+	}
+	f.setLast(ts)
+	return f.save(ctx)
+}
+
+func (f *Follower) setLast(ts *types.TipSet) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.last = ts
+}
+
+// save records the progress. The cursor is only advanced once a journal
+// entry is fully processed; replaying it skips the tipsets already handled.
+func (f *Follower) save(ctx context.Context) error {
+	cp := Checkpoint{Cursor: f.cursor}
+	if last := f.Head(); last != nil {
+		cp.TipSet = last.Key()
+	}
+	if err := f.cps.Save(ctx, cp); err != nil {
+		return xerrors.Errorf("saving checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package follower
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var dummyCid, _ = cid.Parse("bafkqaaa")
+
+type fakeAPI struct {
+	t       *testing.T
+	lk      sync.Mutex
+	tipsets map[types.TipSetKey]*types.TipSet
+
+	cursors []uint64
+	subs    chan chan *api.HeadChanges
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	return &fakeAPI{
+		t:       t,
+		tipsets: map[types.TipSetKey]*types.TipSet{},
+		subs:    make(chan chan *api.HeadChanges, 4),
+	}
+}
+
+func (f *fakeAPI) makeTs(parent *types.TipSet, h abi.ChainEpoch, fork byte) *types.TipSet {
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(f.t, err)
+
+	var parents []cid.Cid
+	if parent != nil {
+		parents = parent.Cids()
+	}
+
+	ts, err := types.NewTipSet([]*types.BlockHeader{{
+		Height:                h,
+		Miner:                 miner,
+		Parents:               parents,
+		Ticket:                &types.Ticket{VRFProof: []byte{fork}},
+		ParentStateRoot:       dummyCid,
+		Messages:              dummyCid,
+		ParentMessageReceipts: dummyCid,
+		BlockSig:              &crypto.Signature{Type: crypto.SigTypeBLS},
+		BLSAggregate:          &crypto.Signature{Type: crypto.SigTypeBLS},
+	}})
+	require.NoError(f.t, err)
+
+	f.lk.Lock()
+	f.tipsets[ts.Key()] = ts
+	f.lk.Unlock()
+	return ts
+}
+
+func (f *fakeAPI) ChainNotifyFrom(ctx context.Context, cursor uint64) (<-chan *api.HeadChanges, error) {
+	f.lk.Lock()
+	f.cursors = append(f.cursors, cursor)
+	f.lk.Unlock()
+
+	select {
+	case ch := <-f.subs:
+		return ch, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeAPI) ChainGetTipSet(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	ts, ok := f.tipsets[tsk]
+	if !ok {
+		return nil, fmt.Errorf("tipset %s not found", tsk)
+	}
+	return ts, nil
+}
+
+type recorder struct {
+	lk     sync.Mutex
+	events []string
+}
+
+func (r *recorder) handler() Handler {
+	return HandlerFuncs{
+		ApplyFunc: func(ctx context.Context, ts *types.TipSet) error {
+			r.record(fmt.Sprintf("apply %d/%d", ts.Height(), ts.Blocks()[0].Ticket.VRFProof[0]))
+			return nil
+		},
+		RevertFunc: func(ctx context.Context, ts *types.TipSet) error {
+			r.record(fmt.Sprintf("revert %d/%d", ts.Height(), ts.Blocks()[0].Ticket.VRFProof[0]))
+			return nil
+		},
+	}
+}
+
+func (r *recorder) record(e string) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) get() []string {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func hc(cursor uint64, truncated bool, changes ...*api.HeadChange) *api.HeadChanges {
+	return &api.HeadChanges{Cursor: cursor, Changes: changes, Truncated: truncated}
+}
+
+func apply(ts *types.TipSet) *api.HeadChange {
+	return &api.HeadChange{Type: store.HCApply, Val: ts}
+}
+
+func revert(ts *types.TipSet) *api.HeadChange {
+	return &api.HeadChange{Type: store.HCRevert, Val: ts}
+}
+
+func runFollower(t *testing.T, f *Follower, rec *recorder, want []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(rec.get()) >= len(want)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, want, rec.get())
+
+	cancel()
+	require.Equal(t, context.Canceled, <-done)
+}
+
+func TestFollower(t *testing.T) {
+	fapi := newFakeAPI(t)
+
+	gen := fapi.makeTs(nil, 0, 0)
+	a1 := fapi.makeTs(gen, 1, 0)
+	a2 := fapi.makeTs(a1, 2, 0)
+	a3 := fapi.makeTs(a2, 3, 0)
+	b3 := fapi.makeTs(a2, 3, 1)
+	b4 := fapi.makeTs(b3, 4, 1)
+	b5 := fapi.makeTs(b4, 5, 1)
+	b6 := fapi.makeTs(b5, 6, 1)
+	b7 := fapi.makeTs(b6, 7, 1)
+
+	cps := NewDatastoreCheckpoints(dssync.MutexWrap(datastore.NewMapDatastore()), datastore.NewKey("/follower"))
+
+	ch := make(chan *api.HeadChanges, 16)
+	ch <- hc(0, false, &api.HeadChange{Type: store.HCCurrent, Val: a2})
+	ch <- hc(1, false, apply(a3))
+	ch <- hc(2, false, revert(a3), apply(b3), apply(b4))
+	// b5 is missing from the truncated entry
+	ch <- hc(3, true, apply(b6))
+	fapi.subs <- ch
+
+	rec := &recorder{}
+	f := New(fapi, rec.handler(), WithCheckpoints(cps))
+	runFollower(t, f, rec, []string{
+		"apply 2/0",
+		"apply 3/0",
+		"revert 3/0",
+		"apply 3/1",
+		"apply 4/1",
+		"apply 5/1",
+		"apply 6/1",
+	})
+	require.True(t, f.Head().Equals(b6))
+
+	cp, err := cps.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), cp.Cursor)
+	require.Equal(t, b6.Key(), cp.TipSet)
+
+	// a new follower resumes from the checkpoint, skipping what was handled
+	ch = make(chan *api.HeadChanges, 16)
+	ch <- hc(3, true, apply(b6))
+	ch <- hc(4, false, apply(b7))
+	fapi.subs <- ch
+
+	rec = &recorder{}
+	f = New(fapi, rec.handler(), WithCheckpoints(cps))
+	runFollower(t, f, rec, []string{
+		"apply 7/1",
+	})
+
+	fapi.lk.Lock()
+	require.Equal(t, []uint64{0, 3}, fapi.cursors[:2])
+	fapi.lk.Unlock()
+}
+
+func TestFollowerStart(t *testing.T) {
+	fapi := newFakeAPI(t)
+
+	gen := fapi.makeTs(nil, 0, 0)
+	a1 := fapi.makeTs(gen, 1, 0)
+	a2 := fapi.makeTs(a1, 2, 0)
+	a3 := fapi.makeTs(a2, 3, 0)
+
+	ch := make(chan *api.HeadChanges, 16)
+	ch <- hc(7, false, &api.HeadChange{Type: store.HCCurrent, Val: a3})
+	fapi.subs <- ch
+
+	rec := &recorder{}
+	f := New(fapi, rec.handler(), WithStart(a1.Key()))
+	runFollower(t, f, rec, []string{
+		"apply 1/0",
+		"apply 2/0",
+		"apply 3/0",
+	})
+}
@@ -15,7 +15,6 @@ import (
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/vm"
-	"github.com/filecoin-project/lotus/lib/sigs/bls"
 )
 
 func MinerCreateBlock(ctx context.Context, sm *stmgr.StateManager, w api.WalletAPI, bt *api.BlockTemplate) (*types.FullBlock, error) {
@@ -96,7 +95,7 @@ func MinerCreateBlock(ctx context.Context, sm *stmgr.StateManager, w api.WalletA
 	}
 	next.Messages = mmcid
 
-	aggSig, err := aggregateSignatures(blsSigs)
+	aggSig, err := types.AggregateSignatures(blsSigs)
 	if err != nil {
 		return nil, err
 	}
@@ -148,39 +147,6 @@ func MinerCreateBlock(ctx context.Context, sm *stmgr.StateManager, w api.WalletA
 	return fullBlock, nil
 }
 
-func aggregateSignatures(sigs []crypto.Signature) (*crypto.Signature, error) {
-	sigsS := make([][]byte, len(sigs))
-	for i := 0; i < len(sigs); i++ {
-		sigsS[i] = sigs[i].Data
-	}
-
-	aggregator := new(bls.AggregateSignature).AggregateCompressed(sigsS)
-	if aggregator == nil {
-		if len(sigs) > 0 {
-			return nil, xerrors.Errorf("bls.Aggregate returned nil with %d signatures", len(sigs))
-		}
-
-		// Note: for blst this condition should not happen - nil should not
-		// be returned
-		return &crypto.Signature{
-			Type: crypto.SigTypeBLS,
-			Data: new(bls.Signature).Compress(),
-		}, nil
-	}
-	aggSigAff := aggregator.ToAffine()
-	if aggSigAff == nil {
-		return &crypto.Signature{
-			Type: crypto.SigTypeBLS,
-			Data: new(bls.Signature).Compress(),
-		}, nil
-	}
-	aggSig := aggSigAff.Compress()
-	return &crypto.Signature{
-		Type: crypto.SigTypeBLS,
-		Data: aggSig,
-	}, nil
-}
-
 func toArray(store adt.Store, cids []cid.Cid) (cid.Cid, error) {
 	arr := adt.MakeEmptyArray(store)
 	for i, c := range cids {
@@ -0,0 +1,90 @@
+package messagepool
+
+import (
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// chainCache holds the message chains of the pending messages of each actor
+// for a base tipset, so that selecting messages repeatedly for the same base
+// (e.g. for several winning tickets, or when a block is retried) doesn't
+// recompute the chains of the actors whose pending messages didn't change.
+// The chains depend on the actor state and base fee of the base tipset, so
+// the cache is dropped once a different base is used.
+type chainCache struct {
+	tsk     types.TipSetKey
+	baseFee types.BigInt
+	actors  map[address.Address]*cachedChains
+}
+
+type cachedChains struct {
+	msgs   map[uint64]*types.SignedMessage
+	chains []*msgChain
+}
+
+// getMessageChains returns the message chains of the actor for the pending
+// messages; it must be called with lk held. The chains are copies, owned by
+// the caller.
+func (mp *MessagePool) getMessageChains(actor address.Address, mset map[uint64]*types.SignedMessage, baseFee types.BigInt, ts *types.TipSet) []*msgChain {
+	cc := mp.chainCache
+	if cc == nil || cc.tsk != ts.Key() || !cc.baseFee.Equals(baseFee) {
+		cc = &chainCache{
+			tsk:     ts.Key(),
+			baseFee: baseFee,
+			actors:  make(map[address.Address]*cachedChains),
+		}
+		mp.chainCache = cc
+	}
+
+	if cached, ok := cc.actors[actor]; ok && sameMessages(cached.msgs, mset) {
+		return copyChains(cached.chains)
+	}
+
+	chains := mp.createMessageChains(actor, mset, baseFee, ts)
+
+	// the set may be the one of the pool, which changes as messages are added
+	msgs := make(map[uint64]*types.SignedMessage, len(mset))
+	for nonce, m := range mset {
+		msgs[nonce] = m
+	}
+	cc.actors[actor] = &cachedChains{msgs: msgs, chains: chains}
+
+	return copyChains(chains)
+}
+
+func sameMessages(a, b map[uint64]*types.SignedMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for nonce, m := range a {
+		if b[nonce] != m {
+			return false
+		}
+	}
+	return true
+}
+
+// copyChains copies the chains of an actor, as selection modifies them while
+// merging, trimming and invalidating.
+func copyChains(chains []*msgChain) []*msgChain {
+	out := make([]*msgChain, len(chains))
+	for i, c := range chains {
+		out[i] = &msgChain{
+			msgs:      append([]*types.SignedMessage(nil), c.msgs...),
+			gasReward: c.gasReward,
+			gasLimit:  c.gasLimit,
+			gasPerf:   c.gasPerf,
+			valid:     c.valid,
+		}
+	}
+
+	for i := 0; i < len(out)-1; i++ {
+		out[i].next = out[i+1]
+	}
+	for i := len(out) - 1; i > 0; i-- {
+		out[i].prev = out[i-1]
+	}
+
+	return out
+}
@@ -11,11 +11,12 @@ import (
 )
 
 var (
-	ReplaceByFeeRatioDefault  = 1.25
-	MemPoolSizeLimitHiDefault = 30000
-	MemPoolSizeLimitLoDefault = 20000
-	PruneCooldownDefault      = time.Minute
-	GasLimitOverestimation    = 1.25
+	ReplaceByFeeRatioDefault   = 1.25
+	MemPoolSizeLimitHiDefault  = 30000
+	MemPoolSizeLimitLoDefault  = 20000
+	MemPoolSizeLimitMaxDefault = 60000
+	PruneCooldownDefault       = time.Minute
+	GasLimitOverestimation     = 1.25
 
 	ConfigKey = datastore.NewKey("/mpool/config")
 )
@@ -34,7 +35,8 @@ func loadConfig(ds dtypes.MetadataDS) (*types.MpoolConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	cfg := new(types.MpoolConfig)
+	// configs saved by older versions lack the newer fields, keep their defaults
+	cfg := DefaultConfig()
 	err = json.Unmarshal(cfgBytes, cfg)
 	return cfg, err
 }
@@ -53,6 +55,14 @@ func (mp *MessagePool) GetConfig() *types.MpoolConfig {
 	return mp.cfg.Clone()
 }
 
+// getConfig returns the current config without copying it; it must not be
+// modified.
+func (mp *MessagePool) getConfig() *types.MpoolConfig {
+	mp.cfgLk.Lock()
+	defer mp.cfgLk.Unlock()
+	return mp.cfg
+}
+
 func validateConfg(cfg *types.MpoolConfig) error {
 	if cfg.ReplaceByFeeRatio < ReplaceByFeeRatioDefault {
 		return fmt.Errorf("'ReplaceByFeeRatio' is less than required %f < %f",
@@ -61,6 +71,21 @@ func validateConfg(cfg *types.MpoolConfig) error {
 	if cfg.GasLimitOverestimation < 1 {
 		return fmt.Errorf("'GasLimitOverestimation' cannot be less than 1")
 	}
+	if cfg.SizeLimitLow > cfg.SizeLimitHigh {
+		return fmt.Errorf("'SizeLimitLow' cannot be greater than 'SizeLimitHigh'")
+	}
+	if cfg.SizeLimitMax != 0 && cfg.SizeLimitMax < cfg.SizeLimitHigh {
+		return fmt.Errorf("'SizeLimitMax' cannot be less than 'SizeLimitHigh'")
+	}
+	if cfg.MaxActorPendingMessages < 1 || cfg.MaxUntrustedActorPendingMessages < 1 {
+		return fmt.Errorf("'MaxActorPendingMessages' and 'MaxUntrustedActorPendingMessages' must be positive")
+	}
+	if cfg.MinUntrustedGasPremium.Int != nil && cfg.MinUntrustedGasPremium.Sign() < 0 {
+		return fmt.Errorf("'MinUntrustedGasPremium' cannot be negative")
+	}
+	if cfg.MinSelectionGasPremium.Int != nil && cfg.MinSelectionGasPremium.Sign() < 0 {
+		return fmt.Errorf("'MinSelectionGasPremium' cannot be negative")
+	}
 	return nil
 }
 
@@ -69,6 +94,12 @@ func (mp *MessagePool) SetConfig(cfg *types.MpoolConfig) error {
 		return err
 	}
 	cfg = cfg.Clone()
+	if cfg.MinUntrustedGasPremium.Int == nil {
+		cfg.MinUntrustedGasPremium = types.NewInt(0)
+	}
+	if cfg.MinSelectionGasPremium.Int == nil {
+		cfg.MinSelectionGasPremium = types.NewInt(0)
+	}
 
 	mp.cfgLk.Lock()
 	mp.cfg = cfg
@@ -88,5 +119,12 @@ func DefaultConfig() *types.MpoolConfig {
 		ReplaceByFeeRatio:      ReplaceByFeeRatioDefault,
 		PruneCooldown:          PruneCooldownDefault,
 		GasLimitOverestimation: GasLimitOverestimation,
+
+		SizeLimitMax:                     MemPoolSizeLimitMaxDefault,
+		MaxActorPendingMessages:          MaxActorPendingMessages,
+		MaxUntrustedActorPendingMessages: MaxUntrustedActorPendingMessages,
+		MinUntrustedGasPremium:           types.NewInt(0),
+
+		MinSelectionGasPremium: types.NewInt(0),
 	}
 }
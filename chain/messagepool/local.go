@@ -0,0 +1,122 @@
+package messagepool
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// LocalMessages returns the messages pushed through this node. They are kept
+// in the datastore and republished, also after restarts, until they are
+// included in the chain or abandoned. The messages are ordered by sender and
+// nonce.
+func (mp *MessagePool) LocalMessages() ([]*types.SignedMessage, error) {
+	res, err := mp.localMsgs.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("query local messages: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var out []*types.SignedMessage
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("r.Error: %w", r.Error)
+		}
+
+		var sm types.SignedMessage
+		if err := sm.UnmarshalCBOR(bytes.NewReader(r.Value)); err != nil {
+			return nil, xerrors.Errorf("unmarshaling local message: %w", err)
+		}
+		out = append(out, &sm)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Message.From != out[j].Message.From {
+			return out[i].Message.From.String() < out[j].Message.From.String()
+		}
+		return out[i].Message.Nonce < out[j].Message.Nonce
+	})
+	return out, nil
+}
+
+// AbandonLocal stops republishing the local message, removing it from the
+// datastore and from the pending messages. The nonce of the message is handed
+// out again by GetNonce if it was the highest pending one; otherwise the
+// pending messages of the sender with higher nonces can't be included until a
+// message with the abandoned nonce is pushed.
+func (mp *MessagePool) AbandonLocal(c cid.Cid) error {
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+
+	key := datastore.NewKey(string(c.Bytes()))
+	b, err := mp.localMsgs.Get(key)
+	if err == datastore.ErrNotFound {
+		return xerrors.Errorf("%s is not a local message", c)
+	}
+	if err != nil {
+		return xerrors.Errorf("getting local message: %w", err)
+	}
+
+	var sm types.SignedMessage
+	if err := sm.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return xerrors.Errorf("unmarshaling local message: %w", err)
+	}
+
+	if err := mp.localMsgs.Delete(key); err != nil {
+		return xerrors.Errorf("deleting local message: %w", err)
+	}
+
+	if mset, ok := mp.pending[sm.Message.From]; ok {
+		if m, ok := mset.msgs[sm.Message.Nonce]; ok && m.Cid() == c {
+			mp.remove(sm.Message.From, sm.Message.Nonce, api.MpoolRemoveAbandoned)
+		}
+	}
+
+	return nil
+}
+
+// pruneLocalMessages deletes the local messages with a nonce below the state
+// nonce of their sender at the current head from the datastore, as they or
+// messages replacing them were included in the chain.
+func (mp *MessagePool) pruneLocalMessages() error {
+	mp.curTsLk.Lock()
+	ts := mp.curTs
+	mp.curTsLk.Unlock()
+
+	msgs, err := mp.LocalMessages()
+	if err != nil {
+		return err
+	}
+
+	nonces := make(map[address.Address]uint64)
+	for _, m := range msgs {
+		snonce, ok := nonces[m.Message.From]
+		if !ok {
+			snonce, err = mp.getStateNonce(m.Message.From, ts)
+			if err != nil {
+				log.Warnf("getting state nonce of %s: %s", m.Message.From, err)
+				continue
+			}
+			nonces[m.Message.From] = snonce
+		}
+
+		if m.Message.Nonce >= snonce {
+			continue
+		}
+
+		if err := mp.localMsgs.Delete(datastore.NewKey(string(m.Cid().Bytes()))); err != nil {
+			return xerrors.Errorf("deleting included local message: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -78,6 +78,8 @@ var (
 	ErrRBFTooLowPremium       = errors.New("replace by fee has too low GasPremium")
 	ErrTooManyPendingMessages = errors.New("too many pending messages for actor")
 	ErrNonceGap               = errors.New("unfulfilled nonce gap")
+	ErrGasPremiumTooLow       = errors.New("gas premium too low")
+	ErrMpoolFull              = errors.New("mpool is full")
 )
 
 const (
@@ -138,6 +140,12 @@ type MessagePool struct {
 	cfgLk sync.Mutex
 	cfg   *types.MpoolConfig
 
+	// selPolicy is guarded by lk
+	selPolicy SelectionPolicy
+
+	// chainCache is guarded by lk
+	chainCache *chainCache
+
 	api Provider
 
 	minGasPrice types.BigInt
@@ -203,11 +211,12 @@ func (ms *msgSet) add(m *types.SignedMessage, mp *MessagePool, strict, untrusted
 	nextNonce := ms.nextNonce
 	nonceGap := false
 
+	cfg := mp.getConfig()
 	maxNonceGap := MaxNonceGap
-	maxActorPendingMessages := MaxActorPendingMessages
+	maxActorPendingMessages := cfg.MaxActorPendingMessages
 	if untrusted {
 		maxNonceGap = 0
-		maxActorPendingMessages = MaxUntrustedActorPendingMessages
+		maxActorPendingMessages = cfg.MaxUntrustedActorPendingMessages
 	}
 
 	switch {
@@ -350,6 +359,7 @@ func New(api Provider, ds dtypes.MetadataDS, netName dtypes.NetworkName, j journ
 		api:           api,
 		netName:       netName,
 		cfg:           cfg,
+		selPolicy:     DefaultSelectionPolicy{},
 		evtTypes: [...]journal.EventType{
 			evtTypeMpoolAdd:    j.RegisterEventType("mpool", "add"),
 			evtTypeMpoolRemove: j.RegisterEventType("mpool", "remove"),
@@ -415,6 +425,9 @@ func (mp *MessagePool) runLoop() {
 			if err := mp.republishPendingMessages(); err != nil {
 				log.Errorf("error while republishing messages: %s", err)
 			}
+			if err := mp.pruneLocalMessages(); err != nil {
+				log.Errorf("error while pruning included local messages: %s", err)
+			}
 		case <-mp.repubTrigger:
 			if err := mp.republishPendingMessages(); err != nil {
 				log.Errorf("error while republishing messages: %s", err)
@@ -534,6 +547,90 @@ func (mp *MessagePool) Push(m *types.SignedMessage) (cid.Cid, error) {
 	return m.Cid(), err
 }
 
+// PushBatch pushes the local messages as a unit: either all of them are
+// added, or none if one of them fails the checks of Push. The messages can't
+// replace pending messages, and the ones of a sender must be in nonce order.
+func (mp *MessagePool) PushBatch(msgs []*types.SignedMessage) ([]cid.Cid, error) {
+	for i, m := range msgs {
+		if err := mp.checkMessage(m); err != nil {
+			return nil, xerrors.Errorf("message %d: %w", i, err)
+		}
+	}
+
+	// serialize push access to reduce lock contention
+	mp.addSema <- struct{}{}
+	defer func() {
+		<-mp.addSema
+	}()
+
+	msgbs := make([][]byte, len(msgs))
+	for i, m := range msgs {
+		msgb, err := m.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		msgbs[i] = msgb
+	}
+
+	// the lock keeps the head from changing while the messages are added, so
+	// that the added ones can be removed again if one fails
+	mp.curTsLk.Lock()
+
+	publish := make([]bool, len(msgs))
+	for i, m := range msgs {
+		err := mp.checkBatchNonce(m)
+		if err == nil {
+			publish[i], err = mp.addTs(m, mp.curTs, true, false)
+		}
+		if err != nil {
+			mp.lk.Lock()
+			for j := i - 1; j >= 0; j-- {
+				mp.remove(msgs[j].Message.From, msgs[j].Message.Nonce, api.MpoolRemoveAbandoned)
+			}
+			mp.lk.Unlock()
+			mp.curTsLk.Unlock()
+			return nil, xerrors.Errorf("message %d: %w", i, err)
+		}
+	}
+	mp.curTsLk.Unlock()
+
+	mp.lk.Lock()
+	for i, m := range msgs {
+		if err := mp.addLocal(m, msgbs[i]); err != nil {
+			mp.lk.Unlock()
+			return nil, err
+		}
+	}
+	mp.lk.Unlock()
+
+	cids := make([]cid.Cid, len(msgs))
+	var perr error
+	for i, m := range msgs {
+		cids[i] = m.Cid()
+		if publish[i] {
+			if err := mp.api.PubSubPublish(build.MessagesTopic(mp.netName), msgbs[i]); err != nil && perr == nil {
+				perr = err
+			}
+		}
+	}
+
+	return cids, perr
+}
+
+// checkBatchNonce refuses messages replacing pending ones, as they couldn't
+// be restored if the batch fails.
+func (mp *MessagePool) checkBatchNonce(m *types.SignedMessage) error {
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+
+	if mset, ok := mp.pending[m.Message.From]; ok {
+		if _, ok := mset.msgs[m.Message.Nonce]; ok {
+			return xerrors.Errorf("message from %s with nonce %d already in mpool: %w", m.Message.From, m.Message.Nonce, ErrSoftValidationFailure)
+		}
+	}
+	return nil
+}
+
 func (mp *MessagePool) checkMessage(m *types.SignedMessage) error {
 	// big messages are bad, anti DOS
 	if m.Size() > 32*1024 {
@@ -658,9 +755,21 @@ func (mp *MessagePool) addTs(m *types.SignedMessage, curTs *types.TipSet, local,
 		return false, xerrors.Errorf("minimum expected nonce is %d: %w", snonce, ErrNonceTooLow)
 	}
 
+	if !local {
+		if minPremium := mp.getConfig().MinUntrustedGasPremium; m.Message.GasPremium.LessThan(minPremium) {
+			return false, xerrors.Errorf("gas premium %s is below the minimum of %s: %w", m.Message.GasPremium, minPremium, ErrGasPremiumTooLow)
+		}
+	}
+
 	mp.lk.Lock()
 	defer mp.lk.Unlock()
 
+	if !local {
+		if sizeMax := mp.getConfig().SizeLimitMax; sizeMax > 0 && mp.currentSize >= sizeMax {
+			return false, xerrors.Errorf("%d pending messages: %w", mp.currentSize, ErrMpoolFull)
+		}
+	}
+
 	publish, err := mp.verifyMsgBeforeAdd(m, curTs, local)
 	if err != nil {
 		return false, err
@@ -740,12 +849,18 @@ func (mp *MessagePool) addLocked(m *types.SignedMessage, strict, untrusted bool)
 		mp.pending[m.Message.From] = mset
 	}
 
+	replaced, replacing := mset.msgs[m.Message.Nonce]
+
 	incr, err := mset.add(m, mp, strict, untrusted)
 	if err != nil {
 		log.Debug(err)
 		return err
 	}
 
+	if replacing {
+		mp.publishRemove(replaced, api.MpoolRemoveReplaced)
+	}
+
 	if incr {
 		mp.currentSize++
 		if mp.currentSize > mp.cfg.SizeLimitHigh {
@@ -820,11 +935,13 @@ func (mp *MessagePool) getStateBalance(addr address.Address, ts *types.TipSet) (
 	return act.Balance, nil
 }
 
-// this method is provided for the gateway to push messages.
-// differences from Push:
-//  - strict checks are enabled
-//  - extra strict add checks are used when adding the messages to the msgSet
-//    that means: no nonce gaps, at most 10 pending messages for the actor
+// PushUntrusted adds a message pushed by a client this node doesn't trust, e.g.
+// through lotus-gateway, and publishes it. Unlike Push, the message is
+// validated with the strict checks of the messages received from the network,
+// and added with the extra strict checks of the untrusted actors: no nonce
+// gaps, and at most MaxUntrustedActorPendingMessages pending messages for the
+// actor. Unlike local messages, it isn't persisted, republished or protected
+// from pruning.
 func (mp *MessagePool) PushUntrusted(m *types.SignedMessage) (cid.Cid, error) {
 	err := mp.checkMessage(m)
 	if err != nil {
@@ -850,13 +967,6 @@ func (mp *MessagePool) PushUntrusted(m *types.SignedMessage) (cid.Cid, error) {
 	}
 	mp.curTsLk.Unlock()
 
-	mp.lk.Lock()
-	if err := mp.addLocal(m, msgb); err != nil {
-		mp.lk.Unlock()
-		return cid.Undef, err
-	}
-	mp.lk.Unlock()
-
 	if publish {
 		err = mp.api.PubSubPublish(build.MessagesTopic(mp.netName), msgb)
 	}
@@ -868,39 +978,47 @@ func (mp *MessagePool) Remove(from address.Address, nonce uint64, applied bool)
 	mp.lk.Lock()
 	defer mp.lk.Unlock()
 
-	mp.remove(from, nonce, applied)
+	reason := api.MpoolRemoveEvicted
+	if applied {
+		reason = api.MpoolRemoveIncluded
+	}
+	mp.remove(from, nonce, reason)
 }
 
-func (mp *MessagePool) remove(from address.Address, nonce uint64, applied bool) {
+func (mp *MessagePool) remove(from address.Address, nonce uint64, reason api.MpoolRemoveReason) {
 	mset, ok := mp.pending[from]
 	if !ok {
 		return
 	}
 
 	if m, ok := mset.msgs[nonce]; ok {
-		mp.changes.Pub(api.MpoolUpdate{
-			Type:    api.MpoolRemove,
-			Message: m,
-		}, localUpdates)
-
-		mp.journal.RecordEvent(mp.evtTypes[evtTypeMpoolRemove], func() interface{} {
-			return MessagePoolEvt{
-				Action:   "remove",
-				Messages: []MessagePoolEvtMessage{{Message: m.Message, CID: m.Cid()}}}
-		})
-
+		mp.publishRemove(m, reason)
 		mp.currentSize--
 	}
 
 	// NB: This deletes any message with the given nonce. This makes sense
 	// as two messages with the same sender cannot have the same nonce
-	mset.rm(nonce, applied)
+	mset.rm(nonce, reason == api.MpoolRemoveIncluded)
 
 	if len(mset.msgs) == 0 {
 		delete(mp.pending, from)
 	}
 }
 
+func (mp *MessagePool) publishRemove(m *types.SignedMessage, reason api.MpoolRemoveReason) {
+	mp.changes.Pub(api.MpoolUpdate{
+		Type:    api.MpoolRemove,
+		Message: m,
+		Reason:  reason,
+	}, localUpdates)
+
+	mp.journal.RecordEvent(mp.evtTypes[evtTypeMpoolRemove], func() interface{} {
+		return MessagePoolEvt{
+			Action:   "remove",
+			Messages: []MessagePoolEvtMessage{{Message: m.Message, CID: m.Cid()}}}
+	})
+}
+
 func (mp *MessagePool) Pending() ([]*types.SignedMessage, *types.TipSet) {
 	mp.curTsLk.Lock()
 	defer mp.curTsLk.Unlock()
@@ -1232,6 +1350,26 @@ func (mp *MessagePool) RecoverSig(msg *types.Message) *types.SignedMessage {
 }
 
 func (mp *MessagePool) Updates(ctx context.Context) (<-chan api.MpoolUpdate, error) {
+	return mp.updates(ctx, func(api.MpoolUpdate) bool { return true })
+}
+
+// UpdatesFor returns the updates of the messages sent from or to one of the
+// addresses. Addresses are compared as given; an ID address doesn't match the
+// key address of the same actor.
+func (mp *MessagePool) UpdatesFor(ctx context.Context, addrs []address.Address) (<-chan api.MpoolUpdate, error) {
+	set := make(map[address.Address]struct{}, len(addrs))
+	for _, a := range addrs {
+		set[a] = struct{}{}
+	}
+
+	return mp.updates(ctx, func(u api.MpoolUpdate) bool {
+		_, from := set[u.Message.Message.From]
+		_, to := set[u.Message.Message.To]
+		return from || to
+	})
+}
+
+func (mp *MessagePool) updates(ctx context.Context, match func(api.MpoolUpdate) bool) (<-chan api.MpoolUpdate, error) {
 	out := make(chan api.MpoolUpdate, 20)
 	sub := mp.changes.Sub(localUpdates)
 
@@ -1242,6 +1380,10 @@ func (mp *MessagePool) Updates(ctx context.Context) (<-chan api.MpoolUpdate, err
 		for {
 			select {
 			case u := <-sub:
+				if !match(u.(api.MpoolUpdate)) {
+					continue
+				}
+
 				select {
 				case out <- u.(api.MpoolUpdate):
 				case <-ctx.Done():
@@ -1266,6 +1408,7 @@ func (mp *MessagePool) loadLocal() error {
 		return xerrors.Errorf("query local messages: %w", err)
 	}
 
+	loaded := 0
 	for r := range res.Next() {
 		if r.Error != nil {
 			return xerrors.Errorf("r.Error: %w", r.Error)
@@ -1278,15 +1421,32 @@ func (mp *MessagePool) loadLocal() error {
 
 		if err := mp.addLoaded(&sm); err != nil {
 			if xerrors.Is(err, ErrNonceTooLow) {
-				continue // todo: drop the message from local cache (if above certain confidence threshold)
+				// the message, or one replacing it, was included while we were down
+				if err := mp.localMsgs.Delete(datastore.NewKey(r.Key)); err != nil {
+					log.Warnf("error deleting included local message: %s", err)
+				}
+				continue
 			}
 
 			log.Errorf("adding local message: %+v", err)
+		} else {
+			loaded++
 		}
 
 		mp.localAddrs[sm.Message.From] = struct{}{}
 	}
 
+	if loaded > 0 {
+		log.Infof("loaded %d local messages, republishing them", loaded)
+
+		// don't wait for the republish interval, the messages may not have
+		// propagated before the restart
+		select {
+		case mp.repubTrigger <- struct{}{}:
+		default:
+		}
+	}
+
 	return nil
 }
 
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/messagepool/gasguess"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/types/mock"
@@ -18,6 +20,7 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
 )
 
 func init() {
@@ -63,7 +66,7 @@ func (tma *testMpoolAPI) nextBlockWithHeight(height uint64) *types.BlockHeader {
 	return newBlk
 }
 
-func (tma *testMpoolAPI) applyBlock(t *testing.T, b *types.BlockHeader) {
+func (tma *testMpoolAPI) applyBlock(t testing.TB, b *types.BlockHeader) {
 	t.Helper()
 	if err := tma.cb(nil, []*types.TipSet{mock.TipSet(b)}); err != nil {
 		t.Fatal(err)
@@ -207,7 +210,7 @@ func assertNonce(t *testing.T, mp *MessagePool, addr address.Address, val uint64
 	}
 }
 
-func mustAdd(t *testing.T, mp *MessagePool, msg *types.SignedMessage) {
+func mustAdd(t testing.TB, mp *MessagePool, msg *types.SignedMessage) {
 	t.Helper()
 	if err := mp.Add(msg); err != nil {
 		t.Fatal(err)
@@ -417,6 +420,273 @@ func TestPruningSimple(t *testing.T) {
 	}
 }
 
+func TestConfigLimits(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w1, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w2, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w2.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tma.setBalance(a1, 1) // in FIL
+	tma.setBalance(a2, 1) // in FIL
+
+	cfg := mp.GetConfig()
+	cfg.SizeLimitHigh = 3
+	cfg.SizeLimitLow = 1
+	cfg.SizeLimitMax = 3
+	cfg.MaxActorPendingMessages = 2
+	cfg.MinUntrustedGasPremium = types.NewInt(5)
+	if err := mp.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	err = mp.Add(makeTestMessage(w1, a1, a2, 0, gasLimit, 1))
+	if !xerrors.Is(err, ErrGasPremiumTooLow) {
+		t.Fatalf("expected a message below the minimum premium to be rejected, got: %v", err)
+	}
+
+	mustAdd(t, mp, makeTestMessage(w1, a1, a2, 0, gasLimit, 10))
+	mustAdd(t, mp, makeTestMessage(w1, a1, a2, 1, gasLimit, 10))
+
+	err = mp.Add(makeTestMessage(w1, a1, a2, 2, gasLimit, 10))
+	if !xerrors.Is(err, ErrTooManyPendingMessages) {
+		t.Fatalf("expected a message over the actor limit to be rejected, got: %v", err)
+	}
+
+	// local messages aren't limited
+	if _, err := mp.Push(makeTestMessage(w1, a1, a2, 2, gasLimit, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	err = mp.Add(makeTestMessage(w2, a2, a1, 0, gasLimit, 10))
+	if !xerrors.Is(err, ErrMpoolFull) {
+		t.Fatalf("expected a message to a full mpool to be rejected, got: %v", err)
+	}
+}
+
+func TestPushUntrusted(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w1, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tma.setBalance(a1, 1) // in FIL
+	tma.setBalance(a2, 0)
+
+	cfg := mp.GetConfig()
+	cfg.MinUntrustedGasPremium = types.NewInt(5)
+	if err := mp.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	_, err = mp.PushUntrusted(makeTestMessage(w1, a1, a2, 0, gasLimit, 1))
+	if !xerrors.Is(err, ErrGasPremiumTooLow) {
+		t.Fatalf("expected a message below the minimum premium to be rejected, got: %v", err)
+	}
+
+	if _, err := mp.PushUntrusted(makeTestMessage(w1, a1, a2, 0, gasLimit, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mp.PushUntrusted(makeTestMessage(w1, a1, a2, 2, gasLimit, 10))
+	if !xerrors.Is(err, ErrNonceGap) {
+		t.Fatalf("expected a nonce gapped message to be rejected, got: %v", err)
+	}
+
+	_, err = mp.PushUntrusted(makeTestMessage(w1, a2, a1, 0, gasLimit, 10))
+	if !xerrors.Is(err, ErrNotEnoughFunds) {
+		t.Fatalf("expected a message without funds to be rejected, got: %v", err)
+	}
+
+	// the message is pending, but not local
+	if pending, _ := mp.PendingFor(a1); len(pending) != 1 {
+		t.Fatalf("expected 1 pending message, got %d", len(pending))
+	}
+
+	local, err := mp.LocalMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local) != 0 {
+		t.Fatalf("expected no local messages, got %d", len(local))
+	}
+	if _, ok := mp.localAddrs[a1]; ok {
+		t.Fatal("expected the sender of an untrusted message not to be local")
+	}
+}
+
+func TestUpdatesFor(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var addrs []address.Address
+	for i := 0; i < 3; i++ {
+		a, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tma.setBalance(a, 1) // in FIL
+		addrs = append(addrs, a)
+	}
+	a1, a2, a3 := addrs[0], addrs[1], addrs[2]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := mp.UpdatesFor(ctx, []address.Address{a3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	unrelated := makeTestMessage(w, a1, a2, 0, gasLimit, 1)
+	to := makeTestMessage(w, a1, a3, 1, gasLimit, 1)
+	from := makeTestMessage(w, a3, a2, 0, gasLimit, 1)
+	for _, m := range []*types.SignedMessage{unrelated, to, from} {
+		mustAdd(t, mp, m)
+	}
+
+	for _, expected := range []*types.SignedMessage{to, from} {
+		select {
+		case u := <-updates:
+			if u.Type != api.MpoolAdd || u.Message.Cid() != expected.Cid() {
+				t.Fatalf("expected the add of %s, got %d of %s", expected.Cid(), u.Type, u.Message.Cid())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for update")
+		}
+	}
+}
+
+func TestUpdateReasons(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tma.setBalance(a1, 1) // in FIL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := mp.Updates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(typ api.MpoolChange, m *types.SignedMessage, reason api.MpoolRemoveReason) {
+		t.Helper()
+		select {
+		case u := <-updates:
+			if u.Type != typ || u.Message.Cid() != m.Cid() || u.Reason != reason {
+				t.Fatalf("expected update %d of %s (%s), got %d of %s (%s)", typ, m.Cid(), reason, u.Type, u.Message.Cid(), u.Reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for update")
+		}
+	}
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	m1 := makeTestMessage(w, a1, a2, 0, gasLimit, 100)
+	mustAdd(t, mp, m1)
+	expect(api.MpoolAdd, m1, api.MpoolRemoveNone)
+
+	m2 := makeTestMessage(w, a1, a2, 0, gasLimit, 200)
+	mustAdd(t, mp, m2)
+	expect(api.MpoolRemove, m1, api.MpoolRemoveReplaced)
+	expect(api.MpoolAdd, m2, api.MpoolRemoveNone)
+
+	m3 := makeTestMessage(w, a1, a2, 1, gasLimit, 100)
+	if _, err := mp.Push(m3); err != nil {
+		t.Fatal(err)
+	}
+	expect(api.MpoolAdd, m3, api.MpoolRemoveNone)
+
+	if err := mp.AbandonLocal(m3.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	expect(api.MpoolRemove, m3, api.MpoolRemoveAbandoned)
+
+	b := tma.nextBlock()
+	tma.setBlockMessages(b, m2)
+	tma.applyBlock(t, b)
+	expect(api.MpoolRemove, m2, api.MpoolRemoveIncluded)
+}
+
 func TestLoadLocal(t *testing.T) {
 	tma := newTestMpoolAPI()
 	ds := datastore.NewMapDatastore()
@@ -489,6 +759,96 @@ func TestLoadLocal(t *testing.T) {
 	}
 }
 
+func TestLocalMessages(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w1, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w2, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w2.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tma.setBalance(a1, 1) // in FIL
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+	var msgs []*types.SignedMessage
+	for i := 0; i < 4; i++ {
+		m := makeTestMessage(w1, a1, a2, uint64(i), gasLimit, uint64(i+1))
+		if _, err := mp.Push(m); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, m)
+	}
+
+	assertLocal := func(expect ...*types.SignedMessage) {
+		t.Helper()
+		local, err := mp.LocalMessages()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(local) != len(expect) {
+			t.Fatalf("expected %d local messages, got %d", len(expect), len(local))
+		}
+		for i, m := range local {
+			if m.Cid() != expect[i].Cid() {
+				t.Fatalf("expected local message %d to be %s, got %s", i, expect[i].Cid(), m.Cid())
+			}
+		}
+	}
+	assertLocal(msgs...)
+
+	// the first message was included
+	tma.setStateNonce(a1, 1)
+	if err := mp.pruneLocalMessages(); err != nil {
+		t.Fatal(err)
+	}
+	assertLocal(msgs[1:]...)
+
+	if err := mp.AbandonLocal(msgs[3].Cid()); err != nil {
+		t.Fatal(err)
+	}
+	assertLocal(msgs[1:3]...)
+	assertNonce(t, mp, a1, 3)
+
+	if err := mp.AbandonLocal(msgs[3].Cid()); err == nil {
+		t.Fatal("expected abandoning a message twice to fail")
+	}
+
+	if err := mp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mp, err = New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pmsgs, _ := mp.Pending()
+	if len(pmsgs) != 2 {
+		t.Fatalf("expected 2 messages to be loaded, got %d", len(pmsgs))
+	}
+	assertLocal(msgs[1:3]...)
+}
+
 func TestClearAll(t *testing.T) {
 	tma := newTestMpoolAPI()
 	ds := datastore.NewMapDatastore()
@@ -670,3 +1030,85 @@ func TestUpdates(t *testing.T) {
 		t.Fatal("expected closed channel, but got an update instead")
 	}
 }
+
+func TestPushBatch(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, "mptest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w1, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tma.setBalance(a1, 1) // in FIL
+	tma.setBalance(a2, 0)
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	var batch []*types.SignedMessage
+	for i := 0; i < 3; i++ {
+		batch = append(batch, makeTestMessage(w1, a1, a2, uint64(i), gasLimit, 1))
+	}
+
+	cids, err := mp.PushBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cids) != 3 || cids[2] != batch[2].Cid() {
+		t.Fatalf("expected the cids of the batch, got %v", cids)
+	}
+
+	// a failing message fails the whole batch
+	_, err = mp.PushBatch([]*types.SignedMessage{
+		makeTestMessage(w1, a1, a2, 3, gasLimit, 1),
+		makeTestMessage(w1, a1, a2, 4, gasLimit, 1),
+		makeTestMessage(w1, a2, a1, 0, gasLimit, 1),
+	})
+	if !xerrors.Is(err, ErrNotEnoughFunds) {
+		t.Fatalf("expected the batch to fail for lack of funds, got: %v", err)
+	}
+
+	// batches can't replace pending messages
+	_, err = mp.PushBatch([]*types.SignedMessage{makeTestMessage(w1, a1, a2, 2, gasLimit, 10)})
+	if err == nil {
+		t.Fatal("expected a batch replacing a pending message to fail")
+	}
+
+	if pending, _ := mp.PendingFor(a1); len(pending) != 3 {
+		t.Fatalf("expected 3 pending messages, got %d", len(pending))
+	}
+	if pending, _ := mp.PendingFor(a2); len(pending) != 0 {
+		t.Fatalf("expected no pending messages, got %d", len(pending))
+	}
+
+	nonce, err := mp.GetNonce(a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 3 {
+		t.Fatalf("expected the next nonce to be 3, got %d", nonce)
+	}
+
+	local, err := mp.LocalMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local) != 3 {
+		t.Fatalf("expected 3 local messages, got %d", len(local))
+	}
+}
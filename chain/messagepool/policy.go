@@ -0,0 +1,93 @@
+package messagepool
+
+import (
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// SelectionPolicy customises which pending messages are selected for the
+// blocks this node produces. The messages the policy accepts are selected by
+// gas performance, the ones of priority actors before all others.
+//
+// The default policy is driven by the mpool config; nodes embedding lotus can
+// provide their own through the node options.
+type SelectionPolicy interface {
+	// Priority returns whether the messages of the actor are selected before
+	// the ones of actors without priority. local is set for actors which
+	// pushed messages through this node.
+	Priority(cfg *types.MpoolConfig, actor address.Address, local bool) bool
+
+	// Accept returns whether the message may be selected. Rejecting a message
+	// also leaves out the messages of the actor with higher nonces.
+	Accept(cfg *types.MpoolConfig, m *types.SignedMessage, baseFee types.BigInt) bool
+}
+
+// DefaultSelectionPolicy gives priority to the PriorityAddrs of the config,
+// and to local actors if PriorityLocal is set, and rejects messages with a gas
+// premium below MinSelectionGasPremium.
+type DefaultSelectionPolicy struct{}
+
+var _ SelectionPolicy = DefaultSelectionPolicy{}
+
+func (DefaultSelectionPolicy) Priority(cfg *types.MpoolConfig, actor address.Address, local bool) bool {
+	if local && cfg.PriorityLocal {
+		return true
+	}
+
+	for _, a := range cfg.PriorityAddrs {
+		if a == actor {
+			return true
+		}
+	}
+	return false
+}
+
+func (DefaultSelectionPolicy) Accept(cfg *types.MpoolConfig, m *types.SignedMessage, baseFee types.BigInt) bool {
+	if cfg.MinSelectionGasPremium.Int == nil {
+		return true
+	}
+	return !m.Message.GasPremium.LessThan(cfg.MinSelectionGasPremium)
+}
+
+// SetSelectionPolicy replaces the policy used for selecting messages.
+func (mp *MessagePool) SetSelectionPolicy(p SelectionPolicy) {
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+	mp.selPolicy = p
+}
+
+// applySelectionPolicy removes the messages rejected by the selection policy
+// from the pending messages, along with the later messages of their actors
+// which can't be included without them.
+func (mp *MessagePool) applySelectionPolicy(pending map[address.Address]map[uint64]*types.SignedMessage, baseFee types.BigInt) {
+	cfg := mp.getConfig()
+
+	for actor, mset := range pending {
+		var rejected bool
+		var minRejected uint64
+		for nonce, m := range mset {
+			if (!rejected || nonce < minRejected) && !mp.selPolicy.Accept(cfg, m, baseFee) {
+				rejected = true
+				minRejected = nonce
+			}
+		}
+		if !rejected {
+			continue
+		}
+
+		// the set may be the one of the pool, don't modify it
+		kept := make(map[uint64]*types.SignedMessage)
+		for nonce, m := range mset {
+			if nonce < minRejected {
+				kept[nonce] = m
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(pending, actor)
+			continue
+		}
+		pending[actor] = kept
+	}
+}
@@ -73,6 +73,7 @@ func (mp *MessagePool) selectMessagesOptimal(curTs, ts *types.TipSet, tq float64
 	if err != nil {
 		return nil, err
 	}
+	mp.applySelectionPolicy(pending, baseFee)
 
 	if len(pending) == 0 {
 		return nil, nil
@@ -96,7 +97,7 @@ func (mp *MessagePool) selectMessagesOptimal(curTs, ts *types.TipSet, tq float64
 	startChains := time.Now()
 	var chains []*msgChain
 	for actor, mset := range pending {
-		next := mp.createMessageChains(actor, mset, baseFee, ts)
+		next := mp.getMessageChains(actor, mset, baseFee, ts)
 		chains = append(chains, next...)
 	}
 	if dt := time.Since(startChains); dt > time.Millisecond {
@@ -113,38 +114,23 @@ func (mp *MessagePool) selectMessagesOptimal(curTs, ts *types.TipSet, tq float64
 		return result, nil
 	}
 
-	// 3. Parition chains into blocks (without trimming)
+	// 3. Partition the chains into the blocks of the tipset, simulating how the miners of
+	//    the blocks pack them in ticket order.
 	//    we use the full blockGasLimit (as opposed to the residual gas limit from the
 	//    priority message selection) as we have to account for what other miners are doing
-	nextChain := 0
-	partitions := make([][]*msgChain, MaxBlocks)
-	for i := 0; i < MaxBlocks && nextChain < len(chains); i++ {
-		gasLimit := int64(build.BlockGasLimit)
-		for nextChain < len(chains) {
-			chain := chains[nextChain]
-			nextChain++
-			partitions[i] = append(partitions[i], chain)
-			gasLimit -= chain.gasLimit
-			if gasLimit < minGas {
-				break
-			}
-		}
-
-	}
+	partitions, unpacked := packBlocks(chains, minGas)
 
 	// 4. Compute effective performance for each chain, based on the partition they fall into
 	//    The effective performance is the gasPerf of the chain * block probability
 	blockProb := mp.blockProbabilities(tq)
-	effChains := 0
 	for i := 0; i < MaxBlocks; i++ {
 		for _, chain := range partitions[i] {
 			chain.SetEffectivePerf(blockProb[i])
 		}
-		effChains += len(partitions[i])
 	}
 
 	// nullify the effective performance of chains that don't fit in any partition
-	for _, chain := range chains[effChains:] {
+	for _, chain := range unpacked {
 		chain.SetNullEffectivePerf()
 	}
 
@@ -383,6 +369,44 @@ tailLoop:
 	return result, nil
 }
 
+// packBlocks simulates the packing of the blocks of a tipset from the chains, sorted by
+// gas performance: the miner of each block, in ticket order, includes the best chains
+// not included in a previous block that fit in the block gas limit, along with their
+// dependencies. The chains which don't fit are left to the next blocks, so a block is
+// filled with the smaller chains after them, as the miners pack their tails. It returns
+// the chains included in each block, in order, and the chains left out of all of them.
+func packBlocks(chains []*msgChain, minGas int64) ([][]*msgChain, []*msgChain) {
+	partitions := make([][]*msgChain, MaxBlocks)
+	packed := make(map[*msgChain]struct{}, len(chains))
+	remaining := chains
+	for i := 0; i < MaxBlocks && len(remaining) > 0; i++ {
+		gasLimit := int64(build.BlockGasLimit)
+		var left []*msgChain
+		for _, chain := range remaining {
+			if gasLimit < minGas || chain.gasLimit > gasLimit {
+				left = append(left, chain)
+				continue
+			}
+
+			// a chain can only be included after its dependencies; they have a higher gas
+			// performance, so they were considered before it
+			if chain.prev != nil {
+				if _, ok := packed[chain.prev]; !ok {
+					left = append(left, chain)
+					continue
+				}
+			}
+
+			partitions[i] = append(partitions[i], chain)
+			packed[chain] = struct{}{}
+			gasLimit -= chain.gasLimit
+		}
+		remaining = left
+	}
+
+	return partitions, remaining
+}
+
 func (mp *MessagePool) selectMessagesGreedy(curTs, ts *types.TipSet) ([]*types.SignedMessage, error) {
 	start := time.Now()
 
@@ -397,6 +421,7 @@ func (mp *MessagePool) selectMessagesGreedy(curTs, ts *types.TipSet) ([]*types.S
 	if err != nil {
 		return nil, err
 	}
+	mp.applySelectionPolicy(pending, baseFee)
 
 	if len(pending) == 0 {
 		return nil, nil
@@ -420,7 +445,7 @@ func (mp *MessagePool) selectMessagesGreedy(curTs, ts *types.TipSet) ([]*types.S
 	startChains := time.Now()
 	var chains []*msgChain
 	for actor, mset := range pending {
-		next := mp.createMessageChains(actor, mset, baseFee, ts)
+		next := mp.getMessageChains(actor, mset, baseFee, ts)
 		chains = append(chains, next...)
 	}
 	if dt := time.Since(startChains); dt > time.Millisecond {
@@ -533,16 +558,18 @@ func (mp *MessagePool) selectPriorityMessages(pending map[address.Address]map[ui
 
 	// 1. Get priority actor chains
 	var chains []*msgChain
-	priority := mp.cfg.PriorityAddrs
-	for _, actor := range priority {
-		mset, ok := pending[actor]
-		if ok {
-			// remove actor from pending set as we are already processed these messages
-			delete(pending, actor)
-			// create chains for the priority actor
-			next := mp.createMessageChains(actor, mset, baseFee, ts)
-			chains = append(chains, next...)
+	cfg := mp.getConfig()
+	for actor, mset := range pending {
+		_, local := mp.localAddrs[actor]
+		if !mp.selPolicy.Priority(cfg, actor, local) {
+			continue
 		}
+
+		// remove actor from pending set as we are already processed these messages
+		delete(pending, actor)
+		// create chains for the priority actor
+		next := mp.getMessageChains(actor, mset, baseFee, ts)
+		chains = append(chains, next...)
 	}
 
 	if len(chains) == 0 {
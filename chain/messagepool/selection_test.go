@@ -667,6 +667,228 @@ func TestPriorityMessageSelection(t *testing.T) {
 	}
 }
 
+// testPolicy gives priority to one actor and rejects the messages of the
+// others from a nonce on.
+type testPolicy struct {
+	priority address.Address
+	maxNonce uint64
+}
+
+func (p testPolicy) Priority(cfg *types.MpoolConfig, actor address.Address, local bool) bool {
+	return actor == p.priority
+}
+
+func (p testPolicy) Accept(cfg *types.MpoolConfig, m *types.SignedMessage, baseFee types.BigInt) bool {
+	return m.Message.From == p.priority || m.Message.Nonce < p.maxNonce
+}
+
+func TestSelectionPolicy(t *testing.T) {
+	mp, tma := makeTestMpool()
+
+	// the actors
+	w1, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w2, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w2.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := tma.nextBlock()
+	ts := mock.TipSet(block)
+	tma.applyBlock(t, block)
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	tma.setBalance(a1, 1) // in FIL
+	tma.setBalance(a2, 1) // in FIL
+
+	mp.SetSelectionPolicy(testPolicy{priority: a2, maxNonce: 5})
+
+	nMessages := 10
+	for i := 0; i < nMessages; i++ {
+		// a1 pays more, but a2 has priority
+		m := makeTestMessage(w1, a1, a2, uint64(i), gasLimit, uint64(10+i))
+		mustAdd(t, mp, m)
+		m = makeTestMessage(w2, a2, a1, uint64(i), gasLimit, uint64(1+i))
+		mustAdd(t, mp, m)
+	}
+
+	msgs, err := mp.SelectMessages(ts, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 15 {
+		t.Fatalf("expected 15 messages but got %d", len(msgs))
+	}
+
+	for i, m := range msgs {
+		from, nonce := a2, uint64(i)
+		if i >= 10 {
+			from, nonce = a1, uint64(i-10)
+		}
+		if m.Message.From != from || m.Message.Nonce != nonce {
+			t.Fatalf("expected message %d to be from %s with nonce %d, got %s/%d", i, from, nonce, m.Message.From, m.Message.Nonce)
+		}
+	}
+}
+
+func TestMessageChainCache(t *testing.T) {
+	mp, tma := makeTestMpool()
+
+	// the actors
+	w1, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := w1.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w2, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2, err := w2.WalletNew(context.Background(), types.KTSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := tma.nextBlock()
+	ts := mock.TipSet(block)
+	tma.applyBlock(t, block)
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin.StorageMarketActorCodeID, M: 2}]
+
+	tma.setBalance(a1, 1) // in FIL
+	tma.setBalance(a2, 1) // in FIL
+
+	for i := 0; i < 10; i++ {
+		m := makeTestMessage(w1, a1, a2, uint64(i), gasLimit, uint64(1+i))
+		mustAdd(t, mp, m)
+		m = makeTestMessage(w2, a2, a1, uint64(i), gasLimit, uint64(1+i))
+		mustAdd(t, mp, m)
+	}
+
+	msgs, err := mp.SelectMessages(ts, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 20 {
+		t.Fatalf("expected 20 messages but got %d", len(msgs))
+	}
+
+	cached1, cached2 := mp.chainCache.actors[a1], mp.chainCache.actors[a2]
+
+	// selecting again reuses the chains, which must not have been modified by
+	// the previous selection
+	msgs2, err := mp.SelectMessages(ts, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs2) != len(msgs) {
+		t.Fatalf("expected %d messages but got %d", len(msgs), len(msgs2))
+	}
+	// the chains of both actors have the same performance, so their order
+	// depends on the iteration of the pending messages
+	selected := make(map[cid.Cid]struct{}, len(msgs))
+	for _, m := range msgs {
+		selected[m.Cid()] = struct{}{}
+	}
+	for _, m := range msgs2 {
+		if _, ok := selected[m.Cid()]; !ok {
+			t.Fatalf("message %s wasn't selected the first time", m.Cid())
+		}
+	}
+	if mp.chainCache.actors[a1] != cached1 || mp.chainCache.actors[a2] != cached2 {
+		t.Fatal("expected the cached chains to be reused")
+	}
+
+	// new messages of an actor recompute its chains
+	m := makeTestMessage(w1, a1, a2, 10, gasLimit, 100)
+	mustAdd(t, mp, m)
+
+	msgs, err = mp.SelectMessages(ts, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 21 {
+		t.Fatalf("expected 21 messages but got %d", len(msgs))
+	}
+	if mp.chainCache.actors[a1] == cached1 || mp.chainCache.actors[a2] != cached2 {
+		t.Fatal("expected only the chains of a1 to be recomputed")
+	}
+
+	// a new head drops the cache
+	block2 := tma.nextBlock()
+	ts2 := mock.TipSet(block2)
+	tma.applyBlock(t, block2)
+
+	if _, err := mp.SelectMessages(ts2, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if mp.chainCache.tsk != ts2.Key() || mp.chainCache.actors[a2] == cached2 {
+		t.Fatal("expected the cache to be dropped on head change")
+	}
+}
+
+func TestPackBlocks(t *testing.T) {
+	limit := int64(build.BlockGasLimit)
+	minGas := int64(gasguess.MinGas)
+
+	// big fills most of the first block, and small, after med in gas performance,
+	// fills its tail as med doesn't fit; child depends on med, so it can only be
+	// included with or after it
+	big := &msgChain{gasLimit: limit - 10*minGas}
+	med := &msgChain{gasLimit: limit / 2}
+	child := &msgChain{gasLimit: limit / 4, prev: med}
+	med.next = child
+	small := &msgChain{gasLimit: 5 * minGas}
+	huge := &msgChain{gasLimit: limit + 1}
+
+	partitions, unpacked := packBlocks([]*msgChain{big, med, child, small, huge}, minGas)
+	if len(partitions) != MaxBlocks {
+		t.Fatalf("expected %d partitions, got %d", MaxBlocks, len(partitions))
+	}
+
+	expected := [][]*msgChain{{big, small}, {med, child}}
+	for i, part := range partitions {
+		var exp []*msgChain
+		if i < len(expected) {
+			exp = expected[i]
+		}
+		if len(part) != len(exp) {
+			t.Fatalf("expected %d chains in block %d, got %d", len(exp), i, len(part))
+		}
+		for j := range part {
+			if part[j] != exp[j] {
+				t.Fatalf("unexpected chain %d in block %d", j, i)
+			}
+		}
+	}
+
+	// the chain larger than a block is never packed
+	if len(unpacked) != 1 || unpacked[0] != huge {
+		t.Fatalf("expected only the huge chain to be left out, got %d chains", len(unpacked))
+	}
+}
+
 func TestPriorityMessageSelection2(t *testing.T) {
 	mp, tma := makeTestMpool()
 
@@ -1291,7 +1513,9 @@ func TestGasReward(t *testing.T) {
 	}
 }
 
-func TestRealWorldSelection(t *testing.T) {
+// makeRealWorldMpool creates a pool with the messages of test-messages.json.gz,
+// a dump of a mainnet message pool.
+func makeRealWorldMpool(t testing.TB) (*MessagePool, *types.TipSet) {
 	// load test-messages.json.gz and rewrite the messages so that
 	// 1) we map each real actor to a test actor so that we can sign the messages
 	// 2) adjust the nonces so that they start from 0
@@ -1390,6 +1614,12 @@ readLoop:
 		mustAdd(t, mp, m)
 	}
 
+	return mp, ts
+}
+
+func TestRealWorldSelection(t *testing.T) {
+	mp, ts := makeRealWorldMpool(t)
+
 	// do message selection and check block packing
 	minGasLimit := int64(0.9 * float64(build.BlockGasLimit))
 
@@ -1464,3 +1694,18 @@ readLoop:
 	}
 
 }
+
+func BenchmarkRealWorldSelection(b *testing.B) {
+	mp, ts := makeRealWorldMpool(b)
+
+	for _, tq := range []float64{1.0, .8, .4, .1, .01} {
+		tq := tq
+		b.Run(fmt.Sprintf("tq=%.2f", tq), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := mp.SelectMessages(ts, tq); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
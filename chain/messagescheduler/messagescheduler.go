@@ -0,0 +1,346 @@
+// Package messagescheduler pushes messages once a given epoch is reached or
+// the base fee falls below a given value, e.g. for timed multisig approvals
+// or fee sensitive batch operations.
+package messagescheduler
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+var log = logging.Logger("messagescheduler")
+
+var errCancelled = xerrors.New("scheduled message was cancelled")
+
+// finishedRetention is how long the pushed and failed messages are listed
+// before they're removed.
+const finishedRetention = abi.ChainEpoch(builtin.EpochsInDay)
+
+// ChainAPI is the chain the schedules are checked against.
+type ChainAPI interface {
+	SubscribeHeadChanges(f store.ReorgNotifee)
+	GetHeaviestTipSet() *types.TipSet
+	ComputeBaseFee(ctx context.Context, ts *types.TipSet) (abi.TokenAmount, error)
+}
+
+// PushAPI pushes the messages once their schedule is met.
+type PushAPI interface {
+	MpoolPush(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error)
+	// SignAndPushMessage is MpoolPushMessage calling signed with the signed
+	// message before pushing it, not pushing it if signed fails.
+	SignAndPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, signed func(*types.SignedMessage) error) (*types.SignedMessage, error)
+}
+
+// StateAPI looks up the signed messages which failed to be pushed, which may
+// have landed on chain already.
+type StateAPI interface {
+	StateSearchMsg(ctx context.Context, msg cid.Cid) (*api.MsgLookup, error)
+}
+
+// Scheduler keeps the scheduled messages in the datastore and pushes them on
+// the first head change meeting their schedule.
+type Scheduler struct {
+	ds    datastore.Batching
+	chain ChainAPI
+	push  PushAPI
+	state StateAPI
+
+	lk     sync.Mutex
+	msgs   map[uint64]*api.ScheduledMessage
+	nextID uint64
+
+	trigger chan struct{}
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+func NewScheduler(ds dtypes.MetadataDS, chain ChainAPI, push PushAPI, state StateAPI) (*Scheduler, error) {
+	s := &Scheduler{
+		ds:    namespace.Wrap(ds, datastore.NewKey("/message-scheduler/")),
+		chain: chain,
+		push:  push,
+		state: state,
+
+		msgs:   map[uint64]*api.ScheduledMessage{},
+		nextID: 1,
+
+		trigger: make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+
+	res, err := s.ds.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("query scheduled messages: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("r.Error: %w", r.Error)
+		}
+
+		var sm api.ScheduledMessage
+		if err := json.Unmarshal(r.Value, &sm); err != nil {
+			return nil, xerrors.Errorf("unmarshaling scheduled message %s: %w", r.Key, err)
+		}
+		s.msgs[sm.ID] = &sm
+		if sm.ID >= s.nextID {
+			s.nextID = sm.ID + 1
+		}
+	}
+
+	return s, nil
+}
+
+// Start checks the schedules on every head change until stopped.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.chain.SubscribeHeadChanges(func(rev, app []*types.TipSet) error {
+		select {
+		case s.trigger <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	go s.run(ctx)
+
+	// the schedules of messages loaded from the datastore may be met already
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.closing)
+	<-s.closed
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	defer close(s.closed)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for {
+		select {
+		case <-s.trigger:
+			s.process(ctx)
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// Schedule adds the message, which must have either Message or Signed set,
+// and returns its ID.
+func (s *Scheduler) Schedule(sm api.ScheduledMessage) (uint64, error) {
+	if (sm.Message == nil) == (sm.Signed == nil) {
+		return 0, xerrors.Errorf("expected either an unsigned or a signed message")
+	}
+	if sm.Message != nil && sm.Message.Nonce != 0 {
+		return 0, xerrors.Errorf("the nonce of an unsigned scheduled message is assigned when it's pushed, expected it to be 0, was %d", sm.Message.Nonce)
+	}
+
+	s.lk.Lock()
+	sm.ID = s.nextID
+	sm.State = api.ScheduledPending
+	sm.Error = ""
+	if err := s.save(&sm); err != nil {
+		s.lk.Unlock()
+		return 0, err
+	}
+	s.nextID++
+	s.msgs[sm.ID] = &sm
+	s.lk.Unlock()
+
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+
+	return sm.ID, nil
+}
+
+// List returns the scheduled messages ordered by ID.
+func (s *Scheduler) List() []api.ScheduledMessage {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	out := make([]api.ScheduledMessage, 0, len(s.msgs))
+	for _, sm := range s.msgs {
+		out = append(out, *sm)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Cancel removes the scheduled message.
+func (s *Scheduler) Cancel(id uint64) error {
+	return s.remove(id)
+}
+
+func (s *Scheduler) remove(id uint64) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if _, ok := s.msgs[id]; !ok {
+		return xerrors.Errorf("no scheduled message with ID %d", id)
+	}
+
+	if err := s.ds.Delete(dsKey(id)); err != nil {
+		return xerrors.Errorf("deleting scheduled message: %w", err)
+	}
+	delete(s.msgs, id)
+	return nil
+}
+
+// process pushes the pending messages whose schedule is met at the head, and
+// the messages signed but maybe not pushed when the node stopped, and removes
+// the messages finished for finishedRetention epochs.
+func (s *Scheduler) process(ctx context.Context) {
+	ts := s.chain.GetHeaviestTipSet()
+	if ts == nil {
+		return
+	}
+
+	var baseFee abi.TokenAmount
+	for _, sm := range s.List() {
+		sm := sm
+		switch sm.State {
+		case api.ScheduledPushed, api.ScheduledFailed:
+			if ts.Height() >= sm.Finished+finishedRetention {
+				if err := s.remove(sm.ID); err != nil {
+					log.Errorf("removing finished scheduled message %d: %s", sm.ID, err)
+				}
+			}
+			continue
+		case api.ScheduledPushing:
+			log.Infow("pushing scheduled message signed before the restart", "id", sm.ID, "cid", sm.Signed.Cid())
+			s.pushed(&sm, ts, s.pushSigned(ctx, sm.Signed))
+			continue
+		}
+
+		if ts.Height() < sm.Schedule.AtEpoch {
+			continue
+		}
+
+		if maxFee := sm.Schedule.MaxBaseFee; maxFee.Int != nil && !maxFee.IsZero() {
+			if baseFee.Int == nil {
+				var err error
+				baseFee, err = s.chain.ComputeBaseFee(ctx, ts)
+				if err != nil {
+					log.Errorf("computing base fee at %s: %s", ts.Key(), err)
+					return
+				}
+			}
+
+			if baseFee.GreaterThan(maxFee) {
+				continue
+			}
+		}
+
+		if sm.Signed != nil {
+			s.pushed(&sm, ts, s.pushSigned(ctx, sm.Signed))
+		} else {
+			_, err := s.push.SignAndPushMessage(ctx, sm.Message, sm.Spec, func(smsg *types.SignedMessage) error {
+				// the signed message is saved before it's pushed, for the
+				// same message to be pushed again after a restart
+				sm.Signed = smsg
+				sm.State = api.ScheduledPushing
+				return s.update(&sm)
+			})
+			s.pushed(&sm, ts, err)
+		}
+	}
+}
+
+// pushSigned pushes the signed message, which isn't an error if the message
+// can't be pushed because it landed on chain already, e.g. when it was pushed
+// before the node restarted and its nonce is used now.
+func (s *Scheduler) pushSigned(ctx context.Context, smsg *types.SignedMessage) error {
+	_, err := s.push.MpoolPush(ctx, smsg)
+	if err == nil {
+		return nil
+	}
+
+	lookup, serr := s.state.StateSearchMsg(ctx, smsg.Cid())
+	if serr != nil {
+		log.Warnw("searching scheduled message which failed to be pushed", "cid", smsg.Cid(), "error", serr)
+		return err
+	}
+	if lookup == nil {
+		return err
+	}
+
+	log.Infow("scheduled message landed on chain already", "cid", smsg.Cid(), "height", lookup.Height)
+	return nil
+}
+
+func (s *Scheduler) pushed(sm *api.ScheduledMessage, ts *types.TipSet, err error) {
+	if err != nil {
+		log.Warnw("pushing scheduled message failed", "id", sm.ID, "error", err)
+		sm.State = api.ScheduledFailed
+		sm.Error = err.Error()
+	} else {
+		log.Infow("pushed scheduled message", "id", sm.ID, "cid", sm.Signed.Cid())
+		sm.State = api.ScheduledPushed
+	}
+	sm.Finished = ts.Height()
+
+	if err := s.update(sm); err != nil && err != errCancelled {
+		log.Errorf("saving scheduled message %d: %s", sm.ID, err)
+	}
+}
+
+// update saves the new state of a scheduled message, unless it was cancelled.
+func (s *Scheduler) update(sm *api.ScheduledMessage) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if _, ok := s.msgs[sm.ID]; !ok {
+		return errCancelled
+	}
+	if err := s.save(sm); err != nil {
+		return err
+	}
+	cp := *sm
+	s.msgs[sm.ID] = &cp
+	return nil
+}
+
+func (s *Scheduler) save(sm *api.ScheduledMessage) error {
+	b, err := json.Marshal(sm)
+	if err != nil {
+		return xerrors.Errorf("marshaling scheduled message: %w", err)
+	}
+	if err := s.ds.Put(dsKey(sm.ID), b); err != nil {
+		return xerrors.Errorf("saving scheduled message: %w", err)
+	}
+	return nil
+}
+
+func dsKey(id uint64) datastore.Key {
+	return datastore.NewKey(strconv.FormatUint(id, 10))
+}
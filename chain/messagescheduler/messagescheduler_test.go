@@ -0,0 +1,260 @@
+package messagescheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+type testChain struct {
+	head    *types.TipSet
+	baseFee abi.TokenAmount
+}
+
+func (tc *testChain) SubscribeHeadChanges(f store.ReorgNotifee) {}
+
+func (tc *testChain) GetHeaviestTipSet() *types.TipSet {
+	return tc.head
+}
+
+func (tc *testChain) ComputeBaseFee(ctx context.Context, ts *types.TipSet) (abi.TokenAmount, error) {
+	return tc.baseFee, nil
+}
+
+type testPush struct {
+	lk     sync.Mutex
+	pushed []*types.SignedMessage
+	err    error
+
+	// called once a message is signed, before it's pushed
+	onSigned func()
+}
+
+func (tp *testPush) MpoolPush(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
+	tp.lk.Lock()
+	defer tp.lk.Unlock()
+	if tp.err != nil {
+		return cid.Undef, tp.err
+	}
+	tp.pushed = append(tp.pushed, smsg)
+	return smsg.Cid(), nil
+}
+
+func (tp *testPush) SignAndPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, signed func(*types.SignedMessage) error) (*types.SignedMessage, error) {
+	smsg := &types.SignedMessage{Message: *msg, Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1}}
+	if err := signed(smsg); err != nil {
+		return nil, err
+	}
+	if tp.onSigned != nil {
+		tp.onSigned()
+	}
+	_, err := tp.MpoolPush(ctx, smsg)
+	return smsg, err
+}
+
+type testState struct {
+	landed map[cid.Cid]abi.ChainEpoch
+}
+
+func (ts *testState) StateSearchMsg(ctx context.Context, msg cid.Cid) (*api.MsgLookup, error) {
+	h, ok := ts.landed[msg]
+	if !ok {
+		return nil, nil
+	}
+	return &api.MsgLookup{Message: msg, Height: h}, nil
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	head := mock.TipSet(mock.MkBlock(nil, 1, 1))
+	tc := &testChain{head: head, baseFee: types.NewInt(200)}
+	tp := &testPush{}
+	ds := datastore.NewMapDatastore()
+
+	s, err := NewScheduler(ds, tc, tp, &testState{})
+	require.NoError(t, err)
+
+	msg := func() *types.Message {
+		return &types.Message{From: from, To: to, Value: types.NewInt(1)}
+	}
+
+	atEpoch, err := s.Schedule(api.ScheduledMessage{
+		Message:  msg(),
+		Schedule: api.MessageSchedule{AtEpoch: head.Height() + 1},
+	})
+	require.NoError(t, err)
+
+	lowFee, err := s.Schedule(api.ScheduledMessage{
+		Signed:   &types.SignedMessage{Message: *msg(), Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1}},
+		Schedule: api.MessageSchedule{MaxBaseFee: types.NewInt(100)},
+	})
+	require.NoError(t, err)
+
+	cancelled, err := s.Schedule(api.ScheduledMessage{Message: msg()})
+	require.NoError(t, err)
+	require.NoError(t, s.Cancel(cancelled))
+
+	_, err = s.Schedule(api.ScheduledMessage{})
+	require.Error(t, err)
+
+	// no schedule is met
+	s.process(ctx)
+	require.Len(t, tp.pushed, 0)
+
+	// the scheduled messages are kept across restarts
+	s, err = NewScheduler(ds, tc, tp, &testState{})
+	require.NoError(t, err)
+	require.Len(t, s.List(), 2)
+
+	tc.head = mock.TipSet(mock.MkBlock(head, 1, 1))
+	s.process(ctx)
+	require.Len(t, tp.pushed, 1)
+
+	tc.baseFee = types.NewInt(100)
+	s.process(ctx)
+	require.Len(t, tp.pushed, 2)
+
+	// pushed messages aren't pushed again
+	s.process(ctx)
+	require.Len(t, tp.pushed, 2)
+
+	list := s.List()
+	require.Len(t, list, 2)
+	require.Equal(t, atEpoch, list[0].ID)
+	require.Equal(t, lowFee, list[1].ID)
+	for _, sm := range list {
+		require.Equal(t, api.ScheduledPushed, sm.State)
+		require.NotNil(t, sm.Signed)
+	}
+}
+
+func TestSchedulerRestartWhilePushing(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	tc := &testChain{head: mock.TipSet(mock.MkBlock(nil, 1, 1))}
+	ds := datastore.NewMapDatastore()
+
+	// the datastore as left by a node stopped between signing the message
+	// and pushing it
+	stopped := datastore.NewMapDatastore()
+	tp := &testPush{onSigned: func() {
+		res, err := ds.Query(query.Query{})
+		require.NoError(t, err)
+		entries, err := res.Rest()
+		require.NoError(t, err)
+		for _, e := range entries {
+			require.NoError(t, stopped.Put(datastore.NewKey(e.Key), e.Value))
+		}
+	}}
+
+	s, err := NewScheduler(ds, tc, tp, &testState{})
+	require.NoError(t, err)
+	_, err = s.Schedule(api.ScheduledMessage{Message: &types.Message{From: from, To: to, Value: types.NewInt(1)}})
+	require.NoError(t, err)
+
+	s.process(ctx)
+	require.Len(t, tp.pushed, 1)
+	signed := tp.pushed[0]
+
+	tp2 := &testPush{}
+	s, err = NewScheduler(stopped, tc, tp2, &testState{})
+	require.NoError(t, err)
+	list := s.List()
+	require.Len(t, list, 1)
+	require.Equal(t, api.ScheduledPushing, list[0].State)
+
+	// the message signed before stopping is pushed again, not signed again
+	s.process(ctx)
+	require.Len(t, tp2.pushed, 1)
+	require.Equal(t, signed.Cid(), tp2.pushed[0].Cid())
+	require.Equal(t, api.ScheduledPushed, s.List()[0].State)
+
+	s.process(ctx)
+	require.Len(t, tp2.pushed, 1)
+}
+
+func TestSchedulerRestartAfterLanding(t *testing.T) {
+	ctx := context.Background()
+
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	tc := &testChain{head: mock.TipSet(mock.MkBlock(nil, 1, 1))}
+	ds := datastore.NewMapDatastore()
+	tp := &testPush{}
+	st := &testState{landed: map[cid.Cid]abi.ChainEpoch{}}
+
+	s, err := NewScheduler(ds, tc, tp, st)
+	require.NoError(t, err)
+
+	signed, err := s.Schedule(api.ScheduledMessage{
+		Signed: &types.SignedMessage{Message: types.Message{From: from, To: to, Value: types.NewInt(1)}, Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1}},
+	})
+	require.NoError(t, err)
+	failed, err := s.Schedule(api.ScheduledMessage{
+		Signed: &types.SignedMessage{Message: types.Message{From: from, To: to, Value: types.NewInt(2)}, Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1}},
+	})
+	require.NoError(t, err)
+
+	// the first message landed before the node restarted, so its nonce is
+	// used and it can't be pushed again
+	list := s.List()
+	st.landed[list[0].Signed.Cid()] = 1
+	tp.err = xerrors.New("minimum expected nonce is 1")
+
+	s.process(ctx)
+	list = s.List()
+	require.Equal(t, signed, list[0].ID)
+	require.Equal(t, api.ScheduledPushed, list[0].State)
+	require.Equal(t, failed, list[1].ID)
+	require.Equal(t, api.ScheduledFailed, list[1].State)
+
+	// finished messages are removed after finishedRetention epochs
+	atHeight := func(h abi.ChainEpoch) *types.TipSet {
+		b := mock.MkBlock(nil, 1, 1)
+		b.Height = h
+		return mock.TipSet(b)
+	}
+
+	tc.head = atHeight(list[0].Finished + finishedRetention - 1)
+	s.process(ctx)
+	require.Len(t, s.List(), 2)
+
+	tc.head = atHeight(list[0].Finished + finishedRetention)
+	s.process(ctx)
+	require.Len(t, s.List(), 0)
+
+	res, err := ds.Query(query.Query{})
+	require.NoError(t, err)
+	entries, err := res.Rest()
+	require.NoError(t, err)
+	require.Len(t, entries, 0)
+}
@@ -90,6 +90,62 @@ func (ms *MessageSigner) SignMessage(ctx context.Context, msg *types.Message, cb
 	return smsg, nil
 }
 
+// SignMessages assigns consecutive nonces to the messages, which must all be
+// from the same address, and signs them. The nonces are only used up if the
+// callback succeeds.
+func (ms *MessageSigner) SignMessages(ctx context.Context, msgs []*types.Message, cb func([]*types.SignedMessage) error) ([]*types.SignedMessage, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	ms.lk.Lock()
+	defer ms.lk.Unlock()
+
+	from := msgs[0].From
+	nonce, err := ms.nextNonce(from)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create nonce: %w", err)
+	}
+
+	smsgs := make([]*types.SignedMessage, len(msgs))
+	for i, msg := range msgs {
+		if msg.From != from {
+			return nil, xerrors.Errorf("message %d is from %s, expected %s", i, msg.From, from)
+		}
+
+		msg.Nonce = nonce + uint64(i)
+
+		mb, err := msg.ToStorageBlock()
+		if err != nil {
+			return nil, xerrors.Errorf("serializing message %d: %w", i, err)
+		}
+
+		sig, err := ms.wallet.WalletSign(ctx, msg.From, mb.Cid().Bytes(), api.MsgMeta{
+			Type:  api.MTChainMsg,
+			Extra: mb.RawData(),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("failed to sign message %d: %w", i, err)
+		}
+
+		smsgs[i] = &types.SignedMessage{
+			Message:   *msg,
+			Signature: *sig,
+		}
+	}
+
+	if err := cb(smsgs); err != nil {
+		return nil, err
+	}
+
+	// If the callback executed successfully, write the last nonce to the datastore
+	if err := ms.saveNonce(from, nonce+uint64(len(msgs))-1); err != nil {
+		return nil, xerrors.Errorf("failed to save nonce: %w", err)
+	}
+
+	return smsgs, nil
+}
+
 // nextNonce gets the next nonce for the given address.
 // If there is no nonce in the datastore, gets the nonce from the message pool.
 func (ms *MessageSigner) nextNonce(addr address.Address) (uint64, error) {
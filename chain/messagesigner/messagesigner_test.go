@@ -198,3 +198,43 @@ func TestMessageSignerSignMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestMessageSignerSignMessages(t *testing.T) {
+	ctx := context.Background()
+
+	w, _ := wallet.NewWallet(wallet.NewMemKeyStore())
+	from, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	to, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+
+	mpool := newMockMpool()
+	mpool.setNonce(from, 5)
+	ds := ds_sync.MutexWrap(datastore.NewMapDatastore())
+	ms := NewMessageSigner(w, mpool, ds)
+
+	batch := func() []*types.Message {
+		return []*types.Message{{To: to, From: from}, {To: to, From: from}, {To: to, From: from}}
+	}
+
+	// the nonces aren't used up if the callback fails
+	_, err = ms.SignMessages(ctx, batch(), func([]*types.SignedMessage) error {
+		return xerrors.Errorf("err")
+	})
+	require.Error(t, err)
+
+	smsgs, err := ms.SignMessages(ctx, batch(), func([]*types.SignedMessage) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, smsgs, 3)
+	for i, smsg := range smsgs {
+		require.Equal(t, uint64(5+i), smsg.Message.Nonce)
+	}
+
+	smsg, err := ms.SignMessage(ctx, &types.Message{To: to, From: from}, func(*types.SignedMessage) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), smsg.Message.Nonce)
+
+	// all messages must be from the same address
+	_, err = ms.SignMessages(ctx, []*types.Message{{To: to, From: from}, {To: from, From: to}}, func([]*types.SignedMessage) error { return nil })
+	require.Error(t, err)
+}
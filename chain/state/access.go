@@ -0,0 +1,62 @@
+package state
+
+import (
+	"github.com/filecoin-project/go-address"
+)
+
+// Access is the set of actors read and written through a state tree, by ID
+// address. Resolving a non-ID address reads the init actor, unless the
+// resolution was already cached by the tree.
+type Access struct {
+	Reads  map[address.Address]struct{}
+	Writes map[address.Address]struct{}
+}
+
+func newAccess() *Access {
+	return &Access{
+		Reads:  make(map[address.Address]struct{}),
+		Writes: make(map[address.Address]struct{}),
+	}
+}
+
+// Touches returns whether any of the actors was read or written.
+func (a *Access) Touches(actors map[address.Address]struct{}) bool {
+	for addr := range a.Reads {
+		if _, ok := actors[addr]; ok {
+			return true
+		}
+	}
+	for addr := range a.Writes {
+		if _, ok := actors[addr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackAccess starts recording the actors accessed through the state tree,
+// dropping the accesses recorded so far.
+func (st *StateTree) TrackAccess() {
+	st.access = newAccess()
+}
+
+// StopTrackingAccess stops recording the actors accessed through the state
+// tree, and returns the accesses recorded since TrackAccess was called, or nil
+// if they weren't tracked.
+func (st *StateTree) StopTrackingAccess() *Access {
+	a := st.access
+	st.access = nil
+	return a
+}
+
+func (st *StateTree) recordRead(addr address.Address) {
+	if st.access != nil {
+		st.access.Reads[addr] = struct{}{}
+	}
+}
+
+func (st *StateTree) recordWrite(addr address.Address) {
+	if st.access != nil {
+		st.access.Writes[addr] = struct{}{}
+	}
+}
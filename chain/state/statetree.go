@@ -32,7 +32,8 @@ type StateTree struct {
 	Store       cbor.IpldStore
 	lookupIDFun func(address.Address) (address.Address, error)
 
-	snaps *stateSnaps
+	snaps  *stateSnaps
+	access *Access // nil unless tracked
 }
 
 type stateSnaps struct {
@@ -227,6 +228,7 @@ func (st *StateTree) SetActor(addr address.Address, act *types.Actor) error {
 	}
 	addr = iaddr
 
+	st.recordWrite(addr)
 	st.snaps.setActor(addr, act)
 	return nil
 }
@@ -288,6 +290,7 @@ func (st *StateTree) GetActor(addr address.Address) (*types.Actor, error) {
 	}
 	addr = iaddr
 
+	st.recordRead(addr)
 	snapAct, err := st.snaps.getActor(addr)
 	if err != nil {
 		return nil, err
@@ -329,6 +332,7 @@ func (st *StateTree) DeleteActor(addr address.Address) error {
 		return err
 	}
 
+	st.recordWrite(addr)
 	st.snaps.deleteActor(addr)
 
 	return nil
@@ -377,3 +377,54 @@ func TestStateTreeConsistency(t *testing.T) {
 		t.Fatal("MISMATCH!")
 	}
 }
+
+func TestTrackAccess(t *testing.T) {
+	cst := cbor.NewMemCborStore()
+	st, err := NewStateTree(cst, VersionForNetwork(build.NewestNetworkVersion))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, _ := address.NewIDAddress(101)
+	a2, _ := address.NewIDAddress(102)
+	a3, _ := address.NewIDAddress(103)
+	act := &types.Actor{
+		Code:    builtin.AccountActorCodeID,
+		Head:    builtin.AccountActorCodeID,
+		Balance: types.NewInt(1),
+	}
+	if err := st.SetActor(a1, act); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is recorded until tracking starts
+	if st.StopTrackingAccess() != nil {
+		t.Fatal("access tracked without TrackAccess")
+	}
+
+	st.TrackAccess()
+	if _, err := st.GetActor(a1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetActor(a2, act); err != nil {
+		t.Fatal(err)
+	}
+	access := st.StopTrackingAccess()
+
+	if _, ok := access.Reads[a1]; !ok {
+		t.Fatal("read of a1 not recorded")
+	}
+	if _, ok := access.Writes[a2]; !ok {
+		t.Fatal("write of a2 not recorded")
+	}
+	if _, ok := access.Writes[a1]; ok {
+		t.Fatal("a1 wasn't written")
+	}
+
+	if !access.Touches(map[address.Address]struct{}{a2: {}}) {
+		t.Fatal("access should touch a2")
+	}
+	if access.Touches(map[address.Address]struct{}{a3: {}}) {
+		t.Fatal("access shouldn't touch a3")
+	}
+}
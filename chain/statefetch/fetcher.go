@@ -0,0 +1,379 @@
+// Package statefetch fetches state trees which are missing from the local
+// blockstore from the network, with several bitswap sessions in parallel.
+package statefetch
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	dstore "github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mh "github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/build"
+	bstore "github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+var log = logging.Logger("statefetch")
+
+var pendingPrefix = dstore.NewKey("/statefetch/pending")
+
+const (
+	// DefaultWorkers is the default number of parallel fetch sessions.
+	DefaultWorkers = 8
+
+	// batchSize is the number of blocks requested from a session at once
+	batchSize = 256
+	// batchTimeout bounds the time a session spends on a batch; the blocks it
+	// didn't get are handed to another session
+	batchTimeout = time.Minute
+	// maxAttempts is the number of batches a block is requested in before
+	// the fetch fails
+	maxAttempts = 5
+)
+
+// Stats describes a completed fetch.
+type Stats struct {
+	// Local is the number of blocks of the tree which were already present
+	Local int
+	// Fetched and FetchedBytes count the blocks fetched from the network
+	Fetched      int
+	FetchedBytes int64
+	Duration     time.Duration
+
+	// Workers is the throughput of each fetch session
+	Workers []Throughput
+	// Peers is the throughput of each peer which sent data during the fetch.
+	// The counters include all the traffic with the peer, not only blocks.
+	Peers []PeerThroughput
+}
+
+type Throughput struct {
+	Blocks int
+	Bytes  int64
+	// Busy is the time the session spent waiting for blocks
+	Busy time.Duration
+}
+
+func (t Throughput) BytesPerSecond() float64 {
+	if t.Busy <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / t.Busy.Seconds()
+}
+
+type PeerThroughput struct {
+	Peer           peer.ID
+	Bytes          int64
+	BytesPerSecond float64
+}
+
+// Fetcher fetches state trees into the local blockstore.
+type Fetcher struct {
+	local   bstore.Blockstore
+	bs      bserv.BlockService
+	ds      dstore.Datastore
+	bw      metrics.Reporter
+	workers int
+}
+
+// NewFetcher creates a fetcher getting blocks through the blockservice, and
+// writing them to the local blockstore. The blockservice doesn't write them
+// itself, and the chain bitswap only keeps the blocks it receives for a couple
+// of block times. Fetches in progress are recorded in the datastore. The bandwidth reporter is used for per-peer accounting
+// and may be nil.
+func NewFetcher(local bstore.Blockstore, bs bserv.BlockService, ds dstore.Datastore, bw metrics.Reporter, workers int) *Fetcher {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Fetcher{
+		local:   local,
+		bs:      bs,
+		ds:      ds,
+		bw:      bw,
+		workers: workers,
+	}
+}
+
+func pendingKey(root cid.Cid) dstore.Key {
+	return pendingPrefix.ChildString(root.String())
+}
+
+// Complete returns whether the tree under root is present locally, which is
+// when its root is present, and no fetch of it was interrupted. It doesn't
+// walk the tree.
+func (f *Fetcher) Complete(root cid.Cid) (bool, error) {
+	has, err := f.local.Has(root)
+	if err != nil || !has {
+		return false, err
+	}
+
+	pending, err := f.ds.Has(pendingKey(root))
+	if err != nil {
+		return false, xerrors.Errorf("checking for pending state fetch: %w", err)
+	}
+	return !pending, nil
+}
+
+type batch struct {
+	cids []cid.Cid
+}
+
+type batchResult struct {
+	worker  int
+	blocks  []blocks.Block
+	missing []cid.Cid
+	busy    time.Duration
+	err     error
+}
+
+// Fetch makes sure that the tree under root is complete in the local
+// blockstore, fetching the missing parts of it. The blocks already present
+// are walked too, so an interrupted fetch is resumed by fetching the same
+// root again.
+func (f *Fetcher) Fetch(ctx context.Context, root cid.Cid) (*Stats, error) {
+	start := build.Clock.Now()
+
+	// the blocks are written as they arrive, so the root is present before
+	// the tree is complete
+	if err := f.ds.Put(pendingKey(root), []byte{}); err != nil {
+		return nil, xerrors.Errorf("recording pending state fetch: %w", err)
+	}
+
+	var startBw map[peer.ID]metrics.Stats
+	if f.bw != nil {
+		startBw = f.bw.GetBandwidthByPeer()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stats := &Stats{Workers: make([]Throughput, f.workers)}
+
+	rootBlk, err := f.local.Get(root)
+	switch err {
+	case nil:
+		stats.Local++
+	case bstore.ErrNotFound:
+		rootBlk, err = f.bs.GetBlock(ctx, root)
+		if err != nil {
+			return nil, xerrors.Errorf("fetching state root %s: %w", root, err)
+		}
+		if err := f.local.Put(rootBlk); err != nil {
+			return nil, xerrors.Errorf("writing state root %s: %w", root, err)
+		}
+		stats.Fetched++
+		stats.FetchedBytes += int64(len(rootBlk.RawData()))
+	default:
+		return nil, xerrors.Errorf("getting state root %s: %w", root, err)
+	}
+
+	work := make(chan batch)
+	results := make(chan batchResult)
+	for i := 0; i < f.workers; i++ {
+		go f.worker(ctx, i, work, results)
+	}
+
+	visited := cid.NewSet()
+	visited.Add(root)
+	attempts := map[cid.Cid]int{}
+	var pending []cid.Cid
+	inflight := 0
+
+	// queue registers the links of a block, walking the local parts of the
+	// tree and queueing the missing blocks
+	queue := func(blk blocks.Block) error {
+		stack := []blocks.Block{blk}
+		for len(stack) > 0 {
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if b.Cid().Prefix().Codec != cid.DagCBOR {
+				continue
+			}
+
+			var links []cid.Cid
+			err := cbg.ScanForLinks(bytes.NewReader(b.RawData()), func(c cid.Cid) {
+				if c.Prefix().MhType == mh.IDENTITY || !visited.Visit(c) {
+					return
+				}
+				links = append(links, c)
+			})
+			if err != nil {
+				return xerrors.Errorf("scanning for links in %s: %w", b.Cid(), err)
+			}
+
+			for _, c := range links {
+				lb, err := f.local.Get(c)
+				switch err {
+				case nil:
+					stats.Local++
+					stack = append(stack, lb)
+				case bstore.ErrNotFound:
+					pending = append(pending, c)
+				default:
+					return xerrors.Errorf("getting %s: %w", c, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := queue(rootBlk); err != nil {
+		return nil, err
+	}
+
+	lastLog := start
+	for len(pending) > 0 || inflight > 0 {
+		var next batch
+		var sendWork chan batch
+		if len(pending) > 0 {
+			n := batchSize
+			if n > len(pending) {
+				n = len(pending)
+			}
+			// copied, as pending is appended to while the batch is in flight
+			next = batch{cids: append([]cid.Cid(nil), pending[len(pending)-n:]...)}
+			sendWork = work
+		}
+
+		select {
+		case sendWork <- next:
+			pending = pending[:len(pending)-len(next.cids)]
+			inflight++
+		case res := <-results:
+			inflight--
+			if res.err != nil {
+				return nil, res.err
+			}
+
+			if err := f.local.PutMany(res.blocks); err != nil {
+				return nil, xerrors.Errorf("writing fetched blocks: %w", err)
+			}
+
+			w := &stats.Workers[res.worker]
+			w.Busy += res.busy
+			for _, b := range res.blocks {
+				w.Blocks++
+				w.Bytes += int64(len(b.RawData()))
+				stats.Fetched++
+				stats.FetchedBytes += int64(len(b.RawData()))
+
+				if err := queue(b); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, c := range res.missing {
+				attempts[c]++
+				if attempts[c] >= maxAttempts {
+					return nil, xerrors.Errorf("couldn't fetch %s after %d attempts", c, attempts[c])
+				}
+				pending = append(pending, c)
+			}
+
+			if build.Clock.Since(lastLog) > 30*time.Second {
+				lastLog = build.Clock.Now()
+				log.Infow("fetching state", "root", root, "fetched", stats.Fetched, "bytes", stats.FetchedBytes, "local", stats.Local, "pending", len(pending))
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err := f.ds.Delete(pendingKey(root)); err != nil {
+		return nil, xerrors.Errorf("clearing pending state fetch: %w", err)
+	}
+
+	stats.Duration = build.Clock.Since(start)
+	if f.bw != nil {
+		stats.Peers = peerThroughput(startBw, f.bw.GetBandwidthByPeer(), stats.Duration)
+	}
+
+	return stats, nil
+}
+
+func (f *Fetcher) worker(ctx context.Context, id int, work <-chan batch, results chan<- batchResult) {
+	// each worker has its own session so that the sessions spread their
+	// wants over different peers
+	ses := bserv.NewSession(ctx, f.bs)
+
+	for {
+		var b batch
+		select {
+		case b = <-work:
+		case <-ctx.Done():
+			return
+		}
+
+		res := f.fetchBatch(ctx, ses, b.cids)
+		res.worker = id
+
+		select {
+		case results <- res:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (f *Fetcher) fetchBatch(ctx context.Context, ses bserv.BlockGetter, cids []cid.Cid) batchResult {
+	start := build.Clock.Now()
+
+	bctx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	want := make(map[cid.Cid]struct{}, len(cids))
+	for _, c := range cids {
+		want[c] = struct{}{}
+	}
+
+	var res batchResult
+	for blk := range ses.GetBlocks(bctx, cids) {
+		if _, ok := want[blk.Cid()]; !ok {
+			continue
+		}
+		delete(want, blk.Cid())
+		res.blocks = append(res.blocks, blk)
+	}
+	res.busy = build.Clock.Since(start)
+
+	if err := ctx.Err(); err != nil {
+		res.err = err
+		return res
+	}
+
+	for c := range want {
+		res.missing = append(res.missing, c)
+	}
+	return res
+}
+
+func peerThroughput(before, after map[peer.ID]metrics.Stats, d time.Duration) []PeerThroughput {
+	var out []PeerThroughput
+	for p, st := range after {
+		in := st.TotalIn - before[p].TotalIn
+		if in <= 0 {
+			continue
+		}
+
+		pt := PeerThroughput{Peer: p, Bytes: in}
+		if d > 0 {
+			pt.BytesPerSecond = float64(in) / d.Seconds()
+		}
+		out = append(out, pt)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Bytes > out[j].Bytes
+	})
+	return out
+}
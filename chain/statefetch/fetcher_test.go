@@ -0,0 +1,143 @@
+package statefetch
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	bstore "github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+// node encodes a dag-cbor array of the links, with a salt to make it unique.
+func node(t *testing.T, salt uint64, links ...cid.Cid) blocks.Block {
+	var buf bytes.Buffer
+	require.NoError(t, cbg.CborWriteHeader(&buf, cbg.MajArray, uint64(len(links)+1)))
+	require.NoError(t, cbg.CborWriteHeader(&buf, cbg.MajUnsignedInt, salt))
+	for _, l := range links {
+		require.NoError(t, cbg.WriteCid(&buf, l))
+	}
+
+	c, err := cid.V1Builder{Codec: cid.DagCBOR, MhType: mh.BLAKE2B_MIN + 31}.Sum(buf.Bytes())
+	require.NoError(t, err)
+	blk, err := blocks.NewBlockWithCid(buf.Bytes(), c)
+	require.NoError(t, err)
+	return blk
+}
+
+// tree builds a root with width children, each with width leaves, and
+// returns the blocks with the root first and the leaves last.
+func tree(t *testing.T, width int) []blocks.Block {
+	var mids, leaves []blocks.Block
+	var salt uint64
+	for i := 0; i < width; i++ {
+		var links []cid.Cid
+		for j := 0; j < width; j++ {
+			salt++
+			leaf := node(t, salt)
+			leaves = append(leaves, leaf)
+			links = append(links, leaf.Cid())
+		}
+		salt++
+		mids = append(mids, node(t, salt, links...))
+	}
+
+	var rootLinks []cid.Cid
+	for _, m := range mids {
+		rootLinks = append(rootLinks, m.Cid())
+	}
+	salt++
+
+	out := []blocks.Block{node(t, salt, rootLinks...)}
+	out = append(out, mids...)
+	return append(out, leaves...)
+}
+
+func setup(t *testing.T, remote []blocks.Block) (*Fetcher, bstore.Blockstore) {
+	src := bstore.NewTemporary()
+	require.NoError(t, src.PutMany(remote))
+
+	local := bstore.NewTemporarySync()
+	bs := bserv.New(local, offline.Exchange(src))
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	return NewFetcher(local, bs, ds, nil, 4), local
+}
+
+func TestFetchState(t *testing.T) {
+	ctx := context.Background()
+	blks := tree(t, 20)
+	root := blks[0].Cid()
+
+	f, local := setup(t, blks)
+
+	complete, err := f.Complete(root)
+	require.NoError(t, err)
+	require.False(t, complete)
+
+	st, err := f.Fetch(ctx, root)
+	require.NoError(t, err)
+	require.Equal(t, len(blks), st.Fetched)
+	require.Equal(t, 0, st.Local)
+
+	var fetched int
+	for _, w := range st.Workers {
+		fetched += w.Blocks
+	}
+	require.Equal(t, len(blks)-1, fetched) // the root isn't fetched by a worker
+
+	for _, b := range blks {
+		has, err := local.Has(b.Cid())
+		require.NoError(t, err)
+		require.True(t, has)
+	}
+
+	complete, err = f.Complete(root)
+	require.NoError(t, err)
+	require.True(t, complete)
+}
+
+func TestFetchPartialState(t *testing.T) {
+	ctx := context.Background()
+	blks := tree(t, 10)
+	root := blks[0].Cid()
+
+	f, local := setup(t, blks)
+
+	// the root and the first subtree are already present
+	require.NoError(t, local.PutMany(blks[:2]))
+	for _, b := range blks[11:21] {
+		require.NoError(t, local.Put(b))
+	}
+
+	st, err := f.Fetch(ctx, root)
+	require.NoError(t, err)
+	require.Equal(t, 12, st.Local)
+	require.Equal(t, len(blks)-12, st.Fetched)
+}
+
+func TestFetchMissingBlock(t *testing.T) {
+	ctx := context.Background()
+	blks := tree(t, 5)
+	root := blks[0].Cid()
+
+	// the last leaf isn't available anywhere
+	f, _ := setup(t, blks[:len(blks)-1])
+
+	_, err := f.Fetch(ctx, root)
+	require.Error(t, err)
+
+	// the root was written, but the interrupted fetch is remembered
+	complete, err := f.Complete(root)
+	require.NoError(t, err)
+	require.False(t, complete)
+}
@@ -0,0 +1,183 @@
+package stmgr
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ipfs/go-cid"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// DefaultStateCacheSize is the default memory budget in bytes of the caches of
+// computed tipset states and loaded actors.
+const DefaultStateCacheSize = 32 << 20
+
+// Estimated memory used by an entry of the caches, including the bookkeeping
+// of the cache.
+const (
+	tipSetStateEntrySize = 256
+	actorEntrySize       = 384
+)
+
+// minTipSetStates is the number of computed tipset states cached whatever the
+// budget, as recomputing them means executing the messages of the tipsets
+// again.
+var minTipSetStates = int(2 * policy.ChainFinality)
+
+type actorCacheKey struct {
+	root cid.Cid
+	addr address.Address
+}
+
+// newStateCaches returns the caches of computed tipset states and loaded
+// actors fitting the memory budget. A quarter of the budget is for tipset
+// states, the rest for actors; the actor cache is nil if it is disabled.
+func newStateCaches(size uint64) (tipsets *lru.ARCCache, actors *lru.ARCCache, err error) {
+	ntipsets := int(size / 4 / tipSetStateEntrySize)
+	if ntipsets < minTipSetStates {
+		ntipsets = minTipSetStates
+	}
+	tipsets, err = lru.NewARC(ntipsets)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("creating tipset state cache: %w", err)
+	}
+
+	used := uint64(ntipsets) * tipSetStateEntrySize
+	if used >= size {
+		return tipsets, nil, nil
+	}
+	nactors := int((size - used) / actorEntrySize)
+	if nactors == 0 {
+		return tipsets, nil, nil
+	}
+	actors, err = lru.NewARC(nactors)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("creating actor cache: %w", err)
+	}
+	return tipsets, actors, nil
+}
+
+// SetCacheSize sets the memory budget in bytes of the caches of computed
+// tipset states and loaded actors, dropping the cached entries. At least the
+// states of the tipsets of two finalities are cached; actors aren't cached if
+// the budget is below that. It must be called before the state manager is
+// used.
+func (sm *StateManager) SetCacheSize(size uint64) error {
+	tipsets, actors, err := newStateCaches(size)
+	if err != nil {
+		return err
+	}
+
+	sm.stlk.Lock()
+	defer sm.stlk.Unlock()
+	sm.stCache = tipsets
+	sm.actorCache = actors
+	return nil
+}
+
+// PruneStates deletes the state trees older than keepEpochs behind the heaviest
+// tipset, as ChainStore.PruneStates does. The computation of states is only
+// stopped while each old state tree is deleted, and the state caches are
+// purged afterwards, as they may refer to the states deleted.
+func (sm *StateManager) PruneStates(ctx context.Context, keepEpochs abi.ChainEpoch, dryRun bool) (*api.ChainPruneResult, error) {
+	res, err := sm.cs.PruneStates(ctx, sm.cs.GetHeaviestTipSet(), keepEpochs, dryRun, (*pruneGuard)(sm))
+	if err != nil || dryRun {
+		return res, err
+	}
+
+	sm.pruneLk.Lock()
+	sm.stlk.Lock()
+	sm.stCache.Purge()
+	if sm.actorCache != nil {
+		sm.actorCache.Purge()
+	}
+	sm.stlk.Unlock()
+	sm.pruneLk.Unlock()
+	return res, nil
+}
+
+type pruneLockKey struct{}
+
+// rlockPrune read locks pruneLk for the calls made with the returned context,
+// unless ctx is already that of a call holding it: the computation of a state
+// can compute other states, and Go's RWMutex isn't reentrant, a read lock
+// waits for the pending write locks.
+func (sm *StateManager) rlockPrune(ctx context.Context) (context.Context, func()) {
+	if ctx.Value(pruneLockKey{}) != nil {
+		return ctx, func() {}
+	}
+
+	sm.pruneLk.RLock()
+	return context.WithValue(ctx, pruneLockKey{}, struct{}{}), sm.pruneLk.RUnlock
+}
+
+// pruneGuard stops the state computations of a StateManager while an old
+// state tree is deleted, and keeps the objects of the cached states.
+type pruneGuard StateManager
+
+func (g *pruneGuard) Lock() []cid.Cid {
+	sm := (*StateManager)(g)
+	sm.pruneLk.Lock()
+
+	sm.stlk.Lock()
+	defer sm.stlk.Unlock()
+	var roots []cid.Cid
+	for _, k := range sm.stCache.Keys() {
+		if v, ok := sm.stCache.Peek(k); ok {
+			// the state and receipts roots
+			roots = append(roots, v.([]cid.Cid)...)
+		}
+	}
+	return roots
+}
+
+func (g *pruneGuard) Unlock() {
+	g.pruneLk.Unlock()
+}
+
+// loadActor gets the actor from the state tree with the given root, through
+// the actor cache.
+func (sm *StateManager) loadActor(root cid.Cid, addr address.Address) (*types.Actor, error) {
+	key := actorCacheKey{root: root, addr: addr}
+	if sm.actorCache != nil {
+		if v, ok := sm.actorCache.Get(key); ok {
+			recordStateCache("actor", true)
+			act := v.(types.Actor)
+			return &act, nil
+		}
+		recordStateCache("actor", false)
+	}
+
+	state, err := sm.StateTree(root)
+	if err != nil {
+		return nil, err
+	}
+	act, err := state.GetActor(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if sm.actorCache != nil {
+		sm.actorCache.Add(key, *act)
+	}
+	return act, nil
+}
+
+func recordStateCache(cache string, hit bool) {
+	ctx, _ := tag.New(context.Background(), tag.Insert(metrics.Cache, cache))
+	if hit {
+		stats.Record(ctx, metrics.StateCacheHit.M(1))
+	} else {
+		stats.Record(ctx, metrics.StateCacheMiss.M(1))
+	}
+}
@@ -0,0 +1,80 @@
+package stmgr_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestLoadActorCache(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	mts, err := cg.NextTipSet()
+	require.NoError(t, err)
+	ts := mts.TipSet.TipSet()
+	sm := cg.StateManager()
+
+	for _, size := range []uint64{stmgr.DefaultStateCacheSize, 0} {
+		require.NoError(t, sm.SetCacheSize(size))
+
+		act, err := sm.LoadActor(ctx, cg.Banker(), ts)
+		require.NoError(t, err)
+		nonce := act.Nonce
+
+		// the cached actor isn't shared with the callers
+		act.Nonce += 100
+		act, err = sm.LoadActorTsk(ctx, cg.Banker(), ts.Key())
+		require.NoError(t, err)
+		require.Equal(t, nonce, act.Nonce)
+
+		raw, err := sm.LoadActorRaw(ctx, cg.Banker(), ts.ParentState())
+		require.NoError(t, err)
+		require.Equal(t, act, raw)
+
+		_, err = sm.LoadActor(ctx, cg.Banker(), ts)
+		require.NoError(t, err)
+	}
+}
+
+func TestPruneStatesPurgesCaches(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+
+	var old, head *types.TipSet
+	for i := 0; i < 20; i++ {
+		mts, err := cg.NextTipSet()
+		require.NoError(t, err)
+		head = mts.TipSet.TipSet()
+		if head.Height() == 3 {
+			old = head
+		}
+	}
+	// the states are pruned behind the head of the chain store, which the
+	// generator doesn't move
+	require.NoError(t, cg.ChainStore().SetHead(head))
+	sm := cg.StateManager()
+
+	_, _, err = sm.TipSetState(ctx, old)
+	require.NoError(t, err)
+
+	_, err = sm.PruneStates(ctx, 10, true)
+	require.NoError(t, err)
+	_, _, err = sm.TipSetState(ctx, old)
+	require.NoError(t, err)
+
+	_, err = sm.PruneStates(ctx, 10, false)
+	require.NoError(t, err)
+	// the state of old isn't served from the cache anymore, and can't be
+	// computed without the pruned parent state
+	_, _, err = sm.TipSetState(ctx, old)
+	require.Error(t, err)
+}
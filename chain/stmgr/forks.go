@@ -3,7 +3,9 @@ package stmgr
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
+	"fmt"
 	"math"
 
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
@@ -149,6 +151,18 @@ func (us UpgradeSchedule) Validate() error {
 	return nil
 }
 
+// Fingerprint returns a hash of the heights and network versions of the
+// upgrades. All nodes of a network run the same schedule, so builds for
+// different networks, or custom builds with different upgrade heights, can be
+// told apart by the fingerprint.
+func (us UpgradeSchedule) Fingerprint() []byte {
+	h := sha256.New()
+	for _, u := range us {
+		_, _ = fmt.Fprintf(h, "%d:%d\n", u.Height, u.Network)
+	}
+	return h.Sum(nil)
+}
+
 func (sm *StateManager) handleStateForks(ctx context.Context, root cid.Cid, height abi.ChainEpoch, cb ExecCallback, ts *types.TipSet) (cid.Cid, error) {
 	retCid := root
 	var err error
@@ -316,3 +316,15 @@ func TestForkRefuseCall(t *testing.T) {
 		}
 	}
 }
+
+func TestUpgradeScheduleFingerprint(t *testing.T) {
+	us := UpgradeSchedule{{Height: 10, Network: 1}, {Height: 20, Network: 2}}
+	require.Equal(t, us.Fingerprint(), UpgradeSchedule{{Height: 10, Network: 1}, {Height: 20, Network: 2}}.Fingerprint())
+
+	// the migrations don't matter, only when the network upgrades
+	require.Equal(t, us.Fingerprint(), UpgradeSchedule{{Height: 10, Network: 1, Expensive: true}, {Height: 20, Network: 2}}.Fingerprint())
+
+	require.NotEqual(t, us.Fingerprint(), UpgradeSchedule{{Height: 10, Network: 1}, {Height: 21, Network: 2}}.Fingerprint())
+	require.NotEqual(t, us.Fingerprint(), UpgradeSchedule{{Height: 10, Network: 1}, {Height: 20, Network: 3}}.Fingerprint())
+	require.NotEqual(t, us.Fingerprint(), UpgradeSchedule{{Height: 10, Network: 1}}.Fingerprint())
+}
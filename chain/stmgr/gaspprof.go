@@ -0,0 +1,202 @@
+package stmgr
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// WriteGasPprof writes the gas charges of the execution trace of a message as
+// a gzipped pprof profile, which can be read with go tool pprof or converted
+// to flame graphs. The samples are the gas charges, in gas units, with the
+// charge name as leaf frame and the calls leading to it, named by the actor
+// code and method, as the stack. The trace must have been recorded with gas
+// tracing enabled.
+//
+// code returns the code of an actor, to name its methods; when it fails, the
+// frames of the actor are named by its address and method number.
+func WriteGasPprof(w io.Writer, trace *types.ExecutionTrace, code func(address.Address) (cid.Cid, error)) error {
+	p := newPprofBuilder()
+
+	var walk func(et *types.ExecutionTrace, stack []uint64)
+	walk = func(et *types.ExecutionTrace, stack []uint64) {
+		frame := p.location(callName(et.Msg, code), et.Msg.To.String())
+		stack = append([]uint64{frame}, stack...)
+
+		for _, gc := range et.GasCharges {
+			if gc.TotalGas == 0 {
+				// markers of execution steps which aren't charged
+				continue
+			}
+			leaf := p.location(gc.Name, "")
+			p.sample(append([]uint64{leaf}, stack...), gc.TotalGas, gc.ComputeGas, gc.StorageGas)
+		}
+
+		for i := range et.Subcalls {
+			walk(&et.Subcalls[i], stack)
+		}
+	}
+	walk(trace, nil)
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(p.encode()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func callName(msg *types.Message, code func(address.Address) (cid.Cid, error)) string {
+	c, err := code(msg.To)
+	if err != nil {
+		return fmt.Sprintf("%s.%d", msg.To, msg.Method)
+	}
+	if m, ok := MethodsMap[c][msg.Method]; ok {
+		return builtin.ActorNameByCode(c) + "." + m.Name
+	}
+	return fmt.Sprintf("%s.%d", builtin.ActorNameByCode(c), msg.Method)
+}
+
+// pprofBuilder builds a profile in the protobuf format of pprof, see
+// https://github.com/google/pprof/blob/master/proto/profile.proto.
+type pprofBuilder struct {
+	strings   []string
+	stringIdx map[string]int64
+
+	locations map[[2]string]uint64
+	functions []pprofFunction
+	samples   []pprofSample
+}
+
+type pprofFunction struct {
+	name, file int64
+}
+
+type pprofSample struct {
+	locations []uint64
+	values    []int64
+}
+
+func newPprofBuilder() *pprofBuilder {
+	p := &pprofBuilder{
+		stringIdx: map[string]int64{},
+		locations: map[[2]string]uint64{},
+	}
+	p.str("") // the first string of the table must be empty
+	return p
+}
+
+func (p *pprofBuilder) str(s string) int64 {
+	if i, ok := p.stringIdx[s]; ok {
+		return i
+	}
+	p.strings = append(p.strings, s)
+	p.stringIdx[s] = int64(len(p.strings) - 1)
+	return p.stringIdx[s]
+}
+
+// location returns the id of the location of a function, each function
+// having a single location; ids start at 1.
+func (p *pprofBuilder) location(name, file string) uint64 {
+	k := [2]string{name, file}
+	if id, ok := p.locations[k]; ok {
+		return id
+	}
+	p.functions = append(p.functions, pprofFunction{name: p.str(name), file: p.str(file)})
+	id := uint64(len(p.functions))
+	p.locations[k] = id
+	return id
+}
+
+func (p *pprofBuilder) sample(locations []uint64, values ...int64) {
+	p.samples = append(p.samples, pprofSample{locations: locations, values: values})
+}
+
+func (p *pprofBuilder) encode() []byte {
+	var b protoBuf
+
+	valueType := func(typ, unit string) []byte {
+		var vt protoBuf
+		vt.varint(1, uint64(p.str(typ)))
+		vt.varint(2, uint64(p.str(unit)))
+		return vt
+	}
+	// sample types, in the order of the values of the samples
+	b.bytes(1, valueType("gas", "units"))
+	b.bytes(1, valueType("compute_gas", "units"))
+	b.bytes(1, valueType("storage_gas", "units"))
+
+	for _, s := range p.samples {
+		var sb protoBuf
+		sb.packed(1, s.locations)
+		values := make([]uint64, len(s.values))
+		for i, v := range s.values {
+			values[i] = uint64(v)
+		}
+		sb.packed(2, values)
+		b.bytes(2, sb)
+	}
+
+	for i, f := range p.functions {
+		id := uint64(i + 1)
+
+		var line protoBuf
+		line.varint(1, id)
+
+		var lb protoBuf
+		lb.varint(1, id)
+		lb.bytes(4, line)
+		b.bytes(4, lb)
+
+		var fb protoBuf
+		fb.varint(1, id)
+		fb.varint(2, uint64(f.name))
+		fb.varint(3, uint64(f.name))
+		fb.varint(4, uint64(f.file))
+		b.bytes(5, fb)
+	}
+
+	defaultType := p.str("gas")
+	for _, s := range p.strings {
+		b.bytes(6, []byte(s))
+	}
+	b.varint(14, uint64(defaultType))
+
+	return b
+}
+
+// protoBuf is a minimal protobuf encoder, enough for the messages of pprof.
+type protoBuf []byte
+
+func (b *protoBuf) uvarint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protoBuf) varint(field int, v uint64) {
+	b.uvarint(uint64(field) << 3)
+	b.uvarint(v)
+}
+
+func (b *protoBuf) bytes(field int, v []byte) {
+	b.uvarint(uint64(field)<<3 | 2)
+	b.uvarint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuf) packed(field int, vs []uint64) {
+	var pb protoBuf
+	for _, v := range vs {
+		pb.uvarint(v)
+	}
+	b.bytes(field, pb)
+}
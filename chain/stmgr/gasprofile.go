@@ -0,0 +1,88 @@
+package stmgr
+
+import (
+	"sort"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// MakeGasProfile aggregates the gas charges of the execution trace of a
+// message, including the ones of its subcalls, by charge point. The trace
+// must have been recorded with gas tracing enabled.
+func MakeGasProfile(mcid cid.Cid, trace *types.ExecutionTrace) *api.GasProfile {
+	entries := make(map[string]*api.GasProfileEntry)
+	entry := func(name string) *api.GasProfileEntry {
+		e, ok := entries[name]
+		if !ok {
+			e = &api.GasProfileEntry{Name: name}
+			entries[name] = e
+		}
+		return e
+	}
+
+	var walk func(et *types.ExecutionTrace)
+	walk = func(et *types.ExecutionTrace) {
+		for _, gc := range et.GasCharges {
+			switch {
+			case gc.Name == "OnIpldGetEnd":
+				// marks the end of a get, with the size of the fetched block
+				e := entry("OnIpldGet")
+				e.Bytes += gasChargeSize(gc)
+				e.TimeTaken += gc.TimeTaken
+				continue
+			case gc.TotalGas == 0 && gc.TotalVirtualGas == 0:
+				// markers of execution steps which aren't charged
+				continue
+			}
+
+			e := entry(gc.Name)
+			e.Count++
+			e.TotalGas += gc.TotalGas
+			e.ComputeGas += gc.ComputeGas
+			e.StorageGas += gc.StorageGas
+			e.Bytes += gasChargeSize(gc)
+			e.TimeTaken += gc.TimeTaken
+		}
+
+		for i := range et.Subcalls {
+			walk(&et.Subcalls[i])
+		}
+	}
+	walk(trace)
+
+	out := &api.GasProfile{
+		MsgCid:  mcid,
+		Charges: make([]api.GasProfileEntry, 0, len(entries)),
+	}
+	if trace.MsgRct != nil {
+		out.GasUsed = trace.MsgRct.GasUsed
+	}
+	for _, e := range entries {
+		out.Charges = append(out.Charges, *e)
+	}
+	sort.Slice(out.Charges, func(i, j int) bool {
+		if out.Charges[i].TotalGas != out.Charges[j].TotalGas {
+			return out.Charges[i].TotalGas > out.Charges[j].TotalGas
+		}
+		return out.Charges[i].Name < out.Charges[j].Name
+	})
+
+	return out
+}
+
+// gasChargeSize returns the data size recorded in the extra of the gas charge,
+// or 0 if it has none.
+func gasChargeSize(gc *types.GasTrace) int64 {
+	switch ex := gc.Extra.(type) {
+	case int:
+		return int64(ex)
+	case map[string]interface{}:
+		if size, ok := ex["size"].(int); ok {
+			return int64(size)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package stmgr_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestMakeGasProfile(t *testing.T) {
+	trace := &types.ExecutionTrace{
+		MsgRct: &types.MessageReceipt{GasUsed: 1000},
+		GasCharges: []*types.GasTrace{
+			{Name: "OnChainMessage", TotalGas: 400, ComputeGas: 100, StorageGas: 300},
+			{Name: "OnIpldGet", TotalGas: 100, ComputeGas: 100},
+			{Name: "OnIpldGetEnd", Extra: 64},
+			{Name: "OnMethodInvocationDone"},
+		},
+		Subcalls: []types.ExecutionTrace{{
+			GasCharges: []*types.GasTrace{
+				{Name: "OnIpldPut", TotalGas: 300, ComputeGas: 100, StorageGas: 200, Extra: 128},
+				{Name: "OnIpldGet", TotalGas: 100, ComputeGas: 100},
+				{Name: "OnIpldGetEnd", Extra: 32},
+				{Name: "OnVerifySignature", TotalGas: 100, ComputeGas: 100, Extra: map[string]interface{}{"type": "bls", "size": 16}},
+			},
+		}},
+	}
+
+	prof := stmgr.MakeGasProfile(cid.Undef, trace)
+	require.Equal(t, int64(1000), prof.GasUsed)
+
+	var names []string
+	for _, e := range prof.Charges {
+		names = append(names, e.Name)
+	}
+	// unpriced markers are left out, ties are sorted by name
+	require.Equal(t, []string{"OnChainMessage", "OnIpldPut", "OnIpldGet", "OnVerifySignature"}, names)
+
+	get := prof.Charges[2]
+	require.Equal(t, int64(2), get.Count)
+	require.Equal(t, int64(200), get.TotalGas)
+	require.Equal(t, int64(96), get.Bytes)
+
+	require.Equal(t, int64(128), prof.Charges[1].Bytes)
+	require.Equal(t, int64(16), prof.Charges[3].Bytes)
+
+	var total int64
+	for _, e := range prof.Charges {
+		total += e.TotalGas
+	}
+	require.Equal(t, prof.GasUsed, total)
+}
+
+func TestWriteGasPprof(t *testing.T) {
+	market, err := address.NewIDAddress(5)
+	require.NoError(t, err)
+	power, err := address.NewIDAddress(4)
+	require.NoError(t, err)
+
+	trace := &types.ExecutionTrace{
+		Msg: &types.Message{To: market, Method: builtin2.MethodsMarket.PublishStorageDeals},
+		GasCharges: []*types.GasTrace{
+			{Name: "OnChainMessage", TotalGas: 400, ComputeGas: 100, StorageGas: 300},
+			{Name: "OnMethodInvocationDone"},
+		},
+		Subcalls: []types.ExecutionTrace{{
+			Msg: &types.Message{To: power, Method: 3},
+			GasCharges: []*types.GasTrace{
+				{Name: "OnIpldPut", TotalGas: 300, ComputeGas: 100, StorageGas: 200},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, stmgr.WriteGasPprof(&buf, trace, func(addr address.Address) (cid.Cid, error) {
+		if addr == market {
+			return builtin2.StorageMarketActorCodeID, nil
+		}
+		return cid.Undef, xerrors.Errorf("actor not found")
+	}))
+
+	zr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	raw, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+
+	// the frames are named by actor code and method, or by address and method
+	// number when the code isn't known; unpriced markers are left out
+	for _, name := range []string{"fil/2/storagemarket.PublishStorageDeals", "f04.3", "OnChainMessage", "OnIpldPut"} {
+		require.True(t, bytes.Contains(raw, []byte(name)), name)
+	}
+	require.False(t, bytes.Contains(raw, []byte("OnMethodInvocationDone")))
+}
@@ -0,0 +1,43 @@
+package stmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPruneLockReentrant(t *testing.T) {
+	sm := &StateManager{}
+
+	ctx, unlock := sm.rlockPrune(context.Background())
+
+	locked := make(chan struct{})
+	go func() {
+		sm.pruneLk.Lock()
+		close(locked)
+		sm.pruneLk.Unlock()
+	}()
+	// let the prune wait for the lock
+	time.Sleep(50 * time.Millisecond)
+
+	// the state computed while computing another doesn't wait for the prune
+	nested := make(chan struct{})
+	go func() {
+		_, unlock := sm.rlockPrune(ctx)
+		unlock()
+		close(nested)
+	}()
+	select {
+	case <-nested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("nested read lock waited for the pending write lock")
+	}
+
+	select {
+	case <-locked:
+		t.Fatal("write lock taken while read locked")
+	default:
+	}
+	unlock()
+	<-locked
+}
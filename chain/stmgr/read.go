@@ -42,25 +42,17 @@ func (sm *StateManager) StateTree(st cid.Cid) (*state.StateTree, error) {
 }
 
 func (sm *StateManager) LoadActor(_ context.Context, addr address.Address, ts *types.TipSet) (*types.Actor, error) {
-	state, err := sm.ParentState(ts)
-	if err != nil {
-		return nil, err
-	}
-	return state.GetActor(addr)
+	return sm.loadActor(sm.parentState(ts), addr)
 }
 
 func (sm *StateManager) LoadActorTsk(_ context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
-	state, err := sm.ParentStateTsk(tsk)
+	ts, err := sm.cs.GetTipSetFromKey(tsk)
 	if err != nil {
-		return nil, err
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
 	}
-	return state.GetActor(addr)
+	return sm.loadActor(sm.parentState(ts), addr)
 }
 
 func (sm *StateManager) LoadActorRaw(_ context.Context, addr address.Address, st cid.Cid) (*types.Actor, error) {
-	state, err := sm.StateTree(st)
-	if err != nil {
-		return nil, err
-	}
-	return state.GetActor(addr)
+	return sm.loadActor(st, addr)
 }
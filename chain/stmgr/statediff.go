@@ -0,0 +1,386 @@
+package stmgr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	init_ "github.com/filecoin-project/lotus/chain/actors/builtin/init"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/multisig"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/paych"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/verifreg"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+)
+
+// DiffActors lists the actors created, deleted and modified between two state
+// trees, ordered by address.
+func DiffActors(oldTree, newTree *state.StateTree) ([]api.ActorChange, error) {
+	old := make(map[address.Address]*types.Actor)
+	if err := oldTree.ForEach(func(addr address.Address, act *types.Actor) error {
+		old[addr] = act
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("loading old actors: %w", err)
+	}
+
+	var out []api.ActorChange
+	if err := newTree.ForEach(func(addr address.Address, act *types.Actor) error {
+		prev, found := old[addr]
+		delete(old, addr)
+		if found && prev.Code == act.Code && prev.Head == act.Head && prev.Nonce == act.Nonce && prev.Balance.Equals(act.Balance) {
+			return nil
+		}
+		out = append(out, actorChange(addr, prev, act))
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("loading new actors: %w", err)
+	}
+	for addr, act := range old {
+		out = append(out, actorChange(addr, act, nil))
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return bytes.Compare(out[i].Address.Bytes(), out[j].Address.Bytes()) < 0
+	})
+	return out, nil
+}
+
+func actorChange(addr address.Address, from, to *types.Actor) api.ActorChange {
+	c := api.ActorChange{
+		Address: addr,
+		Change:  api.ActorModified,
+		From:    from,
+		To:      to,
+	}
+	switch {
+	case from == nil:
+		c.Change = api.ActorCreated
+		c.Name = builtin.ActorNameByCode(to.Code)
+	case to == nil:
+		c.Change = api.ActorDeleted
+		c.Name = builtin.ActorNameByCode(from.Code)
+	default:
+		c.Name = builtin.ActorNameByCode(to.Code)
+	}
+	return c
+}
+
+// DiffActorState returns the difference between two states of an actor, from
+// or to being nil when the actor was created or deleted. The collections of
+// the state are only diffed when the actor exists in both states.
+func DiffActorState(ctx context.Context, store adt.Store, addr address.Address, from, to *types.Actor) (*api.ActorStateDiff, error) {
+	out := &api.ActorStateDiff{ActorChange: actorChange(addr, from, to)}
+
+	fromFields, err := dumpFields(ctx, store, from)
+	if err != nil {
+		return nil, xerrors.Errorf("dumping old state: %w", err)
+	}
+	toFields, err := dumpFields(ctx, store, to)
+	if err != nil {
+		return nil, xerrors.Errorf("dumping new state: %w", err)
+	}
+	fields := make([]string, 0, len(toFields))
+	for f := range toFields {
+		fields = append(fields, f)
+	}
+	for f := range fromFields {
+		if _, ok := toFields[f]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		if !bytes.Equal(fromFields[f], toFields[f]) {
+			out.Fields = append(out.Fields, api.FieldChange{
+				Field: f,
+				From:  fromFields[f],
+				To:    toFields[f],
+			})
+		}
+	}
+
+	if from == nil || to == nil || from.Head == to.Head {
+		return out, nil
+	}
+	if path.Base(builtin.ActorNameByCode(from.Code)) != path.Base(builtin.ActorNameByCode(to.Code)) {
+		return out, nil
+	}
+
+	out.Collections, err = diffCollections(store, from, to)
+	if err != nil {
+		return nil, xerrors.Errorf("diffing collections of %s: %w", out.Name, err)
+	}
+	return out, nil
+}
+
+// dumpFields returns the fields of the state of the actor, rendered as JSON.
+func dumpFields(ctx context.Context, store adt.Store, act *types.Actor) (map[string]json.RawMessage, error) {
+	if act == nil {
+		return nil, nil
+	}
+
+	var raw cbg.Deferred
+	if err := store.Get(ctx, act.Head, &raw); err != nil {
+		return nil, xerrors.Errorf("getting actor head: %w", err)
+	}
+	st, err := vm.DumpActorState(act, raw.Raw)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func diffCollections(store adt.Store, from, to *types.Actor) ([]api.CollectionDiff, error) {
+	switch path.Base(builtin.ActorNameByCode(to.Code)) {
+	case "storageminer":
+		pre, err := miner.Load(store, from)
+		if err != nil {
+			return nil, err
+		}
+		cur, err := miner.Load(store, to)
+		if err != nil {
+			return nil, err
+		}
+
+		sectors, err := miner.DiffSectors(pre, cur)
+		if err != nil {
+			return nil, xerrors.Errorf("diffing sectors: %w", err)
+		}
+		sd := api.CollectionDiff{Name: "Sectors"}
+		for _, s := range sectors.Added {
+			sd.Added = append(sd.Added, api.CollectionEntry{Key: fmt.Sprint(s.SectorNumber), Value: s})
+		}
+		for _, s := range sectors.Removed {
+			sd.Removed = append(sd.Removed, api.CollectionEntry{Key: fmt.Sprint(s.SectorNumber), Value: s})
+		}
+		for _, s := range sectors.Extended {
+			sd.Modified = append(sd.Modified, api.CollectionChange{Key: fmt.Sprint(s.To.SectorNumber), From: s.From, To: s.To})
+		}
+
+		precommits, err := miner.DiffPreCommits(pre, cur)
+		if err != nil {
+			return nil, xerrors.Errorf("diffing precommits: %w", err)
+		}
+		pd := api.CollectionDiff{Name: "PreCommittedSectors"}
+		for _, s := range precommits.Added {
+			pd.Added = append(pd.Added, api.CollectionEntry{Key: fmt.Sprint(s.Info.SectorNumber), Value: s})
+		}
+		for _, s := range precommits.Removed {
+			pd.Removed = append(pd.Removed, api.CollectionEntry{Key: fmt.Sprint(s.Info.SectorNumber), Value: s})
+		}
+
+		return []api.CollectionDiff{sd, pd}, nil
+	case "storagemarket":
+		pre, err := market.Load(store, from)
+		if err != nil {
+			return nil, err
+		}
+		cur, err := market.Load(store, to)
+		if err != nil {
+			return nil, err
+		}
+
+		preProps, err := pre.Proposals()
+		if err != nil {
+			return nil, err
+		}
+		curProps, err := cur.Proposals()
+		if err != nil {
+			return nil, err
+		}
+		props, err := market.DiffDealProposals(preProps, curProps)
+		if err != nil {
+			return nil, err
+		}
+		pd := api.CollectionDiff{Name: "Proposals"}
+		for _, p := range props.Added {
+			pd.Added = append(pd.Added, api.CollectionEntry{Key: fmt.Sprint(p.ID), Value: p.Proposal})
+		}
+		for _, p := range props.Removed {
+			pd.Removed = append(pd.Removed, api.CollectionEntry{Key: fmt.Sprint(p.ID), Value: p.Proposal})
+		}
+
+		preStates, err := pre.States()
+		if err != nil {
+			return nil, err
+		}
+		curStates, err := cur.States()
+		if err != nil {
+			return nil, err
+		}
+		states, err := market.DiffDealStates(preStates, curStates)
+		if err != nil {
+			return nil, err
+		}
+		sd := api.CollectionDiff{Name: "States"}
+		for _, s := range states.Added {
+			sd.Added = append(sd.Added, api.CollectionEntry{Key: fmt.Sprint(s.ID), Value: s.Deal})
+		}
+		for _, s := range states.Removed {
+			sd.Removed = append(sd.Removed, api.CollectionEntry{Key: fmt.Sprint(s.ID), Value: s.Deal})
+		}
+		for _, s := range states.Modified {
+			sd.Modified = append(sd.Modified, api.CollectionChange{Key: fmt.Sprint(s.ID), From: s.From, To: s.To})
+		}
+
+		return []api.CollectionDiff{pd, sd}, nil
+	case "init":
+		return diffEntries([]string{"AddressMap"}, func(act *types.Actor, out []map[string]interface{}) error {
+			st, err := init_.Load(store, act)
+			if err != nil {
+				return err
+			}
+			return st.ForEachActor(func(id abi.ActorID, addr address.Address) error {
+				out[0][addr.String()] = id
+				return nil
+			})
+		}, from, to)
+	case "storagepower":
+		return diffEntries([]string{"Claims"}, func(act *types.Actor, out []map[string]interface{}) error {
+			st, err := power.Load(store, act)
+			if err != nil {
+				return err
+			}
+			return st.ForEachClaim(func(maddr address.Address, claim power.Claim) error {
+				out[0][maddr.String()] = claim
+				return nil
+			})
+		}, from, to)
+	case "verifiedregistry":
+		return diffEntries([]string{"Verifiers", "VerifiedClients"}, func(act *types.Actor, out []map[string]interface{}) error {
+			st, err := verifreg.Load(store, act)
+			if err != nil {
+				return err
+			}
+			if err := st.ForEachVerifier(func(addr address.Address, dcap abi.StoragePower) error {
+				out[0][addr.String()] = dcap
+				return nil
+			}); err != nil {
+				return err
+			}
+			return st.ForEachClient(func(addr address.Address, dcap abi.StoragePower) error {
+				out[1][addr.String()] = dcap
+				return nil
+			})
+		}, from, to)
+	case "multisig":
+		return diffEntries([]string{"PendingTxns"}, func(act *types.Actor, out []map[string]interface{}) error {
+			st, err := multisig.Load(store, act)
+			if err != nil {
+				return err
+			}
+			return st.ForEachPendingTxn(func(id int64, txn multisig.Transaction) error {
+				out[0][fmt.Sprint(id)] = txn
+				return nil
+			})
+		}, from, to)
+	case "paymentchannel":
+		type laneState struct {
+			Redeemed abi.TokenAmount
+			Nonce    uint64
+		}
+		return diffEntries([]string{"LaneStates"}, func(act *types.Actor, out []map[string]interface{}) error {
+			st, err := paych.Load(store, act)
+			if err != nil {
+				return err
+			}
+			return st.ForEachLaneState(func(idx uint64, ls paych.LaneState) error {
+				redeemed, err := ls.Redeemed()
+				if err != nil {
+					return err
+				}
+				nonce, err := ls.Nonce()
+				if err != nil {
+					return err
+				}
+				out[0][fmt.Sprint(idx)] = laneState{Redeemed: redeemed, Nonce: nonce}
+				return nil
+			})
+		}, from, to)
+	default:
+		return nil, nil
+	}
+}
+
+// diffEntries diffs the named collections of two states of an actor, loading
+// the entries of each collection by key with load. It's used for the
+// collections the actor abstraction doesn't diff itself.
+func diffEntries(names []string, load func(*types.Actor, []map[string]interface{}) error, from, to *types.Actor) ([]api.CollectionDiff, error) {
+	mk := func() []map[string]interface{} {
+		m := make([]map[string]interface{}, len(names))
+		for i := range m {
+			m[i] = map[string]interface{}{}
+		}
+		return m
+	}
+	pre, cur := mk(), mk()
+	if err := load(from, pre); err != nil {
+		return nil, xerrors.Errorf("loading old state: %w", err)
+	}
+	if err := load(to, cur); err != nil {
+		return nil, xerrors.Errorf("loading new state: %w", err)
+	}
+
+	out := make([]api.CollectionDiff, len(names))
+	for i, name := range names {
+		out[i].Name = name
+		for _, k := range sortedKeys(pre[i], cur[i]) {
+			p, inPre := pre[i][k]
+			c, inCur := cur[i][k]
+			switch {
+			case !inPre:
+				out[i].Added = append(out[i].Added, api.CollectionEntry{Key: k, Value: c})
+			case !inCur:
+				out[i].Removed = append(out[i].Removed, api.CollectionEntry{Key: k, Value: p})
+			case !reflect.DeepEqual(p, c):
+				out[i].Modified = append(out[i].Modified, api.CollectionChange{Key: k, From: p, To: c})
+			}
+		}
+	}
+	return out, nil
+}
+
+// sortedKeys returns the keys of the two maps, sorted and deduplicated.
+func sortedKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
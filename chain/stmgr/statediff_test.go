@@ -0,0 +1,75 @@
+package stmgr
+
+import (
+	"context"
+	"testing"
+
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestDiffActors(t *testing.T) {
+	ctx := context.Background()
+	cst := cbor.NewMemCborStore()
+
+	addr := func(id uint64) address.Address {
+		a, err := address.NewIDAddress(id)
+		require.NoError(t, err)
+		return a
+	}
+	actor := func(nonce uint64) *types.Actor {
+		return &types.Actor{
+			Code:    builtin0.AccountActorCodeID,
+			Head:    builtin0.AccountActorCodeID,
+			Nonce:   nonce,
+			Balance: types.NewInt(10),
+		}
+	}
+
+	st, err := state.NewStateTree(cst, state.VersionForNetwork(build.NewestNetworkVersion))
+	require.NoError(t, err)
+	require.NoError(t, st.SetActor(addr(100), actor(0)))
+	require.NoError(t, st.SetActor(addr(101), actor(0)))
+	require.NoError(t, st.SetActor(addr(102), actor(0)))
+	oldRoot, err := st.Flush(ctx)
+	require.NoError(t, err)
+
+	st, err = state.LoadStateTree(cst, oldRoot)
+	require.NoError(t, err)
+	require.NoError(t, st.SetActor(addr(100), actor(1)))
+	require.NoError(t, st.DeleteActor(addr(101)))
+	require.NoError(t, st.SetActor(addr(103), actor(0)))
+	newRoot, err := st.Flush(ctx)
+	require.NoError(t, err)
+
+	oldTree, err := state.LoadStateTree(cst, oldRoot)
+	require.NoError(t, err)
+	newTree, err := state.LoadStateTree(cst, newRoot)
+	require.NoError(t, err)
+
+	changes, err := DiffActors(oldTree, newTree)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	require.Equal(t, addr(100), changes[0].Address)
+	require.Equal(t, api.ActorModified, changes[0].Change)
+	require.Equal(t, uint64(0), changes[0].From.Nonce)
+	require.Equal(t, uint64(1), changes[0].To.Nonce)
+
+	require.Equal(t, addr(101), changes[1].Address)
+	require.Equal(t, api.ActorDeleted, changes[1].Change)
+	require.Nil(t, changes[1].To)
+
+	require.Equal(t, addr(103), changes[2].Address)
+	require.Equal(t, api.ActorCreated, changes[2].Change)
+	require.Nil(t, changes[2].From)
+	require.Equal(t, "fil/1/account", changes[2].Name)
+}
@@ -16,6 +16,7 @@ import (
 	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
 	msig0 "github.com/filecoin-project/specs-actors/actors/builtin/multisig"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	logging "github.com/ipfs/go-log/v2"
@@ -43,10 +44,14 @@ import (
 	"github.com/filecoin-project/lotus/chain/vm"
 )
 
-const LookbackNoLimit = abi.ChainEpoch(-1)
+const LookbackNoLimit = api.LookbackNoLimit
 
 var log = logging.Logger("statemgr")
 
+// ErrMessageReplaced is returned when looking for a message replaced by another
+// message from the same sender with the same nonce.
+var ErrMessageReplaced = errors.New("message replaced")
+
 type StateManagerAPI interface {
 	LoadActorTsk(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error)
 	LookupID(ctx context.Context, addr address.Address, ts *types.TipSet) (address.Address, error)
@@ -72,11 +77,14 @@ type StateManager struct {
 	// ErrExpensiveFork.
 	expensiveUpgrades map[abi.ChainEpoch]struct{}
 
-	stCache              map[string][]cid.Cid
+	stCache              *lru.ARCCache // tipset key -> []cid.Cid{state root, receipts root}
+	actorCache           *lru.ARCCache // actorCacheKey -> types.Actor, nil if disabled
 	compWait             map[string]chan struct{}
 	stlk                 sync.Mutex
+	pruneLk              sync.RWMutex // held for writing while deleting an old state, see PruneStates
 	genesisMsigLk        sync.Mutex
 	newVM                func(context.Context, *vm.VMOpts) (*vm.VM, error)
+	parallelism          int // messages executed concurrently, see SetExecutionParallelism
 	preIgnitionGenInfos  *genesisInfo
 	postIgnitionGenInfos *genesisInfo
 }
@@ -120,6 +128,11 @@ func NewStateManagerWithUpgradeSchedule(cs *store.ChainStore, us UpgradeSchedule
 		lastVersion = build.NewestNetworkVersion
 	}
 
+	stCache, actorCache, err := newStateCaches(DefaultStateCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
 	return &StateManager{
 		networkVersions:   networkVersions,
 		latestVersion:     lastVersion,
@@ -127,7 +140,8 @@ func NewStateManagerWithUpgradeSchedule(cs *store.ChainStore, us UpgradeSchedule
 		expensiveUpgrades: expensiveUpgrades,
 		newVM:             vm.NewVM,
 		cs:                cs,
-		stCache:           make(map[string][]cid.Cid),
+		stCache:           stCache,
+		actorCache:        actorCache,
 		compWait:          make(map[string]chan struct{}),
 	}, nil
 }
@@ -147,6 +161,12 @@ func (sm *StateManager) TipSetState(ctx context.Context, ts *types.TipSet) (st c
 		span.AddAttributes(trace.StringAttribute("tipset", fmt.Sprint(ts.Cids())))
 	}
 
+	// states aren't computed nor read from the cache while an old state is
+	// deleted, as they may refer to its objects. pruneLk is always taken
+	// before stlk.
+	ctx, unlock := sm.rlockPrune(ctx)
+	defer unlock()
+
 	ck := cidsToKey(ts.Cids())
 	sm.stlk.Lock()
 	cw, cwok := sm.compWait[ck]
@@ -160,12 +180,14 @@ func (sm *StateManager) TipSetState(ctx context.Context, ts *types.TipSet) (st c
 			return cid.Undef, cid.Undef, ctx.Err()
 		}
 	}
-	cached, ok := sm.stCache[ck]
-	if ok {
+	if v, ok := sm.stCache.Get(ck); ok {
 		sm.stlk.Unlock()
 		span.AddAttributes(trace.BoolAttribute("cache", true))
+		recordStateCache("tipset", true)
+		cached := v.([]cid.Cid)
 		return cached[0], cached[1], nil
 	}
+	recordStateCache("tipset", false)
 	ch := make(chan struct{})
 	sm.compWait[ck] = ch
 
@@ -173,7 +195,7 @@ func (sm *StateManager) TipSetState(ctx context.Context, ts *types.TipSet) (st c
 		sm.stlk.Lock()
 		delete(sm.compWait, ck)
 		if st != cid.Undef {
-			sm.stCache[ck] = []cid.Cid{st, rec}
+			sm.stCache.Add(ck, []cid.Cid{st, rec})
 		}
 		sm.stlk.Unlock()
 		close(ch)
@@ -241,6 +263,9 @@ func (sm *StateManager) ApplyBlocks(ctx context.Context, parentEpoch abi.ChainEp
 			CircSupplyCalc: sm.GetVMCirculatingSupply,
 			NtwkVersion:    sm.GetNtwkVersion,
 			BaseFee:        baseFee,
+			// the callback gets the execution traces, include the gas charges
+			Tracing:     cb != nil,
+			Parallelism: sm.parallelism,
 		}
 
 		return sm.newVM(ctx, vmopt)
@@ -317,15 +342,24 @@ func (sm *StateManager) ApplyBlocks(ctx context.Context, parentEpoch abi.ChainEp
 		penalty := types.NewInt(0)
 		gasReward := big.Zero()
 
+		var msgs []types.ChainMsg
 		for _, cm := range append(b.BlsMessages, b.SecpkMessages...) {
 			m := cm.VMMessage()
 			if _, found := processedMsgs[m.Cid()]; found {
 				continue
 			}
-			r, err := vmi.ApplyMessage(ctx, cm)
-			if err != nil {
-				return cid.Undef, cid.Undef, err
-			}
+			msgs = append(msgs, cm)
+			processedMsgs[m.Cid()] = struct{}{}
+		}
+
+		rets, err := vmi.ApplyMessages(ctx, msgs)
+		if err != nil {
+			return cid.Undef, cid.Undef, err
+		}
+
+		for i, cm := range msgs {
+			m := cm.VMMessage()
+			r := rets[i]
 
 			receipts = append(receipts, &r.MessageReceipt)
 			gasReward = big.Add(gasReward, r.GasCosts.MinerTip)
@@ -336,7 +370,6 @@ func (sm *StateManager) ApplyBlocks(ctx context.Context, parentEpoch abi.ChainEp
 					return cid.Undef, cid.Undef, err
 				}
 			}
-			processedMsgs[m.Cid()] = struct{}{}
 		}
 
 		params, err := actors.SerializeParams(&reward.AwardBlockRewardParams{
@@ -510,7 +543,7 @@ func (sm *StateManager) GetReceipt(ctx context.Context, msg cid.Cid, ts *types.T
 		return nil, fmt.Errorf("failed to load message: %w", err)
 	}
 
-	_, r, _, err := sm.searchBackForMsg(ctx, ts, m, LookbackNoLimit)
+	_, r, _, err := sm.searchBackForMsg(ctx, ts, m, LookbackNoLimit, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to look back through chain for message: %w", err)
 	}
@@ -521,7 +554,10 @@ func (sm *StateManager) GetReceipt(ctx context.Context, msg cid.Cid, ts *types.T
 // WaitForMessage blocks until a message appears on chain. It looks backwards in the chain to see if this has already
 // happened, with an optional limit to how many epochs it will search. It guarantees that the message has been on
 // chain for at least confidence epochs without being reverted before returning.
-func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confidence uint64, lookbackLimit abi.ChainEpoch) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
+// If allowReplaced is true, a message from the same sender with the same nonce and call, e.g. the message with its
+// gas values bumped, is accepted in place of the message, and the CID of the message executed is returned. If another
+// message with the same nonce is executed, the message can't be anymore and an error is returned.
+func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confidence uint64, lookbackLimit abi.ChainEpoch, allowReplaced bool) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -545,7 +581,7 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confid
 		return nil, nil, cid.Undef, fmt.Errorf("expected current head on SHC stream (got %s)", head[0].Type)
 	}
 
-	r, foundMsg, err := sm.tipsetExecutedMessage(head[0].Val, mcid, msg.VMMessage())
+	r, foundMsg, err := sm.tipsetExecutedMessage(head[0].Val, mcid, msg.VMMessage(), allowReplaced)
 	if err != nil {
 		return nil, nil, cid.Undef, err
 	}
@@ -557,18 +593,12 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confid
 	var backTs *types.TipSet
 	var backRcp *types.MessageReceipt
 	var backFm cid.Cid
+	var backErr error
 	backSearchWait := make(chan struct{})
 	go func() {
-		fts, r, foundMsg, err := sm.searchBackForMsg(ctx, head[0].Val, msg, lookbackLimit)
-		if err != nil {
-			log.Warnf("failed to look back through chain for message: %w", err)
-			return
-		}
+		defer close(backSearchWait)
 
-		backTs = fts
-		backRcp = r
-		backFm = foundMsg
-		close(backSearchWait)
+		backTs, backRcp, backFm, backErr = sm.searchBackForMsg(ctx, head[0].Val, msg, lookbackLimit, allowReplaced)
 	}()
 
 	var candidateTs *types.TipSet
@@ -598,7 +628,7 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confid
 					if candidateTs != nil && val.Val.Height() >= candidateTs.Height()+abi.ChainEpoch(confidence) {
 						return candidateTs, candidateRcp, candidateFm, nil
 					}
-					r, foundMsg, err := sm.tipsetExecutedMessage(val.Val, mcid, msg.VMMessage())
+					r, foundMsg, err := sm.tipsetExecutedMessage(val.Val, mcid, msg.VMMessage(), allowReplaced)
 					if err != nil {
 						return nil, nil, cid.Undef, err
 					}
@@ -614,6 +644,13 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confid
 				}
 			}
 		case <-backSearchWait:
+			if backErr != nil {
+				if xerrors.Is(backErr, ErrMessageReplaced) {
+					return nil, nil, cid.Undef, backErr
+				}
+				log.Warnf("failed to look back through chain for message: %s", backErr)
+			}
+
 			// check if we found the message in the chain and that is hasn't been reverted since we started searching
 			if backTs != nil && !reverts[backTs.Key()] {
 				// if head is at or past confidence interval, return immediately
@@ -634,7 +671,10 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid, confid
 	}
 }
 
-func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
+// SearchForMessage looks backwards in the chain, up to lookbackLimit epochs, for
+// the tipset where the message was executed, returning nil if it wasn't found.
+// See WaitForMessage for allowReplaced.
+func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid, lookbackLimit abi.ChainEpoch, allowReplaced bool) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
 	msg, err := sm.cs.GetCMessage(mcid)
 	if err != nil {
 		return nil, nil, cid.Undef, fmt.Errorf("failed to load message: %w", err)
@@ -642,7 +682,7 @@ func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid) (*ty
 
 	head := sm.cs.GetHeaviestTipSet()
 
-	r, foundMsg, err := sm.tipsetExecutedMessage(head, mcid, msg.VMMessage())
+	r, foundMsg, err := sm.tipsetExecutedMessage(head, mcid, msg.VMMessage(), allowReplaced)
 	if err != nil {
 		return nil, nil, cid.Undef, err
 	}
@@ -651,7 +691,7 @@ func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid) (*ty
 		return head, r, foundMsg, nil
 	}
 
-	fts, r, foundMsg, err := sm.searchBackForMsg(ctx, head, msg, LookbackNoLimit)
+	fts, r, foundMsg, err := sm.searchBackForMsg(ctx, head, msg, lookbackLimit, allowReplaced)
 
 	if err != nil {
 		log.Warnf("failed to look back through chain for message %s", mcid)
@@ -671,10 +711,17 @@ func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid) (*ty
 // - 0 then no tipsets are searched
 // - 5 then five tipset are searched
 // - LookbackNoLimit then there is no limit
-func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet, m types.ChainMsg, limit abi.ChainEpoch) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
+func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet, m types.ChainMsg, limit abi.ChainEpoch, allowReplaced bool) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
 	limitHeight := from.Height() - limit
 	noLimit := limit == LookbackNoLimit
 
+	ts, r, err := sm.searchMsgIndex(ctx, from, m.Cid())
+	if err != nil {
+		log.Warnf("message index lookup failed, searching the chain: %s", err)
+	} else if ts != nil && (noLimit || ts.Height() > limitHeight) {
+		return ts, r, m.Cid(), nil
+	}
+
 	cur := from
 	curActor, err := sm.LoadActor(ctx, m.VMMessage().From, cur)
 	if err != nil {
@@ -721,7 +768,7 @@ func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet
 
 		// check that between cur and parent tipset the nonce fell into range of our message
 		if actorNoExist || (curActor.Nonce > mNonce && act.Nonce <= mNonce) {
-			r, foundMsg, err := sm.tipsetExecutedMessage(cur, m.Cid(), m.VMMessage())
+			r, foundMsg, err := sm.tipsetExecutedMessage(cur, m.Cid(), m.VMMessage(), allowReplaced)
 			if err != nil {
 				return nil, nil, cid.Undef, xerrors.Errorf("checking for message execution during lookback: %w", err)
 			}
@@ -736,7 +783,36 @@ func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet
 	}
 }
 
-func (sm *StateManager) tipsetExecutedMessage(ts *types.TipSet, msg cid.Cid, vmm *types.Message) (*types.MessageReceipt, cid.Cid, error) {
+// searchMsgIndex looks the message up in the message index, returning the
+// tipset with its receipt if that tipset is on the chain of from.
+func (sm *StateManager) searchMsgIndex(ctx context.Context, from *types.TipSet, mcid cid.Cid) (*types.TipSet, *types.MessageReceipt, error) {
+	mi, err := sm.cs.GetMsgInfo(mcid)
+	if err != nil || mi == nil || mi.Epoch > from.Height() {
+		return nil, nil, err
+	}
+
+	ts, err := sm.cs.GetTipsetByHeight(ctx, mi.Epoch, from, false)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("loading tipset at indexed epoch: %w", err)
+	}
+	if ts.Key() != mi.TipSet {
+		// reverted since it was indexed
+		return nil, nil, nil
+	}
+
+	r, err := sm.cs.GetParentReceipt(ts.Blocks()[0], mi.Index)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("loading indexed receipt: %w", err)
+	}
+
+	return ts, r, nil
+}
+
+// tipsetExecutedMessage returns the receipt of the message if it was included
+// in the parent of the tipset, or of the message replacing it if allowed, with
+// the CID of the message executed. It fails if another message from the same
+// sender with the same nonce was included.
+func (sm *StateManager) tipsetExecutedMessage(ts *types.TipSet, msg cid.Cid, vmm *types.Message, allowReplaced bool) (*types.MessageReceipt, cid.Cid, error) {
 	// The genesis block did not execute any messages
 	if ts.Height() == 0 {
 		return nil, cid.Undef, nil
@@ -759,7 +835,7 @@ func (sm *StateManager) tipsetExecutedMessage(ts *types.TipSet, msg cid.Cid, vmm
 
 		if m.VMMessage().From == vmm.From { // cheaper to just check origin first
 			if m.VMMessage().Nonce == vmm.Nonce {
-				if m.VMMessage().EqualCall(vmm) {
+				if m.Cid() == msg || allowReplaced && m.VMMessage().EqualCall(vmm) {
 					if m.Cid() != msg {
 						log.Warnw("found message with equal nonce and call params but different CID",
 							"wanted", msg, "found", m.Cid(), "nonce", vmm.Nonce, "from", vmm.From)
@@ -772,9 +848,9 @@ func (sm *StateManager) tipsetExecutedMessage(ts *types.TipSet, msg cid.Cid, vmm
 					return pr, m.Cid(), nil
 				}
 
-				// this should be that message
-				return nil, cid.Undef, xerrors.Errorf("found message with equal nonce as the one we are looking for (F:%s n %d, TS: %s n%d)",
-					msg, vmm.Nonce, m.Cid(), m.VMMessage().Nonce)
+				// the message was replaced, it can't be executed anymore
+				return nil, cid.Undef, xerrors.Errorf("message %s was replaced by message %s with the same nonce %d, executed at %d: %w",
+					msg, m.Cid(), vmm.Nonce, ts.Height(), ErrMessageReplaced)
 			}
 			if m.VMMessage().Nonce < vmm.Nonce {
 				return nil, cid.Undef, nil // don't bother looking further
@@ -888,6 +964,13 @@ func (sm *StateManager) SetVMConstructor(nvm func(context.Context, *vm.VMOpts) (
 	sm.newVM = nvm
 }
 
+// SetExecutionParallelism sets the number of messages of a block executed
+// concurrently when computing tipset states. Messages are executed one by one
+// if it is below 2. It must be called before the state manager is used.
+func (sm *StateManager) SetExecutionParallelism(n int) {
+	sm.parallelism = n
+}
+
 type genesisInfo struct {
 	genesisMsigs []msig0.State
 	// info about the Accounts in the genesis state
@@ -1336,11 +1419,18 @@ func (sm *StateManager) GetVMCirculatingSupplyDetailed(ctx context.Context, heig
 		return api.CirculatingSupply{}, xerrors.Errorf("failed to calculate filBurnt: %w", err)
 	}
 
-	filLocked, err := sm.GetFilLocked(ctx, st)
+	filMarketLocked, err := getFilMarketLocked(ctx, st)
 	if err != nil {
-		return api.CirculatingSupply{}, xerrors.Errorf("failed to calculate filLocked: %w", err)
+		return api.CirculatingSupply{}, xerrors.Errorf("failed to calculate filMarketLocked: %w", err)
 	}
 
+	filPowerLocked, err := getFilPowerLocked(ctx, st)
+	if err != nil {
+		return api.CirculatingSupply{}, xerrors.Errorf("failed to calculate filPowerLocked: %w", err)
+	}
+
+	filLocked := types.BigAdd(filMarketLocked, filPowerLocked)
+
 	ret := types.BigAdd(filVested, filMined)
 	ret = types.BigAdd(ret, filReserveDisbursed)
 	ret = types.BigSub(ret, filBurnt)
@@ -1351,11 +1441,14 @@ func (sm *StateManager) GetVMCirculatingSupplyDetailed(ctx context.Context, heig
 	}
 
 	return api.CirculatingSupply{
-		FilVested:      filVested,
-		FilMined:       filMined,
-		FilBurnt:       filBurnt,
-		FilLocked:      filLocked,
-		FilCirculating: ret,
+		FilVested:           filVested,
+		FilMined:            filMined,
+		FilReserveDisbursed: filReserveDisbursed,
+		FilBurnt:            filBurnt,
+		FilLocked:           filLocked,
+		FilMarketLocked:     filMarketLocked,
+		FilPowerLocked:      filPowerLocked,
+		FilCirculating:      ret,
 	}, nil
 }
 
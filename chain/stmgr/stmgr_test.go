@@ -0,0 +1,130 @@
+package stmgr_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestSearchForReplacedMessage(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	sm := cg.StateManager()
+
+	banker, err := sm.LoadActor(ctx, cg.Banker(), cg.CurTipset.TipSet())
+	require.NoError(t, err)
+
+	sign := func(m *types.Message) *types.SignedMessage {
+		sig, err := cg.Wallet().WalletSign(ctx, cg.Banker(), m.Cid().Bytes(), api.MsgMeta{})
+		require.NoError(t, err)
+		smsg := &types.SignedMessage{Message: *m, Signature: *sig}
+		_, err = cg.ChainStore().PutMessage(smsg)
+		require.NoError(t, err)
+		return smsg
+	}
+	msg := func(value, premium uint64) *types.SignedMessage {
+		return sign(&types.Message{
+			From:       cg.Banker(),
+			To:         mustIDAddr(t, 1000),
+			Nonce:      banker.Nonce,
+			Value:      types.NewInt(value),
+			GasLimit:   types.TestGasLimit,
+			GasFeeCap:  types.NewInt(premium),
+			GasPremium: types.NewInt(premium),
+		})
+	}
+
+	executed := msg(1, 1)
+	gasBumped := msg(1, 0) // same call, different gas values
+	otherCall := msg(2, 1) // same nonce, different call
+
+	included := false
+	cg.GetMessages = func(*gen.ChainGen) ([]*types.SignedMessage, error) {
+		if included {
+			return nil, nil
+		}
+		included = true
+		return []*types.SignedMessage{executed}, nil
+	}
+	var head *types.TipSet
+	for i := 0; i < 5; i++ {
+		mts, err := cg.NextTipSet()
+		require.NoError(t, err)
+		head = mts.TipSet.TipSet()
+	}
+	// the generator doesn't move the head of the chain store
+	require.NoError(t, cg.ChainStore().SetHead(head))
+
+	ts, _, found, err := sm.SearchForMessage(ctx, executed.Cid(), stmgr.LookbackNoLimit, false)
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	require.Equal(t, executed.Cid(), found)
+
+	// the message was executed before the lookback limit
+	ts, _, _, err = sm.SearchForMessage(ctx, executed.Cid(), 1, false)
+	require.NoError(t, err)
+	require.Nil(t, ts)
+
+	// a replacement with only the gas values changed is accepted if allowed
+	ts, _, found, err = sm.SearchForMessage(ctx, gasBumped.Cid(), stmgr.LookbackNoLimit, true)
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	require.Equal(t, executed.Cid(), found)
+
+	_, _, _, err = sm.SearchForMessage(ctx, gasBumped.Cid(), stmgr.LookbackNoLimit, false)
+	require.True(t, xerrors.Is(err, stmgr.ErrMessageReplaced), err)
+
+	_, _, _, err = sm.SearchForMessage(ctx, otherCall.Cid(), stmgr.LookbackNoLimit, true)
+	require.True(t, xerrors.Is(err, stmgr.ErrMessageReplaced), err)
+
+	// waiting for a replaced message fails instead of blocking
+	_, _, _, err = sm.WaitForMessage(ctx, otherCall.Cid(), 1, stmgr.LookbackNoLimit, true)
+	require.True(t, xerrors.Is(err, stmgr.ErrMessageReplaced), err)
+}
+
+func TestVMCirculatingSupplyBreakdown(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	sm := cg.StateManager()
+
+	for i := 0; i < 3; i++ {
+		_, err := cg.NextTipSet()
+		require.NoError(t, err)
+	}
+
+	ts := cg.CurTipset.TipSet()
+	st, err := sm.ParentState(ts)
+	require.NoError(t, err)
+
+	cs, err := sm.GetVMCirculatingSupplyDetailed(ctx, ts.Height(), st)
+	require.NoError(t, err)
+
+	require.True(t, cs.FilMined.GreaterThan(big.Zero()))
+	require.Equal(t, big.Add(cs.FilMarketLocked, cs.FilPowerLocked), cs.FilLocked)
+
+	circ := big.Add(cs.FilVested, cs.FilMined)
+	circ = big.Add(circ, cs.FilReserveDisbursed)
+	circ = big.Sub(circ, cs.FilBurnt)
+	circ = big.Sub(circ, cs.FilLocked)
+	if circ.LessThan(big.Zero()) {
+		circ = big.Zero()
+	}
+	require.Equal(t, circ, cs.FilCirculating)
+
+	vmcs, err := sm.GetVMCirculatingSupply(ctx, ts.Height(), st)
+	require.NoError(t, err)
+	require.Equal(t, cs.FilCirculating, vmcs)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/go-state-types/exitcode"
 	"github.com/filecoin-project/go-state-types/rt"
 
 	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
@@ -386,21 +387,33 @@ func ComputeState(ctx context.Context, sm *StateManager, height abi.ChainEpoch,
 		CircSupplyCalc: sm.GetVMCirculatingSupply,
 		NtwkVersion:    sm.GetNtwkVersion,
 		BaseFee:        ts.Blocks()[0].ParentBaseFee,
+		Tracing:        true,
+		Parallelism:    sm.parallelism,
 	}
 	vmi, err := sm.newVM(ctx, vmopt)
 	if err != nil {
 		return cid.Undef, nil, err
 	}
 
+	// TODO: Use the signed message length for secp messages
+	cms := make([]types.ChainMsg, len(msgs))
 	for i, msg := range msgs {
-		// TODO: Use the signed message length for secp messages
-		ret, err := vmi.ApplyMessage(ctx, msg)
-		if err != nil {
-			return cid.Undef, nil, xerrors.Errorf("applying message %s: %w", msg.Cid(), err)
-		}
+		cms[i] = msg
+	}
+	rets, err := vmi.ApplyMessages(ctx, cms)
+	if err != nil {
+		return cid.Undef, nil, xerrors.Errorf("applying messages: %w", err)
+	}
+
+	tf := traceFunc(&trace)
+	for i, msg := range msgs {
+		ret := rets[i]
 		if ret.ExitCode != 0 {
 			log.Infof("compute state apply message %d failed (exit: %d): %s", i, ret.ExitCode, ret.ActorErr)
 		}
+		if err := tf(msg.Cid(), msg, ret); err != nil {
+			return cid.Undef, nil, xerrors.Errorf("tracing message %s: %w", msg.Cid(), err)
+		}
 	}
 
 	root, err := vmi.Flush(ctx)
@@ -606,6 +619,41 @@ func GetReturnType(ctx context.Context, sm *StateManager, to address.Address, me
 	return reflect.New(m.Ret.Elem()).Interface().(cbg.CBORUnmarshaler), nil
 }
 
+// DecodeParams decodes the params of a call of the given method of an actor of
+// the given code, of any builtin actors version, with the params type of the
+// method.
+func DecodeParams(code cid.Cid, method abi.MethodNum, params []byte) (interface{}, error) {
+	m, found := MethodsMap[code][method]
+	if !found {
+		return nil, xerrors.Errorf("unknown method %d for actor %s", method, code)
+	}
+	return decodeCBOR(m.Params, params)
+}
+
+// DecodeReturn decodes the return value of a call of the given method of an
+// actor of the given code, like DecodeParams.
+func DecodeReturn(code cid.Cid, method abi.MethodNum, ret []byte) (interface{}, error) {
+	m, found := MethodsMap[code][method]
+	if !found {
+		return nil, xerrors.Errorf("unknown method %d for actor %s", method, code)
+	}
+	return decodeCBOR(m.Ret, ret)
+}
+
+func decodeCBOR(t reflect.Type, b []byte) (interface{}, error) {
+	if t.Kind() != reflect.Ptr {
+		return nil, xerrors.Errorf("type %s can't be decoded from CBOR", t)
+	}
+	v, ok := reflect.New(t.Elem()).Interface().(cbg.CBORUnmarshaler)
+	if !ok {
+		return nil, xerrors.Errorf("type %s can't be decoded from CBOR", t)
+	}
+	if err := v.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, xerrors.Errorf("decoding %s: %w", t, err)
+	}
+	return v, nil
+}
+
 func minerHasMinPower(ctx context.Context, sm *StateManager, addr address.Address, ts *types.TipSet) (bool, error) {
 	pact, err := sm.LoadActor(ctx, power.Address, ts)
 	if err != nil {
@@ -714,3 +762,38 @@ func MakeMsgGasCost(msg *types.Message, ret *vm.ApplyRet) api.MsgGasCost {
 		TotalCost:          big.Sub(msg.RequiredFunds(), ret.GasCosts.Refund),
 	}
 }
+
+// MsgGasCostFromReceipt computes the gas cost of an executed message from its
+// receipt and the base fee it was executed with, the way the VM charged it,
+// without executing the message again. The epoch is the epoch the message was
+// executed at.
+func MsgGasCostFromReceipt(cmsg types.ChainMsg, rct *types.MessageReceipt, baseFee abi.TokenAmount, epoch abi.ChainEpoch) api.MsgGasCost {
+	msg := cmsg.VMMessage()
+
+	// messages which fail before execution (e.g. with a bad nonce) don't use
+	// any gas; the sender isn't charged and the miner pays a penalty instead
+	if rct.GasUsed == 0 {
+		penalty := big.Mul(baseFee, big.NewInt(msg.GasLimit))
+		if rct.ExitCode == exitcode.SysErrOutOfGas {
+			gas := vm.PricelistByEpoch(epoch).OnChainMessage(cmsg.ChainLength()).Total()
+			penalty = big.Mul(baseFee, big.NewInt(gas))
+		}
+
+		return api.MsgGasCost{
+			Message:            msg.Cid(),
+			GasUsed:            big.Zero(),
+			BaseFeeBurn:        big.Zero(),
+			OverEstimationBurn: big.Zero(),
+			MinerPenalty:       penalty,
+			MinerTip:           big.Zero(),
+			Refund:             big.Zero(),
+			TotalCost:          big.Zero(),
+		}
+	}
+
+	gc := vm.ComputeGasOutputs(rct.GasUsed, msg.GasLimit, baseFee, msg.GasFeeCap, msg.GasPremium)
+	return MakeMsgGasCost(msg, &vm.ApplyRet{
+		MessageReceipt: *rct,
+		GasCosts:       &gc,
+	})
+}
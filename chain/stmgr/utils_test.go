@@ -0,0 +1,205 @@
+package stmgr_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	miner2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/miner"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+)
+
+func mustIDAddr(t *testing.T, id uint64) address.Address {
+	a, err := address.NewIDAddress(id)
+	require.NoError(t, err)
+	return a
+}
+
+func TestMsgGasCostFromReceipt(t *testing.T) {
+	msg := &types.Message{
+		To:         mustIDAddr(t, 1000),
+		From:       mustIDAddr(t, 1001),
+		Value:      big.Zero(),
+		GasLimit:   1_000_000,
+		GasFeeCap:  abi.NewTokenAmount(300),
+		GasPremium: abi.NewTokenAmount(50),
+	}
+	baseFee := abi.NewTokenAmount(200)
+
+	rct := &types.MessageReceipt{ExitCode: exitcode.Ok, GasUsed: 600_000}
+	gc := stmgr.MsgGasCostFromReceipt(msg, rct, baseFee, 100)
+
+	expected := vm.ComputeGasOutputs(rct.GasUsed, msg.GasLimit, baseFee, msg.GasFeeCap, msg.GasPremium)
+	require.Equal(t, big.NewInt(600_000), gc.GasUsed)
+	require.Equal(t, expected.BaseFeeBurn, gc.BaseFeeBurn)
+	require.Equal(t, expected.OverEstimationBurn, gc.OverEstimationBurn)
+	require.Equal(t, expected.MinerTip, gc.MinerTip)
+	require.Equal(t, expected.Refund, gc.Refund)
+
+	// everything the sender locked is either burnt, paid to the miner or refunded
+	sum := big.Sum(gc.BaseFeeBurn, gc.OverEstimationBurn, gc.MinerTip, gc.Refund)
+	require.Equal(t, msg.RequiredFunds(), sum)
+	require.Equal(t, big.Sub(msg.RequiredFunds(), gc.Refund), gc.TotalCost)
+
+	// a message with a bad nonce doesn't cost the sender anything
+	rct = &types.MessageReceipt{ExitCode: exitcode.SysErrSenderStateInvalid}
+	gc = stmgr.MsgGasCostFromReceipt(msg, rct, baseFee, 100)
+	require.True(t, gc.TotalCost.IsZero())
+	require.Equal(t, big.Mul(baseFee, big.NewInt(msg.GasLimit)), gc.MinerPenalty)
+}
+
+func TestComputeStateTrace(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	mts, err := cg.NextTipSet()
+	require.NoError(t, err)
+	ts := mts.TipSet.TipSet()
+	sm := cg.StateManager()
+
+	st, _, err := sm.TipSetState(ctx, ts)
+	require.NoError(t, err)
+	banker, err := sm.LoadActorRaw(ctx, cg.Banker(), st)
+	require.NoError(t, err)
+
+	to, err := cg.Wallet().WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+
+	// sending to a new address creates its account actor in a subcall
+	msg := &types.Message{
+		From:       cg.Banker(),
+		To:         to,
+		Nonce:      banker.Nonce,
+		Value:      types.NewInt(1),
+		GasLimit:   types.TestGasLimit,
+		GasFeeCap:  types.NewInt(0),
+		GasPremium: types.NewInt(0),
+	}
+	_, trace, err := stmgr.ComputeState(ctx, sm, ts.Height()+1, []*types.Message{msg}, ts)
+	require.NoError(t, err)
+	require.NotEmpty(t, trace)
+
+	ir := trace[len(trace)-1]
+	require.Equal(t, msg.Cid(), ir.MsgCid)
+	require.Equal(t, exitcode.Ok, ir.MsgRct.ExitCode)
+
+	et := ir.ExecutionTrace
+	require.Equal(t, exitcode.Ok, et.MsgRct.ExitCode)
+	require.NotEmpty(t, et.GasCharges)
+	require.Equal(t, "OnChainMessage", et.GasCharges[0].Name)
+	require.Len(t, et.Subcalls, 1)
+	require.Equal(t, exitcode.Ok, et.Subcalls[0].MsgRct.ExitCode)
+	require.NotEmpty(t, et.Subcalls[0].GasCharges)
+}
+
+func TestComputeStateParallel(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	sm := cg.StateManager()
+
+	// two senders funded by the banker, which sends the last message
+	var senders []address.Address
+	var funding []*types.SignedMessage
+	for i := 0; i < 2; i++ {
+		a, err := cg.Wallet().WalletNew(ctx, types.KTSecp256k1)
+		require.NoError(t, err)
+		senders = append(senders, a)
+
+		m := &types.Message{
+			From:       cg.Banker(),
+			To:         a,
+			Nonce:      uint64(i),
+			Value:      types.FromFil(1),
+			GasLimit:   types.TestGasLimit,
+			GasFeeCap:  types.NewInt(1000),
+			GasPremium: types.NewInt(100),
+		}
+		sig, err := cg.Wallet().WalletSign(ctx, cg.Banker(), m.Cid().Bytes(), api.MsgMeta{})
+		require.NoError(t, err)
+		funding = append(funding, &types.SignedMessage{Message: *m, Signature: *sig})
+	}
+	senders = append(senders, cg.Banker())
+
+	cg.GetMessages = func(*gen.ChainGen) ([]*types.SignedMessage, error) {
+		out := funding
+		funding = nil
+		return out, nil
+	}
+	var ts *types.TipSet
+	for i := 0; i < 2; i++ {
+		mts, err := cg.NextTipSet()
+		require.NoError(t, err)
+		ts = mts.TipSet.TipSet()
+	}
+
+	st, _, err := sm.TipSetState(ctx, ts)
+	require.NoError(t, err)
+
+	// the first two messages are independent, the last one conflicts with
+	// the first
+	to := []address.Address{mustIDAddr(t, 1000), mustIDAddr(t, 1001), mustIDAddr(t, 1000)}
+	var msgs []*types.Message
+	for i, from := range senders {
+		act, err := sm.LoadActorRaw(ctx, from, st)
+		require.NoError(t, err)
+		msgs = append(msgs, &types.Message{
+			From:       from,
+			To:         to[i],
+			Nonce:      act.Nonce,
+			Value:      types.NewInt(1),
+			GasLimit:   types.TestGasLimit,
+			GasFeeCap:  types.NewInt(1000),
+			GasPremium: types.NewInt(100),
+		})
+	}
+
+	serial, _, err := stmgr.ComputeState(ctx, sm, ts.Height()+1, msgs, ts)
+	require.NoError(t, err)
+
+	before := vm.StatAppliedConcurrently
+	sm.SetExecutionParallelism(4)
+	parallel, trace, err := stmgr.ComputeState(ctx, sm, ts.Height()+1, msgs, ts)
+	require.NoError(t, err)
+
+	require.Equal(t, serial, parallel)
+	require.Greater(t, vm.StatAppliedConcurrently, before)
+	for _, ir := range trace[len(trace)-len(msgs):] {
+		require.Equal(t, exitcode.Ok, ir.MsgRct.ExitCode)
+	}
+}
+
+func TestDecodeParams(t *testing.T) {
+	var buf bytes.Buffer
+	params := &miner2.ChangePeerIDParams{NewID: []byte("peer")}
+	require.NoError(t, params.MarshalCBOR(&buf))
+
+	for _, code := range []cid.Cid{builtin0.StorageMinerActorCodeID, builtin2.StorageMinerActorCodeID} {
+		p, err := stmgr.DecodeParams(code, builtin2.MethodsMiner.ChangePeerID, buf.Bytes())
+		require.NoError(t, err)
+		// the params are decoded with the type of the actors version of the code
+		b, err := json.Marshal(p)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"NewID":"cGVlcg=="}`, string(b))
+	}
+
+	_, err := stmgr.DecodeParams(builtin2.StorageMinerActorCodeID, 1000, buf.Bytes())
+	require.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ExportIncremental writes a CAR file containing only the objects of the
+// snapshot at ts which are not part of the snapshot at base, an ancestor of
+// ts. Both snapshots are assumed to be exported with the same
+// inclRecentRoots and skipOldMsgs parameters. The roots of the CAR are ts.
+//
+// Importing the increment with ImportIncrement on top of the base snapshot
+// yields the same objects as importing the full snapshot at ts.
+func (cs *ChainStore) ExportIncremental(ctx context.Context, ts, base *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs bool, w io.Writer) error {
+	if base.Height() > ts.Height() {
+		return xerrors.Errorf("base tipset %s (height %d) is above the exported tipset (height %d)", base.Key(), base.Height(), ts.Height())
+	}
+
+	anc, err := cs.GetTipsetByHeight(ctx, base.Height(), ts, true)
+	if err != nil {
+		return xerrors.Errorf("loading ancestor at base height: %w", err)
+	}
+	if !anc.Equals(base) {
+		return xerrors.Errorf("base tipset %s is not an ancestor of %s", base.Key(), ts.Key())
+	}
+
+	seen := cid.NewSet()
+	walked := cid.NewSet()
+
+	log.Infow("walking base snapshot", "height", base.Height())
+	if err := cs.walkSnapshot(ctx, base, inclRecentRoots, skipOldMsgs, seen, walked, func(cid.Cid) error {
+		return ctx.Err()
+	}); err != nil {
+		return xerrors.Errorf("walking base snapshot: %w", err)
+	}
+
+	h := &car.CarHeader{
+		Roots:   ts.Cids(),
+		Version: 1,
+	}
+
+	if err := car.WriteHeader(h, w); err != nil {
+		return xerrors.Errorf("failed to write car header: %s", err)
+	}
+
+	return cs.walkSnapshot(ctx, ts, inclRecentRoots, skipOldMsgs, seen, walked, func(c cid.Cid) error {
+		blk, err := cs.bs.Get(c)
+		if err != nil {
+			return xerrors.Errorf("writing object to car, bs.Get: %w", err)
+		}
+
+		if err := carutil.LdWrite(w, c.Bytes(), blk.RawData()); err != nil {
+			return xerrors.Errorf("failed to write block to car output: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ImportIncrement loads an increment produced by ExportIncremental on top of
+// the chain data already in the store, and returns its root tipset. base must
+// be the head of the snapshot the increment was exported against.
+func (cs *ChainStore) ImportIncrement(ctx context.Context, r io.Reader, base *types.TipSet) (*types.TipSet, error) {
+	header, err := car.LoadCar(cs.Blockstore(), r)
+	if err != nil {
+		return nil, xerrors.Errorf("loadcar failed: %w", err)
+	}
+
+	root, err := cs.LoadTipSet(types.NewTipSetKey(header.Roots...))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load root tipset from increment: %w", err)
+	}
+
+	if root.Height() < base.Height() {
+		return nil, xerrors.Errorf("increment root (height %d) is below the base tipset (height %d)", root.Height(), base.Height())
+	}
+
+	anc, err := cs.GetTipsetByHeight(ctx, base.Height(), root, true)
+	if err != nil {
+		return nil, xerrors.Errorf("increment doesn't connect to the existing chain: %w", err)
+	}
+	if !anc.Equals(base) {
+		return nil, xerrors.Errorf("increment is based on %s, not on %s", anc.Key(), base.Key())
+	}
+
+	return root, nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ExportRange writes a CAR file containing the chain data of all tipsets from
+// head back to the given start epoch (inclusive). Which objects are included
+// is controlled by opts; the roots of the CAR are always the head tipset.
+func (cs *ChainStore) ExportRange(ctx context.Context, head *types.TipSet, start abi.ChainEpoch, opts api.ChainExportRangeOpts, w io.Writer) error {
+	h := &car.CarHeader{
+		Roots:   head.Cids(),
+		Version: 1,
+	}
+
+	if err := car.WriteHeader(h, w); err != nil {
+		return xerrors.Errorf("failed to write car header: %s", err)
+	}
+
+	return cs.WalkRange(ctx, head, start, opts, func(c cid.Cid) error {
+		blk, err := cs.bs.Get(c)
+		if err != nil {
+			return xerrors.Errorf("writing object to car, bs.Get: %w", err)
+		}
+
+		if err := carutil.LdWrite(w, c.Bytes(), blk.RawData()); err != nil {
+			return xerrors.Errorf("failed to write block to car output: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// WalkRange calls cb for every object selected by opts in the tipsets from
+// head back to the given start epoch (inclusive). Each object is visited once.
+func (cs *ChainStore) WalkRange(ctx context.Context, head *types.TipSet, start abi.ChainEpoch, opts api.ChainExportRangeOpts, cb func(cid.Cid) error) error {
+	walked := cid.NewSet()
+
+	visit := func(root cid.Cid) error {
+		if !walked.Visit(root) {
+			return nil
+		}
+
+		cids, err := recurseLinks(cs.bs, walked, root, []cid.Cid{root})
+		if err != nil {
+			return err
+		}
+
+		for _, c := range cids {
+			if c.Prefix().Codec != cid.DagCBOR {
+				continue
+			}
+			if err := cb(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	log.Infow("range export started", "head", head.Height(), "start", start)
+	exportStart := build.Clock.Now()
+
+	for ts := head; ts.Height() >= start; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for _, b := range ts.Blocks() {
+			if opts.Headers && walked.Visit(b.Cid()) {
+				if err := cb(b.Cid()); err != nil {
+					return err
+				}
+			}
+
+			if opts.Messages {
+				if err := visit(b.Messages); err != nil {
+					return xerrors.Errorf("recursing messages of block %s: %w", b.Cid(), err)
+				}
+			}
+
+			if opts.Receipts {
+				if err := visit(b.ParentMessageReceipts); err != nil {
+					return xerrors.Errorf("recursing receipts of block %s: %w", b.Cid(), err)
+				}
+			}
+
+			if opts.StateRoots {
+				if err := visit(b.ParentStateRoot); err != nil {
+					return xerrors.Errorf("recursing state of block %s: %w", b.Cid(), err)
+				}
+			}
+		}
+
+		if ts.Height()%builtin.EpochsInDay == 0 {
+			log.Infow("range export", "height", ts.Height())
+		}
+
+		if ts.Height() == 0 {
+			break
+		}
+
+		parent, err := cs.LoadTipSet(ts.Parents())
+		if err != nil {
+			return xerrors.Errorf("loading parent tipset %s: %w", ts.Parents(), err)
+		}
+		ts = parent
+	}
+
+	log.Infow("range export finished", "duration", build.Clock.Now().Sub(exportStart).Seconds())
+
+	return nil
+}
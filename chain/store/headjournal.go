@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	dstore "github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var headChangesPrefix = dstore.NewKey("headchanges")
+
+const headJournalTopic = "headjournal"
+
+// HeadJournalSize is the number of head changes kept in the head change
+// journal; subscribers can resume from any of them.
+const HeadJournalSize = 10000
+
+// headJournalMaxApply bounds the number of applied tipsets recorded for a
+// single head change, e.g. the whole chain after a snapshot import.
+const headJournalMaxApply = 2000
+
+func headChangeKey(seq uint64) dstore.Key {
+	return headChangesPrefix.ChildString(fmt.Sprintf("%016x", seq))
+}
+
+type headChangeRecord struct {
+	Seq       uint64
+	Revert    []types.TipSetKey
+	Apply     []types.TipSetKey
+	Truncated bool
+}
+
+// journalHeadChange is a ReorgNotifee which persists every head change to
+// the head change journal and publishes it to journal subscribers.
+func (cs *ChainStore) journalHeadChange(rev, app []*types.TipSet) error {
+	hc := &api.HeadChanges{}
+	rec := headChangeRecord{}
+
+	if len(app) > headJournalMaxApply {
+		log.Warnf("only journaling the latest %d of %d applied tipsets", headJournalMaxApply, len(app))
+		rec.Truncated = true
+	}
+
+	for _, ts := range rev {
+		hc.Changes = append(hc.Changes, &api.HeadChange{Type: HCRevert, Val: ts})
+		rec.Revert = append(rec.Revert, ts.Key())
+	}
+	for i, ts := range app {
+		hc.Changes = append(hc.Changes, &api.HeadChange{Type: HCApply, Val: ts})
+		if i >= len(app)-headJournalMaxApply {
+			rec.Apply = append(rec.Apply, ts.Key())
+		}
+	}
+
+	cs.pubLk.Lock()
+	defer cs.pubLk.Unlock()
+
+	rec.Seq = cs.headSeq + 1
+	b, err := json.Marshal(&rec)
+	if err != nil {
+		return xerrors.Errorf("marshaling head change: %w", err)
+	}
+	if err := cs.ds.Put(headChangeKey(rec.Seq), b); err != nil {
+		return xerrors.Errorf("writing head change to journal: %w", err)
+	}
+	cs.headSeq = rec.Seq
+
+	if rec.Seq > HeadJournalSize {
+		if err := cs.ds.Delete(headChangeKey(rec.Seq - HeadJournalSize)); err != nil {
+			log.Warnf("pruning head change journal: %s", err)
+		}
+	}
+
+	hc.Cursor = rec.Seq
+	cs.bestTips.Pub(hc, headJournalTopic)
+	return nil
+}
+
+// SubHeadChangesFrom returns a channel of the head changes recorded in the
+// head change journal. With a zero cursor it starts with the current head,
+// like SubHeadChanges; otherwise it first replays every head change recorded
+// after the cursor, so that a subscriber which stopped can resume without
+// missing reverts.
+func (cs *ChainStore) SubHeadChangesFrom(ctx context.Context, cursor uint64) (<-chan *api.HeadChanges, error) {
+	return cs.subHeadJournal(ctx, func(last uint64, head *types.TipSet) ([]*api.HeadChanges, error) {
+		if cursor == 0 {
+			return currentHead(last, head), nil
+		}
+		return cs.readHeadChanges(cursor, last)
+	})
+}
+
+// SubHeadChangesSince is like SubHeadChangesFrom, but resumes from the last
+// tipset a subscriber processed instead of a journal cursor: the first entry
+// reverts and applies the tipsets between that tipset and the current head,
+// or is the current head when they're the same. Tipsets more than finality
+// behind the head aren't resumed from. With an empty key it starts with the
+// current head.
+func (cs *ChainStore) SubHeadChangesSince(ctx context.Context, tsk types.TipSetKey) (<-chan *api.HeadChanges, error) {
+	var from *types.TipSet
+	if !tsk.IsEmpty() {
+		var err error
+		from, err = cs.LoadTipSet(tsk)
+		if err != nil {
+			return nil, xerrors.Errorf("loading last seen tipset: %w", err)
+		}
+	}
+
+	return cs.subHeadJournal(ctx, func(last uint64, head *types.TipSet) ([]*api.HeadChanges, error) {
+		if from == nil || from.Equals(head) {
+			return currentHead(last, head), nil
+		}
+
+		if behind := head.Height() - from.Height(); behind > build.Finality {
+			return nil, xerrors.Errorf("tipset %s is %d epochs behind the head, more than finality, resync from the current head", from.Key(), behind)
+		}
+
+		revert, apply, err := cs.ReorgOps(from, head)
+		if err != nil {
+			return nil, xerrors.Errorf("computing the path to the head: %w", err)
+		}
+		if len(revert) > int(build.Finality) {
+			return nil, xerrors.Errorf("tipset %s was reverted %d tipsets deep, more than finality, resync from the current head", from.Key(), len(revert))
+		}
+
+		hc := &api.HeadChanges{Cursor: last}
+		for _, ts := range revert {
+			hc.Changes = append(hc.Changes, &api.HeadChange{Type: HCRevert, Val: ts})
+		}
+		// ReorgOps returns the applied tipsets newest first
+		for i := len(apply) - 1; i >= 0; i-- {
+			hc.Changes = append(hc.Changes, &api.HeadChange{Type: HCApply, Val: apply[i]})
+		}
+		return []*api.HeadChanges{hc}, nil
+	})
+}
+
+func currentHead(last uint64, head *types.TipSet) []*api.HeadChanges {
+	return []*api.HeadChanges{{
+		Cursor:  last,
+		Changes: []*api.HeadChange{{Type: HCCurrent, Val: head}},
+	}}
+}
+
+// subHeadJournal subscribes to the head change journal, sending the backlog
+// first. The backlog is computed from the last journal entry and the head at
+// the time of the subscription, so that no head change is missed or sent
+// twice.
+func (cs *ChainStore) subHeadJournal(ctx context.Context, backlogFn func(last uint64, head *types.TipSet) ([]*api.HeadChanges, error)) (<-chan *api.HeadChanges, error) {
+	cs.pubLk.Lock()
+	subch := cs.bestTips.Sub(headJournalTopic)
+	last := cs.headSeq
+	head := cs.GetHeaviestTipSet()
+	cs.pubLk.Unlock()
+
+	backlog, err := backlogFn(last, head)
+	if err != nil {
+		go cs.bestTips.Unsub(subch)
+		return nil, err
+	}
+
+	out := make(chan *api.HeadChanges, 16)
+
+	go func() {
+		defer close(out)
+		var unsubOnce sync.Once
+		unsub := func() {
+			unsubOnce.Do(func() {
+				go cs.bestTips.Unsub(subch)
+			})
+		}
+
+		for _, hc := range backlog {
+			select {
+			case out <- hc:
+			case <-ctx.Done():
+				unsub()
+				return
+			}
+		}
+
+		for {
+			select {
+			case val, ok := <-subch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- val.(*api.HeadChanges):
+				case <-ctx.Done():
+				}
+			case <-ctx.Done():
+				unsub()
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// readHeadChanges loads the journaled head changes after cursor, up to and
+// including last.
+func (cs *ChainStore) readHeadChanges(cursor, last uint64) ([]*api.HeadChanges, error) {
+	if cursor > last {
+		return nil, xerrors.Errorf("cursor %d is ahead of the head change journal (at %d)", cursor, last)
+	}
+	if cursor == last {
+		return nil, nil
+	}
+
+	var out []*api.HeadChanges
+	for seq := cursor + 1; seq <= last; seq++ {
+		b, err := cs.ds.Get(headChangeKey(seq))
+		if err == dstore.ErrNotFound {
+			return nil, xerrors.Errorf("head change %d is no longer in the journal, resync from the current head", seq)
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("reading head change %d: %w", seq, err)
+		}
+
+		var rec headChangeRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, xerrors.Errorf("decoding head change %d: %w", seq, err)
+		}
+
+		hc := &api.HeadChanges{Cursor: rec.Seq, Truncated: rec.Truncated}
+		for _, changes := range []struct {
+			typ  string
+			keys []types.TipSetKey
+		}{{HCRevert, rec.Revert}, {HCApply, rec.Apply}} {
+			for _, k := range changes.keys {
+				ts, err := cs.LoadTipSet(k)
+				if err != nil {
+					return nil, xerrors.Errorf("loading tipset of head change %d: %w", seq, err)
+				}
+				hc.Changes = append(hc.Changes, &api.HeadChange{Type: changes.typ, Val: ts})
+			}
+		}
+
+		out = append(out, hc)
+	}
+
+	return out, nil
+}
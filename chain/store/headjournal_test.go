@@ -0,0 +1,143 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestHeadChangeJournal(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := cg.ChainStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := cs.SubHeadChangesFrom(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(ch <-chan *api.HeadChanges) *api.HeadChanges {
+		select {
+		case hc := <-ch:
+			return hc
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for head change")
+			return nil
+		}
+	}
+
+	cur := next(sub)
+	if len(cur.Changes) != 1 || cur.Changes[0].Type != store.HCCurrent {
+		t.Fatalf("expected the current head first, got %+v", cur.Changes)
+	}
+
+	var live []*api.HeadChanges
+	for i := 0; i < 5; i++ {
+		mts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cs.SetHead(mts.TipSet.TipSet()); err != nil {
+			t.Fatal(err)
+		}
+
+		hc := next(sub)
+		if hc.Cursor <= cur.Cursor {
+			t.Fatalf("cursor didn't advance: %d after %d", hc.Cursor, cur.Cursor)
+		}
+		last := hc.Changes[len(hc.Changes)-1]
+		if last.Type != store.HCApply || !last.Val.Equals(mts.TipSet.TipSet()) {
+			t.Fatalf("expected apply of the new tipset, got %+v", hc.Changes)
+		}
+		live = append(live, hc)
+	}
+
+	// resuming replays everything after the cursor
+	rctx, rcancel := context.WithCancel(ctx)
+	replay, err := cs.SubHeadChangesFrom(rctx, live[1].Cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range live[2:] {
+		hc := next(replay)
+		if hc.Cursor != exp.Cursor || len(hc.Changes) != len(exp.Changes) {
+			t.Fatalf("replayed %d (%d changes), expected %d (%d changes)", hc.Cursor, len(hc.Changes), exp.Cursor, len(exp.Changes))
+		}
+		for i := range hc.Changes {
+			if hc.Changes[i].Type != exp.Changes[i].Type || !hc.Changes[i].Val.Equals(exp.Changes[i].Val) {
+				t.Fatalf("replayed change %d of %d doesn't match", i, hc.Cursor)
+			}
+		}
+	}
+	rcancel()
+
+	if _, err := cs.SubHeadChangesFrom(ctx, live[len(live)-1].Cursor+100); err == nil {
+		t.Fatal("expected a cursor ahead of the journal to fail")
+	}
+}
+
+func TestHeadChangesSince(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := cg.ChainStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var tss []*types.TipSet
+	for i := 0; i < 4; i++ {
+		mts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tss = append(tss, mts.TipSet.TipSet())
+	}
+	if err := cs.SetHead(tss[3]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tipsets after the last seen one are applied, oldest first
+	sub, err := cs.SubHeadChangesSince(ctx, tss[1].Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case hc := <-sub:
+		if len(hc.Changes) != 2 {
+			t.Fatalf("expected 2 changes, got %d", len(hc.Changes))
+		}
+		for i, c := range hc.Changes {
+			if c.Type != store.HCApply || !c.Val.Equals(tss[2+i]) {
+				t.Fatalf("expected apply of tipset %d, got %s of %s", 2+i, c.Type, c.Val.Key())
+			}
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for head change")
+	}
+
+	// a subscriber at the head gets it as current
+	sub, err = cs.SubHeadChangesSince(ctx, tss[3].Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case hc := <-sub:
+		if len(hc.Changes) != 1 || hc.Changes[0].Type != store.HCCurrent {
+			t.Fatalf("expected the current head, got %+v", hc.Changes)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for head change")
+	}
+}
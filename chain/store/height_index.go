@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	dstore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var (
+	heightIndexPrefix  = dstore.NewKey("/heightidx")
+	heightIndexEntries = heightIndexPrefix.ChildString("e")
+	heightIndexTipKey  = heightIndexPrefix.ChildString("tip")
+)
+
+const heightIndexBatchSize = 4096
+
+func heightIndexKey(h abi.ChainEpoch) dstore.Key {
+	return heightIndexEntries.ChildString(strconv.FormatInt(int64(h), 10))
+}
+
+// heightIndex is a persistent index from height to the tipset at that height
+// on the indexed chain. Like the ChainIndex, null rounds map to the first
+// tipset above them.
+//
+// Only entries up to the tip height are trusted. Before the entries above the
+// fork point of a new chain are overwritten, the tip is lowered to the fork
+// point, and it is raised to the new head once all of them are written, so
+// the index stays consistent if the node stops halfway.
+type heightIndex struct {
+	ds     dstore.Batching
+	loadTs loadTipSetFunc
+
+	lk  sync.RWMutex
+	tip abi.ChainEpoch // -1 if nothing is indexed
+
+	updateLk sync.Mutex
+	next     chan *types.TipSet
+}
+
+func newHeightIndex(ds dstore.Batching, lts loadTipSetFunc) *heightIndex {
+	hi := &heightIndex{
+		ds:     ds,
+		loadTs: lts,
+		tip:    -1,
+		next:   make(chan *types.TipSet, 1),
+	}
+
+	b, err := ds.Get(heightIndexTipKey)
+	switch {
+	case err == nil:
+		tip, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			log.Errorf("bad height index tip %q, reindexing: %s", string(b), err)
+			break
+		}
+		hi.tip = abi.ChainEpoch(tip)
+	case err != dstore.ErrNotFound:
+		log.Errorf("loading height index tip: %s", err)
+	}
+
+	return hi
+}
+
+// run indexes the chains passed to notify until the context is cancelled.
+func (hi *heightIndex) run(ctx context.Context) {
+	for {
+		select {
+		case ts := <-hi.next:
+			if err := hi.update(ctx, ts); err != nil {
+				log.Errorf("updating height index: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// notify schedules indexing of the chain of ts. Only the latest tipset
+// passed before the indexer gets to it is indexed.
+func (hi *heightIndex) notify(ts *types.TipSet) {
+	for {
+		select {
+		case hi.next <- ts:
+			return
+		default:
+		}
+
+		select {
+		case <-hi.next:
+		default:
+		}
+	}
+}
+
+// lookup returns the key of the first tipset at or above height h on the
+// chain of ts, if that part of the chain is indexed.
+func (hi *heightIndex) lookup(ts *types.TipSet, h abi.ChainEpoch) (types.TipSetKey, bool, error) {
+	hi.lk.RLock()
+	defer hi.lk.RUnlock()
+
+	if h < 0 || ts.Height() > hi.tip {
+		return types.EmptyTSK, false, nil
+	}
+
+	k, ok, err := hi.get(ts.Height())
+	if err != nil || !ok || k != ts.Key() {
+		return types.EmptyTSK, false, err
+	}
+
+	return hi.get(h)
+}
+
+func (hi *heightIndex) get(h abi.ChainEpoch) (types.TipSetKey, bool, error) {
+	b, err := hi.ds.Get(heightIndexKey(h))
+	if err == dstore.ErrNotFound {
+		return types.EmptyTSK, false, nil
+	}
+	if err != nil {
+		return types.EmptyTSK, false, xerrors.Errorf("reading height index at %d: %w", h, err)
+	}
+
+	k, err := types.TipSetKeyFromBytes(b)
+	if err != nil {
+		return types.EmptyTSK, false, xerrors.Errorf("decoding height index at %d: %w", h, err)
+	}
+	return k, true, nil
+}
+
+func (hi *heightIndex) setTip(h abi.ChainEpoch) error {
+	hi.lk.Lock()
+	defer hi.lk.Unlock()
+
+	if err := hi.ds.Put(heightIndexTipKey, []byte(strconv.FormatInt(int64(h), 10))); err != nil {
+		return xerrors.Errorf("writing height index tip: %w", err)
+	}
+	hi.tip = h
+	return nil
+}
+
+// update indexes the chain of head down to the highest tipset which is
+// already indexed.
+func (hi *heightIndex) update(ctx context.Context, head *types.TipSet) error {
+	hi.updateLk.Lock()
+	defer hi.updateLk.Unlock()
+
+	return hi.index(ctx, head, nil)
+}
+
+// rebuild drops the index and indexes the chain of head down to genesis,
+// calling progress with the height of each indexed tipset.
+func (hi *heightIndex) rebuild(ctx context.Context, head *types.TipSet, progress func(abi.ChainEpoch)) error {
+	hi.updateLk.Lock()
+	defer hi.updateLk.Unlock()
+
+	if err := hi.setTip(-1); err != nil {
+		return err
+	}
+
+	res, err := hi.ds.Query(query.Query{Prefix: heightIndexEntries.String(), KeysOnly: true})
+	if err != nil {
+		return xerrors.Errorf("querying height index: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	for e := range res.Next() {
+		if e.Error != nil {
+			return xerrors.Errorf("reading height index: %w", e.Error)
+		}
+		if err := hi.ds.Delete(dstore.NewKey(e.Key)); err != nil {
+			return xerrors.Errorf("deleting height index entry: %w", err)
+		}
+	}
+
+	return hi.index(ctx, head, progress)
+}
+
+func (hi *heightIndex) index(ctx context.Context, head *types.TipSet, progress func(abi.ChainEpoch)) error {
+	// find the highest tipset of the chain which is already indexed
+	fork := abi.ChainEpoch(-1)
+	for cur := head; ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		k, ok, err := hi.lookup(cur, cur.Height())
+		if err != nil {
+			return err
+		}
+		if ok && k == cur.Key() {
+			fork = cur.Height()
+			break
+		}
+
+		if cur.Height() == 0 {
+			break
+		}
+		if cur, err = hi.loadTs(cur.Parents()); err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
+
+	if fork == head.Height() {
+		return nil
+	}
+	if fork < 0 {
+		log.Infow("building height index", "height", head.Height())
+	}
+
+	if err := hi.setTip(fork); err != nil {
+		return err
+	}
+
+	b, err := hi.ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	var pending int
+	for cur := head; cur.Height() > fork; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var parent *types.TipSet
+		low := abi.ChainEpoch(0)
+		if cur.Height() > 0 {
+			parent, err = hi.loadTs(cur.Parents())
+			if err != nil {
+				return xerrors.Errorf("loading parent tipset: %w", err)
+			}
+			low = parent.Height() + 1
+		}
+
+		for h := low; h <= cur.Height(); h++ {
+			if err := b.Put(heightIndexKey(h), cur.Key().Bytes()); err != nil {
+				return xerrors.Errorf("writing height index: %w", err)
+			}
+			pending++
+		}
+
+		if pending >= heightIndexBatchSize {
+			if err := b.Commit(); err != nil {
+				return xerrors.Errorf("committing height index: %w", err)
+			}
+			if b, err = hi.ds.Batch(); err != nil {
+				return err
+			}
+			pending = 0
+		}
+
+		if progress != nil {
+			progress(cur.Height())
+		}
+
+		if parent == nil {
+			break
+		}
+		cur = parent
+	}
+
+	if err := b.Commit(); err != nil {
+		return xerrors.Errorf("committing height index: %w", err)
+	}
+
+	return hi.setTip(head.Height())
+}
+
+// indexHeights is a ReorgNotifee which keeps the height index following the
+// head.
+func (cs *ChainStore) indexHeights(rev, app []*types.TipSet) error {
+	if len(app) > 0 {
+		cs.heights.notify(app[len(app)-1])
+	}
+	return nil
+}
+
+// ReindexHeights rebuilds the height index for the chain of ts, calling
+// progress with the height of each indexed tipset.
+func (cs *ChainStore) ReindexHeights(ctx context.Context, ts *types.TipSet, progress func(abi.ChainEpoch)) error {
+	return cs.heights.rebuild(ctx, ts, progress)
+}
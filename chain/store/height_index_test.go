@@ -0,0 +1,97 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func TestHeightIndex(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+
+	gencar, err := cg.GenesisCar()
+	require.NoError(t, err)
+
+	ctx := context.TODO()
+
+	nbs := blockstore.NewTemporarySync()
+	ds := syncds.MutexWrap(datastore.NewMapDatastore())
+	cs := store.NewChainStore(nbs, ds, nil, nil)
+
+	_, err = cs.Import(bytes.NewReader(gencar))
+	require.NoError(t, err)
+
+	genTs := mock.TipSet(cg.Genesis())
+	require.NoError(t, cs.PutTipSet(ctx, genTs))
+	require.NoError(t, cs.SetGenesis(cg.Genesis()))
+
+	mkChain := func(from *types.TipSet, n int, nonce uint64) []*types.TipSet {
+		out := []*types.TipSet{from}
+		cur := from
+		for i := 0; i < n; i++ {
+			blk := mock.MkBlock(cur, 1, nonce)
+			if i%10 == 5 {
+				// leave a few null rounds
+				blk.Height += 3
+			}
+			next := mock.TipSet(blk)
+			require.NoError(t, cs.PutTipSet(ctx, next))
+			out = append(out, next)
+			cur = next
+		}
+		return out
+	}
+
+	main := mkChain(genTs, 100, 1)
+	head := main[len(main)-1]
+
+	// a fork off the main chain, which isn't indexed
+	fork := mkChain(main[40], 20, 2)
+
+	require.NoError(t, cs.ReindexHeights(ctx, head, nil))
+
+	check := func(cs *store.ChainStore, chain []*types.TipSet) {
+		tip := chain[len(chain)-1]
+		for h := abi.ChainEpoch(0); h <= tip.Height(); h++ {
+			// expect the first tipset at or above h, or the last one below it
+			// for prev lookups
+			var above, below *types.TipSet
+			for _, ts := range chain {
+				if ts.Height() <= h {
+					below = ts
+				}
+				if ts.Height() >= h && above == nil {
+					above = ts
+				}
+			}
+
+			ts, err := cs.GetTipsetByHeight(ctx, h, tip, false)
+			require.NoError(t, err)
+			require.True(t, above.Equals(ts), "height %d", h)
+
+			ts, err = cs.GetTipsetByHeight(ctx, h, tip, true)
+			require.NoError(t, err)
+			require.True(t, below.Equals(ts), "prev height %d", h)
+		}
+	}
+
+	check(cs, main)
+	check(cs, append(main[:41:41], fork[1:]...))
+
+	// the index survives a restart
+	cs2 := store.NewChainStore(nbs, ds, nil, nil)
+	check(cs2, main)
+}
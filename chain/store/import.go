@@ -0,0 +1,203 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	dstore "github.com/ipfs/go-datastore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var importCheckpointKey = dstore.NewKey("/import/checkpoint")
+
+const defaultImportBatchSize = 4096
+
+// ImportCheckpoint records how much of a CAR stream has been durably imported.
+type ImportCheckpoint struct {
+	// Source identifies the stream being imported, e.g. its path or URL
+	Source string
+	// Roots are the roots from the CAR header
+	Roots []cid.Cid
+	// Offset is the number of bytes of the stream, including the header,
+	// whose blocks have all been written to the blockstore
+	Offset uint64
+	// Blocks is the number of blocks imported so far
+	Blocks uint64
+}
+
+type ImportOpts struct {
+	// Source is recorded in checkpoints, so that an interrupted import can
+	// only be resumed from the same stream
+	Source string
+	// ExpectedRoots, if set, must match the roots in the CAR header
+	ExpectedRoots []cid.Cid
+	// VerifyBlocks checks that every block hashes to its CID
+	VerifyBlocks bool
+	// Resume continues the import from a checkpoint. The reader must be
+	// positioned at the checkpoint's offset.
+	Resume *ImportCheckpoint
+	// Progress, if set, is called after each batch of blocks is written
+	Progress func(ImportCheckpoint)
+	// BatchSize is the number of blocks written between checkpoints
+	BatchSize int
+}
+
+// GetImportCheckpoint returns the checkpoint of an interrupted import of the
+// given source, or nil if there is none.
+func (cs *ChainStore) GetImportCheckpoint(source string) (*ImportCheckpoint, error) {
+	b, err := cs.ds.Get(importCheckpointKey)
+	if err == dstore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("reading import checkpoint: %w", err)
+	}
+
+	var cp ImportCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, xerrors.Errorf("decoding import checkpoint: %w", err)
+	}
+	if cp.Source != source {
+		return nil, nil
+	}
+
+	return &cp, nil
+}
+
+// ImportStream loads a CAR file from r in batches, checkpointing its progress
+// in the metadata datastore, and returns the root tipset. Unlike Import, an
+// interrupted import can be continued with opts.Resume.
+func (cs *ChainStore) ImportStream(ctx context.Context, r io.Reader, opts ImportOpts) (*types.TipSet, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+
+	cp := ImportCheckpoint{Source: opts.Source}
+	if opts.Resume != nil {
+		cp = *opts.Resume
+		if cp.Source != opts.Source {
+			return nil, xerrors.Errorf("checkpoint is for %q, not %q", cp.Source, opts.Source)
+		}
+	} else {
+		hb, size, err := carutil.LdRead(br)
+		if err != nil {
+			return nil, xerrors.Errorf("reading car header: %w", err)
+		}
+
+		var h car.CarHeader
+		if err := cbor.DecodeInto(hb, &h); err != nil {
+			return nil, xerrors.Errorf("decoding car header: %w", err)
+		}
+		if h.Version != 1 {
+			return nil, xerrors.Errorf("unsupported car version %d", h.Version)
+		}
+
+		cp.Roots = h.Roots
+		cp.Offset = size
+	}
+
+	if len(opts.ExpectedRoots) > 0 && types.NewTipSetKey(opts.ExpectedRoots...) != types.NewTipSetKey(cp.Roots...) {
+		return nil, xerrors.Errorf("snapshot roots %s don't match the expected roots %s", cp.Roots, opts.ExpectedRoots)
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	batch := make([]blocks.Block, 0, batchSize)
+	var pending uint64
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := cs.bs.PutMany(batch); err != nil {
+			return xerrors.Errorf("writing blocks: %w", err)
+		}
+
+		cp.Offset += pending
+		cp.Blocks += uint64(len(batch))
+		batch, pending = batch[:0], 0
+
+		b, err := json.Marshal(&cp)
+		if err != nil {
+			return err
+		}
+		if err := cs.ds.Put(importCheckpointKey, b); err != nil {
+			return xerrors.Errorf("writing import checkpoint: %w", err)
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(cp)
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		data, size, err := carutil.LdRead(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("reading block at offset %d: %w", cp.Offset+pending, err)
+		}
+
+		n, c, err := cid.CidFromBytes(data)
+		if err != nil {
+			return nil, xerrors.Errorf("reading cid at offset %d: %w", cp.Offset+pending, err)
+		}
+
+		if opts.VerifyBlocks {
+			hc, err := c.Prefix().Sum(data[n:])
+			if err != nil {
+				return nil, err
+			}
+			if !hc.Equals(c) {
+				return nil, xerrors.Errorf("block %s at offset %d doesn't match its cid", c, cp.Offset+pending)
+			}
+		}
+
+		blk, err := blocks.NewBlockWithCid(data[n:], c)
+		if err != nil {
+			return nil, err
+		}
+
+		batch = append(batch, blk)
+		pending += size
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	root, err := cs.LoadTipSet(types.NewTipSetKey(cp.Roots...))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load root tipset from chainfile: %w", err)
+	}
+
+	if err := cs.ds.Delete(importCheckpointKey); err != nil {
+		return nil, xerrors.Errorf("clearing import checkpoint: %w", err)
+	}
+
+	cs.heights.notify(root)
+
+	return root, nil
+}
@@ -0,0 +1,405 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	dstore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var msgIndexPrefix = dstore.NewKey("/msgindex")
+
+var msgIndexBackfillKey = dstore.NewKey("/msgindex-backfill")
+
+// addrIndexPrefix prefixes the entries of the messages sent and received by
+// each address, keyed by the epoch and index of their receipt so that the
+// entries of a message executed again after a reorg don't collide.
+var addrIndexPrefix = dstore.NewKey("/addrmsgs")
+
+// msgIndexBatchTipSets bounds the number of tipsets indexed in one batch, so
+// that backfill progress is checkpointed regularly in ranges with few
+// messages.
+const msgIndexBatchTipSets = 500
+
+// msgIndexMaxApply bounds the number of applied tipsets queued for indexing,
+// e.g. the whole chain after importing a snapshot. Older messages can be
+// indexed with IndexMessages.
+const msgIndexMaxApply = 200
+
+// MsgInfo records where a message was executed.
+type MsgInfo struct {
+	// Message is the CID of the message
+	Message cid.Cid
+	// TipSet is the tipset holding the receipt of the message, executed on
+	// top of the tipset which included it
+	TipSet types.TipSetKey
+	// Epoch is the height of TipSet
+	Epoch abi.ChainEpoch
+	// Index is the position of the receipt in the parent receipts of TipSet
+	Index int
+}
+
+func msgIndexKey(c cid.Cid) dstore.Key {
+	return msgIndexPrefix.ChildString(c.String())
+}
+
+func addrIndexKey(a address.Address, epoch abi.ChainEpoch, i int) dstore.Key {
+	return addrIndexPrefix.ChildString(a.String()).ChildString(fmt.Sprintf("%016x-%08x", epoch, i))
+}
+
+// GetMsgInfo returns where the message was last recorded to be executed. The
+// entry isn't updated when that tipset is reverted, so callers must check
+// that it's still on their chain.
+func (cs *ChainStore) GetMsgInfo(c cid.Cid) (*MsgInfo, error) {
+	b, err := cs.ds.Get(msgIndexKey(c))
+	if err == dstore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("reading message index: %w", err)
+	}
+
+	var mi MsgInfo
+	if err := json.Unmarshal(b, &mi); err != nil {
+		return nil, xerrors.Errorf("decoding message index entry: %w", err)
+	}
+	return &mi, nil
+}
+
+// indexTipSetMessages records the messages executed in ts, which are the
+// messages included in its parent.
+func (cs *ChainStore) indexTipSetMessages(b dstore.Batch, ts *types.TipSet) (int, error) {
+	// the genesis block didn't execute any messages
+	if ts.Height() == 0 {
+		return 0, nil
+	}
+
+	pts, err := cs.LoadTipSet(ts.Parents())
+	if err != nil {
+		return 0, xerrors.Errorf("loading parent tipset: %w", err)
+	}
+
+	msgs, err := cs.MessagesForTipset(pts)
+	if err != nil {
+		return 0, xerrors.Errorf("loading messages: %w", err)
+	}
+
+	for i, m := range msgs {
+		v, err := json.Marshal(&MsgInfo{
+			Message: m.Cid(),
+			TipSet:  ts.Key(),
+			Epoch:   ts.Height(),
+			Index:   i,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if err := b.Put(msgIndexKey(m.Cid()), v); err != nil {
+			return 0, xerrors.Errorf("writing message index: %w", err)
+		}
+
+		vm := m.VMMessage()
+		for _, a := range []address.Address{vm.From, vm.To} {
+			if err := b.Put(addrIndexKey(a, ts.Height(), i), v); err != nil {
+				return 0, xerrors.Errorf("writing address message index: %w", err)
+			}
+		}
+	}
+
+	return len(msgs), nil
+}
+
+// AddressMessages returns where at most limit messages sent or received by
+// the address were executed on the chain of ts, newest first, or all of them
+// when limit is 0. Addresses are matched as they are set in the messages, so
+// callers should look up both the ID and the key address of an actor.
+func (cs *ChainStore) AddressMessages(ctx context.Context, a address.Address, ts *types.TipSet, limit int) ([]MsgInfo, error) {
+	// the entries are keyed by epoch, newest last
+	res, err := cs.ds.Query(query.Query{
+		Prefix: addrIndexPrefix.ChildString(a.String()).String(),
+		Orders: []query.Order{query.OrderByKeyDescending{}},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("querying address message index: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	out := []MsgInfo{}
+	onChain := map[abi.ChainEpoch]types.TipSetKey{}
+	for limit == 0 || len(out) < limit {
+		e, ok := res.NextSync()
+		if !ok {
+			break
+		}
+		if e.Error != nil {
+			return nil, xerrors.Errorf("reading address message index: %w", e.Error)
+		}
+
+		var mi MsgInfo
+		if err := json.Unmarshal(e.Value, &mi); err != nil {
+			return nil, xerrors.Errorf("decoding address message index entry: %w", err)
+		}
+		if mi.Epoch > ts.Height() {
+			continue
+		}
+
+		// drop the entries of reverted tipsets
+		tsk, ok := onChain[mi.Epoch]
+		if !ok {
+			ets, err := cs.GetTipsetByHeight(ctx, mi.Epoch, ts, false)
+			if err != nil {
+				return nil, xerrors.Errorf("loading tipset at indexed epoch: %w", err)
+			}
+			tsk = ets.Key()
+			onChain[mi.Epoch] = tsk
+		}
+		if tsk == mi.TipSet {
+			out = append(out, mi)
+		}
+	}
+
+	return out, nil
+}
+
+// StartMessageIndex records the messages executed in the tipsets applied
+// from now on, until ctx is cancelled. Indexing runs apart from the reorg
+// worker, so that it doesn't hold up head change notifications; while it's
+// behind, the latest msgIndexMaxApply applied tipsets are queued. Messages of
+// reverted tipsets are re-recorded when they're executed again on the new
+// chain.
+func (cs *ChainStore) StartMessageIndex(ctx context.Context) {
+	var lk sync.Mutex
+	var pending []*types.TipSet
+	wake := make(chan struct{}, 1)
+
+	cs.SubscribeHeadChanges(func(rev, app []*types.TipSet) error {
+		lk.Lock()
+		pending = append(pending, app...)
+		if len(pending) > msgIndexMaxApply {
+			log.Warnf("only indexing the messages of the latest %d of %d applied tipsets; use 'lotus index messages' to index the rest", msgIndexMaxApply, len(pending))
+			pending = pending[len(pending)-msgIndexMaxApply:]
+		}
+		lk.Unlock()
+
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	go func() {
+		for {
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+
+			lk.Lock()
+			app := pending
+			pending = nil
+			lk.Unlock()
+
+			if err := cs.indexMessages(app); err != nil {
+				log.Errorf("indexing messages: %s", err)
+			}
+		}
+	}()
+}
+
+func (cs *ChainStore) indexMessages(app []*types.TipSet) error {
+	b, err := cs.ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	for _, ts := range app {
+		if _, err := cs.indexTipSetMessages(b, ts); err != nil {
+			return xerrors.Errorf("indexing messages of %s: %w", ts.Key(), err)
+		}
+	}
+
+	return b.Commit()
+}
+
+// IndexMessages records the messages executed in the tipsets between the
+// from and to epochs (inclusive) on the chain of ts, and returns the number
+// of messages indexed.
+func (cs *ChainStore) IndexMessages(ctx context.Context, ts *types.TipSet, from, to abi.ChainEpoch) (int, error) {
+	if to > ts.Height() {
+		return 0, xerrors.Errorf("end epoch %d is above the tipset (height %d)", to, ts.Height())
+	}
+	if from > to {
+		return 0, xerrors.Errorf("start epoch %d is above the end epoch %d", from, to)
+	}
+
+	var total int
+	err := cs.indexRange(ctx, ts, from, to, nil, func(_ dstore.Batch, _ abi.ChainEpoch, _, msgs int) error {
+		total += msgs
+		return nil
+	})
+	return total, err
+}
+
+// BackfillCheckpoint is the progress of a message index backfill. Backfills
+// index the range from the top down.
+type BackfillCheckpoint struct {
+	From abi.ChainEpoch
+	To   abi.ChainEpoch
+	// Next is the highest epoch of the range which isn't indexed yet
+	Next abi.ChainEpoch
+}
+
+// BackfillProgress is reported each time a backfill commits what it indexed.
+type BackfillProgress struct {
+	Checkpoint BackfillCheckpoint
+	// TipSets and Messages are the numbers indexed in this run
+	TipSets  int
+	Messages int
+}
+
+// LoadBackfillCheckpoint returns the checkpoint of the last interrupted
+// backfill, or nil if there is none.
+func (cs *ChainStore) LoadBackfillCheckpoint() (*BackfillCheckpoint, error) {
+	b, err := cs.ds.Get(msgIndexBackfillKey)
+	if err == dstore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("reading backfill checkpoint: %w", err)
+	}
+
+	var cp BackfillCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, xerrors.Errorf("decoding backfill checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// BackfillMessageIndex indexes the messages of the range of the checkpoint
+// from its Next epoch down, on the chain of ts. The checkpoint is saved with
+// every commit of the index, so that an interrupted backfill can be resumed,
+// and removed once the range is indexed. The limiter, if not nil, bounds the
+// number of tipsets indexed per second.
+func (cs *ChainStore) BackfillMessageIndex(ctx context.Context, ts *types.TipSet, cp BackfillCheckpoint, limiter *rate.Limiter, progress func(BackfillProgress)) error {
+	if cp.Next > ts.Height() {
+		return xerrors.Errorf("backfill epoch %d is above the tipset (height %d)", cp.Next, ts.Height())
+	}
+	if cp.From > cp.Next {
+		return xerrors.Errorf("start epoch %d is above the backfill epoch %d", cp.From, cp.Next)
+	}
+
+	p := BackfillProgress{Checkpoint: cp}
+	err := cs.indexRange(ctx, ts, cp.From, cp.Next, limiter, func(b dstore.Batch, next abi.ChainEpoch, tipsets, msgs int) error {
+		p.Checkpoint.Next = next
+		p.TipSets += tipsets
+		p.Messages += msgs
+
+		if next < cp.From {
+			if err := b.Delete(msgIndexBackfillKey); err != nil {
+				return xerrors.Errorf("removing backfill checkpoint: %w", err)
+			}
+		} else {
+			v, err := json.Marshal(&p.Checkpoint)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(msgIndexBackfillKey, v); err != nil {
+				return xerrors.Errorf("writing backfill checkpoint: %w", err)
+			}
+		}
+
+		if progress != nil {
+			progress(p)
+		}
+		return nil
+	})
+	return err
+}
+
+// indexRange indexes the messages of the tipsets from the to epoch down to
+// the from epoch on the chain of ts, in batches. Before committing a batch,
+// beforeCommit is called with the highest epoch left to index (below from
+// for the last batch) and the numbers of tipsets and messages in the batch.
+func (cs *ChainStore) indexRange(ctx context.Context, ts *types.TipSet, from, to abi.ChainEpoch, limiter *rate.Limiter, beforeCommit func(b dstore.Batch, next abi.ChainEpoch, tipsets, msgs int) error) error {
+	cur, err := cs.GetTipsetByHeight(ctx, to, ts, true)
+	if err != nil {
+		return xerrors.Errorf("loading tipset at end epoch: %w", err)
+	}
+
+	b, err := cs.ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	var tipsets, pending int
+	next := to
+	commit := func() error {
+		if err := beforeCommit(b, next, tipsets, pending); err != nil {
+			return err
+		}
+		if err := b.Commit(); err != nil {
+			return xerrors.Errorf("committing message index: %w", err)
+		}
+		tipsets, pending = 0, 0
+		return nil
+	}
+
+	for cur.Height() >= from {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := cs.indexTipSetMessages(b, cur)
+		if err != nil {
+			return xerrors.Errorf("indexing messages of %s: %w", cur.Key(), err)
+		}
+		tipsets++
+		pending += n
+		// null rounds between cur and its parent hold nothing to index
+		next = cur.Height() - 1
+
+		if cur.Height() == 0 {
+			break
+		}
+		if cur, err = cs.LoadTipSet(cur.Parents()); err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+		if cur.Height() < next {
+			next = cur.Height()
+		}
+
+		if pending >= 4096 || tipsets >= msgIndexBatchTipSets {
+			if err := commit(); err != nil {
+				return err
+			}
+			if b, err = cs.ds.Batch(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// e.g. when the range only holds null rounds
+	if next >= from {
+		next = from - 1
+	}
+	return commit()
+}
@@ -0,0 +1,249 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/store"
+)
+
+func TestIndexMessages(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tipsets []*gen.MinedTipSet
+	for i := 0; i < 10; i++ {
+		mts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tipsets = append(tipsets, mts)
+	}
+
+	cs := cg.ChainStore()
+	last := tipsets[len(tipsets)-1].TipSet.TipSet()
+
+	n, err := cs.IndexMessages(context.TODO(), last, 0, last.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("expected messages to be indexed")
+	}
+
+	// messages included in a tipset are executed in its child
+	for i, mts := range tipsets {
+		msgs, err := cs.MessagesForTipset(mts.TipSet.TipSet())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) == 0 {
+			t.Fatalf("expected messages in tipset %d", i)
+		}
+
+		for j, m := range msgs {
+			mi, err := cs.GetMsgInfo(m.Cid())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if i == len(tipsets)-1 {
+				// the messages of the last tipset haven't been executed yet
+				if mi != nil {
+					t.Fatalf("unexecuted message %s was indexed", m.Cid())
+				}
+				continue
+			}
+
+			exec := tipsets[i+1].TipSet.TipSet()
+			if mi == nil {
+				t.Fatalf("message %s not indexed", m.Cid())
+			}
+			if mi.TipSet != exec.Key() || mi.Epoch != exec.Height() || mi.Index != j {
+				t.Fatalf("message %s indexed at %s (%d) index %d, expected %s (%d) index %d",
+					m.Cid(), mi.TipSet, mi.Epoch, mi.Index, exec.Key(), exec.Height(), j)
+			}
+
+			if _, err := cs.GetParentReceipt(exec.Blocks()[0], mi.Index); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestStartMessageIndex(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tipsets []*gen.MinedTipSet
+	for i := 0; i < 3; i++ {
+		mts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tipsets = append(tipsets, mts)
+	}
+
+	cs := cg.ChainStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cs.StartMessageIndex(ctx)
+
+	// the messages of the first tipset are executed in the second
+	msgs, err := cs.MessagesForTipset(tipsets[0].TipSet.TipSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SetHead(tipsets[2].TipSet.TipSet()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mi, err := cs.GetMsgInfo(msgs[0].Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mi != nil {
+			if mi.TipSet != tipsets[1].TipSet.TipSet().Key() {
+				t.Fatalf("message indexed at %s, expected %s", mi.TipSet, tipsets[1].TipSet.TipSet().Key())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the applied tipsets to be indexed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAddressMessages(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first *gen.MinedTipSet
+	var last *gen.MinedTipSet
+	for i := 0; i < 6; i++ {
+		if last, err = cg.NextTipSet(); err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = last
+		}
+	}
+
+	cs := cg.ChainStore()
+	head := last.TipSet.TipSet()
+	if _, err := cs.IndexMessages(context.TODO(), head, 0, head.Height()); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := cs.MessagesForTipset(first.TipSet.TipSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := msgs[0].VMMessage().From
+
+	mis, err := cs.AddressMessages(context.TODO(), sender, head, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for i, mi := range mis {
+		if i > 0 && mi.Epoch > mis[i-1].Epoch {
+			t.Fatalf("entries not ordered newest first")
+		}
+		found = found || mi.Message == msgs[0].Cid()
+	}
+	if !found {
+		t.Fatalf("message %s of %s not found", msgs[0].Cid(), sender)
+	}
+
+	// a limited read stops at the newest entries
+	page, err := cs.AddressMessages(context.TODO(), sender, head, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mis) < 2 || len(page) != 1 || page[0] != mis[0] {
+		t.Fatalf("expected the newest of %d entries, got %+v", len(mis), page)
+	}
+
+	// none of the messages were executed by the first tipsets
+	mis, err = cs.AddressMessages(context.TODO(), sender, first.TipSet.TipSet(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mis) != 0 {
+		t.Fatalf("expected no messages executed at the first tipset, got %d", len(mis))
+	}
+}
+
+func TestBackfillMessageIndex(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tipsets []*gen.MinedTipSet
+	for i := 0; i < 10; i++ {
+		mts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tipsets = append(tipsets, mts)
+	}
+
+	cs := cg.ChainStore()
+	last := tipsets[len(tipsets)-1].TipSet.TipSet()
+	from := tipsets[5].TipSet.TipSet().Height()
+
+	var reports []store.BackfillProgress
+	cp := store.BackfillCheckpoint{From: from, To: last.Height(), Next: last.Height()}
+	if err := cs.BackfillMessageIndex(context.TODO(), last, cp, nil, func(p store.BackfillProgress) {
+		reports = append(reports, p)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) == 0 {
+		t.Fatal("expected progress to be reported")
+	}
+	done := reports[len(reports)-1]
+	if done.Checkpoint.Next >= from || done.TipSets != 5 {
+		t.Fatalf("unexpected final progress %+v", done)
+	}
+
+	// the checkpoint of a finished backfill is removed
+	saved, err := cs.LoadBackfillCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != nil {
+		t.Fatalf("unexpected checkpoint %+v", saved)
+	}
+
+	for i, mts := range tipsets[:len(tipsets)-1] {
+		msgs, err := cs.MessagesForTipset(mts.TipSet.TipSet())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mi, err := cs.GetMsgInfo(msgs[0].Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// messages are executed in the child of the tipset including them
+		if indexed := tipsets[i+1].TipSet.TipSet().Height() >= from; indexed != (mi != nil) {
+			t.Fatalf("message executed in tipset %d: expected indexed %t", i+1, indexed)
+		}
+	}
+}
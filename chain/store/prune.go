@@ -0,0 +1,233 @@
+package store
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	bstore "github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+// PruneGuard keeps the states computed while PruneStates runs from losing
+// their objects.
+type PruneGuard interface {
+	// Lock stops the computation of states until Unlock, and returns the
+	// roots of the states computed so far, whose objects are kept.
+	Lock() []cid.Cid
+	Unlock()
+}
+
+// PruneStates deletes the state trees of all tipsets more than keepEpochs
+// behind ts from the chain blockstore. Block headers, messages, receipts, the
+// genesis state and any state objects still referenced by a recent state tree
+// are preserved.
+//
+// The objects to keep are marked without blocking the computation of states.
+// Each old state tree is then deleted holding guard, if not nil, after marking
+// the states computed and the tipsets applied since.
+//
+// When dryRun is set nothing is deleted, and the returned result describes the
+// space that would be reclaimed.
+func (cs *ChainStore) PruneStates(ctx context.Context, ts *types.TipSet, keepEpochs abi.ChainEpoch, dryRun bool, guard PruneGuard) (*api.ChainPruneResult, error) {
+	if keepEpochs <= 0 {
+		return nil, xerrors.Errorf("must keep at least one epoch of state, got %d", keepEpochs)
+	}
+	if ts == nil {
+		ts = cs.GetHeaviestTipSet()
+	}
+
+	res := &api.ChainPruneResult{
+		DryRun:   dryRun,
+		Boundary: ts.Height() - keepEpochs,
+	}
+	if res.Boundary <= 0 {
+		return res, nil
+	}
+
+	live := cid.NewSet()
+	seenRoots := cid.NewSet()
+	var oldRoots []cid.Cid
+
+	mark := func(c cid.Cid) error {
+		return walkObjects(cs.bs, c, live.Visit, nil)
+	}
+
+	markBlock := func(b *types.BlockHeader) error {
+		if !live.Visit(b.Cid()) {
+			return nil
+		}
+		if err := mark(b.Messages); err != nil {
+			return xerrors.Errorf("marking messages of block %s: %w", b.Cid(), err)
+		}
+		if err := mark(b.ParentMessageReceipts); err != nil {
+			return xerrors.Errorf("marking receipts of block %s: %w", b.Cid(), err)
+		}
+
+		if b.Height > res.Boundary || b.Height == 0 {
+			if err := mark(b.ParentStateRoot); err != nil {
+				return xerrors.Errorf("marking state of block %s: %w", b.Cid(), err)
+			}
+		} else if seenRoots.Visit(b.ParentStateRoot) {
+			oldRoots = append(oldRoots, b.ParentStateRoot)
+		}
+		return nil
+	}
+
+	log.Infow("pruning chain state", "head", ts.Height(), "boundary", res.Boundary, "dryRun", dryRun)
+
+	// Mark everything we keep in a single pass over the header chain, and
+	// collect the state roots that fall behind the boundary.
+	for cur := ts; ; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, b := range cur.Blocks() {
+			if err := markBlock(b); err != nil {
+				return nil, err
+			}
+		}
+
+		if cur.Height() == 0 {
+			break
+		}
+
+		next, err := cs.LoadTipSet(cur.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("loading parent tipset %s: %w", cur.Parents(), err)
+		}
+		cur = next
+	}
+
+	// Tipsets applied while pruning may reference state objects that only
+	// existed in old trees; keep those too. The chain is walked down from the
+	// head until a tipset already marked, to include the forks reorged to.
+	markApplied := func() error {
+		for cur := cs.GetHeaviestTipSet(); cur.Height() > 0; {
+			marked := true
+			for _, b := range cur.Blocks() {
+				marked = marked && live.Has(b.Cid())
+				if err := markBlock(b); err != nil {
+					return err
+				}
+			}
+			if marked {
+				return nil
+			}
+
+			next, err := cs.LoadTipSet(cur.Parents())
+			if err != nil {
+				return xerrors.Errorf("loading parent tipset %s: %w", cur.Parents(), err)
+			}
+			cur = next
+		}
+		return nil
+	}
+
+	swept := cid.NewSet()
+	visit := func(c cid.Cid) bool {
+		return !live.Has(c) && swept.Visit(c)
+	}
+
+	sweep := func(root cid.Cid) error {
+		if guard != nil && !dryRun {
+			computed := guard.Lock()
+			defer guard.Unlock()
+
+			for _, c := range computed {
+				// the states computed by old tipsets are old state roots
+				if seenRoots.Has(c) {
+					continue
+				}
+				if err := mark(c); err != nil {
+					return xerrors.Errorf("marking computed state %s: %w", c, err)
+				}
+			}
+		}
+		if err := markApplied(); err != nil {
+			return err
+		}
+
+		return walkObjects(cs.bs, root, visit, func(c cid.Cid, size int) error {
+			res.Objects++
+			res.Bytes += uint64(size)
+			if dryRun {
+				return nil
+			}
+			return cs.bs.DeleteBlock(c)
+		})
+	}
+
+	for _, root := range oldRoots {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		if err := sweep(root); err != nil {
+			return res, xerrors.Errorf("pruning state root %s: %w", root, err)
+		}
+		res.StateRoots++
+	}
+
+	log.Infow("pruned chain state", "roots", res.StateRoots, "objects", res.Objects, "bytes", res.Bytes, "dryRun", dryRun)
+
+	return res, nil
+}
+
+// walkObjects visits root and every object transitively linked from it in
+// post-order, so that a parent is only passed to cb after all of its children.
+// Objects that visit rejects are skipped along with their subtrees, and objects
+// missing from the blockstore are ignored.
+func walkObjects(bs bstore.Blockstore, root cid.Cid, visit func(cid.Cid) bool, cb func(c cid.Cid, size int) error) error {
+	if !visit(root) {
+		return nil
+	}
+
+	if root.Prefix().Codec != cid.DagCBOR {
+		if cb == nil {
+			return nil
+		}
+		size, err := bs.GetSize(root)
+		if err == bstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return xerrors.Errorf("getting size of %s: %w", root, err)
+		}
+		return cb(root, size)
+	}
+
+	blk, err := bs.Get(root)
+	if err == bstore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("getting %s: %w", root, err)
+	}
+
+	var rerr error
+	err = cbg.ScanForLinks(bytes.NewReader(blk.RawData()), func(c cid.Cid) {
+		if rerr != nil {
+			return
+		}
+		rerr = walkObjects(bs, c, visit, cb)
+	})
+	if err != nil {
+		return xerrors.Errorf("scanning for links in %s: %w", root, err)
+	}
+	if rerr != nil {
+		return rerr
+	}
+
+	if cb == nil {
+		return nil
+	}
+	return cb(root, len(blk.RawData()))
+}
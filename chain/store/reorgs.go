@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	dstore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var reorgsPrefix = dstore.NewKey("reorgs")
+
+const reorgTopic = "reorg"
+
+// ReorgJournalSize is the number of reorgs kept in the reorg journal.
+const ReorgJournalSize = 1000
+
+func reorgKey(seq uint64) dstore.Key {
+	return reorgsPrefix.ChildString(fmt.Sprintf("%016x", seq))
+}
+
+// recordReorg is a ReorgNotifee that persists every head change which reverts
+// at least one tipset, and publishes it to reorg subscribers. Plain extensions
+// of the current chain are not recorded.
+func (cs *ChainStore) recordReorg(rev, app []*types.TipSet) error {
+	if len(rev) == 0 {
+		return nil
+	}
+
+	if cs.reorgSeq == 0 {
+		last, err := cs.lastJournalSeq(reorgsPrefix)
+		if err != nil {
+			return xerrors.Errorf("loading reorg journal: %w", err)
+		}
+		cs.reorgSeq = last
+	}
+
+	r, err := cs.describeReorg(rev, app)
+	if err != nil {
+		return xerrors.Errorf("describing reorg: %w", err)
+	}
+
+	cs.reorgSeq++
+	r.Seq = cs.reorgSeq
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return xerrors.Errorf("marshaling reorg: %w", err)
+	}
+	if err := cs.ds.Put(reorgKey(r.Seq), b); err != nil {
+		return xerrors.Errorf("writing reorg to journal: %w", err)
+	}
+
+	if r.Seq > ReorgJournalSize {
+		if err := cs.ds.Delete(reorgKey(r.Seq - ReorgJournalSize)); err != nil {
+			log.Warnf("pruning reorg journal: %s", err)
+		}
+	}
+
+	log.Warnw("chain reorg", "depth", r.Depth, "forkHeight", r.ForkHeight, "from", r.From, "to", r.To,
+		"droppedBlocks", len(r.DroppedBlocks), "droppedMessages", len(r.DroppedMessages))
+
+	cs.journal.RecordEvent(cs.evtTypes[evtTypeReorg], func() interface{} {
+		return r
+	})
+
+	cs.pubLk.Lock()
+	cs.bestTips.Pub(r, reorgTopic)
+	cs.pubLk.Unlock()
+
+	return nil
+}
+
+// describeReorg builds the journal entry for reverting rev (newest first) and
+// applying app (oldest first).
+func (cs *ChainStore) describeReorg(rev, app []*types.TipSet) (*api.ChainReorg, error) {
+	fork, err := cs.LoadTipSet(rev[len(rev)-1].Parents())
+	if err != nil {
+		return nil, xerrors.Errorf("loading fork point: %w", err)
+	}
+
+	from, to := rev[0], fork
+	if len(app) > 0 {
+		to = app[len(app)-1]
+	}
+
+	applied := cid.NewSet()
+	appliedMsgs := cid.NewSet()
+	for _, ts := range app {
+		for _, b := range ts.Blocks() {
+			applied.Add(b.Cid())
+
+			bls, secpk, err := cs.ReadMsgMetaCids(b.Messages)
+			if err != nil {
+				return nil, xerrors.Errorf("reading messages of applied block %s: %w", b.Cid(), err)
+			}
+			for _, c := range bls {
+				appliedMsgs.Add(c)
+			}
+			for _, c := range secpk {
+				appliedMsgs.Add(c)
+			}
+		}
+	}
+
+	r := &api.ChainReorg{
+		Timestamp:  uint64(build.Clock.Now().Unix()),
+		Depth:      len(rev),
+		From:       from.Key(),
+		FromHeight: from.Height(),
+		To:         to.Key(),
+		ToHeight:   to.Height(),
+		ForkHeight: fork.Height(),
+	}
+
+	dropped := cid.NewSet()
+	for _, ts := range rev {
+		for _, b := range ts.Blocks() {
+			if !applied.Has(b.Cid()) {
+				r.DroppedBlocks = append(r.DroppedBlocks, b.Cid())
+			}
+
+			bls, secpk, err := cs.ReadMsgMetaCids(b.Messages)
+			if err != nil {
+				return nil, xerrors.Errorf("reading messages of reverted block %s: %w", b.Cid(), err)
+			}
+			for _, msgs := range [][]cid.Cid{bls, secpk} {
+				for _, c := range msgs {
+					if !appliedMsgs.Has(c) && dropped.Visit(c) {
+						r.DroppedMessages = append(r.DroppedMessages, c)
+					}
+				}
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// lastJournalSeq returns the highest sequence number recorded in the journal
+// under prefix, or 0 if it's empty.
+func (cs *ChainStore) lastJournalSeq(prefix dstore.Key) (uint64, error) {
+	res, err := cs.ds.Query(query.Query{
+		Prefix:   prefix.String(),
+		Orders:   []query.Order{query.OrderByKeyDescending{}},
+		Limit:    1,
+		KeysOnly: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Close() //nolint:errcheck
+
+	e, ok := res.NextSync()
+	if !ok {
+		return 0, nil
+	}
+	if e.Error != nil {
+		return 0, e.Error
+	}
+
+	seq, err := strconv.ParseUint(dstore.RawKey(e.Key).Name(), 16, 64)
+	if err != nil {
+		return 0, xerrors.Errorf("bad journal key %s: %w", e.Key, err)
+	}
+	return seq, nil
+}
+
+// GetReorgs returns the recorded reorgs whose new head is at or above the
+// given height, oldest first. The heights of the new heads aren't ordered
+// across reorgs, so every reorg of the journal is checked.
+func (cs *ChainStore) GetReorgs(since abi.ChainEpoch) ([]*api.ChainReorg, error) {
+	res, err := cs.ds.Query(query.Query{
+		Prefix: reorgsPrefix.String(),
+		Orders: []query.Order{query.OrderByKey{}},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("querying reorg journal: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var out []*api.ChainReorg
+	for {
+		e, ok := res.NextSync()
+		if !ok {
+			break
+		}
+		if e.Error != nil {
+			return nil, xerrors.Errorf("reading reorg journal: %w", e.Error)
+		}
+
+		var r api.ChainReorg
+		if err := json.Unmarshal(e.Value, &r); err != nil {
+			return nil, xerrors.Errorf("decoding reorg %s: %w", e.Key, err)
+		}
+		if r.ToHeight < since {
+			continue
+		}
+		out = append(out, &r)
+	}
+
+	return out, nil
+}
+
+// SubReorgs returns a channel on which every reorg recorded in the journal is
+// delivered until ctx is cancelled.
+func (cs *ChainStore) SubReorgs(ctx context.Context) <-chan *api.ChainReorg {
+	cs.pubLk.Lock()
+	subch := cs.bestTips.Sub(reorgTopic)
+	cs.pubLk.Unlock()
+
+	out := make(chan *api.ChainReorg, 16)
+
+	go func() {
+		defer close(out)
+		var unsubOnce sync.Once
+
+		for {
+			select {
+			case val, ok := <-subch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- val.(*api.ChainReorg):
+				case <-ctx.Done():
+				}
+			case <-ctx.Done():
+				unsubOnce.Do(func() {
+					go cs.bestTips.Unsub(subch)
+				})
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+// BlockFetcher retrieves a block from outside the chain blockstore, e.g. from
+// the network.
+type BlockFetcher func(ctx context.Context, c cid.Cid) (blocks.Block, error)
+
+// ScrubBlockstore re-hashes every block in the chain blockstore and checks it
+// against its CID. If fetch is set, corrupt blocks are deleted and fetched
+// again; a fetched block is only stored if it matches its CID.
+func (cs *ChainStore) ScrubBlockstore(ctx context.Context, fetch BlockFetcher) (*api.ChainCheckResult, error) {
+	keys, err := cs.bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("listing blockstore keys: %w", err)
+	}
+
+	res := &api.ChainCheckResult{}
+	for c := range keys {
+		ok, err := cs.checkBlock(c)
+		if err != nil {
+			return nil, err
+		}
+		res.Checked++
+		if !ok {
+			log.Errorw("corrupt block in chain blockstore", "cid", c)
+			res.Corrupt = append(res.Corrupt, c)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if fetch == nil {
+		return res, nil
+	}
+
+	// repair after the scan, so that the blockstore isn't modified while
+	// iterating over it
+	for _, c := range res.Corrupt {
+		if err := cs.repairBlock(ctx, c, fetch); err != nil {
+			log.Errorw("failed to repair corrupt block", "cid", c, "error", err)
+			continue
+		}
+		res.Repaired = append(res.Repaired, c)
+	}
+
+	return res, nil
+}
+
+// checkBlock reports whether the stored data of the block hashes to its CID.
+func (cs *ChainStore) checkBlock(c cid.Cid) (bool, error) {
+	blk, err := cs.bs.Get(c)
+	if err != nil {
+		return false, xerrors.Errorf("reading block %s: %w", c, err)
+	}
+
+	sum, err := c.Prefix().Sum(blk.RawData())
+	if err != nil {
+		// the data can't be hashed the way the CID describes
+		return false, nil
+	}
+	return sum.Equals(c), nil
+}
+
+func (cs *ChainStore) repairBlock(ctx context.Context, c cid.Cid, fetch BlockFetcher) error {
+	if err := cs.bs.DeleteBlock(c); err != nil {
+		return xerrors.Errorf("deleting corrupt block: %w", err)
+	}
+
+	blk, err := fetch(ctx, c)
+	if err != nil {
+		return xerrors.Errorf("fetching block: %w", err)
+	}
+
+	sum, err := c.Prefix().Sum(blk.RawData())
+	if err != nil || !sum.Equals(c) {
+		return xerrors.Errorf("fetched block doesn't match its CID")
+	}
+
+	if err := cs.bs.Put(blk); err != nil {
+		return xerrors.Errorf("storing fetched block: %w", err)
+	}
+	return nil
+}
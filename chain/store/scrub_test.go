@@ -0,0 +1,73 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func TestScrubBlockstore(t *testing.T) {
+	ctx := context.TODO()
+
+	bs := blockstore.NewTemporarySync()
+	cs := store.NewChainStore(bs, syncds.MutexWrap(datastore.NewMapDatastore()), nil, nil)
+
+	var good []blocks.Block
+	for _, d := range []string{"foo", "bar", "baz"} {
+		b := blocks.NewBlock([]byte(d))
+		require.NoError(t, bs.Put(b))
+		good = append(good, b)
+	}
+
+	// store garbage under the CID of the second block
+	bad, err := blocks.NewBlockWithCid([]byte("garbage"), good[1].Cid())
+	require.NoError(t, err)
+	require.NoError(t, bs.DeleteBlock(good[1].Cid()))
+	require.NoError(t, bs.Put(bad))
+
+	res, err := cs.ScrubBlockstore(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, res.Checked)
+	require.Equal(t, []cid.Cid{good[1].Cid()}, res.Corrupt)
+	require.Empty(t, res.Repaired)
+
+	// a fetcher returning bad data doesn't repair anything
+	res, err = cs.ScrubBlockstore(ctx, func(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+		return bad, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Corrupt, 1)
+	require.Empty(t, res.Repaired)
+
+	// the block may have been deleted by the failed repair
+	require.NoError(t, bs.Put(bad))
+
+	fetch := func(ctx context.Context, c cid.Cid) (blocks.Block, error) {
+		for _, b := range good {
+			if b.Cid().Equals(c) {
+				return b, nil
+			}
+		}
+		return nil, xerrors.Errorf("not found")
+	}
+	res, err = cs.ScrubBlockstore(ctx, fetch)
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{good[1].Cid()}, res.Repaired)
+
+	stored, err := bs.Get(good[1].Cid())
+	require.NoError(t, err)
+	require.Equal(t, good[1].RawData(), stored.RawData())
+
+	res, err = cs.ScrubBlockstore(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, res.Corrupt)
+}
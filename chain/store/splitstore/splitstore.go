@@ -0,0 +1,568 @@
+package splitstore
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	dstore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	bstore "github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+var log = logging.Logger("splitstore")
+
+const (
+	// ColdStoreUniversal moves objects evicted from the hotstore into the
+	// regular chain blockstore.
+	ColdStoreUniversal = "universal"
+	// ColdStoreDiscard deletes objects evicted from the hotstore.
+	ColdStoreDiscard = "discard"
+)
+
+var (
+	// DefaultCompactionThreshold is the number of epochs the hotstore may
+	// grow past the last compaction boundary before it is compacted again.
+	DefaultCompactionThreshold = 5 * build.Finality
+	// DefaultCompactionBoundary is the number of epochs (counted back from
+	// the current head) whose objects are always kept in the hotstore.
+	DefaultCompactionBoundary = 4 * build.Finality
+)
+
+var (
+	trackingPrefix = dstore.NewKey("/tracking")
+	baseEpochKey   = dstore.NewKey("/meta/baseEpoch")
+)
+
+type Config struct {
+	// ColdStoreType is one of ColdStoreUniversal or ColdStoreDiscard.
+	ColdStoreType string
+
+	CompactionThreshold abi.ChainEpoch
+	CompactionBoundary  abi.ChainEpoch
+}
+
+// ChainAccessor is the subset of the ChainStore used by the SplitStore;
+// it exists so that compaction can be exercised without a full ChainStore.
+type ChainAccessor interface {
+	GetHeaviestTipSet() *types.TipSet
+	SubscribeHeadChanges(f store.ReorgNotifee)
+	WalkSnapshot(ctx context.Context, ts *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs bool, cb func(cid.Cid) error) error
+}
+
+// SplitStore is a blockstore that keeps recently written chain objects in a
+// hot store and moves objects older than the compaction boundary to a cold
+// store (or discards them) once they are no longer reachable from the
+// recent chain.
+//
+// Every object written to the hotstore is tracked together with the epoch
+// it was written at. Compaction is triggered by head changes: once the head
+// has advanced CompactionThreshold epochs past the last compaction, all
+// objects reachable from the tipsets between the head and the new boundary
+// are marked live, and every unmarked hot object written before the boundary
+// is moved to the coldstore (or deleted). The objects read from the hotstore
+// while compacting are tracked again at the current epoch, as the objects
+// written meanwhile may reference them.
+type SplitStore struct {
+	compacting int32 // accessed atomically
+
+	cfg Config
+
+	mx        sync.Mutex
+	curTs     *types.TipSet
+	baseEpoch abi.ChainEpoch
+	// boundary of the compaction in progress, zero when not compacting
+	compactBoundary abi.ChainEpoch
+
+	// txnLk is held for reading by the accesses to the hotstore, and for
+	// writing while evicting, so that no object is evicted between being
+	// read or written and being tracked again
+	txnLk sync.RWMutex
+
+	cs ChainAccessor
+
+	meta    dstore.Batching
+	tracker dstore.Batching
+
+	hot  bstore.Blockstore
+	cold bstore.Blockstore // nil when discarding
+}
+
+var _ bstore.Blockstore = (*SplitStore)(nil)
+
+// NewSplitStore creates a SplitStore using hds for hot objects and tracking
+// metadata, and cold for evicted objects.
+func NewSplitStore(hds dstore.Batching, cold bstore.Blockstore, cfg Config) (*SplitStore, error) {
+	switch cfg.ColdStoreType {
+	case "", ColdStoreUniversal:
+		if cold == nil {
+			return nil, xerrors.Errorf("universal coldstore requires a cold blockstore")
+		}
+	case ColdStoreDiscard:
+		cold = nil
+	default:
+		return nil, xerrors.Errorf("unknown coldstore type: %q", cfg.ColdStoreType)
+	}
+
+	if cfg.CompactionThreshold <= 0 {
+		cfg.CompactionThreshold = DefaultCompactionThreshold
+	}
+	if cfg.CompactionBoundary <= 0 {
+		cfg.CompactionBoundary = DefaultCompactionBoundary
+	}
+	if cfg.CompactionBoundary > cfg.CompactionThreshold {
+		return nil, xerrors.Errorf("compaction boundary (%d) must not exceed compaction threshold (%d)", cfg.CompactionBoundary, cfg.CompactionThreshold)
+	}
+
+	ss := &SplitStore{
+		cfg:     cfg,
+		meta:    hds,
+		tracker: namespace.Wrap(hds, trackingPrefix),
+		hot:     bstore.NewBlockstore(hds),
+		cold:    cold,
+	}
+
+	bs, err := hds.Get(baseEpochKey)
+	switch err {
+	case nil:
+		ss.baseEpoch = bytesToEpoch(bs)
+	case dstore.ErrNotFound:
+	default:
+		return nil, xerrors.Errorf("loading base epoch: %w", err)
+	}
+
+	return ss, nil
+}
+
+// Start hooks the SplitStore up to the chain, so that writes are tracked at
+// the current head epoch and compaction is triggered by head changes.
+func (s *SplitStore) Start(cs ChainAccessor) error {
+	s.mx.Lock()
+	s.cs = cs
+	s.curTs = cs.GetHeaviestTipSet()
+	initBase := s.curTs != nil && s.baseEpoch == 0
+	s.mx.Unlock()
+
+	if initBase {
+		// first start on an existing chain; nothing in the hotstore is older
+		// than the current head
+		if err := s.setBaseEpoch(s.curTs.Height()); err != nil {
+			return err
+		}
+	}
+
+	cs.SubscribeHeadChanges(s.HeadChange)
+	return nil
+}
+
+// HeadChange is a store.ReorgNotifee which tracks the current epoch and
+// triggers background compaction once the threshold is reached.
+func (s *SplitStore) HeadChange(_, apply []*types.TipSet) error {
+	if len(apply) == 0 {
+		return nil
+	}
+
+	curTs := apply[len(apply)-1]
+
+	s.mx.Lock()
+	s.curTs = curTs
+	baseEpoch := s.baseEpoch
+	s.mx.Unlock()
+
+	if curTs.Height()-baseEpoch < s.cfg.CompactionThreshold {
+		return nil
+	}
+
+	if !atomic.CompareAndSwapInt32(&s.compacting, 0, 1) {
+		// already compacting
+		return nil
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&s.compacting, 0)
+
+		if err := s.compact(context.TODO(), curTs); err != nil {
+			log.Errorf("error compacting splitstore: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Compacting reports whether a compaction is currently in progress.
+func (s *SplitStore) Compacting() bool {
+	return atomic.LoadInt32(&s.compacting) == 1
+}
+
+func (s *SplitStore) compact(ctx context.Context, curTs *types.TipSet) error {
+	boundaryEpoch := curTs.Height() - s.cfg.CompactionBoundary
+	log.Infow("compacting splitstore", "head", curTs.Height(), "boundary", boundaryEpoch)
+	start := build.Clock.Now()
+
+	s.mx.Lock()
+	s.compactBoundary = boundaryEpoch
+	s.mx.Unlock()
+	defer func() {
+		s.mx.Lock()
+		s.compactBoundary = 0
+		s.mx.Unlock()
+	}()
+
+	// Mark everything reachable from the tipsets we still want to keep hot.
+	// Objects referenced by the current head (and anything written or read
+	// while we are compacting) are at or past the boundary, so they are never
+	// swept.
+	live := cid.NewSet()
+	if err := s.cs.WalkSnapshot(ctx, curTs, s.cfg.CompactionBoundary, true, func(c cid.Cid) error {
+		live.Add(c)
+		return nil
+	}); err != nil {
+		return xerrors.Errorf("marking live objects: %w", err)
+	}
+
+	log.Infow("marking done", "took", build.Clock.Now().Sub(start), "live", live.Len())
+
+	moved, deleted, err := s.sweep(boundaryEpoch, live)
+	if err != nil {
+		return xerrors.Errorf("sweeping hotstore: %w", err)
+	}
+
+	if err := s.setBaseEpoch(boundaryEpoch); err != nil {
+		return err
+	}
+
+	log.Infow("compaction done", "took", build.Clock.Now().Sub(start), "moved", moved, "deleted", deleted)
+	return nil
+}
+
+// sweep evicts every tracked object written before boundaryEpoch which isn't
+// in the live set. Evicted objects are copied to the coldstore unless it is
+// discarding.
+func (s *SplitStore) sweep(boundaryEpoch abi.ChainEpoch, live *cid.Set) (moved, deleted int, err error) {
+	res, err := s.tracker.Query(query.Query{})
+	if err != nil {
+		return 0, 0, xerrors.Errorf("querying tracking store: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var evict []cid.Cid
+	for {
+		r, ok := res.NextSync()
+		if !ok {
+			break
+		}
+		if r.Error != nil {
+			return 0, 0, xerrors.Errorf("iterating tracking store: %w", r.Error)
+		}
+
+		if bytesToEpoch(r.Value) >= boundaryEpoch {
+			continue
+		}
+
+		c, err := cid.Decode(strings.TrimPrefix(r.Key, "/"))
+		if err != nil {
+			log.Warnf("bad key in tracking store (%s): %s", r.Key, err)
+			continue
+		}
+
+		if live.Has(c) {
+			continue
+		}
+
+		evict = append(evict, c)
+	}
+
+	const batchSize = 4096
+	for i := 0; i < len(evict); i += batchSize {
+		end := i + batchSize
+		if end > len(evict) {
+			end = len(evict)
+		}
+
+		n, m, err := s.evict(boundaryEpoch, evict[i:end])
+		if err != nil {
+			return moved, deleted, err
+		}
+		moved += n
+		deleted += m - n
+	}
+
+	return moved, deleted, nil
+}
+
+// evict evicts the objects which are still tracked before boundaryEpoch, and
+// returns the number of objects moved to the coldstore and evicted.
+func (s *SplitStore) evict(boundaryEpoch abi.ChainEpoch, cids []cid.Cid) (int, int, error) {
+	s.txnLk.Lock()
+	defer s.txnLk.Unlock()
+
+	// the objects read or written since the tracking store was queried are
+	// tracked again past the boundary
+	stale := make([]cid.Cid, 0, len(cids))
+	for _, c := range cids {
+		v, err := s.tracker.Get(trackingKey(c))
+		if err == dstore.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return 0, 0, xerrors.Errorf("getting tracking epoch of %s: %w", c, err)
+		}
+		if bytesToEpoch(v) < boundaryEpoch {
+			stale = append(stale, c)
+		}
+	}
+
+	moved := 0
+	if s.cold != nil {
+		blks := make([]blocks.Block, 0, len(stale))
+		for _, c := range stale {
+			blk, err := s.hot.Get(c)
+			if err != nil {
+				if err == bstore.ErrNotFound {
+					continue
+				}
+				return 0, 0, xerrors.Errorf("getting %s from hotstore: %w", c, err)
+			}
+			blks = append(blks, blk)
+		}
+
+		if err := s.cold.PutMany(blks); err != nil {
+			return 0, 0, xerrors.Errorf("moving objects to coldstore: %w", err)
+		}
+		moved = len(blks)
+	}
+
+	batch, err := s.tracker.Batch()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, c := range stale {
+		if err := s.hot.DeleteBlock(c); err != nil && err != bstore.ErrNotFound {
+			return 0, 0, xerrors.Errorf("deleting %s from hotstore: %w", c, err)
+		}
+		if err := batch.Delete(trackingKey(c)); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := batch.Commit(); err != nil {
+		return 0, 0, xerrors.Errorf("committing tracking store batch: %w", err)
+	}
+
+	return moved, len(stale), nil
+}
+
+func (s *SplitStore) setBaseEpoch(epoch abi.ChainEpoch) error {
+	s.mx.Lock()
+	s.baseEpoch = epoch
+	s.mx.Unlock()
+
+	if err := s.meta.Put(baseEpochKey, epochToBytes(epoch)); err != nil {
+		return xerrors.Errorf("persisting base epoch: %w", err)
+	}
+	return nil
+}
+
+func (s *SplitStore) writeEpoch() abi.ChainEpoch {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.curTs == nil {
+		return s.baseEpoch
+	}
+	return s.curTs.Height()
+}
+
+// touch tracks an object read from the hotstore while compacting at the
+// current epoch, if it was written before the compaction boundary, for it not
+// to be evicted while the objects written meanwhile may reference it. It must
+// be called with txnLk held for reading.
+func (s *SplitStore) touch(c cid.Cid) error {
+	s.mx.Lock()
+	boundary := s.compactBoundary
+	s.mx.Unlock()
+	if boundary <= 0 {
+		return nil
+	}
+
+	v, err := s.tracker.Get(trackingKey(c))
+	if err == dstore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytesToEpoch(v) >= boundary {
+		return nil
+	}
+	return s.tracker.Put(trackingKey(c), epochToBytes(s.writeEpoch()))
+}
+
+// Blockstore interface
+
+func (s *SplitStore) DeleteBlock(c cid.Cid) error {
+	if err := s.hot.DeleteBlock(c); err != nil && err != bstore.ErrNotFound {
+		return err
+	}
+	if err := s.tracker.Delete(trackingKey(c)); err != nil {
+		return err
+	}
+	if s.cold != nil {
+		return s.cold.DeleteBlock(c)
+	}
+	return nil
+}
+
+func (s *SplitStore) Has(c cid.Cid) (bool, error) {
+	s.txnLk.RLock()
+	defer s.txnLk.RUnlock()
+
+	has, err := s.hot.Has(c)
+	if err == nil && has {
+		err = s.touch(c)
+	}
+	if err != nil || has || s.cold == nil {
+		return has, err
+	}
+
+	return s.cold.Has(c)
+}
+
+func (s *SplitStore) Get(c cid.Cid) (blocks.Block, error) {
+	s.txnLk.RLock()
+	defer s.txnLk.RUnlock()
+
+	blk, err := s.hot.Get(c)
+	if err == nil {
+		if err := s.touch(c); err != nil {
+			return nil, err
+		}
+	}
+	if err != bstore.ErrNotFound || s.cold == nil {
+		return blk, err
+	}
+
+	return s.cold.Get(c)
+}
+
+func (s *SplitStore) GetSize(c cid.Cid) (int, error) {
+	s.txnLk.RLock()
+	defer s.txnLk.RUnlock()
+
+	size, err := s.hot.GetSize(c)
+	if err == nil {
+		if err := s.touch(c); err != nil {
+			return 0, err
+		}
+	}
+	if err != bstore.ErrNotFound || s.cold == nil {
+		return size, err
+	}
+
+	return s.cold.GetSize(c)
+}
+
+func (s *SplitStore) Put(blk blocks.Block) error {
+	s.txnLk.RLock()
+	defer s.txnLk.RUnlock()
+
+	if err := s.hot.Put(blk); err != nil {
+		return err
+	}
+
+	return s.tracker.Put(trackingKey(blk.Cid()), epochToBytes(s.writeEpoch()))
+}
+
+func (s *SplitStore) PutMany(blks []blocks.Block) error {
+	s.txnLk.RLock()
+	defer s.txnLk.RUnlock()
+
+	if err := s.hot.PutMany(blks); err != nil {
+		return err
+	}
+
+	batch, err := s.tracker.Batch()
+	if err != nil {
+		return err
+	}
+
+	epoch := epochToBytes(s.writeEpoch())
+	for _, blk := range blks {
+		if err := batch.Put(trackingKey(blk.Cid()), epoch); err != nil {
+			return err
+		}
+	}
+
+	return batch.Commit()
+}
+
+// AllKeysChan returns the keys of both the hot and the cold store. Keys that
+// are present in both stores may be returned twice.
+func (s *SplitStore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	hch, err := s.hot.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cold == nil {
+		return hch, nil
+	}
+
+	cch, err := s.cold.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+
+		for _, in := range []<-chan cid.Cid{hch, cch} {
+			for c := range in {
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *SplitStore) HashOnRead(enabled bool) {
+	s.hot.HashOnRead(enabled)
+	if s.cold != nil {
+		s.cold.HashOnRead(enabled)
+	}
+}
+
+func trackingKey(c cid.Cid) dstore.Key {
+	return dstore.NewKey(c.String())
+}
+
+func epochToBytes(epoch abi.ChainEpoch) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(epoch))
+	return buf[:n]
+}
+
+func bytesToEpoch(buf []byte) abi.ChainEpoch {
+	epoch, _ := binary.Uvarint(buf)
+	return abi.ChainEpoch(epoch)
+}
@@ -0,0 +1,191 @@
+package splitstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+type mockChain struct {
+	head *types.TipSet
+	live []cid.Cid
+	// onWalk is called when walking, if set
+	onWalk func()
+}
+
+func (m *mockChain) GetHeaviestTipSet() *types.TipSet {
+	return m.head
+}
+
+func (m *mockChain) SubscribeHeadChanges(f store.ReorgNotifee) {}
+
+func (m *mockChain) WalkSnapshot(ctx context.Context, ts *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs bool, cb func(cid.Cid) error) error {
+	if m.onWalk != nil {
+		m.onWalk()
+	}
+	for _, c := range m.live {
+		if err := cb(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tipsetAt(h abi.ChainEpoch) *types.TipSet {
+	blk := mock.MkBlock(nil, 1, 1)
+	blk.Height = h
+	return mock.TipSet(blk)
+}
+
+func testSplitStore(t *testing.T, coldType string) {
+	hot := syncds.MutexWrap(datastore.NewMapDatastore())
+	cold := blockstore.NewTemporarySync()
+
+	ss, err := NewSplitStore(hot, cold, Config{
+		ColdStoreType:       coldType,
+		CompactionThreshold: 10,
+		CompactionBoundary:  5,
+	})
+	require.NoError(t, err)
+
+	liveBlk := blocks.NewBlock([]byte("live"))
+	deadBlk := blocks.NewBlock([]byte("dead"))
+	coldBlk := blocks.NewBlock([]byte("cold"))
+	require.NoError(t, cold.Put(coldBlk))
+
+	chain := &mockChain{head: tipsetAt(10), live: []cid.Cid{liveBlk.Cid()}}
+	require.NoError(t, ss.Start(chain))
+
+	require.NoError(t, ss.PutMany([]blocks.Block{liveBlk, deadBlk}))
+
+	// reads fall through to the coldstore
+	has, err := ss.Has(coldBlk.Cid())
+	require.NoError(t, err)
+	require.Equal(t, coldType != ColdStoreDiscard, has)
+
+	require.NoError(t, ss.compact(context.TODO(), tipsetAt(25)))
+
+	has, err = ss.hot.Has(liveBlk.Cid())
+	require.NoError(t, err)
+	require.True(t, has, "live object must stay in the hotstore")
+
+	has, err = ss.hot.Has(deadBlk.Cid())
+	require.NoError(t, err)
+	require.False(t, has, "unreachable object must be evicted from the hotstore")
+
+	has, err = cold.Has(deadBlk.Cid())
+	require.NoError(t, err)
+	require.Equal(t, coldType != ColdStoreDiscard, has)
+
+	_, err = ss.Get(deadBlk.Cid())
+	if coldType == ColdStoreDiscard {
+		require.Equal(t, blockstore.ErrNotFound, err)
+	} else {
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, abi.ChainEpoch(20), ss.baseEpoch)
+}
+
+func TestSplitStoreUniversal(t *testing.T) {
+	testSplitStore(t, ColdStoreUniversal)
+}
+
+func TestSplitStoreDiscard(t *testing.T) {
+	testSplitStore(t, ColdStoreDiscard)
+}
+
+func TestSplitStoreCompactionWrites(t *testing.T) {
+	hot := syncds.MutexWrap(datastore.NewMapDatastore())
+
+	ss, err := NewSplitStore(hot, nil, Config{
+		ColdStoreType:       ColdStoreDiscard,
+		CompactionThreshold: 10,
+		CompactionBoundary:  5,
+	})
+	require.NoError(t, err)
+
+	reusedBlk := blocks.NewBlock([]byte("reused"))
+	deadBlk := blocks.NewBlock([]byte("dead"))
+
+	walking := make(chan struct{})
+	resume := make(chan struct{})
+	chain := &mockChain{head: tipsetAt(10), onWalk: func() {
+		close(walking)
+		<-resume
+	}}
+	require.NoError(t, ss.Start(chain))
+
+	require.NoError(t, ss.PutMany([]blocks.Block{reusedBlk, deadBlk}))
+
+	require.NoError(t, ss.HeadChange(nil, []*types.TipSet{tipsetAt(25)}))
+	<-walking
+	require.True(t, ss.Compacting())
+
+	// an object written while compacting references an old object, which is
+	// found in the store and so isn't written again
+	has, err := ss.Has(reusedBlk.Cid())
+	require.NoError(t, err)
+	require.True(t, has)
+	newBlk := blocks.NewBlock([]byte("new"))
+	require.NoError(t, ss.Put(newBlk))
+
+	// and more objects are written and read while sweeping
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var written []cid.Cid
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			blk := blocks.NewBlock([]byte(fmt.Sprintf("written %d", i)))
+			if err := ss.Put(blk); err != nil {
+				t.Error(err)
+				return
+			}
+			written = append(written, blk.Cid())
+			if _, err := ss.Get(reusedBlk.Cid()); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	close(resume)
+	require.Eventually(t, func() bool {
+		return !ss.Compacting()
+	}, 5*time.Second, 10*time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	for _, c := range append([]cid.Cid{reusedBlk.Cid(), newBlk.Cid()}, written...) {
+		has, err := ss.Has(c)
+		require.NoError(t, err)
+		require.True(t, has, "object read or written while compacting must be kept")
+	}
+
+	has, err = ss.Has(deadBlk.Cid())
+	require.NoError(t, err)
+	require.False(t, has, "unreachable object must be evicted")
+}
@@ -83,6 +83,7 @@ type ReorgNotifee func(rev, app []*types.TipSet) error
 // Journal event types.
 const (
 	evtTypeHeadChange = iota
+	evtTypeReorg
 )
 
 type HeadChangeEvt struct {
@@ -116,7 +117,8 @@ type ChainStore struct {
 	tstLk   sync.Mutex
 	tipsets map[abi.ChainEpoch][]cid.Cid
 
-	cindex *ChainIndex
+	cindex  *ChainIndex
+	heights *heightIndex
 
 	reorgCh        chan<- reorg
 	reorgNotifeeCh chan ReorgNotifee
@@ -126,7 +128,10 @@ type ChainStore struct {
 
 	vmcalls vm.SyscallBuilder
 
-	evtTypes [1]journal.EventType
+	reorgSeq uint64
+	headSeq  uint64
+
+	evtTypes [2]journal.EventType
 	journal  journal.Journal
 }
 
@@ -147,14 +152,18 @@ func NewChainStore(bs bstore.Blockstore, ds dstore.Batching, vmcalls vm.SyscallB
 		journal:  j,
 	}
 
-	cs.evtTypes = [1]journal.EventType{
+	cs.evtTypes = [2]journal.EventType{
 		evtTypeHeadChange: j.RegisterEventType("sync", "head_change"),
+		evtTypeReorg:      j.RegisterEventType("sync", "reorg"),
 	}
 
 	ci := NewChainIndex(cs.LoadTipSet)
 
 	cs.cindex = ci
 
+	cs.heights = newHeightIndex(ds, cs.LoadTipSet)
+	go cs.heights.run(context.TODO())
+
 	hcnf := func(rev, app []*types.TipSet) error {
 		cs.pubLk.Lock()
 		defer cs.pubLk.Unlock()
@@ -186,8 +195,14 @@ func NewChainStore(bs bstore.Blockstore, ds dstore.Batching, vmcalls vm.SyscallB
 		return nil
 	}
 
+	if seq, err := cs.lastJournalSeq(headChangesPrefix); err != nil {
+		log.Errorf("loading head change journal: %s", err)
+	} else {
+		cs.headSeq = seq
+	}
+
 	cs.reorgNotifeeCh = make(chan ReorgNotifee)
-	cs.reorgCh = cs.reorgWorker(context.TODO(), []ReorgNotifee{hcnf, hcmetric})
+	cs.reorgCh = cs.reorgWorker(context.TODO(), []ReorgNotifee{hcnf, hcmetric, cs.recordReorg, cs.indexHeights, cs.journalHeadChange})
 
 	return cs
 }
@@ -213,6 +228,7 @@ func (cs *ChainStore) Load() error {
 	}
 
 	cs.heaviest = ts
+	cs.heights.notify(ts)
 
 	return nil
 }
@@ -365,6 +381,9 @@ func (cs *ChainStore) MaybeTakeHeavierTipSet(ctx context.Context, ts *types.TipS
 type reorg struct {
 	old *types.TipSet
 	new *types.TipSet
+
+	// jump skips walking the chain between old and new, see SetTrustedHead
+	jump bool
 }
 
 func (cs *ChainStore) reorgWorker(ctx context.Context, initialNotifees []ReorgNotifee) chan<- reorg {
@@ -381,10 +400,16 @@ func (cs *ChainStore) reorgWorker(ctx context.Context, initialNotifees []ReorgNo
 				notifees = append(notifees, n)
 
 			case r := <-out:
-				revert, apply, err := cs.ReorgOps(r.old, r.new)
-				if err != nil {
-					log.Error("computing reorg ops failed: ", err)
-					continue
+				var revert, apply []*types.TipSet
+				if r.jump {
+					apply = []*types.TipSet{r.new}
+				} else {
+					var err error
+					revert, apply, err = cs.ReorgOps(r.old, r.new)
+					if err != nil {
+						log.Error("computing reorg ops failed: ", err)
+						continue
+					}
 				}
 
 				cs.journal.RecordEvent(cs.evtTypes[evtTypeHeadChange], func() interface{} {
@@ -504,6 +529,31 @@ func (cs *ChainStore) SetHead(ts *types.TipSet) error {
 	return cs.takeHeaviestTipSet(context.TODO(), ts)
 }
 
+// SetTrustedHead makes ts the head when the tipsets between the current head
+// and ts aren't available, as when syncing from a trusted checkpoint. Head
+// change subscribers see ts applied, without the tipsets in between and
+// without reverts.
+func (cs *ChainStore) SetTrustedHead(ts *types.TipSet) error {
+	cs.heaviestLk.Lock()
+	defer cs.heaviestLk.Unlock()
+
+	if cs.heaviest != nil {
+		cs.reorgCh <- reorg{
+			old:  cs.heaviest,
+			new:  ts,
+			jump: true,
+		}
+	}
+
+	log.Infof("Jumping to trusted tipset %s (height=%d)", ts.Cids(), ts.Height())
+	cs.heaviest = ts
+
+	if err := cs.writeHead(ts); err != nil {
+		return xerrors.Errorf("writing chain head: %w", err)
+	}
+	return nil
+}
+
 // Contains returns whether our BlockStore has all blocks in the supplied TipSet.
 func (cs *ChainStore) Contains(ts *types.TipSet) (bool, error) {
 	for _, c := range ts.Cids() {
@@ -1193,7 +1243,7 @@ func (cs *ChainStore) GetTipsetByHeight(ctx context.Context, h abi.ChainEpoch, t
 		return ts, nil
 	}
 
-	lbts, err := cs.cindex.GetTipsetByHeight(ctx, ts, h)
+	lbts, err := cs.lookbackTipset(ctx, ts, h)
 	if err != nil {
 		return nil, err
 	}
@@ -1213,6 +1263,24 @@ func (cs *ChainStore) GetTipsetByHeight(ctx context.Context, h abi.ChainEpoch, t
 	return cs.LoadTipSet(lbts.Parents())
 }
 
+// lookbackTipset returns the first tipset at or above height h on the chain of
+// ts, using the height index when it covers ts.
+func (cs *ChainStore) lookbackTipset(ctx context.Context, ts *types.TipSet, h abi.ChainEpoch) (*types.TipSet, error) {
+	k, ok, err := cs.heights.lookup(ts, h)
+	if err != nil {
+		log.Warnf("height index lookup failed: %s", err)
+	}
+	if ok {
+		lbts, err := cs.LoadTipSet(k)
+		if err == nil {
+			return lbts, nil
+		}
+		log.Warnf("loading tipset from height index: %s", err)
+	}
+
+	return cs.cindex.GetTipsetByHeight(ctx, ts, h)
+}
+
 func recurseLinks(bs bstore.Blockstore, walked *cid.Set, root cid.Cid, in []cid.Cid) ([]cid.Cid, error) {
 	if root.Prefix().Codec != cid.DagCBOR {
 		return in, nil
@@ -1274,13 +1342,16 @@ func (cs *ChainStore) Export(ctx context.Context, ts *types.TipSet, inclRecentRo
 }
 
 func (cs *ChainStore) WalkSnapshot(ctx context.Context, ts *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs bool, cb func(cid.Cid) error) error {
+	return cs.walkSnapshot(ctx, ts, inclRecentRoots, skipOldMsgs, cid.NewSet(), cid.NewSet(), cb)
+}
+
+// walkSnapshot walks the snapshot objects, skipping (and not descending into)
+// objects already in seen or walked.
+func (cs *ChainStore) walkSnapshot(ctx context.Context, ts *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs bool, seen, walked *cid.Set, cb func(cid.Cid) error) error {
 	if ts == nil {
 		ts = cs.GetHeaviestTipSet()
 	}
 
-	seen := cid.NewSet()
-	walked := cid.NewSet()
-
 	blocksToWalk := ts.Cids()
 	currentMinHeight := ts.Height()
 
@@ -1382,6 +1453,8 @@ func (cs *ChainStore) Import(r io.Reader) (*types.TipSet, error) {
 		return nil, xerrors.Errorf("failed to load root tipset from chainfile: %w", err)
 	}
 
+	cs.heights.notify(root)
+
 	return root, nil
 }
 
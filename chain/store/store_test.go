@@ -3,17 +3,23 @@ package store_test
 import (
 	"bytes"
 	"context"
+	"io"
 	"testing"
 
+	"github.com/ipfs/go-cid"
 	datastore "github.com/ipfs/go-datastore"
+	car "github.com/ipld/go-car"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/crypto"
 
+	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/actors/policy"
 	"github.com/filecoin-project/lotus/chain/gen"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
 	"github.com/filecoin-project/lotus/lib/blockstore"
 	"github.com/filecoin-project/lotus/node/repo"
 )
@@ -107,3 +113,401 @@ func TestChainExportImport(t *testing.T) {
 		t.Fatal("imported chain differed from exported chain")
 	}
 }
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, xerrors.New("connection lost")
+}
+
+func TestChainImportStreamResume(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last *types.TipSet
+	for i := 0; i < 30; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last = ts.TipSet.TipSet()
+	}
+
+	buf := new(bytes.Buffer)
+	if err := cg.ChainStore().Export(context.TODO(), last, 0, false, buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	nbs := blockstore.NewTemporary()
+	cs := store.NewChainStore(nbs, datastore.NewMapDatastore(), nil, nil)
+
+	if _, err := cs.ImportStream(context.TODO(), bytes.NewReader(data), store.ImportOpts{
+		Source:        "test",
+		ExpectedRoots: []cid.Cid{cg.Genesis().Cid()},
+	}); err == nil {
+		t.Fatal("expected import with the wrong root to fail")
+	}
+
+	// interrupt the import half way through
+	opts := store.ImportOpts{
+		Source:        "test",
+		ExpectedRoots: last.Cids(),
+		VerifyBlocks:  true,
+		BatchSize:     16,
+	}
+	interrupted := io.MultiReader(bytes.NewReader(data[:len(data)/2]), errReader{})
+	if _, err := cs.ImportStream(context.TODO(), interrupted, opts); err == nil {
+		t.Fatal("expected truncated import to fail")
+	}
+
+	cp, err := cs.GetImportCheckpoint("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp == nil || cp.Offset == 0 || cp.Offset > uint64(len(data)/2) {
+		t.Fatalf("bad checkpoint after interrupted import: %+v", cp)
+	}
+
+	if other, err := cs.GetImportCheckpoint("other"); err != nil || other != nil {
+		t.Fatalf("expected no checkpoint for another source, got %+v (err: %v)", other, err)
+	}
+
+	opts.Resume = cp
+	root, err := cs.ImportStream(context.TODO(), bytes.NewReader(data[cp.Offset:]), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.Equals(last) {
+		t.Fatal("imported chain differed from exported chain")
+	}
+
+	if cp, err := cs.GetImportCheckpoint("test"); err != nil || cp != nil {
+		t.Fatalf("expected checkpoint to be cleared, got %+v (err: %v)", cp, err)
+	}
+}
+
+func TestChainExportIncremental(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tss []*types.TipSet
+	for i := 0; i < 60; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tss = append(tss, ts.TipSet.TipSet())
+	}
+
+	cs := cg.ChainStore()
+	base, last := tss[39], tss[len(tss)-1]
+
+	full := new(bytes.Buffer)
+	if err := cs.Export(context.TODO(), base, 5, false, full); err != nil {
+		t.Fatal(err)
+	}
+
+	inc := new(bytes.Buffer)
+	if err := cs.ExportIncremental(context.TODO(), last, base, 5, false, inc); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cs.ExportIncremental(context.TODO(), base, last, 5, false, new(bytes.Buffer)); err == nil {
+		t.Fatal("expected exporting against a descendant to fail")
+	}
+
+	nbs := blockstore.NewTemporary()
+	ncs := store.NewChainStore(nbs, datastore.NewMapDatastore(), nil, nil)
+
+	root, err := ncs.Import(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.Equals(base) {
+		t.Fatal("imported chain differed from exported chain")
+	}
+
+	root, err = ncs.ImportIncrement(context.TODO(), inc, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.Equals(last) {
+		t.Fatal("imported increment root differed from exported tipset")
+	}
+
+	// the result must contain everything the full snapshot of last would
+	err = cs.WalkSnapshot(context.TODO(), last, 5, false, func(c cid.Cid) error {
+		has, err := nbs.Has(c)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return xerrors.Errorf("object %s missing after applying increment", c)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChainExportRange(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tss []*types.TipSet
+	for i := 0; i < 40; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tss = append(tss, ts.TipSet.TipSet())
+	}
+
+	cs := cg.ChainStore()
+	last := tss[len(tss)-1]
+	start := tss[20].Height()
+
+	buf := new(bytes.Buffer)
+	opts := api.ChainExportRangeOpts{Headers: true, Messages: true}
+	if err := cs.ExportRange(context.TODO(), last, start, opts, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	nbs := blockstore.NewTemporary()
+	hdr, err := car.LoadCar(nbs, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.NewTipSetKey(hdr.Roots...) != last.Key() {
+		t.Fatal("car roots differ from the exported tipset")
+	}
+
+	ncs := store.NewChainStore(nbs, datastore.NewMapDatastore(), nil, nil)
+	for _, ts := range tss {
+		for _, b := range ts.Blocks() {
+			has, err := nbs.Has(b.Cid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if has != (ts.Height() >= start) {
+				t.Fatalf("block at height %d: in export %t, start %d", ts.Height(), has, start)
+			}
+
+			if ts.Height() < start {
+				continue
+			}
+			if _, _, err := ncs.ReadMsgMetaCids(b.Messages); err != nil {
+				t.Fatalf("messages of block at height %d missing: %s", ts.Height(), err)
+			}
+			if has, err := nbs.Has(b.ParentStateRoot); err != nil || has {
+				t.Fatalf("state root at height %d should not be exported (err: %v)", ts.Height(), err)
+			}
+		}
+	}
+}
+
+func TestChainPruneStates(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var old, last *types.TipSet
+	for i := 0; i < 40; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last = ts.TipSet.TipSet()
+		if last.Height() == 5 {
+			old = last
+		}
+	}
+
+	cs := cg.ChainStore()
+	bs := cs.Blockstore()
+
+	dry, err := cs.PruneStates(context.TODO(), last, 10, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Objects == 0 || dry.Bytes == 0 {
+		t.Fatal("expected dry run to find prunable state")
+	}
+	if has, _ := bs.Has(old.ParentState()); !has {
+		t.Fatal("dry run must not delete anything")
+	}
+
+	res, err := cs.PruneStates(context.TODO(), last, 10, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Objects != dry.Objects || res.Bytes != dry.Bytes {
+		t.Fatalf("pruned %d objects (%d bytes), dry run reported %d (%d bytes)", res.Objects, res.Bytes, dry.Objects, dry.Bytes)
+	}
+
+	if has, _ := bs.Has(old.ParentState()); has {
+		t.Fatal("old state root should have been pruned")
+	}
+	if has, _ := bs.Has(old.Cids()[0]); !has {
+		t.Fatal("block headers must be preserved")
+	}
+	if has, _ := bs.Has(last.ParentState()); !has {
+		t.Fatal("recent state must be preserved")
+	}
+
+	again, err := cs.PruneStates(context.TODO(), last, 10, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Objects != 0 {
+		t.Fatalf("expected nothing left to prune, got %d objects", again.Objects)
+	}
+}
+
+func TestChainReorgJournal(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fork, last *types.TipSet
+	var dropped int
+	for i := 0; i < 10; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last = ts.TipSet.TipSet()
+		if i == 6 {
+			fork = last
+		}
+		if i > 6 {
+			dropped += len(last.Blocks())
+		}
+	}
+
+	cs := cg.ChainStore()
+	if err := cs.SetHead(last); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := cs.SubReorgs(ctx)
+
+	if err := cs.SetHead(fork); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-sub
+	if r.Depth != 3 || r.ForkHeight != fork.Height() || r.FromHeight != last.Height() || r.To != fork.Key() {
+		t.Fatalf("unexpected reorg: %+v", r)
+	}
+	if len(r.DroppedBlocks) != dropped {
+		t.Fatalf("expected %d dropped blocks, got %d", dropped, len(r.DroppedBlocks))
+	}
+
+	reorgs, err := cs.GetReorgs(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reorgs) != 1 || reorgs[0].Seq != r.Seq {
+		t.Fatalf("expected the reorg to be journaled, got %+v", reorgs)
+	}
+
+	reorgs, err = cs.GetReorgs(fork.Height() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reorgs) != 0 {
+		t.Fatalf("expected no reorgs above the fork, got %d", len(reorgs))
+	}
+
+	// revert to the fork's parent, the journal is returned oldest first
+	parent, err := cs.LoadTipSet(fork.Parents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SetHead(parent); err != nil {
+		t.Fatal(err)
+	}
+	r2 := <-sub
+
+	reorgs, err = cs.GetReorgs(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reorgs) != 2 || reorgs[0].Seq != r.Seq || reorgs[1].Seq != r2.Seq {
+		t.Fatalf("expected both reorgs oldest first, got %+v", reorgs)
+	}
+
+	// the older reorg to the fork is still returned after the newer one to a
+	// lower height
+	reorgs, err = cs.GetReorgs(fork.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reorgs) != 1 || reorgs[0].Seq != r.Seq {
+		t.Fatalf("expected the reorg to the fork, got %+v", reorgs)
+	}
+}
+
+func TestSetTrustedHead(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last *types.TipSet
+	for i := 0; i < 10; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = ts.TipSet.TipSet()
+	}
+
+	// only the genesis and the trusted tipset are available
+	nbs := blockstore.NewTemporary()
+	if err := vm.Copy(context.TODO(), cg.ChainStore().Blockstore(), nbs, cg.Genesis().ParentStateRoot); err != nil {
+		t.Fatal(err)
+	}
+	cs := store.NewChainStore(nbs, datastore.NewMapDatastore(), nil, nil)
+	if err := cs.PersistBlockHeaders(append([]*types.BlockHeader{cg.Genesis()}, last.Blocks()...)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SetGenesis(cg.Genesis()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := cs.SubHeadChanges(ctx)
+	<-sub // current
+
+	if err := cs.SetTrustedHead(last); err != nil {
+		t.Fatal(err)
+	}
+	if !cs.GetHeaviestTipSet().Equals(last) {
+		t.Fatal("trusted tipset isn't the head")
+	}
+
+	changes := <-sub
+	if len(changes) != 1 || changes[0].Type != store.HCApply || !changes[0].Val.Equals(last) {
+		t.Fatalf("expected only the trusted tipset to be applied, got %+v", changes)
+	}
+}
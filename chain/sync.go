@@ -46,6 +46,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/exchange"
 	"github.com/filecoin-project/lotus/chain/gen"
 	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/statefetch"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
@@ -111,6 +112,9 @@ type Syncer struct {
 	// TipSets known to be invalid
 	bad *BadBlockCache
 
+	// peers whose heads aren't synced to
+	blocked *blockedPeers
+
 	// handle to the block sync service
 	Exchange exchange.Client
 
@@ -131,6 +135,12 @@ type Syncer struct {
 	checkptLk sync.Mutex
 
 	checkpt types.TipSetKey
+	static  []staticCheckpoint
+	// the checkpoint of the lightweight sync mode, also in static
+	trusted *staticCheckpoint
+
+	// fetches the state to execute on when it isn't present locally
+	stateFetcher *statefetch.Fetcher
 
 	ds dtypes.MetadataDS
 }
@@ -159,6 +169,7 @@ func NewSyncer(ds dtypes.MetadataDS, sm *stmgr.StateManager, exchange exchange.C
 		checkpt:        cp,
 		beacon:         beacon,
 		bad:            NewBadBlockCache(),
+		blocked:        newBlockedPeers(),
 		Genesis:        gent,
 		Exchange:       exchange,
 		store:          sm.ChainStore(),
@@ -234,6 +245,11 @@ func (syncer *Syncer) InformNewHead(from peer.ID, fts *store.FullTipSet) bool {
 		return false
 	}
 
+	if reason, ok := syncer.blocked.get(from); ok {
+		log.Debugf("ignoring head %s from blocked peer %s (reason: %s)", fts.TipSet().Cids(), from, reason.Reason)
+		return false
+	}
+
 	for _, b := range fts.Blocks {
 		if reason, ok := syncer.bad.Has(b.Cid()); ok {
 			log.Warnf("InformNewHead called on block marked as bad: %s (reason: %s)", b.Cid(), reason)
@@ -733,9 +749,9 @@ func (syncer *Syncer) ValidateBlock(ctx context.Context, b *types.FullBlock, use
 		return xerrors.Errorf("failed to get lookback tipset for block: %w", err)
 	}
 
-	lbst, _, err := syncer.sm.TipSetState(ctx, lbts)
+	lbst, err := syncer.lookbackState(ctx, baseTs, lbts)
 	if err != nil {
-		return xerrors.Errorf("failed to compute lookback tipset state (epoch %d): %w", lbts.Height(), err)
+		return err
 	}
 
 	prevBeacon, err := syncer.store.GetLatestBeaconEntry(baseTs)
@@ -764,24 +780,6 @@ func (syncer *Syncer) ValidateBlock(ctx context.Context, b *types.FullBlock, use
 		return nil
 	})
 
-	minerCheck := async.Err(func() error {
-		if err := syncer.minerIsValid(ctx, h.Miner, baseTs); err != nil {
-			return xerrors.Errorf("minerIsValid failed: %w", err)
-		}
-		return nil
-	})
-
-	baseFeeCheck := async.Err(func() error {
-		baseFee, err := syncer.store.ComputeBaseFee(ctx, baseTs)
-		if err != nil {
-			return xerrors.Errorf("computing base fee: %w", err)
-		}
-		if types.BigCmp(baseFee, b.Header.ParentBaseFee) != 0 {
-			return xerrors.Errorf("base fee doesn't match: %s (header) != %s (computed)",
-				b.Header.ParentBaseFee, baseFee)
-		}
-		return nil
-	})
 	pweight, err := syncer.store.Weight(ctx, baseTs)
 	if err != nil {
 		return xerrors.Errorf("getting parent weight: %w", err)
@@ -826,122 +824,9 @@ func (syncer *Syncer) ValidateBlock(ctx context.Context, b *types.FullBlock, use
 		return xerrors.Errorf("GetMinerWorkerRaw failed: %w", err)
 	}
 
-	winnerCheck := async.Err(func() error {
-		if h.ElectionProof.WinCount < 1 {
-			return xerrors.Errorf("block is not claiming to be a winner")
-		}
-
-		eligible, err := stmgr.MinerEligibleToMine(ctx, syncer.sm, h.Miner, baseTs, lbts)
-		if err != nil {
-			return xerrors.Errorf("determining if miner has min power failed: %w", err)
-		}
-
-		if !eligible {
-			return xerrors.New("block's miner is ineligible to mine")
-		}
-
-		rBeacon := *prevBeacon
-		if len(h.BeaconEntries) != 0 {
-			rBeacon = h.BeaconEntries[len(h.BeaconEntries)-1]
-		}
-		buf := new(bytes.Buffer)
-		if err := h.Miner.MarshalCBOR(buf); err != nil {
-			return xerrors.Errorf("failed to marshal miner address to cbor: %w", err)
-		}
-
-		vrfBase, err := store.DrawRandomness(rBeacon.Data, crypto.DomainSeparationTag_ElectionProofProduction, h.Height, buf.Bytes())
-		if err != nil {
-			return xerrors.Errorf("could not draw randomness: %w", err)
-		}
-
-		if err := VerifyElectionPoStVRF(ctx, waddr, vrfBase, h.ElectionProof.VRFProof); err != nil {
-			return xerrors.Errorf("validating block election proof failed: %w", err)
-		}
-
-		slashed, err := stmgr.GetMinerSlashed(ctx, syncer.sm, baseTs, h.Miner)
-		if err != nil {
-			return xerrors.Errorf("failed to check if block miner was slashed: %w", err)
-		}
-
-		if slashed {
-			return xerrors.Errorf("received block was from slashed or invalid miner")
-		}
-
-		mpow, tpow, _, err := stmgr.GetPowerRaw(ctx, syncer.sm, lbst, h.Miner)
-		if err != nil {
-			return xerrors.Errorf("failed getting power: %w", err)
-		}
-
-		j := h.ElectionProof.ComputeWinCount(mpow.QualityAdjPower, tpow.QualityAdjPower)
-		if h.ElectionProof.WinCount != j {
-			return xerrors.Errorf("miner claims wrong number of wins: miner: %d, computed: %d", h.ElectionProof.WinCount, j)
-		}
-
-		return nil
-	})
-
-	blockSigCheck := async.Err(func() error {
-		if err := sigs.CheckBlockSignature(ctx, h, waddr); err != nil {
-			return xerrors.Errorf("check block signature failed: %w", err)
-		}
-		return nil
-	})
-
-	beaconValuesCheck := async.Err(func() error {
-		if os.Getenv("LOTUS_IGNORE_DRAND") == "_yes_" {
-			return nil
-		}
-
-		if err := beacon.ValidateBlockValues(syncer.beacon, h, baseTs.Height(), *prevBeacon); err != nil {
-			return xerrors.Errorf("failed to validate blocks random beacon values: %w", err)
-		}
-		return nil
-	})
-
-	tktsCheck := async.Err(func() error {
-		buf := new(bytes.Buffer)
-		if err := h.Miner.MarshalCBOR(buf); err != nil {
-			return xerrors.Errorf("failed to marshal miner address to cbor: %w", err)
-		}
-
-		if h.Height > build.UpgradeSmokeHeight {
-			buf.Write(baseTs.MinTicket().VRFProof)
-		}
-
-		beaconBase := *prevBeacon
-		if len(h.BeaconEntries) != 0 {
-			beaconBase = h.BeaconEntries[len(h.BeaconEntries)-1]
-		}
-
-		vrfBase, err := store.DrawRandomness(beaconBase.Data, crypto.DomainSeparationTag_TicketProduction, h.Height-build.TicketRandomnessLookback, buf.Bytes())
-		if err != nil {
-			return xerrors.Errorf("failed to compute vrf base for ticket: %w", err)
-		}
-
-		err = VerifyElectionPoStVRF(ctx, waddr, vrfBase, h.Ticket.VRFProof)
-		if err != nil {
-			return xerrors.Errorf("validating block tickets failed: %w", err)
-		}
-		return nil
-	})
-
-	wproofCheck := async.Err(func() error {
-		if err := syncer.VerifyWinningPoStProof(ctx, h, *prevBeacon, lbst, waddr); err != nil {
-			return xerrors.Errorf("invalid election post: %w", err)
-		}
-		return nil
-	})
-
-	await := []async.ErrorFuture{
-		minerCheck,
-		tktsCheck,
-		blockSigCheck,
-		beaconValuesCheck,
-		wproofCheck,
-		winnerCheck,
-		msgsCheck,
-		baseFeeCheck,
-		stateRootCheck,
+	await := []async.ErrorFuture{msgsCheck, stateRootCheck}
+	for _, c := range syncer.headerChecks(ctx, h, baseTs, lbts, lbst, prevBeacon, waddr) {
+		await = append(await, async.Err(c.check))
 	}
 
 	var merr error
@@ -978,6 +863,228 @@ func (syncer *Syncer) ValidateBlock(ctx context.Context, b *types.FullBlock, use
 	return nil
 }
 
+// ValidateBlockHeader runs the checks of ValidateBlock which only need the
+// block header: sanity, timestamp, parent weight, base fee, miner eligibility,
+// ticket, election proof, beacon entries, winning PoSt and signature. Messages
+// and the parent state root are not checked. The parent tipset must be known
+// locally.
+//
+// An error is only returned if the checks couldn't be run; failed checks are
+// reported in the result.
+func (syncer *Syncer) ValidateBlockHeader(ctx context.Context, h *types.BlockHeader) (*api.BlockHeaderValidation, error) {
+	res := &api.BlockHeaderValidation{
+		Block: h.Cid(),
+		Valid: true,
+	}
+	report := func(name string, err error) {
+		c := api.BlockHeaderCheck{Name: name}
+		if err != nil {
+			c.Error = err.Error()
+			res.Valid = false
+		}
+		res.Checks = append(res.Checks, c)
+	}
+
+	if err := blockSanityChecks(h); err != nil {
+		report("sanity", err)
+		return res, nil
+	}
+	report("sanity", nil)
+
+	baseTs, err := syncer.store.LoadTipSet(types.NewTipSetKey(h.Parents...))
+	if err != nil {
+		return nil, xerrors.Errorf("load parent tipset failed (%s): %w", h.Parents, err)
+	}
+
+	lbts, err := stmgr.GetLookbackTipSetForRound(ctx, syncer.sm, baseTs, h.Height)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get lookback tipset for block: %w", err)
+	}
+
+	lbst, err := syncer.lookbackState(ctx, baseTs, lbts)
+	if err != nil {
+		return nil, err
+	}
+
+	prevBeacon, err := syncer.store.GetLatestBeaconEntry(baseTs)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get latest beacon entry: %w", err)
+	}
+
+	waddr, err := stmgr.GetMinerWorkerRaw(ctx, syncer.sm, lbst, h.Miner)
+	if err != nil {
+		return nil, xerrors.Errorf("GetMinerWorkerRaw failed: %w", err)
+	}
+
+	checks := []headerCheck{
+		{"timestamp", func() error {
+			nulls := h.Height - (baseTs.Height() + 1)
+			if tgtTs := baseTs.MinTimestamp() + build.BlockDelaySecs*uint64(nulls+1); h.Timestamp != tgtTs {
+				return xerrors.Errorf("block has wrong timestamp: %d != %d", h.Timestamp, tgtTs)
+			}
+
+			now := uint64(build.Clock.Now().Unix())
+			if h.Timestamp > now+build.AllowableClockDriftSecs {
+				return xerrors.Errorf("block was from the future (now=%d, blk=%d): %w", now, h.Timestamp, ErrTemporal)
+			}
+			return nil
+		}},
+		{"parent-weight", func() error {
+			pweight, err := syncer.store.Weight(ctx, baseTs)
+			if err != nil {
+				return xerrors.Errorf("getting parent weight: %w", err)
+			}
+
+			if types.BigCmp(pweight, h.ParentWeight) != 0 {
+				return xerrors.Errorf("parent weight different: %s (header) != %s (computed)",
+					h.ParentWeight, pweight)
+			}
+			return nil
+		}},
+	}
+	checks = append(checks, syncer.headerChecks(ctx, h, baseTs, lbts, lbst, prevBeacon, waddr)...)
+
+	futs := make([]async.ErrorFuture, len(checks))
+	for i, c := range checks {
+		futs[i] = async.Err(c.check)
+	}
+	for i, fut := range futs {
+		report(checks[i].name, fut.AwaitContext(ctx))
+	}
+
+	return res, nil
+}
+
+type headerCheck struct {
+	name  string
+	check func() error
+}
+
+// headerChecks returns the checks of ValidateBlock which only depend on the
+// block header, its parent tipset and the lookback state.
+func (syncer *Syncer) headerChecks(ctx context.Context, h *types.BlockHeader, baseTs, lbts *types.TipSet, lbst cid.Cid, prevBeacon *types.BeaconEntry, waddr address.Address) []headerCheck {
+	return []headerCheck{
+		{"miner", func() error {
+			if err := syncer.minerIsValid(ctx, h.Miner, baseTs); err != nil {
+				return xerrors.Errorf("minerIsValid failed: %w", err)
+			}
+			return nil
+		}},
+		{"ticket", func() error {
+			buf := new(bytes.Buffer)
+			if err := h.Miner.MarshalCBOR(buf); err != nil {
+				return xerrors.Errorf("failed to marshal miner address to cbor: %w", err)
+			}
+
+			if h.Height > build.UpgradeSmokeHeight {
+				buf.Write(baseTs.MinTicket().VRFProof)
+			}
+
+			beaconBase := *prevBeacon
+			if len(h.BeaconEntries) != 0 {
+				beaconBase = h.BeaconEntries[len(h.BeaconEntries)-1]
+			}
+
+			vrfBase, err := store.DrawRandomness(beaconBase.Data, crypto.DomainSeparationTag_TicketProduction, h.Height-build.TicketRandomnessLookback, buf.Bytes())
+			if err != nil {
+				return xerrors.Errorf("failed to compute vrf base for ticket: %w", err)
+			}
+
+			err = VerifyElectionPoStVRF(ctx, waddr, vrfBase, h.Ticket.VRFProof)
+			if err != nil {
+				return xerrors.Errorf("validating block tickets failed: %w", err)
+			}
+			return nil
+		}},
+		{"signature", func() error {
+			if err := sigs.CheckBlockSignature(ctx, h, waddr); err != nil {
+				return xerrors.Errorf("check block signature failed: %w", err)
+			}
+			return nil
+		}},
+		{"beacon", func() error {
+			if os.Getenv("LOTUS_IGNORE_DRAND") == "_yes_" {
+				return nil
+			}
+
+			if err := beacon.ValidateBlockValues(syncer.beacon, h, baseTs.Height(), *prevBeacon); err != nil {
+				return xerrors.Errorf("failed to validate blocks random beacon values: %w", err)
+			}
+			return nil
+		}},
+		{"winning-post", func() error {
+			if err := syncer.VerifyWinningPoStProof(ctx, h, *prevBeacon, lbst, waddr); err != nil {
+				return xerrors.Errorf("invalid election post: %w", err)
+			}
+			return nil
+		}},
+		{"election", func() error {
+			if h.ElectionProof.WinCount < 1 {
+				return xerrors.Errorf("block is not claiming to be a winner")
+			}
+
+			eligible, err := stmgr.MinerEligibleToMine(ctx, syncer.sm, h.Miner, baseTs, lbts)
+			if err != nil {
+				return xerrors.Errorf("determining if miner has min power failed: %w", err)
+			}
+
+			if !eligible {
+				return xerrors.New("block's miner is ineligible to mine")
+			}
+
+			rBeacon := *prevBeacon
+			if len(h.BeaconEntries) != 0 {
+				rBeacon = h.BeaconEntries[len(h.BeaconEntries)-1]
+			}
+			buf := new(bytes.Buffer)
+			if err := h.Miner.MarshalCBOR(buf); err != nil {
+				return xerrors.Errorf("failed to marshal miner address to cbor: %w", err)
+			}
+
+			vrfBase, err := store.DrawRandomness(rBeacon.Data, crypto.DomainSeparationTag_ElectionProofProduction, h.Height, buf.Bytes())
+			if err != nil {
+				return xerrors.Errorf("could not draw randomness: %w", err)
+			}
+
+			if err := VerifyElectionPoStVRF(ctx, waddr, vrfBase, h.ElectionProof.VRFProof); err != nil {
+				return xerrors.Errorf("validating block election proof failed: %w", err)
+			}
+
+			slashed, err := stmgr.GetMinerSlashed(ctx, syncer.sm, baseTs, h.Miner)
+			if err != nil {
+				return xerrors.Errorf("failed to check if block miner was slashed: %w", err)
+			}
+
+			if slashed {
+				return xerrors.Errorf("received block was from slashed or invalid miner")
+			}
+
+			mpow, tpow, _, err := stmgr.GetPowerRaw(ctx, syncer.sm, lbst, h.Miner)
+			if err != nil {
+				return xerrors.Errorf("failed getting power: %w", err)
+			}
+
+			j := h.ElectionProof.ComputeWinCount(mpow.QualityAdjPower, tpow.QualityAdjPower)
+			if h.ElectionProof.WinCount != j {
+				return xerrors.Errorf("miner claims wrong number of wins: miner: %d, computed: %d", h.ElectionProof.WinCount, j)
+			}
+
+			return nil
+		}},
+		{"base-fee", func() error {
+			baseFee, err := syncer.store.ComputeBaseFee(ctx, baseTs)
+			if err != nil {
+				return xerrors.Errorf("computing base fee: %w", err)
+			}
+			if types.BigCmp(baseFee, h.ParentBaseFee) != 0 {
+				return xerrors.Errorf("base fee doesn't match: %s (header) != %s (computed)",
+					h.ParentBaseFee, baseFee)
+			}
+			return nil
+		}},
+	}
+}
+
 func (syncer *Syncer) VerifyWinningPoStProof(ctx context.Context, h *types.BlockHeader, prevBeacon types.BeaconEntry, lbst cid.Cid, waddr address.Address) error {
 	if build.InsecurePoStValidation {
 		if len(h.WinPoStProof) == 0 {
@@ -1304,6 +1411,7 @@ func (syncer *Syncer) collectHeaders(ctx context.Context, incoming *types.TipSet
 	untilHeight := known.Height() + 1
 
 	ss.SetHeight(blockSet[len(blockSet)-1].Height())
+	ss.SetHeadersFetched(uint64(len(blockSet)))
 
 	var acceptedBlocks []cid.Cid
 
@@ -1390,6 +1498,7 @@ loop:
 		acceptedBlocks = append(acceptedBlocks, at.Cids()...)
 
 		ss.SetHeight(blks[len(blks)-1].Height())
+		ss.SetHeadersFetched(uint64(len(blockSet)))
 		at = blks[len(blks)-1].Parents()
 	}
 
@@ -1485,10 +1594,58 @@ func (syncer *Syncer) syncFork(ctx context.Context, incoming *types.TipSet, know
 	return nil, ErrForkTooLong
 }
 
+// SetStateFetcher sets the fetcher used to get the state the synced chain
+// builds on when it's missing locally, e.g. after importing a snapshot
+// without state.
+func (syncer *Syncer) SetStateFetcher(f *statefetch.Fetcher) {
+	syncer.stateFetcher = f
+}
+
+// ensureState fetches the state tree under root if it isn't complete locally.
+func (syncer *Syncer) ensureState(ctx context.Context, root cid.Cid) error {
+	if syncer.stateFetcher == nil {
+		return nil
+	}
+
+	complete, err := syncer.stateFetcher.Complete(root)
+	if err != nil {
+		return err
+	}
+	if complete {
+		return nil
+	}
+
+	ss := extractSyncState(ctx)
+	ss.SetStage(api.StageFetchingState)
+	defer ss.SetStage(api.StageMessages)
+
+	log.Infow("fetching missing state", "root", root)
+	st, err := syncer.stateFetcher.Fetch(ctx, root)
+	if err != nil {
+		return xerrors.Errorf("fetching state %s: %w", root, err)
+	}
+
+	log.Infow("fetched state", "root", root, "fetched", st.Fetched, "bytes", st.FetchedBytes, "local", st.Local, "took", st.Duration)
+	for _, p := range st.Peers {
+		log.Debugw("state fetch peer throughput", "peer", p.Peer, "bytes", p.Bytes, "bytesPerSec", p.BytesPerSecond)
+	}
+	return nil
+}
+
 func (syncer *Syncer) syncMessagesAndCheckState(ctx context.Context, headers []*types.TipSet) error {
 	ss := extractSyncState(ctx)
 	ss.SetHeight(headers[len(headers)-1].Height())
 
+	// validating the first tipset executes the messages of its parent, which
+	// is already local, on the parent state of the parent
+	base, err := syncer.store.LoadTipSet(headers[len(headers)-1].Parents())
+	if err != nil {
+		return xerrors.Errorf("loading base tipset: %w", err)
+	}
+	if err := syncer.ensureState(ctx, base.ParentState()); err != nil {
+		return err
+	}
+
 	return syncer.iterFullTipsets(ctx, headers, func(ctx context.Context, fts *store.FullTipSet) error {
 		log.Debugw("validating tipset", "height", fts.TipSet().Height(), "size", len(fts.TipSet().Cids()))
 		if err := syncer.ValidateTipSet(ctx, fts, true); err != nil {
@@ -1498,6 +1655,7 @@ func (syncer *Syncer) syncMessagesAndCheckState(ctx context.Context, headers []*
 
 		stats.Record(ctx, metrics.ChainNodeWorkerHeight.M(int64(fts.TipSet().Height())))
 		ss.SetHeight(fts.TipSet().Height())
+		ss.AddValidated(1)
 
 		return nil
 	})
@@ -1537,6 +1695,7 @@ func (syncer *Syncer) iterFullTipsets(ctx context.Context, headers []*types.TipS
 		if batchErr != nil {
 			return xerrors.Errorf("failed to fetch messages: %w", batchErr)
 		}
+		ss.AddMessagesFetched(uint64(len(bstout)))
 
 		for bsi := 0; bsi < len(bstout); bsi++ {
 			// temp storage so we don't persist data we dont want to
@@ -1689,12 +1848,28 @@ func (syncer *Syncer) collectChain(ctx context.Context, ts *types.TipSet) error
 
 	ss.Init(syncer.store.GetHeaviestTipSet(), ts)
 
-	headers, err := syncer.collectHeaders(ctx, ts, syncer.store.GetHeaviestTipSet())
+	known, err := syncer.trustedBase(ctx, ts)
+	if err != nil {
+		ss.Error(err)
+		return err
+	}
+	ss.SetStage(api.StageHeaders)
+
+	headers, err := syncer.collectHeaders(ctx, ts, known)
 	if err != nil {
 		ss.Error(err)
 		return err
 	}
 
+	if err := syncer.checkStaticCheckpoints(headers); err != nil {
+		log.Warn("adding chain conflicting with a checkpoint to our bad tipset cache")
+		for _, b := range ts.Blocks() {
+			syncer.bad.Add(b.Cid(), NewBadBlockReason(ts.Cids(), "conflicts with checkpoint"))
+		}
+		ss.Error(err)
+		return err
+	}
+
 	span.AddAttributes(trace.Int64Attribute("syncChainLength", int64(len(headers))))
 
 	if !headers[0].Equals(ts) {
@@ -1758,6 +1933,20 @@ func (syncer *Syncer) CheckBadBlockCache(blk cid.Cid) (string, bool) {
 	return bbr.String(), ok
 }
 
+// ListBad returns the blocks in the bad block cache with the reasons they were
+// marked bad.
+func (syncer *Syncer) ListBad() []api.BadBlock {
+	entries := syncer.bad.Entries()
+	out := make([]api.BadBlock, 0, len(entries))
+	for c, bbr := range entries {
+		out = append(out, api.BadBlock{Block: c, Reason: bbr.String()})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Block.String() < out[j].Block.String()
+	})
+	return out
+}
+
 func (syncer *Syncer) getLatestBeaconEntry(_ context.Context, ts *types.TipSet) (*types.BeaconEntry, error) {
 	cur := ts
 	for i := 0; i < 20; i++ {
@@ -463,6 +463,38 @@ func TestSyncBadTimestamp(t *testing.T) {
 	}
 }
 
+func TestSyncValidateBlockHeader(t *testing.T) {
+	H := 10
+	tu := prepSyncTest(t, H)
+
+	head := tu.getHead(0)
+	h := *head.Blocks()[0]
+
+	res, err := tu.nds[0].SyncValidateBlockHeader(tu.ctx, &h)
+	require.NoError(t, err)
+	require.True(t, res.Valid, "%+v", res.Checks)
+	require.Equal(t, h.Cid(), res.Block)
+
+	// decoded, so that it doesn't carry the validated signature of h
+	raw, err := h.Serialize()
+	require.NoError(t, err)
+	bad, err := types.DecodeBlock(raw)
+	require.NoError(t, err)
+	bad.Timestamp++
+
+	res, err = tu.nds[0].SyncValidateBlockHeader(tu.ctx, bad)
+	require.NoError(t, err)
+	require.False(t, res.Valid)
+
+	failed := map[string]bool{}
+	for _, c := range res.Checks {
+		failed[c.Name] = c.Error != ""
+	}
+	require.True(t, failed["timestamp"])
+	require.True(t, failed["signature"])
+	require.False(t, failed["ticket"])
+}
+
 type badWpp struct{}
 
 func (wpp badWpp) GenerateCandidates(context.Context, abi.PoStRandomness, uint64) ([]uint64, error) {
@@ -19,11 +19,37 @@ type SyncerStateSnapshot struct {
 	Message string
 	Start   time.Time
 	End     time.Time
+
+	HeadersFetched   uint64
+	MessagesFetched  uint64
+	TipSetsValidated uint64
+	// Stages is the time spent in each stage, including the current one
+	Stages []api.SyncStageTime
 }
 
 type SyncerState struct {
 	lk   sync.Mutex
 	data SyncerStateSnapshot
+
+	// stageStart is when the current stage was entered
+	stageStart time.Time
+}
+
+// endStage adds the time spent in the current stage to the stage timings.
+func (ss *SyncerState) endStage(now time.Time) {
+	if ss.stageStart.IsZero() {
+		return
+	}
+	elapsed := now.Sub(ss.stageStart)
+	ss.stageStart = time.Time{}
+
+	for i := range ss.data.Stages {
+		if ss.data.Stages[i].Stage == ss.data.Stage {
+			ss.data.Stages[i].Elapsed += elapsed
+			return
+		}
+	}
+	ss.data.Stages = append(ss.data.Stages, api.SyncStageTime{Stage: ss.data.Stage, Elapsed: elapsed})
 }
 
 func (ss *SyncerState) SetStage(v api.SyncStateStage) {
@@ -33,9 +59,14 @@ func (ss *SyncerState) SetStage(v api.SyncStateStage) {
 
 	ss.lk.Lock()
 	defer ss.lk.Unlock()
+
+	now := build.Clock.Now()
+	ss.endStage(now)
 	ss.data.Stage = v
 	if v == api.StageSyncComplete {
-		ss.data.End = build.Clock.Now()
+		ss.data.End = now
+	} else {
+		ss.stageStart = now
 	}
 }
 
@@ -53,6 +84,11 @@ func (ss *SyncerState) Init(base, target *types.TipSet) {
 	ss.data.Message = ""
 	ss.data.Start = build.Clock.Now()
 	ss.data.End = time.Time{}
+	ss.data.HeadersFetched = 0
+	ss.data.MessagesFetched = 0
+	ss.data.TipSetsValidated = 0
+	ss.data.Stages = nil
+	ss.stageStart = ss.data.Start
 }
 
 func (ss *SyncerState) SetHeight(h abi.ChainEpoch) {
@@ -65,6 +101,39 @@ func (ss *SyncerState) SetHeight(h abi.ChainEpoch) {
 	ss.data.Height = h
 }
 
+// SetHeadersFetched sets the number of tipset headers collected so far.
+func (ss *SyncerState) SetHeadersFetched(n uint64) {
+	if ss == nil {
+		return
+	}
+
+	ss.lk.Lock()
+	defer ss.lk.Unlock()
+	ss.data.HeadersFetched = n
+}
+
+// AddMessagesFetched counts tipsets whose messages were fetched.
+func (ss *SyncerState) AddMessagesFetched(n uint64) {
+	if ss == nil {
+		return
+	}
+
+	ss.lk.Lock()
+	defer ss.lk.Unlock()
+	ss.data.MessagesFetched += n
+}
+
+// AddValidated counts validated tipsets.
+func (ss *SyncerState) AddValidated(n uint64) {
+	if ss == nil {
+		return
+	}
+
+	ss.lk.Lock()
+	defer ss.lk.Unlock()
+	ss.data.TipSetsValidated += n
+}
+
 func (ss *SyncerState) Error(err error) {
 	if ss == nil {
 		return
@@ -72,13 +141,33 @@ func (ss *SyncerState) Error(err error) {
 
 	ss.lk.Lock()
 	defer ss.lk.Unlock()
+
+	now := build.Clock.Now()
+	ss.endStage(now)
 	ss.data.Message = err.Error()
 	ss.data.Stage = api.StageSyncErrored
-	ss.data.End = build.Clock.Now()
+	ss.data.End = now
 }
 
 func (ss *SyncerState) Snapshot() SyncerStateSnapshot {
 	ss.lk.Lock()
 	defer ss.lk.Unlock()
-	return ss.data
+
+	out := ss.data
+	out.Stages = append([]api.SyncStageTime(nil), ss.data.Stages...)
+	if !ss.stageStart.IsZero() {
+		elapsed := build.Clock.Since(ss.stageStart)
+
+		found := false
+		for i := range out.Stages {
+			if out.Stages[i].Stage == out.Stage {
+				out.Stages[i].Elapsed += elapsed
+				found = true
+			}
+		}
+		if !found {
+			out.Stages = append(out.Stages, api.SyncStageTime{Stage: out.Stage, Elapsed: elapsed})
+		}
+	}
+	return out
 }
@@ -0,0 +1,181 @@
+package chain
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/exchange"
+	"github.com/filecoin-project/lotus/chain/types"
+	bstore "github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+// SetTrustedCheckpoint enables the lightweight sync mode, in which a node
+// whose chain is below the checkpoint jumps to it instead of validating the
+// chain from genesis. It does nothing if the checkpoint has no blocks. The
+// checkpoint is also enforced like a static checkpoint.
+func (syncer *Syncer) SetTrustedCheckpoint(cp dtypes.Checkpoint) error {
+	if len(cp.Blocks) == 0 {
+		return nil
+	}
+	if cp.Height <= 0 {
+		return xerrors.Errorf("trusted checkpoint must be above genesis, got height %d", cp.Height)
+	}
+
+	trusted := &staticCheckpoint{
+		height: cp.Height,
+		key:    types.NewTipSetKey(cp.Blocks...),
+	}
+
+	syncer.checkptLk.Lock()
+	syncer.trusted = trusted
+	syncer.static = append(syncer.static, *trusted)
+	sort.Slice(syncer.static, func(i, j int) bool {
+		return syncer.static[i].height < syncer.static[j].height
+	})
+	syncer.checkptLk.Unlock()
+
+	log.Warnw("lightweight sync mode enabled: the chain up to the trusted checkpoint won't be validated",
+		"height", trusted.height, "tipset", trusted.key)
+	return nil
+}
+
+// TrustedCheckpoint returns the checkpoint of the lightweight sync mode, or
+// nil when the chain is validated from genesis.
+func (syncer *Syncer) TrustedCheckpoint() *api.TrustedCheckpoint {
+	trusted := syncer.getTrusted()
+	if trusted == nil {
+		return nil
+	}
+
+	out := &api.TrustedCheckpoint{
+		TipSet: trusted.key,
+		Height: trusted.height,
+	}
+	if head := syncer.store.GetHeaviestTipSet(); head != nil {
+		out.Reached = head.Height() >= trusted.height
+	}
+	return out
+}
+
+func (syncer *Syncer) getTrusted() *staticCheckpoint {
+	syncer.checkptLk.Lock()
+	defer syncer.checkptLk.Unlock()
+	return syncer.trusted
+}
+
+// trustedBase returns the tipset to sync the chain to ts from. That's the
+// head, unless the head is below the trusted checkpoint and ts above it: the
+// node then jumps to the checkpoint, making it the head once its state was
+// fetched.
+func (syncer *Syncer) trustedBase(ctx context.Context, ts *types.TipSet) (*types.TipSet, error) {
+	head := syncer.store.GetHeaviestTipSet()
+
+	trusted := syncer.getTrusted()
+	if trusted == nil || head.Height() >= trusted.height || ts.Height() <= trusted.height {
+		return head, nil
+	}
+
+	log.Infow("jumping to the trusted checkpoint", "height", trusted.height, "tipset", trusted.key)
+
+	cpts, err := syncer.fetchTrustedTipSet(ctx, *trusted)
+	if err != nil {
+		return nil, xerrors.Errorf("fetching trusted checkpoint: %w", err)
+	}
+
+	// the messages of the checkpoint are executed on its parent state when
+	// validating the tipset above it
+	if err := syncer.ensureState(ctx, cpts.ParentState()); err != nil {
+		return nil, xerrors.Errorf("fetching trusted checkpoint state: %w", err)
+	}
+
+	if err := syncer.store.SetTrustedHead(cpts); err != nil {
+		return nil, err
+	}
+	return cpts, nil
+}
+
+// fetchTrustedTipSet fetches the checkpoint tipset with its messages, and
+// the headers of up to a finality of tipsets below it: validating the
+// tipsets above the checkpoint looks them up for randomness, beacon entries
+// and lookbacks.
+func (syncer *Syncer) fetchTrustedTipSet(ctx context.Context, trusted staticCheckpoint) (*types.TipSet, error) {
+	count := int(exchange.MaxRequestLength)
+	if abi.ChainEpoch(count) > trusted.height {
+		count = int(trusted.height)
+	}
+
+	tipsets, err := syncer.Exchange.GetBlocks(ctx, trusted.key, count)
+	if err != nil {
+		return nil, xerrors.Errorf("fetching headers: %w", err)
+	}
+	cpts := tipsets[0]
+	if cpts.Height() != trusted.height {
+		return nil, xerrors.Errorf("trusted checkpoint %s is at height %d, not %d", trusted.key, cpts.Height(), trusted.height)
+	}
+
+	var headers []*types.BlockHeader
+	for _, ts := range tipsets {
+		headers = append(headers, ts.Blocks()...)
+	}
+	if err := syncer.store.PersistBlockHeaders(headers...); err != nil {
+		return nil, xerrors.Errorf("persisting headers: %w", err)
+	}
+
+	msgs, err := syncer.Exchange.GetChainMessages(ctx, tipsets[:1])
+	if err != nil {
+		return nil, xerrors.Errorf("fetching messages: %w", err)
+	}
+
+	bs := bstore.NewTemporary()
+	if _, err := zipTipSetAndMessages(cbor.NewCborStore(bs), cpts, msgs[0].Bls, msgs[0].Secpk, msgs[0].BlsIncludes, msgs[0].SecpkIncludes); err != nil {
+		return nil, xerrors.Errorf("checking messages: %w", err)
+	}
+	if err := persistMessages(ctx, bs, msgs[0]); err != nil {
+		return nil, err
+	}
+	if err := copyBlockstore(ctx, bs, syncer.store.Blockstore()); err != nil {
+		return nil, xerrors.Errorf("persisting messages: %w", err)
+	}
+
+	log.Infow("fetched trusted checkpoint", "height", cpts.Height(), "headers", len(tipsets))
+	return cpts, nil
+}
+
+// lookbackState returns the state of the lookback tipset lbts of a block
+// built on baseTs. Below the trusted checkpoint the node jumped to, the state
+// isn't local and the messages of lbts weren't fetched to compute it: it is
+// then the parent state of the tipset above lbts, fetched on demand with the
+// parent state of lbts, which the miner eligibility is checked against.
+func (syncer *Syncer) lookbackState(ctx context.Context, baseTs, lbts *types.TipSet) (cid.Cid, error) {
+	trusted := syncer.getTrusted()
+	if trusted == nil || lbts.Height() >= trusted.height {
+		lbst, _, err := syncer.sm.TipSetState(ctx, lbts)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to compute lookback tipset state (epoch %d): %w", lbts.Height(), err)
+		}
+		return lbst, nil
+	}
+
+	next, err := syncer.store.GetTipsetByHeight(ctx, lbts.Height()+1, baseTs, false)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting the tipset above the lookback tipset: %w", err)
+	}
+	if next.Parents() != lbts.Key() {
+		return cid.Undef, xerrors.Errorf("tipset %s at height %d isn't built on the lookback tipset %s", next.Key(), next.Height(), lbts.Key())
+	}
+
+	for _, root := range []cid.Cid{lbts.ParentState(), next.ParentState()} {
+		if err := syncer.ensureState(ctx, root); err != nil {
+			return cid.Undef, xerrors.Errorf("fetching lookback state (epoch %d): %w", lbts.Height(), err)
+		}
+	}
+	return next.ParentState(), nil
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// blsDST is the domain separation tag of lib/sigs/bls, which can't be
+// imported from here as it depends on this package.
+const blsDST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_"
+
+// AggregateSignatures aggregates BLS signatures into one, like the
+// BLSAggregate of a block. The aggregate of no signatures is the signature at
+// infinity.
+func AggregateSignatures(sigs []crypto.Signature) (*crypto.Signature, error) {
+	sigsS := make([][]byte, len(sigs))
+	for i := range sigs {
+		if sigs[i].Type != crypto.SigTypeBLS {
+			return nil, xerrors.Errorf("signature %d isn't a BLS signature", i)
+		}
+		sigsS[i] = sigs[i].Data
+	}
+
+	empty := &crypto.Signature{
+		Type: crypto.SigTypeBLS,
+		Data: new(blst.P2Affine).Compress(),
+	}
+	if len(sigs) == 0 {
+		return empty, nil
+	}
+
+	aggregator := new(blst.P2Aggregate).AggregateCompressed(sigsS)
+	if aggregator == nil {
+		return nil, xerrors.Errorf("bls.Aggregate returned nil with %d signatures", len(sigs))
+	}
+	aggSigAff := aggregator.ToAffine()
+	if aggSigAff == nil {
+		return empty, nil
+	}
+	return &crypto.Signature{
+		Type: crypto.SigTypeBLS,
+		Data: aggSigAff.Compress(),
+	}, nil
+}
+
+// VerifyAggregateSignature checks that sig aggregates the signatures of each
+// of msgs by the BLS address signing it.
+func VerifyAggregateSignature(sig *crypto.Signature, signers []address.Address, msgs [][]byte) error {
+	if sig == nil || sig.Type != crypto.SigTypeBLS {
+		return xerrors.Errorf("not a BLS signature")
+	}
+	if len(signers) != len(msgs) {
+		return xerrors.Errorf("%d signers for %d messages", len(signers), len(msgs))
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	pubks := make([][]byte, len(signers))
+	msgsS := make([]blst.Message, len(msgs))
+	for i, s := range signers {
+		if s.Protocol() != address.BLS {
+			return xerrors.Errorf("signer %s isn't a BLS address", s)
+		}
+		pubks[i] = s.Payload()
+		msgsS[i] = msgs[i]
+	}
+
+	if !new(blst.P2Affine).AggregateVerifyCompressed(sig.Data, pubks, msgsS, []byte(blsDST)) {
+		return xerrors.New("bls aggregate signature failed to verify")
+	}
+	return nil
+}
+
+// VerifyMessagesAggregate checks that sig aggregates the signatures of the
+// messages by their senders, which must be BLS addresses.
+func VerifyMessagesAggregate(sig *crypto.Signature, msgs []*Message) error {
+	signers := make([]address.Address, len(msgs))
+	cids := make([][]byte, len(msgs))
+	for i, m := range msgs {
+		signers[i] = m.From
+		cids[i] = m.Cid().Bytes()
+	}
+	return VerifyAggregateSignature(sig, signers, cids)
+}
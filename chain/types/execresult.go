@@ -8,6 +8,8 @@ import (
 	"time"
 )
 
+// ExecutionTrace is the trace of the invocation of a message, holding its
+// receipt, the gas charges made while it ran and the traces of its subcalls.
 type ExecutionTrace struct {
 	Msg        *Message
 	MsgRct     *MessageReceipt
@@ -18,6 +20,7 @@ type ExecutionTrace struct {
 	Subcalls []ExecutionTrace
 }
 
+// GasTrace is a gas charge of an execution trace, labelled by Name.
 type GasTrace struct {
 	Name string
 
@@ -13,6 +13,25 @@ type MpoolConfig struct {
 	ReplaceByFeeRatio      float64
 	PruneCooldown          time.Duration
 	GasLimitOverestimation float64
+
+	// SizeLimitMax is the number of pending messages above which messages
+	// from the network are rejected until pruning makes room; 0 disables it
+	SizeLimitMax int
+	// MaxActorPendingMessages bounds the pending messages per actor received
+	// from the network, MaxUntrustedActorPendingMessages the ones pushed
+	// through MpoolPushUntrusted
+	MaxActorPendingMessages          int
+	MaxUntrustedActorPendingMessages int
+	// MinUntrustedGasPremium is the minimum gas premium of messages received
+	// from the network or pushed through MpoolPushUntrusted
+	MinUntrustedGasPremium BigInt
+
+	// PriorityLocal gives the messages pushed through this node priority in
+	// block production, like the ones of PriorityAddrs
+	PriorityLocal bool
+	// MinSelectionGasPremium is the minimum gas premium of messages selected
+	// for blocks produced by this node
+	MinSelectionGasPremium BigInt
 }
 
 func (mc *MpoolConfig) Clone() *MpoolConfig {
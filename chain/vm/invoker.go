@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"reflect"
 
-	"github.com/filecoin-project/lotus/chain/actors/builtin"
-
 	"github.com/ipfs/go-cid"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/xerrors"
@@ -211,11 +209,9 @@ func DecodeParams(b []byte, out interface{}) error {
 	return um.UnmarshalCBOR(bytes.NewReader(b))
 }
 
+// DumpActorState decodes the state of an actor, of any builtin actors version,
+// with the state type registered by its code.
 func DumpActorState(act *types.Actor, b []byte) (interface{}, error) {
-	if builtin.IsAccountActor(act.Code) { // Account code special case
-		return nil, nil
-	}
-
 	i := NewActorRegistry() // TODO: register builtins in init block
 
 	actInfo, ok := i.actors[act.Code]
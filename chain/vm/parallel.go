@@ -0,0 +1,205 @@
+package vm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"go.opencensus.io/trace"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/lib/bufbstore"
+)
+
+// ApplyMessages applies the messages in order, with the same results as
+// calling ApplyMessage for each of them.
+//
+// If the parallelism of the VM is above 1, the messages are first executed
+// concurrently, each against the state before the first message, recording
+// the actors they access. The results are then merged in order: a message
+// which didn't access any actor written by the messages before it would have
+// had the same result executed after them, so its writes are applied as they
+// are; the other messages are executed again against the merged state. The
+// gas fees paid to the burnt funds and reward actors are credited when
+// merging, as they would otherwise make all messages conflict; the messages
+// accessing these actors are executed again.
+func (vm *VM) ApplyMessages(ctx context.Context, msgs []types.ChainMsg) ([]*ApplyRet, error) {
+	if vm.parallelism < 2 || len(msgs) < 2 {
+		out := make([]*ApplyRet, 0, len(msgs))
+		for _, cm := range msgs {
+			r, err := vm.ApplyMessage(ctx, cm)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	}
+
+	ctx, span := trace.StartSpan(ctx, "vm.ApplyMessages")
+	defer span.End()
+
+	// the concurrent executions read the state through the blockstore
+	root, err := vm.cstate.Flush(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("flushing state tree: %w", err)
+	}
+
+	execs := vm.executeConcurrently(ctx, root, msgs)
+
+	written := make(map[address.Address]struct{})
+	out := make([]*ApplyRet, len(msgs))
+	for i, cm := range msgs {
+		ex := execs[i]
+		if ex.err == nil && !ex.access.Touches(written) {
+			if err := vm.merge(ex); err != nil {
+				return nil, xerrors.Errorf("merging message %s: %w", cm.Cid(), err)
+			}
+			for addr := range ex.access.Writes {
+				written[addr] = struct{}{}
+			}
+			for addr := range ex.vm.deferredCredits {
+				written[addr] = struct{}{}
+			}
+			out[i] = ex.ret
+			atomic.AddUint64(&StatAppliedConcurrently, 1)
+			continue
+		}
+
+		// conflicting or failed, apply the message to the merged state
+		vm.cstate.TrackAccess()
+		r, err := vm.ApplyMessage(ctx, cm)
+		access := vm.cstate.StopTrackingAccess()
+		if err != nil {
+			return nil, err
+		}
+		for addr := range access.Writes {
+			written[addr] = struct{}{}
+		}
+		out[i] = r
+	}
+
+	return out, nil
+}
+
+// concurrentExec is the result of a message executed against the state
+// before the messages applied with it.
+type concurrentExec struct {
+	vm     *VM
+	blocks blockstore.MemStore // blocks written by the execution
+	access *state.Access
+
+	ret *ApplyRet
+	err error
+}
+
+func (vm *VM) executeConcurrently(ctx context.Context, root cid.Cid, msgs []types.ChainMsg) []*concurrentExec {
+	out := make([]*concurrentExec, len(msgs))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < vm.parallelism && w < len(msgs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i] = vm.executeOnFork(ctx, root, msgs[i])
+			}
+		}()
+	}
+	for i := range msgs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return out
+}
+
+func (vm *VM) executeOnFork(ctx context.Context, root cid.Cid, cm types.ChainMsg) *concurrentExec {
+	writes := blockstore.NewTemporary()
+	buf := bufbstore.NewTieredBstore(vm.buf, writes)
+	cst := cbor.NewCborStore(buf)
+	st, err := state.LoadStateTree(cst, root)
+	if err != nil {
+		return &concurrentExec{err: xerrors.Errorf("loading state tree: %w", err)}
+	}
+
+	fork := &VM{
+		cstate:          st,
+		base:            vm.base,
+		cst:             cst,
+		buf:             buf,
+		blockHeight:     vm.blockHeight,
+		areg:            vm.areg,
+		rand:            vm.rand,
+		circSupplyCalc:  vm.circSupplyCalc,
+		ntwkVersion:     vm.ntwkVersion,
+		baseFee:         vm.baseFee,
+		tracing:         vm.tracing,
+		deferredCredits: make(map[address.Address]abi.TokenAmount),
+		Syscalls:        vm.Syscalls,
+	}
+
+	st.TrackAccess()
+	ret, err := fork.ApplyMessage(ctx, cm)
+	return &concurrentExec{
+		vm:     fork,
+		blocks: writes,
+		access: st.StopTrackingAccess(),
+		ret:    ret,
+		err:    err,
+	}
+}
+
+// merge applies the writes and the gas credits of a message executed
+// concurrently to the state of the VM.
+func (vm *VM) merge(ex *concurrentExec) error {
+	blks := make([]blocks.Block, 0, len(ex.blocks))
+	for _, blk := range ex.blocks {
+		blks = append(blks, blk)
+	}
+	if err := vm.buf.PutMany(blks); err != nil {
+		return xerrors.Errorf("copying blocks: %w", err)
+	}
+
+	for addr := range ex.access.Writes {
+		act, err := ex.vm.cstate.GetActor(addr)
+		switch {
+		case xerrors.Is(err, types.ErrActorNotFound):
+			if _, err := vm.cstate.GetActor(addr); xerrors.Is(err, types.ErrActorNotFound) {
+				continue // created and deleted by the message
+			}
+			if err := vm.cstate.DeleteActor(addr); err != nil {
+				return xerrors.Errorf("deleting actor %s: %w", addr, err)
+			}
+		case err != nil:
+			return xerrors.Errorf("getting actor %s: %w", addr, err)
+		default:
+			if err := vm.cstate.SetActor(addr, act); err != nil {
+				return xerrors.Errorf("setting actor %s: %w", addr, err)
+			}
+		}
+	}
+
+	for addr, amt := range ex.vm.deferredCredits {
+		if err := vm.cstate.MutateActor(addr, func(a *types.Actor) error {
+			a.Balance = big.Add(a.Balance, amt)
+			return nil
+		}); err != nil {
+			return xerrors.Errorf("crediting %s: %w", addr, err)
+		}
+	}
+
+	return nil
+}
@@ -49,7 +49,8 @@ func (m *Message) ValueReceived() abi.TokenAmount {
 	return m.msg.Value
 }
 
-// EnableGasTracing, if true, outputs gas tracing in execution traces.
+// EnableGasTracing, if true, outputs gas tracing in execution traces of all
+// VMs. Gas tracing can be enabled for a single VM through VMOpts.Tracing.
 var EnableGasTracing = false
 
 type Runtime struct {
@@ -480,7 +481,7 @@ func (rt *Runtime) stateCommit(oldh, newh cid.Cid) aerrors.ActorError {
 }
 
 func (rt *Runtime) finilizeGasTracing() {
-	if EnableGasTracing {
+	if rt.vm.tracing {
 		if rt.lastGasCharge != nil {
 			rt.lastGasCharge.TimeTaken = time.Since(rt.lastGasChargeTime)
 		}
@@ -514,7 +515,7 @@ func (rt *Runtime) chargeGasFunc(skip int) func(GasCharge) {
 
 func (rt *Runtime) chargeGasInternal(gas GasCharge, skip int) aerrors.ActorError {
 	toUse := gas.Total()
-	if EnableGasTracing {
+	if rt.vm.tracing {
 		var callers [10]uintptr
 
 		cout := 0 //gruntime.Callers(2+skip, callers[:])
@@ -50,6 +50,7 @@ func BenchmarkRuntime_CreateRuntimeChargeGas_TracingDisabled(b *testing.B) {
 	var (
 		cst = cbor.NewCborStore(nil)
 		gch = newGasCharge("foo", 1000, 1000)
+		vm  = &VM{}
 	)
 
 	b.ResetTimer()
@@ -62,6 +63,6 @@ func BenchmarkRuntime_CreateRuntimeChargeGas_TracingDisabled(b *testing.B) {
 		EnableGasTracing = true
 		_ = noop()
 		EnableGasTracing = false
-		_ = (&Runtime{cst: cst}).chargeGasInternal(gch, 0)
+		_ = (&Runtime{cst: cst, vm: vm}).chargeGasInternal(gch, 0)
 	}
 }
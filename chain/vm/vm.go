@@ -46,6 +46,8 @@ var gasOnActorExec = newGasCharge("OnActorExec", 0, 0)
 var (
 	StatSends   uint64
 	StatApplied uint64
+	// messages executed concurrently without conflicts, see ApplyMessages
+	StatAppliedConcurrently uint64
 )
 
 // ResolveToKeyAddr returns the public key type of address (`BLS`/`SECP256K1`) of an account actor identified by `addr`.
@@ -166,6 +168,12 @@ type VM struct {
 	circSupplyCalc CircSupplyCalculator
 	ntwkVersion    NtwkVersionGetter
 	baseFee        abi.TokenAmount
+	tracing        bool
+	parallelism    int
+
+	// gas fees credited to the burnt funds and reward actors by the messages
+	// executed concurrently, merged by ApplyMessages; nil if credited directly
+	deferredCredits map[address.Address]abi.TokenAmount
 
 	Syscalls SyscallBuilder
 }
@@ -179,6 +187,12 @@ type VMOpts struct {
 	CircSupplyCalc CircSupplyCalculator
 	NtwkVersion    NtwkVersionGetter // TODO: stebalien: In what cases do we actually need this? It seems like even when creating new networks we want to use the 'global'/build-default version getter
 	BaseFee        abi.TokenAmount
+	// Tracing records the gas charges of the messages in their execution
+	// traces, see EnableGasTracing.
+	Tracing bool
+	// Parallelism is the number of messages ApplyMessages executes
+	// concurrently; they are executed one by one if it is below 2.
+	Parallelism int
 }
 
 func NewVM(ctx context.Context, opts *VMOpts) (*VM, error) {
@@ -201,6 +215,8 @@ func NewVM(ctx context.Context, opts *VMOpts) (*VM, error) {
 		ntwkVersion:    opts.NtwkVersion,
 		Syscalls:       opts.Syscalls,
 		baseFee:        opts.BaseFee,
+		tracing:        opts.Tracing || EnableGasTracing,
+		parallelism:    opts.Parallelism,
 	}, nil
 }
 
@@ -236,7 +252,7 @@ func (vm *VM) send(ctx context.Context, msg *types.Message, parent *Runtime,
 	}
 
 	rt := vm.makeRuntime(ctx, msg, origin, on, gasUsed, nac)
-	if EnableGasTracing {
+	if vm.tracing {
 		rt.lastGasChargeTime = start
 		if parent != nil {
 			rt.lastGasChargeTime = parent.lastGasChargeTime
@@ -861,6 +877,17 @@ func (vm *VM) transferFromGasHolder(addr address.Address, gasHolder *types.Actor
 		return nil
 	}
 
+	if vm.deferredCredits != nil && (addr == builtin.BurntFundsActorAddr || addr == reward.Address) {
+		if err := deductFunds(gasHolder, amt); err != nil {
+			return err
+		}
+		if prev, ok := vm.deferredCredits[addr]; ok {
+			amt = big.Add(prev, amt)
+		}
+		vm.deferredCredits[addr] = amt
+		return nil
+	}
+
 	return vm.cstate.MutateActor(addr, func(a *types.Actor) error {
 		if err := deductFunds(gasHolder, amt); err != nil {
 			return err
@@ -0,0 +1,44 @@
+package wallet
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// SignAggregate signs each message with the BLS key of its signer in w, and
+// aggregates the signatures. The signers are the key addresses of the senders
+// of the messages.
+func SignAggregate(ctx context.Context, w api.WalletAPI, signers []address.Address, msgs []*types.Message) (*crypto.Signature, error) {
+	if len(signers) != len(msgs) {
+		return nil, xerrors.Errorf("%d signers for %d messages", len(signers), len(msgs))
+	}
+
+	sigs := make([]crypto.Signature, len(msgs))
+	for i, msg := range msgs {
+		if signers[i].Protocol() != address.BLS {
+			return nil, xerrors.Errorf("signer %s of message %d isn't a BLS address", signers[i], i)
+		}
+
+		mb, err := msg.ToStorageBlock()
+		if err != nil {
+			return nil, xerrors.Errorf("serializing message %d: %w", i, err)
+		}
+		sig, err := w.WalletSign(ctx, signers[i], mb.Cid().Bytes(), api.MsgMeta{
+			Type:  api.MTChainMsg,
+			Extra: mb.RawData(),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("signing message %d: %w", i, err)
+		}
+		sigs[i] = *sig
+	}
+
+	return types.AggregateSignatures(sigs)
+}
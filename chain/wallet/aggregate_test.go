@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestSignAggregate(t *testing.T) {
+	ctx := context.Background()
+
+	w, err := NewWallet(NewMemKeyStore())
+	require.NoError(t, err)
+
+	var signers []address.Address
+	var msgs []*types.Message
+	for i := 0; i < 3; i++ {
+		a, err := w.WalletNew(ctx, types.KTBLS)
+		require.NoError(t, err)
+		signers = append(signers, a)
+		msgs = append(msgs, &types.Message{
+			From:       a,
+			To:         a,
+			Nonce:      uint64(i),
+			Value:      types.NewInt(0),
+			GasFeeCap:  types.NewInt(0),
+			GasPremium: types.NewInt(0),
+		})
+	}
+
+	sig, err := SignAggregate(ctx, w, signers, msgs)
+	require.NoError(t, err)
+	require.NoError(t, types.VerifyMessagesAggregate(sig, msgs))
+
+	// a different set of messages doesn't verify
+	require.Error(t, types.VerifyMessagesAggregate(sig, msgs[:2]))
+	msgs[0].Nonce = 10
+	require.Error(t, types.VerifyMessagesAggregate(sig, msgs))
+
+	secp, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	_, err = SignAggregate(ctx, w, []address.Address{secp}, msgs[:1])
+	require.Error(t, err)
+}
@@ -0,0 +1,361 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ErrWalletLocked is returned when a key of an encrypted keystore is needed
+// while the wallet is locked.
+var ErrWalletLocked = xerrors.New("wallet is locked")
+
+const (
+	// KEncryption is the name of the keystore entry holding the parameters
+	// of the passphrase encryption, when the keystore is encrypted.
+	KEncryption = "encryption"
+
+	// KTEncrypted is the type of the keystore entries encrypted with the
+	// passphrase; the private key holds an encryptedKey.
+	KTEncrypted types.KeyType = "encrypted"
+
+	ktScrypt types.KeyType = "scrypt-aes256gcm"
+
+	kEncryptingPrefix = "encrypting-"
+)
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var checkPlaintext = []byte("lotus wallet passphrase check")
+
+type encryptionParams struct {
+	Salt    []byte
+	N, R, P int
+
+	// Check is checkPlaintext sealed with the derived key, to tell a wrong
+	// passphrase apart
+	Check []byte
+}
+
+type encryptedKey struct {
+	Type types.KeyType
+	Data []byte // nonce followed by the sealed private key
+
+	// Address is the address of the sealed key, for it to be known while
+	// the wallet is locked; nil for entries which aren't signing keys
+	Address *address.Address `json:",omitempty"`
+}
+
+func (p *encryptionParams) aead(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), p.Salt, p.N, p.R, p.P, 32)
+	if err != nil {
+		return nil, xerrors.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, xerrors.Errorf("sealed data too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+// isWalletKey tells whether a keystore entry is a key of the wallet, which
+// is encrypted along with the others.
+func isWalletKey(name string) bool {
+	return strings.HasPrefix(name, KNamePrefix) || strings.HasPrefix(name, KTrashPrefix) || name == KDefault || name == KMnemonic
+}
+
+func (w *LocalWallet) loadEncryption() error {
+	ki, err := w.keystore.Get(KEncryption)
+	if err != nil {
+		if xerrors.Is(err, types.ErrKeyInfoNotFound) {
+			return nil
+		}
+		return xerrors.Errorf("getting encryption parameters: %w", err)
+	}
+
+	var params encryptionParams
+	if err := json.Unmarshal(ki.PrivateKey, &params); err != nil {
+		return xerrors.Errorf("decoding encryption parameters: %w", err)
+	}
+	w.encryption = &params
+
+	return w.recoverEncrypting()
+}
+
+// recoverEncrypting finishes replacing the keys whose encryption was
+// interrupted, for which an encrypted copy was saved aside.
+func (w *LocalWallet) recoverEncrypting() error {
+	names, err := w.keystore.List()
+	if err != nil {
+		return xerrors.Errorf("listing keystore: %w", err)
+	}
+	for _, tmp := range names {
+		if !strings.HasPrefix(tmp, kEncryptingPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(tmp, kEncryptingPrefix)
+
+		eki, err := w.keystore.Get(tmp)
+		if err != nil {
+			return xerrors.Errorf("getting encrypted key copy %s: %w", name, err)
+		}
+
+		ki, err := w.keystore.Get(name)
+		if err != nil && !xerrors.Is(err, types.ErrKeyInfoNotFound) {
+			return xerrors.Errorf("getting key %s: %w", name, err)
+		}
+		// the key may have been replaced before the interruption
+		if err != nil || ki.Type != KTEncrypted {
+			log.Warnw("finishing the interrupted encryption of a key", "key", name)
+			if err == nil {
+				if err := w.keystore.Delete(name); err != nil {
+					return xerrors.Errorf("deleting plaintext key %s: %w", name, err)
+				}
+			}
+			if err := w.keystore.Put(name, eki); err != nil {
+				return xerrors.Errorf("saving encrypted key %s: %w", name, err)
+			}
+		}
+
+		if err := w.keystore.Delete(tmp); err != nil {
+			return xerrors.Errorf("deleting encrypted key copy %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// encrypt seals a key for the keystore, when it's encrypted. Must be called
+// with the lock held.
+func (w *LocalWallet) encrypt(ki types.KeyInfo) (types.KeyInfo, error) {
+	if w.encryption == nil {
+		return ki, nil
+	}
+	if w.aead == nil {
+		return types.KeyInfo{}, ErrWalletLocked
+	}
+
+	data, err := seal(w.aead, ki.PrivateKey)
+	if err != nil {
+		return types.KeyInfo{}, xerrors.Errorf("encrypting key: %w", err)
+	}
+	ek := encryptedKey{Type: ki.Type, Data: data}
+	if ki.Type == types.KTSecp256k1 || ki.Type == types.KTBLS {
+		k, err := NewKey(ki)
+		if err != nil {
+			return types.KeyInfo{}, err
+		}
+		ek.Address = &k.Address
+	}
+	b, err := json.Marshal(ek)
+	if err != nil {
+		return types.KeyInfo{}, err
+	}
+	return types.KeyInfo{Type: KTEncrypted, PrivateKey: b}, nil
+}
+
+// decrypt opens a key read from the keystore; keys which weren't encrypted
+// are returned as is. Must be called with the lock held.
+func (w *LocalWallet) decrypt(ki types.KeyInfo) (types.KeyInfo, error) {
+	if ki.Type != KTEncrypted {
+		return ki, nil
+	}
+	if w.aead == nil {
+		return types.KeyInfo{}, ErrWalletLocked
+	}
+
+	var ek encryptedKey
+	if err := json.Unmarshal(ki.PrivateKey, &ek); err != nil {
+		return types.KeyInfo{}, xerrors.Errorf("decoding encrypted key: %w", err)
+	}
+	pk, err := open(w.aead, ek.Data)
+	if err != nil {
+		return types.KeyInfo{}, xerrors.Errorf("decrypting key: %w", err)
+	}
+	return types.KeyInfo{Type: ek.Type, PrivateKey: pk}, nil
+}
+
+// sealedAddress returns the address of an encrypted key, readable while the
+// wallet is locked, if it was recorded.
+func sealedAddress(ki types.KeyInfo) (address.Address, bool, error) {
+	if ki.Type != KTEncrypted {
+		return address.Undef, false, nil
+	}
+	var ek encryptedKey
+	if err := json.Unmarshal(ki.PrivateKey, &ek); err != nil {
+		return address.Undef, false, xerrors.Errorf("decoding encrypted key: %w", err)
+	}
+	if ek.Address == nil {
+		return address.Undef, false, nil
+	}
+	return *ek.Address, true, nil
+}
+
+// Encrypt encrypts the keys of the keystore with a key derived from the
+// passphrase, the keys added later are encrypted too. The wallet is locked
+// afterwards.
+func (w *LocalWallet) Encrypt(passphrase string) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.encryption != nil {
+		return xerrors.Errorf("keystore is already encrypted")
+	}
+	if passphrase == "" {
+		return xerrors.Errorf("empty passphrase")
+	}
+
+	params := &encryptionParams{
+		Salt: make([]byte, 32),
+		N:    scryptN,
+		R:    scryptR,
+		P:    scryptP,
+	}
+	if _, err := rand.Read(params.Salt); err != nil {
+		return err
+	}
+	aead, err := params.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	if params.Check, err = seal(aead, checkPlaintext); err != nil {
+		return err
+	}
+
+	pb, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := w.keystore.Put(KEncryption, types.KeyInfo{Type: ktScrypt, PrivateKey: pb}); err != nil {
+		return xerrors.Errorf("saving encryption parameters: %w", err)
+	}
+	w.encryption = params
+	w.aead = aead
+	defer w.lock()
+
+	names, err := w.keystore.List()
+	if err != nil {
+		return xerrors.Errorf("listing keystore: %w", err)
+	}
+	for _, name := range names {
+		if !isWalletKey(name) {
+			continue
+		}
+		ki, err := w.keystore.Get(name)
+		if err != nil {
+			return xerrors.Errorf("getting key %s: %w", name, err)
+		}
+		if ki.Type == KTEncrypted {
+			continue
+		}
+		eki, err := w.encrypt(ki)
+		if err != nil {
+			return err
+		}
+
+		// the encrypted key is saved aside until the plaintext one is
+		// replaced, so that an interruption doesn't lose it
+		if err := w.keystore.Put(kEncryptingPrefix+name, eki); err != nil {
+			return xerrors.Errorf("saving encrypted key %s: %w", name, err)
+		}
+		if err := w.keystore.Delete(name); err != nil {
+			return xerrors.Errorf("deleting plaintext key %s: %w", name, err)
+		}
+		if err := w.keystore.Put(name, eki); err != nil {
+			return xerrors.Errorf("saving encrypted key %s: %w", name, err)
+		}
+		if err := w.keystore.Delete(kEncryptingPrefix + name); err != nil {
+			return xerrors.Errorf("deleting encrypted key copy %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Unlock makes the keys of an encrypted keystore usable, until Lock is
+// called or the timeout elapses; a zero timeout keeps the wallet unlocked.
+func (w *LocalWallet) Unlock(passphrase string, timeout time.Duration) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.encryption == nil {
+		return xerrors.Errorf("keystore isn't encrypted")
+	}
+
+	aead, err := w.encryption.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	if _, err := open(aead, w.encryption.Check); err != nil {
+		return xerrors.Errorf("wrong passphrase")
+	}
+	w.aead = aead
+
+	if w.lockTimer != nil {
+		w.lockTimer.Stop()
+		w.lockTimer = nil
+	}
+	if timeout > 0 {
+		var t *time.Timer
+		t = time.AfterFunc(timeout, func() {
+			w.lk.Lock()
+			defer w.lk.Unlock()
+			if w.lockTimer == t { // not unlocked again since
+				w.lock()
+			}
+		})
+		w.lockTimer = t
+	}
+
+	return nil
+}
+
+// Lock forgets the key derived from the passphrase and the decrypted keys.
+func (w *LocalWallet) Lock() error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.encryption == nil {
+		return xerrors.Errorf("keystore isn't encrypted")
+	}
+	w.lock()
+	return nil
+}
+
+func (w *LocalWallet) lock() {
+	if w.lockTimer != nil {
+		w.lockTimer.Stop()
+		w.lockTimer = nil
+	}
+	w.aead = nil
+	w.keys = make(map[address.Address]*Key)
+}
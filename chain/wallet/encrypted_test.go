@@ -0,0 +1,115 @@
+package wallet
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestEncryptedKeystore(t *testing.T) {
+	ctx := context.Background()
+	ks := NewMemKeyStore()
+
+	w, err := NewWallet(ks)
+	require.NoError(t, err)
+	addr, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Encrypt("secret"))
+
+	ki, err := ks.Get(KNamePrefix + addr.String())
+	require.NoError(t, err)
+	require.Equal(t, KTEncrypted, ki.Type)
+
+	// the keystore is still encrypted when reopened
+	w, err = NewWallet(ks)
+	require.NoError(t, err)
+
+	has, err := w.WalletHas(ctx, addr)
+	require.NoError(t, err)
+	require.True(t, has)
+
+	_, err = w.WalletExport(ctx, addr)
+	require.True(t, xerrors.Is(err, ErrWalletLocked))
+
+	// the default address is known while locked
+	def, err := w.GetDefault()
+	require.NoError(t, err)
+	require.Equal(t, addr, def)
+
+	require.Error(t, w.Unlock("wrong", 0))
+	require.NoError(t, w.Unlock("secret", 0))
+
+	exported, err := w.WalletExport(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, types.KTSecp256k1, exported.Type)
+
+	addr2, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	ki, err = ks.Get(KNamePrefix + addr2.String())
+	require.NoError(t, err)
+	require.Equal(t, KTEncrypted, ki.Type)
+
+	require.NoError(t, w.Lock())
+	_, err = w.WalletSign(ctx, addr2, []byte("msg"), api.MsgMeta{})
+	require.True(t, xerrors.Is(err, ErrWalletLocked))
+}
+
+func TestEncryptInterrupted(t *testing.T) {
+	ctx := context.Background()
+	ks := NewMemKeyStore()
+
+	w, err := NewWallet(ks)
+	require.NoError(t, err)
+	addr, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	addr2, err := w.WalletNew(ctx, types.KTBLS)
+	require.NoError(t, err)
+	plain, err := ks.Get(KNamePrefix + addr2.String())
+	require.NoError(t, err)
+
+	require.NoError(t, w.Encrypt("secret"))
+
+	// interrupted after deleting the plaintext key
+	name := KNamePrefix + addr.String()
+	eki, err := ks.Get(name)
+	require.NoError(t, err)
+	require.NoError(t, ks.Put(kEncryptingPrefix+name, eki))
+	require.NoError(t, ks.Delete(name))
+
+	// interrupted before deleting the plaintext key
+	name2 := KNamePrefix + addr2.String()
+	eki2, err := ks.Get(name2)
+	require.NoError(t, err)
+	require.NoError(t, ks.Put(kEncryptingPrefix+name2, eki2))
+	require.NoError(t, ks.Delete(name2))
+	require.NoError(t, ks.Put(name2, plain))
+
+	w, err = NewWallet(ks)
+	require.NoError(t, err)
+
+	names, err := ks.List()
+	require.NoError(t, err)
+	for _, n := range names {
+		require.False(t, strings.HasPrefix(n, kEncryptingPrefix), n)
+	}
+	for _, n := range []string{name, name2} {
+		ki, err := ks.Get(n)
+		require.NoError(t, err)
+		require.Equal(t, KTEncrypted, ki.Type)
+	}
+
+	require.NoError(t, w.Unlock("secret", 0))
+	for _, a := range []address.Address{addr, addr2} {
+		_, err := w.WalletExport(ctx, a)
+		require.NoError(t, err)
+	}
+}
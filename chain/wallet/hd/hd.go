@@ -0,0 +1,231 @@
+// Package hd implements the hierarchical deterministic derivation of wallet
+// keys from a BIP39 mnemonic.
+//
+// secp256k1 keys are derived with BIP32 at the BIP44 path m/44'/461'/0'/0/i,
+// the one of the Filecoin Ledger app and of the other Filecoin wallets. BLS
+// keys are derived with EIP-2333 at the path m/12381/461/0/i.
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-crypto"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// Hardened is the offset of the hardened indexes of a path.
+const Hardened = 0x80000000
+
+var (
+	secpBasePath = []uint32{Hardened | 44, Hardened | 461, Hardened, 0}
+	blsBasePath  = []uint32{12381, 461, 0}
+)
+
+// NewMnemonic generates a 24 words mnemonic.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// Seed returns the seed of a mnemonic, checking its words and checksum.
+func Seed(mnemonic string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, xerrors.Errorf("invalid mnemonic: %w", err)
+	}
+	return seed, nil
+}
+
+// Path returns the derivation path of the key of the given type at index.
+func Path(typ types.KeyType, index uint64) ([]uint32, error) {
+	if index >= Hardened {
+		return nil, xerrors.Errorf("index %d out of range", index)
+	}
+
+	var base []uint32
+	switch typ {
+	case types.KTSecp256k1:
+		base = secpBasePath
+	case types.KTBLS:
+		base = blsBasePath
+	default:
+		return nil, xerrors.Errorf("unsupported key type: %s", typ)
+	}
+	return append(append([]uint32(nil), base...), uint32(index)), nil
+}
+
+// PathString formats a path like m/44'/461'/0'/0/0.
+func PathString(path []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, i := range path {
+		if i >= Hardened {
+			fmt.Fprintf(&b, "/%d'", i-Hardened)
+		} else {
+			fmt.Fprintf(&b, "/%d", i)
+		}
+	}
+	return b.String()
+}
+
+// Derive derives the private key of the given type at index from a seed.
+func Derive(seed []byte, typ types.KeyType, index uint64) (types.KeyInfo, error) {
+	path, err := Path(typ, index)
+	if err != nil {
+		return types.KeyInfo{}, err
+	}
+
+	var pk []byte
+	switch typ {
+	case types.KTSecp256k1:
+		pk, err = DeriveSecp256k1(seed, path)
+	case types.KTBLS:
+		pk, err = DeriveBLS(seed, path)
+	}
+	if err != nil {
+		return types.KeyInfo{}, err
+	}
+
+	return types.KeyInfo{
+		Type:       typ,
+		PrivateKey: pk,
+	}, nil
+}
+
+var secpN, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// DeriveSecp256k1 derives a secp256k1 private key with BIP32.
+func DeriveSecp256k1(seed []byte, path []uint32) ([]byte, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed) // nolint:errcheck
+	I := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(I[:32])
+	chain := I[32:]
+	if k.Sign() == 0 || k.Cmp(secpN) >= 0 {
+		return nil, xerrors.Errorf("invalid master key")
+	}
+
+	for _, i := range path {
+		var data []byte
+		if i >= Hardened {
+			data = append([]byte{0}, ser256(k)...)
+		} else {
+			data = compressedPubkey(crypto.PublicKey(ser256(k)))
+		}
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], i)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data) // nolint:errcheck
+		I := mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(I[:32])
+		if il.Cmp(secpN) >= 0 {
+			return nil, xerrors.Errorf("invalid child key %d, use the next index", i)
+		}
+		k = il.Add(il, k).Mod(il, secpN)
+		if k.Sign() == 0 {
+			return nil, xerrors.Errorf("invalid child key %d, use the next index", i)
+		}
+		chain = I[32:]
+	}
+
+	return ser256(k), nil
+}
+
+func ser256(k *big.Int) []byte {
+	b := make([]byte, 32)
+	kb := k.Bytes()
+	copy(b[32-len(kb):], kb)
+	return b
+}
+
+// compressedPubkey compresses an uncompressed (0x04 || x || y) public key.
+func compressedPubkey(pub []byte) []byte {
+	out := make([]byte, 33)
+	out[0] = 2 + pub[64]&1
+	copy(out[1:], pub[1:33])
+	return out
+}
+
+var blsR, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// DeriveBLS derives a BLS private key with EIP-2333, serialized
+// little-endian like the other BLS private keys of the wallet.
+func DeriveBLS(seed []byte, path []uint32) ([]byte, error) {
+	if len(seed) < 32 {
+		return nil, xerrors.Errorf("seed too short")
+	}
+
+	sk := hkdfModR(seed)
+	for _, i := range path {
+		sk = hkdfModR(parentSKToLamportPK(sk, i))
+	}
+
+	le := ser256(sk)
+	for i, j := 0, len(le)-1; i < j; i, j = i+1, j-1 {
+		le[i], le[j] = le[j], le[i]
+	}
+	return le, nil
+}
+
+func hkdfModR(ikm []byte) *big.Int {
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	sk := new(big.Int)
+	for sk.Sign() == 0 {
+		h := sha256.Sum256(salt)
+		salt = h[:]
+
+		okm := make([]byte, 48)
+		r := hkdf.New(sha256.New, append(append([]byte(nil), ikm...), 0), salt, []byte{0, 48})
+		if _, err := io.ReadFull(r, okm); err != nil {
+			panic(err) // only fails when reading more than 255 hashes
+		}
+		sk.SetBytes(okm).Mod(sk, blsR)
+	}
+	return sk
+}
+
+func parentSKToLamportPK(parent *big.Int, index uint32) []byte {
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, index)
+
+	ikm := ser256(parent)
+	notIkm := make([]byte, len(ikm))
+	for i := range ikm {
+		notIkm[i] = ^ikm[i]
+	}
+
+	pk := sha256.New()
+	for _, lamport := range [][]byte{ikmToLamportSK(ikm, salt), ikmToLamportSK(notIkm, salt)} {
+		for i := 0; i < len(lamport); i += 32 {
+			h := sha256.Sum256(lamport[i : i+32])
+			pk.Write(h[:]) // nolint:errcheck
+		}
+	}
+	return pk.Sum(nil)
+}
+
+func ikmToLamportSK(ikm, salt []byte) []byte {
+	okm := make([]byte, 255*32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, nil), okm); err != nil {
+		panic(err)
+	}
+	return okm
+}
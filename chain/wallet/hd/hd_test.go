@@ -0,0 +1,48 @@
+package hd
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestDeriveSecp256k1(t *testing.T) {
+	// BIP32 test vector 1, chain m/0H/1
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	pk, err := DeriveSecp256k1(seed, []uint32{Hardened, 1})
+	require.NoError(t, err)
+	require.Equal(t, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", hex.EncodeToString(pk))
+}
+
+func TestDeriveBLS(t *testing.T) {
+	// EIP-2333 test case 0
+	seed, err := hex.DecodeString("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+	require.NoError(t, err)
+
+	master, _ := new(big.Int).SetString("6083874454709270928345386274498605044986640685124978867557563392430687146096", 10)
+	require.Equal(t, master, hkdfModR(seed))
+
+	pk, err := DeriveBLS(seed, []uint32{0})
+	require.NoError(t, err)
+	for i, j := 0, len(pk)-1; i < j; i, j = i+1, j-1 {
+		pk[i], pk[j] = pk[j], pk[i]
+	}
+	child, _ := new(big.Int).SetString("20397789859736650942317412262472558107875392172444076792671091975210932703118", 10)
+	require.Equal(t, child, new(big.Int).SetBytes(pk))
+}
+
+func TestPathString(t *testing.T) {
+	path, err := Path(types.KTSecp256k1, 3)
+	require.NoError(t, err)
+	require.Equal(t, "m/44'/461'/0'/0/3", PathString(path))
+
+	path, err = Path(types.KTBLS, 3)
+	require.NoError(t, err)
+	require.Equal(t, "m/12381/461/0/3", PathString(path))
+}
@@ -0,0 +1,199 @@
+package wallet
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet/hd"
+)
+
+const (
+	// KMnemonic is the name of the keystore entry holding the BIP39 mnemonic
+	// the HD keys are derived from.
+	KMnemonic = "mnemonic"
+
+	KTMnemonic types.KeyType = "bip39-mnemonic"
+)
+
+// hdGapLimit is the number of consecutive unused indexes after which the
+// export stops looking for derived keys, like BIP44 account discovery.
+const hdGapLimit = 20
+
+// seed returns the seed of the mnemonic of the wallet, generating the
+// mnemonic if asked to and the wallet doesn't have one. Must be called with
+// the lock held.
+func (w *LocalWallet) seed(generate bool) ([]byte, error) {
+	mnemonic, err := w.mnemonic()
+	if xerrors.Is(err, types.ErrKeyInfoNotFound) && generate {
+		if mnemonic, err = hd.NewMnemonic(); err != nil {
+			return nil, xerrors.Errorf("generating mnemonic: %w", err)
+		}
+		eki, err := w.encrypt(types.KeyInfo{Type: KTMnemonic, PrivateKey: []byte(mnemonic)})
+		if err != nil {
+			return nil, err
+		}
+		if err := w.keystore.Put(KMnemonic, eki); err != nil {
+			return nil, xerrors.Errorf("saving mnemonic: %w", err)
+		}
+		log.Warn("generated a new wallet mnemonic, back it up with 'lotus wallet mnemonic export'")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return hd.Seed(mnemonic)
+}
+
+func (w *LocalWallet) mnemonic() (string, error) {
+	ki, err := w.keystore.Get(KMnemonic)
+	if err != nil {
+		return "", xerrors.Errorf("getting mnemonic: %w", err)
+	}
+	ki, err = w.decrypt(ki)
+	if err != nil {
+		return "", err
+	}
+	return string(ki.PrivateKey), nil
+}
+
+func (w *LocalWallet) hasKey(addr address.Address) (bool, error) {
+	_, err := w.keystore.Get(KNamePrefix + addr.String())
+	if xerrors.Is(err, types.ErrKeyInfoNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// WalletNewHD derives a key of the given type at the first index with no key
+// in the wallet.
+func (w *LocalWallet) WalletNewHD(typ types.KeyType) (address.Address, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	seed, err := w.seed(true)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	for i := uint64(0); ; i++ {
+		k, err := deriveKey(seed, typ, i)
+		if err != nil {
+			return address.Undef, err
+		}
+		has, err := w.hasKey(k.Address)
+		if err != nil {
+			return address.Undef, err
+		}
+		if !has {
+			return k.Address, w.putKey(k)
+		}
+	}
+}
+
+// WalletDeriveHD derives the key of the given type at index, and adds it to
+// the wallet if it isn't there already.
+func (w *LocalWallet) WalletDeriveHD(typ types.KeyType, index uint64) (address.Address, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	seed, err := w.seed(true)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	k, err := deriveKey(seed, typ, index)
+	if err != nil {
+		return address.Undef, err
+	}
+	has, err := w.hasKey(k.Address)
+	if err != nil || has {
+		return k.Address, err
+	}
+	return k.Address, w.putKey(k)
+}
+
+// ImportMnemonic sets the mnemonic the HD keys are derived from; the keys
+// have to be derived again with WalletDeriveHD or WalletNewHD.
+func (w *LocalWallet) ImportMnemonic(mnemonic string) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if _, err := hd.Seed(mnemonic); err != nil {
+		return err
+	}
+
+	_, err := w.keystore.Get(KMnemonic)
+	if err == nil {
+		return xerrors.Errorf("the wallet already has a mnemonic")
+	}
+	if !xerrors.Is(err, types.ErrKeyInfoNotFound) {
+		return xerrors.Errorf("getting mnemonic: %w", err)
+	}
+
+	eki, err := w.encrypt(types.KeyInfo{Type: KTMnemonic, PrivateKey: []byte(mnemonic)})
+	if err != nil {
+		return err
+	}
+	if err := w.keystore.Put(KMnemonic, eki); err != nil {
+		return xerrors.Errorf("saving mnemonic: %w", err)
+	}
+	return nil
+}
+
+// ExportMnemonic returns the mnemonic of the wallet, with the paths of the
+// derived keys found in the wallet.
+func (w *LocalWallet) ExportMnemonic() (*api.HDExport, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	mnemonic, err := w.mnemonic()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hd.Seed(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &api.HDExport{Mnemonic: mnemonic}
+	for _, typ := range []types.KeyType{types.KTSecp256k1, types.KTBLS} {
+		for i, unused := uint64(0), 0; unused < hdGapLimit; i++ {
+			k, err := deriveKey(seed, typ, i)
+			if err != nil {
+				return nil, err
+			}
+			has, err := w.hasKey(k.Address)
+			if err != nil {
+				return nil, err
+			}
+			if !has {
+				unused++
+				continue
+			}
+			unused = 0
+
+			path, err := hd.Path(typ, i)
+			if err != nil {
+				return nil, err
+			}
+			out.Keys = append(out.Keys, api.HDKey{
+				Address: k.Address,
+				Type:    typ,
+				Index:   i,
+				Path:    hd.PathString(path),
+			})
+		}
+	}
+
+	return out, nil
+}
+
+func deriveKey(seed []byte, typ types.KeyType, index uint64) (*Key, error) {
+	ki, err := hd.Derive(seed, typ, index)
+	if err != nil {
+		return nil, xerrors.Errorf("deriving key %d: %w", index, err)
+	}
+	return NewKey(ki)
+}
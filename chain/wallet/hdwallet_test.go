@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestHDWallet(t *testing.T) {
+	w, err := NewWallet(NewMemKeyStore())
+	require.NoError(t, err)
+
+	a0, err := w.WalletNewHD(types.KTSecp256k1)
+	require.NoError(t, err)
+	a1, err := w.WalletNewHD(types.KTSecp256k1)
+	require.NoError(t, err)
+	require.NotEqual(t, a0, a1)
+
+	a5, err := w.WalletDeriveHD(types.KTSecp256k1, 5)
+	require.NoError(t, err)
+
+	exp, err := w.ExportMnemonic()
+	require.NoError(t, err)
+	require.Len(t, exp.Keys, 3)
+	require.Equal(t, a5, exp.Keys[2].Address)
+	require.Equal(t, "m/44'/461'/0'/0/5", exp.Keys[2].Path)
+
+	// the keys are derived again from the mnemonic in another wallet
+	restored, err := NewWallet(NewMemKeyStore())
+	require.NoError(t, err)
+	require.NoError(t, restored.ImportMnemonic(exp.Mnemonic))
+	require.Error(t, restored.ImportMnemonic(exp.Mnemonic))
+
+	r0, err := restored.WalletNewHD(types.KTSecp256k1)
+	require.NoError(t, err)
+	require.Equal(t, a0, r0)
+	r5, err := restored.WalletDeriveHD(types.KTSecp256k1, 5)
+	require.NoError(t, err)
+	require.Equal(t, a5, r5)
+}
@@ -23,6 +23,15 @@ import (
 
 var log = logging.Logger("wallet-ledger")
 
+var (
+	// ErrNoDevice is returned when the Filecoin app of a Ledger device can't
+	// be reached.
+	ErrNoDevice = xerrors.New("no ledger device found, connect and unlock it, and open the Filecoin app")
+	// ErrNotSigned is returned when the device didn't sign a message, which
+	// is what happens when it is rejected on the device.
+	ErrNotSigned = xerrors.New("the ledger device didn't sign the message, was it rejected on the device?")
+)
+
 type LedgerWallet struct {
 	ds datastore.Datastore
 }
@@ -46,7 +55,7 @@ func (lw LedgerWallet) WalletSign(ctx context.Context, signer address.Address, t
 
 	fl, err := ledgerfil.FindLedgerFilecoinApp()
 	if err != nil {
-		return nil, err
+		return nil, xerrors.Errorf("%s: %w", err, ErrNoDevice)
 	}
 	defer fl.Close() // nolint:errcheck
 	if meta.Type != api.MTChainMsg {
@@ -69,9 +78,11 @@ func (lw LedgerWallet) WalletSign(ctx context.Context, signer address.Address, t
 		}
 	}
 
+	log.Infow("confirm the message on the ledger device", "signer", signer, "path", ki.Path)
+
 	sig, err := fl.SignSECP256K1(ki.Path, meta.Extra)
 	if err != nil {
-		return nil, err
+		return nil, xerrors.Errorf("signing with ledger: %s: %w", err, ErrNotSigned)
 	}
 
 	return &crypto.Signature{
@@ -199,7 +210,7 @@ func (lw LedgerWallet) WalletNew(ctx context.Context, t types.KeyType) (address.
 
 	fl, err := ledgerfil.FindLedgerFilecoinApp()
 	if err != nil {
-		return address.Undef, xerrors.Errorf("finding ledger: %w", err)
+		return address.Undef, xerrors.Errorf("finding ledger: %s: %w", err, ErrNoDevice)
 	}
 	defer fl.Close() // nolint:errcheck
 
@@ -212,7 +223,7 @@ func (lw LedgerWallet) WalletNew(ctx context.Context, t types.KeyType) (address.
 	log.Warnf("creating key: %s, accept the key in ledger device", addr)
 	_, _, addr, err = fl.ShowAddressPubKeySECP256K1(path)
 	if err != nil {
-		return address.Undef, xerrors.Errorf("verifying public key with ledger: %w", err)
+		return address.Undef, xerrors.Errorf("verifying public key with ledger (was the address rejected on the device?): %w", err)
 	}
 
 	a, err := address.NewFromString(addr)
@@ -21,6 +21,8 @@ type MultiWallet struct {
 	Local  *LocalWallet               `optional:"true"`
 	Remote *remotewallet.RemoteWallet `optional:"true"`
 	Ledger *ledgerwallet.LedgerWallet `optional:"true"`
+
+	Routes Routes `optional:"true"`
 }
 
 type getif interface {
@@ -84,11 +86,64 @@ func (m MultiWallet) WalletNew(ctx context.Context, keyType types.KeyType) (addr
 	return w.WalletNew(ctx, keyType)
 }
 
+// wallets returns the wallets which may have the key of an address, in the
+// order they are looked up, with the route of the address.
+func (m MultiWallet) wallets(addr address.Address) ([]getif, *Route, error) {
+	rt := m.Routes.route(addr)
+	if rt == nil || rt.Backend == "" {
+		return []getif{m.Remote, m.Ledger, m.Local}, rt, nil
+	}
+
+	var w getif
+	switch rt.Backend {
+	case BackendLocal:
+		w = m.Local
+	case BackendRemote:
+		w = m.Remote
+	case BackendLedger:
+		w = m.Ledger
+	default:
+		return nil, nil, xerrors.Errorf("unknown wallet backend %q for %s", rt.Backend, addr)
+	}
+	if w.Get() == nil {
+		return nil, nil, xerrors.Errorf("wallet backend %q for %s isn't enabled", rt.Backend, addr)
+	}
+	return []getif{w}, rt, nil
+}
+
 func (m MultiWallet) WalletHas(ctx context.Context, address address.Address) (bool, error) {
-	w, err := m.find(ctx, address, m.Remote, m.Ledger, m.Local)
+	ws, _, err := m.wallets(address)
+	if err != nil {
+		return false, err
+	}
+	w, err := m.find(ctx, address, ws...)
 	return w != nil, err
 }
 
+// Backend returns the backend holding the key of the address, one of the
+// Backend constants, or an empty string when no backend has it.
+func (m MultiWallet) Backend(ctx context.Context, addr address.Address) (string, error) {
+	ws, _, err := m.wallets(addr)
+	if err != nil {
+		return "", err
+	}
+	w, err := m.find(ctx, addr, ws...)
+	if err != nil || w == nil {
+		return "", err
+	}
+
+	switch w {
+	case api.WalletAPI(m.Local):
+		return BackendLocal, nil
+	case api.WalletAPI(m.Remote):
+		return BackendRemote, nil
+	case api.WalletAPI(m.Ledger):
+		return BackendLedger, nil
+	default:
+		return "", xerrors.Errorf("unknown wallet backend for %s", addr)
+	}
+}
+
 func (m MultiWallet) WalletList(ctx context.Context) ([]address.Address, error) {
 	var out []address.Address
 	seen := map[address.Address]struct{}{}
@@ -114,7 +169,17 @@ func (m MultiWallet) WalletList(ctx context.Context) ([]address.Address, error)
 }
 
 func (m MultiWallet) WalletSign(ctx context.Context, signer address.Address, toSign []byte, meta api.MsgMeta) (*crypto.Signature, error) {
-	w, err := m.find(ctx, signer, m.Remote, m.Ledger, m.Local)
+	ws, rt, err := m.wallets(signer)
+	if err != nil {
+		return nil, err
+	}
+	if rt != nil {
+		if err := rt.check(toSign, meta); err != nil {
+			return nil, xerrors.Errorf("signing policy of %s: %w", signer, err)
+		}
+	}
+
+	w, err := m.find(ctx, signer, ws...)
 	if err != nil {
 		return nil, err
 	}
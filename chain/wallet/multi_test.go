@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	ledgerwallet "github.com/filecoin-project/lotus/chain/wallet/ledger"
+)
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+
+	lw, err := NewWallet(NewMemKeyStore())
+	require.NoError(t, err)
+	local, err := lw.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+
+	// the keys of the ledger wallet are imported without a device
+	ledger := ledgerwallet.NewWallet(dssync.MutexWrap(datastore.NewMapDatastore()))
+	elsewhere, err := NewWallet(NewMemKeyStore())
+	require.NoError(t, err)
+	ledgerAddr, err := elsewhere.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	other, err := elsewhere.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	ki, err := json.Marshal(ledgerwallet.LedgerKeyInfo{Address: ledgerAddr, Path: []uint32{1, 2, 3, 4, 5}})
+	require.NoError(t, err)
+	_, err = ledger.WalletImport(ctx, &types.KeyInfo{Type: types.KTSecp256k1Ledger, PrivateKey: ki})
+	require.NoError(t, err)
+
+	w := MultiWallet{Local: lw, Ledger: ledger}
+	for addr, backend := range map[address.Address]string{
+		local:      BackendLocal,
+		ledgerAddr: BackendLedger,
+		other:      "",
+	} {
+		b, err := w.Backend(ctx, addr)
+		require.NoError(t, err)
+		require.Equal(t, backend, b, addr)
+	}
+}
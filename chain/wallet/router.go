@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"bytes"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// Wallet backends a route can pin signers to
+const (
+	BackendLocal  = "local"
+	BackendRemote = "remote"
+	BackendLedger = "ledger"
+)
+
+// Route pins the signers it matches to a wallet backend, and restricts what
+// they sign.
+type Route struct {
+	// Addresses and Protocols are the signers the route applies to; All
+	// matches every signer.
+	Addresses []address.Address
+	Protocols []address.Protocol
+	All       bool
+
+	// Backend is the only wallet signing for the matched addresses, any
+	// wallet having their key when empty.
+	Backend string
+
+	// MaxValue is the most a message can send, no limit when nil.
+	MaxValue abi.TokenAmount
+	// To and Methods restrict the destinations and methods of the messages,
+	// any when empty.
+	To      []address.Address
+	Methods []abi.MethodNum
+	// OnlyMessages denies signing anything but chain messages.
+	OnlyMessages bool
+}
+
+// Routes are the routes of the wallet, the first matching a signer applies.
+type Routes []Route
+
+func (r Routes) route(signer address.Address) *Route {
+	for i := range r {
+		if r[i].matches(signer) {
+			return &r[i]
+		}
+	}
+	return nil
+}
+
+func (rt *Route) matches(signer address.Address) bool {
+	if rt.All {
+		return true
+	}
+	for _, a := range rt.Addresses {
+		if a == signer {
+			return true
+		}
+	}
+	for _, p := range rt.Protocols {
+		if p == signer.Protocol() {
+			return true
+		}
+	}
+	return false
+}
+
+// restricted returns whether the route restricts the messages signed, which
+// can then only be chain messages.
+func (rt *Route) restricted() bool {
+	return rt.OnlyMessages || !rt.MaxValue.Nil() || len(rt.To) > 0 || len(rt.Methods) > 0
+}
+
+// check returns an error when the route doesn't allow the signer to sign
+// toSign. The message of the metadata must be the one signed, as the wallets
+// checking the messages they sign check it.
+func (rt *Route) check(toSign []byte, meta api.MsgMeta) error {
+	if meta.Type != api.MTChainMsg {
+		if rt.restricted() {
+			return xerrors.Errorf("only chain messages can be signed, not %s", meta.Type)
+		}
+		return nil
+	}
+
+	var msg types.Message
+	if err := msg.UnmarshalCBOR(bytes.NewReader(meta.Extra)); err != nil {
+		return xerrors.Errorf("unmarshalling message: %w", err)
+	}
+
+	_, bc, err := cid.CidFromBytes(toSign)
+	if err != nil {
+		return xerrors.Errorf("getting cid from signing bytes: %w", err)
+	}
+	if !msg.Cid().Equals(bc) {
+		return xerrors.Errorf("cid(meta.Extra).bytes() != toSign")
+	}
+
+	if !rt.MaxValue.Nil() && msg.Value.GreaterThan(rt.MaxValue) {
+		return xerrors.Errorf("message value %s over the limit of %s", types.FIL(msg.Value), types.FIL(rt.MaxValue))
+	}
+
+	if len(rt.To) > 0 {
+		allowed := false
+		for _, to := range rt.To {
+			allowed = allowed || to == msg.To
+		}
+		if !allowed {
+			return xerrors.Errorf("messages to %s aren't allowed", msg.To)
+		}
+	}
+
+	if len(rt.Methods) > 0 {
+		allowed := false
+		for _, m := range rt.Methods {
+			allowed = allowed || m == msg.Method
+		}
+		if !allowed {
+			return xerrors.Errorf("method %d isn't allowed", msg.Method)
+		}
+	}
+
+	return nil
+}
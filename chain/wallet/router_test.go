@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestRoutes(t *testing.T) {
+	ctx := context.Background()
+
+	lw, err := NewWallet(NewMemKeyStore())
+	require.NoError(t, err)
+	owner, err := lw.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	worker, err := lw.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	w := MultiWallet{
+		Local: lw,
+		Routes: Routes{{
+			Addresses: []address.Address{owner},
+			Backend:   BackendLocal,
+			MaxValue:  types.FromFil(10),
+			To:        []address.Address{miner},
+			Methods:   []abi.MethodNum{2},
+		}, {
+			Addresses: []address.Address{worker},
+			Backend:   BackendLedger,
+		}},
+	}
+
+	sign := func(signer address.Address, msg *types.Message) error {
+		mb, err := msg.ToStorageBlock()
+		require.NoError(t, err)
+		_, err = w.WalletSign(ctx, signer, mb.Cid().Bytes(), api.MsgMeta{
+			Type:  api.MTChainMsg,
+			Extra: mb.RawData(),
+		})
+		return err
+	}
+	msg := func(value abi.TokenAmount, method abi.MethodNum) *types.Message {
+		return &types.Message{
+			From:       owner,
+			To:         miner,
+			Value:      value,
+			Method:     method,
+			GasFeeCap:  types.NewInt(0),
+			GasPremium: types.NewInt(0),
+		}
+	}
+
+	require.NoError(t, sign(owner, msg(types.FromFil(1), 2)))
+	require.Error(t, sign(owner, msg(types.FromFil(11), 2)))
+	require.Error(t, sign(owner, msg(types.FromFil(1), 3)))
+
+	// the message checked must be the one signed
+	good, err := msg(types.FromFil(1), 2).ToStorageBlock()
+	require.NoError(t, err)
+	bad, err := msg(types.FromFil(100), 2).ToStorageBlock()
+	require.NoError(t, err)
+	_, err = w.WalletSign(ctx, owner, bad.Cid().Bytes(), api.MsgMeta{
+		Type:  api.MTChainMsg,
+		Extra: good.RawData(),
+	})
+	require.Error(t, err)
+
+	// the restricted signers only sign chain messages
+	_, err = w.WalletSign(ctx, owner, bad.Cid().Bytes(), api.MsgMeta{
+		Type: api.MTUnknown,
+	})
+	require.Error(t, err)
+
+	// the route of the worker pins it to the ledger, which isn't enabled
+	require.Error(t, sign(worker, msg(types.FromFil(1), 2)))
+	has, err := w.WalletHas(ctx, worker)
+	require.Error(t, err)
+	require.False(t, has)
+}
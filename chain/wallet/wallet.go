@@ -2,9 +2,11 @@ package wallet
 
 import (
 	"context"
+	"crypto/cipher"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/filecoin-project/go-state-types/crypto"
 	logging "github.com/ipfs/go-log/v2"
@@ -32,6 +34,12 @@ type LocalWallet struct {
 	keys     map[address.Address]*Key
 	keystore types.KeyStore
 
+	// passphrase encryption of the keystore; aead is only set while the
+	// wallet is unlocked
+	encryption *encryptionParams
+	aead       cipher.AEAD
+	lockTimer  *time.Timer
+
 	lk sync.Mutex
 }
 
@@ -46,6 +54,10 @@ func NewWallet(keystore types.KeyStore) (*LocalWallet, error) {
 		keystore: keystore,
 	}
 
+	if err := w.loadEncryption(); err != nil {
+		return nil, err
+	}
+
 	return w, nil
 }
 
@@ -92,6 +104,10 @@ func (w *LocalWallet) findKey(addr address.Address) (*Key, error) {
 		}
 		return nil, xerrors.Errorf("getting from keystore: %w", err)
 	}
+	ki, err = w.decrypt(ki)
+	if err != nil {
+		return nil, err
+	}
 	k, err = NewKey(ki)
 	if err != nil {
 		return nil, xerrors.Errorf("decoding from keystore: %w", err)
@@ -155,7 +171,11 @@ func (w *LocalWallet) WalletImport(ctx context.Context, ki *types.KeyInfo) (addr
 		return address.Undef, xerrors.Errorf("failed to make key: %w", err)
 	}
 
-	if err := w.keystore.Put(KNamePrefix+k.Address.String(), k.KeyInfo); err != nil {
+	eki, err := w.encrypt(k.KeyInfo)
+	if err != nil {
+		return address.Undef, err
+	}
+	if err := w.keystore.Put(KNamePrefix+k.Address.String(), eki); err != nil {
 		return address.Undef, xerrors.Errorf("saving to keystore: %w", err)
 	}
 
@@ -203,6 +223,15 @@ func (w *LocalWallet) GetDefault() (address.Address, error) {
 	if err != nil {
 		return address.Undef, xerrors.Errorf("failed to get default key: %w", err)
 	}
+	if a, ok, err := sealedAddress(ki); err != nil {
+		return address.Undef, xerrors.Errorf("failed to get default key: %w", err)
+	} else if ok {
+		return a, nil
+	}
+	ki, err = w.decrypt(ki)
+	if err != nil {
+		return address.Undef, xerrors.Errorf("failed to get default key: %w", err)
+	}
 
 	k, err := NewKey(ki)
 	if err != nil {
@@ -243,27 +272,44 @@ func (w *LocalWallet) WalletNew(ctx context.Context, typ types.KeyType) (address
 		return address.Undef, err
 	}
 
-	if err := w.keystore.Put(KNamePrefix+k.Address.String(), k.KeyInfo); err != nil {
-		return address.Undef, xerrors.Errorf("saving to keystore: %w", err)
+	if err := w.putKey(k); err != nil {
+		return address.Undef, err
+	}
+
+	return k.Address, nil
+}
+
+// putKey saves a new key to the keystore, as the default one if there is
+// none. Must be called with the lock held.
+func (w *LocalWallet) putKey(k *Key) error {
+	eki, err := w.encrypt(k.KeyInfo)
+	if err != nil {
+		return err
+	}
+	if err := w.keystore.Put(KNamePrefix+k.Address.String(), eki); err != nil {
+		return xerrors.Errorf("saving to keystore: %w", err)
 	}
 	w.keys[k.Address] = k
 
 	_, err = w.keystore.Get(KDefault)
 	if err != nil {
 		if !xerrors.Is(err, types.ErrKeyInfoNotFound) {
-			return address.Undef, err
+			return err
 		}
 
-		if err := w.keystore.Put(KDefault, k.KeyInfo); err != nil {
-			return address.Undef, xerrors.Errorf("failed to set new key as default: %w", err)
+		if err := w.keystore.Put(KDefault, eki); err != nil {
+			return xerrors.Errorf("failed to set new key as default: %w", err)
 		}
 	}
 
-	return k.Address, nil
+	return nil
 }
 
 func (w *LocalWallet) WalletHas(ctx context.Context, addr address.Address) (bool, error) {
 	k, err := w.findKey(addr)
+	if xerrors.Is(err, ErrWalletLocked) {
+		return true, nil // the key is there, only encrypted
+	}
 	if err != nil {
 		return false, err
 	}
@@ -279,7 +325,13 @@ func (w *LocalWallet) WalletDelete(ctx context.Context, addr address.Address) er
 		return nil // already not there
 	}
 
-	if err := w.keystore.Put(KTrashPrefix+k.Address.String(), k.KeyInfo); err != nil {
+	w.lk.Lock()
+	eki, err := w.encrypt(k.KeyInfo)
+	w.lk.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := w.keystore.Put(KTrashPrefix+k.Address.String(), eki); err != nil {
 		return xerrors.Errorf("failed to mark key %s as trashed: %w", addr, err)
 	}
 
@@ -2,13 +2,20 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-jsonrpc/auth"
 
+	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/api/apiusage"
+	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/node/repo"
 )
 
@@ -18,6 +25,8 @@ var authCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		authCreateAdminToken,
 		authApiInfoToken,
+		authUsageCmd,
+		authConnectionsCmd,
 	},
 }
 
@@ -29,6 +38,10 @@ var authCreateAdminToken = &cli.Command{
 			Name:  "perm",
 			Usage: "permission to assign to the token, one of: read, write, sign, admin",
 		},
+		&cli.StringSliceFlag{
+			Name:  "methods",
+			Usage: "only allow the token to call these methods, or the methods starting with a prefix ending with '*', like 'State*'",
+		},
 	},
 
 	Action: func(cctx *cli.Context) error {
@@ -40,32 +53,160 @@ var authCreateAdminToken = &cli.Command{
 
 		ctx := ReqContext(cctx)
 
-		if !cctx.IsSet("perm") {
-			return xerrors.New("--perm flag not set")
+		perms, err := tokenPerms(cctx, napi)
+		if err != nil {
+			return err
+		}
+
+		token, err := napi.AuthNew(ctx, perms)
+		if err != nil {
+			return err
+		}
+
+		// TODO: Log in audit log when it is implemented
+
+		fmt.Println(string(token))
+		return nil
+	},
+}
+
+// tokenPerms returns the permissions of the token to create, from the --perm
+// and --methods flags.
+func tokenPerms(cctx *cli.Context, napi lapi.Common) ([]auth.Permission, error) {
+	if !cctx.IsSet("perm") {
+		return nil, xerrors.New("--perm flag not set")
+	}
+
+	perm := cctx.String("perm")
+	idx := 0
+	for i, p := range apistruct.AllPermissions {
+		if auth.Permission(perm) == p {
+			idx = i + 1
 		}
+	}
+
+	if idx == 0 {
+		return nil, fmt.Errorf("--perm flag has to be one of: %s", apistruct.AllPermissions)
+	}
 
-		perm := cctx.String("perm")
-		idx := 0
-		for i, p := range apistruct.AllPermissions {
-			if auth.Permission(perm) == p {
-				idx = i + 1
+	// slice on [:idx] so for example: 'sign' gives you [read, write, sign]
+	perms := append([]auth.Permission{}, apistruct.AllPermissions[:idx]...)
+
+	patterns := cctx.StringSlice("methods")
+	if len(patterns) == 0 {
+		return perms, nil
+	}
+
+	caps, err := napi.Discover(ReqContext(cctx))
+	if err != nil {
+		return nil, xerrors.Errorf("listing the methods of the node: %w", err)
+	}
+	for _, pattern := range patterns {
+		matched := false
+		for _, m := range caps.Methods {
+			if m.Name == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(m.Name, strings.TrimSuffix(pattern, "*"))) {
+				matched = true
+				granted := false
+				for _, p := range perms {
+					granted = granted || p == m.Perm
+				}
+				if !granted {
+					log.Warnf("method %s requires the %s permission, which the token won't have", m.Name, m.Perm)
+				}
 			}
 		}
+		if !matched {
+			return nil, xerrors.Errorf("no method of the node matches %q", pattern)
+		}
+		perms = append(perms, apistruct.MethodScope(pattern))
+	}
+	return perms, nil
+}
 
-		if idx == 0 {
-			return fmt.Errorf("--perm flag has to be one of: %s", apistruct.AllPermissions)
+var authUsageCmd = &cli.Command{
+	Name:  "usage",
+	Usage: "Show the API usage per token since the node started",
+	Description: `Tokens are identified by the start of their hash; pass --token to only show
+   the usage of a token. The usage of requests without a valid token is shown
+   as '-'.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "only show the usage of this token",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetAPI(cctx)
+		if err != nil {
+			return err
 		}
+		defer closer()
 
-		// slice on [:idx] so for example: 'sign' gives you [read, write, sign]
-		token, err := napi.AuthNew(ctx, apistruct.AllPermissions[:idx])
+		ctx := ReqContext(cctx)
+
+		usage, err := napi.AuthUsage(ctx)
 		if err != nil {
 			return err
 		}
 
-		// TODO: Log in audit log when it is implemented
+		var only string
+		if cctx.IsSet("token") {
+			only = apiusage.TokenID(cctx.String("token"))
+		}
 
-		fmt.Println(string(token))
-		return nil
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Token\tCalls\tIn\tOut\tExec Time\tLast Used\n")
+		for _, u := range usage {
+			if only != "" && u.TokenID != only {
+				continue
+			}
+
+			id := u.TokenID
+			if id == "" {
+				id = "-"
+			}
+			last := "-"
+			if !u.LastUsed.IsZero() {
+				last = u.LastUsed.Format(time.Stamp)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", id, u.Calls,
+				types.SizeStr(types.NewInt(u.BytesIn)), types.SizeStr(types.NewInt(u.BytesOut)),
+				u.ExecTime.Truncate(time.Millisecond), last)
+		}
+		return tw.Flush()
+	},
+}
+
+var authConnectionsCmd = &cli.Command{
+	Name:  "connections",
+	Usage: "List the open websocket connections to the API and their subscriptions",
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		conns, err := napi.AuthConnections(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "ID\tRemote\tToken\tSince\tMethod\tBuffered\tDropped\n")
+		for _, c := range conns {
+			token := c.TokenID
+			if token == "" {
+				token = "-"
+			}
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t\t\t\n", c.ID, c.RemoteAddr, token, c.Opened.Format(time.Stamp))
+			for _, s := range c.Subscriptions {
+				_, _ = fmt.Fprintf(tw, "\t\t\t%s\t%s\t%d\t%d\n", s.Started.Format(time.Stamp), s.Method, s.Buffered, s.Dropped)
+			}
+		}
+		return tw.Flush()
 	},
 }
 
@@ -77,6 +218,10 @@ var authApiInfoToken = &cli.Command{
 			Name:  "perm",
 			Usage: "permission to assign to the token, one of: read, write, sign, admin",
 		},
+		&cli.StringSliceFlag{
+			Name:  "methods",
+			Usage: "only allow the token to call these methods, or the methods starting with a prefix ending with '*', like 'State*'",
+		},
 	},
 
 	Action: func(cctx *cli.Context) error {
@@ -88,24 +233,12 @@ var authApiInfoToken = &cli.Command{
 
 		ctx := ReqContext(cctx)
 
-		if !cctx.IsSet("perm") {
-			return xerrors.New("--perm flag not set")
-		}
-
-		perm := cctx.String("perm")
-		idx := 0
-		for i, p := range apistruct.AllPermissions {
-			if auth.Permission(perm) == p {
-				idx = i + 1
-			}
-		}
-
-		if idx == 0 {
-			return fmt.Errorf("--perm flag has to be one of: %s", apistruct.AllPermissions)
+		perms, err := tokenPerms(cctx, napi)
+		if err != nil {
+			return err
 		}
 
-		// slice on [:idx] so for example: 'sign' gives you [read, write, sign]
-		token, err := napi.AuthNew(ctx, apistruct.AllPermissions[:idx])
+		token, err := napi.AuthNew(ctx, perms)
 		if err != nil {
 			return err
 		}
@@ -6,14 +6,18 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/DataDog/zstd"
 	"github.com/filecoin-project/go-address"
 	cborutil "github.com/filecoin-project/go-cbor-util"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -25,15 +29,18 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/builtin/power"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 	cid "github.com/ipfs/go-cid"
+	"github.com/ipld/go-car"
 	"github.com/urfave/cli/v2"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"golang.org/x/xerrors"
 
 	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/client"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	types "github.com/filecoin-project/lotus/chain/types"
+	cliutil "github.com/filecoin-project/lotus/cli/util"
 )
 
 var chainCmd = &cli.Command{
@@ -51,10 +58,20 @@ var chainCmd = &cli.Command{
 		chainGetCmd,
 		chainBisectCmd,
 		chainExportCmd,
+		chainExportRangeCmd,
+		chainPruneCmd,
+		chainCheckCmd,
+		chainReorgsCmd,
+		chainGCCmd,
+		chainReindexHeightsCmd,
 		slashConsensusFault,
 		chainGasPriceCmd,
+		chainFeeForecastCmd,
 		chainInspectUsage,
 		chainDecodeCmd,
+		chainBeaconCmd,
+		chainGenesisInfoCmd,
+		chainProbeCmd,
 	},
 }
 
@@ -1022,7 +1039,10 @@ var chainExportCmd = &cli.Command{
 	Name:      "export",
 	Usage:     "export chain to a car file",
 	ArgsUsage: "[outputPath]",
+	Description: `Pass '-' as the output path to write the export to stdout; --compress zstd
+   compresses it on the fly, and the daemon imports compressed exports as they are.`,
 	Flags: []cli.Flag{
+		compressFlag,
 		&cli.StringFlag{
 			Name: "tipset",
 		},
@@ -1033,6 +1053,10 @@ var chainExportCmd = &cli.Command{
 		&cli.BoolFlag{
 			Name: "skip-old-msgs",
 		},
+		&cli.StringFlag{
+			Name:  "base",
+			Usage: "only export objects not already in the snapshot with this head tipset, exported with the same flags",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
@@ -1051,14 +1075,14 @@ var chainExportCmd = &cli.Command{
 			return fmt.Errorf("\"recent-stateroots\" has to be greater than %d", build.Finality)
 		}
 
-		fi, err := os.Create(cctx.Args().First())
+		fi, err := exportOutput(cctx.Args().First(), cctx.String(compressFlag.Name))
 		if err != nil {
 			return err
 		}
 		defer func() {
 			err := fi.Close()
 			if err != nil {
-				fmt.Printf("error closing output file: %+v", err)
+				fmt.Fprintf(os.Stderr, "error closing output file: %+v\n", err)
 			}
 		}()
 
@@ -1073,10 +1097,132 @@ var chainExportCmd = &cli.Command{
 			return fmt.Errorf("must pass recent stateroots along with skip-old-msgs")
 		}
 
-		stream, err := api.ChainExport(ctx, rsrs, skipold, ts.Key())
+		var stream <-chan []byte
+		if cctx.IsSet("base") {
+			base, err := ParseTipSetRef(ctx, api, cctx.String("base"))
+			if err != nil {
+				return xerrors.Errorf("parsing base tipset: %w", err)
+			}
+			stream, err = api.ChainExportIncremental(ctx, rsrs, skipold, base.Key(), ts.Key())
+			if err != nil {
+				return err
+			}
+		} else {
+			stream, err = api.ChainExport(ctx, rsrs, skipold, ts.Key())
+			if err != nil {
+				return err
+			}
+		}
+
+		var last bool
+		for b := range stream {
+			last = len(b) == 0
+
+			_, err := fi.Write(b)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !last {
+			return xerrors.Errorf("incomplete export (remote connection lost?)")
+		}
+
+		return nil
+	},
+}
+
+var chainExportRangeCmd = &cli.Command{
+	Name:      "export-range",
+	Usage:     "export a range of the chain to a car file",
+	ArgsUsage: "[outputPath]",
+	Flags: []cli.Flag{
+		compressFlag,
+		&cli.StringFlag{
+			Name:  "tipset",
+			Usage: "walk back along the chain of the given tipset instead of the current head",
+		},
+		&cli.Int64Flag{
+			Name:     "start",
+			Usage:    "lowest epoch to include in the export",
+			Required: true,
+		},
+		&cli.Int64Flag{
+			Name:  "end",
+			Usage: "highest epoch to include in the export (defaults to the tipset height)",
+		},
+		&cli.BoolFlag{
+			Name:  "headers",
+			Usage: "include block headers",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:  "messages",
+			Usage: "include messages",
+		},
+		&cli.BoolFlag{
+			Name:  "receipts",
+			Usage: "include message receipts",
+		},
+		&cli.BoolFlag{
+			Name:  "stateroots",
+			Usage: "include full state trees",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
 		if err != nil {
 			return err
 		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if !cctx.Args().Present() {
+			return fmt.Errorf("must specify filename to export chain to")
+		}
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+		if ts == nil {
+			ts, err = api.ChainHead(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		start := abi.ChainEpoch(cctx.Int64("start"))
+		end := ts.Height()
+		if cctx.IsSet("end") {
+			end = abi.ChainEpoch(cctx.Int64("end"))
+		}
+		if end > ts.Height() {
+			return fmt.Errorf("end epoch %d is above the tipset height %d", end, ts.Height())
+		}
+
+		opts := lapi.ChainExportRangeOpts{
+			Headers:    cctx.Bool("headers"),
+			Messages:   cctx.Bool("messages"),
+			Receipts:   cctx.Bool("receipts"),
+			StateRoots: cctx.Bool("stateroots"),
+		}
+
+		stream, err := api.ChainExportRange(ctx, ts.Key(), start, end, opts)
+		if err != nil {
+			return err
+		}
+
+		fi, err := exportOutput(cctx.Args().First(), cctx.String(compressFlag.Name))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := fi.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error closing output file: %+v\n", err)
+			}
+		}()
 
 		var last bool
 		for b := range stream {
@@ -1096,6 +1242,303 @@ var chainExportCmd = &cli.Command{
 	},
 }
 
+var compressFlag = &cli.StringFlag{
+	Name:  "compress",
+	Usage: "compress the export on the fly: none, zstd",
+	Value: "none",
+}
+
+// exportOutput opens the output of a chain export, the file at path or stdout
+// for '-', compressed with the given algorithm.
+func exportOutput(path, compress string) (io.WriteCloser, error) {
+	switch compress {
+	case "", "none", "zstd":
+	default:
+		return nil, xerrors.Errorf("unknown compression '%s', expected 'none' or 'zstd'", compress)
+	}
+
+	var out io.WriteCloser = nopWriteCloser{os.Stdout}
+	if path != "-" {
+		fi, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		out = fi
+	}
+
+	if compress != "zstd" {
+		return out, nil
+	}
+	return &zstdOutput{Writer: zstd.NewWriter(out), out: out}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// zstdOutput ends the zstd frame before closing the output.
+type zstdOutput struct {
+	*zstd.Writer
+	out io.Closer
+}
+
+func (o *zstdOutput) Close() error {
+	if err := o.Writer.Close(); err != nil {
+		_ = o.out.Close()
+		return xerrors.Errorf("ending zstd frame: %w", err)
+	}
+	return o.out.Close()
+}
+
+var chainPruneCmd = &cli.Command{
+	Name:  "prune",
+	Usage: "delete old state trees from the chain blockstore",
+	Description: `Deletes state trees older than the given number of epochs behind the chain head.
+   Block headers, messages and receipts are kept, so the chain can still be
+   validated and exported, but state queries at pruned heights will fail.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "keep-epochs",
+			Usage: "number of recent epochs to keep the state for",
+			Value: int64(build.Finality),
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only report how much space would be reclaimed",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		keep := abi.ChainEpoch(cctx.Int64("keep-epochs"))
+		if keep < build.Finality {
+			return fmt.Errorf("\"keep-epochs\" has to be at least %d", build.Finality)
+		}
+
+		res, err := api.ChainPrune(ctx, keep, cctx.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+
+		verb := "Pruned"
+		if res.DryRun {
+			verb = "Would prune"
+		}
+		fmt.Printf("%s %d state trees at or below height %d\n", verb, res.StateRoots, res.Boundary)
+		fmt.Printf("Objects: %d\n", res.Objects)
+		fmt.Printf("Size: %s (%d)\n", types.SizeStr(types.NewInt(res.Bytes)), res.Bytes)
+
+		return nil
+	},
+}
+
+var chainCheckCmd = &cli.Command{
+	Name:  "check",
+	Usage: "check the integrity of the chain blockstore",
+	Description: `Re-hashes every block in the chain blockstore and reports the blocks whose
+   data doesn't match their CID. With --repair, corrupt blocks are deleted and
+   fetched again from the network.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "repair",
+			Usage: "fetch corrupt blocks again from the network",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		res, err := api.ChainCheckBlockstore(ctx, cctx.Bool("repair"))
+		if err != nil {
+			return err
+		}
+
+		repaired := make(map[cid.Cid]struct{}, len(res.Repaired))
+		for _, c := range res.Repaired {
+			repaired[c] = struct{}{}
+		}
+		for _, c := range res.Corrupt {
+			status := "corrupt"
+			if _, ok := repaired[c]; ok {
+				status = "repaired"
+			}
+			fmt.Printf("%s: %s\n", c, status)
+		}
+
+		fmt.Printf("Checked %d blocks, %d corrupt, %d repaired\n", res.Checked, len(res.Corrupt), len(res.Repaired))
+		if len(res.Corrupt) > len(res.Repaired) {
+			return xerrors.Errorf("chain blockstore has %d corrupt blocks", len(res.Corrupt)-len(res.Repaired))
+		}
+		return nil
+	},
+}
+
+var chainReorgsCmd = &cli.Command{
+	Name:  "reorgs",
+	Usage: "list reorgs recorded by the node",
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "since",
+			Usage: "only show reorgs to a head at or above this height",
+		},
+		&cli.BoolFlag{
+			Name:  "follow",
+			Usage: "keep printing new reorgs as they happen",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		var notifs <-chan *lapi.ChainReorg
+		if cctx.Bool("follow") {
+			// subscribe first so that nothing is missed between the two calls
+			notifs, err = api.ChainReorgNotify(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		reorgs, err := api.ChainGetReorgs(ctx, abi.ChainEpoch(cctx.Int64("since")))
+		if err != nil {
+			return err
+		}
+
+		var last uint64
+		printReorg := func(r *lapi.ChainReorg) {
+			if r.Seq <= last {
+				return
+			}
+			last = r.Seq
+			fmt.Printf("%d\t%s\tdepth %d\tfork %d\t%d -> %d\tdropped %d blocks, %d messages\n",
+				r.Seq, time.Unix(int64(r.Timestamp), 0).Format(time.Stamp), r.Depth, r.ForkHeight,
+				r.FromHeight, r.ToHeight, len(r.DroppedBlocks), len(r.DroppedMessages))
+		}
+
+		for _, r := range reorgs {
+			printReorg(r)
+		}
+
+		if notifs == nil {
+			return nil
+		}
+		for r := range notifs {
+			printReorg(r)
+		}
+
+		return nil
+	},
+}
+
+var chainReindexHeightsCmd = &cli.Command{
+	Name:  "reindex-heights",
+	Usage: "rebuild the index used to look up tipsets by height",
+	Description: `Drops the persistent height index and rebuilds it from the given tipset
+   (the chain head by default) down to genesis. Lookups by height fall back to
+   walking the chain while the index is rebuilt.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "tipset",
+			Usage: "tipset to index the chain of",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+		if ts == nil {
+			ts, err = api.ChainHead(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := api.ChainReindexHeights(ctx, ts.Key()); err != nil {
+			return err
+		}
+
+		fmt.Printf("Reindexed heights 0-%d\n", ts.Height())
+		return nil
+	},
+}
+
+var chainGCCmd = &cli.Command{
+	Name:  "gc",
+	Usage: "reclaim disk space used by the chain blockstore",
+	Description: `Runs value log garbage collection on the chain blockstore while the node keeps running.
+   With --compact, the LSM tree is also flattened afterwards, which takes longer
+   but reclaims more space.`,
+	Flags: []cli.Flag{
+		&cli.Float64Flag{
+			Name:  "discard-ratio",
+			Usage: "fraction of a value log file which must be garbage before the file is rewritten",
+			Value: 0.5,
+		},
+		&cli.BoolFlag{
+			Name:  "compact",
+			Usage: "compact the LSM tree after value log GC",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		progress, err := api.ChainBlockstoreGC(ctx, lapi.BlockstoreGCOpts{
+			DiscardRatio: cctx.Float64("discard-ratio"),
+			Compact:      cctx.Bool("compact"),
+		})
+		if err != nil {
+			return err
+		}
+
+		var done bool
+		for p := range progress {
+			if p.Error != "" {
+				return xerrors.Errorf("blockstore GC failed: %s", p.Error)
+			}
+
+			fmt.Printf("%s: %s (rewrites: %d, lsm: %s, vlog: %s)\n", p.Namespace, p.Stage, p.Rewrites,
+				types.SizeStr(types.NewInt(uint64(p.LSMSize))), types.SizeStr(types.NewInt(uint64(p.VlogSize))))
+			done = p.Stage == "done"
+		}
+
+		if !done {
+			return xerrors.Errorf("blockstore GC didn't finish (remote connection lost?)")
+		}
+
+		return nil
+	},
+}
+
 var slashConsensusFault = &cli.Command{
 	Name:      "slash-consensus",
 	Usage:     "Report consensus fault",
@@ -1237,6 +1680,58 @@ var chainGasPriceCmd = &cli.Command{
 	},
 }
 
+var chainFeeForecastCmd = &cli.Command{
+	Name:  "fee-forecast",
+	Usage: "Project the base fee and show the gas premiums of recent messages",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "epochs",
+			Usage: "number of epochs to project the base fee over",
+			Value: 10,
+		},
+		&cli.Uint64Flag{
+			Name:  "lookback",
+			Usage: "number of recent epochs to sample",
+			Value: 20,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		ff, err := api.GasEstimateFeeForecast(ctx, cctx.Uint64("epochs"), cctx.Uint64("lookback"), types.EmptyTSK)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Sampled %d epochs (%d blocks) up to %d, blocks %.1f%% full\n", ff.Epochs, ff.Blocks, ff.Height, ff.Fullness*100)
+
+		fmt.Println("\nProjected base fee:")
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		for i, bf := range ff.BaseFees {
+			fmt.Fprintf(tw, "%d\t%s\t(%s/gas)\n", ff.Height+abi.ChainEpoch(i)+1, bf, types.FIL(bf))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Println("\nGas premium percentiles:")
+		if len(ff.Premiums) == 0 {
+			fmt.Println("no messages in sampled blocks")
+			return nil
+		}
+		tw = tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		for _, p := range ff.Premiums {
+			fmt.Fprintf(tw, "p%d\t%s\n", p.Percentile, p.GasPremium)
+		}
+		return tw.Flush()
+	},
+}
+
 var chainDecodeCmd = &cli.Command{
 	Name:  "decode",
 	Usage: "decode various types",
@@ -1301,3 +1796,302 @@ var chainDecodeParamsCmd = &cli.Command{
 		return nil
 	},
 }
+
+var chainBeaconCmd = &cli.Command{
+	Name:      "beacon",
+	Usage:     "Print the drand beacon schedule and the beacon entry of an epoch",
+	ArgsUsage: "[epoch (defaults to the head)]",
+	Description: `Getting the entry of an epoch which wasn't reached yet waits until drand
+   produces it.`,
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		epoch := head.Height()
+		if cctx.Args().Present() {
+			e, err := strconv.ParseInt(cctx.Args().First(), 10, 64)
+			if err != nil {
+				return xerrors.Errorf("parsing epoch: %w", err)
+			}
+			epoch = abi.ChainEpoch(e)
+		}
+
+		schedule, err := api.BeaconSchedule(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the beacon of an epoch is the last one starting at or before it
+		current := 0
+		for i, b := range schedule {
+			if epoch >= b.Start {
+				current = i
+			}
+		}
+
+		fmt.Println("Schedule:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "\tStart\tChain\tPeriod\tGenesis\tServers\n")
+		for i, b := range schedule {
+			marker := ""
+			if i == current {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", marker, b.Start, b.ChainHash, b.Period,
+				time.Unix(b.GenesisTime, 0).Format(time.RFC3339), strings.Join(b.Servers, ","))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		entry, err := api.BeaconGetEntry(ctx, epoch)
+		if err != nil {
+			return xerrors.Errorf("getting beacon entry for epoch %d: %w", epoch, err)
+		}
+
+		fmt.Printf("\nEntry for epoch %d:\n", epoch)
+		fmt.Printf("Round: %d\n", entry.Round)
+		fmt.Printf("Data:  %x\n", entry.Data)
+		return nil
+	},
+}
+
+var chainGenesisInfoCmd = &cli.Command{
+	Name:  "genesis-info",
+	Usage: "Print the network fingerprint of the node and check its genesis",
+	Description: `The fingerprint is made of the genesis block CID and a hash of the upgrade
+   schedule of this build. The genesis of the node is checked against the genesis
+   built into this binary, or the one given with --expect-genesis; the command
+   fails if they, or the network name given with --expect-network, don't match.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "expect-genesis",
+			Usage: "expected genesis block CID (defaults to the built-in genesis)",
+		},
+		&cli.StringFlag{
+			Name:  "expect-network",
+			Usage: "expected network name",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		gen, err := api.ChainGetGenesis(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting genesis: %w", err)
+		}
+		if len(gen.Cids()) != 1 {
+			return xerrors.Errorf("expected the genesis tipset to have one block, got %d", len(gen.Cids()))
+		}
+		genCid := gen.Cids()[0]
+
+		name, err := api.StateNetworkName(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting network name: %w", err)
+		}
+
+		fmt.Printf("Network:          %s\n", name)
+		fmt.Printf("Genesis:          %s\n", genCid)
+		fmt.Printf("Genesis time:     %s\n", time.Unix(int64(gen.MinTimestamp()), 0).Format(time.RFC3339))
+		fmt.Printf("Upgrade schedule: %x\n", stmgr.DefaultUpgradeSchedule().Fingerprint())
+
+		var expected cid.Cid
+		if s := cctx.String("expect-genesis"); s != "" {
+			expected, err = cid.Decode(s)
+			if err != nil {
+				return xerrors.Errorf("parsing expected genesis CID: %w", err)
+			}
+		} else if genBytes := build.MaybeGenesis(); len(genBytes) > 0 {
+			cr, err := car.NewCarReader(bytes.NewReader(genBytes))
+			if err != nil {
+				return xerrors.Errorf("reading built-in genesis: %w", err)
+			}
+			if len(cr.Header.Roots) != 1 {
+				return xerrors.New("expected built-in genesis to have one root")
+			}
+			expected = cr.Header.Roots[0]
+		}
+
+		var mismatch bool
+		if expected.Defined() {
+			if expected != genCid {
+				fmt.Printf("\nGENESIS MISMATCH: expected %s\n", expected)
+				mismatch = true
+			} else {
+				fmt.Println("\nGenesis matches the expected one")
+			}
+		} else {
+			fmt.Println("\nNo built-in genesis to check against, use --expect-genesis")
+		}
+
+		if n := cctx.String("expect-network"); n != "" {
+			if string(name) != n {
+				fmt.Printf("NETWORK MISMATCH: expected %s\n", n)
+				mismatch = true
+			} else {
+				fmt.Println("Network name matches the expected one")
+			}
+		}
+
+		if mismatch {
+			return xerrors.New("the node is not on the expected network")
+		}
+		return nil
+	},
+}
+
+var chainProbeCmd = &cli.Command{
+	Name:      "probe",
+	Usage:     "Check how much chain history a remote node serves",
+	ArgsUsage: "<[token:]api-endpoint>",
+	Description: `Sample queries against the node at the given API endpoint to find the
+   oldest epochs it serves tipsets, state, messages and receipts for. The
+   endpoint is given like in FULLNODE_API_INFO, as an API multiaddr or URL,
+   optionally prefixed with a token.
+
+   The availability is assumed to be contiguous up to the head; the --samples
+   epochs after the oldest available one are queried to find gaps.`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "time after which a query counts as unavailable",
+			Value: 30 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "samples",
+			Usage: "number of epochs checked for gaps after the oldest available one",
+			Value: 10,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return ShowHelp(cctx, xerrors.Errorf("must pass the API endpoint of the node to probe"))
+		}
+
+		ainfo := cliutil.ParseApiInfo(cctx.Args().First())
+		addr, err := ainfo.DialArgs()
+		if err != nil {
+			return xerrors.Errorf("parsing API endpoint: %w", err)
+		}
+		var headers http.Header
+		if len(ainfo.Token) != 0 {
+			headers = ainfo.AuthHeader()
+		}
+
+		ctx := ReqContext(cctx)
+
+		api, closer, err := client.NewFullNodeRPC(ctx, addr, headers)
+		if err != nil {
+			return xerrors.Errorf("connecting to %s: %w", addr, err)
+		}
+		defer closer()
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting chain head: %w", err)
+		}
+		fmt.Printf("Head: %d (%s)\n", head.Height(), time.Unix(int64(head.MinTimestamp()), 0).Format(time.RFC3339))
+
+		timeout := cctx.Duration("timeout")
+		probe := func(h abi.ChainEpoch, check func(context.Context, *types.TipSet) error) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			ts, err := api.ChainGetTipSetByHeight(ctx, h, head.Key())
+			if err != nil {
+				return xerrors.Errorf("getting tipset: %w", err)
+			}
+			if check == nil {
+				return nil
+			}
+			return check(ctx, ts)
+		}
+
+		probes := []struct {
+			name  string
+			check func(context.Context, *types.TipSet) error
+		}{
+			{"Tipsets", nil},
+			{"State", func(ctx context.Context, ts *types.TipSet) error {
+				_, err := api.StateGetActor(ctx, builtin.StoragePowerActorAddr, ts.Key())
+				return err
+			}},
+			{"Messages", func(ctx context.Context, ts *types.TipSet) error {
+				_, err := api.ChainGetBlockMessages(ctx, ts.Cids()[0])
+				return err
+			}},
+			{"Receipts", func(ctx context.Context, ts *types.TipSet) error {
+				_, err := api.ChainGetParentReceipts(ctx, ts.Cids()[0])
+				return err
+			}},
+		}
+
+		for _, p := range probes {
+			if err := probe(head.Height(), p.check); err != nil {
+				fmt.Printf("%s: unavailable at the head: %s\n", p.name, err)
+				continue
+			}
+
+			oldest := oldestAvailable(0, head.Height(), func(h abi.ChainEpoch) bool {
+				return probe(h, p.check) == nil
+			})
+
+			var gaps []abi.ChainEpoch
+			for _, h := range sampleEpochs(oldest, head.Height(), cctx.Int("samples")) {
+				if err := probe(h, p.check); err != nil {
+					gaps = append(gaps, h)
+				}
+			}
+
+			fmt.Printf("%s: available from epoch %s\n", p.name, EpochTime(head.Height(), oldest))
+			if len(gaps) > 0 {
+				fmt.Printf("\tunavailable at sampled epochs %v\n", gaps)
+			}
+		}
+
+		return nil
+	},
+}
+
+// oldestAvailable returns the lowest epoch in [lo, hi] for which avail is
+// true, with a binary search. avail must be true for hi, and for all epochs
+// after the first available one.
+func oldestAvailable(lo, hi abi.ChainEpoch, avail func(abi.ChainEpoch) bool) abi.ChainEpoch {
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if avail(mid) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return hi
+}
+
+// sampleEpochs returns n epochs spread evenly between from and to, excluding
+// both.
+func sampleEpochs(from, to abi.ChainEpoch, n int) []abi.ChainEpoch {
+	var out []abi.ChainEpoch
+	for i := 1; i <= n; i++ {
+		h := from + (to-from)*abi.ChainEpoch(i)/abi.ChainEpoch(n+1)
+		if h <= from || h >= to || (len(out) > 0 && out[len(out)-1] == h) {
+			continue
+		}
+		out = append(out, h)
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestOldestAvailable(t *testing.T) {
+	var probed int
+	oldest := oldestAvailable(0, 100000, func(h abi.ChainEpoch) bool {
+		probed++
+		return h >= 71234
+	})
+	require.Equal(t, abi.ChainEpoch(71234), oldest)
+	require.LessOrEqual(t, probed, 17)
+
+	require.Equal(t, abi.ChainEpoch(0), oldestAvailable(0, 100, func(abi.ChainEpoch) bool { return true }))
+
+	require.Equal(t, []abi.ChainEpoch{25, 50, 75}, sampleEpochs(0, 100, 3))
+	require.Equal(t, []abi.ChainEpoch{11}, sampleEpochs(10, 12, 5))
+	require.Empty(t, sampleEpochs(10, 11, 5))
+}
+
+func TestExportOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lotus-export-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	data := []byte("car export data, car export data, car export data")
+	for _, compress := range []string{"none", "zstd"} {
+		path := filepath.Join(dir, compress)
+		out, err := exportOutput(path, compress)
+		require.NoError(t, err)
+		_, err = out.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, out.Close())
+
+		b, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		if compress == "zstd" {
+			b, err = zstd.Decompress(nil, b)
+			require.NoError(t, err)
+		}
+		require.Equal(t, data, b)
+	}
+
+	_, err = exportOutput(filepath.Join(dir, "gz"), "gzip")
+	require.Error(t, err)
+}
@@ -74,6 +74,7 @@ var clientCmd = &cli.Command{
 		WithCategory("storage", clientQueryAskCmd),
 		WithCategory("storage", clientListDeals),
 		WithCategory("storage", clientGetDealCmd),
+		WithCategory("storage", clientAuthorizeDelegateCmd),
 		WithCategory("data", clientImportCmd),
 		WithCategory("data", clientDropCmd),
 		WithCategory("data", clientLocalCmd),
@@ -1159,7 +1160,7 @@ func outputStorageDeals(ctx context.Context, out io.Writer, full lapi.FullNode,
 			}
 
 			price := types.FIL(types.BigMul(d.LocalDeal.PricePerEpoch, types.NewInt(d.LocalDeal.Duration)))
-			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%v\t%s\n", d.LocalDeal.CreationTime.Format(time.Stamp), d.LocalDeal.ProposalCid, d.LocalDeal.DealID, d.LocalDeal.Provider, dealStateString(color, d.LocalDeal.State), onChain, slashed, d.LocalDeal.PieceCID, types.SizeStr(types.NewInt(d.LocalDeal.Size)), price, d.LocalDeal.Duration, d.LocalDeal.Verified, d.LocalDeal.Message)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%v\t%s\n", d.LocalDeal.CreationTime.Format(time.Stamp), d.LocalDeal.ProposalCid, d.LocalDeal.DealID, d.LocalDeal.Provider, dealStateString(color, d.LocalDeal.State), onChain, slashed, d.LocalDeal.PieceCID, types.SizeStr(types.NewInt(d.LocalDeal.Size)), price, d.LocalDeal.Duration, d.LocalDeal.Verified, dealMessage(d.LocalDeal))
 		}
 		return w.Flush()
 	}
@@ -1206,13 +1207,22 @@ func outputStorageDeals(ctx context.Context, out io.Writer, full lapi.FullNode,
 			"Price":     price,
 			"Verified":  d.LocalDeal.Verified,
 			"Duration":  d.LocalDeal.Duration,
-			"Message":   d.LocalDeal.Message,
+			"Message":   dealMessage(d.LocalDeal),
 		})
 	}
 
 	return w.Flush(out)
 }
 
+// dealMessage shows the rejection code of rejected deals before the
+// provider's reason.
+func dealMessage(di lapi.DealInfo) string {
+	if di.Rejection != nil {
+		return fmt.Sprintf("rejected (%s): %s", di.Rejection.Code, di.Rejection.Reason)
+	}
+	return di.Message
+}
+
 func dealStateString(c bool, state storagemarket.StorageDealStatus) string {
 	s := storagemarket.DealStates[state]
 	if !c {
@@ -1328,6 +1338,75 @@ var clientInfoCmd = &cli.Command{
 	},
 }
 
+var clientAuthorizeDelegateCmd = &cli.Command{
+	Name:      "authorize-delegate",
+	Usage:     "Authorize an address to make storage deals on behalf of a funding address",
+	ArgsUsage: "<delegate address> <duration in epochs>",
+	Description: `Prints an authorization, signed with the key of the funding address, for the
+   delegate address to make storage deals on its behalf for the given duration.
+   The delegate signs its deal proposals with its own key and pays them from its
+   market escrow, funded from its own wallet when making deals, which the funding
+   address can top up with 'lotus send --from <funder> <delegate> <amount>'. Providers
+   only accepting the deals of some funders import the authorization with
+   'lotus-miner storage-deals authorizations import' to accept the deals of the
+   delegate.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "funding address, defaults to the default wallet address",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return ShowHelp(cctx, fmt.Errorf("must pass the delegate address and the duration"))
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		delegate, err := address.NewFromString(cctx.Args().Get(0))
+		if err != nil {
+			return xerrors.Errorf("parsing delegate address: %w", err)
+		}
+
+		duration, err := strconv.ParseInt(cctx.Args().Get(1), 10, 64)
+		if err != nil || duration <= 0 {
+			return xerrors.Errorf("duration must be a positive number of epochs: %s", cctx.Args().Get(1))
+		}
+
+		var funder address.Address
+		if cctx.IsSet("from") {
+			funder, err = address.NewFromString(cctx.String("from"))
+		} else {
+			funder, err = api.WalletDefaultAddress(ctx)
+		}
+		if err != nil {
+			return xerrors.Errorf("getting funding address: %w", err)
+		}
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		auth, err := api.ClientAuthorizeDelegate(ctx, funder, delegate, head.Height()+abi.ChainEpoch(duration))
+		if err != nil {
+			return err
+		}
+
+		b, err := json.MarshalIndent(auth, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	},
+}
+
 var clientRestartTransfer = &cli.Command{
 	Name:  "restart-transfer",
 	Usage: "Force restart a stalled data transfer",
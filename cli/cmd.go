@@ -11,6 +11,7 @@ import (
 
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/mitchellh/go-homedir"
+	"github.com/multiformats/go-multiaddr"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
@@ -198,6 +199,16 @@ func GetFullNodeAPI(ctx *cli.Context) (api.FullNode, jsonrpc.ClientCloser, error
 		return tn.(api.FullNode), func() {}, nil
 	}
 
+	// the cbor transport of the daemon, served with 'lotus daemon --api-cbor'
+	if info, ok := os.LookupEnv("FULLNODE_CBOR_API_INFO"); ok {
+		ainfo := cliutil.ParseApiInfo(info)
+		ma, err := multiaddr.NewMultiaddr(ainfo.Addr)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("parsing FULLNODE_CBOR_API_INFO address: %w", err)
+		}
+		return client.NewFullNodeCBORRPC(ctx.Context, ma, string(ainfo.Token))
+	}
+
 	addr, headers, err := GetRawAPI(ctx, repo.FullNode)
 	if err != nil {
 		return nil, nil, err
@@ -282,6 +293,7 @@ var Commands = []*cli.Command{
 	WithCategory("developer", mpoolCmd),
 	WithCategory("developer", stateCmd),
 	WithCategory("developer", chainCmd),
+	WithCategory("developer", indexCmd),
 	WithCategory("developer", logCmd),
 	WithCategory("developer", waitApiCmd),
 	WithCategory("developer", fetchParamCmd),
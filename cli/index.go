@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+var indexCmd = &cli.Command{
+	Name:  "index",
+	Usage: "Manage the node's chain indices",
+	Subcommands: []*cli.Command{
+		indexMessagesCmd,
+		indexBackfillCmd,
+	},
+}
+
+var indexMessagesCmd = &cli.Command{
+	Name:  "messages",
+	Usage: "index the messages executed in a range of the chain",
+	Description: `Records where the messages executed between the given epochs were executed, so
+   that StateSearchMsg and StateGetReceipt don't have to walk the chain to find
+   them, and which messages each address sent and received, for 'lotus wallet
+   history'. Messages are indexed during sync; use this for older parts of the
+   chain.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "from",
+			Usage: "first epoch to index",
+		},
+		&cli.Int64Flag{
+			Name:  "to",
+			Usage: "last epoch to index (default: the chain head)",
+			Value: -1,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		to := abi.ChainEpoch(cctx.Int64("to"))
+		if to < 0 {
+			head, err := api.ChainHead(ctx)
+			if err != nil {
+				return err
+			}
+			to = head.Height()
+		}
+
+		n, err := api.ChainIndexMessages(ctx, abi.ChainEpoch(cctx.Int64("from")), to)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Indexed %d messages executed at epochs %d-%d\n", n, cctx.Int64("from"), to)
+		return nil
+	},
+}
+
+var indexBackfillCmd = &cli.Command{
+	Name:  "backfill",
+	Usage: "index the messages of a historical range of the chain, resumably",
+	Description: `Indexes the messages executed between the given epochs like 'lotus index messages',
+   from the top of the range down, printing progress. Progress is checkpointed in
+   the node, so running the command again for the same range after it was
+   interrupted resumes where it stopped; without --to, the interrupted backfill
+   starting at --from is resumed.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "from",
+			Usage: "first epoch to index",
+		},
+		&cli.Int64Flag{
+			Name:  "to",
+			Usage: "last epoch to index (default: the interrupted backfill's, or the chain head)",
+			Value: -1,
+		},
+		&cli.Float64Flag{
+			Name:  "max-rate",
+			Usage: "maximum number of tipsets indexed per second, 0 for no limit",
+		},
+		&cli.BoolFlag{
+			Name:  "restart",
+			Usage: "don't resume an interrupted backfill of the range",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		progress, err := napi.ChainBackfillMessageIndex(ctx, api.IndexBackfillOpts{
+			From:                abi.ChainEpoch(cctx.Int64("from")),
+			To:                  abi.ChainEpoch(cctx.Int64("to")),
+			MaxTipSetsPerSecond: cctx.Float64("max-rate"),
+			Restart:             cctx.Bool("restart"),
+		})
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		var last api.IndexBackfillProgress
+		first := true
+		for p := range progress {
+			if first {
+				first = false
+				if p.Resumed {
+					fmt.Printf("Resuming the backfill of epochs %d-%d at epoch %d\n", p.From, p.To, p.Next)
+				} else {
+					fmt.Printf("Backfilling epochs %d-%d\n", p.From, p.To)
+				}
+			}
+			last = p
+
+			if p.Done {
+				break
+			}
+			if p.TipSets == 0 {
+				continue
+			}
+
+			// tipsets are indexed from the top of the range down
+			left := int64(p.Next - p.From + 1)
+			rate := float64(p.TipSets) / time.Since(start).Seconds()
+			eta := "?"
+			if rate > 0 {
+				eta = (time.Duration(float64(left)/rate) * time.Second).String()
+			}
+			fmt.Printf("epoch %d: %d tipsets, %d messages indexed (%.1f tipsets/s, %d epochs left, eta %s)\n",
+				p.Next, p.TipSets, p.Messages, rate, left, eta)
+		}
+
+		if !last.Done {
+			return xerrors.Errorf("backfill interrupted at epoch %d, run again to resume", last.Next)
+		}
+		if last.Error != "" {
+			return xerrors.Errorf("backfill failed at epoch %d (run again to resume): %s", last.Next, last.Error)
+		}
+
+		fmt.Printf("Indexed %d messages of %d tipsets in %s\n", last.Messages, last.TipSets, time.Since(start).Truncate(time.Second))
+		return nil
+	},
+}
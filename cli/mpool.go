@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	stdbig "math/big"
+	"os"
 	"sort"
 	"strconv"
+	"text/tabwriter"
 
 	cid "github.com/ipfs/go-cid"
 	"github.com/urfave/cli/v2"
@@ -17,7 +19,6 @@ import (
 
 	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
-	"github.com/filecoin-project/lotus/chain/messagepool"
 	"github.com/filecoin-project/lotus/chain/types"
 )
 
@@ -27,12 +28,16 @@ var mpoolCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		mpoolPending,
 		mpoolClear,
+		mpoolLocalCmd,
+		mpoolAbandonCmd,
+		mpoolScheduledCmd,
 		mpoolSub,
 		mpoolStat,
 		mpoolReplaceCmd,
 		mpoolFindCmd,
 		mpoolConfig,
 		mpoolGasPerfCmd,
+		mpoolPushSignedFile,
 	},
 }
 
@@ -132,6 +137,168 @@ var mpoolClear = &cli.Command{
 	},
 }
 
+var mpoolLocalCmd = &cli.Command{
+	Name:  "local",
+	Usage: "List the messages pushed through this node which are republished until included",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "cids",
+			Usage: "only print cids of messages in output",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		msgs, err := api.MpoolLocalMessages(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			if cctx.Bool("cids") {
+				fmt.Println(msg.Cid())
+				continue
+			}
+
+			out, err := json.MarshalIndent(msg, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+		}
+
+		return nil
+	},
+}
+
+var mpoolAbandonCmd = &cli.Command{
+	Name:      "abandon",
+	Usage:     "Stop republishing local messages and remove them from the mpool",
+	ArgsUsage: "[message cid...]",
+	Action: func(cctx *cli.Context) error {
+		if !cctx.Args().Present() {
+			return ShowHelp(cctx, fmt.Errorf("must pass the cids of the messages to abandon"))
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		for _, arg := range cctx.Args().Slice() {
+			mcid, err := cid.Decode(arg)
+			if err != nil {
+				return xerrors.Errorf("parsing message cid %q: %w", arg, err)
+			}
+
+			if err := api.MpoolAbandon(ctx, mcid); err != nil {
+				return xerrors.Errorf("abandoning %s: %w", mcid, err)
+			}
+			fmt.Printf("abandoned %s\n", mcid)
+		}
+
+		return nil
+	},
+}
+
+var mpoolScheduledCmd = &cli.Command{
+	Name:  "scheduled",
+	Usage: "Manage the messages scheduled with 'lotus send --at-epoch/--max-base-fee'",
+	Subcommands: []*cli.Command{
+		mpoolScheduledListCmd,
+		mpoolScheduledCancelCmd,
+	},
+}
+
+var mpoolScheduledListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List the scheduled messages",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		scheduled, err := api.MpoolScheduled(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "ID\tFrom\tTo\tValue\tAt Epoch\tMax Base Fee (aFIL)\tState\tMessage\n")
+		for _, sm := range scheduled {
+			msg := sm.Message
+			if sm.Signed != nil {
+				msg = &sm.Signed.Message
+			}
+
+			maxFee := "-"
+			if sm.Schedule.MaxBaseFee.Int != nil && !sm.Schedule.MaxBaseFee.IsZero() {
+				maxFee = sm.Schedule.MaxBaseFee.String()
+			}
+
+			state := sm.State.String()
+			if sm.Error != "" {
+				state += ": " + sm.Error
+			}
+
+			mcid := "-"
+			if sm.Signed != nil {
+				mcid = sm.Signed.Cid().String()
+			}
+
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", sm.ID, msg.From, msg.To,
+				types.FIL(msg.Value), sm.Schedule.AtEpoch, maxFee, state, mcid)
+		}
+		return tw.Flush()
+	},
+}
+
+var mpoolScheduledCancelCmd = &cli.Command{
+	Name:      "cancel",
+	Usage:     "Cancel pending scheduled messages, or remove the others from the list",
+	ArgsUsage: "[id...]",
+	Action: func(cctx *cli.Context) error {
+		if !cctx.Args().Present() {
+			return ShowHelp(cctx, fmt.Errorf("must pass the IDs of the scheduled messages"))
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		for _, arg := range cctx.Args().Slice() {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return xerrors.Errorf("parsing ID %q: %w", arg, err)
+			}
+
+			if err := api.MpoolScheduledCancel(ctx, id); err != nil {
+				return xerrors.Errorf("cancelling %d: %w", id, err)
+			}
+			fmt.Printf("cancelled %d\n", id)
+		}
+
+		return nil
+	},
+}
+
 var mpoolSub = &cli.Command{
 	Name:  "sub",
 	Usage: "Subscribe to mpool changes",
@@ -331,7 +498,7 @@ var mpoolReplaceCmd = &cli.Command{
 		},
 		&cli.Int64Flag{
 			Name:  "gas-limit",
-			Usage: "gas price for new message",
+			Usage: "gas limit for new message, the limit of the replaced message is kept if not set",
 		},
 		&cli.BoolFlag{
 			Name:  "auto",
@@ -339,7 +506,7 @@ var mpoolReplaceCmd = &cli.Command{
 		},
 		&cli.StringFlag{
 			Name:  "max-fee",
-			Usage: "Spend up to X FIL for this message (applicable for auto mode)",
+			Usage: "Spend up to X FIL for this message (applicable when the fee cap is estimated)",
 		},
 	},
 	ArgsUsage: "<from nonce> | <message-cid>",
@@ -386,79 +553,45 @@ var mpoolReplaceCmd = &cli.Command{
 			return cli.ShowCommandHelp(cctx, cctx.Command.Name)
 		}
 
-		ts, err := api.ChainHead(ctx)
-		if err != nil {
-			return xerrors.Errorf("getting chain head: %w", err)
+		if !cctx.Bool("auto") && !cctx.IsSet("gas-premium") && !cctx.IsSet("gas-feecap") {
+			return xerrors.Errorf("specify --auto or at least one of --gas-premium and --gas-feecap")
 		}
 
-		pending, err := api.MpoolPending(ctx, ts.Key())
-		if err != nil {
-			return err
+		// zero values are estimated by the node
+		spec := &lapi.MpoolReplaceSpec{
+			GasLimit:   cctx.Int64("gas-limit"),
+			GasPremium: big.Zero(),
+			GasFeeCap:  big.Zero(),
+			MaxFee:     big.Zero(),
 		}
-
-		var found *types.SignedMessage
-		for _, p := range pending {
-			if p.Message.From == from && p.Message.Nonce == nonce {
-				found = p
-				break
-			}
-		}
-
-		if found == nil {
-			return fmt.Errorf("no pending message found from %s with nonce %d", from, nonce)
-		}
-
-		msg := found.Message
-
-		if cctx.Bool("auto") {
-			minRBF := messagepool.ComputeMinRBF(msg.GasPremium)
-
-			var mss *lapi.MessageSendSpec
-			if cctx.IsSet("max-fee") {
-				maxFee, err := types.BigFromString(cctx.String("max-fee"))
+		if !cctx.Bool("auto") {
+			if cctx.IsSet("gas-premium") {
+				spec.GasPremium, err = types.BigFromString(cctx.String("gas-premium"))
 				if err != nil {
-					return fmt.Errorf("parsing max-spend: %w", err)
-				}
-				mss = &lapi.MessageSendSpec{
-					MaxFee: maxFee,
+					return fmt.Errorf("parsing gas-premium: %w", err)
 				}
 			}
-
-			// msg.GasLimit = 0 // TODO: need to fix the way we estimate gas limits to account for the messages already being in the mempool
-			msg.GasFeeCap = abi.NewTokenAmount(0)
-			msg.GasPremium = abi.NewTokenAmount(0)
-			retm, err := api.GasEstimateMessageGas(ctx, &msg, mss, types.EmptyTSK)
-			if err != nil {
-				return fmt.Errorf("failed to estimate gas values: %w", err)
-			}
-
-			msg.GasPremium = big.Max(retm.GasPremium, minRBF)
-			msg.GasFeeCap = big.Max(retm.GasFeeCap, msg.GasPremium)
-			messagepool.CapGasFee(&msg, mss.Get().MaxFee)
-		} else {
-			msg.GasLimit = cctx.Int64("gas-limit")
-			msg.GasPremium, err = types.BigFromString(cctx.String("gas-premium"))
-			if err != nil {
-				return fmt.Errorf("parsing gas-premium: %w", err)
+			if cctx.IsSet("gas-feecap") {
+				spec.GasFeeCap, err = types.BigFromString(cctx.String("gas-feecap"))
+				if err != nil {
+					return fmt.Errorf("parsing gas-feecap: %w", err)
+				}
 			}
-			// TODO: estimate fee cap here
-			msg.GasFeeCap, err = types.BigFromString(cctx.String("gas-feecap"))
+		}
+		if cctx.IsSet("max-fee") {
+			spec.MaxFee, err = types.BigFromString(cctx.String("max-fee"))
 			if err != nil {
-				return fmt.Errorf("parsing gas-feecap: %w", err)
+				return fmt.Errorf("parsing max-fee: %w", err)
 			}
 		}
 
-		smsg, err := api.WalletSignMessage(ctx, msg.From, &msg)
-		if err != nil {
-			return fmt.Errorf("failed to sign message: %w", err)
-		}
-
-		cid, err := api.MpoolPush(ctx, smsg)
+		smsg, err := api.MpoolReplace(ctx, from, nonce, spec)
 		if err != nil {
-			return fmt.Errorf("failed to push new message to mempool: %w", err)
+			return fmt.Errorf("failed to replace message: %w", err)
 		}
 
-		fmt.Println("new message cid: ", cid)
+		fmt.Printf("new gas premium: %s, gas fee cap: %s, gas limit: %d\n", smsg.Message.GasPremium, smsg.Message.GasFeeCap, smsg.Message.GasLimit)
+		fmt.Println("new message cid: ", smsg.Cid())
 		return nil
 	},
 }
@@ -576,10 +709,14 @@ var mpoolConfig = &cli.Command{
 
 			fmt.Println(string(bytes))
 		} else {
-			cfg := new(types.MpoolConfig)
+			// fields missing in the new config keep their current values
+			cfg, err := api.MpoolGetConfig(ctx)
+			if err != nil {
+				return err
+			}
 			bytes := []byte(cctx.Args().Get(0))
 
-			err := json.Unmarshal(bytes, cfg)
+			err = json.Unmarshal(bytes, cfg)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
+	"github.com/filecoin-project/lotus/node/repo"
+)
+
+// messageFile is the file of a message signed offline: created unsigned by
+// 'lotus send --create-unsigned' on an online node, signed by 'lotus wallet
+// sign-message-file' on the machine holding the key, and pushed by 'lotus
+// mpool push-signed-file'.
+type messageFile struct {
+	// Message is the message for review, Cbor its canonical encoding in hex,
+	// which is what gets signed; they must match
+	Message *types.Message
+	Cbor    string
+
+	Signature *crypto.Signature `json:",omitempty"`
+}
+
+func writeMessageFile(path string, msg *types.Message, sig *crypto.Signature) error {
+	var buf bytes.Buffer
+	if err := msg.MarshalCBOR(&buf); err != nil {
+		return xerrors.Errorf("encoding message: %w", err)
+	}
+
+	b, err := json.MarshalIndent(&messageFile{
+		Message:   msg,
+		Cbor:      hex.EncodeToString(buf.Bytes()),
+		Signature: sig,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func readMessageFile(path string) (*messageFile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mf messageFile
+	if err := json.Unmarshal(b, &mf); err != nil {
+		return nil, xerrors.Errorf("decoding message file: %w", err)
+	}
+
+	cb, err := hex.DecodeString(mf.Cbor)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding message cbor: %w", err)
+	}
+	msg, err := types.DecodeMessage(cb)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding message cbor: %w", err)
+	}
+	if mf.Message == nil || mf.Message.Cid() != msg.Cid() {
+		return nil, xerrors.Errorf("the message doesn't match its cbor encoding")
+	}
+	mf.Message = msg
+
+	if err := msg.ValidForBlockInclusion(0); err != nil {
+		return nil, xerrors.Errorf("invalid message: %w", err)
+	}
+
+	return &mf, nil
+}
+
+func printMessageSummary(msg *types.Message) {
+	fmt.Fprintf(os.Stderr, "Message %s\n", msg.Cid())
+	fmt.Fprintf(os.Stderr, "  From:       %s\n", msg.From)
+	fmt.Fprintf(os.Stderr, "  To:         %s\n", msg.To)
+	fmt.Fprintf(os.Stderr, "  Value:      %s\n", types.FIL(msg.Value))
+	fmt.Fprintf(os.Stderr, "  Method:     %d\n", msg.Method)
+	fmt.Fprintf(os.Stderr, "  Nonce:      %d\n", msg.Nonce)
+	fmt.Fprintf(os.Stderr, "  Gas limit:  %d\n", msg.GasLimit)
+	fmt.Fprintf(os.Stderr, "  Max fee:    %s\n", types.FIL(msg.RequiredFunds()))
+}
+
+// createUnsignedMessage prefills the nonce and the gas of the message with
+// the node, and writes it to a message file for offline signing.
+func createUnsignedMessage(cctx *cli.Context, api lapi.FullNode, msg *types.Message, path string) error {
+	ctx := ReqContext(cctx)
+
+	// the signer has no chain state to resolve ID addresses
+	if msg.From.Protocol() == address.ID {
+		ka, err := api.StateAccountKey(ctx, msg.From, types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("resolving the key address of %s: %w", msg.From, err)
+		}
+		msg.From = ka
+	}
+
+	if cctx.Int64("nonce") >= 0 {
+		msg.Nonce = uint64(cctx.Int64("nonce"))
+	} else {
+		nonce, err := api.MpoolGetNonce(ctx, msg.From)
+		if err != nil {
+			return xerrors.Errorf("getting nonce: %w", err)
+		}
+		msg.Nonce = nonce
+	}
+
+	msg, err := api.GasEstimateMessageGas(ctx, msg, nil, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("estimating gas: %w", err)
+	}
+
+	if err := writeMessageFile(path, msg, nil); err != nil {
+		return err
+	}
+
+	printMessageSummary(msg)
+	fmt.Fprintf(os.Stderr, "written to %s, sign it with 'lotus wallet sign-message-file'\n", path)
+	return nil
+}
+
+var walletSignMessageFile = &cli.Command{
+	Name:      "sign-message-file",
+	Usage:     "Sign a message file created by 'lotus send --create-unsigned'",
+	ArgsUsage: "<message file> [signed file (default: the message file)]",
+	Description: `Meant for a machine holding the key offline: when no node uses the repo, the
+   key is read from the repo keystore directly, and no chain state is needed.
+   Review the summary of the message before pushing it with
+   'lotus mpool push-signed-file'.`,
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() < 1 || cctx.NArg() > 2 {
+			return ShowHelp(cctx, fmt.Errorf("expected a message file and optionally the signed file"))
+		}
+		in := cctx.Args().Get(0)
+		out := in
+		if cctx.NArg() == 2 {
+			out = cctx.Args().Get(1)
+		}
+
+		mf, err := readMessageFile(in)
+		if err != nil {
+			return err
+		}
+		if mf.Signature != nil {
+			return xerrors.Errorf("the message is already signed")
+		}
+		msg := mf.Message
+		printMessageSummary(msg)
+
+		sig, err := signMessageOffline(cctx, msg)
+		if err != nil {
+			return err
+		}
+
+		if err := writeMessageFile(out, msg, sig); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "signed message written to %s\n", out)
+		return nil
+	},
+}
+
+// signMessageOffline signs with the keystore of the repo, or through the
+// node API when a node runs on the repo.
+func signMessageOffline(cctx *cli.Context, msg *types.Message) (*crypto.Signature, error) {
+	ctx := ReqContext(cctx)
+
+	p, err := homedir.Expand(cctx.String("repo"))
+	if err != nil {
+		return nil, err
+	}
+	r, err := repo.NewFS(p)
+	if err != nil {
+		return nil, err
+	}
+	lr, err := r.Lock(repo.FullNode)
+	if xerrors.Is(err, repo.ErrRepoAlreadyLocked) {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return nil, err
+		}
+		defer closer()
+
+		var sm *types.SignedMessage
+		err = withUnlockedWallet(cctx, api, func() (err error) {
+			sm, err = api.WalletSignMessage(ctx, msg.From, msg)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &sm.Signature, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("opening repo: %w", err)
+	}
+	defer lr.Close() // nolint:errcheck
+
+	ks, err := lr.KeyStore()
+	if err != nil {
+		return nil, err
+	}
+	w, err := wallet.NewWallet(ks)
+	if err != nil {
+		return nil, err
+	}
+
+	mb, err := msg.ToStorageBlock()
+	if err != nil {
+		return nil, xerrors.Errorf("serializing message: %w", err)
+	}
+	sign := func() (*crypto.Signature, error) {
+		return w.WalletSign(ctx, msg.From, mb.Cid().Bytes(), lapi.MsgMeta{
+			Type:  lapi.MTChainMsg,
+			Extra: mb.RawData(),
+		})
+	}
+
+	sig, err := sign()
+	if xerrors.Is(err, wallet.ErrWalletLocked) {
+		pass, perr := readPassphrase("The wallet is locked, passphrase: ")
+		if perr != nil {
+			return nil, perr
+		}
+		if err := w.Unlock(pass, 0); err != nil {
+			return nil, err
+		}
+		sig, err = sign()
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("signing message: %w", err)
+	}
+	return sig, nil
+}
+
+var mpoolPushSignedFile = &cli.Command{
+	Name:      "push-signed-file",
+	Usage:     "Push a message file signed by 'lotus wallet sign-message-file'",
+	ArgsUsage: "<signed file>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return ShowHelp(cctx, fmt.Errorf("expected a signed message file"))
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		mf, err := readMessageFile(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		if mf.Signature == nil {
+			return xerrors.Errorf("the message isn't signed")
+		}
+		sm := &types.SignedMessage{
+			Message:   *mf.Message,
+			Signature: *mf.Signature,
+		}
+
+		ok, err := api.WalletVerify(ctx, sm.Message.From, sm.Message.Cid().Bytes(), &sm.Signature)
+		if err != nil {
+			return xerrors.Errorf("verifying signature: %w", err)
+		}
+		if !ok {
+			return xerrors.Errorf("invalid signature of %s", sm.Message.From)
+		}
+
+		nonce, err := api.MpoolGetNonce(ctx, sm.Message.From)
+		if err != nil {
+			return xerrors.Errorf("getting nonce: %w", err)
+		}
+		if sm.Message.Nonce < nonce {
+			return xerrors.Errorf("nonce %d of the message already used, the next nonce of %s is %d", sm.Message.Nonce, sm.Message.From, nonce)
+		}
+
+		c, err := api.MpoolPush(ctx, sm)
+		if err != nil {
+			return err
+		}
+		fmt.Println(c)
+		return nil
+	},
+}
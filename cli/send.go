@@ -6,10 +6,13 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -17,6 +20,8 @@ import (
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
+	ledgerwallet "github.com/filecoin-project/lotus/chain/wallet/ledger"
 )
 
 var sendCmd = &cli.Command{
@@ -61,6 +66,18 @@ var sendCmd = &cli.Command{
 			Name:  "params-hex",
 			Usage: "specify invocation parameters in hex",
 		},
+		&cli.Int64Flag{
+			Name:  "at-epoch",
+			Usage: "schedule the message to be pushed by the node at this epoch",
+		},
+		&cli.StringFlag{
+			Name:  "max-base-fee",
+			Usage: "schedule the message to be pushed by the node once the base fee is at most this value in AttoFIL",
+		},
+		&cli.StringFlag{
+			Name:  "create-unsigned",
+			Usage: "write the message, with its nonce and gas filled by the node, to this file for 'lotus wallet sign-message-file' instead of sending it",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		if cctx.Args().Len() != 2 {
@@ -143,20 +160,59 @@ var sendCmd = &cli.Command{
 			Params:     params,
 		}
 
+		if path := cctx.String("create-unsigned"); path != "" {
+			if cctx.IsSet("at-epoch") || cctx.IsSet("max-base-fee") {
+				return xerrors.Errorf("unsigned messages can't be scheduled")
+			}
+			return createUnsignedMessage(cctx, api, msg, path)
+		}
+
+		schedule, err := sendSchedule(cctx)
+		if err != nil {
+			return err
+		}
+
 		if cctx.Int64("nonce") > 0 {
 			msg.Nonce = uint64(cctx.Int64("nonce"))
-			sm, err := api.WalletSignMessage(ctx, fromAddr, msg)
+			var sm *types.SignedMessage
+			err := withLedger(ctx, api, fromAddr, func() error {
+				return withUnlockedWallet(cctx, api, func() (err error) {
+					sm, err = api.WalletSignMessage(ctx, fromAddr, msg)
+					return err
+				})
+			})
 			if err != nil {
 				return err
 			}
 
+			if schedule != nil {
+				id, err := api.MpoolScheduleSigned(ctx, sm, *schedule)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("scheduled %s as %d\n", sm.Cid(), id)
+				return nil
+			}
+
 			_, err = api.MpoolPush(ctx, sm)
 			if err != nil {
 				return err
 			}
 			fmt.Println(sm.Cid())
+		} else if schedule != nil {
+			id, err := api.MpoolSchedule(ctx, msg, nil, *schedule)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("scheduled as %d\n", id)
 		} else {
-			sm, err := api.MpoolPushMessage(ctx, msg, nil)
+			var sm *types.SignedMessage
+			err := withLedger(ctx, api, fromAddr, func() error {
+				return withUnlockedWallet(cctx, api, func() (err error) {
+					sm, err = api.MpoolPushMessage(ctx, msg, nil)
+					return err
+				})
+			})
 			if err != nil {
 				return err
 			}
@@ -167,6 +223,47 @@ var sendCmd = &cli.Command{
 	},
 }
 
+// withLedger runs an operation signing a message from the address. When the
+// key of the address is on a Ledger device, the user is told to confirm the
+// message on the device, and failures of the device are explained.
+func withLedger(ctx context.Context, fapi api.FullNode, from address.Address, op func() error) error {
+	backend, err := fapi.WalletBackend(ctx, from)
+	if err != nil {
+		return xerrors.Errorf("getting the wallet backend of %s: %w", from, err)
+	}
+	if backend != wallet.BackendLedger {
+		return op()
+	}
+
+	fmt.Fprintf(os.Stderr, "Confirm the message from %s on the Ledger device\n", from)
+	err = op()
+	if err != nil && (strings.Contains(err.Error(), ledgerwallet.ErrNoDevice.Error()) ||
+		strings.Contains(err.Error(), ledgerwallet.ErrNotSigned.Error())) {
+		return xerrors.Errorf("signing with the Ledger key of %s: %w", from, err)
+	}
+	return err
+}
+
+// sendSchedule returns the schedule of the message, nil if it should be
+// pushed right away.
+func sendSchedule(cctx *cli.Context) (*api.MessageSchedule, error) {
+	if !cctx.IsSet("at-epoch") && !cctx.IsSet("max-base-fee") {
+		return nil, nil
+	}
+
+	schedule := &api.MessageSchedule{
+		AtEpoch: abi.ChainEpoch(cctx.Int64("at-epoch")),
+	}
+	if cctx.IsSet("max-base-fee") {
+		fee, err := types.BigFromString(cctx.String("max-base-fee"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse max base fee: %w", err)
+		}
+		schedule.MaxBaseFee = fee
+	}
+	return schedule, nil
+}
+
 func decodeTypedParams(ctx context.Context, fapi api.FullNode, to address.Address, method abi.MethodNum, paramstr string) ([]byte, error) {
 	act, err := fapi.StateGetActor(ctx, to, types.EmptyTSK)
 	if err != nil {
@@ -3,6 +3,8 @@ package cli
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -13,6 +15,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
@@ -28,14 +31,18 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/go-state-types/network"
 
 	"github.com/filecoin-project/lotus/api"
 	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/apibstore"
 	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
 	"github.com/filecoin-project/lotus/chain/state"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/types"
@@ -54,6 +61,7 @@ var stateCmd = &cli.Command{
 		statePowerCmd,
 		stateSectorsCmd,
 		stateActiveSectorsCmd,
+		stateSectorsExpiringCmd,
 		stateListActorsCmd,
 		stateListMinersCmd,
 		stateCircSupplyCmd,
@@ -63,6 +71,8 @@ var stateCmd = &cli.Command{
 		stateReplayCmd,
 		stateSectorSizeCmd,
 		stateReadStateCmd,
+		stateDiffCmd,
+		stateDecodeCmd,
 		stateListMessagesCmd,
 		stateComputeStateCmd,
 		stateCallCmd,
@@ -72,6 +82,8 @@ var stateCmd = &cli.Command{
 		stateMinerInfo,
 		stateMarketCmd,
 		stateExecTraceCmd,
+		stateNetworkVersionCmd,
+		stateActorCIDsCmd,
 	},
 }
 
@@ -315,6 +327,147 @@ var stateActiveSectorsCmd = &cli.Command{
 	},
 }
 
+// SectorExpirations are the sectors of a partition expiring at an epoch.
+type SectorExpirations struct {
+	Partition uint64
+	Epoch     abi.ChainEpoch
+	OnTime    []uint64
+	Early     []uint64
+}
+
+// DeadlineExpirations are the upcoming expirations of the sectors of a
+// deadline, the earliest first.
+type DeadlineExpirations struct {
+	Deadline    uint64
+	Expirations []SectorExpirations
+}
+
+var stateSectorsExpiringCmd = &cli.Command{
+	Name:      "sectors-expiring",
+	Usage:     "Query the upcoming sector expirations of a miner, by deadline",
+	ArgsUsage: "[minerAddress]",
+	Description: `Lists the sectors expiring on time, and the faulty sectors expiring early if
+   they don't recover, from the expiration queues of the partitions of the miner.
+   The epochs are those of the queues, so the on-time expirations are at the end of
+   the deadline following the expiration of the sectors.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "within",
+			Usage: "only list the expirations within this number of epochs",
+			Value: 30 * 24 * 60 * 60 / int64(build.BlockDelaySecs),
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the expirations as json",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		if !cctx.Args().Present() {
+			return fmt.Errorf("must specify miner to list expirations for")
+		}
+
+		maddr, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+
+		act, err := api.StateGetActor(ctx, maddr, ts.Key())
+		if err != nil {
+			return xerrors.Errorf("getting miner actor: %w", err)
+		}
+		store := adt.WrapStore(ctx, cbor.NewCborStore(apibstore.NewAPIBlockstore(api)))
+		mas, err := miner.Load(store, act)
+		if err != nil {
+			return xerrors.Errorf("loading miner state: %w", err)
+		}
+
+		until := ts.Height() + abi.ChainEpoch(cctx.Int64("within"))
+
+		var out []DeadlineExpirations
+		err = mas.ForEachDeadline(func(dlIdx uint64, dl miner.Deadline) error {
+			dle := DeadlineExpirations{Deadline: dlIdx}
+			err := dl.ForEachPartition(func(partIdx uint64, part miner.Partition) error {
+				return part.ForEachExpiration(func(epoch abi.ChainEpoch, onTime, early bitfield.BitField) error {
+					if epoch > until {
+						return nil
+					}
+
+					var err error
+					e := SectorExpirations{Partition: partIdx, Epoch: epoch}
+					if e.OnTime, err = allSectors(onTime); err != nil {
+						return err
+					}
+					if e.Early, err = allSectors(early); err != nil {
+						return err
+					}
+					if len(e.OnTime)+len(e.Early) > 0 {
+						dle.Expirations = append(dle.Expirations, e)
+					}
+					return nil
+				})
+			})
+			if err != nil {
+				return xerrors.Errorf("deadline %d: %w", dlIdx, err)
+			}
+
+			if len(dle.Expirations) > 0 {
+				sort.SliceStable(dle.Expirations, func(i, j int) bool {
+					return dle.Expirations[i].Epoch < dle.Expirations[j].Epoch
+				})
+				out = append(out, dle)
+			}
+			return nil
+		})
+		if err != nil {
+			return xerrors.Errorf("reading expiration queues: %w", err)
+		}
+
+		if cctx.Bool("json") {
+			b, err := json.MarshalIndent(out, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		if len(out) == 0 {
+			fmt.Println("No sectors expiring")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Deadline\tPartition\tEpoch\tOn Time\tEarly\n")
+		for _, dle := range out {
+			for _, e := range dle.Expirations {
+				_, _ = fmt.Fprintf(tw, "%d\t%d\t%s\t%d\t%d\n", dle.Deadline, e.Partition, EpochTime(ts.Height(), e.Epoch), len(e.OnTime), len(e.Early))
+			}
+		}
+		return tw.Flush()
+	},
+}
+
+func allSectors(bf bitfield.BitField) ([]uint64, error) {
+	n, err := bf.Count()
+	if err != nil {
+		return nil, err
+	}
+	return bf.All(n)
+}
+
 var stateExecTraceCmd = &cli.Command{
 	Name:      "exec-trace",
 	Usage:     "Get the execution trace of a given message",
@@ -396,6 +549,14 @@ var stateReplayCmd = &cli.Command{
 			Name:  "detailed-gas",
 			Usage: "print out detailed gas costs for given message",
 		},
+		&cli.BoolFlag{
+			Name:  "gas-profile",
+			Usage: "print out the gas charges of the message and its subcalls by charge point",
+		},
+		&cli.StringFlag{
+			Name:  "gas-pprof",
+			Usage: "write the gas used by the message by call stack to the given file, as a pprof profile (see go tool pprof)",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		if cctx.Args().Len() != 1 {
@@ -444,10 +605,43 @@ var stateReplayCmd = &cli.Command{
 			printInternalExecutions("\t", res.ExecutionTrace.Subcalls)
 		}
 
+		if cctx.IsSet("gas-pprof") {
+			prof, err := fapi.StateReplayGasPprof(ctx, types.EmptyTSK, mcid)
+			if err != nil {
+				return xerrors.Errorf("getting gas pprof profile: %w", err)
+			}
+			if err := ioutil.WriteFile(cctx.String("gas-pprof"), prof, 0644); err != nil {
+				return xerrors.Errorf("writing gas pprof profile: %w", err)
+			}
+			fmt.Printf("Gas pprof profile written to %s\n", cctx.String("gas-pprof"))
+		}
+
+		if cctx.Bool("gas-profile") {
+			prof, err := fapi.StateReplayGasProfile(ctx, types.EmptyTSK, mcid)
+			if err != nil {
+				return xerrors.Errorf("getting gas profile: %w", err)
+			}
+			return printGasProfile(prof)
+		}
+
 		return nil
 	},
 }
 
+func printGasProfile(prof *api.GasProfile) error {
+	fmt.Println("Gas profile:")
+	tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "Charge\tCount\tGas\t%\tCompute\tStorage\tBytes\tTime")
+	for _, e := range prof.Charges {
+		var pct float64
+		if prof.GasUsed > 0 {
+			pct = float64(e.TotalGas) * 100 / float64(prof.GasUsed)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%d\t%d\t%d\t%s\n", e.Name, e.Count, e.TotalGas, pct, e.ComputeGas, e.StorageGas, e.Bytes, e.TimeTaken)
+	}
+	return tw.Flush()
+}
+
 var stateGetDealSetCmd = &cli.Command{
 	Name:      "get-deal",
 	Usage:     "View on-chain deal info",
@@ -724,6 +918,83 @@ var stateSectorSizeCmd = &cli.Command{
 	},
 }
 
+var stateNetworkVersionCmd = &cli.Command{
+	Name:  "network-version",
+	Usage: "Print the network version at the tipset",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+
+		nv, err := api.StateNetworkVersion(ctx, ts.Key())
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(nv)
+		return nil
+	},
+}
+
+var stateActorCIDsCmd = &cli.Command{
+	Name:  "actor-cids",
+	Usage: "Print the code CIDs of the builtin actors",
+	Flags: []cli.Flag{
+		&cli.UintFlag{
+			Name:  "network-version",
+			Usage: "network version to print the actors of, defaults to the version at the tipset",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		nv := network.Version(cctx.Uint("network-version"))
+		if !cctx.IsSet("network-version") {
+			ts, err := LoadTipSet(ctx, cctx, api)
+			if err != nil {
+				return err
+			}
+
+			nv, err = api.StateNetworkVersion(ctx, ts.Key())
+			if err != nil {
+				return err
+			}
+		}
+
+		codes, err := api.StateActorCodeCIDs(ctx, nv)
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(codes))
+		for name := range codes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("Network Version: %d\n", nv)
+		for _, name := range names {
+			fmt.Printf("%s\t%s\n", name, codes[name])
+		}
+		return nil
+	},
+}
+
 var stateReadStateCmd = &cli.Command{
 	Name:      "read-state",
 	Usage:     "View a json representation of an actors state",
@@ -766,6 +1037,202 @@ var stateReadStateCmd = &cli.Command{
 	},
 }
 
+var stateDiffCmd = &cli.Command{
+	Name:      "diff",
+	Usage:     "View the difference between the states of two tipsets, as json",
+	ArgsUsage: "[fromTipset] [toTipset] [actorAddress (optional)]",
+	Description: `The tipsets are given as by --tipset, e.g. @1000 or a list of block CIDs.
+   Without an actor, the actors created, deleted and modified are listed.`,
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		if cctx.Args().Len() < 2 || cctx.Args().Len() > 3 {
+			return fmt.Errorf("must pass two tipsets, and optionally an actor address")
+		}
+
+		from, err := ParseTipSetRef(ctx, api, cctx.Args().Get(0))
+		if err != nil {
+			return xerrors.Errorf("parsing from tipset: %w", err)
+		}
+		to, err := ParseTipSetRef(ctx, api, cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing to tipset: %w", err)
+		}
+
+		addr := address.Undef
+		if cctx.Args().Len() == 3 {
+			addr, err = address.NewFromString(cctx.Args().Get(2))
+			if err != nil {
+				return err
+			}
+		}
+
+		diff, err := api.StateDiff(ctx, from.Key(), to.Key(), addr)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+
+		return nil
+	},
+}
+
+var stateDecodeCmd = &cli.Command{
+	Name:  "decode",
+	Usage: "Decode message params, return values and actor states as json",
+	Subcommands: []*cli.Command{
+		stateDecodeParamsCmd,
+		stateDecodeReturnCmd,
+		stateDecodeStateCmd,
+	},
+}
+
+var decodeEncodingFlag = &cli.StringFlag{
+	Name:  "encoding",
+	Value: "hex",
+	Usage: "encoding of the value, hex or base64",
+}
+
+func decodeBytes(cctx *cli.Context, s string) ([]byte, error) {
+	switch cctx.String("encoding") {
+	case "hex":
+		return hex.DecodeString(s)
+	case "base64":
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return nil, xerrors.Errorf("unknown encoding %q", cctx.String("encoding"))
+	}
+}
+
+// stateDecodeCallCmd decodes the params or the return value of a call, with
+// the given API method.
+func stateDecodeCallCmd(name, usage string, decode func(lapi.FullNode, context.Context, address.Address, abi.MethodNum, []byte, types.TipSetKey) (interface{}, error)) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "[toAddr method value]",
+		Flags:     []cli.Flag{decodeEncodingFlag},
+		Action: func(cctx *cli.Context) error {
+			api, closer, err := GetFullNodeAPI(cctx)
+			if err != nil {
+				return err
+			}
+			defer closer()
+
+			ctx := ReqContext(cctx)
+
+			if cctx.Args().Len() != 3 {
+				return ShowHelp(cctx, fmt.Errorf("incorrect number of arguments"))
+			}
+
+			to, err := address.NewFromString(cctx.Args().First())
+			if err != nil {
+				return xerrors.Errorf("parsing toAddr: %w", err)
+			}
+
+			method, err := strconv.ParseUint(cctx.Args().Get(1), 10, 64)
+			if err != nil {
+				return xerrors.Errorf("parsing method id: %w", err)
+			}
+
+			value, err := decodeBytes(cctx, cctx.Args().Get(2))
+			if err != nil {
+				return xerrors.Errorf("parsing value: %w", err)
+			}
+
+			ts, err := LoadTipSet(ctx, cctx, api)
+			if err != nil {
+				return err
+			}
+
+			res, err := decode(api, ctx, to, abi.MethodNum(method), value, ts.Key())
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(res, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+
+			return nil
+		},
+	}
+}
+
+var stateDecodeParamsCmd = stateDecodeCallCmd("params", "Decode the params of a message", lapi.FullNode.StateDecodeParams)
+
+var stateDecodeReturnCmd = stateDecodeCallCmd("return", "Decode the return value of a message", lapi.FullNode.StateDecodeReturn)
+
+var stateDecodeStateCmd = &cli.Command{
+	Name:      "state",
+	Usage:     "Decode a state head of an actor",
+	ArgsUsage: "[actorAddress] [stateHeadCid (defaults to the current head of the actor)]",
+	Description: `A past head of the actor can be given, it's decoded with the state type of the
+   actor code at the tipset, which can be set with --tipset.`,
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		if cctx.Args().Len() < 1 || cctx.Args().Len() > 2 {
+			return ShowHelp(cctx, fmt.Errorf("incorrect number of arguments"))
+		}
+
+		addr, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+
+		act, err := api.StateGetActor(ctx, addr, ts.Key())
+		if err != nil {
+			return xerrors.Errorf("getting actor: %w", err)
+		}
+
+		head := act.Head
+		if cctx.Args().Len() == 2 {
+			head, err = cid.Parse(cctx.Args().Get(1))
+			if err != nil {
+				return xerrors.Errorf("parsing state head: %w", err)
+			}
+		}
+
+		st, err := api.StateDecodeActorState(ctx, act.Code, head)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+
+		return nil
+	},
+}
+
 var stateListMessagesCmd = &cli.Command{
 	Name:  "list-messages",
 	Usage: "list messages on chain matching given criteria",
@@ -1299,13 +1766,8 @@ func sumGas(changes []*types.GasTrace) types.GasTrace {
 }
 
 func jsonParams(code cid.Cid, method abi.MethodNum, params []byte) (string, error) {
-	methodMeta, found := stmgr.MethodsMap[code][method]
-	if !found {
-		return "", fmt.Errorf("method %d not found on actor %s", method, code)
-	}
-	re := reflect.New(methodMeta.Params.Elem())
-	p := re.Interface().(cbg.CBORUnmarshaler)
-	if err := p.UnmarshalCBOR(bytes.NewReader(params)); err != nil {
+	p, err := stmgr.DecodeParams(code, method, params)
+	if err != nil {
 		return "", err
 	}
 
@@ -1314,17 +1776,12 @@ func jsonParams(code cid.Cid, method abi.MethodNum, params []byte) (string, erro
 }
 
 func jsonReturn(code cid.Cid, method abi.MethodNum, ret []byte) (string, error) {
-	methodMeta, found := stmgr.MethodsMap[code][method]
-	if !found {
-		return "", fmt.Errorf("method %d not found on actor %s", method, code)
-	}
-	re := reflect.New(methodMeta.Ret.Elem())
-	p := re.Interface().(cbg.CBORUnmarshaler)
-	if err := p.UnmarshalCBOR(bytes.NewReader(ret)); err != nil {
+	r, err := stmgr.DecodeReturn(code, method, ret)
+	if err != nil {
 		return "", err
 	}
 
-	b, err := json.MarshalIndent(p, "", "  ")
+	b, err := json.MarshalIndent(r, "", "  ")
 	return string(b), err
 }
 
@@ -1666,7 +2123,7 @@ var stateCircSupplyCmd = &cli.Command{
 		}
 
 		if cctx.IsSet("vm-supply") {
-			circ, err := api.StateVMCirculatingSupplyInternal(ctx, ts.Key())
+			circ, err := api.StateVMCirculatingSupply(ctx, ts.Key())
 			if err != nil {
 				return err
 			}
@@ -1674,8 +2131,11 @@ var stateCircSupplyCmd = &cli.Command{
 			fmt.Println("Circulating supply: ", types.FIL(circ.FilCirculating))
 			fmt.Println("Mined: ", types.FIL(circ.FilMined))
 			fmt.Println("Vested: ", types.FIL(circ.FilVested))
+			fmt.Println("Reserve disbursed: ", types.FIL(circ.FilReserveDisbursed))
 			fmt.Println("Burnt: ", types.FIL(circ.FilBurnt))
 			fmt.Println("Locked: ", types.FIL(circ.FilLocked))
+			fmt.Println("  In market: ", types.FIL(circ.FilMarketLocked))
+			fmt.Println("  In miners' pledge: ", types.FIL(circ.FilPowerLocked))
 		} else {
 			circ, err := api.StateCirculatingSupply(ctx, ts.Key())
 			if err != nil {
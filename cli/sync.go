@@ -2,13 +2,18 @@ package cli
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/filecoin-project/lotus/chain/types"
 
 	"github.com/filecoin-project/go-state-types/abi"
 	cid "github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/urfave/cli/v2"
 
 	"github.com/filecoin-project/lotus/api"
@@ -24,6 +29,9 @@ var syncCmd = &cli.Command{
 		syncMarkBadCmd,
 		syncUnmarkBadCmd,
 		syncCheckBadCmd,
+		syncListBadCmd,
+		syncPeersCmd,
+		syncValidateHeaderCmd,
 		syncCheckpointCmd,
 	},
 }
@@ -45,6 +53,15 @@ var syncStatusCmd = &cli.Command{
 		}
 
 		fmt.Println("sync status:")
+		if t := state.Trusted; t != nil {
+			reached := "not reached yet"
+			if t.Reached {
+				reached = "reached"
+			}
+			fmt.Printf("mode: lightweight, trusting the chain up to %s (%d, %s)\n", t.TipSet, t.Height, reached)
+		} else {
+			fmt.Println("mode: full validation")
+		}
 		for i, ss := range state.ActiveSyncs {
 			fmt.Printf("worker %d:\n", i)
 			var base, target []cid.Cid
@@ -73,6 +90,25 @@ var syncStatusCmd = &cli.Command{
 			} else {
 				fmt.Printf("\tElapsed: %s\n", ss.End.Sub(ss.Start))
 			}
+			if ss.WeightDiff.Int != nil {
+				fmt.Printf("\tWeight diff:\t%s\n", ss.WeightDiff)
+			}
+			fmt.Printf("\tHeaders fetched: %d\n", ss.HeadersFetched)
+			fmt.Printf("\tMessages fetched: %d tipsets\n", ss.MessagesFetched)
+			fmt.Printf("\tValidated: %d tipsets", ss.TipSetsValidated)
+			if ss.ValidationRate > 0 {
+				fmt.Printf(" (%.2f/s)", ss.ValidationRate)
+			}
+			fmt.Println()
+			if ss.ETA > 0 {
+				fmt.Printf("\tETA: %s\n", ss.ETA.Round(time.Second))
+			}
+			if len(ss.Stages) > 0 {
+				fmt.Printf("\tStage timings:\n")
+				for _, st := range ss.Stages {
+					fmt.Printf("\t\t%s: %s\n", st.Stage, st.Elapsed.Round(time.Millisecond))
+				}
+			}
 			if ss.Stage == api.StageSyncErrored {
 				fmt.Printf("\tError: %s\n", ss.Message)
 			}
@@ -198,6 +234,196 @@ var syncCheckBadCmd = &cli.Command{
 	},
 }
 
+var syncListBadCmd = &cli.Command{
+	Name:  "list-bad",
+	Usage: "list the blocks marked bad, with the reasons they were marked",
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		bad, err := napi.SyncListBad(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(bad) == 0 {
+			fmt.Println("no blocks are marked as bad")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Block\tReason\n")
+		for _, b := range bad {
+			fmt.Fprintf(w, "%s\t%s\n", b.Block, b.Reason)
+		}
+		return w.Flush()
+	},
+}
+
+var syncPeersCmd = &cli.Command{
+	Name:  "peers",
+	Usage: "Manage the peers the chain is synced from",
+	Subcommands: []*cli.Command{
+		syncPeersBlockCmd,
+		syncPeersUnblockCmd,
+		syncPeersListCmd,
+	},
+}
+
+var syncPeersBlockCmd = &cli.Command{
+	Name:      "block",
+	Usage:     "stop syncing from the given peer until it is unblocked or the node restarts",
+	ArgsUsage: "[peerId] [reason]",
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if !cctx.Args().Present() {
+			return fmt.Errorf("must specify peer to block")
+		}
+
+		p, err := peer.Decode(cctx.Args().First())
+		if err != nil {
+			return fmt.Errorf("failed to decode input as a peer id: %s", err)
+		}
+
+		reason := strings.Join(cctx.Args().Tail(), " ")
+		if reason == "" {
+			reason = "manually blocked"
+		}
+		return napi.SyncBlockPeer(ctx, p, reason)
+	},
+}
+
+var syncPeersUnblockCmd = &cli.Command{
+	Name:      "unblock",
+	Usage:     "sync from the given blocked peer again",
+	ArgsUsage: "[peerId]",
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if !cctx.Args().Present() {
+			return fmt.Errorf("must specify peer to unblock")
+		}
+
+		p, err := peer.Decode(cctx.Args().First())
+		if err != nil {
+			return fmt.Errorf("failed to decode input as a peer id: %s", err)
+		}
+
+		return napi.SyncUnblockPeer(ctx, p)
+	},
+}
+
+var syncPeersListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list the blocked peers",
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		blocked, err := napi.SyncBlockedPeers(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(blocked) == 0 {
+			fmt.Println("no peers are blocked")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Peer\tSince\tReason\n")
+		for _, b := range blocked {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", b.ID, b.Since.Format(time.Stamp), b.Reason)
+		}
+		return w.Flush()
+	},
+}
+
+var syncValidateHeaderCmd = &cli.Command{
+	Name:      "validate-header",
+	Usage:     "run the header checks of block validation on a block",
+	ArgsUsage: "[blockCid]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "hex",
+			Usage: "validate the hex-encoded CBOR block header given instead of a block known to the node",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		napi, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		var h *types.BlockHeader
+		if cctx.IsSet("hex") {
+			b, err := hex.DecodeString(strings.TrimSpace(cctx.String("hex")))
+			if err != nil {
+				return fmt.Errorf("decoding hex: %w", err)
+			}
+			h, err = types.DecodeBlock(b)
+			if err != nil {
+				return fmt.Errorf("decoding block header: %w", err)
+			}
+		} else {
+			if !cctx.Args().Present() {
+				return fmt.Errorf("must specify block cid to validate")
+			}
+
+			bcid, err := cid.Decode(cctx.Args().First())
+			if err != nil {
+				return fmt.Errorf("failed to decode input as a cid: %s", err)
+			}
+
+			h, err = napi.ChainGetBlock(ctx, bcid)
+			if err != nil {
+				return err
+			}
+		}
+
+		res, err := napi.SyncValidateBlockHeader(ctx, h)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range res.Checks {
+			if c.Error == "" {
+				fmt.Printf("%-14s ok\n", c.Name)
+			} else {
+				fmt.Printf("%-14s FAILED: %s\n", c.Name, c.Error)
+			}
+		}
+
+		if !res.Valid {
+			return fmt.Errorf("block header %s is invalid", res.Block)
+		}
+
+		fmt.Printf("block header %s is valid\n", res.Block)
+		return nil
+	},
+}
+
 var syncCheckpointCmd = &cli.Command{
 	Name:      "checkpoint",
 	Usage:     "mark a certain tipset as checkpointed; the node will never fork away from this tipset",
@@ -8,15 +8,19 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh/terminal"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/crypto"
 
+	lapi "github.com/filecoin-project/lotus/api"
 	types "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 )
 
@@ -32,8 +36,14 @@ var walletCmd = &cli.Command{
 		walletGetDefault,
 		walletSetDefault,
 		walletSign,
+		walletSignMessageFile,
 		walletVerify,
 		walletDelete,
+		walletEncrypt,
+		walletUnlock,
+		walletLock,
+		walletMnemonic,
+		walletHistory,
 	},
 }
 
@@ -41,6 +51,20 @@ var walletNew = &cli.Command{
 	Name:      "new",
 	Usage:     "Generate a new key of the given type",
 	ArgsUsage: "[bls|secp256k1 (default secp256k1)]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "ledger",
+			Usage: "derive a secp256k1 key on a connected Ledger device, the address has to be confirmed on the device (requires Wallet.EnableLedger)",
+		},
+		&cli.BoolFlag{
+			Name:  "hd",
+			Usage: "derive the key from the mnemonic of the wallet, generated if there is none yet",
+		},
+		&cli.Uint64Flag{
+			Name:  "index",
+			Usage: "with --hd, derive the key at this index instead of the first unused one",
+		},
+	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
 		if err != nil {
@@ -53,8 +77,25 @@ var walletNew = &cli.Command{
 		if t == "" {
 			t = "secp256k1"
 		}
-
-		nk, err := api.WalletNew(ctx, types.KeyType(t))
+		if cctx.Bool("ledger") {
+			if t != "secp256k1" {
+				return xerrors.Errorf("ledger only supports secp256k1 keys")
+			}
+			t = string(types.KTSecp256k1Ledger)
+			fmt.Println("confirm the new address on the ledger device")
+		}
+
+		var nk address.Address
+		switch {
+		case cctx.Bool("hd") && cctx.IsSet("index"):
+			nk, err = api.WalletDeriveHD(ctx, types.KeyType(t), cctx.Uint64("index"))
+		case cctx.Bool("hd"):
+			nk, err = api.WalletNewHD(ctx, types.KeyType(t))
+		case cctx.IsSet("index"):
+			return xerrors.Errorf("--index requires --hd")
+		default:
+			nk, err = api.WalletNew(ctx, types.KeyType(t))
+		}
 		if err != nil {
 			return err
 		}
@@ -382,8 +423,11 @@ var walletSign = &cli.Command{
 			return err
 		}
 
-		sig, err := api.WalletSign(ctx, addr, msg)
-
+		var sig *crypto.Signature
+		err = withUnlockedWallet(cctx, api, func() (err error) {
+			sig, err = api.WalletSign(ctx, addr, msg)
+			return err
+		})
 		if err != nil {
 			return err
 		}
@@ -471,3 +515,266 @@ var walletDelete = &cli.Command{
 		return api.WalletDelete(ctx, addr)
 	},
 }
+
+var walletEncrypt = &cli.Command{
+	Name:  "encrypt",
+	Usage: "Encrypt the keys of the local keystore with a passphrase",
+	Description: `The keys of the wallet, and the ones added later, are encrypted with a key
+   derived from the passphrase. The wallet is locked afterwards, it has to
+   be unlocked with 'lotus wallet unlock' for the node to sign messages.
+
+   The passphrase can't be recovered, the keys are lost without it, so
+   export them beforehand.`,
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		pass, err := readPassphrase("New passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if pass != confirm {
+			return xerrors.Errorf("passphrases don't match")
+		}
+
+		return api.WalletEncrypt(ctx, pass)
+	},
+}
+
+var walletUnlock = &cli.Command{
+	Name:  "unlock",
+	Usage: "Unlock the encrypted keys of the local keystore",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "lock the wallet again after this duration, 0 to keep it unlocked",
+			Value: 15 * time.Minute,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		pass, err := readPassphrase("Passphrase: ")
+		if err != nil {
+			return err
+		}
+
+		return api.WalletUnlock(ctx, pass, cctx.Duration("timeout"))
+	},
+}
+
+var walletLock = &cli.Command{
+	Name:  "lock",
+	Usage: "Lock the encrypted keys of the local keystore",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		return api.WalletLock(ctx)
+	},
+}
+
+// withUnlockedWallet runs an operation which needs signing; when it fails
+// because the wallet is locked, the passphrase is prompted for and the
+// operation retried with the wallet unlocked, which is locked again after.
+func withUnlockedWallet(cctx *cli.Context, api lapi.FullNode, op func() error) error {
+	err := op()
+	if err == nil || !strings.Contains(err.Error(), wallet.ErrWalletLocked.Error()) {
+		return err
+	}
+
+	pass, err := readPassphrase("The wallet is locked, passphrase: ")
+	if err != nil {
+		return err
+	}
+
+	ctx := ReqContext(cctx)
+	if err := api.WalletUnlock(ctx, pass, time.Minute); err != nil {
+		return xerrors.Errorf("unlocking wallet: %w", err)
+	}
+	defer func() {
+		if err := api.WalletLock(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "locking wallet: %s\n", err)
+		}
+	}()
+
+	return op()
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", xerrors.Errorf("reading passphrase: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	pass, err := terminal.ReadPassword(fd)
+	if err != nil {
+		return "", xerrors.Errorf("reading passphrase: %w", err)
+	}
+	return string(pass), nil
+}
+
+var walletMnemonic = &cli.Command{
+	Name:  "mnemonic",
+	Usage: "Manage the mnemonic the HD keys are derived from",
+	Subcommands: []*cli.Command{
+		walletMnemonicExport,
+		walletMnemonicImport,
+	},
+}
+
+var walletMnemonicExport = &cli.Command{
+	Name:  "export",
+	Usage: "Print the mnemonic of the wallet, with the derivation paths of its keys",
+	Description: `The mnemonic is a BIP39 phrase, the secp256k1 keys are derived with BIP32
+   at m/44'/461'/0'/0/i, like the Ledger app and other Filecoin wallets, and
+   the BLS keys with EIP-2333 at m/12381/461/0/i.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the export as json",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		exp, err := api.WalletExportMnemonic(ctx)
+		if err != nil {
+			return err
+		}
+
+		if cctx.Bool("json") {
+			b, err := json.MarshalIndent(exp, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		fmt.Println(exp.Mnemonic)
+		fmt.Println()
+		for _, k := range exp.Keys {
+			fmt.Printf("%s\t%s\t%s\n", k.Address, k.Type, k.Path)
+		}
+		return nil
+	},
+}
+
+var walletMnemonicImport = &cli.Command{
+	Name:  "import",
+	Usage: "Set the mnemonic to derive the HD keys from",
+	Description: `The keys are derived again with 'lotus wallet new --hd', at the first unused
+   index or at the one given with --index.`,
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		mnemonic, err := readPassphrase("Mnemonic: ")
+		if err != nil {
+			return err
+		}
+
+		return api.WalletImportMnemonic(ctx, strings.Join(strings.Fields(mnemonic), " "))
+	},
+}
+
+var walletHistory = &cli.Command{
+	Name:      "history",
+	Usage:     "List the messages sent and received by an address, newest first",
+	ArgsUsage: "<address>",
+	Description: `Only the messages in the message index of the node are listed, the ones of
+   the chain synced with it; see 'lotus index messages' for older ones.`,
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "offset",
+			Usage: "number of newest messages to skip",
+		},
+		&cli.Uint64Flag{
+			Name:  "limit",
+			Usage: "most messages to list, 0 for all",
+			Value: 50,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if cctx.NArg() != 1 {
+			return ShowHelp(cctx, fmt.Errorf("must specify an address"))
+		}
+		addr, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		msgs, err := api.StateListMessagesFor(ctx, addr, types.EmptyTSK, cctx.Uint64("offset"), cctx.Uint64("limit"))
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Height"),
+			tablewriter.Col("Message"),
+			tablewriter.Col("From"),
+			tablewriter.Col("To"),
+			tablewriter.Col("Value"),
+			tablewriter.Col("Method"),
+			tablewriter.Col("Exit"),
+			tablewriter.Col("GasUsed"),
+			tablewriter.Col("GasFeeCap"))
+
+		for _, m := range msgs {
+			tw.Write(map[string]interface{}{
+				"Height":    m.Height,
+				"Message":   m.Cid,
+				"From":      m.Message.From,
+				"To":        m.Message.To,
+				"Value":     types.FIL(m.Message.Value),
+				"Method":    m.Message.Method,
+				"Exit":      m.Receipt.ExitCode,
+				"GasUsed":   m.Receipt.GasUsed,
+				"GasFeeCap": m.Message.GasFeeCap,
+			})
+		}
+
+		return tw.Flush(os.Stdout)
+	},
+}
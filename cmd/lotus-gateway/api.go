@@ -19,17 +19,39 @@ import (
 	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
 	"github.com/filecoin-project/lotus/node/impl/full"
 	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
 )
 
 const (
 	LookbackCap            = time.Hour * 12
 	stateWaitLookbackLimit = abi.ChainEpoch(20)
+	MaxResults             = 10000
 )
 
 var (
 	ErrLookbackTooLong = fmt.Errorf("lookbacks of more than %s are disallowed", LookbackCap)
+	ErrTooManyResults  = fmt.Errorf("too many results")
 )
 
+// Limits are the limits the gateway enforces on the calls it passes through
+type Limits struct {
+	// LookbackCap is how far in the past the tipsets of the calls can be
+	LookbackCap time.Duration
+	// WaitLookbackLimit is how many epochs StateWaitMsg and StateSearchMsg
+	// look back for the message
+	WaitLookbackLimit abi.ChainEpoch
+	// MaxResults is the maximum number of sectors, deals or tipsets returned
+	// by a call
+	MaxResults int
+}
+
+// DefaultLimits are the limits of the gateway when not set at startup
+var DefaultLimits = Limits{
+	LookbackCap:       LookbackCap,
+	WaitLookbackLimit: stateWaitLookbackLimit,
+	MaxResults:        MaxResults,
+}
+
 // gatewayDepsAPI defines the API methods that the GatewayAPI depends on
 // (to make it easy to mock for tests)
 type gatewayDepsAPI interface {
@@ -39,6 +61,7 @@ type gatewayDepsAPI interface {
 	ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
 	ChainReadObj(context.Context, cid.Cid) ([]byte, error)
 	ChainGetNode(ctx context.Context, p string) (*api.IpldObject, error)
+	ChainGetPath(ctx context.Context, from types.TipSetKey, to types.TipSetKey) ([]*api.HeadChange, error)
 	GasEstimateMessageGas(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, tsk types.TipSetKey) (*types.Message, error)
 	MpoolPushUntrusted(ctx context.Context, sm *types.SignedMessage) (cid.Cid, error)
 	MsigGetAvailableBalance(ctx context.Context, addr address.Address, tsk types.TipSetKey) (types.BigInt, error)
@@ -46,7 +69,8 @@ type gatewayDepsAPI interface {
 	StateAccountKey(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
 	StateGetActor(ctx context.Context, actor address.Address, ts types.TipSetKey) (*types.Actor, error)
 	StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
-	StateWaitMsgLimited(ctx context.Context, msg cid.Cid, confidence uint64, h abi.ChainEpoch) (*api.MsgLookup, error)
+	StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
+	StateSearchMsgBounded(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
 	StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error)
 	StateMinerPower(context.Context, address.Address, types.TipSetKey) (*api.MinerPower, error)
 	StateMinerFaults(context.Context, address.Address, types.TipSetKey) (bitfield.BitField, error)
@@ -55,23 +79,41 @@ type gatewayDepsAPI interface {
 	StateMinerDeadlines(context.Context, address.Address, types.TipSetKey) ([]api.Deadline, error)
 	StateMinerAvailableBalance(context.Context, address.Address, types.TipSetKey) (types.BigInt, error)
 	StateMinerProvingDeadline(context.Context, address.Address, types.TipSetKey) (*dline.Info, error)
+	StateMinerSectors(context.Context, address.Address, *bitfield.BitField, types.TipSetKey) ([]*miner.SectorOnChainInfo, error)
+	StateMinerSectorCount(context.Context, address.Address, types.TipSetKey) (api.MinerSectors, error)
+	StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error)
 	StateCirculatingSupply(context.Context, types.TipSetKey) (abi.TokenAmount, error)
+	StateVMCirculatingSupply(context.Context, types.TipSetKey) (api.CirculatingSupply, error)
 	StateVMCirculatingSupplyInternal(context.Context, types.TipSetKey) (api.CirculatingSupply, error)
 }
 
 type GatewayAPI struct {
-	api         gatewayDepsAPI
-	lookbackCap time.Duration
+	api               gatewayDepsAPI
+	lookbackCap       time.Duration
+	waitLookbackLimit abi.ChainEpoch
+	maxResults        int
 }
 
-// NewGatewayAPI creates a new GatewayAPI with the default lookback cap
+// NewGatewayAPI creates a new GatewayAPI with the default limits
 func NewGatewayAPI(api gatewayDepsAPI) *GatewayAPI {
-	return newGatewayAPI(api, LookbackCap)
+	return NewGatewayAPIWithLimits(api, DefaultLimits)
+}
+
+// NewGatewayAPIWithLimits creates a new GatewayAPI with the given limits
+func NewGatewayAPIWithLimits(api gatewayDepsAPI, limits Limits) *GatewayAPI {
+	return &GatewayAPI{
+		api:               api,
+		lookbackCap:       limits.LookbackCap,
+		waitLookbackLimit: limits.WaitLookbackLimit,
+		maxResults:        limits.MaxResults,
+	}
 }
 
 // used by the tests
 func newGatewayAPI(api gatewayDepsAPI, lookbackCap time.Duration) *GatewayAPI {
-	return &GatewayAPI{api: api, lookbackCap: lookbackCap}
+	limits := DefaultLimits
+	limits.LookbackCap = lookbackCap
+	return NewGatewayAPIWithLimits(api, limits)
 }
 
 func (a *GatewayAPI) checkTipsetKey(ctx context.Context, tsk types.TipSetKey) error {
@@ -84,30 +126,35 @@ func (a *GatewayAPI) checkTipsetKey(ctx context.Context, tsk types.TipSetKey) er
 		return err
 	}
 
-	return a.checkTipset(ts)
+	return a.checkTipset(ctx, ts)
 }
 
-func (a *GatewayAPI) checkTipset(ts *types.TipSet) error {
+func (a *GatewayAPI) checkTipset(ctx context.Context, ts *types.TipSet) error {
 	at := time.Unix(int64(ts.Blocks()[0].Timestamp), 0)
-	if err := a.checkTimestamp(at); err != nil {
+	if err := a.checkTimestamp(ctx, at); err != nil {
 		return fmt.Errorf("bad tipset: %w", err)
 	}
 	return nil
 }
 
-func (a *GatewayAPI) checkTipsetHeight(ts *types.TipSet, h abi.ChainEpoch) error {
+func (a *GatewayAPI) checkTipsetHeight(ctx context.Context, ts *types.TipSet, h abi.ChainEpoch) error {
 	tsBlock := ts.Blocks()[0]
 	heightDelta := time.Duration(uint64(tsBlock.Height-h)*build.BlockDelaySecs) * time.Second
 	timeAtHeight := time.Unix(int64(tsBlock.Timestamp), 0).Add(-heightDelta)
 
-	if err := a.checkTimestamp(timeAtHeight); err != nil {
+	if err := a.checkTimestamp(ctx, timeAtHeight); err != nil {
 		return fmt.Errorf("bad tipset height: %w", err)
 	}
 	return nil
 }
 
-func (a *GatewayAPI) checkTimestamp(at time.Time) error {
-	if time.Since(at) > a.lookbackCap {
+func (a *GatewayAPI) checkTimestamp(ctx context.Context, at time.Time) error {
+	lookbackCap := a.lookbackCap
+	if c := callerFromContext(ctx); c != nil && c.quota.Lookback > 0 {
+		lookbackCap = time.Duration(c.quota.Lookback)
+	}
+
+	if time.Since(at) > lookbackCap {
 		return ErrLookbackTooLong
 	}
 
@@ -145,12 +192,12 @@ func (a *GatewayAPI) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoc
 	}
 
 	// Check if the tipset key refers to a tipset that's too far in the past
-	if err := a.checkTipset(ts); err != nil {
+	if err := a.checkTipset(ctx, ts); err != nil {
 		return nil, err
 	}
 
 	// Check if the height is too far in the past
-	if err := a.checkTipsetHeight(ts, h); err != nil {
+	if err := a.checkTipsetHeight(ctx, ts, h); err != nil {
 		return nil, err
 	}
 
@@ -165,6 +212,24 @@ func (a *GatewayAPI) ChainGetNode(ctx context.Context, p string) (*api.IpldObjec
 	return a.api.ChainGetNode(ctx, p)
 }
 
+func (a *GatewayAPI) ChainGetPath(ctx context.Context, from types.TipSetKey, to types.TipSetKey) ([]*api.HeadChange, error) {
+	if err := a.checkTipsetKey(ctx, from); err != nil {
+		return nil, err
+	}
+	if err := a.checkTipsetKey(ctx, to); err != nil {
+		return nil, err
+	}
+
+	path, err := a.api.ChainGetPath(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > a.maxResults {
+		return nil, xerrors.Errorf("path of %d tipsets: %w", len(path), ErrTooManyResults)
+	}
+	return path, nil
+}
+
 func (a *GatewayAPI) GasEstimateMessageGas(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, tsk types.TipSetKey) (*types.Message, error) {
 	if err := a.checkTipsetKey(ctx, tsk); err != nil {
 		return nil, err
@@ -222,7 +287,26 @@ func (a *GatewayAPI) StateLookupID(ctx context.Context, addr address.Address, ts
 }
 
 func (a *GatewayAPI) StateWaitMsg(ctx context.Context, msg cid.Cid, confidence uint64) (*api.MsgLookup, error) {
-	return a.api.StateWaitMsgLimited(ctx, msg, confidence, stateWaitLookbackLimit)
+	return a.api.StateWaitMsgBounded(ctx, msg, confidence, a.waitLookbackLimit, true)
+}
+
+func (a *GatewayAPI) StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return a.api.StateWaitMsgBounded(ctx, msg, confidence, a.waitLookback(limit), allowReplaced)
+}
+
+func (a *GatewayAPI) StateSearchMsg(ctx context.Context, msg cid.Cid) (*api.MsgLookup, error) {
+	return a.api.StateSearchMsgBounded(ctx, msg, a.waitLookbackLimit, true)
+}
+
+func (a *GatewayAPI) StateSearchMsgBounded(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	return a.api.StateSearchMsgBounded(ctx, msg, a.waitLookback(limit), allowReplaced)
+}
+
+func (a *GatewayAPI) waitLookback(limit abi.ChainEpoch) abi.ChainEpoch {
+	if limit == api.LookbackNoLimit || limit > a.waitLookbackLimit {
+		return a.waitLookbackLimit
+	}
+	return limit
 }
 
 func (a *GatewayAPI) StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
@@ -280,6 +364,49 @@ func (a *GatewayAPI) StateMinerProvingDeadline(ctx context.Context, m address.Ad
 	return a.api.StateMinerProvingDeadline(ctx, m, tsk)
 }
 
+func (a *GatewayAPI) StateMinerSectors(ctx context.Context, m address.Address, filter *bitfield.BitField, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	if err := a.checkTipsetKey(ctx, tsk); err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		n, err := filter.Count()
+		if err != nil {
+			return nil, xerrors.Errorf("counting filter: %w", err)
+		}
+		if n > uint64(a.maxResults) {
+			return nil, xerrors.Errorf("filter of %d sectors: %w", n, ErrTooManyResults)
+		}
+	} else {
+		// count the sectors before loading them all
+		count, err := a.api.StateMinerSectorCount(ctx, m, tsk)
+		if err != nil {
+			return nil, xerrors.Errorf("counting sectors: %w", err)
+		}
+		if count.Live > uint64(a.maxResults) {
+			return nil, xerrors.Errorf("%d sectors, pass a filter: %w", count.Live, ErrTooManyResults)
+		}
+	}
+
+	sectors, err := a.api.StateMinerSectors(ctx, m, filter, tsk)
+	if err != nil {
+		return nil, err
+	}
+	if len(sectors) > a.maxResults {
+		return nil, xerrors.Errorf("%d sectors, pass a filter: %w", len(sectors), ErrTooManyResults)
+	}
+	return sectors, nil
+}
+
+func (a *GatewayAPI) StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	if err := a.checkTipsetKey(ctx, tsk); err != nil {
+		return nil, err
+	}
+	if limit == 0 || limit > uint64(a.maxResults) {
+		limit = uint64(a.maxResults)
+	}
+	return a.api.StateMarketDealsPage(ctx, start, limit, tsk)
+}
+
 func (a *GatewayAPI) StateCirculatingSupply(ctx context.Context, tsk types.TipSetKey) (abi.TokenAmount, error) {
 	if err := a.checkTipsetKey(ctx, tsk); err != nil {
 		return types.BigInt{}, err
@@ -288,6 +415,13 @@ func (a *GatewayAPI) StateCirculatingSupply(ctx context.Context, tsk types.TipSe
 
 }
 
+func (a *GatewayAPI) StateVMCirculatingSupply(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+	if err := a.checkTipsetKey(ctx, tsk); err != nil {
+		return api.CirculatingSupply{}, err
+	}
+	return a.api.StateVMCirculatingSupply(ctx, tsk)
+}
+
 func (a *GatewayAPI) StateVMCirculatingSupplyInternal(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
 	if err := a.checkTipsetKey(ctx, tsk); err != nil {
 		return api.CirculatingSupply{}, err
@@ -13,10 +13,13 @@ import (
 	"github.com/filecoin-project/lotus/chain/types/mock"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
 )
 
 func TestGatewayAPIChainGetTipSetByHeight(t *testing.T) {
@@ -104,10 +107,49 @@ func TestGatewayAPIChainGetTipSetByHeight(t *testing.T) {
 	}
 }
 
+func TestGatewayAPIResultLimits(t *testing.T) {
+	ctx := context.Background()
+
+	mock := &mockGatewayDepsAPI{sectors: 3}
+	a := NewGatewayAPIWithLimits(mock, Limits{
+		LookbackCap:       LookbackCap,
+		WaitLookbackLimit: 5,
+		MaxResults:        2,
+	})
+
+	_, err := a.StateMinerSectors(ctx, address.Undef, nil, types.EmptyTSK)
+	require.True(t, xerrors.Is(err, ErrTooManyResults))
+	require.Equal(t, 1, mock.sectorCounts)
+	require.Equal(t, 0, mock.sectorLoads)
+
+	filter := bitfield.NewFromSet([]uint64{1, 2, 3})
+	_, err = a.StateMinerSectors(ctx, address.Undef, &filter, types.EmptyTSK)
+	require.True(t, xerrors.Is(err, ErrTooManyResults))
+
+	mock.sectors = 2
+	sectors, err := a.StateMinerSectors(ctx, address.Undef, nil, types.EmptyTSK)
+	require.NoError(t, err)
+	require.Len(t, sectors, 2)
+
+	_, err = a.StateMarketDealsPage(ctx, 0, 0, types.EmptyTSK)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), mock.dealsLimit)
+
+	_, err = a.StateSearchMsg(ctx, cid.Undef)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(5), mock.searchLimit)
+}
+
 type mockGatewayDepsAPI struct {
 	lk      sync.RWMutex
 	tipsets []*types.TipSet
 
+	sectors      int
+	sectorCounts int
+	sectorLoads  int
+	dealsLimit   uint64
+	searchLimit  abi.ChainEpoch
+
 	gatewayDepsAPI // satisfies all interface requirements but will panic if
 	// methods are called. easier than filling out with panic stubs IMO
 }
@@ -197,10 +239,30 @@ func (m *mockGatewayDepsAPI) StateLookupID(ctx context.Context, addr address.Add
 	panic("implement me")
 }
 
-func (m *mockGatewayDepsAPI) StateWaitMsgLimited(ctx context.Context, msg cid.Cid, confidence uint64, h abi.ChainEpoch) (*api.MsgLookup, error) {
+func (m *mockGatewayDepsAPI) StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
 	panic("implement me")
 }
 
 func (m *mockGatewayDepsAPI) StateReadState(ctx context.Context, act address.Address, ts types.TipSetKey) (*api.ActorState, error) {
 	panic("implement me")
 }
+
+func (m *mockGatewayDepsAPI) StateSearchMsgBounded(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	m.searchLimit = limit
+	return nil, nil
+}
+
+func (m *mockGatewayDepsAPI) StateMinerSectors(ctx context.Context, maddr address.Address, filter *bitfield.BitField, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	m.sectorLoads++
+	return make([]*miner.SectorOnChainInfo, m.sectors), nil
+}
+
+func (m *mockGatewayDepsAPI) StateMinerSectorCount(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (api.MinerSectors, error) {
+	m.sectorCounts++
+	return api.MinerSectors{Live: uint64(m.sectors)}, nil
+}
+
+func (m *mockGatewayDepsAPI) StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	m.dealsLimit = limit
+	return map[string]api.MarketDeal{}, nil
+}
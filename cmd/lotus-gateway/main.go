@@ -6,11 +6,18 @@ import (
 	"net/http"
 	"os"
 
+	"contrib.go.opencensus.io/exporter/prometheus"
 	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/build"
 	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/lotus/lib/lotuslog"
+	"github.com/filecoin-project/lotus/lib/reqid"
+	"github.com/filecoin-project/lotus/metrics"
 	logging "github.com/ipfs/go-log"
+	"go.opencensus.io/stats/view"
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
 
 	"github.com/gorilla/mux"
 	"github.com/urfave/cli/v2"
@@ -56,6 +63,41 @@ var runCmd = &cli.Command{
 			Usage: "host address and port the api server will listen on",
 			Value: "0.0.0.0:2346",
 		},
+		&cli.Float64Flag{
+			Name:  "rate-limit",
+			Usage: "most API calls per second served to all the clients, 0 for no limit",
+		},
+		&cli.Float64Flag{
+			Name:  "per-ip-rate-limit",
+			Usage: "most API calls per second served to a client IP, 0 for no limit",
+		},
+		&cli.Float64Flag{
+			Name:  "per-token-rate-limit",
+			Usage: "most API calls per second served to a token, 0 for no limit",
+		},
+		&cli.IntFlag{
+			Name:  "rate-limit-burst",
+			Usage: "number of API calls over the rate limits served at once (default: the limit)",
+		},
+		&cli.StringFlag{
+			Name:  "token-quotas",
+			Usage: "JSON file of the rate limits and lookback caps by token, like {\"<token>\": {\"Rate\": 50, \"Burst\": 100, \"Lookback\": \"72h\"}}",
+		},
+		&cli.DurationFlag{
+			Name:  "api-max-lookback",
+			Usage: "maximum duration allowable for tipset lookbacks",
+			Value: LookbackCap,
+		},
+		&cli.Int64Flag{
+			Name:  "api-wait-lookback-limit",
+			Usage: "maximum number of epochs StateWaitMsg and StateSearchMsg look back for a message",
+			Value: int64(stateWaitLookbackLimit),
+		},
+		&cli.IntFlag{
+			Name:  "api-max-results",
+			Usage: "maximum number of sectors, deals or tipsets returned by a call",
+			Value: MaxResults,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		log.Info("Starting lotus gateway")
@@ -75,10 +117,40 @@ var runCmd = &cli.Command{
 
 		log.Info("Setting up API endpoint at " + address)
 
+		limits := rateLimits{
+			Global:   rate.Limit(cctx.Float64("rate-limit")),
+			PerIP:    rate.Limit(cctx.Float64("per-ip-rate-limit")),
+			PerToken: rate.Limit(cctx.Float64("per-token-rate-limit")),
+			Burst:    cctx.Int("rate-limit-burst"),
+		}
+		if path := cctx.String("token-quotas"); path != "" {
+			if limits.Tokens, err = loadTokenQuotas(path); err != nil {
+				return xerrors.Errorf("loading token quotas: %w", err)
+			}
+		}
+		limiter := newLimiter(limits)
+
+		if err := view.Register(metrics.GatewayViews...); err != nil {
+			return xerrors.Errorf("registering metrics views: %w", err)
+		}
+		exporter, err := prometheus.NewExporter(prometheus.Options{
+			Namespace: "lotus_gateway",
+		})
+		if err != nil {
+			return xerrors.Errorf("creating the prometheus stats exporter: %w", err)
+		}
+
+		gwapi := NewGatewayAPIWithLimits(api, Limits{
+			LookbackCap:       cctx.Duration("api-max-lookback"),
+			WaitLookbackLimit: abi.ChainEpoch(cctx.Int64("api-wait-lookback-limit")),
+			MaxResults:        cctx.Int("api-max-results"),
+		})
+
 		rpcServer := jsonrpc.NewServer()
-		rpcServer.Register("Filecoin", NewGatewayAPI(api))
+		rpcServer.Register("Filecoin", rateLimitedAPI(gwapi, limiter))
 
-		mux.Handle("/rpc/v0", rpcServer)
+		mux.Handle("/rpc/v0", reqid.Handler(limiter.Handler(rpcServer)))
+		mux.Handle("/debug/metrics", exporter)
 		mux.PathPrefix("/").Handler(http.DefaultServeMux)
 
 		/*ah := &auth.Handler{
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/metrics"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+// limiterIdle is how long the limiters of the IPs and tokens are kept after
+// their last request.
+const limiterIdle = 10 * time.Minute
+
+// names of the limits, as reported in the metrics
+const (
+	limitGlobal = "global"
+	limitIP     = "ip"
+	limitToken  = "token"
+)
+
+// TokenQuota is the quota of a token, from the quota file of the gateway.
+type TokenQuota struct {
+	// Rate is the number of calls per second of the token and Burst the
+	// number of calls it can make at once, the per-token limits when 0. The
+	// tokens of the quota file aren't limited per IP.
+	Rate  float64
+	Burst int
+
+	// Lookback is the lookback cap of the token, the one of the gateway
+	// when 0
+	Lookback config.Duration
+}
+
+// rateLimits are the rate limits of the gateway, in calls per second, no
+// limit when 0.
+type rateLimits struct {
+	Global   rate.Limit
+	PerIP    rate.Limit
+	PerToken rate.Limit
+	// Burst is the number of calls above the limits made at once, the limit
+	// when 0
+	Burst int
+
+	Tokens map[string]TokenQuota
+}
+
+// loadTokenQuotas reads a quota file, a JSON object of the quotas by token.
+func loadTokenQuotas(path string) (map[string]TokenQuota, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotas map[string]TokenQuota
+	if err := json.Unmarshal(b, &quotas); err != nil {
+		return nil, xerrors.Errorf("decoding token quotas: %w", err)
+	}
+	return quotas, nil
+}
+
+type clientLimiter struct {
+	*rate.Limiter
+	lastUsed time.Time
+}
+
+type limiter struct {
+	limits rateLimits
+	global *rate.Limiter
+
+	lk     sync.Mutex
+	ips    map[string]*clientLimiter
+	tokens map[string]*clientLimiter
+	lastGC time.Time
+}
+
+func newLimiter(limits rateLimits) *limiter {
+	l := &limiter{
+		limits: limits,
+		ips:    map[string]*clientLimiter{},
+		tokens: map[string]*clientLimiter{},
+		lastGC: time.Now(),
+	}
+	if limits.Global > 0 {
+		l.global = rate.NewLimiter(limits.Global, l.burst(limits.Global, 0))
+	}
+	return l
+}
+
+func (l *limiter) burst(r rate.Limit, burst int) int {
+	if burst == 0 {
+		burst = l.limits.Burst
+	}
+	if burst == 0 {
+		burst = int(r)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return burst
+}
+
+// caller is the client calling the gateway, in the context of its calls.
+type caller struct {
+	ip    string
+	token string
+	quota TokenQuota
+	// ws is set for websocket connections, the calls of which are limited
+	// one by one, where the other requests are limited by the handler
+	ws bool
+}
+
+type callerKey struct{}
+
+func callerFromContext(ctx context.Context) *caller {
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	return c
+}
+
+// allow returns the limit a call of the client is over, or an empty string.
+func (l *limiter) allow(c *caller) string {
+	if l.global != nil && !l.global.Allow() {
+		return limitGlobal
+	}
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastGC) > limiterIdle {
+		for _, m := range []map[string]*clientLimiter{l.ips, l.tokens} {
+			for k, cl := range m {
+				if now.Sub(cl.lastUsed) > limiterIdle {
+					delete(m, k)
+				}
+			}
+		}
+		l.lastGC = now
+	}
+
+	_, quota := l.limits.Tokens[c.token]
+	if !quota && l.limits.PerIP > 0 && !l.get(l.ips, c.ip, l.limits.PerIP, 0, now).Allow() {
+		return limitIP
+	}
+
+	if c.token != "" {
+		r, burst := l.limits.PerToken, 0
+		if c.quota.Rate > 0 {
+			r, burst = rate.Limit(c.quota.Rate), c.quota.Burst
+		}
+		if r > 0 && !l.get(l.tokens, c.token, r, burst, now).Allow() {
+			return limitToken
+		}
+	}
+
+	return ""
+}
+
+func (l *limiter) get(m map[string]*clientLimiter, k string, r rate.Limit, burst int, now time.Time) *clientLimiter {
+	cl, ok := m[k]
+	if !ok {
+		cl = &clientLimiter{Limiter: rate.NewLimiter(r, l.burst(r, burst))}
+		m[k] = cl
+	}
+	cl.lastUsed = now
+	return cl
+}
+
+func recordRateLimited(ctx context.Context, limit string) {
+	ctx, _ = tag.New(ctx, tag.Upsert(metrics.RateLimit, limit))
+	stats.Record(ctx, metrics.GatewayRateLimited.M(1))
+}
+
+// Handler rate limits the requests, answering 429 Too Many Requests to the
+// ones over a limit, and sets the client of the others in their context.
+func (l *limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &caller{
+			ip:    remoteIP(r),
+			token: requestToken(r),
+			ws:    websocket.IsWebSocketUpgrade(r),
+		}
+		c.quota = l.limits.Tokens[c.token]
+
+		if limit := l.allow(c); limit != "" {
+			recordRateLimited(r.Context(), limit)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, c)))
+	})
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestToken returns the bearer token of the request, like auth.Handler
+// reads it.
+func requestToken(r *http.Request) string {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return r.FormValue("token")
+	}
+	if !strings.HasPrefix(token, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(token, "Bearer ")
+}
+
+// rateLimitedAPI wraps the methods of the gateway API to rate limit the calls
+// made over websockets, and to count the calls.
+func rateLimitedAPI(a api.GatewayAPI, l *limiter) api.GatewayAPI {
+	var out apistruct.GatewayStruct
+	apistruct.Proxy(a, &out.Internal, func(m apistruct.ProxyMethod, next apistruct.ProxyCall) apistruct.ProxyCall {
+		return func(args []reflect.Value) []reflect.Value {
+			ctx := args[0].Interface().(context.Context)
+			stats.Record(ctx, metrics.GatewayRequests.M(1))
+
+			c := callerFromContext(ctx)
+			if c == nil || !c.ws {
+				return next(args)
+			}
+
+			limit := l.allow(c)
+			if limit == "" {
+				return next(args)
+			}
+
+			recordRateLimited(ctx, limit)
+			return apistruct.ErrorResults(m.Type, xerrors.Errorf("rate limit exceeded (%s)", limit))
+		}
+	})
+	return &out
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+func TestRateLimits(t *testing.T) {
+	l := newLimiter(rateLimits{
+		PerIP:    1,
+		PerToken: 2,
+		Tokens: map[string]TokenQuota{
+			"paid": {Rate: 1, Burst: 5},
+		},
+	})
+
+	anon := &caller{ip: "1.2.3.4"}
+	require.Equal(t, "", l.allow(anon))
+	require.Equal(t, limitIP, l.allow(anon))
+	// other IPs have their own limits
+	require.Equal(t, "", l.allow(&caller{ip: "1.2.3.5"}))
+
+	// the tokens of the quota file aren't limited per IP
+	paid := &caller{ip: "1.2.3.4", token: "paid", quota: l.limits.Tokens["paid"]}
+	for i := 0; i < 5; i++ {
+		require.Equal(t, "", l.allow(paid))
+	}
+	require.Equal(t, limitToken, l.allow(paid))
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	l := newLimiter(rateLimits{Global: 1})
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotNil(t, callerFromContext(r.Context()))
+	}))
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/rpc/v0", nil))
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, serve())
+	require.Equal(t, http.StatusTooManyRequests, serve())
+}
+
+func TestTokenLookback(t *testing.T) {
+	a := &GatewayAPI{lookbackCap: time.Hour}
+	at := time.Now().Add(-2 * time.Hour)
+
+	require.Equal(t, ErrLookbackTooLong, a.checkTimestamp(context.Background(), at))
+
+	ctx := context.WithValue(context.Background(), callerKey{}, &caller{
+		token: "archive",
+		quota: TokenQuota{Lookback: config.Duration(3 * time.Hour)},
+	})
+	require.NoError(t, a.checkTimestamp(ctx, at))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/node/repo"
+	"github.com/filecoin-project/lotus/node/repo/remotebs"
+)
+
+var blockstoreCmd = &cli.Command{
+	Name:        "blockstore",
+	Description: "tools for working with node blockstores",
+	Subcommands: []*cli.Command{
+		blockstoreServeCmd,
+	},
+}
+
+var blockstoreServeCmd = &cli.Command{
+	Name:  "serve",
+	Usage: "serve a repo blockstore to other nodes using the 'remote' blockstore backend",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "listen",
+			Usage: "address to listen on",
+			Value: "127.0.0.1:9090",
+		},
+		&cli.StringFlag{
+			Name:  "domain",
+			Usage: "repo datastore to serve",
+			Value: "/chain",
+		},
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "require clients to send this bearer token",
+		},
+		&cli.IntFlag{
+			Name:  "repo-type",
+			Usage: "node type (1 - full, 2 - storage, 3 - worker)",
+			Value: 1,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		r, err := repo.NewFS(cctx.String("repo"))
+		if err != nil {
+			return xerrors.Errorf("opening fs repo: %w", err)
+		}
+
+		exists, err := r.Exists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return xerrors.Errorf("lotus repo doesn't exist")
+		}
+
+		lr, err := r.Lock(repo.RepoType(cctx.Int("repo-type")))
+		if err != nil {
+			return err
+		}
+		defer lr.Close() //nolint:errcheck
+
+		ds, err := lr.Datastore(datastore.NewKey(cctx.String("domain")).String())
+		if err != nil {
+			return err
+		}
+
+		if cctx.String("token") == "" {
+			log.Warn("serving blockstore without authentication")
+		}
+
+		h := remotebs.NewHandler(blockstore.NewBlockstore(ds), cctx.String("token"))
+
+		log.Infof("serving %s blockstore on %s", cctx.String("domain"), cctx.String("listen"))
+		return http.ListenAndServe(cctx.String("listen"), h)
+	},
+}
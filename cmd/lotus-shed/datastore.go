@@ -25,6 +25,9 @@ var datastoreCmd = &cli.Command{
 		datastoreBackupCmd,
 		datastoreListCmd,
 		datastoreGetCmd,
+		datastoreExportCmd,
+		datastoreDeleteCmd,
+		datastoreRestoreCmd,
 	},
 }
 
@@ -149,6 +152,280 @@ var datastoreGetCmd = &cli.Command{
 	},
 }
 
+var datastoreExportCmd = &cli.Command{
+	Name:        "export",
+	Description: "export datastore entries under a prefix into a backup file",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "repo-type",
+			Usage: "node type (1 - full, 2 - storage, 3 - worker)",
+			Value: 1,
+		},
+	},
+	ArgsUsage: "[namespace prefix] [file]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 3 {
+			return xerrors.Errorf("expected 3 arguments")
+		}
+
+		lr, ds, err := openRepoDatastore(cctx, cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		defer lr.Close() //nolint:errcheck
+
+		n, err := writeBackupFile(cctx.Args().Get(2), ds, dsq.Query{
+			Prefix: datastore.NewKey(cctx.Args().Get(1)).String(),
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("exported %d keys\n", n)
+		return nil
+	},
+}
+
+var datastoreDeleteCmd = &cli.Command{
+	Name:        "delete",
+	Description: "delete datastore keys",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "repo-type",
+			Usage: "node type (1 - full, 2 - storage, 3 - worker)",
+			Value: 1,
+		},
+		&cli.BoolFlag{
+			Name:  "prefix",
+			Usage: "delete all keys under the given key instead of just the key",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only print the keys which would be deleted",
+		},
+		&cli.StringFlag{
+			Name:  "backup",
+			Usage: "write the deleted entries to this backup file first",
+		},
+		&cli.BoolFlag{
+			Name:  "no-backup",
+			Usage: "delete without writing a backup (dangerous)",
+		},
+	},
+	ArgsUsage: "[namespace key]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.Errorf("expected 2 arguments")
+		}
+
+		dryRun := cctx.Bool("dry-run")
+		bakPath := cctx.String("backup")
+		if !dryRun && bakPath == "" && !cctx.Bool("no-backup") {
+			return xerrors.Errorf("refusing to delete without --backup; pass --no-backup to override")
+		}
+
+		lr, ds, err := openRepoDatastore(cctx, cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		defer lr.Close() //nolint:errcheck
+
+		key := datastore.NewKey(cctx.Args().Get(1))
+
+		var keys []datastore.Key
+		if cctx.Bool("prefix") {
+			q, err := ds.Query(dsq.Query{
+				Prefix:   key.String(),
+				KeysOnly: true,
+			})
+			if err != nil {
+				return xerrors.Errorf("datastore query: %w", err)
+			}
+
+			res, err := q.Rest()
+			if err != nil {
+				return xerrors.Errorf("listing keys: %w", err)
+			}
+			for _, r := range res {
+				keys = append(keys, datastore.NewKey(r.Key))
+			}
+		} else {
+			has, err := ds.Has(key)
+			if err != nil {
+				return err
+			}
+			if !has {
+				return xerrors.Errorf("key %s not found", key)
+			}
+			keys = append(keys, key)
+		}
+
+		if dryRun {
+			for _, k := range keys {
+				fmt.Println(k)
+			}
+			fmt.Printf("would delete %d keys\n", len(keys))
+			return nil
+		}
+
+		if bakPath != "" {
+			// copy the entries into memory so that the backup contains exactly
+			// the keys being deleted
+			mds := datastore.NewMapDatastore()
+			for _, k := range keys {
+				v, err := ds.Get(k)
+				if err != nil {
+					return xerrors.Errorf("reading %s: %w", k, err)
+				}
+				if err := mds.Put(k, v); err != nil {
+					return err
+				}
+			}
+
+			n, err := writeBackupFile(bakPath, mds, dsq.Query{})
+			if err != nil {
+				return xerrors.Errorf("writing backup: %w", err)
+			}
+			if n < len(keys) {
+				return xerrors.Errorf("backup only contains %d of %d keys, not deleting", n, len(keys))
+			}
+		}
+
+		b, err := ds.Batch()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return xerrors.Errorf("deleting %s: %w", k, err)
+			}
+		}
+		if err := b.Commit(); err != nil {
+			return xerrors.Errorf("committing deletes: %w", err)
+		}
+
+		fmt.Printf("deleted %d keys\n", len(keys))
+		return nil
+	},
+}
+
+var datastoreRestoreCmd = &cli.Command{
+	Name:        "restore",
+	Description: "write the entries from a backup file into a datastore",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "repo-type",
+			Usage: "node type (1 - full, 2 - storage, 3 - worker)",
+			Value: 1,
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only print the keys which would be written",
+		},
+	},
+	ArgsUsage: "[namespace] [file]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.Errorf("expected 2 arguments")
+		}
+
+		f, err := os.Open(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("opening backup file: %w", err)
+		}
+		defer f.Close() // nolint:errcheck
+
+		if cctx.Bool("dry-run") {
+			var n int
+			err := backupds.ReadBackup(f, func(key datastore.Key, value []byte) error {
+				n++
+				fmt.Println(key)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("would write %d keys\n", n)
+			return nil
+		}
+
+		lr, ds, err := openRepoDatastore(cctx, cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		defer lr.Close() //nolint:errcheck
+
+		return backupds.RestoreInto(f, ds)
+	},
+}
+
+// openRepoDatastore locks the repo and opens the given namespace. The caller
+// must close the returned repo.
+func openRepoDatastore(cctx *cli.Context, namespace string) (repo.LockedRepo, datastore.Batching, error) {
+	logging.SetLogLevel("badger", "ERROR") // nolint:errcheck
+
+	r, err := repo.NewFS(cctx.String("repo"))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("opening fs repo: %w", err)
+	}
+
+	exists, err := r.Exists()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, xerrors.Errorf("lotus repo doesn't exist")
+	}
+
+	lr, err := r.Lock(repo.RepoType(cctx.Int("repo-type")))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ds, err := lr.Datastore(datastore.NewKey(namespace).String())
+	if err != nil {
+		_ = lr.Close()
+		return nil, nil, err
+	}
+
+	return lr, ds, nil
+}
+
+// writeBackupFile writes the entries of ds matching q into a new backup
+// file, then reads it back to verify it, returning the number of entries.
+func writeBackupFile(path string, ds datastore.Read, q dsq.Query) (int, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, xerrors.Errorf("creating backup file: %w", err)
+	}
+
+	if err := backupds.WriteBackup(f, ds, q); err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+
+	if err := f.Close(); err != nil {
+		return 0, xerrors.Errorf("closing backup file: %w", err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return 0, xerrors.Errorf("opening backup file: %w", err)
+	}
+	defer f.Close() // nolint:errcheck
+
+	var n int
+	err = backupds.ReadBackup(f, func(key datastore.Key, value []byte) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("verifying backup: %w", err)
+	}
+
+	return n, nil
+}
+
 var datastoreBackupCmd = &cli.Command{
 	Name:        "backup",
 	Description: "manage datastore backups",
@@ -40,8 +40,13 @@ func main() {
 		serveDealStatsCmd,
 		syncCmd,
 		stateTreePruneCmd,
+		stateCmd,
 		datastoreCmd,
+		blockstoreCmd,
 		ledgerCmd,
+		minerCmd,
+		rpcReplayCmd,
+		replayBlockCmd,
 	}
 
 	app := &cli.App{
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
+	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apibstore"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var minerCmd = &cli.Command{
+	Name:  "miner",
+	Usage: "Tools for inspecting miner actors",
+	Subcommands: []*cli.Command{
+		minerPenaltiesCmd,
+	},
+}
+
+const (
+	penaltyFaults        = "faults"
+	penaltyPreCommits    = "expired precommits"
+	penaltyTerminations  = "termination fees"
+	penaltyOtherCronBurn = "other"
+)
+
+var minerPenaltiesCmd = &cli.Command{
+	Name:      "penalties",
+	Usage:     "Attribute the funds burnt by a miner in cron over a range of epochs to their causes",
+	ArgsUsage: "[minerAddress]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "range",
+			Usage:    "epochs to walk, as 'start:end'; the end defaults to the head",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "print every penalty",
+		},
+	},
+	Description: `Re-executes every tipset in the range and walks the execution traces of the
+cron calls to the miner. Burns during proving deadline processing are split
+between the deposits of the precommits which expired in the tipset and fault
+penalties; burns while processing early terminations are termination fees.
+Penalties paid by messages sent to the miner (e.g. TerminateSectors) aren't
+included.`,
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("must pass miner address")
+		}
+
+		api, closer, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		maddr, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		maddr, err = api.StateLookupID(ctx, maddr, types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("looking up miner: %w", err)
+		}
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		start, end, err := parseEpochRange(cctx.String("range"), head.Height())
+		if err != nil {
+			return err
+		}
+
+		ts, err := api.ChainGetTipSetByHeight(ctx, end, head.Key())
+		if err != nil {
+			return xerrors.Errorf("loading end tipset: %w", err)
+		}
+
+		store := adt.WrapStore(ctx, cbor.NewCborStore(apibstore.NewAPIBlockstore(api)))
+
+		totals := map[string]abi.TokenAmount{}
+		for ts.Height() >= start && ts.Height() > 0 {
+			pts, err := api.ChainGetTipSet(ctx, ts.Parents())
+			if err != nil {
+				return xerrors.Errorf("loading parent tipset: %w", err)
+			}
+
+			penalties, err := tipsetPenalties(ctx, api, store, maddr, ts)
+			if err != nil {
+				return xerrors.Errorf("attributing penalties at %d: %w", ts.Height(), err)
+			}
+
+			for _, p := range penalties {
+				if cctx.Bool("verbose") {
+					fmt.Printf("%d\t%s\t%s\n", p.epoch, p.cause, types.FIL(p.amount))
+				}
+				if _, ok := totals[p.cause]; !ok {
+					totals[p.cause] = big.Zero()
+				}
+				totals[p.cause] = big.Add(totals[p.cause], p.amount)
+			}
+
+			ts = pts
+		}
+
+		total := big.Zero()
+		fmt.Printf("Burnt by %s in cron between epochs %d and %d:\n", maddr, start, end)
+		for _, cause := range []string{penaltyFaults, penaltyPreCommits, penaltyTerminations, penaltyOtherCronBurn} {
+			amt, ok := totals[cause]
+			if !ok {
+				amt = big.Zero()
+			}
+			total = big.Add(total, amt)
+			fmt.Printf("  %-20s %s\n", cause+":", types.FIL(amt))
+		}
+		fmt.Printf("  %-20s %s\n", "total:", types.FIL(total))
+
+		return nil
+	},
+}
+
+func parseEpochRange(s string, head abi.ChainEpoch) (abi.ChainEpoch, abi.ChainEpoch, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, 0, xerrors.Errorf("expected a range as 'start:end', got %q", s)
+	}
+
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, xerrors.Errorf("parsing start epoch: %w", err)
+	}
+
+	end := int64(head)
+	if parts[1] != "" {
+		end, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, xerrors.Errorf("parsing end epoch: %w", err)
+		}
+	}
+
+	if start > end {
+		return 0, 0, xerrors.Errorf("start epoch %d is after end epoch %d", start, end)
+	}
+	if abi.ChainEpoch(end) > head {
+		return 0, 0, xerrors.Errorf("end epoch %d is after the head (%d)", end, head)
+	}
+	return abi.ChainEpoch(start), abi.ChainEpoch(end), nil
+}
+
+type penalty struct {
+	epoch  abi.ChainEpoch
+	cause  string
+	amount abi.TokenAmount
+}
+
+// tipsetPenalties returns the funds the miner burnt in the crons run while
+// executing ts on top of its parent.
+func tipsetPenalties(ctx context.Context, fapi api.FullNode, store adt.Store, maddr address.Address, ts *types.TipSet) ([]penalty, error) {
+	out, err := fapi.StateCompute(ctx, ts.Height(), nil, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("computing state: %w", err)
+	}
+
+	// crons run for every null round between the parent and ts, and for ts
+	var crons []types.ExecutionTrace
+	for _, ir := range out.Trace {
+		if ir.Msg.From == builtin.SystemActorAddr && ir.Msg.To == builtin.CronActorAddr {
+			crons = append(crons, ir.ExecutionTrace)
+		}
+	}
+	if len(crons) == 0 {
+		return nil, nil
+	}
+
+	var penalties []penalty
+	var deadlineBurns []int
+	for i, cron := range crons {
+		epoch := ts.Height() - abi.ChainEpoch(len(crons)-1-i)
+
+		err := walkMinerCronCalls(cron, maddr, func(event miner0.CronEventType, burnt abi.TokenAmount) {
+			cause := penaltyOtherCronBurn
+			switch event {
+			case miner0.CronEventProvingDeadline:
+				cause = penaltyFaults
+				deadlineBurns = append(deadlineBurns, len(penalties))
+			case miner0.CronEventProcessEarlyTerminations:
+				cause = penaltyTerminations
+			}
+			penalties = append(penalties, penalty{epoch: epoch, cause: cause, amount: burnt})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(deadlineBurns) == 0 {
+		return penalties, nil
+	}
+
+	// the deadline burns include the deposits of the expired precommits
+	expired, err := expiredPreCommitDeposits(store, maddr, ts.ParentState(), out.Root)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range deadlineBurns {
+		if expired.IsZero() {
+			break
+		}
+
+		p := penalties[i]
+		amt := big.Min(p.amount, expired)
+		expired = big.Sub(expired, amt)
+
+		penalties[i].amount = big.Sub(p.amount, amt)
+		penalties = append(penalties, penalty{epoch: p.epoch, cause: penaltyPreCommits, amount: amt})
+	}
+
+	return penalties, nil
+}
+
+// walkMinerCronCalls calls cb with every burn the miner makes while handling
+// its cron events.
+func walkMinerCronCalls(et types.ExecutionTrace, maddr address.Address, cb func(miner0.CronEventType, abi.TokenAmount)) error {
+	if et.Msg.To == maddr && et.Msg.Method == builtin0.MethodsMiner.OnDeferredCronEvent {
+		var payload miner0.CronEventPayload
+		if err := payload.UnmarshalCBOR(bytes.NewReader(et.Msg.Params)); err != nil {
+			return xerrors.Errorf("decoding cron event payload: %w", err)
+		}
+
+		for _, sub := range et.Subcalls {
+			if sub.Msg.To == builtin.BurntFundsActorAddr && sub.MsgRct != nil && sub.MsgRct.ExitCode.IsSuccess() && !sub.Msg.Value.IsZero() {
+				cb(payload.EventType, sub.Msg.Value)
+			}
+		}
+		return nil
+	}
+
+	for _, sub := range et.Subcalls {
+		if err := walkMinerCronCalls(sub, maddr, cb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiredPreCommitDeposits sums the deposits of the precommits removed
+// between the two states without becoming sectors.
+func expiredPreCommitDeposits(store adt.Store, maddr address.Address, pre, post cid.Cid) (abi.TokenAmount, error) {
+	preSt, err := loadMinerAt(store, maddr, pre)
+	if err != nil {
+		return big.Zero(), err
+	}
+	postSt, err := loadMinerAt(store, maddr, post)
+	if err != nil {
+		return big.Zero(), err
+	}
+
+	changes, err := miner.DiffPreCommits(preSt, postSt)
+	if err != nil {
+		return big.Zero(), xerrors.Errorf("diffing precommits: %w", err)
+	}
+
+	total := big.Zero()
+	for _, pc := range changes.Removed {
+		s, err := postSt.GetSector(pc.Info.SectorNumber)
+		if err != nil {
+			return big.Zero(), xerrors.Errorf("getting sector %d: %w", pc.Info.SectorNumber, err)
+		}
+		if s == nil {
+			total = big.Add(total, pc.PreCommitDeposit)
+		}
+	}
+	return total, nil
+}
+
+func loadMinerAt(store adt.Store, maddr address.Address, root cid.Cid) (miner.State, error) {
+	tree, err := state.LoadStateTree(store, root)
+	if err != nil {
+		return nil, xerrors.Errorf("loading state tree %s: %w", root, err)
+	}
+	act, err := tree.GetActor(maddr)
+	if err != nil {
+		return nil, xerrors.Errorf("getting miner actor: %w", err)
+	}
+	return miner.Load(store, act)
+}
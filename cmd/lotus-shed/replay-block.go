@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/node/repo"
+)
+
+var replayBlockCmd = &cli.Command{
+	Name:  "replay-block",
+	Usage: "re-execute the messages of a block on its parent state and compare the receipts with the chain",
+	Description: `The messages of the blocks before it in its tipset are applied first, as the
+   chain does, then the receipts of the messages of the block are compared with
+   the receipts recorded by the child tipset, and the first divergent message is
+   reported. The receipts are taken from the canonical chain by default; to
+   replay a block on a fork, pass a block built on its tipset with --child.
+
+   The node must not be running, the chain is read from the repo.`,
+	ArgsUsage: "[blockCid]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "child",
+			Usage: "block built on the tipset of the block, whose receipts are compared",
+		},
+		&cli.BoolFlag{
+			Name:  "trace",
+			Usage: "print the execution trace of the first divergent message",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := context.TODO()
+
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("expected the block CID as the only argument")
+		}
+		bcid, err := cid.Decode(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing block CID: %w", err)
+		}
+
+		fsrepo, err := repo.NewFS(cctx.String("repo"))
+		if err != nil {
+			return err
+		}
+
+		lkrepo, err := fsrepo.Lock(repo.FullNode)
+		if err != nil {
+			return err
+		}
+
+		defer lkrepo.Close() //nolint:errcheck
+
+		ds, err := lkrepo.Datastore("/chain")
+		if err != nil {
+			return err
+		}
+
+		mds, err := lkrepo.Datastore("/metadata")
+		if err != nil {
+			return err
+		}
+
+		bs := blockstore.NewBlockstore(ds)
+
+		cs := store.NewChainStore(bs, mds, vm.Syscalls(ffiwrapper.ProofVerifier), nil)
+		if err := cs.Load(); err != nil {
+			return xerrors.Errorf("loading chainstore: %w", err)
+		}
+
+		sm := stmgr.NewStateManager(cs)
+
+		blk, err := cs.GetBlock(bcid)
+		if err != nil {
+			return xerrors.Errorf("getting block: %w", err)
+		}
+
+		child, err := replayChild(ctx, cctx, cs, blk)
+		if err != nil {
+			return err
+		}
+
+		ts, err := cs.LoadTipSet(types.NewTipSetKey(child.Parents...))
+		if err != nil {
+			return xerrors.Errorf("loading tipset of the block: %w", err)
+		}
+		pos := -1
+		for i, c := range ts.Cids() {
+			if c == bcid {
+				pos = i
+			}
+		}
+		if pos < 0 {
+			return xerrors.Errorf("block %s isn't a parent of child block %s", bcid, child.Cid())
+		}
+
+		bms, err := cs.BlockMsgsForTipset(ts)
+		if err != nil {
+			return xerrors.Errorf("getting block messages for tipset: %w", err)
+		}
+		bms = bms[:pos+1]
+
+		// index the messages as the receipts are, skipping the messages
+		// included by several blocks
+		receiptIdx := make(map[cid.Cid]int)
+		var target []cid.Cid
+		processed := make(map[cid.Cid]struct{})
+		for i, bm := range bms {
+			for _, cm := range append(bm.BlsMessages, bm.SecpkMessages...) {
+				if _, found := processed[cm.VMMessage().Cid()]; found {
+					continue
+				}
+				processed[cm.VMMessage().Cid()] = struct{}{}
+
+				receiptIdx[cm.Cid()] = len(receiptIdx)
+				if i == pos {
+					target = append(target, cm.Cid())
+				}
+			}
+		}
+		inTarget := make(map[cid.Cid]struct{}, len(target))
+		for _, c := range target {
+			inTarget[c] = struct{}{}
+		}
+
+		var parentEpoch abi.ChainEpoch
+		if ts.Height() > 0 {
+			parent, err := cs.LoadTipSet(ts.Parents())
+			if err != nil {
+				return xerrors.Errorf("loading parent tipset: %w", err)
+			}
+			parentEpoch = parent.Height()
+		}
+
+		var divergent int
+		cb := func(c cid.Cid, m *types.Message, r *vm.ApplyRet) error {
+			if _, ok := inTarget[c]; !ok {
+				return nil
+			}
+			idx := receiptIdx[c]
+
+			exp, err := cs.GetParentReceipt(child, idx)
+			if err != nil {
+				return xerrors.Errorf("getting receipt %d: %w", idx, err)
+			}
+			if exp.Equals(&r.MessageReceipt) {
+				return nil
+			}
+
+			divergent++
+			if divergent > 1 {
+				return nil
+			}
+
+			fmt.Printf("First divergent message: %s (receipt %d)\n", c, idx)
+			fmt.Printf("  %s -> %s, method %d, nonce %d\n", m.From, m.To, m.Method, m.Nonce)
+			fmt.Printf("  chain:  exit %d, gas used %d, return %x\n", exp.ExitCode, exp.GasUsed, exp.Return)
+			fmt.Printf("  replay: exit %d, gas used %d, return %x\n", r.ExitCode, r.GasUsed, r.Return)
+			if r.ActorErr != nil {
+				fmt.Printf("  replay error: %s\n", r.ActorErr)
+			}
+			if cctx.Bool("trace") {
+				b, err := json.MarshalIndent(r.ExecutionTrace, "", "  ")
+				if err != nil {
+					return xerrors.Errorf("encoding execution trace: %w", err)
+				}
+				fmt.Println(string(b))
+			}
+			return nil
+		}
+
+		r := store.NewChainRand(cs, ts.Cids())
+		st, rectroot, err := sm.ApplyBlocks(ctx, parentEpoch, ts.ParentState(), bms, ts.Height(), r, cb, ts.Blocks()[0].ParentBaseFee, ts)
+		if err != nil {
+			return xerrors.Errorf("applying messages: %w", err)
+		}
+
+		if divergent > 0 {
+			fmt.Printf("%d of %d messages of block %s diverge\n", divergent, len(target), bcid)
+		} else {
+			fmt.Printf("The receipts of the %d messages of block %s match\n", len(target), bcid)
+		}
+
+		// with the last block of the tipset, the whole tipset was replayed
+		if pos == len(ts.Blocks())-1 {
+			if st != child.ParentStateRoot {
+				fmt.Printf("State root differs: chain %s, replay %s\n", child.ParentStateRoot, st)
+			}
+			if rectroot != child.ParentMessageReceipts {
+				fmt.Printf("Receipts root differs: chain %s, replay %s\n", child.ParentMessageReceipts, rectroot)
+			}
+		}
+
+		if divergent > 0 {
+			return xerrors.Errorf("replay diverged")
+		}
+		return nil
+	},
+}
+
+// replayChild returns the block whose parent receipts are compared with the
+// replayed ones: the block passed with --child, or else the first block of the
+// tipset following the one of blk on the canonical chain.
+func replayChild(ctx context.Context, cctx *cli.Context, cs *store.ChainStore, blk *types.BlockHeader) (*types.BlockHeader, error) {
+	if cctx.IsSet("child") {
+		c, err := cid.Decode(cctx.String("child"))
+		if err != nil {
+			return nil, xerrors.Errorf("parsing child block CID: %w", err)
+		}
+		child, err := cs.GetBlock(c)
+		if err != nil {
+			return nil, xerrors.Errorf("getting child block: %w", err)
+		}
+		return child, nil
+	}
+
+	head := cs.GetHeaviestTipSet()
+	if blk.Height >= head.Height() {
+		return nil, xerrors.Errorf("the tipset at height %d hasn't been executed by the chain yet", blk.Height)
+	}
+	next, err := cs.GetTipsetByHeight(ctx, blk.Height+1, head, false)
+	if err != nil {
+		return nil, xerrors.Errorf("getting child tipset: %w", err)
+	}
+	return next.Blocks()[0], nil
+}
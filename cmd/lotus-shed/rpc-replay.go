@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api/apirecord"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var rpcReplayCmd = &cli.Command{
+	Name:  "rpc-replay",
+	Usage: "replay API calls recorded with 'lotus daemon --api-record' against a node and diff the results",
+	Description: `Only calls of read methods are replayed, the others would change the state of
+   the node. Results depending on the head of the chain, like ChainHead, naturally
+   differ unless both nodes are at the same head; skip them with --ignore.`,
+	ArgsUsage: "[record file]",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "ignore",
+			Usage: "methods not to replay",
+		},
+		&cli.IntFlag{
+			Name:  "max-len",
+			Usage: "truncate the results of differing calls to this many bytes, 0 to print them whole",
+			Value: 1024,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("expected the record file as the only argument")
+		}
+
+		f, err := os.Open(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		entries, err := apirecord.ReadEntries(f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+
+		api, closer, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		ignore := map[string]bool{}
+		for _, m := range cctx.StringSlice("ignore") {
+			ignore[m] = true
+		}
+
+		var replayed, skipped, differ, failed int
+		for _, e := range entries {
+			if !e.Replayable() || ignore[e.Method] {
+				skipped++
+				continue
+			}
+
+			got, err := apirecord.Replay(ctx, api, e)
+			if err != nil {
+				failed++
+				fmt.Printf("#%d %s: replay failed: %s\n", e.Seq, e.Method, err)
+				continue
+			}
+			replayed++
+
+			if apirecord.Equal(&e, got) {
+				continue
+			}
+			differ++
+
+			fmt.Printf("#%d %s(%s) differs:\n", e.Seq, e.Method, truncate(params(&e), cctx.Int("max-len")))
+			fmt.Printf("  recorded: %s\n", truncate(describe(&e), cctx.Int("max-len")))
+			fmt.Printf("  replayed: %s\n", truncate(describe(got), cctx.Int("max-len")))
+		}
+
+		fmt.Printf("%d calls replayed, %d differ, %d failed to replay, %d skipped\n", replayed, differ, failed, skipped)
+		if differ > 0 || failed > 0 {
+			return xerrors.Errorf("%d of %d replayed calls differ", differ+failed, replayed+failed)
+		}
+		return nil
+	},
+}
+
+func params(e *apirecord.Entry) string {
+	ps := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		ps[i] = string(p)
+	}
+	return strings.Join(ps, ", ")
+}
+
+func describe(e *apirecord.Entry) string {
+	if e.Error != "" {
+		return "error: " + e.Error
+	}
+	return string(e.Result)
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
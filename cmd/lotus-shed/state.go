@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/urfave/cli/v2"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/vm"
+	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/node/repo"
+)
+
+var stateCmd = &cli.Command{
+	Name:  "state",
+	Usage: "examine the state stored in the local chainstore",
+	Subcommands: []*cli.Command{
+		stateGcEstimateCmd,
+	},
+}
+
+// stateGarbage accounts the objects which would be pruned
+type stateGarbage struct {
+	Objects int
+	Bytes   int64
+}
+
+var stateGcEstimateCmd = &cli.Command{
+	Name:  "gc-estimate",
+	Usage: "estimate how much data pruning the states older than keep-epochs would reclaim",
+	Description: `Marks the objects state-prune keeps, then walks the older state roots and
+   accounts the objects only they reference to the type of the actor they
+   belong to. The objects of the state trees themselves are accounted as
+   'state tree', and objects shared by several actors to the first one walked.
+   Nothing is deleted; the node must not be running.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "repo",
+			Value: "~/.lotus",
+		},
+		&cli.Int64Flag{
+			Name:  "keep-epochs",
+			Usage: "keep the states at or newer than the current height minus this many epochs",
+			Value: 1800, // 2 x finality
+		},
+		&cli.BoolFlag{
+			Name:  "use-bloom-set",
+			Usage: "use a bloom filter for the keep set instead of a map, reduces memory usage but may underestimate",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := context.TODO()
+
+		fsrepo, err := repo.NewFS(cctx.String("repo"))
+		if err != nil {
+			return err
+		}
+
+		lkrepo, err := fsrepo.Lock(repo.FullNode)
+		if err != nil {
+			return err
+		}
+
+		defer lkrepo.Close() //nolint:errcheck
+
+		ds, err := lkrepo.Datastore("/chain")
+		if err != nil {
+			return err
+		}
+
+		defer ds.Close() //nolint:errcheck
+
+		mds, err := lkrepo.Datastore("/metadata")
+		if err != nil {
+			return err
+		}
+		defer mds.Close() //nolint:errcheck
+
+		bs := blockstore.NewBlockstore(ds)
+
+		cs := store.NewChainStore(bs, mds, vm.Syscalls(ffiwrapper.ProofVerifier), nil)
+		if err := cs.Load(); err != nil {
+			return fmt.Errorf("loading chainstore: %w", err)
+		}
+
+		var keepSet cidSet
+		if cctx.Bool("use-bloom-set") {
+			bset, err := newBloomSet(10000000)
+			if err != nil {
+				return err
+			}
+			keepSet = bset
+		} else {
+			keepSet = newMapSet()
+		}
+
+		head := cs.GetHeaviestTipSet()
+		keepEpochs := abi.ChainEpoch(cctx.Int64("keep-epochs"))
+
+		if err := cs.WalkSnapshot(ctx, head, keepEpochs, false, func(c cid.Cid) error {
+			if keepSet.Len()%20 == 0 {
+				fmt.Printf("\renumerating keep set: %d             ", keepSet.Len())
+			}
+			keepSet.Add(c)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("snapshot walk failed: %w", err)
+		}
+		fmt.Println()
+
+		garbage := map[string]*stateGarbage{}
+		counted := cid.NewSet()
+
+		// account walks the objects reachable from root which aren't kept nor
+		// already accounted; their children are either kept or accounted too
+		var account func(root cid.Cid, kind string) error
+		account = func(root cid.Cid, kind string) error {
+			if root.Prefix().MhType == multihash.IDENTITY || keepSet.Has(root) || !counted.Visit(root) {
+				return nil
+			}
+
+			blk, err := bs.Get(root)
+			if err == blockstore.ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return xerrors.Errorf("getting %s: %w", root, err)
+			}
+
+			g, ok := garbage[kind]
+			if !ok {
+				g = &stateGarbage{}
+				garbage[kind] = g
+			}
+			g.Objects++
+			g.Bytes += int64(len(blk.RawData()))
+
+			if root.Prefix().Codec != cid.DagCBOR {
+				return nil
+			}
+
+			var links []cid.Cid
+			if err := cbg.ScanForLinks(bytes.NewReader(blk.RawData()), func(c cid.Cid) {
+				links = append(links, c)
+			}); err != nil {
+				return xerrors.Errorf("scanning %s for links: %w", root, err)
+			}
+			for _, c := range links {
+				if err := account(c, kind); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		var states, missing int
+		for ts := head; ts.Height() > 0; {
+			if ts.Height() <= head.Height()-keepEpochs {
+				root := ts.ParentState()
+				st, err := state.LoadStateTree(cs.Store(ctx), root)
+				if err != nil {
+					// states older than an imported snapshot aren't stored
+					missing++
+				} else {
+					err := st.ForEach(func(_ address.Address, act *types.Actor) error {
+						return account(act.Head, builtin.ActorNameByCode(act.Code))
+					})
+					if err != nil {
+						return xerrors.Errorf("walking the actors of state %s at %d: %w", root, ts.Height(), err)
+					}
+
+					if err := account(root, "state tree"); err != nil {
+						return err
+					}
+					states++
+				}
+
+				if states%20 == 0 {
+					fmt.Printf("\rwalking old states: %d at height %d             ", states, ts.Height())
+				}
+			}
+
+			ts, err = cs.LoadTipSet(ts.Parents())
+			if err != nil {
+				return xerrors.Errorf("loading parent tipset: %w", err)
+			}
+		}
+		fmt.Println()
+
+		kinds := make([]string, 0, len(garbage))
+		for k := range garbage {
+			kinds = append(kinds, k)
+		}
+		sort.Slice(kinds, func(i, j int) bool {
+			return garbage[kinds[i]].Bytes > garbage[kinds[j]].Bytes
+		})
+
+		fmt.Printf("Kept %d objects; walked %d old states", keepSet.Len(), states)
+		if missing > 0 {
+			fmt.Printf(", %d older states aren't stored", missing)
+		}
+		fmt.Println()
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Actor\tObjects\tSize\n")
+		var total stateGarbage
+		for _, k := range kinds {
+			g := garbage[k]
+			total.Objects += g.Objects
+			total.Bytes += g.Bytes
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", k, g.Objects, types.SizeStr(types.NewInt(uint64(g.Bytes))))
+		}
+		_, _ = fmt.Fprintf(tw, "Total\t%d\t%s\n", total.Objects, types.SizeStr(types.NewInt(uint64(total.Bytes))))
+		return tw.Flush()
+	},
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/docker/go-units"
+
 	"github.com/filecoin-project/lotus/api/apibstore"
 	"github.com/filecoin-project/lotus/chain/actors/adt"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
@@ -25,10 +28,13 @@ import (
 
 	"github.com/filecoin-project/specs-actors/actors/builtin"
 	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	power0 "github.com/filecoin-project/specs-actors/actors/builtin/power"
 
+	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/types"
 	lcli "github.com/filecoin-project/lotus/cli"
+	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 	"github.com/filecoin-project/lotus/storage"
 )
@@ -37,11 +43,15 @@ var actorCmd = &cli.Command{
 	Name:  "actor",
 	Usage: "manipulate the miner actor",
 	Subcommands: []*cli.Command{
+		actorCreateCmd,
 		actorSetAddrsCmd,
 		actorWithdrawCmd,
 		actorRepayDebtCmd,
 		actorSetPeeridCmd,
 		actorSetOwnerCmd,
+		actorChangeWorkerCmd,
+		actorProposeChangeWorker,
+		actorConfirmChangeWorker,
 		actorControl,
 	},
 }
@@ -55,6 +65,7 @@ var actorSetAddrsCmd = &cli.Command{
 			Usage: "set gas limit",
 			Value: 0,
 		},
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		nodeAPI, closer, err := lcli.GetStorageMinerAPI(cctx)
@@ -105,14 +116,19 @@ var actorSetAddrsCmd = &cli.Command{
 
 		gasLimit := cctx.Int64("gas-limit")
 
-		smsg, err := api.MpoolPushMessage(ctx, &types.Message{
+		msg := &types.Message{
 			To:       maddr,
 			From:     minfo.Worker,
 			Value:    types.NewInt(0),
 			GasLimit: gasLimit,
 			Method:   builtin.MethodsMiner.ChangeMultiaddrs,
 			Params:   params,
-		}, nil)
+		}
+		if send, err := confirmCosts(ctx, cctx, api, false, messages(msg)); err != nil || !send {
+			return err
+		}
+
+		smsg, err := api.MpoolPushMessage(ctx, msg, nil)
 		if err != nil {
 			return err
 		}
@@ -132,6 +148,7 @@ var actorSetPeeridCmd = &cli.Command{
 			Usage: "set gas limit",
 			Value: 0,
 		},
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		nodeAPI, closer, err := lcli.GetStorageMinerAPI(cctx)
@@ -170,14 +187,19 @@ var actorSetPeeridCmd = &cli.Command{
 
 		gasLimit := cctx.Int64("gas-limit")
 
-		smsg, err := api.MpoolPushMessage(ctx, &types.Message{
+		msg := &types.Message{
 			To:       maddr,
 			From:     minfo.Worker,
 			Value:    types.NewInt(0),
 			GasLimit: gasLimit,
 			Method:   builtin.MethodsMiner.ChangePeerID,
 			Params:   params,
-		}, nil)
+		}
+		if send, err := confirmCosts(ctx, cctx, api, false, messages(msg)); err != nil || !send {
+			return err
+		}
+
+		smsg, err := api.MpoolPushMessage(ctx, msg, nil)
 		if err != nil {
 			return err
 		}
@@ -192,6 +214,13 @@ var actorWithdrawCmd = &cli.Command{
 	Name:      "withdraw",
 	Usage:     "withdraw available balance",
 	ArgsUsage: "[amount (FIL)]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "signer proposing or approving the withdrawal, if the owner is a multisig",
+		},
+		dryRunFlag,
+	},
 	Action: func(cctx *cli.Context) error {
 		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
 		if err != nil {
@@ -212,11 +241,6 @@ var actorWithdrawCmd = &cli.Command{
 			return err
 		}
 
-		mi, err := api.StateMinerInfo(ctx, maddr, types.EmptyTSK)
-		if err != nil {
-			return err
-		}
-
 		available, err := api.StateMinerAvailableBalance(ctx, maddr, types.EmptyTSK)
 		if err != nil {
 			return err
@@ -236,25 +260,37 @@ var actorWithdrawCmd = &cli.Command{
 			}
 		}
 
-		params, err := actors.SerializeParams(&miner0.WithdrawBalanceParams{
-			AmountRequested: amount, // Default to attempting to withdraw all the extra funds in the miner actor
-		})
+		from, err := ownerSigner(cctx)
 		if err != nil {
 			return err
 		}
 
-		smsg, err := api.MpoolPushMessage(ctx, &types.Message{
-			To:     maddr,
-			From:   mi.Owner,
-			Value:  types.NewInt(0),
-			Method: builtin.MethodsMiner.WithdrawBalance,
-			Params: params,
-		}, nil)
+		send, err := confirmCosts(ctx, cctx, api, false, func() ([]*types.Message, error) {
+			mi, err := api.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+			if err != nil {
+				return nil, err
+			}
+			params, err := actors.SerializeParams(&miner0.WithdrawBalanceParams{AmountRequested: amount})
+			if err != nil {
+				return nil, err
+			}
+			msg, err := ownerMessage(ctx, api, maddr, mi, from, builtin.MethodsMiner.WithdrawBalance, params)
+			if err != nil {
+				return nil, err
+			}
+			return []*types.Message{msg}, nil
+		})
+		if err != nil || !send {
+			return err
+		}
+
+		// Default to attempting to withdraw all the extra funds in the miner actor
+		res, err := api.MinerWithdrawBalance(ctx, maddr, amount, from)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Requested rewards withdrawal in message %s\n", smsg.Cid())
+		printOwnerMessage("Requested rewards withdrawal", res)
 
 		return nil
 	},
@@ -269,6 +305,7 @@ var actorRepayDebtCmd = &cli.Command{
 			Name:  "from",
 			Usage: "optionally specify the account to send funds from",
 		},
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
@@ -342,13 +379,18 @@ var actorRepayDebtCmd = &cli.Command{
 			return xerrors.Errorf("sender isn't a controller of miner: %s", fromId)
 		}
 
-		smsg, err := api.MpoolPushMessage(ctx, &types.Message{
+		msg := &types.Message{
 			To:     maddr,
 			From:   fromId,
 			Value:  amount,
 			Method: builtin2.MethodsMiner.RepayDebt,
 			Params: nil,
-		}, nil)
+		}
+		if send, err := confirmCosts(ctx, cctx, api, false, messages(msg)); err != nil || !send {
+			return err
+		}
+
+		smsg, err := api.MpoolPushMessage(ctx, msg, nil)
 		if err != nil {
 			return err
 		}
@@ -480,11 +522,16 @@ var actorControlSet = &cli.Command{
 	Usage:     "Set control address(-es)",
 	ArgsUsage: "[...address]",
 	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "signer proposing or approving the change, if the owner is a multisig",
+		},
 		&cli.BoolFlag{
 			Name:  "really-do-it",
 			Usage: "Actually send transaction performing the action",
 			Value: false,
 		},
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
@@ -555,39 +602,447 @@ var actorControlSet = &cli.Command{
 			}
 		}
 
-		if !cctx.Bool("really-do-it") {
-			fmt.Println("Pass --really-do-it to actually execute this action")
+		from, err := ownerSigner(cctx)
+		if err != nil {
+			return err
+		}
+
+		send, err := confirmCosts(ctx, cctx, api, true, func() ([]*types.Message, error) {
+			controls := make([]address.Address, 0, len(toSet))
+			for _, a := range toSet {
+				id, err := api.StateLookupID(ctx, a, types.EmptyTSK)
+				if err != nil {
+					return nil, err
+				}
+				controls = append(controls, id)
+			}
+			params, aerr := actors.SerializeParams(&miner0.ChangeWorkerAddressParams{
+				NewWorker:       mi.Worker,
+				NewControlAddrs: controls,
+			})
+			if aerr != nil {
+				return nil, aerr
+			}
+			msg, err := ownerMessage(ctx, api, maddr, mi, from, builtin.MethodsMiner.ChangeWorkerAddress, params)
+			if err != nil {
+				return nil, err
+			}
+			return []*types.Message{msg}, nil
+		})
+		if err != nil || !send {
+			return err
+		}
+
+		res, err := api.MinerSetControlAddresses(ctx, maddr, toSet, from)
+		if err != nil {
+			return err
+		}
+
+		printOwnerMessage("Requested control address change", res)
+
+		return nil
+	},
+}
+
+var actorChangeWorkerCmd = &cli.Command{
+	Name:      "change-worker",
+	Usage:     "Change the worker key of the miner",
+	ArgsUsage: "[address]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "signer proposing or approving the change, if the owner is a multisig",
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transaction performing the action",
+			Value: false,
+		},
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return lcli.ShowHelp(cctx, xerrors.Errorf("must pass the new worker address"))
+		}
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		api, acloser, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer acloser()
+
+		ctx := lcli.ReqContext(cctx)
+
+		newWorker, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing address: %w", err)
+		}
+
+		maddr, err := nodeApi.ActorAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		mi, err := api.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Change worker of %s from %s to %s\n", maddr, mi.Worker, newWorker)
+
+		from, err := ownerSigner(cctx)
+		if err != nil {
+			return err
+		}
+
+		send, err := confirmCosts(ctx, cctx, api, true, func() ([]*types.Message, error) {
+			worker, err := api.StateLookupID(ctx, newWorker, types.EmptyTSK)
+			if err != nil {
+				return nil, xerrors.Errorf("looking up new worker %s: %w", newWorker, err)
+			}
+			params, err := actors.SerializeParams(&miner0.ChangeWorkerAddressParams{
+				NewWorker:       worker,
+				NewControlAddrs: mi.ControlAddresses,
+			})
+			if err != nil {
+				return nil, err
+			}
+			msg, err := ownerMessage(ctx, api, maddr, mi, from, builtin.MethodsMiner.ChangeWorkerAddress, params)
+			if err != nil {
+				return nil, err
+			}
+			return []*types.Message{msg}, nil
+		})
+		if err != nil || !send {
+			return err
+		}
+
+		res, err := api.MinerChangeWorker(ctx, maddr, newWorker, from)
+		if err != nil {
+			return err
+		}
+
+		printOwnerMessage("Requested worker change", res)
+
+		return nil
+	},
+}
+
+var actorProposeChangeWorker = &cli.Command{
+	Name:      "propose-change-worker",
+	Usage:     "Propose a worker key change, and schedule its confirmation once it is effective",
+	ArgsUsage: "[address]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "signer proposing or approving the change, if the owner is a multisig",
+		},
+		&cli.BoolFlag{
+			Name:  "no-schedule",
+			Usage: "don't schedule the confirmation, it has to be sent with confirm-change-worker",
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transaction performing the action",
+			Value: false,
+		},
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return lcli.ShowHelp(cctx, xerrors.Errorf("must pass the new worker address"))
+		}
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		api, acloser, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer acloser()
+
+		ctx := lcli.ReqContext(cctx)
+
+		newWorker, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing address: %w", err)
+		}
+
+		maddr, err := nodeApi.ActorAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		mi, err := api.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+		if err != nil {
+			return err
+		}
+
+		if mi.NewWorker != address.Undef {
+			return xerrors.Errorf("a worker key change to %s is already pending, effective at epoch %d; confirm it with confirm-change-worker first", mi.NewWorker, mi.WorkerChangeEpoch)
+		}
+
+		workerID, err := api.StateLookupID(ctx, newWorker, types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("looking up new worker %s, it must exist on chain: %w", newWorker, err)
+		}
+		if workerID == mi.Worker {
+			return xerrors.Errorf("%s already is the worker of %s", newWorker, maddr)
+		}
+
+		// the worker signs the blocks and proofs of the miner, don't switch to a
+		// key the node can't sign with
+		workerKey, err := api.StateAccountKey(ctx, workerID, types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("getting the key of the new worker: %w", err)
+		}
+		has, err := api.WalletHas(ctx, workerKey)
+		if err != nil {
+			return xerrors.Errorf("checking the wallet for %s: %w", workerKey, err)
+		}
+		if !has {
+			return xerrors.Errorf("the key of the new worker %s isn't in the wallet of the node; import it first", workerKey)
+		}
+
+		fmt.Printf("Propose changing the worker of %s from %s to %s\n", maddr, mi.Worker, workerKey)
+
+		from, err := ownerSigner(cctx)
+		if err != nil {
+			return err
+		}
+
+		send, err := confirmCosts(ctx, cctx, api, true, func() ([]*types.Message, error) {
+			params, aerr := actors.SerializeParams(&miner0.ChangeWorkerAddressParams{
+				NewWorker:       workerID,
+				NewControlAddrs: mi.ControlAddresses,
+			})
+			if aerr != nil {
+				return nil, aerr
+			}
+			msg, err := ownerMessage(ctx, api, maddr, mi, from, builtin.MethodsMiner.ChangeWorkerAddress, params)
+			if err != nil {
+				return nil, err
+			}
+			return []*types.Message{msg}, nil
+		})
+		if err != nil || !send {
+			return err
+		}
+
+		res, err := api.MinerChangeWorker(ctx, maddr, newWorker, from)
+		if err != nil {
+			return err
+		}
+
+		printOwnerMessage("Proposed worker change", res)
+
+		wait, err := api.StateWaitMsg(ctx, res.Message, build.MessageConfidence)
+		if err != nil {
+			return xerrors.Errorf("waiting for message %s: %w", res.Message, err)
+		}
+		if wait.Receipt.ExitCode != 0 {
+			return xerrors.Errorf("worker change message %s failed with exit code %d", res.Message, wait.Receipt.ExitCode)
+		}
+
+		mi, err = api.StateMinerInfo(ctx, maddr, wait.TipSet)
+		if err != nil {
+			return err
+		}
+		if mi.NewWorker != workerID {
+			fmt.Println("The worker change isn't pending yet; once the owner multisig approved it, run confirm-change-worker after it is effective")
+			return nil
+		}
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("The new worker becomes effective at epoch %s\n", lcli.EpochTime(head.Height(), mi.WorkerChangeEpoch))
+		fmt.Printf("Until then messages and proofs are still signed by the current worker %s, keep it funded\n", mi.Worker)
+
+		nv, err := api.StateNetworkVersion(ctx, types.EmptyTSK)
+		if err != nil {
+			return err
+		}
+		if actors.VersionForNetwork(nv) == actors.Version0 {
+			fmt.Println("The change is confirmed by the miner actor cron, no confirmation message is needed")
 			return nil
 		}
 
-		cwp := &miner0.ChangeWorkerAddressParams{
-			NewWorker:       mi.Worker,
-			NewControlAddrs: toSet,
+		if cctx.Bool("no-schedule") {
+			fmt.Println("Run confirm-change-worker once the change is effective")
+			return nil
 		}
 
-		sp, err := actors.SerializeParams(cwp)
+		confirm, err := ownerMessage(ctx, api, maddr, mi, from, builtin2.MethodsMiner.ConfirmUpdateWorkerKey, nil)
 		if err != nil {
-			return xerrors.Errorf("serializing params: %w", err)
+			return xerrors.Errorf("building confirmation message: %w", err)
+		}
+		id, err := api.MpoolSchedule(ctx, confirm, nil, lapi.MessageSchedule{AtEpoch: mi.WorkerChangeEpoch})
+		if err != nil {
+			return xerrors.Errorf("scheduling confirmation message: %w", err)
 		}
 
-		smsg, err := api.MpoolPushMessage(ctx, &types.Message{
-			From:   mi.Owner,
-			To:     maddr,
-			Method: builtin.MethodsMiner.ChangeWorkerAddress,
+		fmt.Printf("Scheduled the confirmation for epoch %d as message %d, see 'lotus mpool scheduled list'\n", mi.WorkerChangeEpoch, id)
+		if res.Multisig {
+			fmt.Println("The confirmation is proposed to the owner multisig, other signers have to approve it with confirm-change-worker")
+		}
 
-			Value:  big.Zero(),
-			Params: sp,
-		}, nil)
+		return nil
+	},
+}
+
+var actorConfirmChangeWorker = &cli.Command{
+	Name:      "confirm-change-worker",
+	Usage:     "Confirm a worker key change once it is effective",
+	ArgsUsage: "[address]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "signer proposing or approving the confirmation, if the owner is a multisig",
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transaction performing the action",
+			Value: false,
+		},
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return lcli.ShowHelp(cctx, xerrors.Errorf("must pass the new worker address"))
+		}
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
 		if err != nil {
-			return xerrors.Errorf("mpool push: %w", err)
+			return err
 		}
+		defer closer()
 
-		fmt.Println("Message CID:", smsg.Cid())
+		api, acloser, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer acloser()
+
+		ctx := lcli.ReqContext(cctx)
+
+		newWorker, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing address: %w", err)
+		}
+
+		maddr, err := nodeApi.ActorAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		mi, err := api.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+		if err != nil {
+			return err
+		}
+
+		if mi.NewWorker == address.Undef {
+			return xerrors.Errorf("no worker key change is pending for %s", maddr)
+		}
+		workerID, err := api.StateLookupID(ctx, newWorker, types.EmptyTSK)
+		if err != nil {
+			return xerrors.Errorf("looking up new worker %s: %w", newWorker, err)
+		}
+		if workerID != mi.NewWorker {
+			return xerrors.Errorf("the pending worker key change is to %s, not %s", mi.NewWorker, newWorker)
+		}
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+		if head.Height() < mi.WorkerChangeEpoch {
+			return xerrors.Errorf("the worker key change is effective at epoch %s", lcli.EpochTime(head.Height(), mi.WorkerChangeEpoch))
+		}
+
+		from, err := ownerSigner(cctx)
+		if err != nil {
+			return err
+		}
+
+		send, err := confirmCosts(ctx, cctx, api, true, func() ([]*types.Message, error) {
+			msg, err := ownerMessage(ctx, api, maddr, mi, from, builtin2.MethodsMiner.ConfirmUpdateWorkerKey, nil)
+			if err != nil {
+				return nil, err
+			}
+			return []*types.Message{msg}, nil
+		})
+		if err != nil || !send {
+			return err
+		}
+
+		res, err := api.MinerConfirmChangeWorker(ctx, maddr, from)
+		if err != nil {
+			return err
+		}
+
+		printOwnerMessage("Confirmed worker change", res)
+
+		wait, err := api.StateWaitMsg(ctx, res.Message, build.MessageConfidence)
+		if err != nil {
+			return xerrors.Errorf("waiting for message %s: %w", res.Message, err)
+		}
+		if wait.Receipt.ExitCode != 0 {
+			return xerrors.Errorf("confirmation message %s failed with exit code %d", res.Message, wait.Receipt.ExitCode)
+		}
+
+		mi, err = api.StateMinerInfo(ctx, maddr, wait.TipSet)
+		if err != nil {
+			return err
+		}
+		if mi.Worker == workerID {
+			fmt.Printf("The worker of %s is now %s\n", maddr, newWorker)
+		} else {
+			fmt.Println("The confirmation is pending approval of the owner multisig")
+		}
 
 		return nil
 	},
 }
 
+// ownerSigner parses the --from flag of commands sending messages as the
+// miner owner.
+func ownerSigner(cctx *cli.Context) (address.Address, error) {
+	if !cctx.IsSet("from") {
+		return address.Undef, nil
+	}
+
+	from, err := address.NewFromString(cctx.String("from"))
+	if err != nil {
+		return address.Undef, xerrors.Errorf("parsing from address: %w", err)
+	}
+	return from, nil
+}
+
+func printOwnerMessage(what string, res *lapi.MinerOwnerMessage) {
+	switch {
+	case res.Approved:
+		fmt.Printf("%s: approved pending transaction %d of owner multisig %s in message %s\n", what, res.TxID, res.Owner, res.Message)
+	case res.Multisig:
+		fmt.Printf("%s: proposed to owner multisig %s in message %s\n", what, res.Owner, res.Message)
+		fmt.Println("Other signers can approve the proposal by running the same command with their own --from address")
+	default:
+		fmt.Printf("%s in message %s\n", what, res.Message)
+	}
+}
+
 var actorSetOwnerCmd = &cli.Command{
 	Name:      "set-owner",
 	Usage:     "Set owner address",
@@ -598,13 +1053,9 @@ var actorSetOwnerCmd = &cli.Command{
 			Usage: "Actually send transaction performing the action",
 			Value: false,
 		},
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
-		if !cctx.Bool("really-do-it") {
-			fmt.Println("Pass --really-do-it to actually execute this action")
-			return nil
-		}
-
 		if !cctx.Args().Present() {
 			return fmt.Errorf("must pass address of new owner address")
 		}
@@ -648,13 +1099,26 @@ var actorSetOwnerCmd = &cli.Command{
 			return xerrors.Errorf("serializing params: %w", err)
 		}
 
-		smsg, err := api.MpoolPushMessage(ctx, &types.Message{
+		propose := &types.Message{
 			From:   mi.Owner,
 			To:     maddr,
 			Method: builtin2.MethodsMiner.ChangeOwnerAddress,
 			Value:  big.Zero(),
 			Params: sp,
-		}, nil)
+		}
+		approve := &types.Message{
+			From:   newAddr,
+			To:     maddr,
+			Method: builtin2.MethodsMiner.ChangeOwnerAddress,
+			Value:  big.Zero(),
+			Params: sp,
+		}
+		// the approval can't be estimated before the proposal executed
+		if send, err := confirmCosts(ctx, cctx, api, true, messages(propose, approve)); err != nil || !send {
+			return err
+		}
+
+		smsg, err := api.MpoolPushMessage(ctx, propose, nil)
 		if err != nil {
 			return xerrors.Errorf("mpool push: %w", err)
 		}
@@ -673,13 +1137,7 @@ var actorSetOwnerCmd = &cli.Command{
 			return err
 		}
 
-		smsg, err = api.MpoolPushMessage(ctx, &types.Message{
-			From:   newAddr,
-			To:     maddr,
-			Method: builtin2.MethodsMiner.ChangeOwnerAddress,
-			Value:  big.Zero(),
-			Params: sp,
-		}, nil)
+		smsg, err = api.MpoolPushMessage(ctx, approve, nil)
 		if err != nil {
 			return xerrors.Errorf("mpool push: %w", err)
 		}
@@ -701,3 +1159,161 @@ var actorSetOwnerCmd = &cli.Command{
 		return nil
 	},
 }
+
+var actorCreateCmd = &cli.Command{
+	Name:  "create",
+	Usage: "create a new miner actor on chain and print its ID address",
+	Description: `Submits Power.CreateMiner, waits for the receipt and prints the ID address of
+   the new miner to stdout. Progress is reported on stderr, so the output can be
+   consumed by provisioning scripts. Unlike 'lotus-miner init' this doesn't set up
+   a local miner repo.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "owner",
+			Usage: "owner address of the new miner (defaults to the wallet default address)",
+		},
+		&cli.StringFlag{
+			Name:  "worker",
+			Usage: "worker address of the new miner (defaults to the owner address)",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "address to send the CreateMiner message from (defaults to the owner address)",
+		},
+		&cli.StringFlag{
+			Name:  "sector-size",
+			Usage: "sector size of the new miner",
+			Value: units.BytesSize(float64(build.DefaultSectorSize())),
+		},
+		&cli.StringFlag{
+			Name:     "peer-id",
+			Usage:    "libp2p peer ID the miner will be reachable on",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "multiaddrs",
+			Usage: "multiaddrs the miner can be publicly dialed on",
+		},
+		&cli.Uint64Flag{
+			Name:  "confidence",
+			Usage: "number of block confirmations to wait for",
+			Value: build.MessageConfidence,
+		},
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		api, acloser, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer acloser()
+
+		ctx := lcli.ReqContext(cctx)
+
+		var owner address.Address
+		if cctx.String("owner") != "" {
+			owner, err = address.NewFromString(cctx.String("owner"))
+		} else {
+			owner, err = api.WalletDefaultAddress(ctx)
+		}
+		if err != nil {
+			return xerrors.Errorf("getting owner address: %w", err)
+		}
+
+		worker := owner
+		if cctx.String("worker") != "" {
+			worker, err = address.NewFromString(cctx.String("worker"))
+			if err != nil {
+				return xerrors.Errorf("parsing worker address: %w", err)
+			}
+		}
+
+		sender := owner
+		if cctx.String("from") != "" {
+			sender, err = address.NewFromString(cctx.String("from"))
+			if err != nil {
+				return xerrors.Errorf("parsing from address: %w", err)
+			}
+		}
+
+		ssize, err := units.RAMInBytes(cctx.String("sector-size"))
+		if err != nil {
+			return xerrors.Errorf("parsing sector size: %w", err)
+		}
+
+		spt, err := ffiwrapper.SealProofTypeFromSectorSize(abi.SectorSize(ssize))
+		if err != nil {
+			return err
+		}
+
+		pid, err := peer.Decode(cctx.String("peer-id"))
+		if err != nil {
+			return xerrors.Errorf("parsing peer ID: %w", err)
+		}
+
+		var addrs []abi.Multiaddrs
+		for _, a := range cctx.StringSlice("multiaddrs") {
+			maddr, err := ma.NewMultiaddr(a)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q as a multiaddr: %w", a, err)
+			}
+
+			maddrNop2p, strip := ma.SplitFunc(maddr, func(c ma.Component) bool {
+				return c.Protocol().Code == ma.P_P2P
+			})
+
+			if strip != nil {
+				fmt.Fprintln(os.Stderr, "Stripping peerid ", strip, " from ", maddr)
+			}
+			addrs = append(addrs, maddrNop2p.Bytes())
+		}
+
+		params, err := actors.SerializeParams(&power0.CreateMinerParams{
+			Owner:         owner,
+			Worker:        worker,
+			SealProofType: spt,
+			Peer:          abi.PeerID(pid),
+			Multiaddrs:    addrs,
+		})
+		if err != nil {
+			return err
+		}
+
+		msg := &types.Message{
+			To:     builtin.StoragePowerActorAddr,
+			From:   sender,
+			Value:  big.Zero(),
+			Method: builtin.MethodsPower.CreateMiner,
+			Params: params,
+		}
+		if send, err := confirmCosts(ctx, cctx, api, false, messages(msg)); err != nil || !send {
+			return err
+		}
+
+		smsg, err := api.MpoolPushMessage(ctx, msg, nil)
+		if err != nil {
+			return xerrors.Errorf("pushing CreateMiner message: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Pushed CreateMiner message %s, waiting for %d confirmations\n", smsg.Cid(), cctx.Uint64("confidence"))
+
+		mw, err := api.StateWaitMsg(ctx, smsg.Cid(), cctx.Uint64("confidence"))
+		if err != nil {
+			return xerrors.Errorf("waiting for CreateMiner message: %w", err)
+		}
+
+		if mw.Receipt.ExitCode != 0 {
+			return xerrors.Errorf("create miner failed: exit code %d", mw.Receipt.ExitCode)
+		}
+
+		var retval power0.CreateMinerReturn
+		if err := retval.UnmarshalCBOR(bytes.NewReader(mw.Receipt.Return)); err != nil {
+			return xerrors.Errorf("decoding CreateMiner return: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Created new miner %s (%s)\n", retval.IDAddress, retval.RobustAddress)
+		fmt.Println(retval.IDAddress)
+
+		return nil
+	},
+}
@@ -1,16 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 
+	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/lotus/node/config"
 )
 
 var configCmd = &cli.Command{
 	Name:  "config",
 	Usage: "Output default configuration",
+	Subcommands: []*cli.Command{
+		configGetCmd,
+		configSetCmd,
+	},
 	Action: func(cctx *cli.Context) error {
 		comm, err := config.ConfigComment(config.DefaultStorageMiner())
 		if err != nil {
@@ -20,3 +30,80 @@ var configCmd = &cli.Command{
 		return nil
 	},
 }
+
+var configGetCmd = &cli.Command{
+	Name:      "get",
+	Usage:     "Print a section of the config of the running miner as JSON",
+	ArgsUsage: "[section, e.g. Dealmaking or Sealing.MaxSealingSectors]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() > 1 {
+			return xerrors.Errorf("expected at most one section")
+		}
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		raw, err := nodeApi.ConfigGet(ctx, cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, raw, "", "  "); err != nil {
+			return err
+		}
+		fmt.Println(out.String())
+		return nil
+	},
+}
+
+var configSetCmd = &cli.Command{
+	Name:      "set",
+	Usage:     "Set a section of the config of the running miner from JSON",
+	ArgsUsage: "[section] [json value, or - to read it from stdin]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.Errorf("expected a section and a value")
+		}
+
+		value := []byte(cctx.Args().Get(1))
+		if cctx.Args().Get(1) == "-" {
+			b, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return xerrors.Errorf("reading value: %w", err)
+			}
+			value = b
+		}
+		if !json.Valid(value) {
+			return xerrors.Errorf("value isn't valid JSON")
+		}
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		res, err := nodeApi.ConfigSet(ctx, cctx.Args().First(), value)
+		if err != nil {
+			return err
+		}
+
+		if len(res.Changed) == 0 {
+			fmt.Println("Nothing changed")
+			return nil
+		}
+		for _, s := range res.Changed {
+			fmt.Println("Changed", s)
+		}
+		if res.RestartRequired {
+			fmt.Println("Some of the changes only apply after restarting the miner")
+		}
+		return nil
+	},
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/multisig"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+)
+
+var dryRunFlag = &cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "print the estimated gas and cost of the messages at the current base fee without sending them",
+}
+
+// confirmCosts prints the estimated costs of the messages a command would
+// send if --dry-run is set, or if the command needs --really-do-it and it
+// isn't set. It returns whether the messages should be sent; the messages
+// are only built when their costs are printed.
+func confirmCosts(ctx context.Context, cctx *cli.Context, api lapi.FullNode, needsConfirm bool, build func() ([]*types.Message, error)) (bool, error) {
+	dryRun := cctx.Bool(dryRunFlag.Name)
+	unconfirmed := needsConfirm && !cctx.Bool("really-do-it")
+	if !dryRun && !unconfirmed {
+		return true, nil
+	}
+
+	msgs, err := build()
+	if err != nil {
+		return false, xerrors.Errorf("building messages to estimate: %w", err)
+	}
+	if err := printMessageCosts(ctx, api, msgs); err != nil {
+		return false, err
+	}
+
+	if !dryRun {
+		fmt.Println("Pass --really-do-it to actually execute this action")
+	}
+	return false, nil
+}
+
+// printMessageCosts estimates the gas of the messages and prints what they
+// would cost at the current base fee. Messages depending on the execution of
+// the ones before them may fail to estimate.
+func printMessageCosts(ctx context.Context, api lapi.FullNode, msgs []*types.Message) error {
+	head, err := api.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+	baseFee := head.MinTicketBlock().ParentBaseFee
+
+	tw := tablewriter.New(
+		tablewriter.Col("#"),
+		tablewriter.Col("From"),
+		tablewriter.Col("To"),
+		tablewriter.Col("Method"),
+		tablewriter.Col("Value"),
+		tablewriter.Col("GasLimit"),
+		tablewriter.Col("Fee"),
+		tablewriter.Col("MaxFee"),
+		tablewriter.Col("Total"),
+		tablewriter.NewLineCol("Error"),
+	)
+
+	totalFee, totalMax, total := big.Zero(), big.Zero(), big.Zero()
+	var failed int
+	for i, m := range msgs {
+		row := map[string]interface{}{
+			"#":      i,
+			"From":   m.From,
+			"To":     m.To,
+			"Method": m.Method,
+			"Value":  types.FIL(m.Value),
+		}
+
+		em, err := api.GasEstimateMessageGas(ctx, m, nil, head.Key())
+		if err != nil {
+			failed++
+			row["Error"] = err.Error()
+			tw.Write(row)
+			continue
+		}
+
+		fee, maxFee := gasFees(em, baseFee)
+		cost := big.Add(m.Value, fee)
+		totalFee = big.Add(totalFee, fee)
+		totalMax = big.Add(totalMax, maxFee)
+		total = big.Add(total, cost)
+
+		row["GasLimit"] = em.GasLimit
+		row["Fee"] = types.FIL(fee)
+		row["MaxFee"] = types.FIL(maxFee)
+		row["Total"] = types.FIL(cost)
+		tw.Write(row)
+	}
+
+	fmt.Printf("Estimated at a base fee of %s attoFIL/gas:\n", baseFee)
+	if err := tw.Flush(os.Stdout); err != nil {
+		return err
+	}
+
+	fmt.Printf("Total fees: %s (at most %s)\n", types.FIL(totalFee), types.FIL(totalMax))
+	fmt.Printf("Total cost: %s\n", types.FIL(total))
+	if failed > 0 {
+		fmt.Printf("%d of %d messages couldn't be estimated and aren't included in the totals\n", failed, len(msgs))
+	}
+	return nil
+}
+
+// messages returns a builder of cost estimates for already built messages.
+func messages(msgs ...*types.Message) func() ([]*types.Message, error) {
+	return func() ([]*types.Message, error) {
+		return msgs, nil
+	}
+}
+
+// gasFees returns the fee of the message if its whole gas limit is paid at
+// the base fee, and the most it can pay at its fee cap.
+func gasFees(m *types.Message, baseFee abi.TokenAmount) (abi.TokenAmount, abi.TokenAmount) {
+	limit := big.NewInt(m.GasLimit)
+
+	price := big.Add(baseFee, m.GasPremium)
+	if price.GreaterThan(m.GasFeeCap) {
+		price = m.GasFeeCap
+	}
+	return big.Mul(price, limit), big.Mul(m.GasFeeCap, limit)
+}
+
+// ownerMessage returns the message which sending a miner admin message as the
+// owner sends, like the Miner* owner methods of the full node API: the
+// message itself if the owner is an account, or a proposal of it to the
+// owner multisig from 'from'. Approving an identical pending proposal costs
+// about as much as proposing it.
+func ownerMessage(ctx context.Context, api lapi.FullNode, maddr address.Address, mi miner.MinerInfo, from address.Address, method abi.MethodNum, params []byte) (*types.Message, error) {
+	owner, err := api.StateGetActor(ctx, mi.Owner, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("loading owner actor: %w", err)
+	}
+
+	if !builtin.IsMultisigActor(owner.Code) {
+		return &types.Message{
+			From:   mi.Owner,
+			To:     maddr,
+			Method: method,
+			Value:  big.Zero(),
+			Params: params,
+		}, nil
+	}
+
+	if from == address.Undef {
+		return nil, xerrors.Errorf("the owner of the miner is multisig %s, a signer must be given with --from", mi.Owner)
+	}
+
+	nv, err := api.StateNetworkVersion(ctx, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	return multisig.Message(actors.VersionForNetwork(nv), from).Propose(mi.Owner, maddr, big.Zero(), method, params)
+}
@@ -262,6 +262,7 @@ var stateList = []stateMeta{
 	{col: color.FgRed, state: sealing.RemoveFailed},
 	{col: color.FgRed, state: sealing.DealsExpired},
 	{col: color.FgRed, state: sealing.RecoverDealIDs},
+	{col: color.FgRed, state: sealing.PreCommitExpired},
 }
 
 func init() {
@@ -274,7 +275,7 @@ func init() {
 }
 
 func sectorsInfo(ctx context.Context, napi api.StorageMiner) error {
-	sectors, err := napi.SectorsList(ctx)
+	sectors, err := napi.SectorsListWithDetails(ctx, api.SectorsFilter{})
 	if err != nil {
 		return err
 	}
@@ -282,12 +283,7 @@ func sectorsInfo(ctx context.Context, napi api.StorageMiner) error {
 	buckets := map[sealing.SectorState]int{
 		"Total": len(sectors),
 	}
-	for _, s := range sectors {
-		st, err := napi.SectorsStatus(ctx, s, false)
-		if err != nil {
-			return err
-		}
-
+	for _, st := range sectors {
 		buckets[sealing.SectorState(st.State)]++
 	}
 
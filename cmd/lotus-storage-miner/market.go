@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -22,6 +23,7 @@ import (
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-state-types/abi"
 
+	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/types"
 	lcli "github.com/filecoin-project/lotus/cli"
@@ -307,6 +309,8 @@ var storageDealsCmd = &cli.Command{
 		getBlocklistCmd,
 		resetBlocklistCmd,
 		setSealDurationCmd,
+		dealsSLAReportCmd,
+		dealsAuthorizationsCmd,
 	},
 }
 
@@ -445,6 +449,137 @@ func outputStorageDeals(out io.Writer, deals []storagemarket.MinerDeal, verbose
 	return w.Flush()
 }
 
+var dealsSLAReportCmd = &cli.Command{
+	Name:  "sla-report",
+	Usage: "Print the service level reports of the deals of the miner",
+	Description: `Each row reports the deals over a period, the first one being the current
+   period. Durations are given as median / 90th percentile:
+   - Ingest: time taken by the data transfers of online storage deals
+   - Activation: time from a storage deal proposal being received to the deal being active
+   - Margin: median of the epochs left before the start epoch of the deals at their activation
+   - Response: time for the miner to respond to a retrieval deal proposal
+   - Retrieval: time from a retrieval deal proposal being received to the retrieval being completed`,
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "count",
+			Usage: "number of periods to report, 0 for all of them",
+			Value: 7,
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the reports in JSON",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		smapi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		reports, err := smapi.DealsSLAReports(lcli.DaemonContext(cctx), cctx.Int("count"))
+		if err != nil {
+			return err
+		}
+
+		if cctx.Bool("json") {
+			b, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		stats := func(s lapi.DurationStats) string {
+			if s.Count == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%s / %s (%d)", s.Median.Round(time.Second), s.P90.Round(time.Second), s.Count)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "Period\tIngest\tActivation\tMargin\tLate\tFailed\tResponse\tRetrieval\tFailed\n")
+		for _, r := range reports {
+			period := r.Start.Format(time.Stamp)
+			if r.InProgress {
+				period += " (current)"
+			}
+			margin := "-"
+			if r.ActivationMargin.Count > 0 {
+				margin = fmt.Sprint(r.ActivationMargin.Median)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\t%d\n", period,
+				stats(r.Ingest), stats(r.Activation), margin, r.DealsActivatedLate, r.DealsFailed,
+				stats(r.RetrievalResponse), stats(r.RetrievalCompletion), r.RetrievalsFailed)
+		}
+		return w.Flush()
+	},
+}
+
+var dealsAuthorizationsCmd = &cli.Command{
+	Name:  "authorizations",
+	Usage: "Manage the authorizations of delegates to make deals on behalf of the AuthorizedFunders",
+	Subcommands: []*cli.Command{
+		dealsAuthorizationsImportCmd,
+		dealsAuthorizationsListCmd,
+	},
+}
+
+var dealsAuthorizationsImportCmd = &cli.Command{
+	Name:      "import",
+	Usage:     "Import an authorization made with 'lotus client authorize-delegate'",
+	ArgsUsage: "[<path-of-file-containing-the-authorization> (optional, will read from stdin if omitted)]",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		var r io.Reader = os.Stdin
+		if cctx.Args().Present() && cctx.Args().First() != "-" {
+			f, err := os.Open(cctx.Args().First())
+			if err != nil {
+				return err
+			}
+			defer f.Close() //nolint:errcheck
+			r = f
+		}
+
+		var auth lapi.SignedDealAuthorization
+		if err := json.NewDecoder(r).Decode(&auth); err != nil {
+			return xerrors.Errorf("decoding authorization: %w", err)
+		}
+
+		return api.DealsImportAuthorization(lcli.DaemonContext(cctx), auth)
+	},
+}
+
+var dealsAuthorizationsListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List the authorizations imported",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		auths, err := api.DealsListAuthorizations(lcli.DaemonContext(cctx))
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "Delegate\tFunder\tExpiration\n")
+		for _, a := range auths {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\n", a.Delegate, a.Funder, a.Expiration)
+		}
+		return w.Flush()
+	},
+}
+
 var getBlocklistCmd = &cli.Command{
 	Name:  "get-blocklist",
 	Usage: "List the contents of the miner's piece CID blocklist",
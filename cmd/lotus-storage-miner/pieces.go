@@ -8,6 +8,7 @@ import (
 	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/ipfs/go-cid"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 )
 
 var piecesCmd = &cli.Command{
@@ -19,6 +20,7 @@ var piecesCmd = &cli.Command{
 		piecesListCidInfosCmd,
 		piecesInfoCmd,
 		piecesCidInfoCmd,
+		piecesVerifyCmd,
 	},
 }
 
@@ -138,3 +140,53 @@ var piecesCidInfoCmd = &cli.Command{
 		return w.Flush()
 	},
 }
+
+var piecesVerifyCmd = &cli.Command{
+	Name:      "verify",
+	Usage:     "recompute the commitment of every stored copy of a piece",
+	ArgsUsage: "[pieceCid]",
+	Action: func(cctx *cli.Context) error {
+		if !cctx.Args().Present() {
+			return lcli.ShowHelp(cctx, fmt.Errorf("must specify piece cid"))
+		}
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		c, err := cid.Decode(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		pv, err := nodeApi.MarketVerifyPiece(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Piece: ", pv.PieceCID)
+		w := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "DealID\tSectorID\tLength\tOffset\tStatus")
+		for _, cp := range pv.Copies {
+			status := "ok"
+			switch {
+			case cp.Error != "":
+				status = "error: " + cp.Error
+			case !cp.Match:
+				status = "MISMATCH: " + cp.CommP.String()
+			}
+			fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\n", cp.DealID, cp.SectorID, cp.Length, cp.Offset, status)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if !pv.Valid {
+			return xerrors.Errorf("piece %s failed verification", pv.PieceCID)
+		}
+		return nil
+	},
+}
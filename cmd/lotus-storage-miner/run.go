@@ -18,9 +18,13 @@ import (
 	"github.com/filecoin-project/go-jsonrpc/auth"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apiconns"
+	"github.com/filecoin-project/lotus/api/apimetrics"
 	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/api/apiusage"
 	"github.com/filecoin-project/lotus/build"
 	lcli "github.com/filecoin-project/lotus/cli"
+	"github.com/filecoin-project/lotus/lib/reqid"
 	"github.com/filecoin-project/lotus/lib/ulimit"
 	"github.com/filecoin-project/lotus/node"
 	"github.com/filecoin-project/lotus/node/impl"
@@ -50,6 +54,24 @@ var runCmd = &cli.Command{
 			Usage: "manage open file limit",
 			Value: true,
 		},
+		&cli.DurationFlag{
+			Name:  "api-slow-call",
+			Usage: "log the API calls taking longer than this, with their method and token, 0 to disable",
+		},
+		&cli.IntFlag{
+			Name:  "api-max-subscriptions",
+			Usage: "the number of subscriptions, like ChainNotify, a websocket connection to the API can have at once, 0 for no limit",
+		},
+		&cli.IntFlag{
+			Name:  "api-subscription-buffer",
+			Usage: "the number of values buffered for each subscription while its consumer is slow",
+			Value: apiconns.DefaultLimits.Buffer,
+		},
+		&cli.StringFlag{
+			Name:  "api-subscription-overflow",
+			Usage: "what to do when the buffer of a subscription is full: 'disconnect' closes the connection, 'drop-oldest' drops the oldest value",
+			Value: string(apiconns.DefaultLimits.Overflow),
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		if !cctx.Bool("enable-gpu-proving") {
@@ -146,8 +168,23 @@ var runCmd = &cli.Command{
 
 		mux := mux.NewRouter()
 
+		usage := minerapi.(*impl.StorageMinerAPI).Usage
+
+		overflow, err := apiconns.ParsePolicy(cctx.String("api-subscription-overflow"))
+		if err != nil {
+			return err
+		}
+		conns := minerapi.(*impl.StorageMinerAPI).Conns
+		conns.SetLimits(apiconns.Limits{
+			MaxSubscriptions: cctx.Int("api-max-subscriptions"),
+			Buffer:           cctx.Int("api-subscription-buffer"),
+			Overflow:         overflow,
+		})
+
 		rpcServer := jsonrpc.NewServer()
-		rpcServer.Register("Filecoin", apistruct.PermissionedStorMinerAPI(minerapi))
+		rpcAPI := apiconns.LimitStorageMinerAPI(apiusage.TrackStorageMinerAPI(minerapi, usage), conns)
+		rpcAPI = apimetrics.MetricedStorageMinerAPI(rpcAPI, cctx.Duration("api-slow-call"))
+		rpcServer.Register("Filecoin", apistruct.PermissionedStorMinerAPI(rpcAPI))
 
 		mux.Handle("/rpc/v0", rpcServer)
 		mux.PathPrefix("/remote").HandlerFunc(minerapi.(*impl.StorageMinerAPI).ServeRemote)
@@ -158,7 +195,7 @@ var runCmd = &cli.Command{
 			Next:   mux.ServeHTTP,
 		}
 
-		srv := &http.Server{Handler: ah}
+		srv := &http.Server{Handler: reqid.Handler(usage.Handler(minerapi.AuthVerify, conns.Handler(ah)))}
 
 		sigChan := make(chan os.Signal, 2)
 		go func() {
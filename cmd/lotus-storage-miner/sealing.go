@@ -14,6 +14,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
 	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 
 	"github.com/filecoin-project/lotus/chain/types"
@@ -27,6 +28,8 @@ var sealingCmd = &cli.Command{
 		sealingJobsCmd,
 		sealingWorkersCmd,
 		sealingSchedDiagCmd,
+		sealingPauseCmd,
+		sealingResumeCmd,
 	},
 }
 
@@ -215,3 +218,90 @@ var sealingSchedDiagCmd = &cli.Command{
 		return nil
 	},
 }
+
+var sealingPauseCmd = &cli.Command{
+	Name:      "pause",
+	Usage:     "Stop assigning new sealing tasks to workers",
+	ArgsUsage: "[task types (e.g. PC1, seal/v0/commit/2), all if none are given]",
+	Description: `Tasks already running on workers are finished, new ones are queued until
+   resumed with 'lotus-miner sealing resume'. The pause doesn't survive restarts.`,
+	Action: func(cctx *cli.Context) error {
+		return setSchedPaused(cctx, true)
+	},
+}
+
+var sealingResumeCmd = &cli.Command{
+	Name:        "resume",
+	Usage:       "Resume assigning sealing tasks to workers",
+	ArgsUsage:   "[task types (e.g. PC1, seal/v0/commit/2), all if none are given]",
+	Description: `Resuming some task types doesn't lift a pause of all types.`,
+	Action: func(cctx *cli.Context) error {
+		return setSchedPaused(cctx, false)
+	},
+}
+
+var allTaskTypes = []sealtasks.TaskType{
+	sealtasks.TTAddPiece,
+	sealtasks.TTPreCommit1,
+	sealtasks.TTPreCommit2,
+	sealtasks.TTCommit1,
+	sealtasks.TTCommit2,
+	sealtasks.TTFinalize,
+	sealtasks.TTFetch,
+	sealtasks.TTUnseal,
+	sealtasks.TTReadUnsealed,
+}
+
+// parseTaskType accepts the full name of a task type or its short name.
+func parseTaskType(s string) (sealtasks.TaskType, error) {
+	for _, tt := range allTaskTypes {
+		if s == string(tt) || strings.EqualFold(s, strings.TrimSpace(tt.Short())) {
+			return tt, nil
+		}
+	}
+	return "", xerrors.Errorf("unknown task type %q", s)
+}
+
+func setSchedPaused(cctx *cli.Context, pause bool) error {
+	var tasks []sealtasks.TaskType
+	for _, arg := range cctx.Args().Slice() {
+		tt, err := parseTaskType(arg)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, tt)
+	}
+
+	nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	ctx := lcli.ReqContext(cctx)
+
+	if pause {
+		err = nodeApi.SealingSchedPause(ctx, tasks)
+	} else {
+		err = nodeApi.SealingSchedResume(ctx, tasks)
+	}
+	if err != nil {
+		return err
+	}
+
+	what := "all tasks"
+	if len(tasks) > 0 {
+		names := make([]string, len(tasks))
+		for i, tt := range tasks {
+			names[i] = string(tt)
+		}
+		what = strings.Join(names, ", ")
+	}
+
+	if pause {
+		fmt.Printf("Paused assigning %s\n", what)
+	} else {
+		fmt.Printf("Resumed assigning %s\n", what)
+	}
+	return nil
+}
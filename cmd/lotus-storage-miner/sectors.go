@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -9,16 +10,25 @@ import (
 
 	"github.com/docker/go-units"
 	"github.com/fatih/color"
+	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 
 	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apibstore"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 
@@ -40,6 +50,8 @@ var sectorsCmd = &cli.Command{
 		sectorsStartSealCmd,
 		sectorsSealDelayCmd,
 		sectorsCapacityCollateralCmd,
+		sectorsCompactPartitionsCmd,
+		sectorsExtendCmd,
 	},
 }
 
@@ -103,6 +115,12 @@ var sectorsStatusCmd = &cli.Command{
 		fmt.Printf("TicketH:\t%d\n", status.Ticket.Epoch)
 		fmt.Printf("Seed:\t\t%x\n", status.Seed.Value)
 		fmt.Printf("SeedH:\t\t%d\n", status.Seed.Epoch)
+		if status.TicketExpiration != 0 {
+			fmt.Printf("TicketExp:\t%d\n", status.TicketExpiration)
+		}
+		if status.SeedExpiration != 0 {
+			fmt.Printf("SeedExp:\t%d\n", status.SeedExpiration)
+		}
 		fmt.Printf("Precommit:\t%s\n", status.PreCommitMsg)
 		fmt.Printf("Commit:\t\t%s\n", status.CommitMsg)
 		fmt.Printf("Proof:\t\t%x\n", status.Proof)
@@ -154,7 +172,7 @@ var sectorsListCmd = &cli.Command{
 		},
 		&cli.BoolFlag{
 			Name:  "fast",
-			Usage: "don't show on-chain info for better performance",
+			Usage: "don't show the expiration and deal weight columns",
 		},
 	},
 	Action: func(cctx *cli.Context) error {
@@ -174,12 +192,7 @@ var sectorsListCmd = &cli.Command{
 
 		ctx := lcli.ReqContext(cctx)
 
-		list, err := nodeApi.SectorsList(ctx)
-		if err != nil {
-			return err
-		}
-
-		maddr, err := nodeApi.ActorAddress(ctx)
+		sectors, err := nodeApi.SectorsListWithDetails(ctx, api.SectorsFilter{})
 		if err != nil {
 			return err
 		}
@@ -189,28 +202,6 @@ var sectorsListCmd = &cli.Command{
 			return err
 		}
 
-		activeSet, err := fullApi.StateMinerActiveSectors(ctx, maddr, head.Key())
-		if err != nil {
-			return err
-		}
-		activeIDs := make(map[abi.SectorNumber]struct{}, len(activeSet))
-		for _, info := range activeSet {
-			activeIDs[info.SectorNumber] = struct{}{}
-		}
-
-		sset, err := fullApi.StateMinerSectors(ctx, maddr, nil, head.Key())
-		if err != nil {
-			return err
-		}
-		commitedIDs := make(map[abi.SectorNumber]struct{}, len(activeSet))
-		for _, info := range sset {
-			commitedIDs[info.SectorNumber] = struct{}{}
-		}
-
-		sort.Slice(list, func(i, j int) bool {
-			return list[i] < list[j]
-		})
-
 		tw := tablewriter.New(
 			tablewriter.Col("ID"),
 			tablewriter.Col("State"),
@@ -219,25 +210,12 @@ var sectorsListCmd = &cli.Command{
 			tablewriter.Col("Expiration"),
 			tablewriter.Col("Deals"),
 			tablewriter.Col("DealWeight"),
-			tablewriter.NewLineCol("Error"),
 			tablewriter.NewLineCol("EarlyExpiration"))
 
 		fast := cctx.Bool("fast")
 
-		for _, s := range list {
-			st, err := nodeApi.SectorsStatus(ctx, s, !fast)
-			if err != nil {
-				tw.Write(map[string]interface{}{
-					"ID":    s,
-					"Error": err,
-				})
-				continue
-			}
-
+		for _, st := range sectors {
 			if cctx.Bool("show-removed") || st.State != api.SectorState(sealing.Removed) {
-				_, inSSet := commitedIDs[s]
-				_, inASet := activeIDs[s]
-
 				dw := .0
 				if st.Expiration-st.Activation > 0 {
 					dw = float64(big.Div(st.DealWeight, big.NewInt(int64(st.Expiration-st.Activation))).Uint64())
@@ -256,10 +234,10 @@ var sectorsListCmd = &cli.Command{
 				}
 
 				m := map[string]interface{}{
-					"ID":      s,
+					"ID":      st.SectorID,
 					"State":   color.New(stateOrder[sealing.SectorState(st.State)].col).Sprint(st.State),
-					"OnChain": yesno(inSSet),
-					"Active":  yesno(inASet),
+					"OnChain": yesno(st.OnChain),
+					"Active":  yesno(st.Active),
 				}
 
 				if deals > 0 {
@@ -272,12 +250,12 @@ var sectorsListCmd = &cli.Command{
 				}
 
 				if !fast {
-					if !inSSet {
+					if !st.OnChain {
 						m["Expiration"] = "n/a"
 					} else {
 						m["Expiration"] = lcli.EpochTime(head.Height(), exp)
 
-						if !fast && deals > 0 {
+						if deals > 0 {
 							m["DealWeight"] = units.BytesSize(dw)
 						}
 
@@ -476,6 +454,481 @@ var sectorsCapacityCollateralCmd = &cli.Command{
 	},
 }
 
+var sectorsCompactPartitionsCmd = &cli.Command{
+	Name:  "compact-partitions",
+	Usage: "removes dead sectors from partitions and reduces the number of partitions used if possible",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:     "deadline",
+			Usage:    "the deadline to compact the partitions in",
+			Required: true,
+		},
+		&cli.Int64SliceFlag{
+			Name:  "partitions",
+			Usage: "the partitions to compact, all the ones with dead sectors and without faults by default",
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transaction performing the action",
+			Value: false,
+		},
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		mApi, mCloser, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer mCloser()
+
+		nApi, nCloser, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer nCloser()
+
+		ctx := lcli.ReqContext(cctx)
+
+		maddr, err := mApi.ActorAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		head, err := nApi.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		mact, err := nApi.StateGetActor(ctx, maddr, head.Key())
+		if err != nil {
+			return err
+		}
+		mas, err := miner.Load(adt.WrapStore(ctx, cbor.NewCborStore(apibstore.NewAPIBlockstore(nApi))), mact)
+		if err != nil {
+			return err
+		}
+
+		dlIdx := cctx.Uint64("deadline")
+		nd, err := mas.NumDeadlines()
+		if err != nil {
+			return err
+		}
+		if dlIdx >= nd {
+			return xerrors.Errorf("deadline %d out of range, the miner has %d deadlines", dlIdx, nd)
+		}
+
+		di, err := nApi.StateMinerProvingDeadline(ctx, maddr, head.Key())
+		if err != nil {
+			return err
+		}
+		if !miner.DeadlineIsMutable(di.PeriodStart, dlIdx, head.Height()) {
+			return xerrors.Errorf("deadline %d can't be compacted while it is open or about to open, retry after it closed", dlIdx)
+		}
+
+		dl, err := mas.LoadDeadline(dlIdx)
+		if err != nil {
+			return err
+		}
+		eligible, err := miner.CompactablePartitions(dl)
+		if err != nil {
+			return xerrors.Errorf("deadline %d can't be compacted: %w", dlIdx, err)
+		}
+
+		parts := eligible
+		if cctx.IsSet("partitions") {
+			isEligible := make(map[uint64]bool, len(eligible))
+			for _, p := range eligible {
+				isEligible[p] = true
+			}
+
+			parts = nil
+			for _, p := range cctx.Int64Slice("partitions") {
+				if p < 0 || !isEligible[uint64(p)] {
+					return xerrors.Errorf("partition %d has no dead sectors or has faults, it can't be compacted", p)
+				}
+				parts = append(parts, uint64(p))
+			}
+		}
+		if len(parts) == 0 {
+			fmt.Printf("Deadline %d has no partitions to compact\n", dlIdx)
+			return nil
+		}
+
+		mi, err := nApi.StateMinerInfo(ctx, maddr, head.Key())
+		if err != nil {
+			return err
+		}
+
+		var live, dead uint64
+		for _, p := range parts {
+			part, err := dl.LoadPartition(p)
+			if err != nil {
+				return xerrors.Errorf("loading partition %d: %w", p, err)
+			}
+			all, err := part.AllSectors()
+			if err != nil {
+				return err
+			}
+			ls, err := part.LiveSectors()
+			if err != nil {
+				return err
+			}
+			nall, err := all.Count()
+			if err != nil {
+				return err
+			}
+			nlive, err := ls.Count()
+			if err != nil {
+				return err
+			}
+			live += nlive
+			dead += nall - nlive
+		}
+		after := (live + mi.WindowPoStPartitionSectors - 1) / mi.WindowPoStPartitionSectors
+
+		fmt.Printf("Compacting %d partitions of deadline %d with %d live and %d dead sectors into %d partitions\n", len(parts), dlIdx, live, dead, after)
+
+		params, err := actors.SerializeParams(&miner0.CompactPartitionsParams{
+			Deadline:   dlIdx,
+			Partitions: bitfield.NewFromSet(parts),
+		})
+		if err != nil {
+			return xerrors.Errorf("serializing params: %w", err)
+		}
+
+		msg := &types.Message{
+			From:   mi.Worker,
+			To:     maddr,
+			Method: builtin2.MethodsMiner.CompactPartitions,
+			Value:  big.Zero(),
+			Params: params,
+		}
+		if send, err := confirmCosts(ctx, cctx, nApi, true, messages(msg)); err != nil || !send {
+			return err
+		}
+
+		smsg, err := nApi.MpoolPushMessage(ctx, msg, nil)
+		if err != nil {
+			return xerrors.Errorf("mpool push: %w", err)
+		}
+
+		fmt.Println("Message CID:", smsg.Cid())
+
+		return nil
+	},
+}
+
+var sectorsExtendCmd = &cli.Command{
+	Name:  "extend",
+	Usage: "extend the expiration of the active sectors expiring before an epoch",
+	Description: `Selects the active sectors of the miner expiring before --expiring-before,
+   and extends their expiration to --new-expiration, in as few messages as the
+   declaration limits of the miner actor and --max-message-gas allow. The faulty
+   sectors aren't extended.
+
+   The sectors which can't be extended that far, past the maximum lifetime of
+   their seal proof after their activation, are extended as far as they can be.`,
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "expiring-before",
+			Usage:    "extend the sectors expiring before this epoch",
+			Required: true,
+		},
+		&cli.Int64Flag{
+			Name:     "new-expiration",
+			Usage:    "the new expiration epoch of the sectors",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "deals-only",
+			Usage: "only extend the sectors with deals",
+		},
+		&cli.BoolFlag{
+			Name:  "cc-only",
+			Usage: "only extend the committed capacity sectors, without deals",
+		},
+		&cli.Uint64Flag{
+			Name:  "max-sectors",
+			Usage: "the most sectors extended by a message",
+			Value: miner0.AddressedSectorsMax,
+		},
+		&cli.Int64Flag{
+			Name:  "max-message-gas",
+			Usage: "the most gas a message can use, the sectors are split into more messages otherwise",
+			Value: int64(build.BlockGasTarget),
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transactions performing the action",
+			Value: false,
+		},
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Bool("deals-only") && cctx.Bool("cc-only") {
+			return xerrors.Errorf("--deals-only and --cc-only can't be used together")
+		}
+
+		mApi, mCloser, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer mCloser()
+
+		nApi, nCloser, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer nCloser()
+
+		ctx := lcli.ReqContext(cctx)
+
+		maddr, err := mApi.ActorAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		head, err := nApi.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		before := abi.ChainEpoch(cctx.Int64("expiring-before"))
+		newExp := abi.ChainEpoch(cctx.Int64("new-expiration"))
+		if newExp < head.Height()+miner0.MinSectorExpiration {
+			return xerrors.Errorf("new expiration %d must be at least %d epochs after the current epoch %d", newExp, miner0.MinSectorExpiration, head.Height())
+		}
+		if newExp > head.Height()+policy.GetMaxSectorExpirationExtension() {
+			return xerrors.Errorf("new expiration %d must be at most %d epochs after the current epoch %d", newExp, policy.GetMaxSectorExpirationExtension(), head.Height())
+		}
+		maxSectors := cctx.Uint64("max-sectors")
+		if maxSectors == 0 || maxSectors > miner0.AddressedSectorsMax {
+			return xerrors.Errorf("--max-sectors must be between 1 and %d", miner0.AddressedSectorsMax)
+		}
+
+		mact, err := nApi.StateGetActor(ctx, maddr, head.Key())
+		if err != nil {
+			return err
+		}
+		mas, err := miner.Load(adt.WrapStore(ctx, cbor.NewCborStore(apibstore.NewAPIBlockstore(nApi))), mact)
+		if err != nil {
+			return err
+		}
+
+		sectors, err := nApi.StateMinerActiveSectors(ctx, maddr, head.Key())
+		if err != nil {
+			return xerrors.Errorf("getting active sectors: %w", err)
+		}
+		infos := make(map[abi.SectorNumber]*miner.SectorOnChainInfo, len(sectors))
+		for _, s := range sectors {
+			infos[s.SectorNumber] = s
+		}
+
+		selected := func(s *miner.SectorOnChainInfo) bool {
+			switch {
+			case s.Expiration >= before, s.Expiration >= newExp:
+				return false
+			case cctx.Bool("deals-only"):
+				return len(s.DealIDs) > 0
+			case cctx.Bool("cc-only"):
+				return len(s.DealIDs) == 0
+			}
+			return true
+		}
+
+		var parts []partitionSectors
+		var total, nparts, clamped, maxed int
+		err = mas.ForEachDeadline(func(dlIdx uint64, dl miner.Deadline) error {
+			return dl.ForEachPartition(func(partIdx uint64, part miner.Partition) error {
+				active, err := part.ActiveSectors()
+				if err != nil {
+					return err
+				}
+				// the sectors extended to the same epoch share an extension
+				byExp := map[abi.ChainEpoch]*partitionSectors{}
+				err = active.ForEach(func(sn uint64) error {
+					s, ok := infos[abi.SectorNumber(sn)]
+					if !ok || !selected(s) {
+						return nil
+					}
+					exp, err := sectorNewExpiration(s, newExp)
+					if err != nil {
+						return err
+					}
+					if exp <= s.Expiration {
+						maxed++
+						return nil
+					}
+					if exp < newExp {
+						clamped++
+					}
+
+					ps, ok := byExp[exp]
+					if !ok {
+						ps = &partitionSectors{Deadline: dlIdx, Partition: partIdx, NewExpiration: exp}
+						byExp[exp] = ps
+					}
+					ps.Sectors = append(ps.Sectors, sn)
+					total++
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				if len(byExp) > 0 {
+					nparts++
+				}
+				start := len(parts)
+				for _, ps := range byExp {
+					parts = append(parts, *ps)
+				}
+				sort.Slice(parts[start:], func(i, j int) bool {
+					return parts[start+i].NewExpiration < parts[start+j].NewExpiration
+				})
+				return nil
+			})
+		})
+		if err != nil {
+			return xerrors.Errorf("selecting sectors: %w", err)
+		}
+		if maxed > 0 {
+			fmt.Printf("Skipping %d sectors already expiring at the most they can be extended to\n", maxed)
+		}
+		if total == 0 {
+			fmt.Println("No sectors to extend")
+			return nil
+		}
+
+		mi, err := nApi.StateMinerInfo(ctx, maddr, head.Key())
+		if err != nil {
+			return err
+		}
+
+		msgs, err := extensionMessages(ctx, nApi, mi.Worker, maddr, parts, maxSectors, cctx.Int64("max-message-gas"), head.Key())
+		if err != nil {
+			return err
+		}
+
+		if clamped > 0 {
+			fmt.Printf("Extending %d sectors short of epoch %d, as far as they can be extended\n", clamped, newExp)
+		}
+		fmt.Printf("Extending %d sectors in %d partitions to epoch %d in %d messages\n", total, nparts, newExp, len(msgs))
+		if send, err := confirmCosts(ctx, cctx, nApi, true, messages(msgs...)); err != nil || !send {
+			return err
+		}
+
+		for i, msg := range msgs {
+			smsg, err := nApi.MpoolPushMessage(ctx, msg, nil)
+			if err != nil {
+				return xerrors.Errorf("mpool push of message %d of %d: %w", i+1, len(msgs), err)
+			}
+			fmt.Println("Message CID:", smsg.Cid())
+		}
+
+		return nil
+	},
+}
+
+// sectorNewExpiration returns the epoch the sector can be extended to, newExp
+// at most: the miner actor rejects expirations past the maximum lifetime of the
+// seal proof of the sector after its activation. The maximum extension is
+// measured from the current epoch, which newExp is already checked against.
+func sectorNewExpiration(s *miner.SectorOnChainInfo, newExp abi.ChainEpoch) (abi.ChainEpoch, error) {
+	lifetime, err := policy.GetSectorMaxLifetime(s.SealProof)
+	if err != nil {
+		return 0, xerrors.Errorf("sector %d: %w", s.SectorNumber, err)
+	}
+	if limit := s.Activation + lifetime; newExp > limit {
+		newExp = limit
+	}
+	return newExp, nil
+}
+
+// partitionSectors are sectors of a partition, extended to NewExpiration.
+type partitionSectors struct {
+	Deadline      uint64
+	Partition     uint64
+	Sectors       []uint64
+	NewExpiration abi.ChainEpoch
+}
+
+// extensionMessages returns the messages extending the sectors of the
+// partitions, with at most maxSectors sectors each, and fewer when a message
+// would use more than maxGas.
+func extensionMessages(ctx context.Context, nApi api.FullNode, from, maddr address.Address, parts []partitionSectors, maxSectors uint64, maxGas int64, tsk types.TipSetKey) ([]*types.Message, error) {
+	for {
+		var msgs []*types.Message
+		for _, params := range extensionParams(parts, maxSectors) {
+			sp, aerr := actors.SerializeParams(params)
+			if aerr != nil {
+				return nil, xerrors.Errorf("serializing params: %w", aerr)
+			}
+			msg := &types.Message{
+				From:   from,
+				To:     maddr,
+				Method: builtin2.MethodsMiner.ExtendSectorExpiration,
+				Value:  big.Zero(),
+				Params: sp,
+			}
+
+			est, err := nApi.GasEstimateMessageGas(ctx, msg, nil, tsk)
+			if err != nil {
+				return nil, xerrors.Errorf("estimating message gas: %w", err)
+			}
+			if est.GasLimit > maxGas {
+				msgs = nil
+				break
+			}
+			msgs = append(msgs, msg)
+		}
+		if msgs != nil {
+			return msgs, nil
+		}
+
+		if maxSectors == 1 {
+			return nil, xerrors.Errorf("extending a single sector uses more than %d gas", maxGas)
+		}
+		maxSectors /= 2
+	}
+}
+
+// extensionParams splits the extensions of the sectors of the partitions into
+// the params of as few messages as the miner actor takes: at most
+// AddressedPartitionsMax extensions, and maxSectors sectors, each.
+func extensionParams(parts []partitionSectors, maxSectors uint64) []*miner0.ExtendSectorExpirationParams {
+	var out []*miner0.ExtendSectorExpirationParams
+	cur := &miner0.ExtendSectorExpirationParams{}
+	var n uint64
+	for _, p := range parts {
+		secs := p.Sectors
+		for len(secs) > 0 {
+			if uint64(len(cur.Extensions)) >= miner0.AddressedPartitionsMax || n >= maxSectors {
+				out = append(out, cur)
+				cur = &miner0.ExtendSectorExpirationParams{}
+				n = 0
+			}
+
+			take := uint64(len(secs))
+			if left := maxSectors - n; take > left {
+				take = left
+			}
+			cur.Extensions = append(cur.Extensions, miner0.ExpirationExtension{
+				Deadline:      p.Deadline,
+				Partition:     p.Partition,
+				Sectors:       bitfield.NewFromSet(secs[:take]),
+				NewExpiration: p.NewExpiration,
+			})
+			n += take
+			secs = secs[take:]
+		}
+	}
+	if len(cur.Extensions) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
 var sectorsUpdateCmd = &cli.Command{
 	Name:      "update-state",
 	Usage:     "ADVANCED: manually update the state of a sector, this may aid in error recovery",
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+)
+
+func TestExtensionParams(t *testing.T) {
+	numbers := func(from, n uint64) []uint64 {
+		out := make([]uint64, n)
+		for i := range out {
+			out[i] = from + uint64(i)
+		}
+		return out
+	}
+
+	// too many sectors for one message
+	parts := []partitionSectors{
+		{Deadline: 0, Partition: 0, Sectors: numbers(0, miner0.AddressedSectorsMax-10), NewExpiration: 1000},
+		{Deadline: 1, Partition: 0, Sectors: numbers(100000, 30), NewExpiration: 1000},
+	}
+	params := extensionParams(parts, miner0.AddressedSectorsMax)
+	require.Len(t, params, 2)
+	require.Len(t, params[0].Extensions, 2)
+	require.Len(t, params[1].Extensions, 1)
+
+	var total uint64
+	for _, p := range params {
+		var n uint64
+		for _, ext := range p.Extensions {
+			c, err := ext.Sectors.Count()
+			require.NoError(t, err)
+			require.EqualValues(t, 1000, ext.NewExpiration)
+			n += c
+		}
+		require.LessOrEqual(t, n, uint64(miner0.AddressedSectorsMax))
+		total += n
+	}
+	require.Equal(t, uint64(miner0.AddressedSectorsMax+20), total)
+
+	// too many partitions for one message
+	parts = nil
+	for i := 0; i < int(miner0.AddressedPartitionsMax)+1; i++ {
+		parts = append(parts, partitionSectors{Deadline: uint64(i % 48), Partition: uint64(i / 48), Sectors: []uint64{uint64(i)}, NewExpiration: 1000})
+	}
+	params = extensionParams(parts, miner0.AddressedSectorsMax)
+	require.Len(t, params, 2)
+	require.Len(t, params[0].Extensions, int(miner0.AddressedPartitionsMax))
+	require.Len(t, params[1].Extensions, 1)
+
+	// fewer sectors per message
+	parts = []partitionSectors{{Sectors: numbers(0, 10), NewExpiration: 1000}}
+	params = extensionParams(parts, 4)
+	require.Len(t, params, 3)
+
+	require.Empty(t, extensionParams(nil, miner0.AddressedSectorsMax))
+}
+
+func TestSectorNewExpiration(t *testing.T) {
+	proof := abi.RegisteredSealProof_StackedDrg32GiBV1
+	lifetime, err := policy.GetSectorMaxLifetime(proof)
+	require.NoError(t, err)
+	maxExt := policy.GetMaxSectorExpirationExtension()
+
+	s := &miner.SectorOnChainInfo{SealProof: proof, Activation: 1000, Expiration: 2000}
+
+	exp, err := sectorNewExpiration(s, 5000)
+	require.NoError(t, err)
+	require.EqualValues(t, 5000, exp)
+
+	// the maximum extension is measured from the current epoch, not from the
+	// activation
+	require.Less(t, int64(maxExt), int64(lifetime))
+	exp, err = sectorNewExpiration(s, s.Activation+maxExt+1)
+	require.NoError(t, err)
+	require.Equal(t, s.Activation+maxExt+1, exp)
+
+	// clamped to the maximum lifetime after the activation
+	exp, err = sectorNewExpiration(s, s.Activation+lifetime+1)
+	require.NoError(t, err)
+	require.Equal(t, s.Activation+lifetime, exp)
+
+	_, err = sectorNewExpiration(&miner.SectorOnChainInfo{SealProof: -1}, 5000)
+	require.Error(t, err)
+}
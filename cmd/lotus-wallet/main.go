@@ -2,22 +2,30 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 
+	"github.com/gbrlsnchs/jwt/v3"
 	"github.com/gorilla/mux"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-jsonrpc/auth"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/wallet"
 	ledgerwallet "github.com/filecoin-project/lotus/chain/wallet/ledger"
 	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/lotus/lib/lotuslog"
+	"github.com/filecoin-project/lotus/node/modules"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/repo"
 )
 
@@ -30,6 +38,7 @@ func main() {
 
 	local := []*cli.Command{
 		runCmd,
+		getAPIKeyCmd,
 	}
 
 	app := &cli.App{
@@ -67,6 +76,14 @@ var runCmd = &cli.Command{
 			Name:  "ledger",
 			Usage: "use a ledger device instead of an on-disk wallet",
 		},
+		&cli.BoolFlag{
+			Name:  "allow-export",
+			Usage: "allow exporting private keys, with admin tokens",
+		},
+		&cli.StringSliceFlag{
+			Name:  "export-address",
+			Usage: "only allow exporting the keys of these addresses",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		log.Info("Starting lotus wallet")
@@ -75,30 +92,28 @@ var runCmd = &cli.Command{
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 
-		repoPath := cctx.String(FlagWalletRepo)
-		r, err := repo.NewFS(repoPath)
-		if err != nil {
-			return err
+		export := ExportPolicy{Allow: cctx.Bool("allow-export")}
+		for _, s := range cctx.StringSlice("export-address") {
+			addr, err := address.NewFromString(s)
+			if err != nil {
+				return xerrors.Errorf("parsing export address %q: %w", s, err)
+			}
+			export.Addresses = append(export.Addresses, addr)
 		}
 
-		ok, err := r.Exists()
+		lr, err := openRepo(cctx)
 		if err != nil {
 			return err
 		}
-		if !ok {
-			if err := r.Init(repo.Worker); err != nil {
-				return err
-			}
-		}
 
-		lr, err := r.Lock(repo.Wallet)
+		ks, err := lr.KeyStore()
 		if err != nil {
 			return err
 		}
 
-		ks, err := lr.KeyStore()
+		secret, err := modules.APISecret(ks, lr)
 		if err != nil {
-			return err
+			return xerrors.Errorf("loading API secret: %w", err)
 		}
 
 		lw, err := wallet.NewWallet(ks)
@@ -125,18 +140,18 @@ var runCmd = &cli.Command{
 		log.Info("Setting up API endpoint at " + address)
 
 		rpcServer := jsonrpc.NewServer()
-		rpcServer.Register("Filecoin", &LoggedWallet{under: w})
+		rpcServer.Register("Filecoin", apistruct.PermissionedWalletAPI(&LoggedWallet{under: &PolicyWallet{WalletAPI: w, Export: export}}))
 
 		mux.Handle("/rpc/v0", rpcServer)
 		mux.PathPrefix("/").Handler(http.DefaultServeMux) // pprof
 
-		/*ah := &auth.Handler{
-			Verify: nodeApi.AuthVerify,
+		ah := &auth.Handler{
+			Verify: authVerify(secret),
 			Next:   mux.ServeHTTP,
-		}*/
+		}
 
 		srv := &http.Server{
-			Handler: mux,
+			Handler: ah,
 			BaseContext: func(listener net.Listener) context.Context {
 				return ctx
 			},
@@ -159,3 +174,83 @@ var runCmd = &cli.Command{
 		return srv.Serve(nl)
 	},
 }
+
+var getAPIKeyCmd = &cli.Command{
+	Name:  "get-api-key",
+	Usage: "Print a token to access the wallet API, the wallet must not be running",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "perm",
+			Usage: "permission of the token, one of read, write, sign or admin; nodes using the wallet need sign",
+			Value: "sign",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		perm := auth.Permission(cctx.String("perm"))
+
+		var perms []auth.Permission
+		for _, p := range apistruct.AllPermissions {
+			perms = append(perms, p)
+			if p == perm {
+				break
+			}
+		}
+		if perms[len(perms)-1] != perm {
+			return xerrors.Errorf("unknown permission %q", cctx.String("perm"))
+		}
+
+		lr, err := openRepo(cctx)
+		if err != nil {
+			return err
+		}
+		defer lr.Close() //nolint:errcheck
+
+		ks, err := lr.KeyStore()
+		if err != nil {
+			return err
+		}
+
+		secret, err := modules.APISecret(ks, lr)
+		if err != nil {
+			return xerrors.Errorf("loading API secret: %w", err)
+		}
+
+		token, err := jwt.Sign(&modules.JwtPayload{Allow: perms}, (*jwt.HMACSHA)(secret))
+		if err != nil {
+			return xerrors.Errorf("signing token: %w", err)
+		}
+
+		fmt.Println(string(token))
+		return nil
+	},
+}
+
+func openRepo(cctx *cli.Context) (repo.LockedRepo, error) {
+	r, err := repo.NewFS(cctx.String(FlagWalletRepo))
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := r.Exists()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := r.Init(repo.Worker); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.Lock(repo.Wallet)
+}
+
+func authVerify(secret *dtypes.APIAlg) func(ctx context.Context, token string) ([]auth.Permission, error) {
+	return func(ctx context.Context, token string) ([]auth.Permission, error) {
+		var payload modules.JwtPayload
+		if _, err := jwt.Verify([]byte(token), (*jwt.HMACSHA)(secret), &payload); err != nil {
+			return nil, xerrors.Errorf("JWT Verification failed: %w", err)
+		}
+
+		return apistruct.Permissions(payload.Allow, payload.Methods), nil
+	}
+}
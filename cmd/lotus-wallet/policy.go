@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// ExportPolicy controls which private keys can be exported from the wallet.
+// Exporting keys requires an admin token in any case.
+type ExportPolicy struct {
+	Allow bool
+	// Addresses, when set, are the only addresses which can be exported
+	Addresses []address.Address
+}
+
+func (p ExportPolicy) allowed(addr address.Address) bool {
+	if !p.Allow {
+		return false
+	}
+	if len(p.Addresses) == 0 {
+		return true
+	}
+	for _, a := range p.Addresses {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyWallet enforces the export policy of the wallet.
+type PolicyWallet struct {
+	api.WalletAPI
+
+	Export ExportPolicy
+}
+
+func (w *PolicyWallet) WalletExport(ctx context.Context, addr address.Address) (*types.KeyInfo, error) {
+	if !w.Export.allowed(addr) {
+		return nil, xerrors.Errorf("exporting key %s denied by the wallet export policy", addr)
+	}
+
+	return w.WalletAPI.WalletExport(ctx, addr)
+}
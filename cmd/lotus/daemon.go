@@ -4,6 +4,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
@@ -14,8 +15,12 @@ import (
 	"os"
 	"runtime/pprof"
 	"strings"
+	"time"
 
+	"github.com/DataDog/zstd"
+	"github.com/docker/go-units"
 	paramfetch "github.com/filecoin-project/go-paramfetch"
+	"github.com/ipfs/go-cid"
 	"github.com/mitchellh/go-homedir"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/urfave/cli/v2"
@@ -27,6 +32,8 @@ import (
 	"gopkg.in/cheggaaa/pb.v1"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apiconns"
+	"github.com/filecoin-project/lotus/api/apirecord"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
@@ -104,11 +111,23 @@ var DaemonCmd = &cli.Command{
 		},
 		&cli.StringFlag{
 			Name:  "import-chain",
-			Usage: "on first run, load chain from given file or url and validate",
+			Usage: "on first run, load chain from given file or url ('-' for stdin), optionally zstd compressed, and validate",
 		},
 		&cli.StringFlag{
 			Name:  "import-snapshot",
-			Usage: "import chain state from a given chain export file or url",
+			Usage: "import chain state from a given chain export file or url ('-' for stdin), optionally zstd compressed",
+		},
+		&cli.StringFlag{
+			Name:  "import-root",
+			Usage: "comma-separated block CIDs of the expected head tipset of the imported chain",
+		},
+		&cli.BoolFlag{
+			Name:  "import-verify-blocks",
+			Usage: "check that every imported block matches its CID",
+		},
+		&cli.StringSliceFlag{
+			Name:  "import-increment",
+			Usage: "apply incremental snapshots (from 'lotus chain export --base') from the given files or urls, in order, on top of the current chain",
 		},
 		&cli.BoolFlag{
 			Name:  "halt-after-import",
@@ -136,6 +155,36 @@ var DaemonCmd = &cli.Command{
 			Name:  "config",
 			Usage: "specify path of config file to use",
 		},
+		&cli.StringFlag{
+			Name:  "api-record",
+			Usage: "development: record the API calls to the given file, for replaying them with 'lotus-shed rpc-replay'; the params and results of admin methods aren't recorded",
+		},
+		&cli.StringFlag{
+			Name:  "api-cbor",
+			Usage: "also serve the API with the binary cbor rpc transport on the given multiaddr, like /ip4/127.0.0.1/tcp/1235 or /unix/path/to/socket; the clients use FULLNODE_CBOR_API_INFO",
+		},
+		&cli.BoolFlag{
+			Name:  "api-rest",
+			Usage: "also serve the read-only REST API for the common chain queries under /v1/, like /v1/tipset/head",
+		},
+		&cli.DurationFlag{
+			Name:  "api-slow-call",
+			Usage: "log the API calls taking longer than this, with their method and token, 0 to disable",
+		},
+		&cli.IntFlag{
+			Name:  "api-max-subscriptions",
+			Usage: "the number of subscriptions, like ChainNotify, a websocket connection to the API can have at once, 0 for no limit",
+		},
+		&cli.IntFlag{
+			Name:  "api-subscription-buffer",
+			Usage: "the number of values buffered for each subscription while its consumer is slow",
+			Value: apiconns.DefaultLimits.Buffer,
+		},
+		&cli.StringFlag{
+			Name:  "api-subscription-overflow",
+			Usage: "what to do when the buffer of a subscription is full: 'disconnect' closes the connection, 'drop-oldest' drops the oldest value",
+			Value: string(apiconns.DefaultLimits.Overflow),
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		isLite := cctx.Bool("lite")
@@ -227,9 +276,28 @@ var DaemonCmd = &cli.Command{
 				issnapshot = true
 			}
 
-			if err := ImportChain(r, chainfile, issnapshot); err != nil {
+			var opts importOpts
+			if cctx.IsSet("import-root") {
+				for _, cs := range strings.Split(cctx.String("import-root"), ",") {
+					c, err := cid.Decode(strings.TrimSpace(cs))
+					if err != nil {
+						return xerrors.Errorf("parsing import root: %w", err)
+					}
+					opts.expectedRoots = append(opts.expectedRoots, c)
+				}
+			}
+			opts.verifyBlocks = cctx.Bool("import-verify-blocks")
+
+			if err := ImportChain(ctx, r, chainfile, issnapshot, opts); err != nil {
+				return err
+			}
+		}
+		if incs := cctx.StringSlice("import-increment"); len(incs) > 0 {
+			if err := ImportIncrements(r, incs); err != nil {
 				return err
 			}
+		}
+		if chainfile != "" || snapshot != "" || cctx.IsSet("import-increment") {
 			if cctx.Bool("halt-after-import") {
 				fmt.Println("Chain import complete, halting as requested...")
 				return nil
@@ -314,8 +382,35 @@ var DaemonCmd = &cli.Command{
 			return xerrors.Errorf("getting api endpoint: %w", err)
 		}
 
+		var rec *apirecord.Recorder
+		if path := cctx.String("api-record"); path != "" {
+			rec, err = apirecord.NewRecorder(path)
+			if err != nil {
+				return err
+			}
+			defer rec.Close() //nolint:errcheck
+			log.Warnw("recording API calls", "file", path)
+		}
+
+		var cborEndpoint multiaddr.Multiaddr
+		if addr := cctx.String("api-cbor"); addr != "" {
+			if cborEndpoint, err = multiaddr.NewMultiaddr(addr); err != nil {
+				return xerrors.Errorf("parsing cbor api address: %w", err)
+			}
+		}
+
+		overflow, err := apiconns.ParsePolicy(cctx.String("api-subscription-overflow"))
+		if err != nil {
+			return err
+		}
+		connLimits := apiconns.Limits{
+			MaxSubscriptions: cctx.Int("api-max-subscriptions"),
+			Buffer:           cctx.Int("api-subscription-buffer"),
+			Overflow:         overflow,
+		}
+
 		// TODO: properly parse api endpoint (or make it a URL)
-		return serveRPC(api, stop, endpoint, shutdownChan)
+		return serveRPC(api, stop, endpoint, cborEndpoint, cctx.Bool("api-rest"), cctx.Duration("api-slow-call"), connLimits, shutdownChan, rec)
 	},
 	Subcommands: []*cli.Command{
 		daemonStopCmd,
@@ -356,41 +451,20 @@ func importKey(ctx context.Context, api api.FullNode, f string) error {
 	return nil
 }
 
-func ImportChain(r repo.Repo, fname string, snapshot bool) (err error) {
-	var rd io.Reader
-	var l int64
-	if strings.HasPrefix(fname, "http://") || strings.HasPrefix(fname, "https://") {
-		resp, err := http.Get(fname) //nolint:gosec
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close() //nolint:errcheck
-
-		if resp.StatusCode != http.StatusOK {
-			return xerrors.Errorf("non-200 response: %d", resp.StatusCode)
-		}
+type importOpts struct {
+	expectedRoots []cid.Cid
+	verifyBlocks  bool
+}
 
-		rd = resp.Body
-		l = resp.ContentLength
-	} else {
+// ImportChain imports a chain export into the repo. Imports are checkpointed,
+// so that an interrupted import of the same file or url continues where it
+// stopped.
+func ImportChain(ctx context.Context, r repo.Repo, fname string, snapshot bool, opts importOpts) (err error) {
+	if fname != "-" && !strings.HasPrefix(fname, "http://") && !strings.HasPrefix(fname, "https://") {
 		fname, err = homedir.Expand(fname)
 		if err != nil {
 			return err
 		}
-
-		fi, err := os.Open(fname)
-		if err != nil {
-			return err
-		}
-		defer fi.Close() //nolint:errcheck
-
-		st, err := os.Stat(fname)
-		if err != nil {
-			return err
-		}
-
-		rd = fi
-		l = st.Size()
 	}
 
 	lr, err := r.Lock(repo.FullNode)
@@ -417,21 +491,36 @@ func ImportChain(r repo.Repo, fname string, snapshot bool) (err error) {
 	}
 	cst := store.NewChainStore(bs, mds, vm.Syscalls(ffiwrapper.ProofVerifier), j)
 
-	log.Infof("importing chain from %s...", fname)
-
-	bufr := bufio.NewReaderSize(rd, 1<<20)
-
-	bar := pb.New64(l)
-	br := bar.NewProxyReader(bufr)
-	bar.ShowTimeLeft = true
-	bar.ShowPercent = true
-	bar.ShowSpeed = true
-	bar.Units = pb.U_BYTES
+	cp, err := cst.GetImportCheckpoint(fname)
+	if err != nil {
+		return err
+	}
+	if cp != nil && fname == "-" {
+		log.Warnf("can't resume an import from stdin, starting over")
+		cp = nil
+	}
 
-	bar.Start()
-	ts, err := cst.Import(br)
-	bar.Finish()
+	var offset int64
+	if cp != nil {
+		offset = int64(cp.Offset)
+		log.Infof("resuming import of %s at byte %d (%d blocks already imported)", fname, cp.Offset, cp.Blocks)
+	} else {
+		log.Infof("importing chain from %s...", fname)
+	}
 
+	rd, l, closer, err := openChainExport(fname, offset)
+	if err != nil {
+		return err
+	}
+	defer closer() //nolint:errcheck
+
+	ts, err := cst.ImportStream(ctx, rd, store.ImportOpts{
+		Source:        fname,
+		ExpectedRoots: opts.expectedRoots,
+		VerifyBlocks:  opts.verifyBlocks,
+		Resume:        cp,
+		Progress:      importProgressLogger(offset, l),
+	})
 	if err != nil {
 		return xerrors.Errorf("importing chain failed: %w", err)
 	}
@@ -466,3 +555,234 @@ func ImportChain(r repo.Repo, fname string, snapshot bool) (err error) {
 
 	return nil
 }
+
+// importProgressLogger returns an import progress callback which logs the
+// import rate and, if the size of the export is known, the remaining time.
+func importProgressLogger(startOffset, size int64) func(store.ImportCheckpoint) {
+	start := build.Clock.Now()
+	var last time.Time
+	var startBlocks uint64
+	var haveStart bool
+
+	return func(cp store.ImportCheckpoint) {
+		if !haveStart {
+			startBlocks, haveStart = cp.Blocks, true
+		}
+
+		now := build.Clock.Now()
+		if now.Sub(last) < 5*time.Second {
+			return
+		}
+		last = now
+
+		took := now.Sub(start).Seconds()
+		if took <= 0 {
+			return
+		}
+		blkRate := float64(cp.Blocks-startBlocks) / took
+		byteRate := float64(int64(cp.Offset)-startOffset) / took
+
+		if size <= 0 || byteRate <= 0 {
+			log.Infow("importing", "blocks", cp.Blocks, "blocks/s", int64(blkRate), "bytes", units.BytesSize(float64(cp.Offset)))
+			return
+		}
+
+		eta := time.Duration(float64(size-int64(cp.Offset))/byteRate) * time.Second
+		log.Infow("importing", "blocks", cp.Blocks, "blocks/s", int64(blkRate),
+			"bytes", units.BytesSize(float64(cp.Offset)), "total", units.BytesSize(float64(size)),
+			"percent", fmt.Sprintf("%.1f", float64(cp.Offset)*100/float64(size)), "eta", eta.Truncate(time.Second))
+	}
+}
+
+// zstdMagic starts the zstd frames.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// openChainExport opens a chain export like openChainFile, decompressing it
+// when it's compressed with zstd, like the exports of 'lotus chain export
+// --compress zstd'. The offset is the one in the decompressed export, and
+// the size of compressed exports isn't known.
+func openChainExport(fname string, offset int64) (io.Reader, int64, func() error, error) {
+	rd, size, closer, err := openChainFile(fname, 0)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	br := bufio.NewReader(rd)
+	if magic, _ := br.Peek(len(zstdMagic)); !bytes.Equal(magic, zstdMagic) {
+		if offset == 0 {
+			return br, size, closer, nil
+		}
+		if fname == "-" {
+			// stdin can't be reopened, and br read ahead of the offset
+			if _, err := io.CopyN(ioutil.Discard, br, offset); err != nil {
+				_ = closer()
+				return nil, 0, nil, xerrors.Errorf("skipping to offset %d: %w", offset, err)
+			}
+			return br, size, closer, nil
+		}
+		_ = closer()
+		return openChainFile(fname, offset)
+	}
+
+	zr := zstd.NewReader(br)
+	zcloser := func() error {
+		_ = zr.Close()
+		return closer()
+	}
+	if offset > 0 {
+		if _, err := io.CopyN(ioutil.Discard, zr, offset); err != nil {
+			_ = zcloser()
+			return nil, 0, nil, xerrors.Errorf("skipping to offset %d: %w", offset, err)
+		}
+	}
+	return zr, -1, zcloser, nil
+}
+
+// openChainFile opens a chain export from a local file, an http(s) url or
+// stdin ('-'), positioned at the given offset. The total size of the export
+// is returned if known, otherwise -1.
+func openChainFile(fname string, offset int64) (io.Reader, int64, func() error, error) {
+	if fname == "-" {
+		if offset > 0 {
+			if _, err := io.CopyN(ioutil.Discard, os.Stdin, offset); err != nil {
+				return nil, 0, nil, xerrors.Errorf("skipping to offset %d: %w", offset, err)
+			}
+		}
+		return os.Stdin, -1, func() error { return nil }, nil
+	}
+
+	if strings.HasPrefix(fname, "http://") || strings.HasPrefix(fname, "https://") {
+		req, err := http.NewRequest(http.MethodGet, fname, nil)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+			size := int64(-1)
+			if resp.ContentLength >= 0 {
+				size = resp.ContentLength + offset
+			}
+			return resp.Body, size, resp.Body.Close, nil
+		case http.StatusOK:
+			if offset > 0 {
+				log.Warnf("server doesn't support range requests, skipping %d bytes", offset)
+				if _, err := io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+					_ = resp.Body.Close()
+					return nil, 0, nil, xerrors.Errorf("skipping to offset %d: %w", offset, err)
+				}
+			}
+			return resp.Body, resp.ContentLength, resp.Body.Close, nil
+		default:
+			_ = resp.Body.Close()
+			return nil, 0, nil, xerrors.Errorf("non-200 response: %d", resp.StatusCode)
+		}
+	}
+
+	fname, err := homedir.Expand(fname)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	fi, err := os.Open(fname)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	st, err := fi.Stat()
+	if err != nil {
+		_ = fi.Close()
+		return nil, 0, nil, err
+	}
+
+	if _, err := fi.Seek(offset, io.SeekStart); err != nil {
+		_ = fi.Close()
+		return nil, 0, nil, xerrors.Errorf("seeking to offset %d: %w", offset, err)
+	}
+
+	return fi, st.Size(), fi.Close, nil
+}
+
+// ImportIncrements applies incremental snapshots, in order, on top of the
+// chain head stored in the repo.
+func ImportIncrements(r repo.Repo, fnames []string) error {
+	lr, err := r.Lock(repo.FullNode)
+	if err != nil {
+		return err
+	}
+	defer lr.Close() //nolint:errcheck
+
+	ds, err := lr.Datastore("/chain")
+	if err != nil {
+		return err
+	}
+
+	mds, err := lr.Datastore("/metadata")
+	if err != nil {
+		return err
+	}
+
+	bs := blockstore.NewBlockstore(ds)
+
+	j, err := journal.OpenFSJournal(lr, journal.EnvDisabledEvents())
+	if err != nil {
+		return xerrors.Errorf("failed to open journal: %w", err)
+	}
+	cst := store.NewChainStore(bs, mds, vm.Syscalls(ffiwrapper.ProofVerifier), j)
+
+	if err := cst.Load(); err != nil {
+		return xerrors.Errorf("loading chain head (increments need an existing chain): %w", err)
+	}
+
+	for _, fname := range fnames {
+		base := cst.GetHeaviestTipSet()
+
+		if err := importIncrement(cst, fname, base); err != nil {
+			return xerrors.Errorf("importing increment %s: %w", fname, err)
+		}
+	}
+
+	return nil
+}
+
+func importIncrement(cst *store.ChainStore, fname string, base *types.TipSet) error {
+	rd, l, closer, err := openChainExport(fname, 0)
+	if err != nil {
+		return err
+	}
+	defer closer() //nolint:errcheck
+
+	log.Infof("importing increment from %s on top of %s (height %d)...", fname, base.Cids(), base.Height())
+
+	bufr := bufio.NewReaderSize(rd, 1<<20)
+
+	bar := pb.New64(l)
+	br := bar.NewProxyReader(bufr)
+	bar.ShowTimeLeft = true
+	bar.ShowPercent = true
+	bar.ShowSpeed = true
+	bar.Units = pb.U_BYTES
+
+	bar.Start()
+	ts, err := cst.ImportIncrement(context.TODO(), br, base)
+	bar.Finish()
+
+	if err != nil {
+		return err
+	}
+
+	if err := cst.FlushValidationCache(); err != nil {
+		return xerrors.Errorf("flushing validation cache failed: %w", err)
+	}
+
+	log.Infof("accepting %s as new head", ts.Cids())
+	return cst.SetHead(ts)
+}
@@ -0,0 +1,52 @@
+// +build !nodaemon
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenChainExport(t *testing.T) {
+	data := bytes.Repeat([]byte("chain export "), 1000)
+	compressed, err := zstd.Compress(nil, data)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "chain-export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	stdin := os.Stdin
+	defer func() {
+		os.Stdin = stdin
+	}()
+
+	for name, export := range map[string][]byte{"plain": data, "zstd": compressed} {
+		fname := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(fname, export, 0644))
+
+		for _, offset := range []int64{0, 100} {
+			// from the file and from stdin
+			for _, src := range []string{fname, "-"} {
+				f, err := os.Open(fname)
+				require.NoError(t, err)
+				os.Stdin = f
+
+				rd, _, closer, err := openChainExport(src, offset)
+				require.NoError(t, err)
+				b, err := ioutil.ReadAll(rd)
+				require.NoError(t, err)
+				require.NoError(t, closer())
+				require.NoError(t, f.Close())
+
+				require.Equal(t, data[offset:], b, "%s from %s at offset %d", name, src, offset)
+			}
+		}
+	}
+}
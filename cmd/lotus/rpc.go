@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
@@ -21,30 +23,59 @@ import (
 	"github.com/filecoin-project/go-jsonrpc/auth"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apiconns"
+	"github.com/filecoin-project/lotus/api/apimetrics"
+	"github.com/filecoin-project/lotus/api/apirecord"
+	"github.com/filecoin-project/lotus/api/apirest"
 	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/api/apiusage"
+	"github.com/filecoin-project/lotus/lib/cborrpc"
+	"github.com/filecoin-project/lotus/lib/reqid"
+	"github.com/filecoin-project/lotus/lib/rpcbatch"
 	"github.com/filecoin-project/lotus/node"
 	"github.com/filecoin-project/lotus/node/impl"
 )
 
 var log = logging.Logger("main")
 
-func serveRPC(a api.FullNode, stop node.StopFunc, addr multiaddr.Multiaddr, shutdownCh <-chan struct{}) error {
+func serveRPC(a api.FullNode, stop node.StopFunc, addr, cborAddr multiaddr.Multiaddr, rest bool, slowCall time.Duration, connLimits apiconns.Limits, shutdownCh <-chan struct{}, rec *apirecord.Recorder) error {
+	rpcAPI := a
+	if rec != nil {
+		rpcAPI = apirecord.RecordFullAPI(a, rec)
+	}
+
+	usage := a.(*impl.FullNodeAPI).Usage
+	rpcAPI = apiusage.TrackFullAPI(rpcAPI, usage)
+	conns := a.(*impl.FullNodeAPI).Conns
+	conns.SetLimits(connLimits)
+	rpcAPI = apiconns.LimitFullAPI(rpcAPI, conns)
+	rpcAPI = apimetrics.MetricedFullAPI(rpcAPI, slowCall)
+
 	rpcServer := jsonrpc.NewServer()
-	rpcServer.Register("Filecoin", apistruct.PermissionedFullAPI(a))
+	rpcServer.Register("Filecoin", apistruct.PermissionedFullAPI(rpcAPI))
 
 	ah := &auth.Handler{
 		Verify: a.AuthVerify,
-		Next:   rpcServer.ServeHTTP,
+		Next:   rpcbatch.Handler(rpcServer, rpcbatch.DefaultParallelism).ServeHTTP,
 	}
 
-	http.Handle("/rpc/v0", ah)
+	http.Handle("/rpc/v0", reqid.Handler(apimetrics.Handler(&apistruct.FullNodeStruct{}, usage.Handler(a.AuthVerify, conns.Handler(ah)))))
 
 	importAH := &auth.Handler{
 		Verify: a.AuthVerify,
 		Next:   handleImport(a.(*impl.FullNodeAPI)),
 	}
 
-	http.Handle("/rest/v0/import", importAH)
+	http.Handle("/rest/v0/import", usage.Handler(a.AuthVerify, importAH))
+
+	if rest {
+		restAH := &auth.Handler{
+			Verify: a.AuthVerify,
+			Next:   apirest.NewHandler(apistruct.PermissionedFullAPI(rpcAPI)).ServeHTTP,
+		}
+
+		http.Handle(apirest.Prefix, reqid.Handler(usage.Handler(a.AuthVerify, restAH)))
+	}
 
 	exporter, err := prometheus.NewExporter(prometheus.Options{
 		Namespace: "lotus",
@@ -60,6 +91,33 @@ func serveRPC(a api.FullNode, stop node.StopFunc, addr multiaddr.Multiaddr, shut
 		return xerrors.Errorf("could not listen: %w", err)
 	}
 
+	var cborLst manet.Listener
+	if cborAddr != nil {
+		cborServer := cborrpc.NewServer(a.AuthVerify)
+		cborServer.Register("Filecoin", apistruct.PermissionedFullAPI(rpcAPI))
+		// the same middlewares as /rpc/v0; the calls go through the same
+		// metered API
+		cborServer.Use(func(ctx context.Context, conn net.Conn, token string, next func(context.Context, net.Conn)) {
+			next(usage.TrackConn(ctx, conn, token))
+		})
+		cborServer.Use(func(ctx context.Context, conn net.Conn, token string, next func(context.Context, net.Conn)) {
+			ctx, done := conns.TrackConn(ctx, conn)
+			defer done()
+			next(ctx, conn)
+		})
+
+		cborLst, err = manet.Listen(cborAddr)
+		if err != nil {
+			return xerrors.Errorf("could not listen on cbor api address: %w", err)
+		}
+		go func() {
+			if err := cborServer.Serve(manet.NetListener(cborLst)); err != nil {
+				log.Infof("cbor rpc server stopped: %s", err)
+			}
+		}()
+		log.Infow("serving the cbor rpc transport", "address", cborAddr)
+	}
+
 	srv := &http.Server{Handler: http.DefaultServeMux}
 
 	sigCh := make(chan os.Signal, 2)
@@ -76,6 +134,11 @@ func serveRPC(a api.FullNode, stop node.StopFunc, addr multiaddr.Multiaddr, shut
 		if err := srv.Shutdown(context.TODO()); err != nil {
 			log.Errorf("shutting down RPC server failed: %s", err)
 		}
+		if cborLst != nil {
+			if err := cborLst.Close(); err != nil {
+				log.Errorf("closing cbor RPC listener failed: %s", err)
+			}
+		}
 		if err := stop(context.TODO()); err != nil {
 			log.Errorf("graceful shutting down failed: %s", err)
 		}
@@ -104,6 +167,12 @@ func handleImport(a *impl.FullNodeAPI) func(w http.ResponseWriter, r *http.Reque
 			_ = json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: missing write permission"})
 			return
 		}
+		// the import is the upload of ClientImport
+		if !apistruct.ScopeAllows(r.Context(), "ClientImport") {
+			w.WriteHeader(401)
+			_ = json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: missing scope to call ClientImport"})
+			return
+		}
 
 		c, err := a.ClientImportLocal(r.Context(), r.Body)
 		if err != nil {
@@ -114,7 +183,7 @@ func handleImport(a *impl.FullNodeAPI) func(w http.ResponseWriter, r *http.Reque
 		w.WriteHeader(200)
 		err = json.NewEncoder(w).Encode(struct{ Cid cid.Cid }{c})
 		if err != nil {
-			log.Errorf("/rest/v0/import: Writing response failed: %+v", err)
+			reqid.Logger(r.Context(), log).Errorf("/rest/v0/import: Writing response failed: %+v", err)
 			return
 		}
 	}
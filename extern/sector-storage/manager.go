@@ -514,6 +514,14 @@ func (m *Manager) SchedDiag(ctx context.Context) (interface{}, error) {
 	return m.sched.Info(ctx)
 }
 
+func (m *Manager) SchedPause(ctx context.Context, tasks ...sealtasks.TaskType) error {
+	return m.sched.Pause(ctx, tasks...)
+}
+
+func (m *Manager) SchedResume(ctx context.Context, tasks ...sealtasks.TaskType) error {
+	return m.sched.Resume(ctx, tasks...)
+}
+
 func (m *Manager) Close(ctx context.Context) error {
 	return m.sched.Close(ctx)
 }
@@ -64,10 +64,13 @@ type scheduler struct {
 
 	schedule       chan *workerRequest
 	windowRequests chan *schedWindowRequest
+	pauseRequests  chan *schedPauseRequest
 
 	// owned by the sh.runSched goroutine
 	schedQueue  *requestQueue
 	openWindows []*schedWindowRequest
+	pausedAll   bool
+	paused      map[sealtasks.TaskType]struct{}
 
 	info chan func(interface{})
 
@@ -154,8 +157,10 @@ func newScheduler(spt abi.RegisteredSealProof) *scheduler {
 
 		schedule:       make(chan *workerRequest),
 		windowRequests: make(chan *schedWindowRequest, 20),
+		pauseRequests:  make(chan *schedPauseRequest),
 
 		schedQueue: &requestQueue{},
+		paused:     map[sealtasks.TaskType]struct{}{},
 
 		info: make(chan func(interface{})),
 
@@ -215,6 +220,15 @@ type SchedDiagRequestInfo struct {
 type SchedDiagInfo struct {
 	Requests    []SchedDiagRequestInfo
 	OpenWindows []WorkerID
+	PausedAll   bool
+	Paused      []sealtasks.TaskType
+}
+
+type schedPauseRequest struct {
+	pause bool
+	tasks []sealtasks.TaskType // all task types if empty
+
+	done chan struct{}
 }
 
 func (sh *scheduler) runSched() {
@@ -247,6 +261,11 @@ func (sh *scheduler) runSched() {
 			doSched = true
 		case ireq := <-sh.info:
 			ireq(sh.diag())
+		case preq := <-sh.pauseRequests:
+			sh.setPaused(preq)
+			close(preq.done)
+			// assign the tasks which were held back
+			doSched = !preq.pause
 
 		case <-iw:
 			initialised = true
@@ -298,9 +317,43 @@ func (sh *scheduler) diag() SchedDiagInfo {
 		out.OpenWindows = append(out.OpenWindows, window.worker)
 	}
 
+	out.PausedAll = sh.pausedAll
+	for tt := range sh.paused {
+		out.Paused = append(out.Paused, tt)
+	}
+	sort.Slice(out.Paused, func(i, j int) bool {
+		return out.Paused[i] < out.Paused[j]
+	})
+
 	return out
 }
 
+func (sh *scheduler) setPaused(req *schedPauseRequest) {
+	if len(req.tasks) == 0 {
+		sh.pausedAll = req.pause
+		if !req.pause {
+			sh.paused = map[sealtasks.TaskType]struct{}{}
+		}
+		return
+	}
+
+	for _, tt := range req.tasks {
+		if req.pause {
+			sh.paused[tt] = struct{}{}
+		} else {
+			delete(sh.paused, tt)
+		}
+	}
+}
+
+func (sh *scheduler) isPaused(tt sealtasks.TaskType) bool {
+	if sh.pausedAll {
+		return true
+	}
+	_, paused := sh.paused[tt]
+	return paused
+}
+
 func (sh *scheduler) trySched() {
 	/*
 		This assigns tasks to workers based on:
@@ -350,6 +403,11 @@ func (sh *scheduler) trySched() {
 			needRes := ResourceTable[task.taskType][sh.spt]
 
 			task.indexHeap = sqi
+			if sh.isPaused(task.taskType) {
+				// no acceptable windows, the task stays queued
+				return
+			}
+
 			for wnd, windowRequest := range sh.openWindows {
 				worker, ok := sh.workers[windowRequest.worker]
 				if !ok {
@@ -834,6 +892,42 @@ func (sh *scheduler) Info(ctx context.Context) (interface{}, error) {
 	}
 }
 
+// Pause stops assigning tasks of the given types, or of all types if none are
+// given, to workers. Tasks already assigned run to completion; new ones are
+// queued until resumed.
+func (sh *scheduler) Pause(ctx context.Context, tasks ...sealtasks.TaskType) error {
+	return sh.setPausedReq(ctx, true, tasks)
+}
+
+// Resume resumes assigning tasks of the given types, or of all types if none
+// are given. Resuming some types doesn't lift a pause of all types.
+func (sh *scheduler) Resume(ctx context.Context, tasks ...sealtasks.TaskType) error {
+	return sh.setPausedReq(ctx, false, tasks)
+}
+
+func (sh *scheduler) setPausedReq(ctx context.Context, pause bool, tasks []sealtasks.TaskType) error {
+	req := &schedPauseRequest{
+		pause: pause,
+		tasks: tasks,
+		done:  make(chan struct{}),
+	}
+
+	select {
+	case sh.pauseRequests <- req:
+	case <-sh.closing:
+		return xerrors.New("closing")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-req.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (sh *scheduler) Close(ctx context.Context) error {
 	close(sh.closing)
 	select {
@@ -329,6 +329,18 @@ func TestSched(t *testing.T) {
 		}
 	}
 
+	pause := func(tasks ...sealtasks.TaskType) task {
+		return func(t *testing.T, s *scheduler, index *stores.Index, meta *runMeta) {
+			require.NoError(t, s.Pause(ctx, tasks...))
+		}
+	}
+
+	resume := func(tasks ...sealtasks.TaskType) task {
+		return func(t *testing.T, s *scheduler, index *stores.Index, meta *runMeta) {
+			require.NoError(t, s.Resume(ctx, tasks...))
+		}
+	}
+
 	t.Run("one-pc1", testFunc([]workerSpec{
 		{name: "fred", taskTypes: map[sealtasks.TaskType]struct{}{sealtasks.TTPreCommit1: {}}},
 	}, []task{
@@ -407,6 +419,45 @@ func TestSched(t *testing.T) {
 		taskDone("t4"),
 	}))
 
+	t.Run("pause-pc1", testFunc([]workerSpec{
+		{name: "fred", taskTypes: map[sealtasks.TaskType]struct{}{sealtasks.TTPreCommit1: {}, sealtasks.TTPreCommit2: {}}},
+	}, []task{
+		pause(sealtasks.TTPreCommit1),
+
+		sched("pc1", "fred", 8, sealtasks.TTPreCommit1),
+		taskNotScheduled("pc1"),
+
+		// other task types are still assigned
+		sched("pc2", "fred", 9, sealtasks.TTPreCommit2),
+		taskDone("pc2"),
+
+		resume(sealtasks.TTPreCommit1),
+		taskDone("pc1"),
+	}))
+
+	t.Run("pause-all", testFunc([]workerSpec{
+		{name: "fred", taskTypes: map[sealtasks.TaskType]struct{}{sealtasks.TTPreCommit1: {}}},
+	}, []task{
+		sched("t1", "fred", 8, sealtasks.TTPreCommit1),
+		taskStarted("t1"),
+
+		pause(),
+
+		sched("t2", "fred", 9, sealtasks.TTPreCommit1),
+		taskNotScheduled("t2"),
+
+		// tasks in flight finish
+		taskDone("t1"),
+		taskNotScheduled("t2"),
+
+		// resuming a task type doesn't lift the global pause
+		resume(sealtasks.TTPreCommit1),
+		taskNotScheduled("t2"),
+
+		resume(),
+		taskDone("t2"),
+	}))
+
 	twoPC1 := func(prefix string, sid abi.SectorNumber, schedAssert func(name string) task) task {
 		return multTask(
 			sched(prefix+"-a", "fred", sid, sealtasks.TTPreCommit1),
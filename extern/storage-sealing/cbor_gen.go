@@ -475,7 +475,7 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write([]byte{183}); err != nil {
+	if _, err := w.Write([]byte{184, 25}); err != nil {
 		return err
 	}
 
@@ -613,6 +613,28 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.TicketExpiration (abi.ChainEpoch) (int64)
+	if len("TicketExpiration") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TicketExpiration\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("TicketExpiration"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("TicketExpiration")); err != nil {
+		return err
+	}
+
+	if t.TicketExpiration >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.TicketExpiration)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.TicketExpiration-1)); err != nil {
+			return err
+		}
+	}
+
 	// t.PreCommit1Out (storage.PreCommit1Out) (slice)
 	if len("PreCommit1Out") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"PreCommit1Out\" was too long")
@@ -845,6 +867,28 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.SeedExpiration (abi.ChainEpoch) (int64)
+	if len("SeedExpiration") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"SeedExpiration\" was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajTextString, uint64(len("SeedExpiration"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("SeedExpiration")); err != nil {
+		return err
+	}
+
+	if t.SeedExpiration >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SeedExpiration)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.SeedExpiration-1)); err != nil {
+			return err
+		}
+	}
+
 	// t.CommitMessage (cid.Cid) (struct)
 	if len("CommitMessage") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"CommitMessage\" was too long")
@@ -1141,6 +1185,32 @@ func (t *SectorInfo) UnmarshalCBOR(r io.Reader) error {
 
 				t.TicketEpoch = abi.ChainEpoch(extraI)
 			}
+			// t.TicketExpiration (abi.ChainEpoch) (int64)
+		case "TicketExpiration":
+			{
+				maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.TicketExpiration = abi.ChainEpoch(extraI)
+			}
 			// t.PreCommit1Out (storage.PreCommit1Out) (slice)
 		case "PreCommit1Out":
 
@@ -1369,6 +1439,32 @@ func (t *SectorInfo) UnmarshalCBOR(r io.Reader) error {
 
 				t.SeedEpoch = abi.ChainEpoch(extraI)
 			}
+			// t.SeedExpiration (abi.ChainEpoch) (int64)
+		case "SeedExpiration":
+			{
+				maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.SeedExpiration = abi.ChainEpoch(extraI)
+			}
 			// t.CommitMessage (cid.Cid) (struct)
 		case "CommitMessage":
 
@@ -36,6 +36,7 @@ type ErrBadSeed struct{ error }
 type ErrInvalidProof struct{ error }
 type ErrNoPrecommit struct{ error }
 type ErrCommitWaitFailed struct{ error }
+type ErrExpiredPreCommit struct{ error }
 
 func checkPieces(ctx context.Context, maddr address.Address, si SectorInfo, api SealingAPI) error {
 	tok, height, err := api.ChainHead(ctx)
@@ -102,10 +103,6 @@ func checkPrecommit(ctx context.Context, maddr address.Address, si SectorInfo, t
 
 	msd := policy.GetMaxProveCommitDuration(actors.VersionForNetwork(nv), si.SectorType)
 
-	if height-(si.TicketEpoch+policy.SealRandomnessLookback) > msd {
-		return &ErrExpiredTicket{xerrors.Errorf("ticket expired: seal height: %d, head: %d", si.TicketEpoch+policy.SealRandomnessLookback, height)}
-	}
-
 	pci, err := api.StateSectorPreCommitInfo(ctx, maddr, si.SectorNumber, tok)
 	if err != nil {
 		if err == ErrSectorAllocated {
@@ -121,10 +118,16 @@ func checkPrecommit(ctx context.Context, maddr address.Address, si SectorInfo, t
 		return &ErrPrecommitOnChain{xerrors.Errorf("precommit already on chain")}
 	}
 
+	// the ticket only needs to be valid until the precommit lands, sealing
+	// again with a new ticket isn't needed once it did
+	if height-(si.TicketEpoch+policy.SealRandomnessLookback) > msd {
+		return &ErrExpiredTicket{xerrors.Errorf("ticket expired: seal height: %d, head: %d", si.TicketEpoch+policy.SealRandomnessLookback, height)}
+	}
+
 	return nil
 }
 
-func (m *Sealing) checkCommit(ctx context.Context, si SectorInfo, proof []byte, tok TipSetToken) (err error) {
+func (m *Sealing) checkCommit(ctx context.Context, si SectorInfo, proof []byte, tok TipSetToken, height abi.ChainEpoch) (err error) {
 	if si.SeedEpoch == 0 {
 		return &ErrBadSeed{xerrors.Errorf("seed epoch was not set")}
 	}
@@ -148,6 +151,14 @@ func (m *Sealing) checkCommit(ctx context.Context, si SectorInfo, proof []byte,
 		return &ErrNoPrecommit{xerrors.Errorf("precommit info not found on-chain")}
 	}
 
+	seedExpiration, err := m.seedExpiration(ctx, tok, si.SectorType, pci.PreCommitEpoch)
+	if err != nil {
+		return &ErrApi{err}
+	}
+	if height > seedExpiration {
+		return &ErrExpiredPreCommit{xerrors.Errorf("precommit expired: precommit epoch: %d, expiration: %d, head: %d", pci.PreCommitEpoch, seedExpiration, height)}
+	}
+
 	if pci.PreCommitEpoch+policy.GetPreCommitChallengeDelay() != si.SeedEpoch {
 		return &ErrBadSeed{xerrors.Errorf("seed epoch doesn't match on chain info: %d != %d", pci.PreCommitEpoch+policy.GetPreCommitChallengeDelay(), si.SeedEpoch)}
 	}
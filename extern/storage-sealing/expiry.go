@@ -0,0 +1,87 @@
+package sealing
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+)
+
+// Estimated number of epochs it takes a sector from the start of PreCommit2
+// until its precommit lands on chain, and from the seed being ready until its
+// prove commit lands on chain. Sectors are assumed to go through these steps
+// one at a time, so a sector waits about as long for each sector queued ahead
+// of it.
+var (
+	preCommitEpochsEstimate = abi.ChainEpoch(builtin.EpochsInHour)
+	commitEpochsEstimate    = abi.ChainEpoch(builtin.EpochsInHour)
+)
+
+func (m *Sealing) maxProveCommitDuration(ctx context.Context, tok TipSetToken, spt abi.RegisteredSealProof) (abi.ChainEpoch, error) {
+	nv, err := m.api.StateNetworkVersion(ctx, tok)
+	if err != nil {
+		return 0, xerrors.Errorf("getting network version: %w", err)
+	}
+
+	return policy.GetMaxProveCommitDuration(actors.VersionForNetwork(nv), spt), nil
+}
+
+// ticketExpiration returns the last epoch at which the precommit of a sector
+// sealed with the ticket of the given epoch can land on chain.
+func (m *Sealing) ticketExpiration(ctx context.Context, tok TipSetToken, spt abi.RegisteredSealProof, ticketEpoch abi.ChainEpoch) (abi.ChainEpoch, error) {
+	msd, err := m.maxProveCommitDuration(ctx, tok, spt)
+	if err != nil {
+		return 0, err
+	}
+
+	return ticketEpoch + policy.SealRandomnessLookback + msd, nil
+}
+
+// seedExpiration returns the last epoch at which the prove commit of a sector
+// precommitted at the given epoch can land on chain.
+func (m *Sealing) seedExpiration(ctx context.Context, tok TipSetToken, spt abi.RegisteredSealProof, preCommitEpoch abi.ChainEpoch) (abi.ChainEpoch, error) {
+	msd, err := m.maxProveCommitDuration(ctx, tok, spt)
+	if err != nil {
+		return 0, err
+	}
+
+	return preCommitEpoch + msd, nil
+}
+
+// expiryAtRisk returns whether a step taking about estimate epochs per sector
+// queued in one of the given states may not be done by the expiration, and
+// the number of epochs the step is expected to take.
+func (m *Sealing) expiryAtRisk(sector SectorInfo, height, expiration, estimate abi.ChainEpoch, queue ...SectorState) (bool, abi.ChainEpoch) {
+	ahead := m.stats.curInStates(m.minerSector(sector.SectorNumber), queue...)
+	needed := estimate * abi.ChainEpoch(ahead+1)
+	return height+needed > expiration, needed
+}
+
+// warnTicketExpiry logs a warning if the precommit of the sector may not land
+// on chain before its ticket expires.
+func (m *Sealing) warnTicketExpiry(sector SectorInfo, height abi.ChainEpoch) {
+	if sector.TicketExpiration == 0 {
+		return // sealed before the expiration was tracked
+	}
+
+	if risk, needed := m.expiryAtRisk(sector, height, sector.TicketExpiration, preCommitEpochsEstimate, PreCommit2, PreCommitting, PreCommitWait); risk {
+		log.Warnw("sector at risk of ticket expiry, precommit may not land in time", "sector", sector.SectorNumber, "height", height, "ticketExpiration", sector.TicketExpiration, "estimatedEpochs", needed)
+	}
+}
+
+// warnSeedExpiry logs a warning if the prove commit of the sector may not land
+// on chain before its precommit expires.
+func (m *Sealing) warnSeedExpiry(sector SectorInfo, height abi.ChainEpoch) {
+	if sector.SeedExpiration == 0 {
+		return
+	}
+
+	if risk, needed := m.expiryAtRisk(sector, height, sector.SeedExpiration, commitEpochsEstimate, Committing, SubmitCommit, CommitWait); risk {
+		log.Warnw("sector at risk of precommit expiry, prove commit may not land in time", "sector", sector.SectorNumber, "height", height, "seedExpiration", sector.SeedExpiration, "estimatedEpochs", needed)
+	}
+}
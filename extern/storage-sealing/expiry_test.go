@@ -0,0 +1,41 @@
+package sealing
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryAtRisk(t *testing.T) {
+	ma, _ := address.NewIDAddress(55151)
+	m := &Sealing{
+		maddr: ma,
+		stats: SectorStats{
+			bySector: map[abi.SectorID]statSectorState{},
+			byState:  map[abi.SectorID]SectorState{},
+		},
+	}
+
+	sector := SectorInfo{SectorNumber: 1, State: PreCommit2}
+	m.stats.updateSector(m.minerSector(1), PreCommit2)
+
+	// only the sector itself is queued
+	risk, needed := m.expiryAtRisk(sector, 100, 200, 50, PreCommit2, PreCommitting)
+	require.False(t, risk)
+	require.Equal(t, abi.ChainEpoch(50), needed)
+
+	m.stats.updateSector(m.minerSector(2), PreCommit2)
+	m.stats.updateSector(m.minerSector(3), PreCommitting)
+	m.stats.updateSector(m.minerSector(4), WaitSeed)
+
+	risk, needed = m.expiryAtRisk(sector, 100, 200, 50, PreCommit2, PreCommitting)
+	require.True(t, risk)
+	require.Equal(t, abi.ChainEpoch(150), needed)
+
+	// sectors leaving the queue don't count anymore
+	m.stats.updateSector(m.minerSector(2), WaitSeed)
+	risk, _ = m.expiryAtRisk(sector, 100, 200, 50, PreCommit2, PreCommitting)
+	require.False(t, risk)
+}
@@ -72,6 +72,7 @@ var fsmPlanners = map[SectorState]func(events []statemachine.Event, state *Secto
 	WaitSeed: planOne(
 		on(SectorSeedReady{}, Committing),
 		on(SectorChainPreCommitFailed{}, PreCommitFailed),
+		on(SectorPreCommitExpired{}, PreCommitExpired),
 	),
 	Committing: planCommitting,
 	SubmitCommit: planOne(
@@ -121,6 +122,7 @@ var fsmPlanners = map[SectorState]func(events []statemachine.Event, state *Secto
 		on(SectorRetryCommitWait{}, CommitWait),
 		on(SectorDealsExpired{}, DealsExpired),
 		on(SectorInvalidDealIDs{}, RecoverDealIDs),
+		on(SectorPreCommitExpired{}, PreCommitExpired),
 	),
 	FinalizeFailed: planOne(
 		on(SectorRetryFinalize{}, FinalizeSector),
@@ -132,6 +134,9 @@ var fsmPlanners = map[SectorState]func(events []statemachine.Event, state *Secto
 	RecoverDealIDs: planOne(
 		onReturning(SectorUpdateDealIDs{}),
 	),
+	PreCommitExpired: planOne(
+	// SectorRemove (global)
+	),
 
 	// Post-seal
 
@@ -306,6 +311,8 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 		return m.handleDealsExpired, processed, nil
 	case RecoverDealIDs:
 		return m.handleRecoverDealIDs, processed, nil
+	case PreCommitExpired:
+		return m.handlePreCommitExpired, processed, nil
 
 	// Post-seal
 	case Proving:
@@ -102,15 +102,17 @@ func (evt SectorPacked) apply(state *SectorInfo) {
 }
 
 type SectorPreCommit1 struct {
-	PreCommit1Out storage.PreCommit1Out
-	TicketValue   abi.SealRandomness
-	TicketEpoch   abi.ChainEpoch
+	PreCommit1Out    storage.PreCommit1Out
+	TicketValue      abi.SealRandomness
+	TicketEpoch      abi.ChainEpoch
+	TicketExpiration abi.ChainEpoch
 }
 
 func (evt SectorPreCommit1) apply(state *SectorInfo) {
 	state.PreCommit1Out = evt.PreCommit1Out
 	state.TicketEpoch = evt.TicketEpoch
 	state.TicketValue = evt.TicketValue
+	state.TicketExpiration = evt.TicketExpiration
 	state.PreCommit2Fails = 0
 }
 
@@ -168,13 +170,15 @@ func (evt SectorPreCommitted) apply(state *SectorInfo) {
 }
 
 type SectorSeedReady struct {
-	SeedValue abi.InteractiveSealRandomness
-	SeedEpoch abi.ChainEpoch
+	SeedValue      abi.InteractiveSealRandomness
+	SeedEpoch      abi.ChainEpoch
+	SeedExpiration abi.ChainEpoch
 }
 
 func (evt SectorSeedReady) apply(state *SectorInfo) {
 	state.SeedEpoch = evt.SeedEpoch
 	state.SeedValue = evt.SeedValue
+	state.SeedExpiration = evt.SeedExpiration
 }
 
 type SectorComputeProofFailed struct{ error }
@@ -196,6 +200,11 @@ type SectorDealsExpired struct{ error }
 func (evt SectorDealsExpired) FormatError(xerrors.Printer) (next error) { return evt.error }
 func (evt SectorDealsExpired) apply(*SectorInfo)                        {}
 
+type SectorPreCommitExpired struct{ error }
+
+func (evt SectorPreCommitExpired) FormatError(xerrors.Printer) (next error) { return evt.error }
+func (evt SectorPreCommitExpired) apply(*SectorInfo)                        {}
+
 type SectorCommitted struct {
 	Proof []byte
 }
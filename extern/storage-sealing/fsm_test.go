@@ -34,6 +34,7 @@ func TestHappyPath(t *testing.T) {
 			maddr: ma,
 			stats: SectorStats{
 				bySector: map[abi.SectorID]statSectorState{},
+				byState:  map[abi.SectorID]SectorState{},
 			},
 			notifee: func(before, after SectorInfo) {
 				notif = append(notif, struct{ before, after SectorInfo }{before, after})
@@ -91,6 +92,7 @@ func TestSeedRevert(t *testing.T) {
 			maddr: ma,
 			stats: SectorStats{
 				bySector: map[abi.SectorID]statSectorState{},
+				byState:  map[abi.SectorID]SectorState{},
 			},
 		},
 		t:     t,
@@ -141,6 +143,7 @@ func TestPlanCommittingHandlesSectorCommitFailed(t *testing.T) {
 			maddr: ma,
 			stats: SectorStats{
 				bySector: map[abi.SectorID]statSectorState{},
+				byState:  map[abi.SectorID]SectorState{},
 			},
 		},
 		t:     t,
@@ -154,3 +157,35 @@ func TestPlanCommittingHandlesSectorCommitFailed(t *testing.T) {
 
 	require.Equal(t, CommitFailed, m.state.State)
 }
+
+func TestPreCommitExpired(t *testing.T) {
+	ma, _ := address.NewIDAddress(55151)
+	m := test{
+		s: &Sealing{
+			maddr: ma,
+			stats: SectorStats{
+				bySector: map[abi.SectorID]statSectorState{},
+				byState:  map[abi.SectorID]SectorState{},
+			},
+		},
+		t:     t,
+		state: &SectorInfo{State: CommitFailed},
+	}
+
+	m.planSingle(SectorPreCommitExpired{})
+	require.Equal(m.t, PreCommitExpired, m.state.State)
+
+	// the sector can't go back to precommitting
+	_, _, err := m.s.plan([]statemachine.Event{{User: SectorChainPreCommitFailed{}}}, m.state)
+	require.Error(t, err)
+
+	m.planSingle(SectorRemove{})
+	require.Equal(m.t, Removing, m.state.State)
+
+	m.planSingle(SectorRemoved{})
+	require.Equal(m.t, Removed, m.state.State)
+
+	m.state = &SectorInfo{State: WaitSeed}
+	m.planSingle(SectorPreCommitExpired{})
+	require.Equal(m.t, PreCommitExpired, m.state.State)
+}
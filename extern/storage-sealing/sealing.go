@@ -130,6 +130,7 @@ func New(api SealingAPI, fc FeeConfig, events Events, maddr address.Address, ds
 
 		stats: SectorStats{
 			bySector: map[abi.SectorID]statSectorState{},
+			byState:  map[abi.SectorID]SectorState{},
 		},
 	}
 
@@ -26,6 +26,7 @@ var ExistSectorStateList = map[SectorState]struct{}{
 	FinalizeFailed:       {},
 	DealsExpired:         {},
 	RecoverDealIDs:       {},
+	PreCommitExpired:     {},
 	Faulty:               {},
 	FaultReported:        {},
 	FaultedFinal:         {},
@@ -62,6 +63,7 @@ const (
 	FinalizeFailed       SectorState = "FinalizeFailed"
 	DealsExpired         SectorState = "DealsExpired"
 	RecoverDealIDs       SectorState = "RecoverDealIDs"
+	PreCommitExpired     SectorState = "PreCommitExpired" // the seed wasn't proven in time, the sector can't be committed
 
 	Faulty        SectorState = "Faulty"        // sector is corrupted or gone for some reason
 	FaultReported SectorState = "FaultReported" // sector has been declared as a fault on chain
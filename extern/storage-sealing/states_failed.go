@@ -67,6 +67,13 @@ func (m *Sealing) handleSealPrecommit2Failed(ctx statemachine.Context, sector Se
 		return ctx.Send(SectorRetrySealPreCommit1{})
 	}
 
+	// the PreCommit1 output can't be used with an expired ticket, get a new
+	// ticket instead of failing when precommitting
+	if _, height, err := m.api.ChainHead(ctx.Context()); err == nil && sector.TicketExpiration != 0 && height > sector.TicketExpiration {
+		log.Warnf("sector %d ticket expired at %d, head %d, redoing PreCommit1 with a new ticket", sector.SectorNumber, sector.TicketExpiration, height)
+		return ctx.Send(SectorRetrySealPreCommit1{})
+	}
+
 	return ctx.Send(SectorRetrySealPreCommit2{})
 }
 
@@ -187,7 +194,7 @@ func (m *Sealing) handleCommitFailed(ctx statemachine.Context, sector SectorInfo
 		}
 	}
 
-	if err := m.checkCommit(ctx.Context(), sector, sector.Proof, tok); err != nil {
+	if err := m.checkCommit(ctx.Context(), sector, sector.Proof, tok, height); err != nil {
 		switch err.(type) {
 		case *ErrApi:
 			log.Errorf("handleCommitFailed: api error, not proceeding: %+v", err)
@@ -221,6 +228,8 @@ func (m *Sealing) handleCommitFailed(ctx statemachine.Context, sector SectorInfo
 			}
 
 			return ctx.Send(SectorRetryCommitWait{})
+		case *ErrExpiredPreCommit:
+			return ctx.Send(SectorPreCommitExpired{xerrors.Errorf("precommit expired: %w", err)})
 		default:
 			return xerrors.Errorf("checkCommit sanity check error (%T): %w", err, err)
 		}
@@ -274,6 +283,13 @@ func (m *Sealing) handleDealsExpired(ctx statemachine.Context, sector SectorInfo
 	return ctx.Send(SectorRemove{})
 }
 
+func (m *Sealing) handlePreCommitExpired(ctx statemachine.Context, sector SectorInfo) error {
+	// The sector number stays allocated once precommitted, so the sector
+	// can't be precommitted again, and its deposit is burnt
+	log.Errorf("sector %d precommit expired at %d, removing the sector", sector.SectorNumber, sector.SeedExpiration)
+	return ctx.Send(SectorRemove{})
+}
+
 func (m *Sealing) handleRecoverDealIDs(ctx statemachine.Context, sector SectorInfo) error {
 	tok, height, err := m.api.ChainHead(ctx.Context())
 	if err != nil {
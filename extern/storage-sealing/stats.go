@@ -19,6 +19,7 @@ type SectorStats struct {
 	lk sync.Mutex
 
 	bySector map[abi.SectorID]statSectorState
+	byState  map[abi.SectorID]SectorState
 	totals   [nsst]uint64
 }
 
@@ -33,6 +34,7 @@ func (ss *SectorStats) updateSector(id abi.SectorID, st SectorState) {
 
 	sst := toStatState(st)
 	ss.bySector[id] = sst
+	ss.byState[id] = st
 	ss.totals[sst]++
 }
 
@@ -43,3 +45,23 @@ func (ss *SectorStats) curSealing() uint64 {
 
 	return ss.totals[sstSealing] + ss.totals[sstFailed]
 }
+
+// return the number of sectors other than the given one in one of the states
+func (ss *SectorStats) curInStates(except abi.SectorID, states ...SectorState) uint64 {
+	ss.lk.Lock()
+	defer ss.lk.Unlock()
+
+	var n uint64
+	for id, st := range ss.byState {
+		if id == except {
+			continue
+		}
+		for _, s := range states {
+			if st == s {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
@@ -73,9 +73,10 @@ type SectorInfo struct {
 	Pieces []Piece
 
 	// PreCommit1
-	TicketValue   abi.SealRandomness
-	TicketEpoch   abi.ChainEpoch
-	PreCommit1Out storage.PreCommit1Out
+	TicketValue      abi.SealRandomness
+	TicketEpoch      abi.ChainEpoch
+	TicketExpiration abi.ChainEpoch // last epoch the precommit using the ticket can land at
+	PreCommit1Out    storage.PreCommit1Out
 
 	// PreCommit2
 	CommD *cid.Cid
@@ -90,8 +91,9 @@ type SectorInfo struct {
 	PreCommit2Fails uint64
 
 	// WaitSeed
-	SeedValue abi.InteractiveSealRandomness
-	SeedEpoch abi.ChainEpoch
+	SeedValue      abi.InteractiveSealRandomness
+	SeedEpoch      abi.ChainEpoch
+	SeedExpiration abi.ChainEpoch // last epoch the prove commit can land at, after which the precommit expires
 
 	// Committing
 	CommitMessage *cid.Cid
@@ -39,6 +39,7 @@ func TestSectorInfoSelialization(t *testing.T) {
 		Proof:            nil,
 		TicketValue:      []byte{87, 78, 7, 87},
 		TicketEpoch:      345,
+		TicketExpiration: 4275,
 		PreCommitMessage: nil,
 		SeedValue:        []byte{},
 		SeedEpoch:        0,
@@ -64,6 +65,7 @@ func TestSectorInfoSelialization(t *testing.T) {
 	assert.Equal(t, si.CommD, si2.CommD)
 	assert.Equal(t, si.TicketValue, si2.TicketValue)
 	assert.Equal(t, si.TicketEpoch, si2.TicketEpoch)
+	assert.Equal(t, si.TicketExpiration, si2.TicketExpiration)
 
 	assert.Equal(t, si, si2)
 
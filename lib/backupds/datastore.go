@@ -30,6 +30,19 @@ func Wrap(child datastore.Batching) *Datastore {
 // Writes a datastore dump into the provided writer as
 // [array(*) of [key, value] tuples, checksum]
 func (d *Datastore) Backup(out io.Writer) error {
+	d.backupLk.Lock()
+	defer d.backupLk.Unlock()
+
+	log.Info("Starting datastore backup")
+	defer log.Info("Datastore backup done")
+
+	return WriteBackup(out, d.child, query.Query{})
+}
+
+// WriteBackup writes the entries of ds matching the query into the provided
+// writer, in the same format as Datastore.Backup. The query must not be
+// keys-only.
+func WriteBackup(out io.Writer, ds datastore.Read, q query.Query) error {
 	scratch := make([]byte, 9)
 
 	if err := cbg.WriteMajorTypeHeaderBuf(scratch, out, cbg.MajArray, 2); err != nil {
@@ -46,13 +59,7 @@ func (d *Datastore) Backup(out io.Writer) error {
 			return xerrors.Errorf("writing header: %w", err)
 		}
 
-		d.backupLk.Lock()
-		defer d.backupLk.Unlock()
-
-		log.Info("Starting datastore backup")
-		defer log.Info("Datastore backup done")
-
-		qr, err := d.child.Query(query.Query{})
+		qr, err := ds.Query(q)
 		if err != nil {
 			return xerrors.Errorf("query: %w", err)
 		}
@@ -64,6 +71,10 @@ func (d *Datastore) Backup(out io.Writer) error {
 		}()
 
 		for result := range qr.Next() {
+			if result.Error != nil {
+				return xerrors.Errorf("query result: %w", result.Error)
+			}
+
 			if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajArray, 2); err != nil {
 				return xerrors.Errorf("writing tuple header: %w", err)
 			}
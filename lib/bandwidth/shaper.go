@@ -0,0 +1,184 @@
+// Package bandwidth implements shared bandwidth caps on streams, which can
+// change with the time of day.
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/build"
+)
+
+// minBurst is the smallest chunk a stream is shaped at, so that low caps
+// don't turn every read into a syscall.
+const minBurst = 16 << 10
+
+// Window replaces the default cap between two times of day. A window with
+// Start after End wraps around midnight.
+type Window struct {
+	Start TimeOfDay
+	End   TimeOfDay
+	// Limit is the cap in bytes per second during the window; 0 = no limit
+	Limit int64
+}
+
+// TimeOfDay is an offset from local midnight.
+type TimeOfDay time.Duration
+
+// ParseTimeOfDay parses a time of day formatted as "15:04".
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, xerrors.Errorf("parsing time of day %q: %w", s, err)
+	}
+	return TimeOfDay(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
+}
+
+func (w Window) contains(tod TimeOfDay) bool {
+	if w.Start <= w.End {
+		return tod >= w.Start && tod < w.End
+	}
+	return tod >= w.Start || tod < w.End
+}
+
+// Shaper caps the combined throughput of all the streams it wraps.
+type Shaper struct {
+	def     int64
+	windows []Window
+
+	lk      sync.Mutex
+	current int64
+	limiter *rate.Limiter
+}
+
+// NewShaper creates a shaper with the default cap in bytes per second (0 = no
+// limit), and windows overriding it at certain times of day. The first
+// matching window applies.
+func NewShaper(limit int64, windows []Window) *Shaper {
+	s := &Shaper{
+		def:     limit,
+		windows: windows,
+		current: -1,
+	}
+	s.update()
+	return s
+}
+
+// LimitAt returns the cap in bytes per second at the given time.
+func (s *Shaper) LimitAt(t time.Time) int64 {
+	y, m, d := t.Date()
+	tod := TimeOfDay(t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location())))
+
+	for _, w := range s.windows {
+		if w.contains(tod) {
+			return w.Limit
+		}
+	}
+	return s.def
+}
+
+// update applies the cap for the current time, returning the limiter, or nil
+// if there's no cap.
+func (s *Shaper) update() *rate.Limiter {
+	limit := s.LimitAt(build.Clock.Now())
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if limit == s.current {
+		return s.limiter
+	}
+	s.current = limit
+
+	if limit <= 0 {
+		s.limiter = nil
+		return nil
+	}
+
+	burst := int(limit)
+	if burst < minBurst {
+		burst = minBurst
+	}
+	// streams waiting on the old limiter finish their wait there
+	s.limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	return s.limiter
+}
+
+// Reader wraps the reader so that reads count towards the cap. The context
+// bounds the time spent waiting for bandwidth.
+func (s *Shaper) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if s == nil {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, s: s}
+}
+
+// Writer wraps the writer so that writes count towards the cap.
+func (s *Shaper) Writer(ctx context.Context, w io.Writer) io.Writer {
+	if s == nil {
+		return w
+	}
+	return &writer{ctx: ctx, w: w, s: s}
+}
+
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+	s   *Shaper
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	l := r.s.update()
+	if l == nil {
+		return r.r.Read(p)
+	}
+
+	if len(p) > l.Burst() {
+		p = p[:l.Burst()]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := l.WaitN(r.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	ctx context.Context
+	w   io.Writer
+	s   *Shaper
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		l := w.s.update()
+		if l == nil {
+			n, err := w.w.Write(p)
+			return written + n, err
+		}
+
+		chunk := p
+		if len(chunk) > l.Burst() {
+			chunk = chunk[:l.Burst()]
+		}
+		if err := l.WaitN(w.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
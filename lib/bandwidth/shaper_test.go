@@ -0,0 +1,89 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	tod, err := ParseTimeOfDay("07:30")
+	require.NoError(t, err)
+	require.Equal(t, TimeOfDay(7*time.Hour+30*time.Minute), tod)
+
+	_, err = ParseTimeOfDay("25:00")
+	require.Error(t, err)
+	_, err = ParseTimeOfDay("noon")
+	require.Error(t, err)
+}
+
+func TestLimitAt(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2020, 10, 1, h, m, 0, 0, time.Local)
+	}
+
+	s := NewShaper(1000, []Window{
+		{Start: TimeOfDay(9 * time.Hour), End: TimeOfDay(17 * time.Hour), Limit: 100},
+		// wraps around midnight
+		{Start: TimeOfDay(22 * time.Hour), End: TimeOfDay(6 * time.Hour), Limit: 0},
+		// shadowed by the first window
+		{Start: TimeOfDay(12 * time.Hour), End: TimeOfDay(13 * time.Hour), Limit: 5},
+	})
+
+	require.Equal(t, int64(1000), s.LimitAt(at(8, 59)))
+	require.Equal(t, int64(100), s.LimitAt(at(9, 0)))
+	require.Equal(t, int64(100), s.LimitAt(at(12, 30)))
+	require.Equal(t, int64(1000), s.LimitAt(at(17, 0)))
+	require.Equal(t, int64(0), s.LimitAt(at(23, 0)))
+	require.Equal(t, int64(0), s.LimitAt(at(0, 0)))
+	require.Equal(t, int64(0), s.LimitAt(at(5, 59)))
+	require.Equal(t, int64(1000), s.LimitAt(at(6, 0)))
+}
+
+func TestNilShaper(t *testing.T) {
+	var s *Shaper
+	data := bytes.Repeat([]byte{1}, 1<<20)
+
+	out, err := ioutil.ReadAll(s.Reader(context.Background(), bytes.NewReader(data)))
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+
+	var buf bytes.Buffer
+	_, err = s.Writer(context.Background(), &buf).Write(data)
+	require.NoError(t, err)
+	require.Equal(t, data, buf.Bytes())
+}
+
+func TestShaperPassesData(t *testing.T) {
+	// a cap well above the amount written, so that the test doesn't wait
+	s := NewShaper(64<<20, nil)
+	data := bytes.Repeat([]byte{2}, 3*minBurst+7)
+
+	var buf bytes.Buffer
+	n, err := s.Writer(context.Background(), &buf).Write(data)
+	require.NoError(t, err)
+	require.Equal(t, len(data), n)
+	require.Equal(t, data, buf.Bytes())
+
+	out, err := ioutil.ReadAll(s.Reader(context.Background(), bytes.NewReader(data)))
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+}
+
+func TestShaperContextCancel(t *testing.T) {
+	// the first burst is free, waiting for the second one outlasts the context
+	s := NewShaper(1, nil)
+	data := bytes.Repeat([]byte{3}, 2*minBurst)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var buf bytes.Buffer
+	n, err := s.Writer(ctx, &buf).Write(data)
+	require.Error(t, err)
+	require.Equal(t, minBurst, n)
+}
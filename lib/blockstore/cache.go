@@ -0,0 +1,296 @@
+package blockstore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"go.opencensus.io/stats"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+// DefaultBlockCacheSize is the default capacity of the chain blockstore cache
+// in bytes.
+const DefaultBlockCacheSize = 512 << 20
+
+// BlockCacheStats is a snapshot of the counters of a BlockCache.
+type BlockCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+
+	// Entries and Bytes describe the cached blocks
+	Entries int
+	Bytes   uint64
+	// Capacity is the maximum size of the cached blocks in bytes
+	Capacity uint64
+}
+
+// BlockCache is a read-through cache of block data in front of a blockstore,
+// bounded by the total size of the cached blocks.
+//
+// It uses the 2Q replacement policy, so that a scan over many blocks which
+// are read only once (e.g. a chain export or a state walk) doesn't evict the
+// blocks that are read repeatedly: blocks first enter a small FIFO queue, and
+// are only promoted to the main LRU queue when they're read again after
+// falling out of it.
+type BlockCache struct {
+	bs Blockstore
+
+	lk      sync.Mutex
+	entries map[cid.Cid]*list.Element
+
+	// in is the FIFO queue of recently added blocks, main the LRU queue of
+	// frequently read blocks, and ghosts remembers the keys recently evicted
+	// from in
+	in, main, ghosts *list.List
+	inBytes          uint64
+	mainBytes        uint64
+	ghostBytes       uint64
+
+	capacity uint64
+
+	hits, misses, evictions uint64
+}
+
+type cacheEntry struct {
+	blk   blocks.Block
+	key   cid.Cid
+	size  uint64
+	queue *list.List
+}
+
+var _ Blockstore = (*BlockCache)(nil)
+
+// NewBlockCache wraps the blockstore in a cache holding up to capacity bytes
+// of block data. Identity CIDs are served by an IdStore and never cached.
+func NewBlockCache(bs Blockstore, capacity uint64) *BlockCache {
+	return &BlockCache{
+		bs:       WrapIDStore(bs),
+		entries:  map[cid.Cid]*list.Element{},
+		in:       list.New(),
+		main:     list.New(),
+		ghosts:   list.New(),
+		capacity: capacity,
+	}
+}
+
+// Stats returns the current cache counters.
+func (c *BlockCache) Stats() BlockCacheStats {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	return BlockCacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   c.in.Len() + c.main.Len(),
+		Bytes:     c.inBytes + c.mainBytes,
+		Capacity:  c.capacity,
+	}
+}
+
+func cacheable(k cid.Cid) bool {
+	return k.Prefix().MhType != mh.IDENTITY
+}
+
+// lookup returns the cached block, marking it as used.
+func (c *BlockCache) lookup(k cid.Cid) (blocks.Block, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok || el.Value.(*cacheEntry).queue == c.ghosts {
+		c.misses++
+		stats.Record(context.TODO(), metrics.BlockstoreCacheMiss.M(1))
+		return nil, false
+	}
+
+	e := el.Value.(*cacheEntry)
+	if e.queue == c.main {
+		c.main.MoveToFront(el)
+	}
+
+	c.hits++
+	stats.Record(context.TODO(), metrics.BlockstoreCacheHit.M(1))
+	return e.blk, true
+}
+
+// add caches the block. Blocks which were recently evicted from the FIFO
+// queue go straight to the main queue.
+func (c *BlockCache) add(b blocks.Block) {
+	size := uint64(len(b.RawData()))
+	if size > c.capacity/4 {
+		return
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	k := b.Cid()
+	queue := c.in
+	if el, ok := c.entries[k]; ok {
+		e := el.Value.(*cacheEntry)
+		if e.queue != c.ghosts {
+			return
+		}
+		c.ghosts.Remove(el)
+		c.ghostBytes -= e.size
+		queue = c.main
+	}
+
+	c.entries[k] = queue.PushFront(&cacheEntry{blk: b, key: k, size: size, queue: queue})
+	if queue == c.main {
+		c.mainBytes += size
+	} else {
+		c.inBytes += size
+	}
+
+	c.evict()
+}
+
+func (c *BlockCache) evict() {
+	var evicted int64
+	for c.inBytes+c.mainBytes > c.capacity {
+		evicted++
+
+		// keep the FIFO queue at a quarter of the capacity
+		if c.inBytes > c.capacity/4 || c.main.Len() == 0 {
+			el := c.in.Back()
+			e := el.Value.(*cacheEntry)
+			c.in.Remove(el)
+			c.inBytes -= e.size
+
+			e.blk = nil
+			e.queue = c.ghosts
+			c.entries[e.key] = c.ghosts.PushFront(e)
+			c.ghostBytes += e.size
+			continue
+		}
+
+		el := c.main.Back()
+		e := el.Value.(*cacheEntry)
+		c.main.Remove(el)
+		c.mainBytes -= e.size
+		delete(c.entries, e.key)
+	}
+
+	// remember evictions worth up to half of the capacity
+	for c.ghostBytes > c.capacity/2 {
+		el := c.ghosts.Back()
+		e := el.Value.(*cacheEntry)
+		c.ghosts.Remove(el)
+		c.ghostBytes -= e.size
+		delete(c.entries, e.key)
+	}
+
+	if evicted > 0 {
+		c.evictions += uint64(evicted)
+		stats.Record(context.TODO(), metrics.BlockstoreCacheEviction.M(evicted))
+	}
+}
+
+func (c *BlockCache) remove(k cid.Cid) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok {
+		return
+	}
+
+	e := el.Value.(*cacheEntry)
+	e.queue.Remove(el)
+	switch e.queue {
+	case c.in:
+		c.inBytes -= e.size
+	case c.main:
+		c.mainBytes -= e.size
+	case c.ghosts:
+		c.ghostBytes -= e.size
+	}
+	delete(c.entries, k)
+}
+
+func (c *BlockCache) Get(k cid.Cid) (blocks.Block, error) {
+	if !cacheable(k) {
+		return c.bs.Get(k)
+	}
+
+	if b, ok := c.lookup(k); ok {
+		return b, nil
+	}
+
+	b, err := c.bs.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	c.add(b)
+	return b, nil
+}
+
+func (c *BlockCache) Has(k cid.Cid) (bool, error) {
+	if cacheable(k) {
+		c.lk.Lock()
+		el, ok := c.entries[k]
+		cached := ok && el.Value.(*cacheEntry).queue != c.ghosts
+		c.lk.Unlock()
+		if cached {
+			return true, nil
+		}
+	}
+	return c.bs.Has(k)
+}
+
+func (c *BlockCache) GetSize(k cid.Cid) (int, error) {
+	if cacheable(k) {
+		c.lk.Lock()
+		el, ok := c.entries[k]
+		if ok && el.Value.(*cacheEntry).queue != c.ghosts {
+			size := el.Value.(*cacheEntry).size
+			c.lk.Unlock()
+			return int(size), nil
+		}
+		c.lk.Unlock()
+	}
+	return c.bs.GetSize(k)
+}
+
+func (c *BlockCache) Put(b blocks.Block) error {
+	if err := c.bs.Put(b); err != nil {
+		return err
+	}
+	if cacheable(b.Cid()) {
+		c.add(b)
+	}
+	return nil
+}
+
+func (c *BlockCache) PutMany(bs []blocks.Block) error {
+	if err := c.bs.PutMany(bs); err != nil {
+		return err
+	}
+	for _, b := range bs {
+		if cacheable(b.Cid()) {
+			c.add(b)
+		}
+	}
+	return nil
+}
+
+func (c *BlockCache) DeleteBlock(k cid.Cid) error {
+	c.remove(k)
+	return c.bs.DeleteBlock(k)
+}
+
+func (c *BlockCache) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	return c.bs.AllKeysChan(ctx)
+}
+
+func (c *BlockCache) HashOnRead(enabled bool) {
+	c.bs.HashOnRead(enabled)
+}
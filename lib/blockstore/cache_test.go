@@ -0,0 +1,80 @@
+package blockstore
+
+import (
+	"fmt"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/stretchr/testify/require"
+)
+
+func mkBlocks(n, size int) []blocks.Block {
+	out := make([]blocks.Block, n)
+	for i := range out {
+		data := make([]byte, size)
+		copy(data, fmt.Sprintf("block %d", i))
+		out[i] = blocks.NewBlock(data)
+	}
+	return out
+}
+
+func TestBlockCache(t *testing.T) {
+	bs := NewTemporarySync()
+	blks := mkBlocks(100, 100)
+	for _, b := range blks {
+		require.NoError(t, bs.Put(b))
+	}
+
+	c := NewBlockCache(bs, 1000)
+
+	get := func(b blocks.Block) {
+		got, err := c.Get(b.Cid())
+		require.NoError(t, err)
+		require.Equal(t, b.RawData(), got.RawData())
+	}
+
+	get(blks[0])
+	get(blks[0])
+	st := c.Stats()
+	require.Equal(t, uint64(1), st.Hits)
+	require.Equal(t, uint64(1), st.Misses)
+	require.Equal(t, 1, st.Entries)
+	require.Equal(t, uint64(100), st.Bytes)
+
+	// the cache never exceeds its capacity
+	for _, b := range blks {
+		get(b)
+		require.LessOrEqual(t, c.Stats().Bytes, uint64(1000))
+	}
+	require.NotZero(t, c.Stats().Evictions)
+
+	// blocks read again after falling out of the FIFO queue are promoted,
+	// and survive a scan over blocks which are only read once
+	c = NewBlockCache(bs, 1000)
+	hot := blks[:5]
+	for _, b := range hot {
+		get(b)
+	}
+	for _, b := range blks[5:15] {
+		get(b)
+	}
+	for _, b := range hot {
+		get(b)
+	}
+	for _, b := range blks[50:] {
+		get(b)
+	}
+	hits := c.Stats().Hits
+	for _, b := range hot {
+		get(b)
+	}
+	require.Equal(t, hits+uint64(len(hot)), c.Stats().Hits)
+
+	// deleted blocks are dropped from the cache
+	require.NoError(t, c.DeleteBlock(hot[0].Cid()))
+	has, err := c.Has(hot[0].Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+	_, err = c.Get(hot[0].Cid())
+	require.Equal(t, ErrNotFound, err)
+}
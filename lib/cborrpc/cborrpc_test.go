@@ -0,0 +1,338 @@
+package cborrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"go.opencensus.io/trace"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type Deal struct {
+	Client   address.Address
+	Worker   address.Address // left undefined
+	Price    types.BigInt
+	Start    abi.ChainEpoch
+	Label    string `json:"label"`
+	Pieces   []cid.Cid
+	Verified bool
+	Ratio    float64
+	Meta     interface{}
+	Next     *Deal
+}
+
+type testHandler struct{}
+
+func (h *testHandler) Deals(ctx context.Context, start abi.DealID, msg *types.Message) (map[string]Deal, error) {
+	return map[string]Deal{
+		"1": {
+			Client:   msg.From,
+			Price:    types.NewInt(uint64(start)),
+			Start:    -5,
+			Label:    "deal",
+			Pieces:   []cid.Cid{msg.Cid()},
+			Verified: true,
+			Ratio:    0.5,
+			Meta:     map[string]interface{}{"a": "b"},
+			Next:     &Deal{Client: msg.To, Price: types.NewInt(0)},
+		},
+	}, nil
+}
+
+func (h *testHandler) Fail(ctx context.Context) error {
+	return xerrors.New("failed")
+}
+
+func (h *testHandler) Admin(ctx context.Context) (string, error) {
+	if !auth.HasPerm(ctx, []auth.Permission{"read"}, "admin") {
+		return "", xerrors.New("missing permission")
+	}
+	return "ok", nil
+}
+
+func (h *testHandler) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (h *testHandler) TraceID(ctx context.Context) (string, error) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return "", nil
+	}
+	return span.SpanContext().TraceID.String(), nil
+}
+
+// Sub sends n values, or values until the context is done when n is negative.
+func (h *testHandler) Sub(ctx context.Context, n int) (<-chan int, error) {
+	if n == 0 {
+		return nil, xerrors.New("no values")
+	}
+
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 0; n < 0 || i < n; i++ {
+			select {
+			case ch <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+type valueKey struct{}
+
+func (h *testHandler) Value(ctx context.Context) (string, error) {
+	v, _ := ctx.Value(valueKey{}).(string)
+	return v, nil
+}
+
+type testClient struct {
+	Deals   func(ctx context.Context, start abi.DealID, msg *types.Message) (map[string]Deal, error)
+	Fail    func(ctx context.Context) error
+	Admin   func(ctx context.Context) (string, error)
+	Wait    func(ctx context.Context) error
+	TraceID func(ctx context.Context) (string, error)
+	Sub     func(ctx context.Context, n int) (<-chan int, error)
+	Value   func(ctx context.Context) (string, error)
+}
+
+func newTestClient(t *testing.T, token string, mws ...ConnMiddleware) (*testClient, ClientCloser) {
+	srv := NewServer(func(ctx context.Context, token string) ([]auth.Permission, error) {
+		if token != "secret" {
+			return nil, xerrors.New("bad token")
+		}
+		return []auth.Permission{"read", "admin"}, nil
+	})
+	srv.Register("Test", &testHandler{})
+	for _, mw := range mws {
+		srv.Use(mw)
+	}
+
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lst.Close() })
+	go srv.Serve(lst) // nolint:errcheck
+
+	conn, err := net.Dial("tcp", lst.Addr().String())
+	require.NoError(t, err)
+
+	var c testClient
+	closer, err := NewMergeClient(context.Background(), conn, "Test", []interface{}{&c}, token)
+	require.NoError(t, err)
+	return &c, closer
+}
+
+func TestCalls(t *testing.T) {
+	ctx := context.Background()
+	c, closer := newTestClient(t, "")
+	defer closer()
+
+	from, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(101)
+	require.NoError(t, err)
+	msg := &types.Message{
+		From:       from,
+		To:         to,
+		Value:      types.NewInt(0),
+		GasFeeCap:  types.NewInt(0),
+		GasPremium: types.NewInt(0),
+	}
+
+	deals, err := c.Deals(ctx, 7, msg)
+	require.NoError(t, err)
+	require.Equal(t, map[string]Deal{
+		"1": {
+			Client:   from,
+			Price:    types.NewInt(7),
+			Start:    -5,
+			Label:    "deal",
+			Pieces:   []cid.Cid{msg.Cid()},
+			Verified: true,
+			Ratio:    0.5,
+			Meta:     map[string]interface{}{"a": "b"},
+			Next:     &Deal{Client: to, Price: types.NewInt(0)},
+		},
+	}, deals)
+
+	require.EqualError(t, c.Fail(ctx), "failed")
+
+	// no token, so the default permissions
+	_, err = c.Admin(ctx)
+	require.Error(t, err)
+
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, c.Wait(cctx))
+
+	// the connection is still usable after the cancelled call
+	require.EqualError(t, c.Fail(ctx), "failed")
+
+	closer()
+	require.Equal(t, ErrClosed, c.Fail(ctx))
+}
+
+func TestSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	c, closer := newTestClient(t, "")
+	defer closer()
+
+	_, err := c.Sub(ctx, 0)
+	require.EqualError(t, err, "no values")
+
+	// the channel is closed after the values sent by the handler
+	ch, err := c.Sub(ctx, 3)
+	require.NoError(t, err)
+	var got []int
+	for v := range ch {
+		got = append(got, v)
+	}
+	require.Equal(t, []int{0, 1, 2}, got)
+
+	// cancelling the call closes the channel, and the handler's
+	cctx, cancel := context.WithCancel(ctx)
+	ch, err = c.Sub(cctx, -1)
+	require.NoError(t, err)
+	require.Equal(t, 0, <-ch)
+	require.Equal(t, 1, <-ch)
+	cancel()
+	for range ch {
+	}
+	require.EqualError(t, c.Fail(ctx), "failed")
+
+	// closing the client closes the channels
+	ch, err = c.Sub(ctx, -1)
+	require.NoError(t, err)
+	require.Equal(t, 0, <-ch)
+	closer()
+	for range ch {
+	}
+}
+
+func TestToken(t *testing.T) {
+	ctx := context.Background()
+
+	c, closer := newTestClient(t, "secret")
+	defer closer()
+	res, err := c.Admin(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "ok", res)
+
+	// the server closes the connections with invalid tokens
+	c, closer = newTestClient(t, "wrong")
+	defer closer()
+	_, err = c.Admin(ctx)
+	require.Error(t, err)
+}
+
+func TestSpanContext(t *testing.T) {
+	c, closer := newTestClient(t, "")
+	defer closer()
+
+	id, err := c.TraceID(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, id)
+
+	// the calls are served in the trace of the caller
+	ctx, span := trace.StartSpan(context.Background(), "test")
+	defer span.End()
+	id, err = c.TraceID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, span.SpanContext().TraceID.String(), id)
+}
+
+type countingConn struct {
+	net.Conn
+	written int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(&c.written, int64(n))
+	return n, err
+}
+
+func TestMiddlewares(t *testing.T) {
+	var lk sync.Mutex
+	var cc *countingConn
+	var tokens []string
+	c, closer := newTestClient(t, "secret",
+		func(ctx context.Context, conn net.Conn, token string, next func(context.Context, net.Conn)) {
+			lk.Lock()
+			tokens = append(tokens, token)
+			lk.Unlock()
+			next(context.WithValue(ctx, valueKey{}, "outer"), conn)
+		},
+		func(ctx context.Context, conn net.Conn, token string, next func(context.Context, net.Conn)) {
+			counted := &countingConn{Conn: conn}
+			lk.Lock()
+			tokens = append(tokens, token)
+			cc = counted
+			lk.Unlock()
+
+			v := ctx.Value(valueKey{}).(string)
+			next(context.WithValue(ctx, valueKey{}, v+",inner"), counted)
+		})
+	defer closer()
+
+	v, err := c.Value(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "outer,inner", v)
+
+	lk.Lock()
+	defer lk.Unlock()
+	require.Equal(t, []string{"secret", "secret"}, tokens)
+	// the calls are served over the connection given by the middlewares
+	require.Greater(t, atomic.LoadInt64(&cc.written), int64(0))
+}
+
+func TestReadLimits(t *testing.T) {
+	header := func(maj byte, n uint64) *bytes.Buffer {
+		var buf bytes.Buffer
+		require.NoError(t, newEncoder(&buf).header(maj, n))
+		return &buf
+	}
+
+	// the token is read before authentication, so its length is bounded
+	buf := header(cbg.MajTextString, maxTokenLength+1)
+	_, err := newDecoder(bufio.NewReader(buf)).readStringMax(maxTokenLength)
+	require.Error(t, err)
+
+	// a long value declared without its bytes is an error, not an allocation of
+	// the declared length
+	buf = header(cbg.MajByteString, maxLength)
+	buf.Write(make([]byte, 10))
+	_, err = newDecoder(bufio.NewReader(buf)).readBytes()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// values longer than a chunk are read in full
+	val := make([]byte, 3*readChunk+5)
+	for i := range val {
+		val[i] = byte(i)
+	}
+	buf = header(cbg.MajByteString, uint64(len(val)))
+	buf.Write(val)
+	b, err := newDecoder(bufio.NewReader(buf)).readBytes()
+	require.NoError(t, err)
+	require.Equal(t, val, b)
+}
@@ -0,0 +1,449 @@
+package cborrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"sync"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"golang.org/x/xerrors"
+)
+
+// ErrClosed is returned by the calls made after the connection of the client
+// is closed.
+var ErrClosed = xerrors.New("cbor rpc connection closed")
+
+// ClientCloser closes the connection of a client.
+type ClientCloser func()
+
+type response struct {
+	res reflect.Value
+	err error
+}
+
+type pendingCall struct {
+	result reflect.Type
+	done   chan response
+}
+
+// subscription queues the values received for a call returning a channel,
+// so that reading the responses never waits on the consumer of the channel.
+type subscription struct {
+	elem reflect.Type
+	ch   reflect.Value
+
+	lk     sync.Mutex
+	queue  []reflect.Value
+	closed bool
+	wake   chan struct{}
+}
+
+func newSubscription(t reflect.Type) *subscription {
+	return &subscription{
+		elem: t.Elem(),
+		ch:   reflect.MakeChan(reflect.ChanOf(reflect.BothDir, t.Elem()), 0),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (s *subscription) push(v reflect.Value, closed bool) {
+	s.lk.Lock()
+	if v.IsValid() {
+		s.queue = append(s.queue, v)
+	}
+	s.closed = s.closed || closed
+	s.lk.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+type client struct {
+	conn net.Conn
+
+	wlk sync.Mutex
+	w   *bufio.Writer
+
+	lk      sync.Mutex
+	nextID  uint64
+	pending map[uint64]*pendingCall
+	subs    map[uint64]*subscription
+	closed  chan struct{}
+	err     error
+}
+
+// NewMergeClient fills the func fields of the structs of outs, like the
+// Internal structs of the apistruct package, with calls of the methods
+// namespace.FieldName served on the connection, like NewMergeClient of
+// go-jsonrpc. The client sends the token to the server first; the connection
+// is closed with the closer or when the context is done.
+func NewMergeClient(ctx context.Context, conn net.Conn, namespace string, outs []interface{}, token string) (ClientCloser, error) {
+	c := &client{
+		conn:    conn,
+		w:       bufio.NewWriter(conn),
+		pending: map[uint64]*pendingCall{},
+		subs:    map[uint64]*subscription{},
+		closed:  make(chan struct{}),
+	}
+
+	if err := newEncoder(c.w).writeString(token); err != nil {
+		return nil, xerrors.Errorf("sending token: %w", err)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, xerrors.Errorf("sending token: %w", err)
+	}
+
+	for _, out := range outs {
+		if err := c.fill(namespace, out); err != nil {
+			return nil, err
+		}
+	}
+
+	go c.readResponses()
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.close(ErrClosed)
+		case <-c.closed:
+		}
+	}()
+
+	return func() {
+		c.close(ErrClosed)
+	}, nil
+}
+
+func (c *client) fill(namespace string, out interface{}) error {
+	sv := reflect.ValueOf(out)
+	if sv.Kind() != reflect.Ptr || sv.Elem().Kind() != reflect.Struct {
+		return xerrors.Errorf("expected a pointer to a struct, got %T", out)
+	}
+	sv = sv.Elem()
+
+	for i := 0; i < sv.NumField(); i++ {
+		field := sv.Type().Field(i)
+		if field.Type.Kind() != reflect.Func {
+			continue
+		}
+
+		m, ok := newMethod(reflect.Zero(field.Type))
+		if !ok {
+			return xerrors.Errorf("%s must return an error last", field.Name)
+		}
+		sv.Field(i).Set(reflect.MakeFunc(field.Type, c.makeCall(namespace+"."+field.Name, m)))
+	}
+	return nil
+}
+
+func (c *client) makeCall(name string, m method) func([]reflect.Value) []reflect.Value {
+	results := func(res reflect.Value, err error) []reflect.Value {
+		out := make([]reflect.Value, 0, 2)
+		if m.result != nil {
+			if !res.IsValid() || err != nil {
+				res = reflect.Zero(m.result)
+			}
+			out = append(out, res)
+		}
+		errv := reflect.Zero(errorType)
+		if err != nil {
+			errv = reflect.ValueOf(&err).Elem()
+		}
+		return append(out, errv)
+	}
+
+	return func(args []reflect.Value) []reflect.Value {
+		ctx := context.Background()
+		if m.hasCtx {
+			ctx = args[0].Interface().(context.Context)
+			args = args[1:]
+		}
+
+		if m.channels {
+			sub := newSubscription(m.result)
+			_, err := c.call(ctx, name, nil, args, sub)
+			if err != nil {
+				return results(reflect.Value{}, err)
+			}
+			return results(sub.ch.Convert(m.result), nil)
+		}
+
+		res, err := c.call(ctx, name, m.result, args, nil)
+		return results(res, err)
+	}
+}
+
+// call makes a call, and for the methods returning a channel, forwards the
+// values received to the channel of sub until the server closes it or the
+// context is done.
+func (c *client) call(ctx context.Context, name string, result reflect.Type, args []reflect.Value, sub *subscription) (reflect.Value, error) {
+	pc := &pendingCall{result: result, done: make(chan response, 1)}
+
+	c.lk.Lock()
+	if c.err != nil {
+		c.lk.Unlock()
+		return reflect.Value{}, c.err
+	}
+	id := c.nextID
+	c.nextID++
+	c.pending[id] = pc
+	if sub != nil {
+		// the values can be read before the response is handed to the call
+		c.subs[id] = sub
+	}
+	c.lk.Unlock()
+
+	var buf bytes.Buffer
+	if err := writeRequest(&buf, id, name, args, trace.FromContext(ctx)); err != nil {
+		c.forget(id)
+		return reflect.Value{}, xerrors.Errorf("encoding params of %s: %w", name, err)
+	}
+	if err := c.send(buf.Bytes()); err != nil {
+		c.forget(id)
+		return reflect.Value{}, err
+	}
+
+	select {
+	case resp := <-pc.done:
+		if sub != nil {
+			if resp.err != nil {
+				c.forget(id)
+			} else {
+				go c.runSubscription(ctx, id, sub)
+			}
+		}
+		return resp.res, resp.err
+	case <-ctx.Done():
+		c.cancel(id)
+		return reflect.Value{}, ctx.Err()
+	}
+}
+
+// runSubscription hands the values of the subscription to its channel, in
+// order, and closes the channel once the server closed it, or cancels the
+// call once the context is done.
+func (c *client) runSubscription(ctx context.Context, id uint64, sub *subscription) {
+	defer sub.ch.Close()
+
+	for {
+		sub.lk.Lock()
+		if len(sub.queue) == 0 {
+			closed := sub.closed
+			sub.lk.Unlock()
+			if closed {
+				return
+			}
+
+			select {
+			case <-sub.wake:
+				continue
+			case <-ctx.Done():
+				c.cancel(id)
+				return
+			}
+		}
+		v := sub.queue[0]
+		sub.queue[0] = reflect.Value{}
+		sub.queue = sub.queue[1:]
+		sub.lk.Unlock()
+
+		chosen, _, _ := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectSend, Chan: sub.ch, Send: v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		})
+		if chosen == 1 {
+			c.cancel(id)
+			return
+		}
+	}
+}
+
+// cancel forgets a call, and asks the server to cancel it.
+func (c *client) cancel(id uint64) {
+	c.forget(id)
+
+	var buf bytes.Buffer
+	if err := writeFrameHeader(&buf, 2, frameCancel, id); err == nil {
+		_ = c.send(buf.Bytes())
+	}
+}
+
+// writeRequest writes a request frame, with the span context of the call when
+// it has a span.
+func writeRequest(buf *bytes.Buffer, id uint64, name string, args []reflect.Value, span *trace.Span) error {
+	n := uint64(4)
+	if span != nil {
+		n++
+	}
+
+	e := newEncoder(buf)
+	if err := e.header(cbg.MajArray, n); err != nil {
+		return err
+	}
+	if err := e.header(cbg.MajUnsignedInt, frameRequest); err != nil {
+		return err
+	}
+	if err := e.header(cbg.MajUnsignedInt, id); err != nil {
+		return err
+	}
+	if err := e.writeString(name); err != nil {
+		return err
+	}
+	if err := e.header(cbg.MajArray, uint64(len(args))); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := e.encode(arg); err != nil {
+			return err
+		}
+	}
+	if span != nil {
+		return e.writeBytes(propagation.Binary(span.SpanContext()))
+	}
+	return nil
+}
+
+func (c *client) send(b []byte) error {
+	c.wlk.Lock()
+	defer c.wlk.Unlock()
+
+	if _, err := c.w.Write(b); err != nil {
+		c.close(err)
+		return err
+	}
+	if err := c.w.Flush(); err != nil {
+		c.close(err)
+		return err
+	}
+	return nil
+}
+
+func (c *client) forget(id uint64) {
+	c.lk.Lock()
+	delete(c.pending, id)
+	delete(c.subs, id)
+	c.lk.Unlock()
+}
+
+func (c *client) readResponses() {
+	d := newDecoder(bufio.NewReader(c.conn))
+	for {
+		if err := c.readResponse(d); err != nil {
+			c.close(xerrors.Errorf("reading response: %w", err))
+			return
+		}
+	}
+}
+
+func (c *client) readResponse(d *decoder) error {
+	n, err := d.readLength(cbg.MajArray)
+	if err != nil {
+		return err
+	}
+	var kind, id uint64
+	if err := d.decode(reflect.ValueOf(&kind).Elem()); err != nil {
+		return err
+	}
+	if err := d.decode(reflect.ValueOf(&id).Elem()); err != nil {
+		return err
+	}
+	switch {
+	case kind == frameChanValue && n == 3:
+		return c.readChanValue(d, id)
+	case kind == frameChanClose && n == 2:
+		c.lk.Lock()
+		sub, ok := c.subs[id]
+		delete(c.subs, id)
+		c.lk.Unlock()
+		if ok {
+			sub.push(reflect.Value{}, true)
+		}
+		return nil
+	case kind == frameResponse && n == 4:
+	default:
+		return xerrors.Errorf("unexpected frame %d of %d elements", kind, n)
+	}
+
+	var callErr error
+	if null, err := d.null(); err != nil {
+		return err
+	} else if !null {
+		msg, err := d.readString()
+		if err != nil {
+			return err
+		}
+		callErr = xerrors.New(msg)
+	}
+
+	c.lk.Lock()
+	pc, ok := c.pending[id]
+	delete(c.pending, id)
+	c.lk.Unlock()
+
+	// the calls cancelled since, failed, or returning only an error
+	if !ok || pc.result == nil || callErr != nil {
+		if err := d.skip(); err != nil {
+			return err
+		}
+		if ok {
+			pc.done <- response{err: callErr}
+		}
+		return nil
+	}
+
+	res := reflect.New(pc.result).Elem()
+	if err := d.decode(res); err != nil {
+		err = xerrors.Errorf("decoding result: %w", err)
+		pc.done <- response{err: err}
+		return err
+	}
+	pc.done <- response{res: res, err: callErr}
+	return nil
+}
+
+// readChanValue reads a value sent on the channel of a call, skipping it when
+// the call was cancelled since.
+func (c *client) readChanValue(d *decoder, id uint64) error {
+	c.lk.Lock()
+	sub, ok := c.subs[id]
+	c.lk.Unlock()
+	if !ok {
+		return d.skip()
+	}
+
+	v := reflect.New(sub.elem).Elem()
+	if err := d.decode(v); err != nil {
+		return xerrors.Errorf("decoding channel value: %w", err)
+	}
+	sub.push(v, false)
+	return nil
+}
+
+// close fails the pending calls, and the calls made after, and closes the
+// channels of the calls once their values are consumed.
+func (c *client) close(err error) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.closed)
+	_ = c.conn.Close()
+
+	for id, pc := range c.pending {
+		pc.done <- response{err: err}
+		delete(c.pending, id)
+	}
+	for id, sub := range c.subs {
+		sub.push(reflect.Value{}, true)
+		delete(c.subs, id)
+	}
+}
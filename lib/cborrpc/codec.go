@@ -0,0 +1,674 @@
+package cborrpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+)
+
+// maxLength bounds the length of the strings, byte strings, arrays and maps
+// read from the connections, and maxPrealloc the number of elements
+// allocated before they are read. Longer strings and byte strings are read in
+// chunks of readChunk bytes.
+const (
+	maxLength   = 1 << 30
+	maxPrealloc = 1 << 12
+	readChunk   = 1 << 16
+)
+
+const (
+	cborFalse   = 0xf4
+	cborTrue    = 0xf5
+	cborNull    = 0xf6
+	cborFloat64 = 0xfb
+)
+
+var (
+	cidType              = reflect.TypeOf(cid.Cid{})
+	addressType          = reflect.TypeOf(address.Address{})
+	marshalerType        = reflect.TypeOf((*cbg.CBORMarshaler)(nil)).Elem()
+	unmarshalerType      = reflect.TypeOf((*cbg.CBORUnmarshaler)(nil)).Elem()
+	jsonMarshalerType    = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType  = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	emptyInterfaceType   = reflect.TypeOf((*interface{})(nil)).Elem()
+	structFieldsCache    sync.Map // reflect.Type -> []structField
+	errUnsupportedFormat = xerrors.New("unexpected cbor value")
+)
+
+// hasCBOR returns whether the values of the type encode themselves, like the
+// types generated by cbor-gen.
+func hasCBOR(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return pt.Implements(marshalerType) && pt.Implements(unmarshalerType)
+}
+
+// hasJSON returns whether the values of the type encode themselves to JSON
+// only, they're sent as a byte string of their JSON.
+func hasJSON(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return pt.Implements(jsonMarshalerType) && pt.Implements(jsonUnmarshalerType)
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+// fieldsOf returns the fields of a struct sent over the connections, the
+// exported fields named like in JSON.
+func fieldsOf(t reflect.Type) []structField {
+	if f, ok := structFieldsCache.Load(t); ok {
+		return f.([]structField)
+	}
+
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: i})
+	}
+
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+type encoder struct {
+	w       io.Writer
+	scratch []byte
+}
+
+func newEncoder(w io.Writer) *encoder {
+	return &encoder{w: w, scratch: make([]byte, 9)}
+}
+
+func (e *encoder) header(maj byte, l uint64) error {
+	return cbg.WriteMajorTypeHeaderBuf(e.scratch, e.w, maj, l)
+}
+
+func (e *encoder) writeByte(b byte) error {
+	e.scratch[0] = b
+	_, err := e.w.Write(e.scratch[:1])
+	return err
+}
+
+func (e *encoder) writeString(s string) error {
+	if err := e.header(cbg.MajTextString, uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, s)
+	return err
+}
+
+func (e *encoder) writeBytes(b []byte) error {
+	if err := e.header(cbg.MajByteString, uint64(len(b))); err != nil {
+		return err
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+func (e *encoder) writeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.writeBytes(b)
+}
+
+// encode writes a value: the types generated by cbor-gen encode themselves,
+// the other values are encoded from their kind, structs as maps of their
+// fields. Interface values, and the types only encoding to JSON, are sent as
+// their JSON. The undefined cids and addresses, which don't encode
+// themselves, are sent as null.
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		return e.writeByte(cborNull)
+	}
+
+	t := v.Type()
+	switch {
+	case t == cidType:
+		c := v.Interface().(cid.Cid)
+		if !c.Defined() {
+			return e.writeByte(cborNull)
+		}
+		return cbg.WriteCidBuf(e.scratch, e.w, c)
+	case t == addressType:
+		a := v.Interface().(address.Address)
+		if a == address.Undef {
+			return e.writeByte(cborNull)
+		}
+		return a.MarshalCBOR(e.w)
+	case t.Kind() == reflect.Interface:
+		if v.IsNil() {
+			return e.writeByte(cborNull)
+		}
+		return e.writeJSON(v.Interface())
+	case t.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			return e.writeByte(cborNull)
+		}
+		return e.encode(v.Elem())
+	case hasCBOR(t):
+		return addr(v).Interface().(cbg.CBORMarshaler).MarshalCBOR(e.w)
+	case hasJSON(t):
+		return e.writeJSON(addr(v).Interface())
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return e.writeByte(cborTrue)
+		}
+		return e.writeByte(cborFalse)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); n < 0 {
+			return e.header(cbg.MajNegativeInt, uint64(-n-1))
+		}
+		return e.header(cbg.MajUnsignedInt, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return e.header(cbg.MajUnsignedInt, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.scratch[0] = cborFloat64
+		binary.BigEndian.PutUint64(e.scratch[1:], math.Float64bits(v.Float()))
+		_, err := e.w.Write(e.scratch)
+		return err
+	case reflect.String:
+		return e.writeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			return e.writeByte(cborNull)
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return e.writeBytes(v.Bytes())
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return e.writeBytes(b)
+		}
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return e.writeByte(cborNull)
+		}
+		if err := e.header(cbg.MajMap, uint64(v.Len())); err != nil {
+			return err
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.encode(iter.Key()); err != nil {
+				return err
+			}
+			if err := e.encode(iter.Value()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		fields := fieldsOf(t)
+		if err := e.header(cbg.MajMap, uint64(len(fields))); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := e.writeString(f.name); err != nil {
+				return err
+			}
+			if err := e.encode(v.Field(f.index)); err != nil {
+				return xerrors.Errorf("encoding %s.%s: %w", t, f.name, err)
+			}
+		}
+		return nil
+	default:
+		return xerrors.Errorf("unsupported type %s", t)
+	}
+}
+
+func (e *encoder) encodeArray(v reflect.Value) error {
+	if err := e.header(cbg.MajArray, uint64(v.Len())); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addr returns a pointer to the value, a copy of it when it isn't
+// addressable.
+func addr(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+type decoder struct {
+	br      *bufio.Reader
+	scratch []byte
+}
+
+func newDecoder(br *bufio.Reader) *decoder {
+	return &decoder{br: br, scratch: make([]byte, 8)}
+}
+
+// header reads the header of the next value. The floats are read here, as
+// cbor-gen refuses their eight bytes when they'd fit in four.
+func (d *decoder) header() (byte, uint64, error) {
+	b, err := d.br.Peek(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0] == cborFloat64 {
+		if _, err := d.br.Discard(1); err != nil {
+			return 0, 0, err
+		}
+		if _, err := io.ReadFull(d.br, d.scratch[:8]); err != nil {
+			return 0, 0, err
+		}
+		return cbg.MajOther, binary.BigEndian.Uint64(d.scratch[:8]), nil
+	}
+	return cbg.CborReadHeaderBuf(d.br, d.scratch)
+}
+
+// null reads a null value, if it is the next one.
+func (d *decoder) null() (bool, error) {
+	b, err := d.br.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	if b == cborNull {
+		return true, nil
+	}
+	return false, d.br.UnreadByte()
+}
+
+// read reads a value of n bytes, at most max. The buffer of a long value
+// grows as its bytes are read, so a peer can't make us allocate the length it
+// declares without sending it.
+func (d *decoder) read(n, max uint64) ([]byte, error) {
+	if n > max {
+		return nil, xerrors.Errorf("value of %d bytes is too long", n)
+	}
+	if n <= readChunk {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(d.br, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	b := make([]byte, 0, readChunk)
+	for uint64(len(b)) < n {
+		chunk := n - uint64(len(b))
+		if chunk > readChunk {
+			chunk = readChunk
+		}
+		start := len(b)
+		b = append(b, make([]byte, chunk)...)
+		if _, err := io.ReadFull(d.br, b[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (d *decoder) readString() (string, error) {
+	return d.readStringMax(maxLength)
+}
+
+// readStringMax reads a string of at most max bytes.
+func (d *decoder) readStringMax(max uint64) (string, error) {
+	maj, n, err := d.header()
+	if err != nil {
+		return "", err
+	}
+	if maj != cbg.MajTextString {
+		return "", xerrors.Errorf("expected a string: %w", errUnsupportedFormat)
+	}
+	b, err := d.read(n, max)
+	return string(b), err
+}
+
+func (d *decoder) readBytes() ([]byte, error) {
+	maj, n, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajByteString {
+		return nil, xerrors.Errorf("expected a byte string: %w", errUnsupportedFormat)
+	}
+	return d.read(n, maxLength)
+}
+
+func (d *decoder) readLength(maj byte) (int, error) {
+	m, n, err := d.header()
+	if err != nil {
+		return 0, err
+	}
+	if m != maj {
+		return 0, xerrors.Errorf("expected major type %d, got %d: %w", maj, m, errUnsupportedFormat)
+	}
+	if n > maxLength {
+		return 0, xerrors.Errorf("%d elements are too many", n)
+	}
+	return int(n), nil
+}
+
+// decode reads a value written by encode into v, which must be settable.
+func (d *decoder) decode(v reflect.Value) error {
+	t := v.Type()
+	switch {
+	case t == cidType:
+		if null, err := d.null(); err != nil || null {
+			v.Set(reflect.ValueOf(cid.Undef))
+			return err
+		}
+		c, err := cbg.ReadCid(d.br)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(c))
+		return nil
+	case t == addressType:
+		if null, err := d.null(); err != nil || null {
+			v.Set(reflect.ValueOf(address.Undef))
+			return err
+		}
+		var a address.Address
+		if err := a.UnmarshalCBOR(d.br); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(a))
+		return nil
+	case t.Kind() == reflect.Interface:
+		if null, err := d.null(); err != nil || null {
+			v.Set(reflect.Zero(t))
+			return err
+		}
+		if t != emptyInterfaceType {
+			return xerrors.Errorf("unsupported interface type %s", t)
+		}
+		b, err := d.readBytes()
+		if err != nil {
+			return err
+		}
+		var out interface{}
+		if err := json.Unmarshal(b, &out); err != nil {
+			return err
+		}
+		if out != nil {
+			v.Set(reflect.ValueOf(out))
+		}
+		return nil
+	case t.Kind() == reflect.Ptr:
+		if null, err := d.null(); err != nil || null {
+			v.Set(reflect.Zero(t))
+			return err
+		}
+		p := reflect.New(t.Elem())
+		if err := d.decode(p.Elem()); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	case hasCBOR(t):
+		return v.Addr().Interface().(cbg.CBORUnmarshaler).UnmarshalCBOR(d.br)
+	case hasJSON(t):
+		b, err := d.readBytes()
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, v.Addr().Interface())
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := d.br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case cborTrue:
+			v.SetBool(true)
+		case cborFalse:
+			v.SetBool(false)
+		default:
+			return xerrors.Errorf("expected a bool: %w", errUnsupportedFormat)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		maj, extra, err := d.header()
+		if err != nil {
+			return err
+		}
+		if extra > math.MaxInt64 {
+			return xerrors.Errorf("integer overflows %s", t)
+		}
+		n := int64(extra)
+		switch maj {
+		case cbg.MajUnsignedInt:
+		case cbg.MajNegativeInt:
+			n = -1 - n
+		default:
+			return xerrors.Errorf("expected an integer: %w", errUnsupportedFormat)
+		}
+		if v.OverflowInt(n) {
+			return xerrors.Errorf("integer overflows %s", t)
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		maj, extra, err := d.header()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return xerrors.Errorf("expected an unsigned integer: %w", errUnsupportedFormat)
+		}
+		if v.OverflowUint(extra) {
+			return xerrors.Errorf("integer overflows %s", t)
+		}
+		v.SetUint(extra)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		maj, extra, err := d.header()
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajOther {
+			return xerrors.Errorf("expected a float: %w", errUnsupportedFormat)
+		}
+		v.SetFloat(math.Float64frombits(extra))
+		return nil
+	case reflect.String:
+		s, err := d.readString()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Slice:
+		if null, err := d.null(); err != nil || null {
+			v.Set(reflect.Zero(t))
+			return err
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			b, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		n, err := d.readLength(cbg.MajArray)
+		if err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(t, 0, prealloc(n))
+		for i := 0; i < n; i++ {
+			s = reflect.Append(s, reflect.Zero(t.Elem()))
+			if err := d.decode(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			b, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			if len(b) != t.Len() {
+				return xerrors.Errorf("expected %d bytes, got %d", t.Len(), len(b))
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		n, err := d.readLength(cbg.MajArray)
+		if err != nil {
+			return err
+		}
+		if n != t.Len() {
+			return xerrors.Errorf("expected %d elements, got %d", t.Len(), n)
+		}
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if null, err := d.null(); err != nil || null {
+			v.Set(reflect.Zero(t))
+			return err
+		}
+		n, err := d.readLength(cbg.MajMap)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(t, prealloc(n))
+		for i := 0; i < n; i++ {
+			k := reflect.New(t.Key()).Elem()
+			if err := d.decode(k); err != nil {
+				return err
+			}
+			e := reflect.New(t.Elem()).Elem()
+			if err := d.decode(e); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		n, err := d.readLength(cbg.MajMap)
+		if err != nil {
+			return err
+		}
+		fields := fieldsOf(t)
+		for i := 0; i < n; i++ {
+			name, err := d.readString()
+			if err != nil {
+				return err
+			}
+
+			idx := -1
+			for _, f := range fields {
+				if f.name == name {
+					idx = f.index
+					break
+				}
+			}
+			if idx < 0 {
+				// fields of a newer version of the struct
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := d.decode(v.Field(idx)); err != nil {
+				return xerrors.Errorf("decoding %s.%s: %w", t, name, err)
+			}
+		}
+		return nil
+	default:
+		return xerrors.Errorf("unsupported type %s", t)
+	}
+}
+
+// skip reads the next value without decoding it.
+func (d *decoder) skip() error {
+	maj, extra, err := d.header()
+	if err != nil {
+		return err
+	}
+
+	switch maj {
+	case cbg.MajByteString, cbg.MajTextString:
+		if extra > maxLength {
+			return xerrors.Errorf("value of %d bytes is too long", extra)
+		}
+		_, err := d.br.Discard(int(extra))
+		return err
+	case cbg.MajArray, cbg.MajMap:
+		if extra > maxLength {
+			return xerrors.Errorf("%d elements are too many", extra)
+		}
+		if maj == cbg.MajMap {
+			extra *= 2
+		}
+		for i := uint64(0); i < extra; i++ {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case cbg.MajTag:
+		return d.skip()
+	default:
+		return nil
+	}
+}
+
+func prealloc(n int) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+	return n
+}
@@ -0,0 +1,438 @@
+// Package cborrpc is a binary RPC transport for the lotus APIs, framing the
+// calls in CBOR over TCP or unix socket connections, for the high-volume
+// consumers for which marshalling the large state objects to JSON dominates.
+//
+// The clients fill the Internal structs of the apistruct package like the
+// go-jsonrpc clients, so the API structs stay the only definition of the API.
+// The methods returning channels are served as subscriptions: the values sent
+// on the channel of the handler are forwarded to the channel returned to the
+// client until either side closes it. The connections can be wrapped by
+// middlewares, like the http handlers serving the API.
+package cborrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+
+	logging "github.com/ipfs/go-log/v2"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+var log = logging.Logger("cborrpc")
+
+// The frames are CBOR arrays starting with their kind and the ID of the call:
+//
+//	[frameRequest, id, method, [params...]]
+//	[frameRequest, id, method, [params...], span context]
+//	[frameCancel, id]
+//	[frameResponse, id, error or null, result]
+//	[frameChanValue, id, value]
+//	[frameChanClose, id]
+//
+// The span context, in the binary format of opencensus, is that of the call
+// on the client, so the calls are part of its trace. Before its first request,
+// the client sends its token, a string which can be empty.
+//
+// The response to a call of a method returning a channel has a null result,
+// and is followed by a value frame for each value sent on the channel, then
+// by a close frame once the channel is closed. The client cancels the call to
+// unsubscribe.
+const (
+	frameRequest = iota
+	frameCancel
+	frameResponse
+	frameChanValue
+	frameChanClose
+)
+
+// maxTokenLength bounds the length of the token, which is read before the
+// client is authenticated.
+const maxTokenLength = 4 << 10
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// VerifyFunc returns the permissions of a token, like the AuthVerify method
+// of the API.
+type VerifyFunc func(ctx context.Context, token string) ([]auth.Permission, error)
+
+type method struct {
+	fn     reflect.Value
+	hasCtx bool
+	params []reflect.Type
+	// result is nil for the methods returning only an error
+	result   reflect.Type
+	channels bool
+}
+
+func newMethod(fn reflect.Value) (method, bool) {
+	ft := fn.Type()
+	if ft.NumOut() == 0 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != errorType {
+		return method{}, false
+	}
+
+	m := method{fn: fn}
+	for i := 0; i < ft.NumIn(); i++ {
+		if i == 0 && ft.In(0) == contextType {
+			m.hasCtx = true
+			continue
+		}
+		m.params = append(m.params, ft.In(i))
+	}
+	if ft.NumOut() == 2 {
+		m.result = ft.Out(0)
+		m.channels = m.result.Kind() == reflect.Chan
+	}
+	return m, true
+}
+
+// ConnMiddleware wraps the serving of the connections of a Server, like the
+// http middlewares wrap the serving of the requests. It's called once the
+// client is authenticated, with the context of the connection, carrying the
+// permissions of the client, and the token of the client, and serves the
+// connection with next, which returns once it's closed.
+type ConnMiddleware func(ctx context.Context, conn net.Conn, token string, next func(context.Context, net.Conn))
+
+// Server serves the methods of the handlers registered on it.
+type Server struct {
+	verify      VerifyFunc
+	methods     map[string]method
+	middlewares []ConnMiddleware
+}
+
+// NewServer creates a server checking the tokens of the clients with verify.
+// The clients are given the default permissions of the API when verify is
+// nil, or when they don't send a token.
+func NewServer(verify VerifyFunc) *Server {
+	return &Server{
+		verify:  verify,
+		methods: map[string]method{},
+	}
+}
+
+// Register serves the methods of the handler as namespace.MethodName, like
+// RPCServer.Register of go-jsonrpc.
+func (s *Server) Register(namespace string, handler interface{}) {
+	hv := reflect.ValueOf(handler)
+	for i := 0; i < hv.NumMethod(); i++ {
+		m, ok := newMethod(hv.Method(i))
+		if !ok {
+			continue
+		}
+		s.methods[namespace+"."+hv.Type().Method(i).Name] = m
+	}
+}
+
+// Use wraps the serving of the connections with mw. The first middleware
+// used is the outermost, like in a chain of http handlers.
+func (s *Server) Use(mw ConnMiddleware) {
+	s.middlewares = append(s.middlewares, mw)
+}
+
+// Serve serves the connections of the listener until it is closed.
+func (s *Server) Serve(lst net.Listener) error {
+	for {
+		conn, err := lst.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+type serverConn struct {
+	conn net.Conn
+
+	wlk sync.Mutex
+	w   *bufio.Writer
+
+	lk      sync.Mutex
+	cancels map[uint64]context.CancelFunc
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close() // nolint:errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the frames are read from the connection given by the middlewares, after
+	// the token
+	src := &connReader{Reader: conn}
+	d := newDecoder(bufio.NewReader(src))
+	token, err := d.readStringMax(maxTokenLength)
+	if err != nil {
+		log.Warnf("reading token from %s: %s", conn.RemoteAddr(), err)
+		return
+	}
+	if s.verify != nil && token != "" {
+		perms, err := s.verify(ctx, token)
+		if err != nil {
+			log.Warnf("verifying token from %s: %s", conn.RemoteAddr(), err)
+			return
+		}
+		ctx = auth.WithPerm(ctx, perms)
+	}
+
+	serve := func(ctx context.Context, conn net.Conn) {
+		src.Reader = conn
+		sc := &serverConn{
+			conn:    conn,
+			w:       bufio.NewWriter(conn),
+			cancels: map[uint64]context.CancelFunc{},
+		}
+		for {
+			if err := s.readFrame(ctx, d, sc); err != nil {
+				if err != context.Canceled {
+					log.Debugf("connection from %s: %s", conn.RemoteAddr(), err)
+				}
+				return
+			}
+		}
+	}
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		mw, next := s.middlewares[i], serve
+		serve = func(ctx context.Context, conn net.Conn) {
+			mw(ctx, conn, token, next)
+		}
+	}
+	serve(ctx, conn)
+}
+
+type connReader struct {
+	io.Reader
+}
+
+// readFrame reads a frame of the client, and starts serving it when it's a
+// request.
+func (s *Server) readFrame(ctx context.Context, d *decoder, sc *serverConn) error {
+	n, err := d.readLength(cbg.MajArray)
+	if err != nil {
+		return err
+	}
+	var kind, id uint64
+	if err := d.decode(reflect.ValueOf(&kind).Elem()); err != nil {
+		return err
+	}
+	if err := d.decode(reflect.ValueOf(&id).Elem()); err != nil {
+		return err
+	}
+
+	switch {
+	case kind == frameCancel && n == 2:
+		sc.lk.Lock()
+		if cancel, ok := sc.cancels[id]; ok {
+			cancel()
+		}
+		sc.lk.Unlock()
+		return nil
+	case kind == frameRequest && (n == 4 || n == 5):
+	default:
+		return xerrors.Errorf("unexpected frame %d of %d elements", kind, n)
+	}
+
+	name, err := d.readString()
+	if err != nil {
+		return err
+	}
+	m, ok := s.methods[name]
+	if !ok {
+		if err := d.skip(); err != nil {
+			return err
+		}
+		if n == 5 {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+		go sc.respond(ctx, id, reflect.Value{}, xerrors.Errorf("method '%s' not found", name))
+		return nil
+	}
+
+	np, err := d.readLength(cbg.MajArray)
+	if err != nil {
+		return err
+	}
+	if np != len(m.params) {
+		return xerrors.Errorf("%s takes %d params, got %d", name, len(m.params), np)
+	}
+
+	params := make([]reflect.Value, 0, len(m.params))
+	for _, pt := range m.params {
+		p := reflect.New(pt).Elem()
+		if err := d.decode(p); err != nil {
+			return xerrors.Errorf("decoding params of %s: %w", name, err)
+		}
+		params = append(params, p)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	var span *trace.Span
+	if n == 5 {
+		b, err := d.readBytes()
+		if err != nil {
+			cancel()
+			return err
+		}
+		if psc, ok := propagation.FromBinary(b); ok {
+			cctx, span = trace.StartSpanWithRemoteParent(cctx, "api.handle", psc)
+			span.AddAttributes(trace.StringAttribute("method", name))
+		}
+	}
+
+	args := make([]reflect.Value, 0, len(params)+1)
+	if m.hasCtx {
+		args = append(args, reflect.ValueOf(cctx))
+	}
+	args = append(args, params...)
+
+	sc.lk.Lock()
+	sc.cancels[id] = cancel
+	sc.lk.Unlock()
+
+	go func() {
+		defer func() {
+			sc.lk.Lock()
+			delete(sc.cancels, id)
+			sc.lk.Unlock()
+			cancel()
+			if span != nil {
+				span.End()
+			}
+		}()
+
+		out := m.fn.Call(args)
+		var res reflect.Value
+		if m.result != nil {
+			res = out[0]
+		}
+		err, _ := out[len(out)-1].Interface().(error)
+		if !m.channels || err != nil {
+			sc.respond(cctx, id, res, err)
+			return
+		}
+
+		// the call stays cancellable until the channel is closed
+		sc.respond(cctx, id, reflect.Value{}, nil)
+		sc.forward(cctx, id, res)
+	}()
+	return nil
+}
+
+// forward sends the values of the channel returned by a call to the client,
+// until the channel is closed or the call is cancelled.
+func (sc *serverConn) forward(ctx context.Context, id uint64, ch reflect.Value) {
+	defer func() {
+		var buf bytes.Buffer
+		if err := writeFrameHeader(&buf, 2, frameChanClose, id); err == nil {
+			sc.write(buf.Bytes())
+		}
+	}()
+	if ch.IsNil() {
+		return
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 1 {
+			// the handler closes the channel once the context is done, it
+			// mustn't block on it until then
+			go func() {
+				for {
+					if _, ok := ch.Recv(); !ok {
+						return
+					}
+				}
+			}()
+			return
+		}
+		if !ok {
+			return
+		}
+
+		var buf bytes.Buffer
+		err := writeFrameHeader(&buf, 3, frameChanValue, id)
+		if err == nil {
+			err = newEncoder(&buf).encode(v)
+		}
+		if err != nil {
+			reqid.Logger(ctx, log).Errorf("encoding channel value: %s", err)
+			continue
+		}
+		sc.write(buf.Bytes())
+	}
+}
+
+func (sc *serverConn) respond(ctx context.Context, id uint64, res reflect.Value, callErr error) {
+	var buf bytes.Buffer
+	if err := writeResponse(&buf, id, res, callErr); err != nil {
+		reqid.Logger(ctx, log).Errorf("encoding response: %s", err)
+		buf.Reset()
+		if err := writeResponse(&buf, id, reflect.Value{}, xerrors.Errorf("encoding response: %w", err)); err != nil {
+			return
+		}
+	}
+	sc.write(buf.Bytes())
+}
+
+// write writes a frame, closing the connection when it fails.
+func (sc *serverConn) write(b []byte) {
+	sc.wlk.Lock()
+	defer sc.wlk.Unlock()
+
+	if _, err := sc.w.Write(b); err != nil {
+		_ = sc.conn.Close()
+		return
+	}
+	if err := sc.w.Flush(); err != nil {
+		_ = sc.conn.Close()
+	}
+}
+
+// writeFrameHeader writes the header of a frame of n elements, with its kind
+// and the ID of its call.
+func writeFrameHeader(buf *bytes.Buffer, n, kind, id uint64) error {
+	e := newEncoder(buf)
+	if err := e.header(cbg.MajArray, n); err != nil {
+		return err
+	}
+	if err := e.header(cbg.MajUnsignedInt, kind); err != nil {
+		return err
+	}
+	return e.header(cbg.MajUnsignedInt, id)
+}
+
+func writeResponse(buf *bytes.Buffer, id uint64, res reflect.Value, callErr error) error {
+	if err := writeFrameHeader(buf, 4, frameResponse, id); err != nil {
+		return err
+	}
+	e := newEncoder(buf)
+	if callErr != nil {
+		if err := e.writeString(callErr.Error()); err != nil {
+			return err
+		}
+		return e.writeByte(cborNull)
+	}
+	if err := e.writeByte(cborNull); err != nil {
+		return err
+	}
+	return e.encode(res)
+}
@@ -0,0 +1,228 @@
+// Package clockcheck measures the drift of the system clock against NTP
+// servers. Blocks with timestamps too far in the future are rejected by the
+// network, and blocks mined late miss their epoch, so miners must not mine
+// with a skewed clock.
+package clockcheck
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"go.opencensus.io/stats"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/metrics"
+)
+
+var log = logging.Logger("clockcheck")
+
+// QueryTimeout bounds a query when the context has no deadline
+var QueryTimeout = 5 * time.Second
+
+// DefaultInterval is the interval of the monitors created without one
+const DefaultInterval = 10 * time.Minute
+
+// seconds between the NTP epoch (1900) and the unix epoch
+const ntpEpochOffset = 2208988800
+
+// Query returns the offset of the NTP server's clock from the system clock,
+// positive if the system clock is behind, using SNTP (RFC 4330). The port
+// defaults to 123.
+func Query(ctx context.Context, server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, QueryTimeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, xerrors.Errorf("dialing %s: %w", server, err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	// LI = 0, VN = 4, Mode = 3 (client); the transmit timestamp is echoed by
+	// the server as the originate timestamp, which ties the reply to the request
+	req := make([]byte, 48)
+	req[0] = 0x23
+	sent := time.Now()
+	putNtpTime(req[40:], sent)
+
+	if _, err := conn.Write(req); err != nil {
+		return 0, xerrors.Errorf("sending request to %s: %w", server, err)
+	}
+
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, xerrors.Errorf("reading response from %s: %w", server, err)
+	}
+	received := time.Now()
+
+	if n < 48 {
+		return 0, xerrors.Errorf("short response from %s: %d bytes", server, n)
+	}
+	if mode := resp[0] & 0x7; mode != 4 {
+		return 0, xerrors.Errorf("unexpected mode %d in response from %s", mode, server)
+	}
+	if resp[1] == 0 {
+		return 0, xerrors.Errorf("%s refused the request (kiss-o'-death %q)", server, resp[12:16])
+	}
+	if string(resp[24:32]) != string(req[40:48]) {
+		return 0, xerrors.Errorf("response from %s doesn't match the request", server)
+	}
+
+	serverReceived := ntpTime(resp[32:40])
+	serverSent := ntpTime(resp[40:48])
+
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	nsecs := (int64(binary.BigEndian.Uint32(b[4:8])) * 1e9) >> 32
+	return time.Unix(secs, nsecs)
+}
+
+func putNtpTime(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b[0:4], uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[4:8], uint32((int64(t.Nanosecond())<<32)/1e9))
+}
+
+// Monitor measures the clock drift periodically. A nil Monitor does no checks.
+type Monitor struct {
+	servers  []string
+	interval time.Duration
+	maxDrift time.Duration
+
+	query func(ctx context.Context, server string) (time.Duration, error)
+
+	lk       sync.Mutex
+	drift    time.Duration
+	measured bool
+
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+// NewMonitor creates a monitor querying the servers, in order until one of
+// them responds, every interval, or every DefaultInterval when interval isn't
+// positive.
+func NewMonitor(servers []string, interval, maxDrift time.Duration) *Monitor {
+	if interval <= 0 {
+		log.Warnf("invalid clock check interval %s, checking every %s", interval, DefaultInterval)
+		interval = DefaultInterval
+	}
+
+	return &Monitor{
+		servers:  servers,
+		interval: interval,
+		maxDrift: maxDrift,
+		query:    Query,
+	}
+}
+
+// Start measures the drift right away, and then every interval until stopped.
+func (m *Monitor) Start() {
+	m.closing = make(chan struct{})
+	m.closed = make(chan struct{})
+
+	go func() {
+		defer close(m.closed)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-m.closing:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		for {
+			if err := m.Check(ctx); err != nil {
+				log.Warnf("checking clock drift: %s", err)
+			}
+
+			select {
+			case <-time.After(m.interval):
+			case <-m.closing:
+				return
+			}
+		}
+	}()
+}
+
+func (m *Monitor) Stop() {
+	close(m.closing)
+	<-m.closed
+}
+
+// Check measures the drift, logging an error if it exceeds the maximum.
+func (m *Monitor) Check(ctx context.Context) error {
+	var errs []error
+	for _, server := range m.servers {
+		drift, err := m.query(ctx, server)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		m.lk.Lock()
+		m.drift = drift
+		m.measured = true
+		m.lk.Unlock()
+
+		stats.Record(ctx, metrics.ClockDrift.M(float64(drift)/float64(time.Millisecond)))
+
+		if err := m.Err(); err != nil {
+			log.Errorw("system clock drift exceeds the allowed maximum, blocks will not be mined until the clock is fixed",
+				"drift", drift, "max", m.maxDrift, "server", server)
+		} else {
+			log.Debugw("clock drift", "drift", drift, "server", server)
+		}
+		return nil
+	}
+
+	return xerrors.Errorf("no NTP server responded: %v", errs)
+}
+
+// Drift returns the last measured drift, and whether there is one.
+func (m *Monitor) Drift() (time.Duration, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.drift, m.measured
+}
+
+// Err returns an error if the last measured drift exceeds the maximum. Until
+// a server responds, the clock is assumed to be correct.
+func (m *Monitor) Err() error {
+	drift, ok := m.Drift()
+	if !ok {
+		return nil
+	}
+
+	if drift > m.maxDrift || drift < -m.maxDrift {
+		return xerrors.Errorf("system clock drift of %s exceeds the maximum of %s", drift, m.maxDrift)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package clockcheck
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serveNTP answers SNTP requests with the local time shifted by offset.
+func serveNTP(t *testing.T, offset time.Duration) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 48 {
+				continue
+			}
+
+			resp := make([]byte, 48)
+			resp[0] = 0x24 // VN = 4, Mode = 4 (server)
+			resp[1] = 1
+			copy(resp[24:32], buf[40:48])
+			now := time.Now().Add(offset)
+			putNtpTime(resp[32:], now)
+			putNtpTime(resp[40:], now)
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+
+	for _, offset := range []time.Duration{0, 3 * time.Second, -time.Minute} {
+		drift, err := Query(ctx, serveNTP(t, offset))
+		require.NoError(t, err)
+		require.InDelta(t, float64(offset), float64(drift), float64(100*time.Millisecond))
+	}
+}
+
+func TestNtpTime(t *testing.T) {
+	b := make([]byte, 8)
+	now := time.Unix(1600000000, 123456789)
+	putNtpTime(b, now)
+	require.Equal(t, uint32(1600000000+ntpEpochOffset), binary.BigEndian.Uint32(b))
+	require.InDelta(t, float64(now.UnixNano()), float64(ntpTime(b).UnixNano()), 10)
+}
+
+func TestMonitor(t *testing.T) {
+	ctx := context.Background()
+
+	var nilMonitor *Monitor
+	require.NoError(t, nilMonitor.Err())
+
+	drift := map[string]time.Duration{"good": 0}
+	m := NewMonitor([]string{"down", "good"}, time.Minute, time.Second)
+	m.query = func(ctx context.Context, server string) (time.Duration, error) {
+		d, ok := drift[server]
+		if !ok {
+			return 0, net.UnknownNetworkError(server)
+		}
+		return d, nil
+	}
+
+	// the clock is assumed to be correct until it's measured
+	require.NoError(t, m.Err())
+
+	require.NoError(t, m.Check(ctx))
+	require.NoError(t, m.Err())
+
+	drift["down"] = -2 * time.Second
+	require.NoError(t, m.Check(ctx))
+	d, ok := m.Drift()
+	require.True(t, ok)
+	require.Equal(t, -2*time.Second, d)
+	require.Error(t, m.Err())
+
+	m.servers = []string{"none"}
+	require.Error(t, m.Check(ctx))
+	// the last measurement is kept
+	require.Error(t, m.Err())
+}
+
+func TestMonitorInterval(t *testing.T) {
+	m := NewMonitor([]string{"good"}, 0, time.Second)
+	require.Equal(t, DefaultInterval, m.interval)
+
+	var lk sync.Mutex
+	var queries int
+	m.query = func(ctx context.Context, server string) (time.Duration, error) {
+		lk.Lock()
+		defer lk.Unlock()
+		queries++
+		return 0, nil
+	}
+
+	// without an interval, the monitor doesn't check in a loop
+	m.Start()
+	time.Sleep(50 * time.Millisecond)
+	m.Stop()
+
+	lk.Lock()
+	defer lk.Unlock()
+	require.Equal(t, 1, queries)
+}
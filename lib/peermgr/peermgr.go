@@ -27,6 +27,9 @@ var log = logging.Logger("peermgr")
 const (
 	MaxFilPeers = 32
 	MinFilPeers = 12
+
+	// ReputationFlushInterval is how often peer reputation is persisted.
+	ReputationFlushInterval = time.Minute
 )
 
 type MaybePeerMgr struct {
@@ -56,6 +59,8 @@ type PeerMgr struct {
 	notifee        *net.NotifyBundle
 	filPeerEmitter event.Emitter
 
+	rep *PeerReputation
+
 	done chan struct{}
 }
 
@@ -63,7 +68,12 @@ type NewFilPeer struct {
 	Id peer.ID
 }
 
-func NewPeerMgr(lc fx.Lifecycle, h host.Host, dht *dht.IpfsDHT, bootstrap dtypes.BootstrapPeers) (*PeerMgr, error) {
+func NewPeerMgr(lc fx.Lifecycle, h host.Host, dht *dht.IpfsDHT, bootstrap dtypes.BootstrapPeers, ds dtypes.MetadataDS) (*PeerMgr, error) {
+	rep, err := NewPeerReputation(ds)
+	if err != nil {
+		return nil, xerrors.Errorf("loading peer reputation: %w", err)
+	}
+
 	pm := &PeerMgr{
 		h:             h,
 		dht:           dht,
@@ -75,6 +85,8 @@ func NewPeerMgr(lc fx.Lifecycle, h host.Host, dht *dht.IpfsDHT, bootstrap dtypes
 		maxFilPeers: MaxFilPeers,
 		minFilPeers: MinFilPeers,
 
+		rep: rep,
+
 		done: make(chan struct{}),
 	}
 	emitter, err := h.EventBus().Emitter(new(NewFilPeer))
@@ -88,6 +100,7 @@ func NewPeerMgr(lc fx.Lifecycle, h host.Host, dht *dht.IpfsDHT, bootstrap dtypes
 			return multierr.Combine(
 				pm.filPeerEmitter.Close(),
 				pm.Stop(ctx),
+				pm.rep.Flush(pm.h.Peerstore().Addrs),
 			)
 		},
 	})
@@ -126,6 +139,11 @@ func (pmgr *PeerMgr) SetPeerLatency(p peer.ID, latency time.Duration) {
 
 }
 
+// Reputation returns the long-term record of peer usefulness.
+func (pmgr *PeerMgr) Reputation() *PeerReputation {
+	return pmgr.rep
+}
+
 func (pmgr *PeerMgr) Disconnect(p peer.ID) {
 	if pmgr.h.Network().Connectedness(p) == net.NotConnected {
 		pmgr.peersLk.Lock()
@@ -141,9 +159,16 @@ func (pmgr *PeerMgr) Stop(ctx context.Context) error {
 }
 
 func (pmgr *PeerMgr) Run(ctx context.Context) {
+	go pmgr.connectReputable(ctx, pmgr.minFilPeers)
+
 	tick := build.Clock.Ticker(time.Second * 5)
+	flush := build.Clock.Ticker(ReputationFlushInterval)
 	for {
 		select {
+		case <-flush.C:
+			if err := pmgr.rep.Flush(pmgr.h.Peerstore().Addrs); err != nil {
+				log.Warnf("persisting peer reputation: %s", err)
+			}
 		case <-tick.C:
 			pcount := pmgr.getPeerCount()
 			if pcount < pmgr.minFilPeers {
@@ -199,8 +224,48 @@ func (pmgr *PeerMgr) doExpand(ctx context.Context) {
 		return
 	}
 
+	// prefer peers which served us well before
+	if pmgr.connectReputable(ctx, pmgr.minFilPeers-pcount) > 0 && pmgr.getPeerCount() >= pmgr.minFilPeers {
+		return
+	}
+
 	// if we already have some peers and need more, the dht is really good at connecting to most peers. Use that for now until something better comes along.
 	if err := pmgr.dht.Bootstrap(ctx); err != nil {
 		log.Warnf("dht bootstrapping failed: %s", err)
 	}
 }
+
+// connectReputable tries to connect to up to n of the best peers from the
+// reputation records which we aren't connected to, returning the number of
+// new connections.
+func (pmgr *PeerMgr) connectReputable(ctx context.Context, n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	var connected int
+	for _, rec := range pmgr.rep.Best(2 * n) {
+		if connected >= n {
+			break
+		}
+		if pmgr.h.Network().Connectedness(rec.Peer) == net.Connected {
+			continue
+		}
+
+		ai := rec.AddrInfo()
+		if len(ai.Addrs) == 0 {
+			continue
+		}
+
+		if err := pmgr.h.Connect(ctx, ai); err != nil {
+			log.Debugf("failed to reconnect to reputable peer %s: %s", rec.Peer, err)
+			continue
+		}
+		connected++
+	}
+
+	if connected > 0 {
+		log.Infof("reconnected to %d previously useful peers", connected)
+	}
+	return connected
+}
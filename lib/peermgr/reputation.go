@@ -0,0 +1,232 @@
+package peermgr
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/build"
+)
+
+// ReputationExpiry is how long a peer which isn't seen again is remembered.
+const ReputationExpiry = 14 * 24 * time.Hour
+
+// reputationInvAlpha smooths the recorded latency, see exchange.localInvAlpha
+const reputationInvAlpha = 10
+
+// PeerRecord is the long-term record of how useful a peer has been.
+type PeerRecord struct {
+	Peer peer.ID
+
+	// Successes and Failures count chain exchange requests
+	Successes uint64
+	Failures  uint64
+	// Invalid counts responses which failed validation
+	Invalid uint64
+	// BlocksServed is the number of tipsets received from the peer
+	BlocksServed uint64
+	// Latency is the moving average of the request latency per tipset
+	Latency time.Duration
+
+	LastSeen time.Time
+	Addrs    []string
+}
+
+// Score rates the peer, higher is better. Peers which served many tipsets
+// quickly and reliably score well; invalid responses weigh five times as much
+// as failures.
+func (r *PeerRecord) Score() float64 {
+	reliability := float64(r.Successes+1) / float64(r.Successes+r.Failures+5*r.Invalid+2)
+	return reliability * math.Log1p(float64(r.BlocksServed)) / (1 + r.Latency.Seconds())
+}
+
+// AddrInfo returns the recorded addresses of the peer.
+func (rec *PeerRecord) AddrInfo() peer.AddrInfo {
+	ai := peer.AddrInfo{ID: rec.Peer}
+	for _, s := range rec.Addrs {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			log.Warnf("bad recorded address %q for peer %s: %s", s, rec.Peer, err)
+			continue
+		}
+		ai.Addrs = append(ai.Addrs, a)
+	}
+	return ai
+}
+
+// PeerReputation keeps PeerRecords for the peers we've exchanged chain data
+// with, persisted in the metadata datastore so that they survive restarts.
+type PeerReputation struct {
+	ds datastore.Batching
+
+	lk    sync.Mutex
+	peers map[peer.ID]*PeerRecord
+	dirty map[peer.ID]struct{}
+}
+
+func NewPeerReputation(ds datastore.Batching) (*PeerReputation, error) {
+	r := &PeerReputation{
+		ds:    namespace.Wrap(ds, datastore.NewKey("/peerrep")),
+		peers: map[peer.ID]*PeerRecord{},
+		dirty: map[peer.ID]struct{}{},
+	}
+
+	res, err := r.ds.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("querying peer reputation: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	expired := build.Clock.Now().Add(-ReputationExpiry)
+	for e := range res.Next() {
+		if e.Error != nil {
+			return nil, xerrors.Errorf("reading peer reputation: %w", e.Error)
+		}
+
+		var rec PeerRecord
+		if err := json.Unmarshal(e.Value, &rec); err != nil {
+			log.Warnf("bad peer reputation record %s: %s", e.Key, err)
+			continue
+		}
+		if rec.LastSeen.Before(expired) {
+			if err := r.ds.Delete(datastore.NewKey(e.Key)); err != nil {
+				log.Warnf("deleting expired peer reputation record: %s", err)
+			}
+			continue
+		}
+
+		r.peers[rec.Peer] = &rec
+	}
+
+	return r, nil
+}
+
+func (r *PeerReputation) record(p peer.ID) *PeerRecord {
+	rec, ok := r.peers[p]
+	if !ok {
+		rec = &PeerRecord{Peer: p}
+		r.peers[p] = rec
+	}
+	rec.LastSeen = build.Clock.Now()
+	r.dirty[p] = struct{}{}
+	return rec
+}
+
+// RecordSuccess records a request which returned the given number of tipsets.
+func (r *PeerReputation) RecordSuccess(p peer.ID, dur time.Duration, tipsets uint64) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	rec := r.record(p)
+	rec.Successes++
+	rec.BlocksServed += tipsets
+
+	if tipsets == 0 {
+		tipsets = 1
+	}
+	lat := dur / time.Duration(tipsets)
+	if rec.Latency == 0 {
+		rec.Latency = lat
+	} else {
+		rec.Latency += (lat - rec.Latency) / reputationInvAlpha
+	}
+}
+
+func (r *PeerReputation) RecordFailure(p peer.ID) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	r.record(p).Failures++
+}
+
+// RecordInvalid records a response which failed validation.
+func (r *PeerReputation) RecordInvalid(p peer.ID) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	r.record(p).Invalid++
+}
+
+func (r *PeerReputation) Get(p peer.ID) (PeerRecord, bool) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	rec, ok := r.peers[p]
+	if !ok {
+		return PeerRecord{}, false
+	}
+	return *rec, true
+}
+
+// Best returns up to n records with a positive score, best first.
+func (r *PeerReputation) Best(n int) []PeerRecord {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	out := make([]PeerRecord, 0, len(r.peers))
+	for _, rec := range r.peers {
+		if rec.Score() > 0 {
+			out = append(out, *rec)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Score() > out[j].Score()
+	})
+
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Flush persists the records which changed since the last flush. addrs is
+// used to record the current addresses of the peers.
+func (r *PeerReputation) Flush(addrs func(peer.ID) []ma.Multiaddr) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if len(r.dirty) == 0 {
+		return nil
+	}
+
+	b, err := r.ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	for p := range r.dirty {
+		rec := r.peers[p]
+
+		if as := addrs(p); len(as) > 0 {
+			rec.Addrs = rec.Addrs[:0]
+			for _, a := range as {
+				rec.Addrs = append(rec.Addrs, a.String())
+			}
+		}
+
+		v, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(datastore.NewKey(p.String()), v); err != nil {
+			return xerrors.Errorf("writing peer reputation: %w", err)
+		}
+	}
+
+	if err := b.Commit(); err != nil {
+		return xerrors.Errorf("committing peer reputation: %w", err)
+	}
+
+	r.dirty = map[peer.ID]struct{}{}
+	return nil
+}
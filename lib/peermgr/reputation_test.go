@@ -0,0 +1,73 @@
+package peermgr
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerReputation(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	rep, err := NewPeerReputation(ds)
+	require.NoError(t, err)
+
+	// the IDs must be valid to be read back from the datastore
+	fast, slow, bad := randPeerID(t), randPeerID(t), randPeerID(t)
+	for i := 0; i < 10; i++ {
+		rep.RecordSuccess(fast, 100*time.Millisecond, 50)
+		rep.RecordSuccess(slow, 2*time.Second, 50)
+		rep.RecordSuccess(bad, 100*time.Millisecond, 50)
+		rep.RecordInvalid(bad)
+	}
+	rep.RecordFailure(slow)
+
+	best := rep.Best(10)
+	require.Len(t, best, 3)
+	require.Equal(t, fast, best[0].Peer)
+	require.Equal(t, bad, best[2].Peer)
+
+	require.Len(t, rep.Best(1), 1)
+
+	addr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1347")
+	require.NoError(t, err)
+	require.NoError(t, rep.Flush(func(p peer.ID) []ma.Multiaddr {
+		if p == fast {
+			return []ma.Multiaddr{addr}
+		}
+		return nil
+	}))
+
+	// records survive a restart
+	rep, err = NewPeerReputation(ds)
+	require.NoError(t, err)
+
+	rec, ok := rep.Get(slow)
+	require.True(t, ok)
+	require.EqualValues(t, 10, rec.Successes)
+	require.EqualValues(t, 1, rec.Failures)
+	require.EqualValues(t, 500, rec.BlocksServed)
+
+	rec, ok = rep.Get(fast)
+	require.True(t, ok)
+	ai := rec.AddrInfo()
+	require.Equal(t, fast, ai.ID)
+	require.Len(t, ai.Addrs, 1)
+	require.True(t, addr.Equal(ai.Addrs[0]))
+	require.Equal(t, fast, rep.Best(1)[0].Peer)
+}
+
+func randPeerID(t *testing.T) peer.ID {
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	p, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+	return p
+}
@@ -0,0 +1,98 @@
+// Package reqid correlates the API requests with the log lines and traces
+// emitted while serving them, across the lotus services.
+//
+// The correlation ID of a request is the ID of its trace. The clients can
+// pick it with the X-Request-ID header: an ID of 32 hex digits, like a UUID,
+// is used as the trace ID as is, and other IDs are hashed into one. The API
+// clients pass the span context of their calls along with them, so the calls
+// lotus-gateway and lotus-miner make to the daemon while serving a request
+// carry its ID.
+//
+// Over websockets, the header of the connection applies to all its calls
+// which don't carry a span context.
+//
+// The spans of the traces carry the ID wherever the request context goes. The
+// log lines carry it when they are logged through Logger, as the API handlers
+// and the API middlewares do; the lines logged by the subsystems a request
+// hands work to, like the syncer or the message pool, don't.
+package reqid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+)
+
+// Header is the HTTP header carrying the correlation ID of a request, set on
+// the responses too.
+const Header = "X-Request-ID"
+
+// maxIDLength is the length of the longest ID accepted from the clients.
+const maxIDLength = 128
+
+// FromContext returns the correlation ID of the request served with ctx.
+func FromContext(ctx context.Context) (string, bool) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return "", false
+	}
+	return span.SpanContext().TraceID.String(), true
+}
+
+// EventLogger is implemented by the loggers of both go-log versions used
+// across lotus.
+type EventLogger interface {
+	With(args ...interface{}) *zap.SugaredLogger
+}
+
+// Logger returns l with the correlation ID of the request served with ctx,
+// if any, attached to its lines.
+func Logger(ctx context.Context, l EventLogger) *zap.SugaredLogger {
+	if id, ok := FromContext(ctx); ok {
+		return l.With("request_id", id)
+	}
+	return l.With()
+}
+
+// TraceID returns the trace ID of the requests with the given correlation ID.
+func TraceID(id string) trace.TraceID {
+	var tid trace.TraceID
+	if b, err := hex.DecodeString(strings.ReplaceAll(id, "-", "")); err == nil && len(b) == len(tid) {
+		copy(tid[:], b)
+	}
+	if tid == (trace.TraceID{}) {
+		h := sha256.Sum256([]byte(id))
+		copy(tid[:], h[:])
+	}
+	return tid
+}
+
+// Handler serves the requests with next in a span of the trace identified by
+// their X-Request-ID header, or of a new trace when they don't have one, and
+// sets the header of the responses to their correlation ID.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var span *trace.Span
+		if id := r.Header.Get(Header); id != "" && len(id) <= maxIDLength {
+			// without a parent span ID, the span is the root of the trace
+			ctx, span = trace.StartSpanWithRemoteParent(ctx, "api.request", trace.SpanContext{
+				TraceID: TraceID(id),
+			})
+			span.AddAttributes(trace.StringAttribute("request_id", id))
+		} else {
+			ctx, span = trace.StartSpan(ctx, "api.request")
+		}
+		defer span.End()
+		span.AddAttributes(trace.StringAttribute("path", r.URL.Path))
+
+		w.Header().Set(Header, span.SpanContext().TraceID.String())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,36 @@
+package reqid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceID(t *testing.T) {
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", TraceID("4bf92f35-77b3-4da6-a3ce-929d0e0e4736").String())
+	require.Equal(t, TraceID("some-request"), TraceID("some-request"))
+	require.NotEqual(t, TraceID("some-request"), TraceID("other-request"))
+}
+
+func TestHandler(t *testing.T) {
+	var served string
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served, _ = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/rpc/v0", nil)
+	req.Header.Set(Header, "4bf92f3577b34da6a3ce929d0e0e4736")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", served)
+	require.Equal(t, served, rec.Header().Get(Header))
+
+	// the requests without an ID are given one
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rpc/v0", nil))
+	require.NotEmpty(t, served)
+	require.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", served)
+	require.Equal(t, served, rec.Header().Get(Header))
+}
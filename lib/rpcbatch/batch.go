@@ -0,0 +1,239 @@
+// Package rpcbatch adds JSON-RPC 2.0 batch requests to a JSON-RPC handler
+// serving single requests, like the go-jsonrpc server.
+//
+// The entries of a batch are passed to the handler under internal IDs, as
+// go-jsonrpc only takes numeric IDs, and their responses are sent back in one
+// array under the IDs of the client. Over HTTP, each entry is a separate
+// request to the handler; over websockets, the handler serves the connection
+// and only the frames holding batches are intercepted, see batchConn.
+package rpcbatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+)
+
+var log = logging.Logger("rpcbatch")
+
+// DefaultParallelism is the number of entries of a batch executed
+// concurrently by default.
+const DefaultParallelism = 16
+
+// maxBatchSize is the largest batch read, the request size limit of
+// go-jsonrpc.
+const maxBatchSize = 100 << 20
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeInternalError  = -32603
+)
+
+type handler struct {
+	next        http.Handler
+	parallelism int
+}
+
+// Handler serves the batch requests by executing their entries with next, at
+// most parallelism at a time; other requests are passed to next.
+func Handler(next http.Handler, parallelism int) http.Handler {
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	return &handler{next: next, parallelism: parallelism}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.next.ServeHTTP(&hijackWriter{ResponseWriter: w, parallelism: h.parallelism}, r)
+		return
+	}
+	if r.Method != http.MethodPost || r.Body == nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBatchSize+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !isBatch(body) {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if len(body) > maxBatchSize {
+		writeJSON(w, errorResponse(nil, codeInvalidRequest, "batch too large"))
+		return
+	}
+
+	var reqs []json.RawMessage
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		writeJSON(w, errorResponse(nil, codeParseError, err.Error()))
+		return
+	}
+	if len(reqs) == 0 {
+		writeJSON(w, errorResponse(nil, codeInvalidRequest, "empty batch"))
+		return
+	}
+
+	resps := make([]json.RawMessage, len(reqs))
+	throttle := make(chan struct{}, h.parallelism)
+	var wg sync.WaitGroup
+	for i := range reqs {
+		throttle <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-throttle }()
+			resps[i] = h.call(r, reqs[i], i)
+		}(i)
+	}
+	wg.Wait()
+
+	// notifications have no response, and a batch of notifications has none
+	out := make([]json.RawMessage, 0, len(resps))
+	for _, resp := range resps {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, out)
+}
+
+// call executes the request at index i of a batch, returning its response.
+func (h *handler) call(r *http.Request, req json.RawMessage, i int) json.RawMessage {
+	if !isObject(req) {
+		return mustMarshal(errorResponse(nil, codeInvalidRequest, "batch entry is not an object"))
+	}
+	id := requestID(req)
+	if id != nil {
+		var err error
+		if req, err = withID(req, i); err != nil {
+			return mustMarshal(errorResponse(id, codeInvalidRequest, err.Error()))
+		}
+	}
+
+	ir := r.Clone(r.Context())
+	ir.Body = ioutil.NopCloser(bytes.NewReader(req))
+	ir.ContentLength = int64(len(req))
+
+	rec := &recorder{header: http.Header{}, code: http.StatusOK}
+	h.next.ServeHTTP(rec, ir)
+
+	resp := bytes.TrimSpace(rec.body.Bytes())
+	if len(resp) == 0 || id == nil {
+		return nil
+	}
+	out, err := withID(resp, id)
+	if err != nil {
+		return mustMarshal(errorResponse(id, codeInternalError, string(resp)))
+	}
+	return out
+}
+
+func isBatch(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '['
+}
+
+// isObject returns whether a batch entry is a JSON object, other entries are
+// invalid requests.
+func isObject(req json.RawMessage) bool {
+	req = bytes.TrimSpace(req)
+	return len(req) > 0 && req[0] == '{'
+}
+
+// requestID returns the ID of a request, nil for notifications.
+func requestID(req json.RawMessage) json.RawMessage {
+	var r struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(req, &r) != nil || len(r.ID) == 0 || string(r.ID) == "null" {
+		return nil
+	}
+	return r.ID
+}
+
+func withID(msg json.RawMessage, id interface{}) (json.RawMessage, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return nil, xerrors.Errorf("decoding message: %w", err)
+	}
+	idb, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	m["id"] = idb
+	return json.Marshal(m)
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type response struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Error:   &rpcError{Code: code, Message: msg},
+	}
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("writing batch response: %s", err)
+	}
+}
+
+// recorder is the response writer of the entries of HTTP batches.
+type recorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(code int) {
+	r.code = code
+}
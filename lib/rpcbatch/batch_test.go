@@ -0,0 +1,177 @@
+package rpcbatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-jsonrpc"
+)
+
+type PowerHandler struct{}
+
+func (h *PowerHandler) Power(ctx context.Context, miner int) (int, error) {
+	return miner * 10, nil
+}
+
+type result struct {
+	ID     interface{}
+	Result int
+}
+
+const batchReq = `[
+	{"jsonrpc": "2.0", "id": "a", "method": "Test.Power", "params": [1]},
+	{"jsonrpc": "2.0", "method": "Test.Power", "params": [2]},
+	{"jsonrpc": "2.0", "id": 7, "method": "Test.Power", "params": [3]}
+]`
+
+func checkBatch(t *testing.T, resp []result) {
+	require.Len(t, resp, 2)
+	require.Equal(t, "a", resp[0].ID)
+	require.Equal(t, 10, resp[0].Result)
+	require.Equal(t, float64(7), resp[1].ID)
+	require.Equal(t, 30, resp[1].Result)
+}
+
+func testServer(t *testing.T) *httptest.Server {
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register("Test", &PowerHandler{})
+	return httptest.NewServer(Handler(rpcServer, 2))
+}
+
+func TestHTTPBatch(t *testing.T) {
+	srv := testServer(t)
+	defer srv.Close()
+
+	r, err := http.Post(srv.URL, "application/json", strings.NewReader(batchReq))
+	require.NoError(t, err)
+	defer r.Body.Close() // nolint:errcheck
+
+	var resp []result
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&resp))
+	checkBatch(t, resp)
+
+	// single requests are served as before
+	r, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "Test.Power", "params": [4]}`))
+	require.NoError(t, err)
+	defer r.Body.Close() // nolint:errcheck
+
+	var single result
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&single))
+	require.Equal(t, 40, single.Result)
+}
+
+func TestInvalidBatchEntries(t *testing.T) {
+	srv := testServer(t)
+	defer srv.Close()
+
+	const req = `[1, "x", {"jsonrpc": "2.0", "id": 2, "method": "Test.Power", "params": [2]}]`
+	checkResp := func(resp []json.RawMessage) {
+		require.Len(t, resp, 3)
+		for _, r := range resp[:2] {
+			require.JSONEq(t, `{"jsonrpc": "2.0", "id": null, "error": {"code": -32600, "message": "batch entry is not an object"}}`, string(r))
+		}
+		var res result
+		require.NoError(t, json.Unmarshal(resp[2], &res))
+		require.Equal(t, float64(2), res.ID)
+		require.Equal(t, 20, res.Result)
+	}
+
+	r, err := http.Post(srv.URL, "application/json", strings.NewReader(req))
+	require.NoError(t, err)
+	defer r.Body.Close() // nolint:errcheck
+
+	var resp []json.RawMessage
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&resp))
+	checkResp(resp)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(req)))
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	resp = nil
+	require.NoError(t, json.Unmarshal(msg, &resp))
+	checkResp(resp)
+}
+
+func TestWebsocketBatch(t *testing.T) {
+	srv := testServer(t)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(batchReq)))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc": "2.0", "id": 1, "method": "Test.Power", "params": [4]}`)))
+
+	var batch []result
+	var single *result
+	for batch == nil || single == nil {
+		_, msg, err := conn.ReadMessage()
+		require.NoError(t, err)
+		if isBatch(msg) {
+			require.NoError(t, json.Unmarshal(msg, &batch))
+		} else {
+			single = new(result)
+			require.NoError(t, json.Unmarshal(msg, single))
+		}
+	}
+
+	checkBatch(t, batch)
+	require.Equal(t, float64(1), single.ID)
+	require.Equal(t, 40, single.Result)
+}
+
+func TestWebsocketLargeBatch(t *testing.T) {
+	srv := testServer(t)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	// more entries than the parallelism, with responses spanning many frames
+	var reqs []string
+	for i := 0; i < 500; i++ {
+		reqs = append(reqs, fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "method": "Test.Power", "params": [%d]}`, i, i))
+	}
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("["+strings.Join(reqs, ",")+"]")))
+
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var resp []result
+	require.NoError(t, json.Unmarshal(msg, &resp))
+	require.Len(t, resp, 500)
+	for i, r := range resp {
+		require.Equal(t, float64(i), r.ID)
+		require.Equal(t, i*10, r.Result)
+	}
+}
+
+func TestWebsocketClient(t *testing.T) {
+	srv := testServer(t)
+	defer srv.Close()
+
+	// go-jsonrpc clients are served as before
+	var client struct {
+		Power func(context.Context, int) (int, error)
+	}
+	closer, err := jsonrpc.NewClient(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), "Test", &client, nil)
+	require.NoError(t, err)
+	defer closer()
+
+	p, err := client.Power(context.Background(), 5)
+	require.NoError(t, err)
+	require.Equal(t, 50, p)
+}
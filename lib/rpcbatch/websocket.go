@@ -0,0 +1,470 @@
+package rpcbatch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// idBase is the first internal ID of the entries of the websocket batches,
+// far from the IDs clients count from.
+const idBase = 1 << 62
+
+// websocket opcodes, RFC 6455 section 5.2
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+)
+
+var (
+	errIncompleteFrame = xerrors.New("incomplete websocket frame")
+	errConnClosed      = xerrors.New("connection closed")
+)
+
+// hijackWriter hands the handler a batchConn when it takes over the
+// connection to serve a websocket session.
+type hijackWriter struct {
+	http.ResponseWriter
+	parallelism int
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, xerrors.New("the connection can't be hijacked")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bc := &batchConn{
+		Conn:     conn,
+		src:      brw.Reader,
+		pending:  map[int64]*pendingCall{},
+		nextID:   idBase,
+		throttle: make(chan struct{}, w.parallelism),
+		closed:   make(chan struct{}),
+	}
+	return bc, bufio.NewReadWriter(bufio.NewReader(bc), bufio.NewWriter(bc)), nil
+}
+
+type pendingCall struct {
+	b     *batch
+	index int
+	id    json.RawMessage
+}
+
+type batch struct {
+	resps []json.RawMessage
+	left  int
+}
+
+type queuedCall struct {
+	msg []byte
+	// set when a response is expected, and a throttle token is needed
+	call bool
+}
+
+// batchConn is the connection of a websocket client as seen by the handler.
+// Frames pass through it unchanged, except the frames of the messages holding
+// a batch: the entries of the batch are read by the handler as
+// separate frames under internal IDs, and the frames of their responses are
+// held back until the batch is complete, to be sent to the client as one
+// array.
+type batchConn struct {
+	net.Conn
+
+	// read side, from the client to the handler
+	src        *bufio.Reader
+	rbuf       []byte
+	queued     []queuedCall
+	fragmented bool
+	batchMsg   []byte
+	batchOp    byte
+
+	// write side, from the handler to the client
+	wlk      sync.Mutex
+	upgraded bool
+	wbuf     []byte
+	passing  bool
+	msg      []byte
+	msgOp    byte
+
+	lk      sync.Mutex
+	pending map[int64]*pendingCall
+	nextID  int64
+
+	// holds a token for each entry of a batch waiting for its response
+	throttle chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *batchConn) Read(p []byte) (int, error) {
+	for len(c.rbuf) == 0 {
+		if err := c.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+// fill loads the next frame read by the handler: the next queued entry of a
+// batch, or the next frame of the client.
+func (c *batchConn) fill() error {
+	if len(c.queued) > 0 {
+		q := c.queued[0]
+		if q.call {
+			select {
+			case c.throttle <- struct{}{}:
+			case <-c.closed:
+				return errConnClosed
+			}
+		}
+		c.queued = c.queued[1:]
+		c.rbuf = encodeFrame(opText, q.msg, true)
+		return nil
+	}
+
+	raw, err := c.readClientFrame()
+	if err != nil {
+		return err
+	}
+	f, _, err := parseFrame(raw)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case f.opcode >= opClose:
+		// control frames can come between the frames of a message
+	case c.batchMsg != nil:
+		if len(c.batchMsg)+len(f.payload) > maxBatchSize {
+			return xerrors.New("batch too large")
+		}
+		c.batchMsg = append(c.batchMsg, f.payload...)
+		if !f.fin {
+			return nil
+		}
+		msg := c.batchMsg
+		c.batchMsg = nil
+		return c.startBatch(c.batchOp, msg)
+	case f.opcode == opContinuation:
+		c.fragmented = !f.fin
+	case (f.opcode == opText || f.opcode == opBinary) && !c.fragmented && isBatch(f.payload):
+		if f.fin {
+			return c.startBatch(f.opcode, f.payload)
+		}
+		c.batchMsg = append([]byte{}, f.payload...)
+		c.batchOp = f.opcode
+		return nil
+	default:
+		c.fragmented = !f.fin
+	}
+	c.rbuf = raw
+	return nil
+}
+
+func (c *batchConn) readClientFrame() ([]byte, error) {
+	hdr, err := c.src.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if hdr, err = c.src.Peek(headerLen(hdr)); err != nil {
+		return nil, err
+	}
+	length := payloadLen(hdr)
+	if length > maxBatchSize {
+		return nil, xerrors.Errorf("websocket frame of %d bytes is too large", length)
+	}
+
+	raw := make([]byte, len(hdr)+int(length))
+	if _, err := io.ReadFull(c.src, raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// startBatch queues the entries of a batch for the handler.
+func (c *batchConn) startBatch(op byte, data []byte) error {
+	var reqs []json.RawMessage
+	if err := json.Unmarshal(data, &reqs); err != nil {
+		return c.writeClient(encodeFrame(op, mustMarshal(errorResponse(nil, codeParseError, err.Error())), false))
+	}
+	if len(reqs) == 0 {
+		return c.writeClient(encodeFrame(op, mustMarshal(errorResponse(nil, codeInvalidRequest, "empty batch")), false))
+	}
+
+	b := &batch{resps: make([]json.RawMessage, len(reqs))}
+	c.lk.Lock()
+	for i, req := range reqs {
+		if !isObject(req) {
+			b.resps[i] = mustMarshal(errorResponse(nil, codeInvalidRequest, "batch entry is not an object"))
+			continue
+		}
+		id := requestID(req)
+		if id == nil {
+			// notifications are passed as they are, with no response
+			c.queued = append(c.queued, queuedCall{msg: req})
+			continue
+		}
+
+		c.nextID++
+		call, err := withID(req, c.nextID)
+		if err != nil {
+			b.resps[i] = mustMarshal(errorResponse(id, codeInvalidRequest, err.Error()))
+			continue
+		}
+		c.pending[c.nextID] = &pendingCall{b: b, index: i, id: id}
+		c.queued = append(c.queued, queuedCall{msg: call, call: true})
+		b.left++
+	}
+	c.lk.Unlock()
+
+	if b.left == 0 {
+		if f := batchFrame(op, b); f != nil {
+			return c.writeClient(f)
+		}
+	}
+	return nil
+}
+
+func (c *batchConn) Write(p []byte) (int, error) {
+	c.wlk.Lock()
+	defer c.wlk.Unlock()
+
+	data := p
+	if !c.upgraded {
+		// the handshake response comes first, in one write
+		end := bytes.Index(data, []byte("\r\n\r\n"))
+		if end < 0 {
+			return c.Conn.Write(p)
+		}
+		if _, err := c.Conn.Write(data[:end+4]); err != nil {
+			return 0, err
+		}
+		c.upgraded = true
+		data = data[end+4:]
+	}
+
+	c.wbuf = append(c.wbuf, data...)
+	for len(c.wbuf) > 0 {
+		f, n, err := parseFrame(c.wbuf)
+		if err == errIncompleteFrame {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		raw := c.wbuf[:n]
+
+		if err := c.handlerFrame(raw, f); err != nil {
+			return 0, err
+		}
+		c.wbuf = c.wbuf[n:]
+	}
+	if len(c.wbuf) == 0 {
+		c.wbuf = nil
+	}
+
+	return len(p), nil
+}
+
+// handlerFrame passes a frame written by the handler to the client. While
+// entries of batches wait for their responses, the data frames are
+// assembled into messages, so that the responses can be matched by ID.
+func (c *batchConn) handlerFrame(raw []byte, f frame) error {
+	switch {
+	case f.opcode >= opClose:
+		// control frames can come between the frames of a message
+		return c.forward(raw)
+	case c.msg != nil:
+		c.msg = append(c.msg, f.payload...)
+	case c.passing || !c.hasPending():
+		c.passing = !f.fin
+		return c.forward(raw)
+	default:
+		c.msg = append([]byte{}, f.payload...)
+		c.msgOp = f.opcode
+	}
+
+	if !f.fin {
+		return nil
+	}
+	msg := c.msg
+	c.msg = nil
+	return c.handlerMessage(c.msgOp, msg)
+}
+
+func (c *batchConn) hasPending() bool {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return len(c.pending) > 0
+}
+
+func (c *batchConn) handlerMessage(op byte, data []byte) error {
+	var resp struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	var id int64
+	if json.Unmarshal(data, &resp) != nil || resp.Method != "" || json.Unmarshal(resp.ID, &id) != nil {
+		return c.forward(encodeFrame(op, data, false))
+	}
+
+	c.lk.Lock()
+	pc, ok := c.pending[id]
+	delete(c.pending, id)
+	c.lk.Unlock()
+	if !ok {
+		return c.forward(encodeFrame(op, data, false))
+	}
+	<-c.throttle
+
+	out, err := withID(data, pc.id)
+	if err != nil {
+		out = mustMarshal(errorResponse(pc.id, codeInternalError, err.Error()))
+	}
+
+	c.lk.Lock()
+	pc.b.resps[pc.index] = out
+	pc.b.left--
+	complete := pc.b.left == 0
+	c.lk.Unlock()
+
+	if !complete {
+		return nil
+	}
+	if f := batchFrame(op, pc.b); f != nil {
+		return c.forward(f)
+	}
+	return nil
+}
+
+// batchFrame returns the frame of the responses of a batch, nil when it's a
+// batch of notifications.
+func batchFrame(op byte, b *batch) []byte {
+	out := make([]json.RawMessage, 0, len(b.resps))
+	for _, resp := range b.resps {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return encodeFrame(op, mustMarshal(out), false)
+}
+
+// writeClient writes a frame to the client from the read side.
+func (c *batchConn) writeClient(raw []byte) error {
+	c.wlk.Lock()
+	defer c.wlk.Unlock()
+	return c.forward(raw)
+}
+
+func (c *batchConn) forward(raw []byte) error {
+	_, err := c.Conn.Write(raw)
+	return err
+}
+
+func (c *batchConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+func headerLen(hdr []byte) int {
+	n := 2
+	switch hdr[1] & 0x7f {
+	case 126:
+		n += 2
+	case 127:
+		n += 8
+	}
+	if hdr[1]&0x80 != 0 {
+		n += 4
+	}
+	return n
+}
+
+func payloadLen(hdr []byte) uint64 {
+	switch l := hdr[1] & 0x7f; l {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(hdr[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(hdr[2:10])
+	default:
+		return uint64(l)
+	}
+}
+
+// parseFrame decodes the frame at the start of b, returning it with its
+// size, and unmasks its payload.
+func parseFrame(b []byte) (frame, int, error) {
+	if len(b) < 2 || len(b) < headerLen(b) {
+		return frame{}, 0, errIncompleteFrame
+	}
+	hl := headerLen(b)
+	length := payloadLen(b)
+	if length > uint64(len(b)-hl) {
+		return frame{}, 0, errIncompleteFrame
+	}
+	n := hl + int(length)
+
+	f := frame{
+		fin:     b[0]&0x80 != 0,
+		opcode:  b[0] & 0x0f,
+		payload: b[hl:n],
+	}
+	if b[1]&0x80 != 0 {
+		key := b[hl-4 : hl]
+		payload := make([]byte, length)
+		for i := range payload {
+			payload[i] = f.payload[i] ^ key[i%4]
+		}
+		f.payload = payload
+	}
+	return f, n, nil
+}
+
+// encodeFrame builds an unfragmented frame; frames of the client must be
+// masked, a zero key leaves the payload as it is.
+func encodeFrame(op byte, payload []byte, masked bool) []byte {
+	out := []byte{0x80 | op, 0}
+	switch l := len(payload); {
+	case l < 126:
+		out[1] = byte(l)
+	case l <= 0xffff:
+		out[1] = 126
+		out = append(out, 0, 0)
+		binary.BigEndian.PutUint16(out[2:], uint16(l))
+	default:
+		out[1] = 127
+		out = append(out, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(out[2:], uint64(l))
+	}
+	if masked {
+		out[1] |= 0x80
+		out = append(out, 0, 0, 0, 0)
+	}
+	return append(out, payload...)
+}
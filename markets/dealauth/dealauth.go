@@ -0,0 +1,235 @@
+// Package dealauth lets a funding address authorize another address, its
+// delegate, to make storage deals on its behalf, so that automated deal clients
+// don't need to hold the key of the funds they spend.
+//
+// The market actor only accepts proposals signed by the key of their client,
+// so the delegate is the client of its deals: it signs their proposals with its
+// own key and pays them from its market escrow, which the funder tops up. The
+// authorization is signed by the key of the funder, and is verified against the
+// key of the funder in the chain state, so providers accepting the deals of some
+// funders can tell the deals of their delegates from the deals of anyone else.
+package dealauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/crypto"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/sigs"
+	_ "github.com/filecoin-project/lotus/lib/sigs/bls"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+)
+
+// signingPrefix separates the signatures of authorizations from the signatures
+// of any other data.
+const signingPrefix = "lotus deal authorization:"
+
+// SigningBytes returns the bytes signed by the funder of an authorization.
+func SigningBytes(auth api.DealAuthorization) ([]byte, error) {
+	b, err := json.Marshal(auth)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding authorization: %w", err)
+	}
+	return append([]byte(signingPrefix), b...), nil
+}
+
+// Signer signs with the keys of a wallet.
+type Signer interface {
+	WalletSign(ctx context.Context, signer address.Address, toSign []byte, meta api.MsgMeta) (*crypto.Signature, error)
+}
+
+// Sign signs the authorization with the key of its funder. The funder and the
+// delegate must be key addresses.
+func Sign(ctx context.Context, w Signer, auth api.DealAuthorization) (*api.SignedDealAuthorization, error) {
+	if auth.Funder.Protocol() == address.ID || auth.Delegate.Protocol() == address.ID {
+		return nil, xerrors.Errorf("the funder and delegate of an authorization must be key addresses")
+	}
+
+	b, err := SigningBytes(auth)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.WalletSign(ctx, auth.Funder, b, api.MsgMeta{Type: api.MTDealAuthorization})
+	if err != nil {
+		return nil, xerrors.Errorf("signing authorization: %w", err)
+	}
+
+	return &api.SignedDealAuthorization{
+		DealAuthorization: auth,
+		Signature:         *sig,
+	}, nil
+}
+
+// ChainAPI is the chain state the authorizations are verified against.
+type ChainAPI interface {
+	ChainHead(context.Context) (*types.TipSet, error)
+	StateAccountKey(context.Context, address.Address, types.TipSetKey) (address.Address, error)
+}
+
+// Verify checks that the authorization is signed by the key of its funder in
+// the state of ts, and that it doesn't expire before ts.
+func Verify(ctx context.Context, chain ChainAPI, ts *types.TipSet, auth *api.SignedDealAuthorization) error {
+	if ts.Height() > auth.Expiration {
+		return xerrors.Errorf("authorization of %s by %s expired at epoch %d", auth.Delegate, auth.Funder, auth.Expiration)
+	}
+
+	key, err := chain.StateAccountKey(ctx, auth.Funder, ts.Key())
+	if err != nil {
+		return xerrors.Errorf("getting the key of funder %s: %w", auth.Funder, err)
+	}
+
+	b, err := SigningBytes(auth.DealAuthorization)
+	if err != nil {
+		return err
+	}
+	if err := sigs.Verify(&auth.Signature, key, b); err != nil {
+		return xerrors.Errorf("authorization of %s is not signed by funder %s: %w", auth.Delegate, auth.Funder, err)
+	}
+	return nil
+}
+
+// Authorizations keeps the authorizations imported by a provider, and checks
+// the storage deals proposed to it against them.
+type Authorizations struct {
+	ds    datastore.Batching
+	chain ChainAPI
+	lk    sync.Mutex
+}
+
+func NewAuthorizations(ds datastore.Batching, chain ChainAPI) *Authorizations {
+	return &Authorizations{
+		ds:    namespace.Wrap(ds, datastore.NewKey("/deals/authorizations")),
+		chain: chain,
+	}
+}
+
+// Import verifies the authorization at the chain head and keeps it, replacing
+// any previous authorization of the delegate by the same funder.
+func (a *Authorizations) Import(ctx context.Context, auth api.SignedDealAuthorization) error {
+	if auth.Delegate.Protocol() == address.ID {
+		return xerrors.Errorf("the delegate of an authorization must be a key address")
+	}
+
+	ts, err := a.chain.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+	if err := Verify(ctx, a.chain, ts, &auth); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(&auth)
+	if err != nil {
+		return err
+	}
+
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	return a.ds.Put(authKey(auth.Delegate, auth.Funder), b)
+}
+
+// List returns the authorizations imported.
+func (a *Authorizations) List() ([]api.SignedDealAuthorization, error) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	return a.query(query.Query{})
+}
+
+func (a *Authorizations) query(q query.Query) ([]api.SignedDealAuthorization, error) {
+	res, err := a.ds.Query(q)
+	if err != nil {
+		return nil, xerrors.Errorf("querying authorizations: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	out := []api.SignedDealAuthorization{}
+	for e := range res.Next() {
+		if e.Error != nil {
+			return nil, xerrors.Errorf("reading authorizations: %w", e.Error)
+		}
+
+		var auth api.SignedDealAuthorization
+		if err := json.Unmarshal(e.Value, &auth); err != nil {
+			return nil, xerrors.Errorf("decoding authorization %s: %w", e.Key, err)
+		}
+		out = append(out, auth)
+	}
+	return out, nil
+}
+
+// Accept checks that the client of the deal is one of the funders, or a
+// delegate they authorized. Any client is accepted when there are no funders.
+// It returns the rejection reason otherwise.
+func (a *Authorizations) Accept(ctx context.Context, deal storagemarket.MinerDeal, funders []address.Address) (bool, string, error) {
+	if len(funders) == 0 {
+		return true, "", nil
+	}
+
+	ts, err := a.chain.ChainHead(ctx)
+	if err != nil {
+		return false, "", xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	client, err := a.chain.StateAccountKey(ctx, deal.Proposal.Client, ts.Key())
+	if err != nil {
+		return false, "", xerrors.Errorf("getting the key of client %s: %w", deal.Proposal.Client, err)
+	}
+
+	allowed := map[address.Address]struct{}{}
+	for _, f := range funders {
+		key, err := a.chain.StateAccountKey(ctx, f, ts.Key())
+		if err != nil {
+			return false, "", xerrors.Errorf("getting the key of funder %s: %w", f, err)
+		}
+		if key == client {
+			return true, "", nil
+		}
+		allowed[key] = struct{}{}
+	}
+
+	a.lk.Lock()
+	auths, err := a.query(query.Query{Prefix: delegateKey(client).String()})
+	a.lk.Unlock()
+	if err != nil {
+		return false, "", err
+	}
+
+	reason := fmt.Sprintf("client %s is neither an authorized funder nor one of their delegates", deal.Proposal.Client)
+	for i := range auths {
+		key, err := a.chain.StateAccountKey(ctx, auths[i].Funder, ts.Key())
+		if err != nil {
+			return false, "", xerrors.Errorf("getting the key of funder %s: %w", auths[i].Funder, err)
+		}
+		if _, ok := allowed[key]; !ok {
+			continue
+		}
+
+		if err := Verify(ctx, a.chain, ts, &auths[i]); err != nil {
+			reason = err.Error()
+			continue
+		}
+		return true, "", nil
+	}
+
+	return false, reason, nil
+}
+
+func delegateKey(delegate address.Address) datastore.Key {
+	return datastore.NewKey(delegate.String())
+}
+
+func authKey(delegate, funder address.Address) datastore.Key {
+	return delegateKey(delegate).ChildString(funder.String())
+}
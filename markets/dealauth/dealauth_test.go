@@ -0,0 +1,129 @@
+package dealauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+	"github.com/filecoin-project/lotus/chain/wallet"
+)
+
+type mockChain struct {
+	height abi.ChainEpoch
+	keys   map[address.Address]address.Address
+}
+
+func (m *mockChain) ChainHead(context.Context) (*types.TipSet, error) {
+	b := mock.MkBlock(nil, 1, 1)
+	b.Height = m.height
+	return mock.TipSet(b), nil
+}
+
+func (m *mockChain) StateAccountKey(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error) {
+	if addr.Protocol() != address.ID {
+		return addr, nil
+	}
+	key, ok := m.keys[addr]
+	if !ok {
+		return address.Undef, xerrors.Errorf("actor %s not found", addr)
+	}
+	return key, nil
+}
+
+func mkDeal(client address.Address) storagemarket.MinerDeal {
+	return storagemarket.MinerDeal{
+		ClientDealProposal: market.ClientDealProposal{
+			Proposal: market.DealProposal{
+				Client: client,
+			},
+		},
+	}
+}
+
+func TestAuthorizations(t *testing.T) {
+	ctx := context.Background()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	require.NoError(t, err)
+	funder, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	other, err := w.WalletNew(ctx, types.KTBLS)
+	require.NoError(t, err)
+	delegate, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+	stranger, err := w.WalletNew(ctx, types.KTSecp256k1)
+	require.NoError(t, err)
+
+	funderID, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	delegateID, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	chain := &mockChain{
+		height: 100,
+		keys: map[address.Address]address.Address{
+			funderID:   funder,
+			delegateID: delegate,
+		},
+	}
+	auths := NewAuthorizations(dssync.MutexWrap(datastore.NewMapDatastore()), chain)
+
+	accept := func(client address.Address, funders ...address.Address) bool {
+		ok, reason, err := auths.Accept(ctx, mkDeal(client), funders)
+		require.NoError(t, err)
+		if ok {
+			require.Empty(t, reason)
+		} else {
+			require.NotEmpty(t, reason)
+		}
+		return ok
+	}
+
+	// any client is accepted without funders
+	require.True(t, accept(stranger))
+	// the funders are accepted, whatever their address
+	require.True(t, accept(funderID, funder))
+	require.True(t, accept(funder, funderID))
+	require.False(t, accept(delegate, funder))
+
+	_, err = Sign(ctx, w, api.DealAuthorization{Funder: funderID, Delegate: delegate, Expiration: 200})
+	require.Error(t, err)
+
+	auth, err := Sign(ctx, w, api.DealAuthorization{Funder: funder, Delegate: delegate, Expiration: 200})
+	require.NoError(t, err)
+
+	// the authorization must be signed by the funder
+	forged := *auth
+	forged.Funder = other
+	require.Error(t, auths.Import(ctx, forged))
+	tampered := *auth
+	tampered.Expiration = 300
+	require.Error(t, auths.Import(ctx, tampered))
+
+	require.NoError(t, auths.Import(ctx, *auth))
+	list, err := auths.List()
+	require.NoError(t, err)
+	require.Equal(t, []api.SignedDealAuthorization{*auth}, list)
+
+	require.True(t, accept(delegate, funder))
+	require.True(t, accept(delegateID, funderID))
+	// only for the funders it was authorized by
+	require.False(t, accept(delegate, other))
+	require.False(t, accept(stranger, funder))
+
+	// until it expires
+	chain.height = 201
+	require.False(t, accept(delegate, funder))
+	require.Error(t, auths.Import(ctx, *auth))
+}
@@ -0,0 +1,132 @@
+package dealfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+type acceptedDeal struct {
+	Time time.Time
+	Size uint64
+}
+
+// ClientQuota keeps track of the storage deals accepted from each client
+// address, so that a single client can't take up all of the miner's
+// onboarding capacity. Accepted deals are recorded in the datastore and
+// forgotten once they fall out of the quota period.
+type ClientQuota struct {
+	ds datastore.Batching
+	lk sync.Mutex
+}
+
+func NewClientQuota(ds datastore.Batching) *ClientQuota {
+	return &ClientQuota{
+		ds: namespace.Wrap(ds, datastore.NewKey("/deals/client-quota")),
+	}
+}
+
+// Accept checks that accepting the deal keeps its client within the limits,
+// and if so records it. It returns the rejection reason otherwise.
+func (q *ClientQuota) Accept(deal storagemarket.MinerDeal, limits dtypes.StorageDealClientLimits) (bool, string, error) {
+	if limits.MaxDeals == 0 && limits.MaxBytes == 0 {
+		return true, "", nil
+	}
+	if limits.Period <= 0 {
+		return false, "", xerrors.Errorf("client quota period must be positive, was %s", limits.Period)
+	}
+
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	client := deal.Proposal.Client
+	key := clientKey(client).ChildString(deal.ProposalCid.String())
+
+	// the filter may run again for a deal which was already accepted
+	if has, err := q.ds.Has(key); err != nil {
+		return false, "", xerrors.Errorf("checking client quota: %w", err)
+	} else if has {
+		return true, "", nil
+	}
+
+	now := build.Clock.Now()
+	deals, bytes, err := q.usage(client, now.Add(-limits.Period))
+	if err != nil {
+		return false, "", err
+	}
+
+	size := uint64(deal.Proposal.PieceSize)
+	if limits.MaxDeals > 0 && deals+1 > limits.MaxDeals {
+		return false, fmt.Sprintf("client %s has reached the limit of %d deals per %s", client, limits.MaxDeals, limits.Period), nil
+	}
+	if limits.MaxBytes > 0 && bytes+size > limits.MaxBytes {
+		return false, fmt.Sprintf("deal of %s would exceed the limit of %s per %s for client %s (%s already accepted)",
+			types.SizeStr(types.NewInt(size)), types.SizeStr(types.NewInt(limits.MaxBytes)), limits.Period, client,
+			types.SizeStr(types.NewInt(bytes))), nil
+	}
+
+	b, err := json.Marshal(&acceptedDeal{Time: now, Size: size})
+	if err != nil {
+		return false, "", err
+	}
+	if err := q.ds.Put(key, b); err != nil {
+		return false, "", xerrors.Errorf("recording accepted deal: %w", err)
+	}
+
+	return true, "", nil
+}
+
+// usage returns the number and total size of the deals accepted from the
+// client since the given time, and deletes older records.
+func (q *ClientQuota) usage(client address.Address, since time.Time) (deals, bytes uint64, err error) {
+	res, err := q.ds.Query(query.Query{Prefix: clientKey(client).String()})
+	if err != nil {
+		return 0, 0, xerrors.Errorf("querying client quota: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var expired []datastore.Key
+	for e := range res.Next() {
+		if e.Error != nil {
+			return 0, 0, xerrors.Errorf("reading client quota: %w", e.Error)
+		}
+
+		var d acceptedDeal
+		if err := json.Unmarshal(e.Value, &d); err != nil {
+			return 0, 0, xerrors.Errorf("decoding accepted deal %s: %w", e.Key, err)
+		}
+
+		if d.Time.Before(since) {
+			expired = append(expired, datastore.NewKey(e.Key))
+			continue
+		}
+
+		deals++
+		bytes += d.Size
+	}
+
+	for _, k := range expired {
+		if err := q.ds.Delete(k); err != nil {
+			return 0, 0, xerrors.Errorf("deleting expired quota record: %w", err)
+		}
+	}
+
+	return deals, bytes, nil
+}
+
+func clientKey(client address.Address) datastore.Key {
+	return datastore.NewKey(client.String())
+}
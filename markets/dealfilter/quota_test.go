@@ -0,0 +1,84 @@
+package dealfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+)
+
+func TestClientQuota(t *testing.T) {
+	q := NewClientQuota(dssync.MutexWrap(datastore.NewMapDatastore()))
+
+	var n int
+	mkDeal := func(client uint64, size abi.PaddedPieceSize) storagemarket.MinerDeal {
+		n++
+		addr, err := address.NewIDAddress(client)
+		require.NoError(t, err)
+		h, err := multihash.Sum([]byte{byte(n)}, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+
+		return storagemarket.MinerDeal{
+			ClientDealProposal: market.ClientDealProposal{
+				Proposal: market.DealProposal{
+					Client:    addr,
+					PieceSize: size,
+				},
+			},
+			ProposalCid: cid.NewCidV1(cid.DagCBOR, h),
+		}
+	}
+
+	limits := dtypes.StorageDealClientLimits{
+		MaxDeals: 3,
+		MaxBytes: 4 << 10,
+		Period:   time.Hour,
+	}
+
+	accept := func(deal storagemarket.MinerDeal) bool {
+		ok, reason, err := q.Accept(deal, limits)
+		require.NoError(t, err)
+		if ok {
+			require.Empty(t, reason)
+		} else {
+			require.NotEmpty(t, reason)
+		}
+		return ok
+	}
+
+	d := mkDeal(1000, 1<<10)
+	require.True(t, accept(d))
+	// the same deal only counts once
+	require.True(t, accept(d))
+	require.True(t, accept(mkDeal(1000, 1<<10)))
+
+	// too large for the remaining 2KiB
+	require.False(t, accept(mkDeal(1000, 4<<10)))
+	require.True(t, accept(mkDeal(1000, 2<<10)))
+	// out of deals
+	require.False(t, accept(mkDeal(1000, 128)))
+
+	// other clients have their own quota
+	require.True(t, accept(mkDeal(1001, 4<<10)))
+
+	// the quota frees up once the period passes
+	require.False(t, accept(mkDeal(1000, 128)))
+	limits.Period = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	require.True(t, accept(mkDeal(1000, 128)))
+
+	// no limits
+	limits = dtypes.StorageDealClientLimits{}
+	require.True(t, accept(mkDeal(1001, 1<<30)))
+}
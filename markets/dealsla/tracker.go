@@ -0,0 +1,406 @@
+package dealsla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/raulk/clock"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+)
+
+var log = logging.Logger("dealsla")
+
+var (
+	reportsPrefix = datastore.NewKey("/reports")
+	currentKey    = datastore.NewKey("/current")
+)
+
+// EpochFunc returns the current chain epoch.
+type EpochFunc func(context.Context) (abi.ChainEpoch, error)
+
+// samples are the measurements of the deals in a period, from which its
+// report is computed.
+type samples struct {
+	Start time.Time
+
+	Ingest             []time.Duration
+	Activation         []time.Duration
+	ActivationMargin   []abi.ChainEpoch
+	DealsActivatedLate int
+	DealsFailed        int
+
+	RetrievalResponse   []time.Duration
+	RetrievalCompletion []time.Duration
+	RetrievalsFailed    int
+}
+
+type storageDeal struct {
+	received      time.Time
+	transferStart time.Time
+	rejected      bool
+}
+
+type retrievalDeal struct {
+	received  time.Time
+	responded bool
+}
+
+// Tracker measures the service levels of the storage and retrieval deals of
+// the miner from their state changes, and aggregates them in reports over
+// periods of fixed length, aligned on multiples of the period since the Unix
+// epoch. The reports of past periods are kept in the datastore.
+type Tracker struct {
+	ds     datastore.Batching
+	period time.Duration
+	kept   int
+	epoch  EpochFunc
+	clock  clock.Clock
+
+	lk        sync.Mutex
+	cur       *samples
+	storage   map[cid.Cid]*storageDeal
+	retrieval map[retrievalmarket.ProviderDealIdentifier]*retrievalDeal
+}
+
+// NewTracker returns a tracker reporting over periods of the given length,
+// keeping the reports of the last kept periods, or all of them if kept is 0.
+// The measurements of the current period saved by Stop are loaded back.
+func NewTracker(ds datastore.Batching, period time.Duration, kept int, epoch EpochFunc) (*Tracker, error) {
+	if period <= 0 {
+		return nil, xerrors.Errorf("deal SLA report period must be positive, was %s", period)
+	}
+
+	t := &Tracker{
+		ds:        namespace.Wrap(ds, datastore.NewKey("/deals/sla")),
+		period:    period,
+		kept:      kept,
+		epoch:     epoch,
+		clock:     build.Clock,
+		storage:   map[cid.Cid]*storageDeal{},
+		retrieval: map[retrievalmarket.ProviderDealIdentifier]*retrievalDeal{},
+	}
+
+	b, err := t.ds.Get(currentKey)
+	switch err {
+	case nil:
+		var cur samples
+		if err := json.Unmarshal(b, &cur); err != nil {
+			return nil, xerrors.Errorf("decoding deal SLA measurements: %w", err)
+		}
+		t.cur = &cur
+	case datastore.ErrNotFound:
+		t.cur = &samples{Start: t.periodStart(t.clock.Now())}
+	default:
+		return nil, xerrors.Errorf("loading deal SLA measurements: %w", err)
+	}
+
+	return t, nil
+}
+
+// Stop saves the measurements of the current period.
+func (t *Tracker) Stop() error {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if err := t.roll(t.clock.Now()); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(t.cur)
+	if err != nil {
+		return err
+	}
+	return t.ds.Put(currentKey, b)
+}
+
+func (t *Tracker) periodStart(now time.Time) time.Time {
+	return now.Truncate(t.period)
+}
+
+// OnStorageEvent records the state changes of storage deals, it's meant to be
+// subscribed to the events of the storage provider.
+func (t *Tracker) OnStorageEvent(evt storagemarket.ProviderEvent, deal storagemarket.MinerDeal) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	now := t.clock.Now()
+	if err := t.roll(now); err != nil {
+		log.Errorf("closing deal SLA report: %+v", err)
+	}
+
+	received := deal.CreationTime.Time()
+	d, ok := t.storage[deal.ProposalCid]
+	if ok {
+		received = d.received
+	}
+	track := func() *storageDeal {
+		if d == nil {
+			d = &storageDeal{received: received}
+			t.storage[deal.ProposalCid] = d
+		}
+		return d
+	}
+
+	switch evt {
+	case storagemarket.ProviderEventOpen:
+		track()
+	case storagemarket.ProviderEventDealRejected:
+		track().rejected = true
+	case storagemarket.ProviderEventDataTransferInitiated:
+		track().transferStart = now
+	case storagemarket.ProviderEventDataTransferCompleted:
+		// the start of transfers is only known if they started while the
+		// miner was running
+		if d != nil && !d.transferStart.IsZero() {
+			t.cur.Ingest = append(t.cur.Ingest, now.Sub(d.transferStart))
+			d.transferStart = time.Time{}
+		}
+	case storagemarket.ProviderEventDealActivated:
+		delete(t.storage, deal.ProposalCid)
+		if !received.IsZero() {
+			t.cur.Activation = append(t.cur.Activation, now.Sub(received))
+		}
+
+		epoch, err := t.epoch(context.TODO())
+		if err != nil {
+			log.Warnf("getting epoch of the activation of deal %s: %s", deal.ProposalCid, err)
+			break
+		}
+		margin := deal.Proposal.StartEpoch - epoch
+		t.cur.ActivationMargin = append(t.cur.ActivationMargin, margin)
+		if margin < 0 {
+			t.cur.DealsActivatedLate++
+		}
+	case storagemarket.ProviderEventFailed:
+		delete(t.storage, deal.ProposalCid)
+		if d == nil || !d.rejected {
+			t.cur.DealsFailed++
+		}
+	case storagemarket.ProviderEventDealExpired, storagemarket.ProviderEventDealSlashed:
+		delete(t.storage, deal.ProposalCid)
+	}
+}
+
+// OnRetrievalEvent records the state changes of retrieval deals, it's meant
+// to be subscribed to the events of the retrieval provider.
+func (t *Tracker) OnRetrievalEvent(evt retrievalmarket.ProviderEvent, deal retrievalmarket.ProviderDealState) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	now := t.clock.Now()
+	if err := t.roll(now); err != nil {
+		log.Errorf("closing deal SLA report: %+v", err)
+	}
+
+	id := deal.Identifier()
+	d, ok := t.retrieval[id]
+	if !ok {
+		if deal.Status != retrievalmarket.DealStatusNew {
+			// received before the miner started
+			return
+		}
+		d = &retrievalDeal{received: now}
+		t.retrieval[id] = d
+	}
+
+	if deal.Status != retrievalmarket.DealStatusNew && !d.responded {
+		d.responded = true
+		t.cur.RetrievalResponse = append(t.cur.RetrievalResponse, now.Sub(d.received))
+	}
+
+	switch deal.Status {
+	case retrievalmarket.DealStatusCompleted:
+		delete(t.retrieval, id)
+		t.cur.RetrievalCompletion = append(t.cur.RetrievalCompletion, now.Sub(d.received))
+	case retrievalmarket.DealStatusErrored:
+		delete(t.retrieval, id)
+		t.cur.RetrievalsFailed++
+	case retrievalmarket.DealStatusRejected:
+		delete(t.retrieval, id)
+	}
+}
+
+// roll closes the current period if it's over, saving its report, and
+// forgets the deals which have been in progress for longer than a period.
+func (t *Tracker) roll(now time.Time) error {
+	if now.Before(t.cur.Start.Add(t.period)) {
+		return nil
+	}
+
+	closed := t.cur
+	t.cur = &samples{Start: t.periodStart(now)}
+
+	// the deals are only tracked until the steps measured from the events
+	// complete, which takes less than a period but for stuck deals
+	for c, d := range t.storage {
+		if d.received.Before(closed.Start) {
+			delete(t.storage, c)
+		}
+	}
+	for id, d := range t.retrieval {
+		if d.received.Before(closed.Start) {
+			delete(t.retrieval, id)
+		}
+	}
+
+	b, err := json.Marshal(t.report(closed, false))
+	if err != nil {
+		return err
+	}
+	if err := t.ds.Put(reportKey(closed.Start), b); err != nil {
+		return xerrors.Errorf("saving deal SLA report: %w", err)
+	}
+
+	if t.kept == 0 {
+		return nil
+	}
+	return t.prune(t.cur.Start.Add(-time.Duration(t.kept) * t.period))
+}
+
+// prune deletes the reports of the periods starting before the given time.
+func (t *Tracker) prune(before time.Time) error {
+	res, err := t.ds.Query(query.Query{Prefix: reportsPrefix.String(), KeysOnly: true})
+	if err != nil {
+		return xerrors.Errorf("querying deal SLA reports: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var old []datastore.Key
+	for e := range res.Next() {
+		if e.Error != nil {
+			return xerrors.Errorf("reading deal SLA reports: %w", e.Error)
+		}
+		if datastore.NewKey(e.Key).BaseNamespace() < reportKey(before).BaseNamespace() {
+			old = append(old, datastore.NewKey(e.Key))
+		}
+	}
+
+	for _, k := range old {
+		if err := t.ds.Delete(k); err != nil {
+			return xerrors.Errorf("deleting deal SLA report: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reports returns the reports of the last count periods, most recent first,
+// starting with the report of the current period; 0 returns all of them.
+func (t *Tracker) Reports(count int) ([]api.DealSLAReport, error) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if err := t.roll(t.clock.Now()); err != nil {
+		return nil, err
+	}
+
+	out := []api.DealSLAReport{t.report(t.cur, true)}
+
+	res, err := t.ds.Query(query.Query{Prefix: reportsPrefix.String()})
+	if err != nil {
+		return nil, xerrors.Errorf("querying deal SLA reports: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var past []api.DealSLAReport
+	for e := range res.Next() {
+		if e.Error != nil {
+			return nil, xerrors.Errorf("reading deal SLA reports: %w", e.Error)
+		}
+		var r api.DealSLAReport
+		if err := json.Unmarshal(e.Value, &r); err != nil {
+			return nil, xerrors.Errorf("decoding deal SLA report %s: %w", e.Key, err)
+		}
+		past = append(past, r)
+	}
+	sort.Slice(past, func(i, j int) bool {
+		return past[i].Start.After(past[j].Start)
+	})
+	out = append(out, past...)
+
+	if count > 0 && len(out) > count {
+		out = out[:count]
+	}
+	return out, nil
+}
+
+func (t *Tracker) report(s *samples, inProgress bool) api.DealSLAReport {
+	return api.DealSLAReport{
+		Start:      s.Start,
+		End:        s.Start.Add(t.period),
+		InProgress: inProgress,
+
+		Ingest:             durationStats(s.Ingest),
+		Activation:         durationStats(s.Activation),
+		ActivationMargin:   epochStats(s.ActivationMargin),
+		DealsActivatedLate: s.DealsActivatedLate,
+		DealsFailed:        s.DealsFailed,
+
+		RetrievalResponse:   durationStats(s.RetrievalResponse),
+		RetrievalCompletion: durationStats(s.RetrievalCompletion),
+		RetrievalsFailed:    s.RetrievalsFailed,
+	}
+}
+
+func durationStats(ds []time.Duration) api.DurationStats {
+	if len(ds) == 0 {
+		return api.DurationStats{}
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return api.DurationStats{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Median: sorted[percentile(len(sorted), 50)],
+		P90:    sorted[percentile(len(sorted), 90)],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+func epochStats(es []abi.ChainEpoch) api.EpochStats {
+	if len(es) == 0 {
+		return api.EpochStats{}
+	}
+	sorted := append([]abi.ChainEpoch(nil), es...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return api.EpochStats{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Median: sorted[percentile(len(sorted), 50)],
+		P90:    sorted[percentile(len(sorted), 90)],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the index of the p-th percentile of n sorted values,
+// using the nearest rank.
+func percentile(n, p int) int {
+	i := (n*p + 99) / 100
+	if i < 1 {
+		i = 1
+	}
+	return i - 1
+}
+
+// reportKey returns the key of the report of the period starting at the
+// given time; keys sort in the order of the periods.
+func reportKey(start time.Time) datastore.Key {
+	return reportsPrefix.ChildString(fmt.Sprintf("%020d", start.Unix()))
+}
@@ -0,0 +1,157 @@
+package dealsla
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/multiformats/go-multihash"
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+func TestTracker(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	clk := clock.NewMock()
+	clk.Set(time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC))
+
+	epoch := abi.ChainEpoch(100)
+	newTracker := func() *Tracker {
+		tr, err := NewTracker(ds, 24*time.Hour, 2, func(context.Context) (abi.ChainEpoch, error) {
+			return epoch, nil
+		})
+		require.NoError(t, err)
+		tr.clock = clk
+		// the measurements loaded are kept, a new period starts on the mock clock
+		if tr.cur.Start.After(clk.Now()) {
+			tr.cur = &samples{Start: tr.periodStart(clk.Now())}
+		}
+		return tr
+	}
+	tr := newTracker()
+
+	var n int
+	mkDeal := func(start abi.ChainEpoch) storagemarket.MinerDeal {
+		n++
+		h, err := multihash.Sum([]byte{byte(n)}, multihash.SHA2_256, -1)
+		require.NoError(t, err)
+
+		return storagemarket.MinerDeal{
+			ClientDealProposal: market.ClientDealProposal{
+				Proposal: market.DealProposal{StartEpoch: start},
+			},
+			ProposalCid:  cid.NewCidV1(cid.DagCBOR, h),
+			CreationTime: cbg.CborTime(clk.Now()),
+		}
+	}
+
+	// a deal going through
+	deal := mkDeal(150)
+	tr.OnStorageEvent(storagemarket.ProviderEventOpen, deal)
+	clk.Add(time.Minute)
+	tr.OnStorageEvent(storagemarket.ProviderEventDataTransferInitiated, deal)
+	clk.Add(10 * time.Minute)
+	tr.OnStorageEvent(storagemarket.ProviderEventDataTransferCompleted, deal)
+	clk.Add(time.Hour)
+	tr.OnStorageEvent(storagemarket.ProviderEventDealActivated, deal)
+
+	// rejected deals aren't failures
+	rejected := mkDeal(150)
+	tr.OnStorageEvent(storagemarket.ProviderEventOpen, rejected)
+	tr.OnStorageEvent(storagemarket.ProviderEventDealRejected, rejected)
+	tr.OnStorageEvent(storagemarket.ProviderEventFailed, rejected)
+
+	failed := mkDeal(150)
+	tr.OnStorageEvent(storagemarket.ProviderEventOpen, failed)
+	tr.OnStorageEvent(storagemarket.ProviderEventFailed, failed)
+
+	// activated after its start epoch
+	late := mkDeal(90)
+	tr.OnStorageEvent(storagemarket.ProviderEventDealActivated, late)
+
+	retrieval := retrievalmarket.ProviderDealState{
+		DealProposal: retrievalmarket.DealProposal{ID: 1},
+		Status:       retrievalmarket.DealStatusNew,
+	}
+	tr.OnRetrievalEvent(retrievalmarket.ProviderEventOpen, retrieval)
+	clk.Add(2 * time.Second)
+	retrieval.Status = retrievalmarket.DealStatusAccepted
+	tr.OnRetrievalEvent(retrievalmarket.ProviderEventDealAccepted, retrieval)
+	clk.Add(time.Minute)
+	retrieval.Status = retrievalmarket.DealStatusCompleted
+	tr.OnRetrievalEvent(retrievalmarket.ProviderEventComplete, retrieval)
+
+	check := func(r api.DealSLAReport) {
+		require.Equal(t, 1, r.Ingest.Count)
+		require.Equal(t, 10*time.Minute, r.Ingest.Median)
+		require.Equal(t, 2, r.Activation.Count)
+		require.Equal(t, 71*time.Minute, r.Activation.Max)
+		require.Equal(t, 2, r.ActivationMargin.Count)
+		require.Equal(t, abi.ChainEpoch(-10), r.ActivationMargin.Min)
+		require.Equal(t, abi.ChainEpoch(50), r.ActivationMargin.Max)
+		require.Equal(t, 1, r.DealsActivatedLate)
+		require.Equal(t, 1, r.DealsFailed)
+		require.Equal(t, 2*time.Second, r.RetrievalResponse.Median)
+		require.Equal(t, 62*time.Second, r.RetrievalCompletion.Median)
+		require.Equal(t, 0, r.RetrievalsFailed)
+	}
+
+	reports, err := tr.Reports(0)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.True(t, reports[0].InProgress)
+	check(reports[0])
+
+	// the measurements of the current period survive restarts
+	require.NoError(t, tr.Stop())
+	tr = newTracker()
+	reports, err = tr.Reports(0)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	check(reports[0])
+
+	clk.Add(24 * time.Hour)
+	reports, err = tr.Reports(0)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.True(t, reports[0].InProgress)
+	require.Equal(t, 0, reports[0].Activation.Count)
+	require.False(t, reports[1].InProgress)
+	require.Equal(t, reports[0].Start, reports[1].End)
+	check(reports[1])
+
+	// only the last 2 past reports are kept
+	for i := 0; i < 3; i++ {
+		clk.Add(24 * time.Hour)
+		_, err = tr.Reports(0)
+		require.NoError(t, err)
+	}
+	reports, err = tr.Reports(0)
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	require.True(t, reports[1].Start.After(reports[2].Start))
+
+	reports, err = tr.Reports(1)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+}
+
+func TestPercentile(t *testing.T) {
+	require.Equal(t, 0, percentile(1, 50))
+	require.Equal(t, 0, percentile(1, 90))
+	require.Equal(t, 0, percentile(2, 50))
+	require.Equal(t, 1, percentile(2, 90))
+	require.Equal(t, 4, percentile(10, 50))
+	require.Equal(t, 8, percentile(10, 90))
+}
@@ -12,6 +12,7 @@ import (
 
 // Distribution
 var defaultMillisecondsDistribution = view.Distribution(0.01, 0.05, 0.1, 0.3, 0.6, 0.8, 1, 2, 3, 4, 5, 6, 8, 10, 13, 16, 20, 25, 30, 40, 50, 65, 80, 100, 130, 160, 200, 250, 300, 400, 500, 650, 800, 1000, 2000, 5000, 10000, 20000, 50000, 100000)
+var defaultBytesDistribution = view.Distribution(1<<8, 1<<10, 1<<12, 1<<14, 1<<16, 1<<18, 1<<20, 1<<22, 1<<24, 1<<26, 1<<28)
 
 // Global Tags
 var (
@@ -24,6 +25,10 @@ var (
 	MessageTo, _    = tag.NewKey("message_to")
 	MessageNonce, _ = tag.NewKey("message_nonce")
 	ReceivedFrom, _ = tag.NewKey("received_from")
+	Status, _       = tag.NewKey("status")
+	Cache, _        = tag.NewKey("cache")
+	RateLimit, _    = tag.NewKey("rate_limit")
+	APIMethod, _    = tag.NewKey("method")
 )
 
 // Measures
@@ -49,6 +54,23 @@ var (
 	PubsubRecvRPC                       = stats.Int64("pubsub/recv_rpc", "Counter for total received RPCs", stats.UnitDimensionless)
 	PubsubSendRPC                       = stats.Int64("pubsub/send_rpc", "Counter for total sent RPCs", stats.UnitDimensionless)
 	PubsubDropRPC                       = stats.Int64("pubsub/drop_rpc", "Counter for total dropped RPCs", stats.UnitDimensionless)
+	BlockstoreCacheHit                  = stats.Int64("blockstore/cache/hit", "Counter for chain blockstore cache hits", stats.UnitDimensionless)
+	BlockstoreCacheMiss                 = stats.Int64("blockstore/cache/miss", "Counter for chain blockstore cache misses", stats.UnitDimensionless)
+	BlockstoreCacheEviction             = stats.Int64("blockstore/cache/eviction", "Counter for blocks evicted from the chain blockstore cache", stats.UnitDimensionless)
+	StateCacheHit                       = stats.Int64("stmgr/cache/hit", "Counter for state manager cache hits, by cache", stats.UnitDimensionless)
+	StateCacheMiss                      = stats.Int64("stmgr/cache/miss", "Counter for state manager cache misses, by cache", stats.UnitDimensionless)
+	ChainExchangeServerRequests         = stats.Int64("chainxchg/server/requests", "Counter for ChainExchange requests served, by response status", stats.UnitDimensionless)
+	ChainExchangeServerActive           = stats.Int64("chainxchg/server/active", "Number of ChainExchange requests being serviced", stats.UnitDimensionless)
+	ChainExchangeServerBytes            = stats.Int64("chainxchg/server/bytes", "Counter for ChainExchange response bytes served", stats.UnitBytes)
+	ChainExchangeServerDuration         = stats.Float64("chainxchg/server/duration_ms", "Duration of servicing ChainExchange requests in ms", stats.UnitMilliseconds)
+	ClockDrift                          = stats.Float64("clock/drift_ms", "Drift of the system clock from NTP in ms, positive when behind", stats.UnitMilliseconds)
+	GatewayRequests                     = stats.Int64("gateway/requests", "Counter for gateway API calls", stats.UnitDimensionless)
+	GatewayRateLimited                  = stats.Int64("gateway/rate_limited", "Counter for gateway API calls over a rate limit, by limit", stats.UnitDimensionless)
+	APIRequests                         = stats.Int64("api/requests", "Counter for API calls, by method", stats.UnitDimensionless)
+	APIRequestErrors                    = stats.Int64("api/request_errors", "Counter for API calls returning an error, by method", stats.UnitDimensionless)
+	APIRequestDuration                  = stats.Float64("api/request_duration_ms", "Duration of API calls in ms, by method", stats.UnitMilliseconds)
+	APIRequestBytes                     = stats.Int64("api/request_bytes", "Size of the API requests made over HTTP, by method", stats.UnitBytes)
+	APIResponseBytes                    = stats.Int64("api/response_bytes", "Size of the API responses made over HTTP, by method", stats.UnitBytes)
 )
 
 var (
@@ -137,6 +159,83 @@ var (
 		Measure:     PubsubDropRPC,
 		Aggregation: view.Count(),
 	}
+	BlockstoreCacheHitView = &view.View{
+		Measure:     BlockstoreCacheHit,
+		Aggregation: view.Count(),
+	}
+	BlockstoreCacheMissView = &view.View{
+		Measure:     BlockstoreCacheMiss,
+		Aggregation: view.Count(),
+	}
+	BlockstoreCacheEvictionView = &view.View{
+		Measure:     BlockstoreCacheEviction,
+		Aggregation: view.Sum(),
+	}
+	StateCacheHitView = &view.View{
+		Measure:     StateCacheHit,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{Cache},
+	}
+	StateCacheMissView = &view.View{
+		Measure:     StateCacheMiss,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{Cache},
+	}
+	ChainExchangeServerRequestsView = &view.View{
+		Measure:     ChainExchangeServerRequests,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{Status},
+	}
+	ChainExchangeServerActiveView = &view.View{
+		Measure:     ChainExchangeServerActive,
+		Aggregation: view.LastValue(),
+	}
+	ChainExchangeServerBytesView = &view.View{
+		Measure:     ChainExchangeServerBytes,
+		Aggregation: view.Sum(),
+	}
+	ChainExchangeServerDurationView = &view.View{
+		Measure:     ChainExchangeServerDuration,
+		Aggregation: defaultMillisecondsDistribution,
+	}
+	ClockDriftView = &view.View{
+		Measure:     ClockDrift,
+		Aggregation: view.LastValue(),
+	}
+	GatewayRequestsView = &view.View{
+		Measure:     GatewayRequests,
+		Aggregation: view.Count(),
+	}
+	GatewayRateLimitedView = &view.View{
+		Measure:     GatewayRateLimited,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{RateLimit},
+	}
+	APIRequestsView = &view.View{
+		Measure:     APIRequests,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{APIMethod},
+	}
+	APIRequestErrorsView = &view.View{
+		Measure:     APIRequestErrors,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{APIMethod},
+	}
+	APIRequestDurationView = &view.View{
+		Measure:     APIRequestDuration,
+		Aggregation: defaultMillisecondsDistribution,
+		TagKeys:     []tag.Key{APIMethod},
+	}
+	APIRequestBytesView = &view.View{
+		Measure:     APIRequestBytes,
+		Aggregation: defaultBytesDistribution,
+		TagKeys:     []tag.Key{APIMethod},
+	}
+	APIResponseBytesView = &view.View{
+		Measure:     APIResponseBytes,
+		Aggregation: defaultBytesDistribution,
+		TagKeys:     []tag.Key{APIMethod},
+	}
 )
 
 // DefaultViews is an array of OpenCensus views for metric gathering purposes
@@ -161,6 +260,28 @@ var DefaultViews = append([]*view.View{
 	PubsubRecvRPCView,
 	PubsubSendRPCView,
 	PubsubDropRPCView,
+	BlockstoreCacheHitView,
+	BlockstoreCacheMissView,
+	BlockstoreCacheEvictionView,
+	StateCacheHitView,
+	StateCacheMissView,
+	ChainExchangeServerRequestsView,
+	ChainExchangeServerActiveView,
+	ChainExchangeServerBytesView,
+	ChainExchangeServerDurationView,
+	ClockDriftView,
+	APIRequestsView,
+	APIRequestErrorsView,
+	APIRequestDurationView,
+	APIRequestBytesView,
+	APIResponseBytesView,
+},
+	rpcmetrics.DefaultViews...)
+
+// GatewayViews are the views of the metrics of lotus-gateway
+var GatewayViews = append([]*view.View{
+	GatewayRequestsView,
+	GatewayRateLimitedView,
 },
 	rpcmetrics.DefaultViews...)
 
@@ -7,7 +7,9 @@ import (
 
 	"github.com/filecoin-project/lotus/chain"
 	"github.com/filecoin-project/lotus/chain/exchange"
+	"github.com/filecoin-project/lotus/chain/statefetch"
 	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/store/splitstore"
 	"github.com/filecoin-project/lotus/chain/vm"
 	"github.com/filecoin-project/lotus/chain/wallet"
 	"github.com/filecoin-project/lotus/node/hello"
@@ -35,11 +37,14 @@ import (
 	storage2 "github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apiconns"
+	"github.com/filecoin-project/lotus/api/apiusage"
 	"github.com/filecoin-project/lotus/chain/beacon"
 	"github.com/filecoin-project/lotus/chain/gen"
 	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
 	"github.com/filecoin-project/lotus/chain/market"
 	"github.com/filecoin-project/lotus/chain/messagepool"
+	"github.com/filecoin-project/lotus/chain/messagescheduler"
 	"github.com/filecoin-project/lotus/chain/messagesigner"
 	"github.com/filecoin-project/lotus/chain/metrics"
 	"github.com/filecoin-project/lotus/chain/stmgr"
@@ -52,10 +57,13 @@ import (
 	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
 	"github.com/filecoin-project/lotus/journal"
 	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/lib/clockcheck"
 	"github.com/filecoin-project/lotus/lib/peermgr"
 	_ "github.com/filecoin-project/lotus/lib/sigs/bls"
 	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+	"github.com/filecoin-project/lotus/markets/dealauth"
 	"github.com/filecoin-project/lotus/markets/dealfilter"
+	"github.com/filecoin-project/lotus/markets/dealsla"
 	"github.com/filecoin-project/lotus/markets/storageadapter"
 	"github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/node/config"
@@ -113,6 +121,8 @@ const (
 
 	// filecoin
 	SetGenesisKey
+	StartSplitstoreKey
+	StartMessageIndexKey
 
 	RunHelloKey
 	RunChainExchangeKey
@@ -133,10 +143,13 @@ const (
 	// daemon
 	ExtractApiKey
 	HeadMetricsKey
+	ChainBlockstoreGCKey
+	ChainBlockstoreScrubKey
 	SettlePaymentChannelsKey
 	RunPeerTaggerKey
 
 	SetApiEndpointKey
+	RunClockCheckKey
 
 	_nInvokes // keep this last
 )
@@ -170,6 +183,8 @@ func defaults() []Option {
 		Override(new(record.Validator), modules.RecordValidator),
 		Override(new(dtypes.Bootstrapper), dtypes.Bootstrapper(false)),
 		Override(new(dtypes.ShutdownChan), make(chan struct{})),
+		Override(new(*apiusage.Tracker), apiusage.NewTracker),
+		Override(new(*apiconns.Registry), apiconns.NewRegistry),
 
 		// Filecoin modules
 
@@ -244,6 +259,8 @@ func Online() Option {
 			Override(new(dtypes.BootstrapPeers), modules.BuiltinBootstrap),
 			Override(new(dtypes.DrandBootstrap), modules.DrandBootstrap),
 			Override(new(dtypes.DrandSchedule), modules.BuiltinDrandConfig),
+			Override(new(dtypes.SyncCheckpoints), modules.BuiltinSyncCheckpoints),
+			Override(new(dtypes.TrustedCheckpoint), dtypes.TrustedCheckpoint{}),
 
 			Override(new(ffiwrapper.Verifier), ffiwrapper.ProofVerifier),
 			Override(new(vm.SyscallBuilder), vm.Syscalls),
@@ -266,8 +283,14 @@ func Online() Option {
 			// It will be called implicitly by the Syncer constructor.
 			Override(new(chain.SyncManagerCtor), func() chain.SyncManagerCtor { return chain.NewSyncManager }),
 			Override(new(*chain.Syncer), modules.NewSyncer),
+			Override(new(*statefetch.Fetcher), modules.StateFetcher(statefetch.DefaultWorkers)),
+			Override(new(exchange.BandwidthLimits), exchange.BandwidthLimits{}),
+			Override(new(exchange.ServerLimits), exchange.DefaultServerLimits),
 			Override(new(exchange.Client), exchange.NewClient),
+			Override(new(messagepool.SelectionPolicy), messagepool.DefaultSelectionPolicy{}),
 			Override(new(*messagepool.MessagePool), modules.MessagePool),
+			Override(new(*messagescheduler.Scheduler), modules.MessageScheduler),
+			Override(new(*impl.MsgWatchEvents), impl.NewMsgWatchEvents),
 
 			Override(new(modules.Genesis), modules.ErrorGenesis),
 			Override(new(dtypes.AfterGenesisSet), modules.SetGenesis),
@@ -362,6 +385,8 @@ func Online() Option {
 			Override(HandleRetrievalKey, modules.HandleRetrieval),
 			Override(GetParamsKey, modules.GetParams),
 			Override(HandleDealsKey, modules.HandleDeals),
+			Override(new(*dealsla.Tracker), modules.DealSLATracker(config.DefaultStorageMiner().Dealmaking)),
+			Override(new(*dealauth.Authorizations), modules.DealAuthorizations),
 			Override(new(gen.WinningPoStProver), storage.NewWinningPoStProver),
 			Override(new(*miner.Miner), modules.SetupBlockProducer),
 
@@ -379,6 +404,10 @@ func Online() Option {
 			Override(new(dtypes.GetSealingConfigFunc), modules.NewGetSealConfigFunc),
 			Override(new(dtypes.SetExpectedSealDurationFunc), modules.NewSetExpectedSealDurationFunc),
 			Override(new(dtypes.GetExpectedSealDurationFunc), modules.NewGetExpectedSealDurationFunc),
+			Override(new(dtypes.GetStorageDealClientLimitsFunc), modules.NewGetStorageDealClientLimitsFunc),
+			Override(new(dtypes.GetStorageDealAuthorizedFundersFunc), modules.NewGetStorageDealAuthorizedFundersFunc),
+			Override(new(dtypes.GetConfigSectionFunc), modules.NewGetConfigSectionFunc),
+			Override(new(dtypes.SetConfigSectionFunc), modules.NewSetConfigSectionFunc),
 		),
 	)
 }
@@ -416,6 +445,8 @@ func ConfigCommon(cfg *config.Common) Option {
 		Override(SetApiEndpointKey, func(lr repo.LockedRepo, e dtypes.APIEndpoint) error {
 			return lr.SetAPIEndpoint(e)
 		}),
+		Override(new(*clockcheck.Monitor), modules.ClockMonitor(cfg.Clock)),
+		Override(RunClockCheckKey, modules.RunClockCheck),
 		Override(new(sectorstorage.URLs), func(e dtypes.APIEndpoint) (sectorstorage.URLs, error) {
 			ip := cfg.API.RemoteListenAddress
 
@@ -462,6 +493,29 @@ func ConfigFullNode(c interface{}) Option {
 			Override(HeadMetricsKey, metrics.SendHeadNotifs(cfg.Metrics.Nickname)),
 		),
 
+		Override(new(dtypes.ChainBlockstore), modules.ChainBlockstoreBackend(&cfg.Chainstore)),
+		Override(new(*stmgr.StateManager), modules.StateManager(&cfg.Chainstore)),
+		Override(new(dtypes.SyncCheckpoints), modules.ConfigSyncCheckpoints(cfg.Sync)),
+		Override(new(dtypes.TrustedCheckpoint), modules.ConfigTrustedCheckpoint(cfg.Sync)),
+		Override(new(exchange.BandwidthLimits), modules.ChainExchangeBandwidth(cfg.Sync)),
+		Override(new(exchange.ServerLimits), modules.ChainExchangeServerLimits(cfg.Sync)),
+		Override(new(*statefetch.Fetcher), modules.StateFetcher(cfg.Sync.StateFetchWorkers)),
+
+		If(cfg.Chainstore.EnableSplitstore,
+			Override(new(*splitstore.SplitStore), modules.SplitBlockstore(&cfg.Chainstore)),
+			Override(new(dtypes.ChainBlockstore), modules.ChainSplitBlockstore(&cfg.Chainstore)),
+			Override(StartSplitstoreKey, modules.StartSplitstore),
+		),
+		If(cfg.Chainstore.EnableMessageIndex,
+			Override(StartMessageIndexKey, modules.StartMessageIndex),
+		),
+		If(cfg.Chainstore.GC.Interval > 0,
+			Override(ChainBlockstoreGCKey, modules.ScheduleChainBlockstoreGC(cfg.Chainstore.GC, cfg.Chainstore.EnableSplitstore)),
+		),
+		If(cfg.Chainstore.Scrub.Interval > 0,
+			Override(ChainBlockstoreScrubKey, modules.ScheduleChainBlockstoreScrub(cfg.Chainstore.Scrub)),
+		),
+
 		If(cfg.Wallet.RemoteBackend != "",
 			Override(new(*remotewallet.RemoteWallet), remotewallet.SetupRemoteWallet(cfg.Wallet.RemoteBackend)),
 		),
@@ -472,6 +526,9 @@ func ConfigFullNode(c interface{}) Option {
 			Unset(new(*wallet.LocalWallet)),
 			Override(new(wallet.Default), wallet.NilDefault),
 		),
+		If(len(cfg.Wallet.Routes) > 0,
+			Override(new(wallet.Routes), modules.WalletRoutes(cfg.Wallet)),
+		),
 	)
 }
 
@@ -493,6 +550,7 @@ func ConfigStorageMiner(c interface{}) Option {
 		),
 
 		Override(new(storagemarket.StorageProviderNode), storageadapter.NewProviderNodeAdapter(&cfg.Fees)),
+		Override(new(*dealsla.Tracker), modules.DealSLATracker(cfg.Dealmaking)),
 
 		Override(new(sectorstorage.SealerConfig), cfg.Storage),
 		Override(new(*storage.Miner), modules.StorageMiner(cfg.Fees)),
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ipfs/go-cid"
 
+	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/types"
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
 )
@@ -15,14 +16,17 @@ type Common struct {
 	API    API
 	Libp2p Libp2p
 	Pubsub Pubsub
+	Clock  Clock
 }
 
 // FullNode is a full node config
 type FullNode struct {
 	Common
-	Client  Client
-	Metrics Metrics
-	Wallet  Wallet
+	Client     Client
+	Metrics    Metrics
+	Wallet     Wallet
+	Chainstore Chainstore
+	Sync       Sync
 }
 
 // // Common
@@ -45,6 +49,22 @@ type DealmakingConfig struct {
 	PieceCidBlocklist             []cid.Cid
 	ExpectedSealDuration          Duration
 
+	// Quotas on the storage deals accepted from a single client address
+	// within ClientQuotaPeriod; 0 = no limit
+	MaxDealsPerClient     uint64
+	MaxDealBytesPerClient uint64
+	ClientQuotaPeriod     Duration
+
+	// Addresses the storage deals are only accepted from, along with the
+	// delegates they authorized (see DealsImportAuthorization); empty =
+	// any client
+	AuthorizedFunders []string
+
+	// Length of the periods of the deal SLA reports, and number of past
+	// reports kept; 0 = keep all
+	SLAReportPeriod Duration
+	SLAReportsKept  int
+
 	Filter          string
 	RetrievalFilter string
 }
@@ -96,6 +116,17 @@ type Pubsub struct {
 	RemoteTracer string
 }
 
+// Clock configures the checks of the system clock against NTP servers
+type Clock struct {
+	// NTPServers are queried in order until one of them responds; leave
+	// empty to disable the checks
+	NTPServers []string
+	// CheckInterval is the time between checks
+	CheckInterval Duration
+	// MaxDrift is the drift from NTP above which blocks aren't mined
+	MaxDrift Duration
+}
+
 // // Full Node
 
 type Metrics struct {
@@ -110,9 +141,179 @@ type Client struct {
 }
 
 type Wallet struct {
+	// RemoteBackend is the API info of a lotus-wallet service signing for
+	// the node, as "token:multiaddr"; the token is printed by
+	// lotus-wallet get-api-key
 	RemoteBackend string
 	EnableLedger  bool
-	DisableLocal  bool
+	// DisableLocal disables the wallet of the node repo, so that the node
+	// never holds private keys
+	DisableLocal bool
+
+	// Routes pin the signers they match to a backend and restrict what they
+	// sign, the first route matching a signer applies. The signers with no
+	// route are signed for by the first backend having their key
+	Routes []WalletRoute
+}
+
+// WalletRoute is the signing policy of some addresses of the wallet
+type WalletRoute struct {
+	// Addresses the route applies to, the key addresses signing; besides
+	// addresses, "secp256k1" and "bls" match all the addresses of a type, and
+	// "*" all the addresses
+	Addresses []string
+	// Backend is the only wallet signing for the addresses, "local",
+	// "remote" or "ledger"; any wallet having their key when empty
+	Backend string
+
+	// MaxValue is the most FIL a message can send, like "10 FIL", no limit
+	// when not set
+	MaxValue string
+	// To restricts the destinations of the messages, as they are set in the
+	// messages, when not empty
+	To []string
+	// Methods restricts the method numbers the messages call, when not empty
+	Methods []uint64
+	// OnlyMessages denies signing anything but chain messages, like blocks
+	// or deal proposals
+	OnlyMessages bool
+}
+
+type Chainstore struct {
+	// Backend selects where chain blocks are stored
+	Backend BlockstoreBackend
+
+	// CacheSize is the size in bytes of the in-memory cache of chain
+	// blocks; 0 disables the cache
+	CacheSize uint64
+
+	// StateCacheSize is the memory budget in bytes of the caches of recently
+	// computed tipset states and loaded actors; the states of the tipsets of
+	// two finalities are always cached
+	StateCacheSize uint64
+
+	// ExecutionParallelism is the number of messages of a block executed
+	// concurrently when computing tipset states; messages are executed one
+	// by one if it is below 2
+	ExecutionParallelism int
+
+	// EnableMessageIndex indexes the messages executed in the synced chain,
+	// speeding up message searches and listing the messages of an address
+	EnableMessageIndex bool
+
+	EnableSplitstore bool
+	Splitstore       Splitstore
+
+	GC BlockstoreGC
+
+	Scrub BlockstoreScrub
+}
+
+type BlockstoreBackend struct {
+	// Type is "badger" to keep blocks in the local repo, or "remote" to use
+	// a key-value service shared with other nodes
+	Type string
+
+	Remote RemoteBlockstore
+}
+
+type RemoteBlockstore struct {
+	// Endpoint is the base URL of the key-value service
+	Endpoint string
+	// Token, if set, is sent as a bearer token
+	Token string
+	// Timeout for each request to the service
+	Timeout Duration
+}
+
+type Splitstore struct {
+	// ColdStoreType is either "universal", moving objects evicted from the
+	// hotstore into the regular chain blockstore, or "discard", deleting them
+	ColdStoreType string
+
+	// CompactionThreshold is the number of epochs the head may advance past
+	// the last compaction boundary before the hotstore is compacted again
+	CompactionThreshold uint64
+	// CompactionBoundary is the number of recent epochs which are always
+	// kept in the hotstore
+	CompactionBoundary uint64
+}
+
+type BlockstoreGC struct {
+	// Interval between scheduled value log GC runs on the chain blockstore,
+	// e.g. "24h". Runs are postponed while the node is catching up with the
+	// chain. Zero disables scheduled GC.
+	Interval Duration
+	// DiscardRatio is the fraction of a value log file which must be garbage
+	// before the file is rewritten
+	DiscardRatio float64
+	// Compact also flattens the LSM tree after value log GC
+	Compact bool
+}
+
+type BlockstoreScrub struct {
+	// Interval between scheduled integrity checks of the chain blockstore,
+	// which re-hash every block against its CID, e.g. "168h". Checks are
+	// postponed while the node is catching up with the chain. Zero disables
+	// scheduled checks.
+	Interval Duration
+	// Repair fetches corrupt blocks again from the network
+	Repair bool
+}
+
+type Sync struct {
+	// Checkpoints are tipsets which every synced chain must include at their
+	// heights, in addition to the built-in checkpoints of the network.
+	Checkpoints []Checkpoint
+	// IgnoreBuiltinCheckpoints disables the built-in checkpoints
+	IgnoreBuiltinCheckpoints bool
+
+	// Caps in bytes per second on the chain data fetched from and served to
+	// peers over ChainExchange, shared by all peers; 0 = no limit. Fetching
+	// carries most of the data of an initial sync. Very low caps make peers
+	// time out on large responses.
+	FetchBandwidth uint64
+	ServeBandwidth uint64
+	// BandwidthSchedule replaces the caps during windows of the day, e.g. to
+	// only sync at full speed at night. The first matching window applies.
+	BandwidthSchedule []BandwidthWindow
+
+	// Limits on serving ChainExchange requests to a single peer, so that a
+	// misbehaving peer can't saturate the disk reads of the node: requests
+	// serviced at once (further ones are turned away), bytes per second on
+	// top of ServeBandwidth, and the size of a response (longer chain
+	// segments are returned partially); 0 = no limit
+	ServePeerConcurrentRequests int
+	ServePeerBandwidth          uint64
+	ServeMaxResponseBytes       uint64
+
+	// StateFetchWorkers is the number of parallel bitswap sessions fetching
+	// state which is missing locally; 0 = the default of 8
+	StateFetchWorkers int
+
+	// TrustedCheckpoint enables the lightweight sync mode when set. A node
+	// whose chain is below the checkpoint only fetches the headers down to
+	// it, takes the state at the checkpoint from the network instead of
+	// executing the messages of all the epochs before it, and validates the
+	// chain from there. Everything up to the checkpoint is trusted, so it
+	// must come from a source the operator trusts.
+	TrustedCheckpoint Checkpoint
+}
+
+type BandwidthWindow struct {
+	// Start and End are local times of day formatted as "15:04"; a window
+	// ending before it starts wraps around midnight
+	Start string
+	End   string
+
+	FetchBandwidth uint64
+	ServeBandwidth uint64
+}
+
+type Checkpoint struct {
+	Height int64
+	// Blocks are the CIDs of the blocks of the tipset
+	Blocks []cid.Cid
 }
 
 func defCommon() Common {
@@ -138,6 +339,11 @@ func defCommon() Common {
 			DirectPeers:  nil,
 			RemoteTracer: "/dns4/pubsub-tracer.filecoin.io/tcp/4001/p2p/QmTd6UvR47vUidRNZ1ZKXHrAFhqTJAD27rKL9XYghEKgKX",
 		},
+		Clock: Clock{
+			NTPServers:    []string{"pool.ntp.org"},
+			CheckInterval: Duration(10 * time.Minute),
+			MaxDrift:      Duration(time.Duration(build.AllowableClockDriftSecs) * time.Second),
+		},
 	}
 
 }
@@ -146,6 +352,30 @@ func defCommon() Common {
 func DefaultFullNode() *FullNode {
 	return &FullNode{
 		Common: defCommon(),
+		Chainstore: Chainstore{
+			Backend: BlockstoreBackend{
+				Type: "badger",
+				Remote: RemoteBlockstore{
+					Timeout: Duration(30 * time.Second),
+				},
+			},
+			CacheSize:        512 << 20,
+			StateCacheSize:   32 << 20,
+			EnableSplitstore: false,
+			Splitstore: Splitstore{
+				ColdStoreType:       "universal",
+				CompactionThreshold: 5 * 900, // 5 finalities
+				CompactionBoundary:  4 * 900,
+			},
+			GC: BlockstoreGC{
+				Interval:     0,
+				DiscardRatio: 0.5,
+			},
+		},
+		Sync: Sync{
+			ServePeerConcurrentRequests: 8,
+			ServeMaxResponseBytes:       64 << 20,
+		},
 	}
 }
 
@@ -180,6 +410,11 @@ func DefaultStorageMiner() *StorageMiner {
 			PieceCidBlocklist:             []cid.Cid{},
 			// TODO: It'd be nice to set this based on sector size
 			ExpectedSealDuration: Duration(time.Hour * 24),
+
+			ClientQuotaPeriod: Duration(time.Hour * 24),
+
+			SLAReportPeriod: Duration(time.Hour * 24),
+			SLAReportsKept:  30,
 		},
 
 		Fees: MinerFeeConfig{
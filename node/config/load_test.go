@@ -61,3 +61,25 @@ func TestParitalConfig(t *testing.T) {
 			"config from reader should contain changes")
 	}
 }
+
+func TestDecodeWalletRoutes(t *testing.T) {
+	assert := assert.New(t)
+	cfgString := `
+		[[Wallet.Routes]]
+		Addresses = ["bls"]
+		Backend = "ledger"
+		MaxValue = "10 FIL"
+		OnlyMessages = true
+		`
+	expected := DefaultFullNode()
+	expected.Wallet.Routes = []WalletRoute{{
+		Addresses:    []string{"bls"},
+		Backend:      "ledger",
+		MaxValue:     "10 FIL",
+		OnlyMessages: true,
+	}}
+
+	cfg, err := FromReader(bytes.NewReader([]byte(cfgString)), DefaultFullNode())
+	assert.NoError(err, "error should be nil")
+	assert.Equal(expected, cfg, "config from reader should contain the route")
+}
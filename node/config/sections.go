@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// sectionValue returns the value of a section of the config, given as a
+// dot-separated path of field names, e.g. "Dealmaking" or "Libp2p.ConnMgrLow",
+// and the path with the names as spelled in the config. Names are matched
+// case-insensitively; the empty path is the whole config.
+func sectionValue(cfg interface{}, section string) (reflect.Value, string, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, "", xerrors.Errorf("expected pointer to config struct, got %T", cfg)
+	}
+	v = v.Elem()
+
+	if section == "" {
+		return v, "", nil
+	}
+	var path []string
+	for _, name := range strings.Split(section, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, "", xerrors.Errorf("config section %q not found", section)
+		}
+		f, ok := v.Type().FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+		if !ok {
+			return reflect.Value{}, "", xerrors.Errorf("config section %q not found", section)
+		}
+		v = v.FieldByIndex(f.Index)
+		path = append(path, f.Name)
+	}
+	return v, strings.Join(path, "."), nil
+}
+
+// SectionJSON returns the JSON encoding of a section of the config, see
+// SetSectionJSON.
+func SectionJSON(cfg interface{}, section string) ([]byte, error) {
+	v, _, err := sectionValue(cfg, section)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v.Interface())
+}
+
+// SetSectionJSON sets a section of the config, given as a dot-separated path of
+// field names, from its JSON encoding. Fields missing from the JSON object keep
+// their values; unknown fields and invalid values are rejected, leaving the
+// config unchanged. def must be a default config of the same type; it is used
+// as scratch space so that the config isn't modified until the JSON is
+// validated. It returns the paths of the settings changed.
+func SetSectionJSON(cfg, def interface{}, section string, data []byte) ([]string, error) {
+	if reflect.TypeOf(cfg) != reflect.TypeOf(def) {
+		return nil, xerrors.Errorf("config type %T doesn't match default config type %T", cfg, def)
+	}
+
+	cur, path, err := sectionValue(cfg, section)
+	if err != nil {
+		return nil, err
+	}
+	scratch, _, err := sectionValue(def, section)
+	if err != nil {
+		return nil, err
+	}
+
+	// start from the current values, then apply the new ones
+	curJSON, err := json.Marshal(cur.Interface())
+	if err != nil {
+		return nil, xerrors.Errorf("encoding current config: %w", err)
+	}
+	if err := json.Unmarshal(curJSON, scratch.Addr().Interface()); err != nil {
+		return nil, xerrors.Errorf("decoding current config: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(scratch.Addr().Interface()); err != nil {
+		return nil, xerrors.Errorf("decoding config section %q: %w", section, err)
+	}
+	if dec.More() {
+		return nil, xerrors.Errorf("decoding config section %q: unexpected data after value", section)
+	}
+
+	changed, err := changedSettings(path, cur, scratch)
+	if err != nil {
+		return nil, err
+	}
+
+	cur.Set(scratch)
+	return changed, nil
+}
+
+// isSection returns whether the type is a struct of settings, as opposed to a
+// single setting of a struct type encoded as a whole, like a duration.
+func isSection(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	pt := reflect.PtrTo(t)
+	if pt.Implements(textMarshalerType) || pt.Implements(jsonMarshalerType) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// changedSettings returns the paths of the settings which differ between the
+// values, comparing the fields of sections and the JSON encodings of the rest.
+func changedSettings(path string, a, b reflect.Value) ([]string, error) {
+	if isSection(a.Type()) {
+		var out []string
+		for i := 0; i < a.NumField(); i++ {
+			f := a.Type().Field(i)
+			p := f.Name
+			if f.Anonymous {
+				p = path
+			} else if path != "" {
+				p = path + "." + f.Name
+			}
+
+			changed, err := changedSettings(p, a.Field(i), b.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, changed...)
+		}
+		return out, nil
+	}
+
+	aj, err := json.Marshal(a.Interface())
+	if err != nil {
+		return nil, err
+	}
+	bj, err := json.Marshal(b.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(aj, bj) {
+		return nil, nil
+	}
+	return []string{path}, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestSectionJSON(t *testing.T) {
+	cfg := DefaultStorageMiner()
+
+	b, err := SectionJSON(cfg, "dealmaking")
+	require.NoError(t, err)
+	var dm DealmakingConfig
+	require.NoError(t, json.Unmarshal(b, &dm))
+	require.Equal(t, cfg.Dealmaking.ExpectedSealDuration, dm.ExpectedSealDuration)
+
+	// fields of the embedded common config are sections too
+	b, err = SectionJSON(cfg, "API.ListenAddress")
+	require.NoError(t, err)
+	require.Equal(t, `"`+cfg.API.ListenAddress+`"`, string(b))
+
+	_, err = SectionJSON(cfg, "NotASection")
+	require.Error(t, err)
+	_, err = SectionJSON(cfg, "API.ListenAddress.Foo")
+	require.Error(t, err)
+}
+
+func TestSetSectionJSON(t *testing.T) {
+	cfg := DefaultStorageMiner()
+	prevDelay := cfg.Sealing.WaitDealsDelay
+
+	changed, err := SetSectionJSON(cfg, DefaultStorageMiner(), "sealing", []byte(`{"MaxSealingSectors": 7}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Sealing.MaxSealingSectors"}, changed)
+	require.Equal(t, uint64(7), cfg.Sealing.MaxSealingSectors)
+	require.Equal(t, prevDelay, cfg.Sealing.WaitDealsDelay)
+
+	// FIL amounts are encoded in attoFIL
+	changed, err = SetSectionJSON(cfg, DefaultStorageMiner(), "Fees", []byte(`{"MaxCommitGasFee": 300000000000000000, "MaxPreCommitGasFee": `+cfg.Fees.MaxPreCommitGasFee.Int.String()+`}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Fees.MaxCommitGasFee"}, changed)
+	require.Equal(t, types.MustParseFIL("0.3").String(), cfg.Fees.MaxCommitGasFee.String())
+
+	changed, err = SetSectionJSON(cfg, DefaultStorageMiner(), "", []byte(`{"API": {"Timeout": "1m"}}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"API.Timeout"}, changed)
+	require.Equal(t, Duration(time.Minute), cfg.API.Timeout)
+
+	// invalid values and unknown fields leave the config unchanged
+	_, err = SetSectionJSON(cfg, DefaultStorageMiner(), "Sealing", []byte(`{"MaxSealingSectors": 9, "WaitDealsDelay": "soon"}`))
+	require.Error(t, err)
+	_, err = SetSectionJSON(cfg, DefaultStorageMiner(), "Sealing", []byte(`{"MaxSealingSectors": 9, "Foo": 1}`))
+	require.Error(t, err)
+	require.Equal(t, uint64(7), cfg.Sealing.MaxSealingSectors)
+
+	_, err = SetSectionJSON(cfg, DefaultFullNode(), "API", []byte(`{}`))
+	require.Error(t, err)
+}
@@ -104,6 +104,11 @@ func (hs *Service) HandleStream(s inet.Stream) {
 		build.Clock.Sleep(time.Millisecond * 300)
 	}
 
+	if hs.syncer.IsPeerBlocked(s.Conn().RemotePeer()) {
+		log.Debugw("not syncing from blocked peer", "peer", s.Conn().RemotePeer())
+		return
+	}
+
 	if hs.pmgr != nil {
 		hs.pmgr.AddFilecoinPeer(s.Conn().RemotePeer())
 	}
@@ -1,6 +1,7 @@
 package impl
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,10 +10,11 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/lotus/lib/backupds"
+	"github.com/filecoin-project/lotus/lib/reqid"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
-func backup(mds dtypes.MetadataDS, fpath string) error {
+func backup(ctx context.Context, mds dtypes.MetadataDS, fpath string) error {
 	bb, ok := os.LookupEnv("LOTUS_BACKUP_BASE_PATH")
 	if !ok {
 		return xerrors.Errorf("LOTUS_BACKUP_BASE_PATH env var not set")
@@ -54,7 +56,7 @@ func backup(mds dtypes.MetadataDS, fpath string) error {
 
 	if err := bds.Backup(out); err != nil {
 		if cerr := out.Close(); cerr != nil {
-			log.Errorw("error closing backup file while handling backup error", "closeErr", cerr, "backupErr", err)
+			reqid.Logger(ctx, log).Errorw("error closing backup file while handling backup error", "closeErr", cerr, "backupErr", err)
 		}
 		return xerrors.Errorf("backup error: %w", err)
 	}
@@ -50,6 +50,7 @@ import (
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/markets/dealauth"
 	"github.com/filecoin-project/lotus/markets/utils"
 	"github.com/filecoin-project/lotus/node/impl/full"
 	"github.com/filecoin-project/lotus/node/impl/paych"
@@ -183,6 +184,28 @@ func (a *API) ClientStartDeal(ctx context.Context, params *api.StartDealParams)
 	return &result.ProposalCid, nil
 }
 
+func (a *API) ClientAuthorizeDelegate(ctx context.Context, funder, delegate address.Address, expiration abi.ChainEpoch) (*api.SignedDealAuthorization, error) {
+	var err error
+	if funder.Protocol() == address.ID {
+		funder, err = a.StateAccountKey(ctx, funder, types.EmptyTSK)
+		if err != nil {
+			return nil, xerrors.Errorf("getting the key of funder: %w", err)
+		}
+	}
+	if delegate.Protocol() == address.ID {
+		delegate, err = a.StateAccountKey(ctx, delegate, types.EmptyTSK)
+		if err != nil {
+			return nil, xerrors.Errorf("getting the key of delegate: %w", err)
+		}
+	}
+
+	return dealauth.Sign(ctx, a.WalletAPI.WalletAPI, api.DealAuthorization{
+		Funder:     funder,
+		Delegate:   delegate,
+		Expiration: expiration,
+	})
+}
+
 func (a *API) ClientListDeals(ctx context.Context) ([]api.DealInfo, error) {
 	deals, err := a.SMDealClient.ListLocalDeals(ctx)
 	if err != nil {
@@ -206,6 +229,7 @@ func (a *API) ClientListDeals(ctx context.Context) ([]api.DealInfo, error) {
 			DealID:        v.DealID,
 			CreationTime:  v.CreationTime.Time(),
 			Verified:      v.Proposal.VerifiedDeal,
+			Rejection:     dealRejection(v.State, v.Message),
 		}
 	}
 
@@ -230,6 +254,7 @@ func (a *API) ClientGetDealInfo(ctx context.Context, d cid.Cid) (*api.DealInfo,
 		DealID:        v.DealID,
 		CreationTime:  v.CreationTime.Time(),
 		Verified:      v.Proposal.VerifiedDeal,
+		Rejection:     dealRejection(v.State, v.Message),
 	}, nil
 }
 
@@ -872,6 +897,18 @@ func newDealInfo(v storagemarket.ClientDeal) api.DealInfo {
 		DealID:        v.DealID,
 		CreationTime:  v.CreationTime.Time(),
 		Verified:      v.Proposal.VerifiedDeal,
+		Rejection:     dealRejection(v.State, v.Message),
+	}
+}
+
+// dealRejection recovers the provider's rejection reason from the message of
+// a failed deal.
+func dealRejection(state storagemarket.StorageDealStatus, msg string) *api.DealRejection {
+	switch state {
+	case storagemarket.StorageDealProposalRejected, storagemarket.StorageDealFailing, storagemarket.StorageDealError:
+		return api.ParseDealRejection(msg)
+	default:
+		return nil
 	}
 }
 
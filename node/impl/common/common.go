@@ -22,6 +22,9 @@ import (
 	"github.com/filecoin-project/go-jsonrpc/auth"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apiconns"
+	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/api/apiusage"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/modules/lp2p"
@@ -37,10 +40,15 @@ type CommonAPI struct {
 	Reporter     metrics.Reporter
 	Sk           *dtypes.ScoreKeeper
 	ShutdownChan dtypes.ShutdownChan
+	Usage        *apiusage.Tracker
+	Conns        *apiconns.Registry
 }
 
 type jwtPayload struct {
 	Allow []auth.Permission
+	// Methods are the patterns of the method scopes of the token, see
+	// apistruct.MethodScope; the token can call any method when empty
+	Methods []string `json:",omitempty"`
 }
 
 func (a *CommonAPI) AuthVerify(ctx context.Context, token string) ([]auth.Permission, error) {
@@ -49,17 +57,33 @@ func (a *CommonAPI) AuthVerify(ctx context.Context, token string) ([]auth.Permis
 		return nil, xerrors.Errorf("JWT Verification failed: %w", err)
 	}
 
-	return payload.Allow, nil
+	return apistruct.Permissions(payload.Allow, payload.Methods), nil
 }
 
 func (a *CommonAPI) AuthNew(ctx context.Context, perms []auth.Permission) ([]byte, error) {
+	allow, methods := apistruct.SplitScopes(perms)
+	for _, m := range methods {
+		if m == "" {
+			return nil, xerrors.Errorf("empty method scope")
+		}
+	}
+
 	p := jwtPayload{
-		Allow: perms, // TODO: consider checking validity
+		Allow:   allow, // TODO: consider checking validity
+		Methods: methods,
 	}
 
 	return jwt.Sign(&p, (*jwt.HMACSHA)(a.APISecret))
 }
 
+func (a *CommonAPI) AuthUsage(ctx context.Context) ([]api.TokenUsage, error) {
+	return a.Usage.Usage(), nil
+}
+
+func (a *CommonAPI) AuthConnections(ctx context.Context) ([]api.APIConnection, error) {
+	return a.Conns.Connections(), nil
+}
+
 func (a *CommonAPI) NetConnectedness(ctx context.Context, pid peer.ID) (network.Connectedness, error) {
 	return a.Host.Network().Connectedness(pid), nil
 }
@@ -189,6 +213,45 @@ func (a *CommonAPI) Version(context.Context) (api.Version, error) {
 	}, nil
 }
 
+func (a *CommonAPI) Discover(ctx context.Context) (api.APICapabilities, error) {
+	v, err := a.Version(ctx)
+	if err != nil {
+		return api.APICapabilities{}, err
+	}
+
+	var methods []api.APIMethod
+	switch build.RunningNodeType {
+	case build.NodeFull:
+		methods = apistruct.Methods(&apistruct.FullNodeStruct{})
+	case build.NodeMiner:
+		methods = apistruct.Methods(&apistruct.StorageMinerStruct{})
+	default:
+		methods = apistruct.Methods(&apistruct.CommonStruct{})
+	}
+
+	var perms []auth.Permission
+	for _, p := range apistruct.AllPermissions {
+		if auth.HasPerm(ctx, apistruct.DefaultPerms, p) {
+			perms = append(perms, p)
+		}
+	}
+	for i := range methods {
+		methods[i].Allowed = auth.HasPerm(ctx, apistruct.DefaultPerms, methods[i].Perm) &&
+			apistruct.ScopeAllows(ctx, methods[i].Name)
+	}
+
+	major, minor, patch := v.APIVersion.Ints()
+	return api.APICapabilities{
+		Version:     v.Version,
+		APIVersion:  v.APIVersion,
+		Major:       major,
+		Minor:       minor,
+		Patch:       patch,
+		Methods:     methods,
+		Permissions: perms,
+	}, nil
+}
+
 func (a *CommonAPI) LogList(context.Context) ([]string, error) {
 	return logging.GetSubsystems(), nil
 }
@@ -21,6 +21,7 @@ type FullNodeAPI struct {
 	full.ChainAPI
 	client.API
 	full.MpoolAPI
+	full.MessageSchedulerAPI
 	full.GasAPI
 	market.MarketAPI
 	paych.PaychAPI
@@ -30,11 +31,12 @@ type FullNodeAPI struct {
 	full.SyncAPI
 	full.BeaconAPI
 
-	DS dtypes.MetadataDS
+	DS             dtypes.MetadataDS
+	MsgWatchEvents *MsgWatchEvents
 }
 
 func (n *FullNodeAPI) CreateBackup(ctx context.Context, fpath string) error {
-	return backup(n.DS, fpath)
+	return backup(ctx, n.DS, fpath)
 }
 
 var _ api.FullNode = &FullNodeAPI{}
@@ -2,18 +2,23 @@ package full
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/beacon"
+	"github.com/filecoin-project/lotus/chain/beacon/drand"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"go.uber.org/fx"
 )
 
 type BeaconAPI struct {
 	fx.In
 
-	Beacon beacon.Schedule
+	Beacon      beacon.Schedule
+	DrandConfig dtypes.DrandSchedule
 }
 
 func (a *BeaconAPI) BeaconGetEntry(ctx context.Context, epoch abi.ChainEpoch) (*types.BeaconEntry, error) {
@@ -34,3 +39,22 @@ func (a *BeaconAPI) BeaconGetEntry(ctx context.Context, epoch abi.ChainEpoch) (*
 		return nil, ctx.Err()
 	}
 }
+
+func (a *BeaconAPI) BeaconSchedule(ctx context.Context) ([]api.BeaconInfo, error) {
+	out := make([]api.BeaconInfo, 0, len(a.DrandConfig))
+	for _, dp := range a.DrandConfig {
+		info, err := drand.ChainInfo(dp.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, api.BeaconInfo{
+			Start:       dp.Start,
+			ChainHash:   hex.EncodeToString(info.Hash()),
+			Period:      info.Period,
+			GenesisTime: info.GenesisTime,
+			Servers:     dp.Config.Servers,
+		})
+	}
+	return out, nil
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"go.uber.org/fx"
+	"golang.org/x/time/rate"
 	"golang.org/x/xerrors"
 
 	"github.com/ipfs/go-blockservice"
@@ -31,10 +32,16 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/store/splitstore"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/vm"
 	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/lib/reqid"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/filecoin-project/lotus/node/repo"
 )
 
 var log = logging.Logger("fullnode")
@@ -64,7 +71,12 @@ type ChainAPI struct {
 	WalletAPI
 	ChainModuleAPI
 
-	Chain *store.ChainStore
+	Chain           *store.ChainStore
+	StateManager    *stmgr.StateManager
+	Repo            repo.LockedRepo
+	BlockService    dtypes.ChainBlockService
+	ChainBlockstore dtypes.ChainBlockstore
+	Splitstore      *splitstore.SplitStore `optional:"true"`
 }
 
 func (a *ChainAPI) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
@@ -318,6 +330,9 @@ func (a *ChainAPI) ChainTipSetWeight(ctx context.Context, tsk types.TipSetKey) (
 	return a.Chain.Weight(ctx, ts)
 }
 
+// errNotFound is returned by resolveOnce when a HAMT or AMT key is missing.
+var errNotFound = xerrors.New("not found")
+
 // This allows us to lookup string keys in the actor's adt.Map type.
 type stringKey string
 
@@ -372,7 +387,7 @@ func resolveOnce(bs blockstore.Blockstore) func(ctx context.Context, ds ipld.Nod
 			if found, err := h.Get(stringKey(names[0][3:]), &deferred); err != nil {
 				return nil, nil, xerrors.Errorf("resolve hamt: %w", err)
 			} else if !found {
-				return nil, nil, xerrors.Errorf("resolve hamt: not found")
+				return nil, nil, xerrors.Errorf("resolve hamt: %w", errNotFound)
 			}
 			var m interface{}
 			if err := cbor.DecodeInto(deferred.Raw, &m); err != nil {
@@ -419,7 +434,7 @@ func resolveOnce(bs blockstore.Blockstore) func(ctx context.Context, ds ipld.Nod
 			if found, err := a.Get(idx, &deferred); err != nil {
 				return nil, nil, xerrors.Errorf("resolve amt: %w", err)
 			} else if !found {
-				return nil, nil, xerrors.Errorf("resolve amt: not found")
+				return nil, nil, xerrors.Errorf("resolve amt: %w", errNotFound)
 			}
 			var m interface{}
 			if err := cbor.DecodeInto(deferred.Raw, &m); err != nil {
@@ -505,13 +520,12 @@ func resolveOnce(bs blockstore.Blockstore) func(ctx context.Context, ds ipld.Nod
 	}
 }
 
-func (a *ChainAPI) ChainGetNode(ctx context.Context, p string) (*api.IpldObject, error) {
+func resolveNode(ctx context.Context, bs blockstore.Blockstore, p string) (ipld.Node, error) {
 	ip, err := path.ParsePath(p)
 	if err != nil {
 		return nil, xerrors.Errorf("parsing path: %w", err)
 	}
 
-	bs := a.Chain.Blockstore()
 	bsvc := blockservice.New(bs, offline.Exchange(bs))
 
 	dag := merkledag.NewDAGService(bsvc)
@@ -521,7 +535,11 @@ func (a *ChainAPI) ChainGetNode(ctx context.Context, p string) (*api.IpldObject,
 		ResolveOnce: resolveOnce(bs),
 	}
 
-	node, err := r.ResolvePath(ctx, ip)
+	return r.ResolvePath(ctx, ip)
+}
+
+func (a *ChainAPI) ChainGetNode(ctx context.Context, p string) (*api.IpldObject, error) {
+	node, err := resolveNode(ctx, a.Chain.Blockstore(), p)
 	if err != nil {
 		return nil, err
 	}
@@ -546,12 +564,67 @@ func (a *ChainAPI) ChainExport(ctx context.Context, nroots abi.ChainEpoch, skipo
 	if err != nil {
 		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
 	}
+
+	return exportStream(ctx, func(w io.Writer) error {
+		return a.Chain.Export(ctx, ts, nroots, skipoldmsgs, w)
+	}), nil
+}
+
+func (a *ChainAPI) ChainExportIncremental(ctx context.Context, nroots abi.ChainEpoch, skipoldmsgs bool, base types.TipSetKey, tsk types.TipSetKey) (<-chan []byte, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+	bts, err := a.Chain.LoadTipSet(base)
+	if err != nil {
+		return nil, xerrors.Errorf("loading base tipset %s: %w", base, err)
+	}
+	if bts.Height() > ts.Height() {
+		return nil, xerrors.Errorf("base tipset is above the exported tipset")
+	}
+	if anc, err := a.Chain.GetTipsetByHeight(ctx, bts.Height(), ts, true); err != nil || !anc.Equals(bts) {
+		return nil, xerrors.Errorf("base tipset %s is not an ancestor of %s (err: %v)", base, tsk, err)
+	}
+
+	return exportStream(ctx, func(w io.Writer) error {
+		return a.Chain.ExportIncremental(ctx, ts, bts, nroots, skipoldmsgs, w)
+	}), nil
+}
+
+func (a *ChainAPI) ChainExportRange(ctx context.Context, tsk types.TipSetKey, start, end abi.ChainEpoch, opts api.ChainExportRangeOpts) (<-chan []byte, error) {
+	if start > end {
+		return nil, xerrors.Errorf("start epoch %d is after end epoch %d", start, end)
+	}
+	if !opts.Headers && !opts.Messages && !opts.Receipts && !opts.StateRoots {
+		return nil, xerrors.Errorf("nothing to export")
+	}
+
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+	if end < ts.Height() {
+		ts, err = a.Chain.GetTipsetByHeight(ctx, end, ts, true)
+		if err != nil {
+			return nil, xerrors.Errorf("loading tipset at end epoch %d: %w", end, err)
+		}
+	}
+
+	return exportStream(ctx, func(w io.Writer) error {
+		return a.Chain.ExportRange(ctx, ts, start, opts, w)
+	}), nil
+}
+
+// exportStream runs export in the background, streaming its output in
+// chunks. An empty chunk is sent after the last one to signal a complete
+// export.
+func exportStream(ctx context.Context, export func(w io.Writer) error) <-chan []byte {
 	r, w := io.Pipe()
 	out := make(chan []byte)
 	go func() {
 		bw := bufio.NewWriterSize(w, 1<<20)
 
-		err := a.Chain.Export(ctx, ts, nroots, skipoldmsgs, bw)
+		err := export(bw)
 		bw.Flush()            //nolint:errcheck // it is a write to a pipe
 		w.CloseWithError(err) //nolint:errcheck // it is a pipe
 	}()
@@ -562,14 +635,14 @@ func (a *ChainAPI) ChainExport(ctx context.Context, nroots abi.ChainEpoch, skipo
 			buf := make([]byte, 1<<20)
 			n, err := r.Read(buf)
 			if err != nil && err != io.EOF {
-				log.Errorf("chain export pipe read failed: %s", err)
+				reqid.Logger(ctx, log).Errorf("chain export pipe read failed: %s", err)
 				return
 			}
 			if n > 0 {
 				select {
 				case out <- buf[:n]:
 				case <-ctx.Done():
-					log.Warnf("export writer failed: %s", ctx.Err())
+					reqid.Logger(ctx, log).Warnf("export writer failed: %s", ctx.Err())
 					return
 				}
 			}
@@ -578,7 +651,7 @@ func (a *ChainAPI) ChainExport(ctx context.Context, nroots abi.ChainEpoch, skipo
 				select {
 				case out <- []byte{}:
 				case <-ctx.Done():
-					log.Warnf("export writer failed: %s", ctx.Err())
+					reqid.Logger(ctx, log).Warnf("export writer failed: %s", ctx.Err())
 					return
 				}
 
@@ -587,5 +660,173 @@ func (a *ChainAPI) ChainExport(ctx context.Context, nroots abi.ChainEpoch, skipo
 		}
 	}()
 
+	return out
+}
+
+func (a *ChainAPI) ChainPrune(ctx context.Context, keep abi.ChainEpoch, dryRun bool) (*api.ChainPruneResult, error) {
+	if keep < build.Finality {
+		// the states of a finality are needed to handle reorgs
+		return nil, xerrors.Errorf("must keep at least %d epochs of state, got %d", build.Finality, keep)
+	}
+	return a.StateManager.PruneStates(ctx, keep, dryRun)
+}
+
+func (a *ChainAPI) ChainStat(ctx context.Context) (*api.ChainStat, error) {
+	out := &api.ChainStat{}
+
+	if c, ok := a.ChainBlockstore.(*blockstore.BlockCache); ok {
+		st := c.Stats()
+		out.BlockstoreCache = &api.BlockstoreCacheStat{
+			Hits:      st.Hits,
+			Misses:    st.Misses,
+			Evictions: st.Evictions,
+			Entries:   st.Entries,
+			Bytes:     st.Bytes,
+			Capacity:  st.Capacity,
+		}
+	}
+
+	return out, nil
+}
+
+func (a *ChainAPI) ChainCheckBlockstore(ctx context.Context, repair bool) (*api.ChainCheckResult, error) {
+	var fetch store.BlockFetcher
+	if repair {
+		fetch = a.BlockService.GetBlock
+	}
+	return a.Chain.ScrubBlockstore(ctx, fetch)
+}
+
+func (a *ChainAPI) ChainReindexHeights(ctx context.Context, tsk types.TipSetKey) error {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+
+	start := build.Clock.Now()
+	err = a.Chain.ReindexHeights(ctx, ts, func(h abi.ChainEpoch) {
+		if h%10000 == 0 {
+			reqid.Logger(ctx, log).Infow("reindexing heights", "height", h)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	reqid.Logger(ctx, log).Infow("reindexed heights", "height", ts.Height(), "took", build.Clock.Since(start))
+	return nil
+}
+
+func (a *ChainAPI) ChainIndexMessages(ctx context.Context, from, to abi.ChainEpoch) (int, error) {
+	return a.Chain.IndexMessages(ctx, a.Chain.GetHeaviestTipSet(), from, to)
+}
+
+func (a *ChainAPI) ChainBackfillMessageIndex(ctx context.Context, opts api.IndexBackfillOpts) (<-chan api.IndexBackfillProgress, error) {
+	head := a.Chain.GetHeaviestTipSet()
+
+	to := opts.To
+	if to < 0 {
+		to = head.Height()
+	}
+	cp := store.BackfillCheckpoint{From: opts.From, To: to, Next: to}
+
+	var resumed bool
+	if !opts.Restart {
+		prev, err := a.Chain.LoadBackfillCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil && prev.From == opts.From && (opts.To < 0 || prev.To == opts.To) {
+			cp, resumed = *prev, true
+		}
+	}
+
+	var limiter *rate.Limiter
+	if opts.MaxTipSetsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.MaxTipSetsPerSecond), 1)
+	}
+
+	out := make(chan api.IndexBackfillProgress, 16)
+	go func() {
+		defer close(out)
+
+		send := func(p api.IndexBackfillProgress) {
+			select {
+			case out <- p:
+			case <-ctx.Done():
+			}
+		}
+
+		last := api.IndexBackfillProgress{From: cp.From, To: cp.To, Next: cp.Next, Resumed: resumed}
+		send(last)
+
+		err := a.Chain.BackfillMessageIndex(ctx, head, cp, limiter, func(p store.BackfillProgress) {
+			last.Next = p.Checkpoint.Next
+			last.TipSets = p.TipSets
+			last.Messages = p.Messages
+			send(last)
+		})
+
+		last.Done = true
+		if err != nil {
+			last.Error = err.Error()
+		}
+		send(last)
+	}()
+
+	return out, nil
+}
+
+func (a *ChainAPI) ChainNotifyFrom(ctx context.Context, cursor uint64) (<-chan *api.HeadChanges, error) {
+	return a.Chain.SubHeadChangesFrom(ctx, cursor)
+}
+
+func (a *ChainAPI) ChainNotifySince(ctx context.Context, tsk types.TipSetKey) (<-chan *api.HeadChanges, error) {
+	return a.Chain.SubHeadChangesSince(ctx, tsk)
+}
+
+func (a *ChainAPI) ChainGetReorgs(ctx context.Context, since abi.ChainEpoch) ([]*api.ChainReorg, error) {
+	return a.Chain.GetReorgs(since)
+}
+
+func (a *ChainAPI) ChainReorgNotify(ctx context.Context) (<-chan *api.ChainReorg, error) {
+	return a.Chain.SubReorgs(ctx), nil
+}
+
+func (a *ChainAPI) ChainBlockstoreGC(ctx context.Context, opts api.BlockstoreGCOpts) (<-chan api.BlockstoreGCProgress, error) {
+	gc, ok := a.Repo.(repo.DatastoreGC)
+	if !ok {
+		return nil, xerrors.Errorf("repo doesn't support online datastore GC")
+	}
+
+	out := make(chan api.BlockstoreGCProgress, 16)
+	go func() {
+		defer close(out)
+
+		send := func(p api.BlockstoreGCProgress) {
+			select {
+			case out <- p:
+			case <-ctx.Done():
+			}
+		}
+
+		err := gc.GCDatastores(ctx, repo.ChainDatastores(a.Splitstore != nil), repo.DatastoreGCOpts{
+			DiscardRatio: opts.DiscardRatio,
+			Compact:      opts.Compact,
+		}, func(p repo.DatastoreGCProgress) {
+			send(api.BlockstoreGCProgress{
+				Namespace: p.Namespace,
+				Stage:     p.Stage,
+				Rewrites:  p.Rewrites,
+				LSMSize:   p.LSMSize,
+				VlogSize:  p.VlogSize,
+			})
+		})
+		if err != nil {
+			reqid.Logger(ctx, log).Errorf("chain blockstore GC failed: %s", err)
+			send(api.BlockstoreGCProgress{Error: err.Error()})
+		}
+	}()
+
 	return out, nil
 }
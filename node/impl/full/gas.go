@@ -191,6 +191,105 @@ func gasEstimateGasPremium(cstore *store.ChainStore, nblocksincl uint64) (types.
 	return premium, nil
 }
 
+// defaultFeeForecastLookback is the number of epochs sampled by
+// GasEstimateFeeForecast if the lookback isn't set.
+const defaultFeeForecastLookback = 20
+
+func (a *GasAPI) GasEstimateFeeForecast(ctx context.Context, nepochs, lookback uint64, tsk types.TipSetKey) (*api.FeeForecast, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+	return gasEstimateFeeForecast(ctx, a.Chain, ts, nepochs, lookback)
+}
+func gasEstimateFeeForecast(ctx context.Context, cstore *store.ChainStore, ts *types.TipSet, nepochs, lookback uint64) (*api.FeeForecast, error) {
+	if lookback == 0 {
+		lookback = defaultFeeForecastLookback
+	}
+
+	baseFee, err := cstore.ComputeBaseFee(ctx, ts)
+	if err != nil {
+		return nil, xerrors.Errorf("computing base fee: %w", err)
+	}
+
+	out := &api.FeeForecast{Height: ts.Height()}
+
+	var prices []gasMeta
+	var gasLimit int64
+	cur := ts
+	for out.Epochs < lookback {
+		msgs, err := cstore.MessagesForTipset(cur)
+		if err != nil {
+			return nil, xerrors.Errorf("loading messages: %w", err)
+		}
+		for _, msg := range msgs {
+			prices = append(prices, gasMeta{
+				price: msg.VMMessage().GasPremium,
+				limit: msg.VMMessage().GasLimit,
+			})
+			gasLimit += msg.VMMessage().GasLimit
+		}
+		out.Epochs++
+		out.Blocks += len(cur.Blocks())
+
+		if cur.Height() == 0 {
+			break // genesis
+		}
+		cur, err = cstore.LoadTipSet(cur.Parents())
+		if err != nil {
+			return nil, xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
+
+	// assume the blocks of the next epochs are as full as the sampled ones
+	perBlock := gasLimit / int64(out.Blocks)
+	out.Fullness = float64(perBlock) / float64(build.BlockGasTarget)
+
+	out.BaseFees = make([]types.BigInt, 0, nepochs)
+	for i := uint64(0); i < nepochs; i++ {
+		out.BaseFees = append(out.BaseFees, baseFee)
+		baseFee = store.ComputeNextBaseFee(baseFee, perBlock, 1, ts.Height()+abi.ChainEpoch(i)+1)
+	}
+
+	out.Premiums = premiumPercentiles(prices, api.FeeForecastPercentiles)
+
+	return out, nil
+}
+
+// premiumPercentiles returns the gas premiums at the given percentiles of the
+// gas limit of the messages, or nil if there are none.
+func premiumPercentiles(prices []gasMeta, percentiles []int) []api.PremiumPercentile {
+	var total int64
+	for _, price := range prices {
+		total += price.limit
+	}
+	if total == 0 {
+		return nil
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		// sort asc by price
+		return prices[i].price.LessThan(prices[j].price)
+	})
+
+	out := make([]api.PremiumPercentile, 0, len(percentiles))
+	var at int64
+	i := 0
+	for _, p := range percentiles {
+		threshold := total * int64(p) / 100
+		for i < len(prices)-1 && at+prices[i].limit < threshold {
+			at += prices[i].limit
+			i++
+		}
+		out = append(out, api.PremiumPercentile{
+			Percentile: p,
+			GasPremium: prices[i].price,
+		})
+	}
+
+	return out
+}
+
 func (a *GasAPI) GasEstimateGasLimit(ctx context.Context, msgIn *types.Message, _ types.TipSetKey) (int64, error) {
 	return gasEstimateGasLimit(ctx, a.Chain, a.Stmgr, a.Mpool, msgIn)
 }
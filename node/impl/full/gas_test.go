@@ -38,3 +38,19 @@ func TestMedian(t *testing.T) {
 		{big.NewInt(30), build.BlockGasTarget / 2},
 	}, 2))
 }
+
+func TestPremiumPercentiles(t *testing.T) {
+	require.Nil(t, premiumPercentiles(nil, []int{50}))
+
+	pp := premiumPercentiles([]gasMeta{
+		{big.NewInt(30), 10},
+		{big.NewInt(10), 70},
+		{big.NewInt(20), 20},
+	}, []int{10, 70, 75, 90, 100})
+	require.Len(t, pp, 5)
+
+	expect := []int64{10, 10, 20, 20, 30}
+	for i, p := range pp {
+		require.Equal(t, types.NewInt(uint64(expect[i])), p.GasPremium, "percentile %d", p.Percentile)
+	}
+}
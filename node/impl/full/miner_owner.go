@@ -0,0 +1,236 @@
+package full
+
+import (
+	"bytes"
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
+	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/multisig"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func (a *MsigAPI) MinerWithdrawBalance(ctx context.Context, maddr address.Address, amount abi.TokenAmount, from address.Address) (*api.MinerOwnerMessage, error) {
+	available, err := a.StateAPI.StateMinerAvailableBalance(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("getting available balance: %w", err)
+	}
+	if amount.GreaterThan(available) {
+		return nil, xerrors.Errorf("can't withdraw more funds than available; requested: %s; available: %s", types.FIL(amount), types.FIL(available))
+	}
+
+	params, err := actors.SerializeParams(&miner0.WithdrawBalanceParams{
+		AmountRequested: amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a.sendAsOwner(ctx, maddr, from, builtin0.MethodsMiner.WithdrawBalance, params)
+}
+
+func (a *MsigAPI) MinerChangeWorker(ctx context.Context, maddr address.Address, newWorker address.Address, from address.Address) (*api.MinerOwnerMessage, error) {
+	mi, err := a.StateAPI.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+
+	worker, err := a.StateAPI.StateLookupID(ctx, newWorker, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("looking up new worker %s: %w", newWorker, err)
+	}
+
+	params, err := actors.SerializeParams(&miner0.ChangeWorkerAddressParams{
+		NewWorker:       worker,
+		NewControlAddrs: mi.ControlAddresses,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a.sendAsOwner(ctx, maddr, from, builtin0.MethodsMiner.ChangeWorkerAddress, params)
+}
+
+func (a *MsigAPI) MinerConfirmChangeWorker(ctx context.Context, maddr address.Address, from address.Address) (*api.MinerOwnerMessage, error) {
+	nv, err := a.StateAPI.StateNetworkVersion(ctx, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	if actors.VersionForNetwork(nv) == actors.Version0 {
+		return nil, xerrors.Errorf("worker key changes are confirmed by the miner cron before actors v2")
+	}
+
+	mi, err := a.StateAPI.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	if mi.NewWorker == address.Undef {
+		return nil, xerrors.Errorf("miner %s has no pending worker key change", maddr)
+	}
+
+	head := a.StateAPI.Chain.GetHeaviestTipSet()
+	if head.Height() < mi.WorkerChangeEpoch {
+		return nil, xerrors.Errorf("worker key change to %s is effective at epoch %d, current epoch is %d", mi.NewWorker, mi.WorkerChangeEpoch, head.Height())
+	}
+
+	return a.sendAsOwner(ctx, maddr, from, builtin2.MethodsMiner.ConfirmUpdateWorkerKey, nil)
+}
+
+func (a *MsigAPI) MinerSetControlAddresses(ctx context.Context, maddr address.Address, addrs []address.Address, from address.Address) (*api.MinerOwnerMessage, error) {
+	mi, err := a.StateAPI.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+
+	controls := make([]address.Address, 0, len(addrs))
+	for _, addr := range addrs {
+		id, err := a.StateAPI.StateLookupID(ctx, addr, types.EmptyTSK)
+		if err != nil {
+			return nil, xerrors.Errorf("looking up control address %s: %w", addr, err)
+		}
+		controls = append(controls, id)
+	}
+
+	params, err := actors.SerializeParams(&miner0.ChangeWorkerAddressParams{
+		NewWorker:       mi.Worker,
+		NewControlAddrs: controls,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a.sendAsOwner(ctx, maddr, from, builtin0.MethodsMiner.ChangeWorkerAddress, params)
+}
+
+// sendAsOwner sends a message with the given method and params from the owner
+// of the miner to the miner actor. If the owner is a multisig, the message is
+// proposed to it from 'from', or an identical pending proposal is approved.
+func (a *MsigAPI) sendAsOwner(ctx context.Context, maddr address.Address, from address.Address, method abi.MethodNum, params []byte) (*api.MinerOwnerMessage, error) {
+	mi, err := a.StateAPI.StateMinerInfo(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("getting miner info: %w", err)
+	}
+
+	owner, err := a.StateAPI.StateGetActor(ctx, mi.Owner, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("loading owner actor: %w", err)
+	}
+
+	out := &api.MinerOwnerMessage{Owner: mi.Owner}
+
+	if !builtin.IsMultisigActor(owner.Code) {
+		if from != address.Undef {
+			fromID, err := a.StateAPI.StateLookupID(ctx, from, types.EmptyTSK)
+			if err != nil {
+				return nil, xerrors.Errorf("looking up sender: %w", err)
+			}
+			if fromID != mi.Owner {
+				return nil, xerrors.Errorf("sender %s isn't the owner of the miner (%s)", from, mi.Owner)
+			}
+		}
+
+		smsg, err := a.MpoolAPI.MpoolPushMessage(ctx, &types.Message{
+			From:   mi.Owner,
+			To:     maddr,
+			Method: method,
+			Value:  big.Zero(),
+			Params: params,
+		}, nil)
+		if err != nil {
+			return nil, xerrors.Errorf("mpool push: %w", err)
+		}
+
+		out.Message = smsg.Cid()
+		return out, nil
+	}
+
+	out.Multisig = true
+
+	if from == address.Undef {
+		return nil, xerrors.Errorf("the owner of the miner is multisig %s, a signer must be given to propose the message", mi.Owner)
+	}
+
+	fromID, err := a.StateAPI.StateLookupID(ctx, from, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("looking up sender: %w", err)
+	}
+	maddrID, err := a.StateAPI.StateLookupID(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("looking up miner: %w", err)
+	}
+
+	mst, err := multisig.Load(a.StateAPI.Chain.Store(ctx), owner)
+	if err != nil {
+		return nil, xerrors.Errorf("loading multisig state: %w", err)
+	}
+
+	signers, err := mst.Signers()
+	if err != nil {
+		return nil, err
+	}
+	var isSigner bool
+	for _, s := range signers {
+		isSigner = isSigner || s == fromID
+	}
+	if !isSigner {
+		return nil, xerrors.Errorf("%s isn't a signer of the owner multisig %s", from, mi.Owner)
+	}
+
+	// look for an identical proposal to approve instead of proposing again
+	var pending *multisig.Transaction
+	var pendingID int64
+	err = mst.ForEachPendingTxn(func(id int64, txn multisig.Transaction) error {
+		if pending != nil || txn.Method != method || !txn.Value.IsZero() || !bytes.Equal(txn.Params, params) {
+			return nil
+		}
+		to, err := a.StateAPI.StateLookupID(ctx, txn.To, types.EmptyTSK)
+		if err != nil || to != maddrID {
+			return nil
+		}
+
+		pending, pendingID = &txn, id
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("reading pending multisig transactions: %w", err)
+	}
+
+	if pending == nil {
+		c, err := a.MsigPropose(ctx, mi.Owner, maddr, big.Zero(), from, uint64(method), params)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Message = c
+		return out, nil
+	}
+
+	for _, approver := range pending.Approved {
+		if approver == fromID {
+			return nil, xerrors.Errorf("%s has already approved the pending transaction %d", from, pendingID)
+		}
+	}
+	if len(pending.Approved) == 0 {
+		return nil, xerrors.Errorf("pending transaction %d has no proposer", pendingID)
+	}
+
+	c, err := a.MsigApproveTxnHash(ctx, mi.Owner, uint64(pendingID), pending.Approved[0], pending.To, pending.Value, from, uint64(method), params)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Message = c
+	out.Approved = true
+	out.TxID = pendingID
+	return out, nil
+}
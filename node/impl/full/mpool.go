@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/ipfs/go-cid"
 	"go.uber.org/fx"
 	"golang.org/x/xerrors"
@@ -13,6 +14,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/messagepool"
 	"github.com/filecoin-project/lotus/chain/messagesigner"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/reqid"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
@@ -124,6 +126,14 @@ func (a *MpoolAPI) MpoolClear(ctx context.Context, local bool) error {
 	return nil
 }
 
+func (a *MpoolAPI) MpoolLocalMessages(ctx context.Context) ([]*types.SignedMessage, error) {
+	return a.Mpool.LocalMessages()
+}
+
+func (a *MpoolAPI) MpoolAbandon(ctx context.Context, msg cid.Cid) error {
+	return a.Mpool.AbandonLocal(msg)
+}
+
 func (m *MpoolModule) MpoolPush(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
 	return m.Mpool.Push(smsg)
 }
@@ -133,6 +143,13 @@ func (a *MpoolAPI) MpoolPushUntrusted(ctx context.Context, smsg *types.SignedMes
 }
 
 func (a *MpoolAPI) MpoolPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (*types.SignedMessage, error) {
+	return a.SignAndPushMessage(ctx, msg, spec, nil)
+}
+
+// SignAndPushMessage is MpoolPushMessage, calling signed when set with the
+// signed message before pushing it. The message isn't pushed, and its nonce
+// is freed, if signed returns an error.
+func (a *MpoolAPI) SignAndPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, signed func(*types.SignedMessage) error) (*types.SignedMessage, error) {
 	cp := *msg
 	msg = &cp
 	inMsg := *msg
@@ -165,7 +182,7 @@ func (a *MpoolAPI) MpoolPushMessage(ctx context.Context, msg *types.Message, spe
 	}
 
 	if msg.From.Protocol() == address.ID {
-		log.Warnf("Push from ID address (%s), adjusting to %s", msg.From, fromA)
+		reqid.Logger(ctx, log).Warnf("Push from ID address (%s), adjusting to %s", msg.From, fromA)
 		msg.From = fromA
 	}
 
@@ -180,6 +197,11 @@ func (a *MpoolAPI) MpoolPushMessage(ctx context.Context, msg *types.Message, spe
 
 	// Sign and push the message
 	return a.MessageSigner.SignMessage(ctx, msg, func(smsg *types.SignedMessage) error {
+		if signed != nil {
+			if err := signed(smsg); err != nil {
+				return err
+			}
+		}
 		if _, err := a.MpoolModuleAPI.MpoolPush(ctx, smsg); err != nil {
 			return xerrors.Errorf("mpool push: failed to push message: %w", err)
 		}
@@ -187,6 +209,172 @@ func (a *MpoolAPI) MpoolPushMessage(ctx context.Context, msg *types.Message, spe
 	})
 }
 
+func (a *MpoolAPI) MpoolBatchPush(ctx context.Context, smsgs []*types.SignedMessage) ([]cid.Cid, error) {
+	return a.Mpool.PushBatch(smsgs)
+}
+
+func (a *MpoolAPI) MpoolBatchPushMessage(ctx context.Context, msgs []*types.Message, spec *api.MessageSendSpec) ([]*types.SignedMessage, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	fromA, err := a.Stmgr.ResolveToKeyAddress(ctx, msgs[0].From, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("getting key address: %w", err)
+	}
+	{
+		done, err := a.PushLocks.TakeLock(ctx, fromA)
+		if err != nil {
+			return nil, xerrors.Errorf("taking lock: %w", err)
+		}
+		defer done()
+	}
+
+	out := make([]*types.Message, len(msgs))
+	total := big.Zero()
+	for i, msg := range msgs {
+		cp := *msg
+		msg = &cp
+		inMsg := *msg
+
+		if msg.From != msgs[0].From {
+			from, err := a.Stmgr.ResolveToKeyAddress(ctx, msg.From, nil)
+			if err != nil {
+				return nil, xerrors.Errorf("message %d: getting key address: %w", i, err)
+			}
+			if from != fromA {
+				return nil, xerrors.Errorf("message %d is from %s, expected all messages to be from %s", i, msg.From, msgs[0].From)
+			}
+		}
+
+		if msg.Nonce != 0 {
+			return nil, xerrors.Errorf("MpoolBatchPushMessage expects message nonces to be 0, message %d nonce was %d", i, msg.Nonce)
+		}
+
+		msg, err = a.GasAPI.GasEstimateMessageGas(ctx, msg, spec, types.EmptyTSK)
+		if err != nil {
+			return nil, xerrors.Errorf("message %d: GasEstimateMessageGas error: %w", i, err)
+		}
+
+		if msg.GasPremium.GreaterThan(msg.GasFeeCap) {
+			inJson, _ := json.Marshal(inMsg)
+			outJson, _ := json.Marshal(msg)
+			return nil, xerrors.Errorf("message %d: After estimation, GasPremium is greater than GasFeeCap, inmsg: %s, outmsg: %s",
+				i, inJson, outJson)
+		}
+
+		msg.From = fromA
+		total = big.Add(total, msg.Value)
+		out[i] = msg
+	}
+
+	b, err := a.WalletBalance(ctx, fromA)
+	if err != nil {
+		return nil, xerrors.Errorf("mpool push: getting origin balance: %w", err)
+	}
+
+	if b.LessThan(total) {
+		return nil, xerrors.Errorf("mpool push: not enough funds: %s < %s", b, total)
+	}
+
+	// Sign and push the messages
+	return a.MessageSigner.SignMessages(ctx, out, func(smsgs []*types.SignedMessage) error {
+		if _, err := a.Mpool.PushBatch(smsgs); err != nil {
+			return xerrors.Errorf("mpool push: failed to push messages: %w", err)
+		}
+		return nil
+	})
+}
+
+func (a *MpoolAPI) MpoolReplace(ctx context.Context, from address.Address, nonce uint64, spec *api.MpoolReplaceSpec) (*types.SignedMessage, error) {
+	if spec == nil {
+		spec = &api.MpoolReplaceSpec{}
+	}
+
+	fromA, err := a.Stmgr.ResolveToKeyAddress(ctx, from, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("getting key address: %w", err)
+	}
+	{
+		done, err := a.PushLocks.TakeLock(ctx, fromA)
+		if err != nil {
+			return nil, xerrors.Errorf("taking lock: %w", err)
+		}
+		defer done()
+	}
+
+	pending, _ := a.Mpool.PendingFor(fromA)
+	var found *types.SignedMessage
+	for _, sm := range pending {
+		if sm.Message.Nonce == nonce {
+			found = sm
+			break
+		}
+	}
+	if found == nil {
+		return nil, xerrors.Errorf("no pending message from %s with nonce %d", from, nonce)
+	}
+
+	msg := found.Message
+	minRBF := messagepool.ComputeMinRBF(msg.GasPremium)
+
+	if spec.GasLimit > 0 {
+		msg.GasLimit = spec.GasLimit
+	}
+
+	maxFee := spec.MaxFee
+	if maxFee.Int == nil {
+		maxFee = big.Zero()
+	}
+
+	premiumSet := spec.GasPremium.Int != nil && !spec.GasPremium.IsZero()
+	feeCapSet := spec.GasFeeCap.Int != nil && !spec.GasFeeCap.IsZero()
+
+	if premiumSet {
+		msg.GasPremium = spec.GasPremium
+	}
+	if feeCapSet {
+		msg.GasFeeCap = spec.GasFeeCap
+	}
+
+	if !premiumSet || !feeCapSet {
+		est := msg
+		est.GasFeeCap = big.Zero()
+		est.GasPremium = big.Zero()
+
+		estimated, err := a.GasAPI.GasEstimateMessageGas(ctx, &est, &api.MessageSendSpec{MaxFee: maxFee}, types.EmptyTSK)
+		if err != nil {
+			return nil, xerrors.Errorf("estimating gas: %w", err)
+		}
+
+		if !premiumSet {
+			msg.GasPremium = big.Max(estimated.GasPremium, minRBF)
+		}
+		if !feeCapSet {
+			msg.GasFeeCap = big.Max(estimated.GasFeeCap, msg.GasPremium)
+			messagepool.CapGasFee(&msg, maxFee)
+		}
+	}
+
+	if msg.GasPremium.LessThan(minRBF) {
+		return nil, xerrors.Errorf("gas premium %s is below the minimum of %s needed to replace the message", msg.GasPremium, minRBF)
+	}
+	if msg.GasPremium.GreaterThan(msg.GasFeeCap) {
+		return nil, xerrors.Errorf("gas premium %s is greater than the fee cap %s", msg.GasPremium, msg.GasFeeCap)
+	}
+
+	smsg, err := a.WalletSignMessage(ctx, msg.From, &msg)
+	if err != nil {
+		return nil, xerrors.Errorf("signing replacement message: %w", err)
+	}
+
+	if _, err := a.MpoolModuleAPI.MpoolPush(ctx, smsg); err != nil {
+		return nil, xerrors.Errorf("mpool push: failed to push replacement message: %w", err)
+	}
+
+	return smsg, nil
+}
+
 func (a *MpoolAPI) MpoolGetNonce(ctx context.Context, addr address.Address) (uint64, error) {
 	return a.Mpool.GetNonce(addr)
 }
@@ -194,3 +382,31 @@ func (a *MpoolAPI) MpoolGetNonce(ctx context.Context, addr address.Address) (uin
 func (a *MpoolAPI) MpoolSub(ctx context.Context) (<-chan api.MpoolUpdate, error) {
 	return a.Mpool.Updates(ctx)
 }
+
+func (a *MpoolAPI) MpoolSubAddress(ctx context.Context, addrs []address.Address) (<-chan api.MpoolUpdate, error) {
+	if len(addrs) == 0 {
+		return nil, xerrors.New("no addresses to subscribe to")
+	}
+
+	// messages may refer to an actor by its ID or its key address, match both
+	ts := a.Chain.GetHeaviestTipSet()
+	all := make([]address.Address, 0, 2*len(addrs))
+	for _, addr := range addrs {
+		all = append(all, addr)
+
+		var alias address.Address
+		var err error
+		if addr.Protocol() == address.ID {
+			alias, err = a.Stmgr.ResolveToKeyAddress(ctx, addr, ts)
+		} else {
+			alias, err = a.Stmgr.LookupID(ctx, addr, ts)
+		}
+		if err != nil {
+			// the actor may not exist yet
+			continue
+		}
+		all = append(all, alias)
+	}
+
+	return a.Mpool.UpdatesFor(ctx, all)
+}
@@ -0,0 +1,41 @@
+package full
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/messagescheduler"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type MessageSchedulerAPI struct {
+	fx.In
+
+	Scheduler *messagescheduler.Scheduler
+}
+
+func (a *MessageSchedulerAPI) MpoolSchedule(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec, schedule api.MessageSchedule) (uint64, error) {
+	cp := *msg
+	return a.Scheduler.Schedule(api.ScheduledMessage{
+		Schedule: schedule,
+		Message:  &cp,
+		Spec:     spec,
+	})
+}
+
+func (a *MessageSchedulerAPI) MpoolScheduleSigned(ctx context.Context, smsg *types.SignedMessage, schedule api.MessageSchedule) (uint64, error) {
+	return a.Scheduler.Schedule(api.ScheduledMessage{
+		Schedule: schedule,
+		Signed:   smsg,
+	})
+}
+
+func (a *MessageSchedulerAPI) MpoolScheduled(ctx context.Context) ([]api.ScheduledMessage, error) {
+	return a.Scheduler.List(), nil
+}
+
+func (a *MessageSchedulerAPI) MpoolScheduledCancel(ctx context.Context, id uint64) error {
+	return a.Scheduler.Cancel(id)
+}
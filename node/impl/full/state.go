@@ -3,6 +3,7 @@ package full
 import (
 	"bytes"
 	"context"
+	"sort"
 	"strconv"
 
 	cid "github.com/ipfs/go-cid"
@@ -19,6 +20,8 @@ import (
 	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/actors/builtin"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/market"
 	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
@@ -36,6 +39,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/vm"
 	"github.com/filecoin-project/lotus/chain/wallet"
 	"github.com/filecoin-project/lotus/lib/bufbstore"
+	"github.com/filecoin-project/lotus/lib/reqid"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
@@ -44,6 +48,7 @@ type StateModuleAPI interface {
 	StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error)
 	StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
 	StateWaitMsg(ctx context.Context, msg cid.Cid, confidence uint64) (*api.MsgLookup, error)
+	StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
 	MsigGetAvailableBalance(ctx context.Context, addr address.Address, tsk types.TipSetKey) (types.BigInt, error)
 	MsigGetVested(ctx context.Context, addr address.Address, start types.TipSetKey, end types.TipSetKey) (types.BigInt, error)
 }
@@ -347,39 +352,46 @@ func (a *StateAPI) StateCall(ctx context.Context, msg *types.Message, tsk types.
 }
 
 func (a *StateAPI) StateReplay(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) (*api.InvocResult, error) {
+	_, res, err := a.replay(ctx, tsk, mc)
+	return res, err
+}
+
+// replay replays the message as StateReplay, and also returns the tipset the
+// message was replayed in.
+func (a *StateAPI) replay(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) (*types.TipSet, *api.InvocResult, error) {
 	msgToReplay := mc
 	var ts *types.TipSet
 	var err error
 	if tsk == types.EmptyTSK {
 		mlkp, err := a.StateSearchMsg(ctx, mc)
 		if err != nil {
-			return nil, xerrors.Errorf("searching for msg %s: %w", mc, err)
+			return nil, nil, xerrors.Errorf("searching for msg %s: %w", mc, err)
 		}
 		if mlkp == nil {
-			return nil, xerrors.Errorf("didn't find msg %s", mc)
+			return nil, nil, xerrors.Errorf("didn't find msg %s", mc)
 		}
 
 		msgToReplay = mlkp.Message
 
 		executionTs, err := a.Chain.GetTipSetFromKey(mlkp.TipSet)
 		if err != nil {
-			return nil, xerrors.Errorf("loading tipset %s: %w", mlkp.TipSet, err)
+			return nil, nil, xerrors.Errorf("loading tipset %s: %w", mlkp.TipSet, err)
 		}
 
 		ts, err = a.Chain.LoadTipSet(executionTs.Parents())
 		if err != nil {
-			return nil, xerrors.Errorf("loading parent tipset %s: %w", mlkp.TipSet, err)
+			return nil, nil, xerrors.Errorf("loading parent tipset %s: %w", mlkp.TipSet, err)
 		}
 	} else {
 		ts, err = a.Chain.LoadTipSet(tsk)
 		if err != nil {
-			return nil, xerrors.Errorf("loading specified tipset %s: %w", tsk, err)
+			return nil, nil, xerrors.Errorf("loading specified tipset %s: %w", tsk, err)
 		}
 	}
 
 	m, r, err := a.StateManager.Replay(ctx, ts, msgToReplay)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var errstr string
@@ -387,7 +399,7 @@ func (a *StateAPI) StateReplay(ctx context.Context, tsk types.TipSetKey, mc cid.
 		errstr = r.ActorErr.Error()
 	}
 
-	return &api.InvocResult{
+	return ts, &api.InvocResult{
 		MsgCid:         msgToReplay,
 		Msg:            m,
 		MsgRct:         &r.MessageReceipt,
@@ -398,6 +410,45 @@ func (a *StateAPI) StateReplay(ctx context.Context, tsk types.TipSetKey, mc cid.
 	}, nil
 }
 
+func (a *StateAPI) StateReplayGasProfile(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) (*api.GasProfile, error) {
+	res, err := a.StateReplay(ctx, tsk, mc)
+	if err != nil {
+		return nil, err
+	}
+
+	return stmgr.MakeGasProfile(res.MsgCid, &res.ExecutionTrace), nil
+}
+
+func (a *StateAPI) StateReplayGasPprof(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) ([]byte, error) {
+	ts, res, err := a.replay(ctx, tsk, mc)
+	if err != nil {
+		return nil, err
+	}
+
+	// actors created by the message are only in the state after the tipset,
+	// actors deleted by it only in the state before
+	post, _, err := a.StateManager.TipSetState(ctx, ts)
+	if err != nil {
+		return nil, xerrors.Errorf("computing tipset state: %w", err)
+	}
+	code := func(addr address.Address) (cid.Cid, error) {
+		act, err := a.StateManager.LoadActorRaw(ctx, addr, post)
+		if err != nil {
+			act, err = a.StateManager.LoadActorRaw(ctx, addr, ts.ParentState())
+		}
+		if err != nil {
+			return cid.Undef, err
+		}
+		return act.Code, nil
+	}
+
+	var buf bytes.Buffer
+	if err := stmgr.WriteGasPprof(&buf, &res.ExecutionTrace, code); err != nil {
+		return nil, xerrors.Errorf("writing profile: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 func stateForTs(ctx context.Context, ts *types.TipSet, cstore *store.ChainStore, smgr *stmgr.StateManager) (*state.StateTree, error) {
 	if ts == nil {
 		ts = cstore.GetHeaviestTipSet()
@@ -424,12 +475,12 @@ func (m *StateModule) StateGetActor(ctx context.Context, actor address.Address,
 	if err != nil {
 		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
 	}
-	state, err := m.stateForTs(ctx, ts)
+	st, _, err := m.StateManager.TipSetState(ctx, ts)
 	if err != nil {
 		return nil, xerrors.Errorf("computing tipset state failed: %w", err)
 	}
 
-	return state.GetActor(actor)
+	return m.StateManager.LoadActorRaw(ctx, actor, st)
 }
 
 func (m *StateModule) StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error) {
@@ -481,6 +532,33 @@ func (a *StateAPI) StateReadState(ctx context.Context, actor address.Address, ts
 	}, nil
 }
 
+func (a *StateAPI) StateDecodeParams(ctx context.Context, toAddr address.Address, method abi.MethodNum, params []byte, tsk types.TipSetKey) (interface{}, error) {
+	act, err := a.StateGetActor(ctx, toAddr, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("getting actor: %w", err)
+	}
+
+	return stmgr.DecodeParams(act.Code, method, params)
+}
+
+func (a *StateAPI) StateDecodeReturn(ctx context.Context, toAddr address.Address, method abi.MethodNum, ret []byte, tsk types.TipSetKey) (interface{}, error) {
+	act, err := a.StateGetActor(ctx, toAddr, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("getting actor: %w", err)
+	}
+
+	return stmgr.DecodeReturn(act.Code, method, ret)
+}
+
+func (a *StateAPI) StateDecodeActorState(ctx context.Context, code cid.Cid, head cid.Cid) (interface{}, error) {
+	blk, err := a.Chain.Blockstore().Get(head)
+	if err != nil {
+		return nil, xerrors.Errorf("getting state head: %w", err)
+	}
+
+	return vm.DumpActorState(&types.Actor{Code: code, Head: head}, blk.RawData())
+}
+
 // This is on StateAPI because miner.Miner requires this, and MinerAPI requires miner.Miner
 func (a *StateAPI) MinerGetBaseInfo(ctx context.Context, maddr address.Address, epoch abi.ChainEpoch, tsk types.TipSetKey) (*api.MiningBaseInfo, error) {
 	return stmgr.MinerGetBaseInfo(ctx, a.StateManager, a.Beacon, tsk, epoch, maddr, a.ProofVerifier)
@@ -505,27 +583,27 @@ func (a *StateAPI) MinerCreateBlock(ctx context.Context, bt *api.BlockTemplate)
 }
 
 func (m *StateModule) StateWaitMsg(ctx context.Context, msg cid.Cid, confidence uint64) (*api.MsgLookup, error) {
-	return stateWaitMsgLimited(ctx, m.StateManager, m.Chain, msg, confidence, stmgr.LookbackNoLimit)
+	return m.StateWaitMsgBounded(ctx, msg, confidence, api.LookbackNoLimit, true)
 }
 func (a *StateAPI) StateWaitMsgLimited(ctx context.Context, msg cid.Cid, confidence uint64, lookbackLimit abi.ChainEpoch) (*api.MsgLookup, error) {
-	return stateWaitMsgLimited(ctx, a.StateManager, a.Chain, msg, confidence, lookbackLimit)
+	return a.StateWaitMsgBounded(ctx, msg, confidence, lookbackLimit, true)
 }
-func stateWaitMsgLimited(ctx context.Context, smgr *stmgr.StateManager, cstore *store.ChainStore, msg cid.Cid, confidence uint64, lookbackLimit abi.ChainEpoch) (*api.MsgLookup, error) {
-	ts, recpt, found, err := smgr.WaitForMessage(ctx, msg, confidence, lookbackLimit)
+func (m *StateModule) StateWaitMsgBounded(ctx context.Context, msg cid.Cid, confidence uint64, lookbackLimit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	ts, recpt, found, err := m.StateManager.WaitForMessage(ctx, msg, confidence, lookbackLimit, allowReplaced)
 	if err != nil {
 		return nil, err
 	}
 
 	var returndec interface{}
 	if recpt.ExitCode == 0 && len(recpt.Return) > 0 {
-		cmsg, err := cstore.GetCMessage(msg)
+		cmsg, err := m.Chain.GetCMessage(found)
 		if err != nil {
 			return nil, xerrors.Errorf("failed to load message after successful receipt search: %w", err)
 		}
 
 		vmsg := cmsg.VMMessage()
 
-		t, err := stmgr.GetReturnType(ctx, smgr, vmsg.To, vmsg.Method, ts)
+		t, err := stmgr.GetReturnType(ctx, m.StateManager, vmsg.To, vmsg.Method, ts)
 		if err != nil {
 			return nil, xerrors.Errorf("failed to get return type: %w", err)
 		}
@@ -547,7 +625,11 @@ func stateWaitMsgLimited(ctx context.Context, smgr *stmgr.StateManager, cstore *
 }
 
 func (a *StateAPI) StateSearchMsg(ctx context.Context, msg cid.Cid) (*api.MsgLookup, error) {
-	ts, recpt, found, err := a.StateManager.SearchForMessage(ctx, msg)
+	return a.StateSearchMsgBounded(ctx, msg, api.LookbackNoLimit, true)
+}
+
+func (a *StateAPI) StateSearchMsgBounded(ctx context.Context, msg cid.Cid, lookbackLimit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error) {
+	ts, recpt, found, err := a.StateManager.SearchForMessage(ctx, msg, lookbackLimit, allowReplaced)
 	if err != nil {
 		return nil, err
 	}
@@ -563,6 +645,31 @@ func (a *StateAPI) StateSearchMsg(ctx context.Context, msg cid.Cid) (*api.MsgLoo
 	return nil, nil
 }
 
+func (a *StateAPI) StateMsgGasCost(ctx context.Context, msg cid.Cid) (*api.MsgGasCost, error) {
+	ts, recpt, found, err := a.StateManager.SearchForMessage(ctx, msg, stmgr.LookbackNoLimit, true)
+	if err != nil {
+		return nil, err
+	}
+	if ts == nil {
+		return nil, nil
+	}
+
+	// the receipts in ts are for the messages included in its parent, which
+	// were executed with the base fee of the parent
+	pts, err := a.Chain.LoadTipSet(ts.Parents())
+	if err != nil {
+		return nil, xerrors.Errorf("loading parent of execution tipset: %w", err)
+	}
+
+	cmsg, err := a.Chain.GetCMessage(found)
+	if err != nil {
+		return nil, xerrors.Errorf("loading message %s: %w", found, err)
+	}
+
+	gc := stmgr.MsgGasCostFromReceipt(cmsg, recpt, pts.Blocks()[0].ParentBaseFee, pts.Height())
+	return &gc, nil
+}
+
 func (a *StateAPI) StateGetReceipt(ctx context.Context, msg cid.Cid, tsk types.TipSetKey) (*types.MessageReceipt, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -636,6 +743,14 @@ func (a *StateAPI) StateMarketParticipants(ctx context.Context, tsk types.TipSet
 }
 
 func (a *StateAPI) StateMarketDeals(ctx context.Context, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	return a.marketDeals(ctx, 0, 0, tsk)
+}
+
+func (a *StateAPI) StateMarketDealsPage(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
+	return a.marketDeals(ctx, start, limit, tsk)
+}
+
+func (a *StateAPI) marketDeals(ctx context.Context, start abi.DealID, limit uint64, tsk types.TipSetKey) (map[string]api.MarketDeal, error) {
 	out := map[string]api.MarketDeal{}
 
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
@@ -658,7 +773,7 @@ func (a *StateAPI) StateMarketDeals(ctx context.Context, tsk types.TipSetKey) (m
 		return nil, err
 	}
 
-	if err := da.ForEach(func(dealID abi.DealID, d market.DealProposal) error {
+	add := func(dealID abi.DealID, d market.DealProposal) error {
 		s, found, err := sa.Get(dealID)
 		if err != nil {
 			return xerrors.Errorf("failed to get state for deal in proposals array: %w", err)
@@ -670,9 +785,33 @@ func (a *StateAPI) StateMarketDeals(ctx context.Context, tsk types.TipSetKey) (m
 			State:    *s,
 		}
 		return nil
-	}); err != nil {
+	}
+
+	if start == 0 && limit == 0 {
+		if err := da.ForEach(add); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	// the deal IDs are allocated in sequence, so the deals of a page are
+	// looked up from start rather than iterating over all the deals before
+	// it; only the IDs of the deals removed since are looked up in vain
+	next, err := state.NextID()
+	if err != nil {
 		return nil, err
 	}
+	for dealID := start; dealID < next && (limit == 0 || uint64(len(out)) < limit); dealID++ {
+		d, found, err := da.Get(dealID)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to get proposal of deal %d: %w", dealID, err)
+		} else if !found {
+			continue
+		}
+		if err := add(dealID, *d); err != nil {
+			return nil, err
+		}
+	}
 	return out, nil
 }
 
@@ -700,6 +839,61 @@ func (a *StateAPI) StateChangedActors(ctx context.Context, old cid.Cid, new cid.
 	return state.Diff(oldTree, newTree)
 }
 
+func (a *StateAPI) StateDiff(ctx context.Context, from, to types.TipSetKey, actor address.Address) (*api.StateDiff, error) {
+	var out api.StateDiff
+	var trees [2]*state.StateTree
+	for i, tsk := range []types.TipSetKey{from, to} {
+		ts, err := a.Chain.GetTipSetFromKey(tsk)
+		if err != nil {
+			return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+		}
+		st, _, err := a.StateManager.TipSetState(ctx, ts)
+		if err != nil {
+			return nil, xerrors.Errorf("computing tipset state: %w", err)
+		}
+		if i == 0 {
+			out.From = st
+		} else {
+			out.To = st
+		}
+		trees[i], err = state.LoadStateTree(a.Chain.Store(ctx), st)
+		if err != nil {
+			return nil, xerrors.Errorf("loading state tree: %w", err)
+		}
+	}
+
+	if actor == address.Undef {
+		actors, err := stmgr.DiffActors(trees[0], trees[1])
+		if err != nil {
+			return nil, xerrors.Errorf("diffing actors: %w", err)
+		}
+		out.Actors = actors
+		return &out, nil
+	}
+
+	var acts [2]*types.Actor
+	for i, tree := range trees {
+		act, err := tree.GetActor(actor)
+		switch {
+		case xerrors.Is(err, types.ErrActorNotFound):
+		case err != nil:
+			return nil, xerrors.Errorf("getting actor: %w", err)
+		default:
+			acts[i] = act
+		}
+	}
+	if acts[0] == nil && acts[1] == nil {
+		return nil, xerrors.Errorf("actor %s not found in either state", actor)
+	}
+
+	diff, err := stmgr.DiffActorState(ctx, a.Chain.Store(ctx), actor, acts[0], acts[1])
+	if err != nil {
+		return nil, xerrors.Errorf("diffing actor state: %w", err)
+	}
+	out.Actor = diff
+	return &out, nil
+}
+
 func (a *StateAPI) StateMinerSectorCount(ctx context.Context, addr address.Address, tsk types.TipSetKey) (api.MinerSectors, error) {
 	act, err := a.StateManager.LoadActorTsk(ctx, addr, tsk)
 	if err != nil {
@@ -840,6 +1034,89 @@ func (a *StateAPI) StateListMessages(ctx context.Context, match *api.MessageMatc
 	return out, nil
 }
 
+func (a *StateAPI) StateListMessagesFor(ctx context.Context, addr address.Address, tsk types.TipSetKey, offset, limit uint64) ([]*api.AddrMessage, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+	if ts == nil {
+		ts = a.Chain.GetHeaviestTipSet()
+	}
+
+	// messages name actors by their ID or key address, look both up
+	addrs := []address.Address{addr}
+	if addr.Protocol() == address.ID {
+		if ka, err := a.StateManager.ResolveToKeyAddress(ctx, addr, ts); err == nil {
+			addrs = append(addrs, ka)
+		}
+	} else if id, err := a.StateManager.LookupID(ctx, addr, ts); err == nil {
+		addrs = append(addrs, id)
+	}
+
+	// each address is read up to the end of the page, the page is cut from
+	// their merged entries
+	pageEnd := 0
+	if limit > 0 {
+		pageEnd = int(offset + limit)
+	}
+
+	var mis []store.MsgInfo
+	seen := map[cid.Cid]struct{}{}
+	for _, la := range addrs {
+		found, err := a.Chain.AddressMessages(ctx, la, ts, pageEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, mi := range found {
+			if _, ok := seen[mi.Message]; ok {
+				continue
+			}
+			seen[mi.Message] = struct{}{}
+			mis = append(mis, mi)
+		}
+	}
+	sort.SliceStable(mis, func(i, j int) bool {
+		if mis[i].Epoch != mis[j].Epoch {
+			return mis[i].Epoch > mis[j].Epoch
+		}
+		return mis[i].Index > mis[j].Index
+	})
+
+	if offset >= uint64(len(mis)) {
+		return []*api.AddrMessage{}, nil
+	}
+	mis = mis[offset:]
+	if limit > 0 && limit < uint64(len(mis)) {
+		mis = mis[:limit]
+	}
+
+	out := make([]*api.AddrMessage, 0, len(mis))
+	for _, mi := range mis {
+		cm, err := a.Chain.GetCMessage(mi.Message)
+		if err != nil {
+			return nil, xerrors.Errorf("loading message %s: %w", mi.Message, err)
+		}
+		ets, err := a.Chain.LoadTipSet(mi.TipSet)
+		if err != nil {
+			return nil, xerrors.Errorf("loading tipset %s: %w", mi.TipSet, err)
+		}
+		r, err := a.Chain.GetParentReceipt(ets.Blocks()[0], mi.Index)
+		if err != nil {
+			return nil, xerrors.Errorf("loading receipt of %s: %w", mi.Message, err)
+		}
+
+		out = append(out, &api.AddrMessage{
+			Cid:     mi.Message,
+			Message: cm.VMMessage(),
+			Receipt: r,
+			TipSet:  mi.TipSet,
+			Height:  mi.Epoch,
+		})
+	}
+
+	return out, nil
+}
+
 func (a *StateAPI) StateCompute(ctx context.Context, height abi.ChainEpoch, msgs []*types.Message, tsk types.TipSetKey) (*api.ComputeStateOutput, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -1077,7 +1354,7 @@ func (a *StateAPI) StateMinerInitialPledgeCollateral(ctx context.Context, maddr
 		return types.EmptyInt, xerrors.Errorf("loading reward actor state: %w", err)
 	}
 
-	circSupply, err := a.StateVMCirculatingSupplyInternal(ctx, ts.Key())
+	circSupply, err := a.StateVMCirculatingSupply(ctx, ts.Key())
 	if err != nil {
 		return big.Zero(), xerrors.Errorf("getting circulating supply: %w", err)
 	}
@@ -1135,7 +1412,7 @@ func (a *StateAPI) StateVerifierStatus(ctx context.Context, addr address.Address
 
 	aid, err := a.StateLookupID(ctx, addr, tsk)
 	if err != nil {
-		log.Warnf("lookup failure %v", err)
+		reqid.Logger(ctx, log).Warnf("lookup failure %v", err)
 		return nil, err
 	}
 
@@ -1166,7 +1443,7 @@ func (a *StateAPI) StateVerifiedClientStatus(ctx context.Context, addr address.A
 
 	aid, err := a.StateLookupID(ctx, addr, tsk)
 	if err != nil {
-		log.Warnf("lookup failure %v", err)
+		reqid.Logger(ctx, log).Warnf("lookup failure %v", err)
 		return nil, err
 	}
 
@@ -1231,7 +1508,7 @@ func (a *StateAPI) StateDealProviderCollateralBounds(ctx context.Context, size a
 		return api.DealCollateralBounds{}, xerrors.Errorf("failed to load reward actor state: %w", err)
 	}
 
-	circ, err := a.StateVMCirculatingSupplyInternal(ctx, ts.Key())
+	circ, err := a.StateVMCirculatingSupply(ctx, ts.Key())
 	if err != nil {
 		return api.DealCollateralBounds{}, xerrors.Errorf("getting total circulating supply: %w", err)
 	}
@@ -1272,7 +1549,7 @@ func (a *StateAPI) StateCirculatingSupply(ctx context.Context, tsk types.TipSetK
 	return a.StateManager.GetCirculatingSupply(ctx, ts.Height(), sTree)
 }
 
-func (a *StateAPI) StateVMCirculatingSupplyInternal(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+func (a *StateAPI) StateVMCirculatingSupply(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
 		return api.CirculatingSupply{}, xerrors.Errorf("loading tipset %s: %w", tsk, err)
@@ -1285,6 +1562,10 @@ func (a *StateAPI) StateVMCirculatingSupplyInternal(ctx context.Context, tsk typ
 	return a.StateManager.GetVMCirculatingSupplyDetailed(ctx, ts.Height(), sTree)
 }
 
+func (a *StateAPI) StateVMCirculatingSupplyInternal(ctx context.Context, tsk types.TipSetKey) (api.CirculatingSupply, error) {
+	return a.StateVMCirculatingSupply(ctx, tsk)
+}
+
 func (a *StateAPI) StateNetworkVersion(ctx context.Context, tsk types.TipSetKey) (network.Version, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -1293,3 +1574,11 @@ func (a *StateAPI) StateNetworkVersion(ctx context.Context, tsk types.TipSetKey)
 
 	return a.StateManager.GetNtwkVersion(ctx, ts.Height()), nil
 }
+
+func (a *StateAPI) StateActorCodeCIDs(ctx context.Context, nv network.Version) (map[string]cid.Cid, error) {
+	if nv > build.NewestNetworkVersion {
+		return nil, xerrors.Errorf("unsupported network version %d", nv)
+	}
+
+	return builtin.ActorCodeCIDs(actors.VersionForNetwork(nv))
+}
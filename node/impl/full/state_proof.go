@@ -0,0 +1,114 @@
+package full
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func (a *StateAPI) StateGetProof(ctx context.Context, actor address.Address, p string, tsk types.TipSetKey) (*api.StateProof, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+
+	// resolve the address outside of the proof, a verifier can only check
+	// lookups in the actors HAMT
+	idAddr, err := a.StateManager.LookupID(ctx, actor, ts)
+	if err != nil {
+		return nil, xerrors.Errorf("resolving actor address: %w", err)
+	}
+
+	return proveActorPath(ctx, a.Chain.Blockstore(), ts.ParentState(), idAddr, p)
+}
+
+// proveActorPath resolves the path in the state of the actor with the given ID
+// address, recording the blocks visited on the way.
+func proveActorPath(ctx context.Context, bs blockstore.Blockstore, root cid.Cid, idAddr address.Address, p string) (*api.StateProof, error) {
+	rbs := &recordingBlockstore{Blockstore: bs}
+
+	st, err := state.LoadStateTree(cbor.NewCborStore(rbs), root)
+	if err != nil {
+		return nil, xerrors.Errorf("loading state tree: %w", err)
+	}
+
+	act, err := st.GetActor(idAddr)
+	if err != nil {
+		return nil, xerrors.Errorf("getting actor: %w", err)
+	}
+
+	ip := "/ipfs/" + act.Head.String()
+	if p = strings.Trim(p, "/"); p != "" {
+		ip += "/" + p
+	}
+
+	nd, err := resolveNode(ctx, rbs, ip)
+	if err != nil {
+		return nil, xerrors.Errorf("resolving path: %w", err)
+	}
+
+	return &api.StateProof{
+		StateRoot: root,
+		Actor:     idAddr,
+		Head:      act.Head,
+		Path:      p,
+		Value:     nd.Cid(),
+		Blocks:    rbs.proofBlocks(),
+	}, nil
+}
+
+// recordingBlockstore records every block read through it, in order, so that
+// the blocks visited by a lookup can be returned as a merkle proof.
+type recordingBlockstore struct {
+	blockstore.Blockstore
+
+	lk     sync.Mutex
+	seen   map[cid.Cid]struct{}
+	blocks []blocks.Block
+}
+
+func (r *recordingBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	blk, err := r.Blockstore.Get(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if r.seen == nil {
+		r.seen = map[cid.Cid]struct{}{}
+	}
+	if _, ok := r.seen[c]; !ok {
+		r.seen[c] = struct{}{}
+		r.blocks = append(r.blocks, blk)
+	}
+
+	return blk, nil
+}
+
+func (r *recordingBlockstore) proofBlocks() []api.ProofBlock {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	out := make([]api.ProofBlock, len(r.blocks))
+	for i, blk := range r.blocks {
+		out[i] = api.ProofBlock{
+			Cid:  blk.Cid(),
+			Data: blk.RawData(),
+		}
+	}
+	return out
+}
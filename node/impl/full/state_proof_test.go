@@ -0,0 +1,81 @@
+package full
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func TestStateProof(t *testing.T) {
+	ctx := context.Background()
+	bs := blockstore.NewTemporary()
+	cst := cbor.NewCborStore(bs)
+
+	// actor state is a HAMT keyed by address
+	m, err := adt.NewMap(adt.WrapStore(ctx, cst), actors.Version0)
+	require.NoError(t, err)
+	for id := uint64(1000); id < 1100; id++ {
+		addr, err := address.NewIDAddress(id)
+		require.NoError(t, err)
+		v := cbg.CborInt(int64(id))
+		require.NoError(t, m.Put(abi.AddrKey(addr), &v))
+	}
+	head, err := m.Root()
+	require.NoError(t, err)
+
+	st, err := state.NewStateTree(cst, types.StateTreeVersion1)
+	require.NoError(t, err)
+	for id := uint64(100); id < 200; id++ {
+		addr, err := address.NewIDAddress(id)
+		require.NoError(t, err)
+		require.NoError(t, st.SetActor(addr, &types.Actor{Code: builtin.AccountActorCodeID, Head: head, Balance: types.NewInt(id)}))
+	}
+	root, err := st.Flush(ctx)
+	require.NoError(t, err)
+
+	actor, err := address.NewIDAddress(150)
+	require.NoError(t, err)
+	path := "@Ha:" + idAddr(t, 1042)
+
+	proof, err := proveActorPath(ctx, bs, root, actor, path)
+	require.NoError(t, err)
+	require.Equal(t, root, proof.StateRoot)
+	require.Equal(t, head, proof.Head)
+	require.NotEmpty(t, proof.Blocks)
+
+	// the proof blocks alone must be enough to repeat the lookup
+	pbs := blockstore.NewTemporary()
+	for _, pb := range proof.Blocks {
+		blk, err := blocks.NewBlockWithCid(pb.Data, pb.Cid)
+		require.NoError(t, err)
+		require.NoError(t, pbs.Put(blk))
+	}
+
+	verified, err := proveActorPath(ctx, pbs, root, actor, path)
+	require.NoError(t, err)
+	require.Equal(t, proof.Value, verified.Value)
+	require.Len(t, verified.Blocks, len(proof.Blocks))
+
+	// and must not contain the whole state
+	all, err := bs.AllKeysChan(ctx)
+	require.NoError(t, err)
+	var total int
+	for range all {
+		total++
+	}
+	require.Less(t, len(proof.Blocks), total)
+}
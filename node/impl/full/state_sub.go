@@ -0,0 +1,276 @@
+package full
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	evstate "github.com/filecoin-project/lotus/chain/events/state"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+func (a *StateAPI) StateSubscribeChanges(ctx context.Context, filter api.StateChangeFilter) (<-chan []*api.StateChangeEvent, error) {
+	if filter.MaxHeight != 0 && filter.MaxHeight < filter.MinHeight {
+		return nil, xerrors.Errorf("max height %d is below min height %d", filter.MaxHeight, filter.MinHeight)
+	}
+	if filter.Predicate != "" {
+		actor, err := evstate.PredicateActor(filter.Predicate)
+		if err != nil {
+			return nil, xerrors.Errorf("%w (known predicates: %v)", err, evstate.PredicateNames())
+		}
+		if actor != address.Undef && len(filter.Actors) == 0 {
+			filter.Actors = []address.Address{actor}
+		}
+	}
+
+	preds := evstate.NewStatePredicates(statePredicatesAPI{a})
+
+	hcs := a.Chain.SubHeadChanges(ctx)
+	out := make(chan []*api.StateChangeEvent, 16)
+
+	go func() {
+		defer close(out)
+
+		for changes := range hcs {
+			var res []*api.StateChangeEvent
+			for _, hc := range changes {
+				var typ string
+				switch hc.Type {
+				case store.HCApply:
+					typ = api.StateChangeApply
+				case store.HCRevert:
+					typ = api.StateChangeRevert
+				default:
+					continue // the head when subscribing
+				}
+
+				evts, err := a.matchStateChanges(ctx, preds, filter, hc.Val)
+				if err != nil {
+					reqid.Logger(ctx, log).Warnf("state change subscription (tipset %s): %s", hc.Val.Key(), err)
+					continue
+				}
+				for _, evt := range evts {
+					evt.Type = typ
+				}
+				res = append(res, evts...)
+			}
+
+			if len(res) == 0 {
+				continue
+			}
+
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+type actorStates struct {
+	addr     address.Address
+	from, to *types.Actor
+}
+
+// matchStateChanges returns the changes of the tipset matching the filter,
+// between the states computed by the parent tipset and by the tipset.
+func (a *StateAPI) matchStateChanges(ctx context.Context, preds *evstate.StatePredicates, filter api.StateChangeFilter, ts *types.TipSet) ([]*api.StateChangeEvent, error) {
+	if ts.Height() < filter.MinHeight || (filter.MaxHeight != 0 && ts.Height() > filter.MaxHeight) {
+		return nil, nil
+	}
+
+	pts, err := a.Chain.LoadTipSet(ts.Parents())
+	if err != nil {
+		return nil, xerrors.Errorf("loading parent tipset: %w", err)
+	}
+
+	cst := a.Chain.Store(ctx)
+	oldTree, err := state.LoadStateTree(cst, pts.ParentState())
+	if err != nil {
+		return nil, xerrors.Errorf("loading parent state tree: %w", err)
+	}
+	newTree, err := state.LoadStateTree(cst, ts.ParentState())
+	if err != nil {
+		return nil, xerrors.Errorf("loading state tree: %w", err)
+	}
+
+	// the messages executed by the tipset are the ones of its parent
+	msgs, err := a.Chain.MessagesForTipset(pts)
+	if err != nil {
+		return nil, xerrors.Errorf("loading messages: %w", err)
+	}
+	sent := make(map[address.Address][]cid.Cid)
+	for _, m := range msgs {
+		vmsg := m.VMMessage()
+		if len(filter.Methods) > 0 && !hasMethod(filter.Methods, vmsg.Method) {
+			continue
+		}
+		to, found, err := lookupChangedID(oldTree, newTree, vmsg.To)
+		if err != nil {
+			return nil, xerrors.Errorf("resolving recipient of %s: %w", m.Cid(), err)
+		}
+		if found {
+			sent[to] = append(sent[to], m.Cid())
+		}
+	}
+
+	var candidates []actorStates
+	if len(filter.Actors) > 0 || len(filter.Methods) > 0 {
+		addrs := filter.Actors
+		if len(addrs) == 0 {
+			for addr := range sent {
+				addrs = append(addrs, addr)
+			}
+			sort.Slice(addrs, func(i, j int) bool {
+				return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+			})
+		}
+
+		for _, addr := range addrs {
+			c, err := changedActorStates(oldTree, newTree, addr)
+			if err != nil {
+				return nil, xerrors.Errorf("loading actor %s: %w", addr, err)
+			}
+			if c != nil {
+				candidates = append(candidates, *c)
+			}
+		}
+	} else {
+		changes, err := stmgr.DiffActors(oldTree, newTree)
+		if err != nil {
+			return nil, xerrors.Errorf("diffing state trees: %w", err)
+		}
+		for _, c := range changes {
+			candidates = append(candidates, actorStates{addr: c.Address, from: c.From, to: c.To})
+		}
+	}
+
+	var out []*api.StateChangeEvent
+	for _, c := range candidates {
+		if len(filter.Methods) > 0 && len(sent[c.addr]) == 0 {
+			continue
+		}
+
+		evt := &api.StateChangeEvent{
+			TipSet:   ts.Key(),
+			Height:   ts.Height(),
+			Actor:    c.addr,
+			From:     c.from,
+			To:       c.to,
+			Messages: sent[c.addr],
+		}
+
+		if filter.Predicate != "" {
+			// predicates compare two states of the actor
+			if c.from == nil || c.to == nil {
+				continue
+			}
+
+			pred, err := preds.Named(filter.Predicate, c.addr)
+			if err != nil {
+				continue
+			}
+			// StateGetActor loads the states computed by the parents of the
+			// given tipsets, i.e. the ones we're comparing
+			matched, data, err := pred(ctx, pts.Key(), ts.Key())
+			if err != nil {
+				// e.g. the predicate of a miner on an actor of another type
+				reqid.Logger(ctx, log).Debugf("state change predicate %q on %s: %s", filter.Predicate, c.addr, err)
+				continue
+			}
+			if !matched {
+				continue
+			}
+			evt.Data = data
+		}
+
+		out = append(out, evt)
+	}
+
+	return out, nil
+}
+
+func hasMethod(methods []abi.MethodNum, m abi.MethodNum) bool {
+	for _, method := range methods {
+		if method == m {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupChangedID resolves the ID address of an actor in either state tree,
+// the actor may have been created or deleted between them.
+func lookupChangedID(oldTree, newTree *state.StateTree, addr address.Address) (address.Address, bool, error) {
+	for _, st := range []*state.StateTree{newTree, oldTree} {
+		id, err := st.LookupID(addr)
+		if err == nil {
+			return id, true, nil
+		}
+		if !xerrors.Is(err, types.ErrActorNotFound) {
+			return address.Undef, false, err
+		}
+	}
+	return address.Undef, false, nil
+}
+
+// changedActorStates returns the states of an actor in the two state trees,
+// or nil if it didn't change.
+func changedActorStates(oldTree, newTree *state.StateTree, addr address.Address) (*actorStates, error) {
+	id, found, err := lookupChangedID(oldTree, newTree, addr)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var acts [2]*types.Actor
+	for i, st := range []*state.StateTree{oldTree, newTree} {
+		act, err := st.GetActor(id)
+		if err != nil {
+			if !xerrors.Is(err, types.ErrActorNotFound) {
+				return nil, err
+			}
+			act = nil
+		}
+		acts[i] = act
+	}
+
+	from, to := acts[0], acts[1]
+	if from == nil && to == nil {
+		return nil, nil
+	}
+	if from != nil && to != nil && from.Code == to.Code && from.Head == to.Head && from.Nonce == to.Nonce && from.Balance.Equals(to.Balance) {
+		return nil, nil
+	}
+	return &actorStates{addr: id, from: from, to: to}, nil
+}
+
+// statePredicatesAPI gives the state predicates access to the chain.
+type statePredicatesAPI struct {
+	*StateAPI
+}
+
+func (s statePredicatesAPI) ChainHasObj(ctx context.Context, c cid.Cid) (bool, error) {
+	return s.Chain.Blockstore().Has(c)
+}
+
+func (s statePredicatesAPI) ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error) {
+	blk, err := s.Chain.Blockstore().Get(c)
+	if err != nil {
+		return nil, xerrors.Errorf("blockstore get: %w", err)
+	}
+	return blk.RawData(), nil
+}
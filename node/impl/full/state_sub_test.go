@@ -0,0 +1,101 @@
+package full
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/policy"
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func init() {
+	policy.SetSupportedProofTypes(abi.RegisteredSealProof_StackedDrg2KiBV1)
+	policy.SetConsensusMinerMinPower(abi.NewStoragePower(2048))
+	policy.SetMinVerifiedDealSize(abi.NewStoragePower(256))
+}
+
+func nextStateChanges(t *testing.T, sub <-chan []*api.StateChangeEvent) []*api.StateChangeEvent {
+	select {
+	case evts, ok := <-sub:
+		require.True(t, ok, "subscription closed")
+		return evts
+	case <-time.After(5 * time.Second):
+		t.Fatal("no state changes")
+		return nil
+	}
+}
+
+func TestStateSubscribeChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+	cs := cg.ChainStore()
+	a := &StateAPI{Chain: cs, StateManager: cg.StateManager()}
+
+	var tss []*types.TipSet
+	for i := 0; i < 3; i++ {
+		mts, err := cg.NextTipSet()
+		require.NoError(t, err)
+		tss = append(tss, mts.TipSet.TipSet())
+	}
+
+	sub, err := a.StateSubscribeChanges(ctx, api.StateChangeFilter{Actors: []address.Address{cg.Banker()}})
+	require.NoError(t, err)
+
+	// the messages the banker sends in a tipset change its state in the next
+	require.NoError(t, cs.SetHead(tss[2]))
+	evts := nextStateChanges(t, sub)
+	require.Len(t, evts, 2)
+	for i, evt := range evts {
+		ts := tss[i+1]
+		require.Equal(t, api.StateChangeApply, evt.Type)
+		require.Equal(t, ts.Key(), evt.TipSet)
+		require.Equal(t, ts.Height(), evt.Height)
+		require.Equal(t, address.ID, evt.Actor.Protocol())
+		require.NotNil(t, evt.From)
+		require.NotNil(t, evt.To)
+		require.Greater(t, evt.To.Nonce, evt.From.Nonce)
+		// only the messages to an actor are listed, and the banker only sends
+		require.Empty(t, evt.Messages)
+	}
+	banker := evts[0].Actor
+
+	// a fork on top of the first tipset reverts the changes of the others, and
+	// applies the messages of the first tipset again
+	fork, err := cg.NextTipSetFromMinersWithMessages(tss[0], cg.Miners, make([][]*types.SignedMessage, len(cg.Miners)))
+	require.NoError(t, err)
+	require.NoError(t, cs.SetHead(fork.TipSet()))
+	evts = nextStateChanges(t, sub)
+	require.Len(t, evts, 3)
+	for i, ts := range []*types.TipSet{tss[2], tss[1]} {
+		require.Equal(t, api.StateChangeRevert, evts[i].Type)
+		require.Equal(t, ts.Key(), evts[i].TipSet)
+		require.Equal(t, banker, evts[i].Actor)
+	}
+	require.Equal(t, api.StateChangeApply, evts[2].Type)
+	require.Equal(t, fork.TipSet().Key(), evts[2].TipSet)
+	require.Equal(t, evts[1].To, evts[2].To)
+
+	// the subscription is closed once cancelled
+	cancel()
+	for {
+		select {
+		case _, ok := <-sub:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("cancelled subscription wasn't closed")
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package full
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-path/resolver"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+func (a *StateAPI) StateWatchPaths(ctx context.Context, paths []api.StateWatchPath) (<-chan []*api.StateWatchChange, error) {
+	if len(paths) == 0 {
+		return nil, xerrors.Errorf("no paths to watch")
+	}
+	for i, p := range paths {
+		switch p.Diff {
+		case "", "@H", "@Ha", "@Hi", "@Hu", "@A":
+		default:
+			return nil, xerrors.Errorf("watch %d: unknown diff type %q", i, p.Diff)
+		}
+	}
+
+	hcs := a.Chain.SubHeadChanges(ctx)
+	out := make(chan []*api.StateWatchChange, 16)
+
+	go func() {
+		defer close(out)
+
+		var last *types.TipSet
+		for changes := range hcs {
+			to, err := a.headAfter(changes)
+			if err != nil {
+				reqid.Logger(ctx, log).Errorf("state watch: %s", err)
+				continue
+			}
+
+			if last == nil {
+				last = to
+				continue
+			}
+
+			var res []*api.StateWatchChange
+			for i, p := range paths {
+				c, err := a.diffWatchPath(ctx, p, last, to)
+				if err != nil {
+					reqid.Logger(ctx, log).Warnf("state watch %d (%s %q): %s", i, p.Actor, p.Path, err)
+					continue
+				}
+				if c != nil {
+					c.Watch = i
+					res = append(res, c)
+				}
+			}
+			last = to
+
+			if len(res) == 0 {
+				continue
+			}
+
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// headAfter returns the chain head after the given batch of head changes.
+func (a *StateAPI) headAfter(changes []*api.HeadChange) (*types.TipSet, error) {
+	if len(changes) == 0 {
+		return nil, xerrors.Errorf("empty head change notification")
+	}
+
+	hc := changes[len(changes)-1]
+	if hc.Type != store.HCRevert {
+		return hc.Val, nil
+	}
+
+	return a.Chain.LoadTipSet(hc.Val.Parents())
+}
+
+func (a *StateAPI) diffWatchPath(ctx context.Context, p api.StateWatchPath, from, to *types.TipSet) (*api.StateWatchChange, error) {
+	oldHead, err := a.watchedActorHead(ctx, p.Actor, from)
+	if err != nil {
+		return nil, err
+	}
+	newHead, err := a.watchedActorHead(ctx, p.Actor, to)
+	if err != nil {
+		return nil, err
+	}
+	if oldHead == newHead {
+		return nil, nil
+	}
+
+	oldNd, err := a.resolveWatchPath(ctx, oldHead, p.Path)
+	if err != nil {
+		return nil, xerrors.Errorf("resolving old value: %w", err)
+	}
+	newNd, err := a.resolveWatchPath(ctx, newHead, p.Path)
+	if err != nil {
+		return nil, xerrors.Errorf("resolving new value: %w", err)
+	}
+	if watchCid(oldNd) == watchCid(newNd) {
+		return nil, nil
+	}
+
+	c := &api.StateWatchChange{
+		TipSet: to.Key(),
+		Height: to.Height(),
+	}
+
+	if p.Diff == "" {
+		if oldNd != nil {
+			c.Old = &api.IpldObject{Cid: oldNd.Cid(), Obj: oldNd}
+		}
+		if newNd != nil {
+			c.New = &api.IpldObject{Cid: newNd.Cid(), Obj: newNd}
+		}
+		return c, nil
+	}
+
+	c.Entries, err = a.diffWatchedCollection(ctx, p.Diff,
+		watchCid(oldNd), a.StateManager.GetNtwkVersion(ctx, from.Height()),
+		watchCid(newNd), a.StateManager.GetNtwkVersion(ctx, to.Height()))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Entries) == 0 {
+		return nil, nil
+	}
+
+	return c, nil
+}
+
+// watchedActorHead returns the head of the actor's state, or cid.Undef if the
+// actor doesn't exist.
+func (a *StateAPI) watchedActorHead(ctx context.Context, addr address.Address, ts *types.TipSet) (cid.Cid, error) {
+	act, err := a.StateManager.LoadActor(ctx, addr, ts)
+	if xerrors.Is(err, types.ErrActorNotFound) {
+		return cid.Undef, nil
+	}
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("loading actor at %s: %w", ts.Key(), err)
+	}
+	return act.Head, nil
+}
+
+// resolveWatchPath resolves the path relative to the given actor state,
+// returning nil if the actor or path doesn't exist.
+func (a *StateAPI) resolveWatchPath(ctx context.Context, head cid.Cid, p string) (ipld.Node, error) {
+	if !head.Defined() {
+		return nil, nil
+	}
+
+	ip := "/ipfs/" + head.String()
+	if p = strings.Trim(p, "/"); p != "" {
+		ip += "/" + p
+	}
+
+	nd, err := resolveNode(ctx, a.Chain.Blockstore(), ip)
+	if xerrors.Is(err, errNotFound) || xerrors.As(err, new(resolver.ErrNoLink)) {
+		return nil, nil
+	}
+	return nd, err
+}
+
+func watchCid(nd ipld.Node) cid.Cid {
+	if nd == nil {
+		return cid.Undef
+	}
+	return nd.Cid()
+}
+
+func (a *StateAPI) diffWatchedCollection(ctx context.Context, kind string, oldRoot cid.Cid, oldNv network.Version, newRoot cid.Cid, newNv network.Version) ([]api.StateWatchEntry, error) {
+	adtStore := adt.WrapStore(ctx, cbor.NewCborStore(a.Chain.Blockstore()))
+	d := &watchDiff{kind: kind}
+
+	if kind == "@A" {
+		load := func(root cid.Cid, nv network.Version) (adt.Array, error) {
+			if !root.Defined() {
+				return adt.NewArray(adtStore, actors.VersionForNetwork(nv))
+			}
+			return adt.AsArray(adtStore, root, nv)
+		}
+
+		pre, err := load(oldRoot, oldNv)
+		if err != nil {
+			return nil, xerrors.Errorf("loading old amt: %w", err)
+		}
+		cur, err := load(newRoot, newNv)
+		if err != nil {
+			return nil, xerrors.Errorf("loading new amt: %w", err)
+		}
+		if err := adt.DiffAdtArray(pre, cur, &watchArrayDiff{d}); err != nil {
+			return nil, xerrors.Errorf("diffing amt: %w", err)
+		}
+		return d.entries, nil
+	}
+
+	load := func(root cid.Cid, nv network.Version) (adt.Map, error) {
+		if !root.Defined() {
+			return adt.NewMap(adtStore, actors.VersionForNetwork(nv))
+		}
+		return adt.AsMap(adtStore, root, actors.VersionForNetwork(nv))
+	}
+
+	pre, err := load(oldRoot, oldNv)
+	if err != nil {
+		return nil, xerrors.Errorf("loading old hamt: %w", err)
+	}
+	cur, err := load(newRoot, newNv)
+	if err != nil {
+		return nil, xerrors.Errorf("loading new hamt: %w", err)
+	}
+	if err := adt.DiffAdtMap(pre, cur, &watchMapDiff{d}); err != nil {
+		return nil, xerrors.Errorf("diffing hamt: %w", err)
+	}
+	return d.entries, nil
+}
+
+// watchDiff collects decoded HAMT/AMT entry changes for state watches. Keys
+// are rendered according to the path syntax used by ChainGetNode.
+type watchDiff struct {
+	kind    string
+	entries []api.StateWatchEntry
+}
+
+func (d *watchDiff) add(key string, from, to *cbg.Deferred) error {
+	e := api.StateWatchEntry{Key: key}
+	if from != nil {
+		if err := cbor.DecodeInto(from.Raw, &e.Old); err != nil {
+			return xerrors.Errorf("decoding old value of %s: %w", key, err)
+		}
+	}
+	if to != nil {
+		if err := cbor.DecodeInto(to.Raw, &e.New); err != nil {
+			return xerrors.Errorf("decoding new value of %s: %w", key, err)
+		}
+	}
+	d.entries = append(d.entries, e)
+	return nil
+}
+
+func (d *watchDiff) mapKey(k string) (string, error) {
+	switch d.kind {
+	case "@Ha":
+		addr, err := address.NewFromBytes([]byte(k))
+		if err != nil {
+			return "", err
+		}
+		return addr.String(), nil
+	case "@Hi":
+		i, err := abi.ParseIntKey(k)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(i, 10), nil
+	case "@Hu":
+		u, err := abi.ParseUIntKey(k)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(u, 10), nil
+	default:
+		return k, nil
+	}
+}
+
+type watchMapDiff struct{ *watchDiff }
+
+func (d *watchMapDiff) AsKey(key string) (abi.Keyer, error) {
+	return stringKey(key), nil
+}
+
+func (d *watchMapDiff) Add(key string, val *cbg.Deferred) error {
+	return d.change(key, nil, val)
+}
+
+func (d *watchMapDiff) Modify(key string, from, to *cbg.Deferred) error {
+	return d.change(key, from, to)
+}
+
+func (d *watchMapDiff) Remove(key string, val *cbg.Deferred) error {
+	return d.change(key, val, nil)
+}
+
+func (d *watchMapDiff) change(key string, from, to *cbg.Deferred) error {
+	k, err := d.mapKey(key)
+	if err != nil {
+		return xerrors.Errorf("parsing %s key: %w", d.kind, err)
+	}
+	return d.add(k, from, to)
+}
+
+type watchArrayDiff struct{ *watchDiff }
+
+func (d *watchArrayDiff) Add(key uint64, val *cbg.Deferred) error {
+	return d.add(strconv.FormatUint(key, 10), nil, val)
+}
+
+func (d *watchArrayDiff) Modify(key uint64, from, to *cbg.Deferred) error {
+	return d.add(strconv.FormatUint(key, 10), from, to)
+}
+
+func (d *watchArrayDiff) Remove(key uint64, val *cbg.Deferred) error {
+	return d.add(strconv.FormatUint(key, 10), val, nil)
+}
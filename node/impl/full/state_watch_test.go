@@ -0,0 +1,77 @@
+package full
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func idAddr(t *testing.T, id uint64) string {
+	addr, err := address.NewIDAddress(id)
+	require.NoError(t, err)
+	return addr.String()
+}
+
+func TestStateWatchHamtDiff(t *testing.T) {
+	ctx := context.Background()
+	cs := store.NewChainStore(blockstore.NewTemporary(), datastore.NewMapDatastore(), nil, nil)
+	a := &StateAPI{Chain: cs}
+
+	adtStore := adt.WrapStore(ctx, cbor.NewCborStore(cs.Blockstore()))
+	mkMap := func(vals map[uint64]int64) cid.Cid {
+		m, err := adt.NewMap(adtStore, actors.Version0)
+		require.NoError(t, err)
+		for id, v := range vals {
+			addr, err := address.NewIDAddress(id)
+			require.NoError(t, err)
+			v := cbg.CborInt(v)
+			require.NoError(t, m.Put(abi.AddrKey(addr), &v))
+		}
+		c, err := m.Root()
+		require.NoError(t, err)
+		return c
+	}
+
+	oldRoot := mkMap(map[uint64]int64{1000: 1, 1001: 2, 1003: 5})
+	newRoot := mkMap(map[uint64]int64{1000: 1, 1001: 3, 1002: 4})
+
+	entries, err := a.diffWatchedCollection(ctx, "@Ha", oldRoot, network.Version0, newRoot, network.Version0)
+	require.NoError(t, err)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	require.Len(t, entries, 3)
+
+	require.Equal(t, idAddr(t, 1001), entries[0].Key)
+	require.NotNil(t, entries[0].Old)
+	require.NotNil(t, entries[0].New)
+
+	require.Equal(t, idAddr(t, 1002), entries[1].Key)
+	require.Nil(t, entries[1].Old)
+	require.NotNil(t, entries[1].New)
+
+	require.Equal(t, idAddr(t, 1003), entries[2].Key)
+	require.NotNil(t, entries[2].Old)
+	require.Nil(t, entries[2].New)
+
+	// a missing collection is treated as empty
+	entries, err = a.diffWatchedCollection(ctx, "@Ha", cid.Undef, network.Version0, newRoot, network.Version0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+}
@@ -3,8 +3,10 @@ package full
 import (
 	"context"
 	"sync/atomic"
+	"time"
 
 	cid "github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"go.uber.org/fx"
 	"golang.org/x/xerrors"
@@ -15,6 +17,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/vm"
+	"github.com/filecoin-project/lotus/lib/reqid"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
@@ -32,11 +35,14 @@ func (a *SyncAPI) SyncState(ctx context.Context) (*api.SyncState, error) {
 
 	out := &api.SyncState{
 		VMApplied: atomic.LoadUint64(&vm.StatApplied),
+		Trusted:   a.Syncer.TrustedCheckpoint(),
 	}
 
+	head := a.Syncer.ChainStore().GetHeaviestTipSet()
+
 	for i := range states {
 		ss := &states[i]
-		out.ActiveSyncs = append(out.ActiveSyncs, api.ActiveSync{
+		as := api.ActiveSync{
 			Base:    ss.Base,
 			Target:  ss.Target,
 			Stage:   ss.Stage,
@@ -44,7 +50,37 @@ func (a *SyncAPI) SyncState(ctx context.Context) (*api.SyncState, error) {
 			Start:   ss.Start,
 			End:     ss.End,
 			Message: ss.Message,
-		})
+
+			HeadersFetched:   ss.HeadersFetched,
+			MessagesFetched:  ss.MessagesFetched,
+			TipSetsValidated: ss.TipSetsValidated,
+			Stages:           ss.Stages,
+			WeightDiff:       types.NewInt(0),
+		}
+
+		var msgTime time.Duration
+		for _, st := range ss.Stages {
+			switch st.Stage {
+			case api.StageMessages, api.StageFetchingMessages:
+				msgTime += st.Elapsed
+			}
+		}
+		if msgTime > 0 && ss.TipSetsValidated > 0 {
+			as.ValidationRate = float64(ss.TipSetsValidated) / msgTime.Seconds()
+		}
+
+		if ss.Target != nil {
+			if as.ValidationRate > 0 && ss.End.IsZero() && ss.Target.Height() > ss.Height {
+				// null rounds make this an overestimate
+				left := float64(ss.Target.Height() - ss.Height)
+				as.ETA = time.Duration(left / as.ValidationRate * float64(time.Second))
+			}
+			if head != nil {
+				as.WeightDiff = types.BigSub(ss.Target.ParentWeight(), head.ParentWeight())
+			}
+		}
+
+		out.ActiveSyncs = append(out.ActiveSyncs, as)
 	}
 	return out, nil
 }
@@ -56,7 +92,7 @@ func (a *SyncAPI) SyncSubmitBlock(ctx context.Context, blk *types.BlockMsg) erro
 	}
 
 	if err := a.SlashFilter.MinedBlock(blk.Header, parent.Height); err != nil {
-		log.Errorf("<!!> SLASH FILTER ERROR: %s", err)
+		reqid.Logger(ctx, log).Errorf("<!!> SLASH FILTER ERROR: %s", err)
 		return xerrors.Errorf("<!!> SLASH FILTER ERROR: %w", err)
 	}
 
@@ -102,24 +138,24 @@ func (a *SyncAPI) SyncIncomingBlocks(ctx context.Context) (<-chan *types.BlockHe
 }
 
 func (a *SyncAPI) SyncCheckpoint(ctx context.Context, tsk types.TipSetKey) error {
-	log.Warnf("Marking tipset %s as checkpoint", tsk)
+	reqid.Logger(ctx, log).Warnf("Marking tipset %s as checkpoint", tsk)
 	return a.Syncer.SetCheckpoint(tsk)
 }
 
 func (a *SyncAPI) SyncMarkBad(ctx context.Context, bcid cid.Cid) error {
-	log.Warnf("Marking block %s as bad", bcid)
+	reqid.Logger(ctx, log).Warnf("Marking block %s as bad", bcid)
 	a.Syncer.MarkBad(bcid)
 	return nil
 }
 
 func (a *SyncAPI) SyncUnmarkBad(ctx context.Context, bcid cid.Cid) error {
-	log.Warnf("Unmarking block %s as bad", bcid)
+	reqid.Logger(ctx, log).Warnf("Unmarking block %s as bad", bcid)
 	a.Syncer.UnmarkBad(bcid)
 	return nil
 }
 
 func (a *SyncAPI) SyncUnmarkAllBad(ctx context.Context) error {
-	log.Warnf("Dropping bad block cache")
+	reqid.Logger(ctx, log).Warnf("Dropping bad block cache")
 	a.Syncer.UnmarkAllBad()
 	return nil
 }
@@ -133,6 +169,28 @@ func (a *SyncAPI) SyncCheckBad(ctx context.Context, bcid cid.Cid) (string, error
 	return reason, nil
 }
 
+func (a *SyncAPI) SyncListBad(ctx context.Context) ([]api.BadBlock, error) {
+	return a.Syncer.ListBad(), nil
+}
+
+func (a *SyncAPI) SyncBlockPeer(ctx context.Context, p peer.ID, reason string) error {
+	reqid.Logger(ctx, log).Warnw("Blocking sync peer", "peer", p, "reason", reason)
+	a.Syncer.BlockPeer(p, reason)
+	return nil
+}
+
+func (a *SyncAPI) SyncUnblockPeer(ctx context.Context, p peer.ID) error {
+	if !a.Syncer.UnblockPeer(p) {
+		return xerrors.Errorf("peer %s isn't blocked", p)
+	}
+	reqid.Logger(ctx, log).Warnw("Unblocked sync peer", "peer", p)
+	return nil
+}
+
+func (a *SyncAPI) SyncBlockedPeers(ctx context.Context) ([]api.BlockedPeer, error) {
+	return a.Syncer.BlockedPeers(), nil
+}
+
 func (a *SyncAPI) SyncValidateTipset(ctx context.Context, tsk types.TipSetKey) (bool, error) {
 	ts, err := a.Syncer.ChainStore().LoadTipSet(tsk)
 	if err != nil {
@@ -151,3 +209,7 @@ func (a *SyncAPI) SyncValidateTipset(ctx context.Context, tsk types.TipSetKey) (
 
 	return true, nil
 }
+
+func (a *SyncAPI) SyncValidateBlockHeader(ctx context.Context, h *types.BlockHeader) (*api.BlockHeaderValidation, error) {
+	return a.Syncer.ValidateBlockHeader(ctx, h)
+}
@@ -2,6 +2,7 @@ package full
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/fx"
 	"golang.org/x/xerrors"
@@ -22,6 +23,8 @@ type WalletAPI struct {
 
 	StateManagerAPI stmgr.StateManagerAPI
 	Default         wallet.Default
+	Local           *wallet.LocalWallet `optional:"true"`
+	Wallets         wallet.MultiWallet
 	api.WalletAPI
 }
 
@@ -35,6 +38,14 @@ func (a *WalletAPI) WalletBalance(ctx context.Context, addr address.Address) (ty
 	return act.Balance, nil
 }
 
+func (a *WalletAPI) WalletBackend(ctx context.Context, k address.Address) (string, error) {
+	keyAddr, err := a.StateManagerAPI.ResolveToKeyAddress(ctx, k, nil)
+	if err != nil {
+		return "", xerrors.Errorf("failed to resolve ID address: %w", err)
+	}
+	return a.Wallets.Backend(ctx, keyAddr)
+}
+
 func (a *WalletAPI) WalletSign(ctx context.Context, k address.Address, msg []byte) (*crypto.Signature, error) {
 	keyAddr, err := a.StateManagerAPI.ResolveToKeyAddress(ctx, k, nil)
 	if err != nil {
@@ -70,6 +81,19 @@ func (a *WalletAPI) WalletSignMessage(ctx context.Context, k address.Address, ms
 	}, nil
 }
 
+func (a *WalletAPI) WalletSignAggregate(ctx context.Context, msgs []*types.Message) (*crypto.Signature, error) {
+	signers := make([]address.Address, len(msgs))
+	for i, msg := range msgs {
+		keyAddr, err := a.StateManagerAPI.ResolveToKeyAddress(ctx, msg.From, nil)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to resolve ID address %s: %w", msg.From, err)
+		}
+		signers[i] = keyAddr
+	}
+
+	return wallet.SignAggregate(ctx, a.WalletAPI, signers, msgs)
+}
+
 func (a *WalletAPI) WalletVerify(ctx context.Context, k address.Address, msg []byte, sig *crypto.Signature) (bool, error) {
 	return sigs.Verify(sig, k, msg) == nil, nil
 }
@@ -85,3 +109,66 @@ func (a *WalletAPI) WalletSetDefault(ctx context.Context, addr address.Address)
 func (a *WalletAPI) WalletValidateAddress(ctx context.Context, str string) (address.Address, error) {
 	return address.NewFromString(str)
 }
+
+func (a *WalletAPI) local() (*wallet.LocalWallet, error) {
+	if a.Local == nil {
+		return nil, xerrors.Errorf("not supported; local wallet disabled")
+	}
+	return a.Local, nil
+}
+
+func (a *WalletAPI) WalletEncrypt(ctx context.Context, passphrase string) error {
+	w, err := a.local()
+	if err != nil {
+		return err
+	}
+	return w.Encrypt(passphrase)
+}
+
+func (a *WalletAPI) WalletUnlock(ctx context.Context, passphrase string, timeout time.Duration) error {
+	w, err := a.local()
+	if err != nil {
+		return err
+	}
+	return w.Unlock(passphrase, timeout)
+}
+
+func (a *WalletAPI) WalletLock(ctx context.Context) error {
+	w, err := a.local()
+	if err != nil {
+		return err
+	}
+	return w.Lock()
+}
+
+func (a *WalletAPI) WalletNewHD(ctx context.Context, typ types.KeyType) (address.Address, error) {
+	w, err := a.local()
+	if err != nil {
+		return address.Undef, err
+	}
+	return w.WalletNewHD(typ)
+}
+
+func (a *WalletAPI) WalletDeriveHD(ctx context.Context, typ types.KeyType, index uint64) (address.Address, error) {
+	w, err := a.local()
+	if err != nil {
+		return address.Undef, err
+	}
+	return w.WalletDeriveHD(typ, index)
+}
+
+func (a *WalletAPI) WalletImportMnemonic(ctx context.Context, mnemonic string) error {
+	w, err := a.local()
+	if err != nil {
+		return err
+	}
+	return w.ImportMnemonic(mnemonic)
+}
+
+func (a *WalletAPI) WalletExportMnemonic(ctx context.Context) (*api.HDExport, error) {
+	w, err := a.local()
+	if err != nil {
+		return nil, err
+	}
+	return w.ExportMnemonic()
+}
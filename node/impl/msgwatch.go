@@ -0,0 +1,258 @@
+package impl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"go.uber.org/fx"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/events"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/helpers"
+)
+
+// MsgWatchEvents are the chain events shared by the message watches of a
+// node. They are started by the first watch, and stopped with the node.
+type MsgWatchEvents struct {
+	ctx context.Context
+
+	once sync.Once
+	ev   *events.Events
+}
+
+func NewMsgWatchEvents(mctx helpers.MetricsCtx, lc fx.Lifecycle) *MsgWatchEvents {
+	return &MsgWatchEvents{ctx: helpers.LifecycleCtx(mctx, lc)}
+}
+
+func (mwe *MsgWatchEvents) events(n *FullNodeAPI) *events.Events {
+	mwe.once.Do(func() {
+		mwe.ev = events.NewEvents(mwe.ctx, n)
+	})
+	return mwe.ev
+}
+
+func (n *FullNodeAPI) StateWatchMsgs(ctx context.Context, w api.MsgWatch) (<-chan *api.MsgWatchEvent, error) {
+	return watchMsgs(ctx, n, n.MsgWatchEvents.events(n), w)
+}
+
+// msgWatchAPI is the part of the full node API the message watches use.
+type msgWatchAPI interface {
+	ChainGetMessage(ctx context.Context, msg cid.Cid) (*types.Message, error)
+	StateSearchMsgBounded(ctx context.Context, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
+	StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
+}
+
+// watchMsgs registers the watch w with the shared events ev. The events can't
+// forget their handlers, so the handlers of w ignore the chain once ctx is
+// done.
+func watchMsgs(ctx context.Context, a msgWatchAPI, ev *events.Events, w api.MsgWatch) (<-chan *api.MsgWatchEvent, error) {
+	if !w.Message.Defined() && w.To == address.Undef {
+		return nil, xerrors.Errorf("either a message or a recipient must be watched")
+	}
+
+	timeout := abi.ChainEpoch(events.NoTimeout)
+	if w.Timeout != 0 {
+		timeout = w.Timeout
+	}
+
+	var match events.MsgMatchFunc
+	var lookup *api.MsgLookup
+	if w.Message.Defined() {
+		msg, err := a.ChainGetMessage(ctx, w.Message)
+		if err != nil {
+			return nil, xerrors.Errorf("loading message: %w", err)
+		}
+
+		lookup, err = a.StateSearchMsgBounded(ctx, w.Message, api.LookbackNoLimit, false)
+		if err != nil {
+			return nil, xerrors.Errorf("searching message: %w", err)
+		}
+
+		match = func(m *types.Message) (bool, bool, error) {
+			if ctx.Err() != nil {
+				return false, false, nil
+			}
+			// not matching once, for the message still to be matched by the
+			// other watches sharing the events
+			return false, msg.Equals(m), nil
+		}
+	} else {
+		toID, err := a.StateLookupID(ctx, w.To, types.EmptyTSK)
+		if err != nil {
+			// the actor may not exist yet
+			toID = w.To
+		}
+
+		match = func(m *types.Message) (bool, bool, error) {
+			if ctx.Err() != nil || (m.To != w.To && m.To != toID) {
+				return false, false, nil
+			}
+			return false, w.Method == nil || m.Method == *w.Method, nil
+		}
+	}
+
+	mw := &msgWatcher{
+		ctx:       ctx,
+		out:       make(chan *api.MsgWatchEvent, 16),
+		notify:    make(chan struct{}, 1),
+		confirmed: map[abi.ChainEpoch][]*api.MsgWatchEvent{},
+	}
+	go mw.run()
+
+	if lookup != nil {
+		// the message was executed before watching; it isn't matched again
+		// unless re-included after a revert
+		timeout = events.NoTimeout
+
+		err := ev.ChainAt(func(ctx context.Context, ts *types.TipSet, curH abi.ChainEpoch) error {
+			rec := lookup.Receipt
+			mw.confirm(&api.MsgWatchEvent{
+				Type:    api.MsgConfirmed,
+				Message: w.Message,
+				Receipt: &rec,
+				TipSet:  lookup.TipSet,
+				Height:  lookup.Height,
+				Head:    curH,
+			})
+			return nil
+		}, mw.revert, int(w.Confidence), lookup.Height)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := ev.Called(func(ts *types.TipSet) (done bool, more bool, err error) {
+		return lookup != nil, true, nil
+	}, func(msg *types.Message, rec *types.MessageReceipt, ts *types.TipSet, curH abi.ChainEpoch) (more bool, err error) {
+		if ctx.Err() != nil {
+			return false, nil
+		}
+
+		if msg == nil {
+			mw.send(&api.MsgWatchEvent{
+				Type:   api.MsgTimeout,
+				TipSet: ts.Key(),
+				Height: ts.Height(),
+				Head:   curH,
+			})
+			return false, nil
+		}
+
+		mc := msg.Cid()
+		if w.Message.Defined() {
+			// the CID of the signed message for secp messages
+			mc = w.Message
+		}
+
+		mw.confirm(&api.MsgWatchEvent{
+			Type:    api.MsgConfirmed,
+			Message: mc,
+			Receipt: rec,
+			TipSet:  ts.Key(),
+			Height:  ts.Height(),
+			Head:    curH,
+		})
+		return true, nil
+	}, mw.revert, int(w.Confidence), timeout, match)
+	if err != nil {
+		return nil, err
+	}
+
+	return mw.out, nil
+}
+
+// msgWatcher relays the events of a watch to its consumer. The events are
+// queued rather than sent from the handlers, for a slow consumer not to hold
+// up the events shared with the other watches.
+type msgWatcher struct {
+	ctx context.Context
+	out chan *api.MsgWatchEvent
+
+	lk     sync.Mutex
+	queue  []*api.MsgWatchEvent
+	notify chan struct{}
+
+	// confirmed events by height of the tipset executing the messages, to
+	// report their reverts
+	confirmed map[abi.ChainEpoch][]*api.MsgWatchEvent
+}
+
+// run sends the queued events until the context of the watch is done.
+func (mw *msgWatcher) run() {
+	defer close(mw.out)
+
+	for {
+		mw.lk.Lock()
+		var next *api.MsgWatchEvent
+		if len(mw.queue) > 0 {
+			next = mw.queue[0]
+		}
+		mw.lk.Unlock()
+
+		if next == nil {
+			select {
+			case <-mw.notify:
+				continue
+			case <-mw.ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case mw.out <- next:
+			mw.lk.Lock()
+			mw.queue[0] = nil
+			mw.queue = mw.queue[1:]
+			mw.lk.Unlock()
+		case <-mw.ctx.Done():
+			return
+		}
+	}
+}
+
+func (mw *msgWatcher) send(evt *api.MsgWatchEvent) {
+	if mw.ctx.Err() != nil {
+		return
+	}
+
+	mw.lk.Lock()
+	mw.queue = append(mw.queue, evt)
+	mw.lk.Unlock()
+
+	select {
+	case mw.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (mw *msgWatcher) confirm(evt *api.MsgWatchEvent) {
+	mw.lk.Lock()
+	mw.confirmed[evt.Height] = append(mw.confirmed[evt.Height], evt)
+	mw.lk.Unlock()
+
+	mw.send(evt)
+}
+
+func (mw *msgWatcher) revert(ctx context.Context, ts *types.TipSet) error {
+	mw.lk.Lock()
+	confirmed := mw.confirmed[ts.Height()]
+	delete(mw.confirmed, ts.Height())
+	mw.lk.Unlock()
+
+	for _, c := range confirmed {
+		mw.send(&api.MsgWatchEvent{
+			Type:    api.MsgReverted,
+			Message: c.Message,
+			Receipt: c.Receipt,
+			TipSet:  ts.Key(),
+			Height:  ts.Height(),
+		})
+	}
+	return nil
+}
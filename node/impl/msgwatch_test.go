@@ -0,0 +1,239 @@
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/events"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/mock"
+)
+
+// fakeChain serves the chain events and the message watches from tipsets
+// made by the tests.
+type fakeChain struct {
+	t *testing.T
+
+	head    *types.TipSet
+	tipsets map[types.TipSetKey]*types.TipSet
+	blkMsgs map[cid.Cid][]*types.Message
+	msgs    map[cid.Cid]*types.Message
+
+	notifs chan []*api.HeadChange
+	done   chan struct{}
+}
+
+func newFakeChain(t *testing.T) *fakeChain {
+	fc := &fakeChain{
+		t:       t,
+		tipsets: map[types.TipSetKey]*types.TipSet{},
+		blkMsgs: map[cid.Cid][]*types.Message{},
+		msgs:    map[cid.Cid]*types.Message{},
+		notifs:  make(chan []*api.HeadChange, 1),
+		done:    make(chan struct{}),
+	}
+	fc.head = fc.mkTipSet(nil, 0)
+	return fc
+}
+
+// mkTipSet makes a tipset on top of parent including msgs, distinguished
+// from the other tipsets at its height by nonce.
+func (fc *fakeChain) mkTipSet(parent *types.TipSet, nonce uint64, msgs ...*types.Message) *types.TipSet {
+	ts := mock.TipSet(mock.MkBlock(parent, 1, nonce))
+	fc.tipsets[ts.Key()] = ts
+	fc.blkMsgs[ts.Cids()[0]] = msgs
+	for _, m := range msgs {
+		fc.msgs[m.Cid()] = m
+	}
+	return ts
+}
+
+// change notifies the events of a head change, and waits for them to process
+// it.
+func (fc *fakeChain) change(rev, app []*types.TipSet) {
+	var hcs []*api.HeadChange
+	for _, ts := range rev {
+		hcs = append(hcs, &api.HeadChange{Type: store.HCRevert, Val: ts})
+	}
+	for _, ts := range app {
+		hcs = append(hcs, &api.HeadChange{Type: store.HCApply, Val: ts})
+	}
+	fc.head = app[len(app)-1]
+
+	fc.notifs <- hcs
+	select {
+	case <-fc.done:
+	case <-time.After(5 * time.Second):
+		fc.t.Fatal("head change wasn't processed")
+	}
+}
+
+// apply applies tipsets on top of each other, from the head.
+func (fc *fakeChain) apply(n int, msgs ...*types.Message) []*types.TipSet {
+	var app []*types.TipSet
+	for i := 0; i < n; i++ {
+		var ts *types.TipSet
+		if i == 0 {
+			ts = fc.mkTipSet(fc.head, 0, msgs...)
+		} else {
+			ts = fc.mkTipSet(app[i-1], 0)
+		}
+		app = append(app, ts)
+	}
+	fc.change(nil, app)
+	return app
+}
+
+func (fc *fakeChain) NotifDone() {
+	fc.done <- struct{}{}
+}
+
+func (fc *fakeChain) ChainNotify(context.Context) (<-chan []*api.HeadChange, error) {
+	fc.notifs <- []*api.HeadChange{{Type: store.HCCurrent, Val: fc.head}}
+	return fc.notifs, nil
+}
+
+func (fc *fakeChain) ChainGetBlockMessages(ctx context.Context, blk cid.Cid) (*api.BlockMessages, error) {
+	return &api.BlockMessages{BlsMessages: fc.blkMsgs[blk]}, nil
+}
+
+func (fc *fakeChain) ChainGetTipSetByHeight(ctx context.Context, h abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	for ts := fc.head; ts != nil; ts = fc.tipsets[ts.Parents()] {
+		if ts.Height() == h {
+			return ts, nil
+		}
+	}
+	return nil, xerrors.Errorf("no tipset at height %d", h)
+}
+
+func (fc *fakeChain) ChainHead(context.Context) (*types.TipSet, error) {
+	return fc.head, nil
+}
+
+func (fc *fakeChain) StateGetReceipt(context.Context, cid.Cid, types.TipSetKey) (*types.MessageReceipt, error) {
+	return &types.MessageReceipt{GasUsed: 1}, nil
+}
+
+func (fc *fakeChain) ChainGetTipSet(ctx context.Context, tsk types.TipSetKey) (*types.TipSet, error) {
+	return fc.tipsets[tsk], nil
+}
+
+func (fc *fakeChain) StateGetActor(context.Context, address.Address, types.TipSetKey) (*types.Actor, error) {
+	return nil, types.ErrActorNotFound
+}
+
+func (fc *fakeChain) ChainGetMessage(ctx context.Context, msg cid.Cid) (*types.Message, error) {
+	m, ok := fc.msgs[msg]
+	if !ok {
+		return nil, xerrors.Errorf("message %s not found", msg)
+	}
+	return m, nil
+}
+
+func (fc *fakeChain) StateSearchMsgBounded(context.Context, cid.Cid, abi.ChainEpoch, bool) (*api.MsgLookup, error) {
+	return nil, nil
+}
+
+func (fc *fakeChain) StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error) {
+	return address.Undef, types.ErrActorNotFound
+}
+
+func nextEvent(t *testing.T, out <-chan *api.MsgWatchEvent) *api.MsgWatchEvent {
+	select {
+	case evt, ok := <-out:
+		require.True(t, ok, "watch closed")
+		return evt
+	case <-time.After(5 * time.Second):
+		t.Fatal("no watch event")
+		return nil
+	}
+}
+
+func noEvent(t *testing.T, out <-chan *api.MsgWatchEvent) {
+	select {
+	case evt := <-out:
+		t.Fatalf("unexpected watch event %+v", evt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchMsgs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fc := newFakeChain(t)
+	ev := events.NewEvents(ctx, fc)
+
+	msg := &types.Message{
+		To:         mock.Address(1000),
+		From:       mock.Address(1001),
+		Nonce:      1,
+		Value:      types.NewInt(0),
+		GasFeeCap:  types.NewInt(0),
+		GasPremium: types.NewInt(0),
+	}
+	fc.msgs[msg.Cid()] = msg
+
+	_, err := watchMsgs(ctx, fc, ev, api.MsgWatch{})
+	require.Error(t, err)
+
+	wctx, wcancel := context.WithCancel(ctx)
+	byMsg, err := watchMsgs(wctx, fc, ev, api.MsgWatch{Message: msg.Cid(), Confidence: 2})
+	require.NoError(t, err)
+	// the watches share the events
+	byTo, err := watchMsgs(ctx, fc, ev, api.MsgWatch{To: msg.To, Confidence: 2})
+	require.NoError(t, err)
+	timeout, err := watchMsgs(ctx, fc, ev, api.MsgWatch{To: mock.Address(1002), Confidence: 1, Timeout: 3})
+	require.NoError(t, err)
+
+	// the message is included at height 1, and executed at height 2
+	app := fc.apply(2, msg)
+	noEvent(t, byMsg)
+
+	app = append(app, fc.apply(2)...)
+	for _, out := range []<-chan *api.MsgWatchEvent{byMsg, byTo} {
+		evt := nextEvent(t, out)
+		require.Equal(t, api.MsgConfirmed, evt.Type)
+		require.Equal(t, msg.Cid(), evt.Message)
+		require.Equal(t, app[1].Key(), evt.TipSet)
+		require.Equal(t, abi.ChainEpoch(2), evt.Height)
+		require.Equal(t, abi.ChainEpoch(4), evt.Head)
+		require.NotNil(t, evt.Receipt)
+	}
+
+	evt := nextEvent(t, timeout)
+	require.Equal(t, api.MsgTimeout, evt.Type)
+	require.Equal(t, app[2].Key(), evt.TipSet)
+	require.Equal(t, abi.ChainEpoch(4), evt.Head)
+	noEvent(t, timeout)
+
+	// a cancelled watch is closed, and the others still get their events
+	wcancel()
+	select {
+	case _, ok := <-byMsg:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled watch wasn't closed")
+	}
+
+	// the tipset executing the message is reverted, by a fork from the
+	// genesis which doesn't include the message
+	fork := fc.mkTipSet(fc.tipsets[app[0].Parents()], 1)
+	fc.change([]*types.TipSet{app[3], app[2], app[1], app[0]}, []*types.TipSet{fork})
+	evt = nextEvent(t, byTo)
+	require.Equal(t, api.MsgReverted, evt.Type)
+	require.Equal(t, msg.Cid(), evt.Message)
+	require.Equal(t, app[1].Key(), evt.TipSet)
+	require.Equal(t, abi.ChainEpoch(2), evt.Height)
+	noEvent(t, byTo)
+}
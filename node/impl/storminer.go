@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"time"
 
@@ -24,13 +25,18 @@ import (
 	sectorstorage "github.com/filecoin-project/lotus/extern/sector-storage"
 	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
 	"github.com/filecoin-project/lotus/extern/sector-storage/fsutil"
+	"github.com/filecoin-project/lotus/extern/sector-storage/sealtasks"
 	"github.com/filecoin-project/lotus/extern/sector-storage/stores"
 	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
 	sealing "github.com/filecoin-project/lotus/extern/storage-sealing"
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/apistruct"
+	lminer "github.com/filecoin-project/lotus/chain/actors/builtin/miner"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/reqid"
+	"github.com/filecoin-project/lotus/markets/dealauth"
+	"github.com/filecoin-project/lotus/markets/dealsla"
 	"github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/node/impl/common"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
@@ -58,6 +64,9 @@ type StorageMinerAPI struct {
 
 	DS dtypes.MetadataDS
 
+	DealSLA  *dealsla.Tracker
+	DealAuth *dealauth.Authorizations
+
 	ConsiderOnlineStorageDealsConfigFunc       dtypes.ConsiderOnlineStorageDealsConfigFunc
 	SetConsiderOnlineStorageDealsConfigFunc    dtypes.SetConsiderOnlineStorageDealsConfigFunc
 	ConsiderOnlineRetrievalDealsConfigFunc     dtypes.ConsiderOnlineRetrievalDealsConfigFunc
@@ -72,6 +81,8 @@ type StorageMinerAPI struct {
 	GetSealingConfigFunc                       dtypes.GetSealingConfigFunc
 	GetExpectedSealDurationFunc                dtypes.GetExpectedSealDurationFunc
 	SetExpectedSealDurationFunc                dtypes.SetExpectedSealDurationFunc
+	GetConfigSectionFunc                       dtypes.GetConfigSectionFunc
+	SetConfigSectionFunc                       dtypes.SetConfigSectionFunc
 }
 
 func (sm *StorageMinerAPI) ServeRemote(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +91,12 @@ func (sm *StorageMinerAPI) ServeRemote(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: missing write permission"})
 		return
 	}
+	// the remote storage isn't an API method a token can be scoped to
+	if auth.HasPerm(r.Context(), nil, apistruct.PermScoped) {
+		w.WriteHeader(401)
+		_ = json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: scoped tokens can't access the remote storage"})
+		return
+	}
 
 	sm.StorageMgr.ServeHTTP(w, r)
 }
@@ -122,13 +139,7 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 		return api.SectorInfo{}, err
 	}
 
-	deals := make([]abi.DealID, len(info.Pieces))
-	for i, piece := range info.Pieces {
-		if piece.DealInfo == nil {
-			continue
-		}
-		deals[i] = piece.DealInfo.DealID
-	}
+	sInfo := sm.sectorInfo(info)
 
 	log := make([]api.SectorLog, len(info.Log))
 	for i, l := range info.Log {
@@ -139,9 +150,121 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 			Message:   l.Message,
 		}
 	}
+	sInfo.Log = log
+
+	if !showOnChainInfo {
+		return sInfo, nil
+	}
+
+	onChainInfo, err := sm.Full.StateSectorGetInfo(ctx, sm.Miner.Address(), sid, types.EmptyTSK)
+	if err != nil {
+		return sInfo, err
+	}
+	if onChainInfo == nil {
+		return sInfo, nil
+	}
+	setOnChainInfo(&sInfo, onChainInfo)
+
+	ex, err := sm.Full.StateSectorExpiration(ctx, sm.Miner.Address(), sid, types.EmptyTSK)
+	if err != nil {
+		return sInfo, nil
+	}
+	sInfo.OnTime = ex.OnTime
+	sInfo.Early = ex.Early
 
-	sInfo := api.SectorInfo{
-		SectorID: sid,
+	return sInfo, nil
+}
+
+func (sm *StorageMinerAPI) SectorsListWithDetails(ctx context.Context, filter api.SectorsFilter) ([]api.SectorDetails, error) {
+	sectors, err := sm.Miner.ListSectors()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := sm.Full.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	onChain, err := sm.Full.StateMinerSectors(ctx, sm.Miner.Address(), nil, head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting on-chain sectors: %w", err)
+	}
+	onChainInfos := make(map[abi.SectorNumber]*lminer.SectorOnChainInfo, len(onChain))
+	for _, info := range onChain {
+		onChainInfos[info.SectorNumber] = info
+	}
+
+	active, err := sm.Full.StateMinerActiveSectors(ctx, sm.Miner.Address(), head.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting active sectors: %w", err)
+	}
+	activeIDs := make(map[abi.SectorNumber]struct{}, len(active))
+	for _, info := range active {
+		activeIDs[info.SectorNumber] = struct{}{}
+	}
+
+	states := make(map[api.SectorState]struct{}, len(filter.States))
+	for _, st := range filter.States {
+		states[st] = struct{}{}
+	}
+	numbers := make(map[abi.SectorNumber]struct{}, len(filter.Sectors))
+	for _, sn := range filter.Sectors {
+		numbers[sn] = struct{}{}
+	}
+
+	out := make([]api.SectorDetails, 0, len(sectors))
+	for _, info := range sectors {
+		if _, ok := states[api.SectorState(info.State)]; len(states) > 0 && !ok {
+			continue
+		}
+		if _, ok := numbers[info.SectorNumber]; len(numbers) > 0 && !ok {
+			continue
+		}
+
+		d := api.SectorDetails{SectorInfo: sm.sectorInfo(info)}
+		if filter.WithDeals && !hasDeals(d.Deals) {
+			continue
+		}
+
+		if oci, ok := onChainInfos[info.SectorNumber]; ok {
+			d.OnChain = true
+			setOnChainInfo(&d.SectorInfo, oci)
+			d.OnTime = oci.Expiration
+		}
+		_, d.Active = activeIDs[info.SectorNumber]
+
+		// only sectors which aren't active can be set to expire early, so
+		// their expiration is looked up separately
+		if d.OnChain && !d.Active {
+			ex, err := sm.Full.StateSectorExpiration(ctx, sm.Miner.Address(), info.SectorNumber, head.Key())
+			if err == nil {
+				d.OnTime = ex.OnTime
+				d.Early = ex.Early
+			}
+		}
+
+		out = append(out, d)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].SectorID < out[j].SectorID
+	})
+	return out, nil
+}
+
+// sectorInfo converts the sealing state of a sector, without its log.
+func (sm *StorageMinerAPI) sectorInfo(info sealing.SectorInfo) api.SectorInfo {
+	deals := make([]abi.DealID, len(info.Pieces))
+	for i, piece := range info.Pieces {
+		if piece.DealInfo == nil {
+			continue
+		}
+		deals[i] = piece.DealInfo.DealID
+	}
+
+	return api.SectorInfo{
+		SectorID: info.SectorNumber,
 		State:    api.SectorState(info.State),
 		CommD:    info.CommD,
 		CommR:    info.CommR,
@@ -158,10 +281,12 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 		PreCommitMsg: info.PreCommitMessage,
 		CommitMsg:    info.CommitMessage,
 		Retries:      info.InvalidProofs,
-		ToUpgrade:    sm.Miner.IsMarkedForUpgrade(sid),
+		ToUpgrade:    sm.Miner.IsMarkedForUpgrade(info.SectorNumber),
+
+		TicketExpiration: info.TicketExpiration,
+		SeedExpiration:   info.SeedExpiration,
 
 		LastErr: info.LastErr,
-		Log:     log,
 		// on chain info
 		SealProof:          0,
 		Activation:         0,
@@ -172,36 +297,26 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 		OnTime:             0,
 		Early:              0,
 	}
+}
 
-	if !showOnChainInfo {
-		return sInfo, nil
-	}
-
-	onChainInfo, err := sm.Full.StateSectorGetInfo(ctx, sm.Miner.Address(), sid, types.EmptyTSK)
-	if err != nil {
-		return sInfo, err
-	}
-	if onChainInfo == nil {
-		return sInfo, nil
-	}
+func setOnChainInfo(sInfo *api.SectorInfo, onChainInfo *lminer.SectorOnChainInfo) {
 	sInfo.SealProof = onChainInfo.SealProof
 	sInfo.Activation = onChainInfo.Activation
 	sInfo.Expiration = onChainInfo.Expiration
 	sInfo.DealWeight = onChainInfo.DealWeight
 	sInfo.VerifiedDealWeight = onChainInfo.VerifiedDealWeight
 	sInfo.InitialPledge = onChainInfo.InitialPledge
+}
 
-	ex, err := sm.Full.StateSectorExpiration(ctx, sm.Miner.Address(), sid, types.EmptyTSK)
-	if err != nil {
-		return sInfo, nil
+func hasDeals(deals []abi.DealID) bool {
+	for _, deal := range deals {
+		if deal != 0 {
+			return true
+		}
 	}
-	sInfo.OnTime = ex.OnTime
-	sInfo.Early = ex.Early
-
-	return sInfo, nil
+	return false
 }
 
-// List all staged sectors
 func (sm *StorageMinerAPI) SectorsList(context.Context) ([]abi.SectorNumber, error) {
 	sectors, err := sm.Miner.ListSectors()
 	if err != nil {
@@ -288,7 +403,7 @@ func (sm *StorageMinerAPI) WorkerConnect(ctx context.Context, url string) error
 		return xerrors.Errorf("connecting remote storage failed: %w", err)
 	}
 
-	log.Infof("Connected to a remote worker at %s", url)
+	reqid.Logger(ctx, log).Infof("Connected to a remote worker at %s", url)
 
 	return sm.StorageMgr.AddWorker(ctx, w)
 }
@@ -297,6 +412,14 @@ func (sm *StorageMinerAPI) SealingSchedDiag(ctx context.Context) (interface{}, e
 	return sm.StorageMgr.SchedDiag(ctx)
 }
 
+func (sm *StorageMinerAPI) SealingSchedPause(ctx context.Context, tasks []sealtasks.TaskType) error {
+	return sm.StorageMgr.SchedPause(ctx, tasks...)
+}
+
+func (sm *StorageMinerAPI) SealingSchedResume(ctx context.Context, tasks []sealtasks.TaskType) error {
+	return sm.StorageMgr.SchedResume(ctx, tasks...)
+}
+
 func (sm *StorageMinerAPI) MarketImportDealData(ctx context.Context, propCid cid.Cid, path string) error {
 	fi, err := os.Open(path)
 	if err != nil {
@@ -443,6 +566,18 @@ func (sm *StorageMinerAPI) DealsSetConsiderOnlineRetrievalDeals(ctx context.Cont
 	return sm.SetConsiderOnlineRetrievalDealsConfigFunc(b)
 }
 
+func (sm *StorageMinerAPI) DealsSLAReports(ctx context.Context, count int) ([]api.DealSLAReport, error) {
+	return sm.DealSLA.Reports(count)
+}
+
+func (sm *StorageMinerAPI) DealsImportAuthorization(ctx context.Context, auth api.SignedDealAuthorization) error {
+	return sm.DealAuth.Import(ctx, auth)
+}
+
+func (sm *StorageMinerAPI) DealsListAuthorizations(ctx context.Context) ([]api.SignedDealAuthorization, error) {
+	return sm.DealAuth.List()
+}
+
 func (sm *StorageMinerAPI) DealsConsiderOfflineStorageDeals(ctx context.Context) (bool, error) {
 	return sm.ConsiderOfflineStorageDealsConfigFunc()
 }
@@ -519,7 +654,22 @@ func (sm *StorageMinerAPI) PiecesGetCIDInfo(ctx context.Context, payloadCid cid.
 }
 
 func (sm *StorageMinerAPI) CreateBackup(ctx context.Context, fpath string) error {
-	return backup(sm.DS, fpath)
+	return backup(ctx, sm.DS, fpath)
+}
+
+func (sm *StorageMinerAPI) ConfigGet(ctx context.Context, section string) (json.RawMessage, error) {
+	return sm.GetConfigSectionFunc(section)
+}
+
+func (sm *StorageMinerAPI) ConfigSet(ctx context.Context, section string, value json.RawMessage) (*api.ConfigSetResult, error) {
+	changed, restart, err := sm.SetConfigSectionFunc(section, value)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ConfigSetResult{
+		Changed:         changed,
+		RestartRequired: restart,
+	}, nil
 }
 
 var _ api.StorageMiner = &StorageMinerAPI{}
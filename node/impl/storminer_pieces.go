@@ -0,0 +1,101 @@
+package impl
+
+import (
+	"context"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
+	"github.com/filecoin-project/lotus/extern/sector-storage/storiface"
+	"github.com/filecoin-project/lotus/lib/reqid"
+)
+
+func (sm *StorageMinerAPI) MarketVerifyPiece(ctx context.Context, pieceCid cid.Cid) (*api.PieceVerification, error) {
+	pi, err := sm.PieceStore.GetPieceInfo(pieceCid)
+	if err != nil {
+		return nil, xerrors.Errorf("getting piece info: %w", err)
+	}
+
+	mid, err := address.IDFromAddress(sm.Miner.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	out := &api.PieceVerification{
+		PieceCID: pieceCid,
+		Valid:    len(pi.Deals) > 0,
+	}
+
+	// deals for the same piece may share a copy
+	checked := map[piecestore.DealInfo]struct{}{}
+	for _, d := range pi.Deals {
+		loc := piecestore.DealInfo{SectorID: d.SectorID, Offset: d.Offset, Length: d.Length}
+		if _, ok := checked[loc]; ok {
+			continue
+		}
+		checked[loc] = struct{}{}
+
+		cv := api.PieceCopyVerification{
+			DealID:   d.DealID,
+			SectorID: d.SectorID,
+			Offset:   d.Offset,
+			Length:   d.Length,
+		}
+
+		commP, err := sm.pieceCommP(ctx, abi.ActorID(mid), d)
+		if err != nil {
+			reqid.Logger(ctx, log).Warnw("failed to read piece copy", "piece", pieceCid, "sector", d.SectorID, "error", err)
+			cv.Error = err.Error()
+		} else {
+			cv.CommP = commP
+			cv.Match = commP.Equals(pieceCid)
+			if !cv.Match {
+				reqid.Logger(ctx, log).Errorw("stored piece doesn't match its commitment", "piece", pieceCid, "sector", d.SectorID, "commP", commP)
+			}
+		}
+
+		out.Valid = out.Valid && cv.Match
+		out.Copies = append(out.Copies, cv)
+	}
+
+	return out, nil
+}
+
+// pieceCommP reads the piece from its sector and recomputes its commitment.
+func (sm *StorageMinerAPI) pieceCommP(ctx context.Context, mid abi.ActorID, d piecestore.DealInfo) (cid.Cid, error) {
+	si, err := sm.Miner.GetSectorInfo(d.SectorID)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	var commD cid.Cid
+	if si.CommD != nil {
+		commD = *si.CommD
+	}
+
+	sid := abi.SectorID{Miner: mid, Number: d.SectorID}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r, w := io.Pipe()
+	go func() {
+		err := sm.IStorageMgr.ReadPiece(ctx, w, sid, storiface.UnpaddedByteIndex(d.Offset.Unpadded()), d.Length.Unpadded(), si.TicketValue, commD)
+		_ = w.CloseWithError(err)
+	}()
+	defer r.Close() //nolint:errcheck
+
+	commP, err := ffiwrapper.GeneratePieceCIDFromFile(si.SectorType, r, d.Length.Unpadded())
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("computing commP: %w", err)
+	}
+
+	return commP, nil
+}
@@ -12,11 +12,14 @@ import (
 	"github.com/ipfs/go-datastore"
 	"github.com/ipld/go-car"
 	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/routing"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"go.uber.org/fx"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
 	"github.com/filecoin-project/lotus/journal"
 
@@ -26,13 +29,17 @@ import (
 	"github.com/filecoin-project/lotus/chain/exchange"
 	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
 	"github.com/filecoin-project/lotus/chain/messagepool"
+	"github.com/filecoin-project/lotus/chain/statefetch"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/store/splitstore"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/vm"
+	"github.com/filecoin-project/lotus/lib/bandwidth"
 	"github.com/filecoin-project/lotus/lib/blockstore"
 	"github.com/filecoin-project/lotus/lib/bufbstore"
 	"github.com/filecoin-project/lotus/lib/timedbs"
+	"github.com/filecoin-project/lotus/node/config"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/modules/helpers"
 	"github.com/filecoin-project/lotus/node/repo"
@@ -62,12 +69,13 @@ func ChainBitswap(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, rt r
 	return exch
 }
 
-func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds dtypes.MetadataDS, nn dtypes.NetworkName, j journal.Journal) (*messagepool.MessagePool, error) {
+func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds dtypes.MetadataDS, nn dtypes.NetworkName, j journal.Journal, policy messagepool.SelectionPolicy) (*messagepool.MessagePool, error) {
 	mpp := messagepool.NewProvider(sm, ps)
 	mp, err := messagepool.New(mpp, ds, nn, j)
 	if err != nil {
 		return nil, xerrors.Errorf("constructing mpool: %w", err)
 	}
+	mp.SetSelectionPolicy(policy)
 	lc.Append(fx.Hook{
 		OnStop: func(_ context.Context) error {
 			return mp.Close()
@@ -76,19 +84,97 @@ func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds
 	return mp, nil
 }
 
-func ChainBlockstore(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo) (dtypes.ChainBlockstore, error) {
-	blocks, err := r.Datastore("/chain")
-	if err != nil {
-		return nil, err
+// StateManager constructs the state manager with caches fitting the
+// configured memory budget, and the configured execution parallelism.
+func StateManager(cfg *config.Chainstore) func(cs *store.ChainStore, us stmgr.UpgradeSchedule) (*stmgr.StateManager, error) {
+	return func(cs *store.ChainStore, us stmgr.UpgradeSchedule) (*stmgr.StateManager, error) {
+		sm, err := stmgr.NewStateManagerWithUpgradeSchedule(cs, us)
+		if err != nil {
+			return nil, err
+		}
+		if err := sm.SetCacheSize(cfg.StateCacheSize); err != nil {
+			return nil, xerrors.Errorf("setting state cache size: %w", err)
+		}
+		sm.SetExecutionParallelism(cfg.ExecutionParallelism)
+		return sm, nil
 	}
+}
 
-	bs := blockstore.NewBlockstore(blocks)
-	cbs, err := blockstore.CachedBlockstore(helpers.LifecycleCtx(mctx, lc), bs, blockstore.DefaultCacheOpts())
-	if err != nil {
-		return nil, err
+func ChainBlockstore(r repo.LockedRepo) (dtypes.ChainBlockstore, error) {
+	return ChainBlockstoreBackend(&config.Chainstore{CacheSize: blockstore.DefaultBlockCacheSize})(r)
+}
+
+// ChainBlockstoreBackend opens the chain blockstore with the configured
+// backend, behind a cache of the configured size.
+func ChainBlockstoreBackend(cfg *config.Chainstore) func(r repo.LockedRepo) (dtypes.ChainBlockstore, error) {
+	return func(r repo.LockedRepo) (dtypes.ChainBlockstore, error) {
+		bs, err := repo.OpenBlockstore(r, "/chain", cfg.Backend)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.CacheSize == 0 {
+			return bs, nil
+		}
+		return blockstore.NewBlockCache(bs, cfg.CacheSize), nil
 	}
+}
 
-	return cbs, nil
+// SplitBlockstore opens the splitstore, with the chain blockstore as its
+// coldstore unless objects evicted from the hotstore are discarded.
+func SplitBlockstore(cfg *config.Chainstore) func(r repo.LockedRepo) (*splitstore.SplitStore, error) {
+	return func(r repo.LockedRepo) (*splitstore.SplitStore, error) {
+		hot, err := r.Datastore("/splitstore")
+		if err != nil {
+			return nil, err
+		}
+
+		var cold blockstore.Blockstore
+		if cfg.Splitstore.ColdStoreType != splitstore.ColdStoreDiscard {
+			cold, err = repo.OpenBlockstore(r, "/chain", cfg.Backend)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ss, err := splitstore.NewSplitStore(hot, cold, splitstore.Config{
+			ColdStoreType:       cfg.Splitstore.ColdStoreType,
+			CompactionThreshold: abi.ChainEpoch(cfg.Splitstore.CompactionThreshold),
+			CompactionBoundary:  abi.ChainEpoch(cfg.Splitstore.CompactionBoundary),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("creating splitstore: %w", err)
+		}
+
+		return ss, nil
+	}
+}
+
+// ChainSplitBlockstore puts the splitstore behind a cache of the configured
+// size, like the chain blockstore of the nodes without a splitstore.
+func ChainSplitBlockstore(cfg *config.Chainstore) func(ss *splitstore.SplitStore) dtypes.ChainBlockstore {
+	return func(ss *splitstore.SplitStore) dtypes.ChainBlockstore {
+		if cfg.CacheSize == 0 {
+			return ss
+		}
+		return blockstore.NewBlockCache(ss, cfg.CacheSize)
+	}
+}
+
+// StartSplitstore starts tracking the chain in the splitstore once the node
+// starts.
+func StartSplitstore(lc fx.Lifecycle, ss *splitstore.SplitStore, cs *store.ChainStore) {
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			return ss.Start(cs)
+		},
+	})
+}
+
+// StartMessageIndex indexes the messages of the tipsets synced while the node
+// runs.
+func StartMessageIndex(mctx helpers.MetricsCtx, lc fx.Lifecycle, cs *store.ChainStore) {
+	cs.StartMessageIndex(helpers.LifecycleCtx(mctx, lc))
 }
 
 func ChainGCBlockstore(bs dtypes.ChainBlockstore, gcl dtypes.ChainGCLocker) dtypes.ChainGCBlockstore {
@@ -190,6 +276,9 @@ type SyncerParams struct {
 	Host         host.Host
 	Beacon       beacon.Schedule
 	Verifier     ffiwrapper.Verifier
+	Checkpoints  dtypes.SyncCheckpoints
+	Trusted      dtypes.TrustedCheckpoint
+	StateFetch   *statefetch.Fetcher
 }
 
 func NewSyncer(params SyncerParams) (*chain.Syncer, error) {
@@ -208,6 +297,15 @@ func NewSyncer(params SyncerParams) (*chain.Syncer, error) {
 		return nil, err
 	}
 
+	// set first, the static checkpoints below it aren't checked locally
+	if err := syncer.SetTrustedCheckpoint(dtypes.Checkpoint(params.Trusted)); err != nil {
+		return nil, xerrors.Errorf("setting trusted sync checkpoint: %w", err)
+	}
+	if err := syncer.SetStaticCheckpoints(params.Checkpoints); err != nil {
+		return nil, xerrors.Errorf("setting sync checkpoints: %w", err)
+	}
+	syncer.SetStateFetcher(params.StateFetch)
+
 	lc.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
 			syncer.Start()
@@ -221,6 +319,86 @@ func NewSyncer(params SyncerParams) (*chain.Syncer, error) {
 	return syncer, nil
 }
 
+func BuiltinSyncCheckpoints() dtypes.SyncCheckpoints {
+	return build.BuiltinCheckpoints
+}
+
+// ConfigSyncCheckpoints returns the checkpoints from the config, along with
+// the built-in ones unless they're disabled.
+func ConfigSyncCheckpoints(cfg config.Sync) func() dtypes.SyncCheckpoints {
+	return func() dtypes.SyncCheckpoints {
+		var out dtypes.SyncCheckpoints
+		if !cfg.IgnoreBuiltinCheckpoints {
+			out = append(out, build.BuiltinCheckpoints...)
+		}
+		for _, cp := range cfg.Checkpoints {
+			out = append(out, dtypes.Checkpoint{
+				Height: abi.ChainEpoch(cp.Height),
+				Blocks: cp.Blocks,
+			})
+		}
+		return out
+	}
+}
+
+// ConfigTrustedCheckpoint returns the checkpoint of the lightweight sync mode.
+func ConfigTrustedCheckpoint(cfg config.Sync) func() dtypes.TrustedCheckpoint {
+	return func() dtypes.TrustedCheckpoint {
+		return dtypes.TrustedCheckpoint{
+			Height: abi.ChainEpoch(cfg.TrustedCheckpoint.Height),
+			Blocks: cfg.TrustedCheckpoint.Blocks,
+		}
+	}
+}
+
+// StateFetcher sets up the fetcher of state missing from the chain blockstore.
+func StateFetcher(workers int) func(bs dtypes.ChainBlockstore, bserv dtypes.ChainBlockService, ds dtypes.MetadataDS, bw metrics.Reporter) *statefetch.Fetcher {
+	return func(bs dtypes.ChainBlockstore, bserv dtypes.ChainBlockService, ds dtypes.MetadataDS, bw metrics.Reporter) *statefetch.Fetcher {
+		return statefetch.NewFetcher(bs, bserv, ds, bw, workers)
+	}
+}
+
+// ChainExchangeBandwidth sets up the bandwidth caps of ChainExchange.
+func ChainExchangeBandwidth(cfg config.Sync) func() (exchange.BandwidthLimits, error) {
+	return func() (exchange.BandwidthLimits, error) {
+		var fetchWindows, serveWindows []bandwidth.Window
+		for i, w := range cfg.BandwidthSchedule {
+			start, err := bandwidth.ParseTimeOfDay(w.Start)
+			if err != nil {
+				return exchange.BandwidthLimits{}, xerrors.Errorf("bandwidth window %d: %w", i, err)
+			}
+			end, err := bandwidth.ParseTimeOfDay(w.End)
+			if err != nil {
+				return exchange.BandwidthLimits{}, xerrors.Errorf("bandwidth window %d: %w", i, err)
+			}
+
+			fetchWindows = append(fetchWindows, bandwidth.Window{Start: start, End: end, Limit: int64(w.FetchBandwidth)})
+			serveWindows = append(serveWindows, bandwidth.Window{Start: start, End: end, Limit: int64(w.ServeBandwidth)})
+		}
+
+		var out exchange.BandwidthLimits
+		if cfg.FetchBandwidth > 0 || len(fetchWindows) > 0 {
+			out.Client = bandwidth.NewShaper(int64(cfg.FetchBandwidth), fetchWindows)
+		}
+		if cfg.ServeBandwidth > 0 || len(serveWindows) > 0 {
+			out.Server = bandwidth.NewShaper(int64(cfg.ServeBandwidth), serveWindows)
+		}
+		return out, nil
+	}
+}
+
+// ChainExchangeServerLimits sets up the limits on serving ChainExchange
+// requests to a peer.
+func ChainExchangeServerLimits(cfg config.Sync) func() exchange.ServerLimits {
+	return func() exchange.ServerLimits {
+		return exchange.ServerLimits{
+			MaxConcurrentRequests: cfg.ServePeerConcurrentRequests,
+			PeerBandwidth:         int64(cfg.ServePeerBandwidth),
+			MaxResponseBytes:      int64(cfg.ServeMaxResponseBytes),
+		}
+	}
+}
+
 func NewSlashFilter(ds dtypes.MetadataDS) *slashfilter.SlashFilter {
 	return slashfilter.New(ds)
 }
@@ -0,0 +1,39 @@
+package modules
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/filecoin-project/lotus/lib/clockcheck"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+// ClockMonitor checks the drift of the system clock against the configured
+// NTP servers while the node runs. It returns nil, disabling the checks, if
+// no servers are configured.
+func ClockMonitor(cfg config.Clock) func(lc fx.Lifecycle) *clockcheck.Monitor {
+	return func(lc fx.Lifecycle) *clockcheck.Monitor {
+		if len(cfg.NTPServers) == 0 {
+			log.Warn("no NTP servers configured, not checking the system clock")
+			return nil
+		}
+
+		m := clockcheck.NewMonitor(cfg.NTPServers, time.Duration(cfg.CheckInterval), time.Duration(cfg.MaxDrift))
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				m.Start()
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				m.Stop()
+				return nil
+			},
+		})
+		return m
+	}
+}
+
+// RunClockCheck starts the clock checks on nodes which don't mine.
+func RunClockCheck(*clockcheck.Monitor) {}
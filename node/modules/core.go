@@ -40,6 +40,9 @@ const KTJwtHmacSecret = "jwt-hmac-secret" //nolint:gosec
 
 type JwtPayload struct {
 	Allow []auth.Permission
+	// Methods are the patterns of the method scopes of the token, see
+	// apistruct.MethodScope; the token can call any method when empty
+	Methods []string `json:",omitempty"`
 }
 
 func APISecret(keystore types.KeyStore, lr repo.LockedRepo) (*dtypes.APIAlg, error) {
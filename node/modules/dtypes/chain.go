@@ -1,4 +1,25 @@
 package dtypes
 
+import (
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
 type NetworkName string
 type AfterGenesisSet struct{}
+
+// Checkpoint pins the tipset at a height of the chain: the syncer rejects
+// any chain which doesn't include the tipset made of exactly these blocks at
+// that height.
+type Checkpoint struct {
+	Height abi.ChainEpoch
+	Blocks []cid.Cid
+}
+
+// SyncCheckpoints are the tipsets every synced chain must include.
+type SyncCheckpoints []Checkpoint
+
+// TrustedCheckpoint is the tipset the lightweight sync mode trusts the chain
+// up to; the mode is disabled when it has no blocks.
+type TrustedCheckpoint Checkpoint
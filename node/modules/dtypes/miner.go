@@ -2,6 +2,7 @@ package dtypes
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/ipfs/go-cid"
@@ -72,5 +73,31 @@ type SetExpectedSealDurationFunc func(time.Duration) error
 // too determine how long sealing is expected to take
 type GetExpectedSealDurationFunc func() (time.Duration, error)
 
+// StorageDealClientLimits bounds the storage deals accepted from a single
+// client address within Period. Zero limits are unlimited.
+type StorageDealClientLimits struct {
+	MaxDeals uint64
+	MaxBytes uint64
+	Period   time.Duration
+}
+
+// GetStorageDealClientLimitsFunc is a function which reads the per-client
+// storage deal quotas from the miner config.
+type GetStorageDealClientLimitsFunc func() (StorageDealClientLimits, error)
+
+// GetStorageDealAuthorizedFundersFunc is a function which reads the funders
+// whose storage deals, and those of their delegates, are the only ones
+// accepted; none means deals are accepted from any client.
+type GetStorageDealAuthorizedFundersFunc func() ([]address.Address, error)
+
+// GetConfigSectionFunc is a function which returns a section of the miner
+// config encoded in JSON, see config.SectionJSON.
+type GetConfigSectionFunc func(section string) (json.RawMessage, error)
+
+// SetConfigSectionFunc is a function which sets a section of the miner config
+// from its JSON encoding, see config.SetSectionJSON. It returns the settings
+// changed, and whether some of them only apply after restarting the miner.
+type SetConfigSectionFunc func(section string, value json.RawMessage) (changed []string, restartRequired bool, err error)
+
 type StorageDealFilter func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error)
 type RetrievalDealFilter func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error)
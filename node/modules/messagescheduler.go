@@ -0,0 +1,34 @@
+package modules
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/filecoin-project/lotus/chain/messagescheduler"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/node/impl/full"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/filecoin-project/lotus/node/modules/helpers"
+)
+
+func MessageScheduler(mctx helpers.MetricsCtx, lc fx.Lifecycle, ds dtypes.MetadataDS, cs *store.ChainStore, mpool full.MpoolAPI, state full.StateAPI) (*messagescheduler.Scheduler, error) {
+	s, err := messagescheduler.NewScheduler(ds, cs, &mpool, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := helpers.LifecycleCtx(mctx, lc)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			s.Start(ctx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			s.Stop()
+			return nil
+		},
+	})
+
+	return s, nil
+}
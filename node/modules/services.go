@@ -2,6 +2,7 @@ package modules
 
 import (
 	"context"
+	"time"
 
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/namespace"
@@ -28,6 +29,7 @@ import (
 	"github.com/filecoin-project/lotus/journal"
 	"github.com/filecoin-project/lotus/lib/peermgr"
 	marketevents "github.com/filecoin-project/lotus/markets/loggers"
+	"github.com/filecoin-project/lotus/node/config"
 	"github.com/filecoin-project/lotus/node/hello"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/modules/helpers"
@@ -145,6 +147,7 @@ type RandomBeaconParams struct {
 	PubSub      *pubsub.PubSub `optional:"true"`
 	Cs          *store.ChainStore
 	DrandConfig dtypes.DrandSchedule
+	DS          dtypes.MetadataDS
 }
 
 func BuiltinDrandConfig() dtypes.DrandSchedule {
@@ -159,7 +162,7 @@ func RandomSchedule(p RandomBeaconParams, _ dtypes.AfterGenesisSet) (beacon.Sche
 
 	shd := beacon.Schedule{}
 	for _, dc := range p.DrandConfig {
-		bc, err := drand.NewDrandBeacon(gen.Timestamp, build.BlockDelaySecs, p.PubSub, dc.Config)
+		bc, err := drand.NewDrandBeacon(gen.Timestamp, build.BlockDelaySecs, p.PubSub, dc.Config, p.DS)
 		if err != nil {
 			return nil, xerrors.Errorf("creating drand beacon: %w", err)
 		}
@@ -181,3 +184,111 @@ func OpenFilesystemJournal(lr repo.LockedRepo, lc fx.Lifecycle, disabled journal
 
 	return jrnl, err
 }
+
+// ScheduleChainBlockstoreGC periodically runs value log GC on the chain
+// blockstore datastores. A run is postponed while the chain head lags behind
+// the wall clock, so that GC doesn't compete with catching up on sync.
+func ScheduleChainBlockstoreGC(cfg config.BlockstoreGC, splitstore bool) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, r repo.LockedRepo, cs *store.ChainStore) error {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, r repo.LockedRepo, cs *store.ChainStore) error {
+		gc, ok := r.(repo.DatastoreGC)
+		if !ok {
+			return xerrors.Errorf("repo doesn't support online datastore GC")
+		}
+
+		ctx := helpers.LifecycleCtx(mctx, lc)
+		opts := repo.DatastoreGCOpts{
+			DiscardRatio: cfg.DiscardRatio,
+			Compact:      cfg.Compact,
+		}
+
+		go func() {
+			ticker := build.Clock.Ticker(time.Duration(cfg.Interval))
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+
+				if !waitChainSynced(ctx, cs, "blockstore GC") {
+					return
+				}
+
+				log.Info("starting scheduled blockstore GC")
+				err := gc.GCDatastores(ctx, repo.ChainDatastores(splitstore), opts, func(p repo.DatastoreGCProgress) {
+					log.Infow("blockstore GC", "datastore", p.Namespace, "stage", p.Stage, "rewrites", p.Rewrites,
+						"lsm", p.LSMSize, "vlog", p.VlogSize)
+				})
+				if err != nil {
+					log.Errorf("scheduled blockstore GC failed: %s", err)
+				}
+			}
+		}()
+
+		return nil
+	}
+}
+
+// ScheduleChainBlockstoreScrub periodically checks the integrity of the chain
+// blockstore, optionally fetching corrupt blocks again from the network.
+func ScheduleChainBlockstoreScrub(cfg config.BlockstoreScrub) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, cs *store.ChainStore, bserv dtypes.ChainBlockService) {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, cs *store.ChainStore, bserv dtypes.ChainBlockService) {
+		ctx := helpers.LifecycleCtx(mctx, lc)
+
+		var fetch store.BlockFetcher
+		if cfg.Repair {
+			fetch = bserv.GetBlock
+		}
+
+		go func() {
+			ticker := build.Clock.Ticker(time.Duration(cfg.Interval))
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+
+				if !waitChainSynced(ctx, cs, "blockstore integrity check") {
+					return
+				}
+
+				log.Info("starting scheduled blockstore integrity check")
+				res, err := cs.ScrubBlockstore(ctx, fetch)
+				if err != nil {
+					log.Errorf("scheduled blockstore integrity check failed: %s", err)
+					continue
+				}
+				if len(res.Corrupt) > 0 {
+					log.Errorw("blockstore integrity check found corrupt blocks", "checked", res.Checked,
+						"corrupt", len(res.Corrupt), "repaired", len(res.Repaired))
+				} else {
+					log.Infow("blockstore integrity check passed", "checked", res.Checked)
+				}
+			}
+		}()
+	}
+}
+
+// waitChainSynced blocks until the chain head is close to the wall clock, so
+// that heavy maintenance doesn't compete with catching up on sync. It returns
+// false if the context is cancelled first.
+func waitChainSynced(ctx context.Context, cs *store.ChainStore, what string) bool {
+	for {
+		head := cs.GetHeaviestTipSet()
+		if head != nil && build.Clock.Now().Unix()-int64(head.MinTimestamp()) < int64(3*build.BlockDelaySecs) {
+			return true
+		}
+
+		log.Infof("chain not in sync, postponing scheduled %s", what)
+		select {
+		case <-build.Clock.After(time.Minute):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -2,9 +2,11 @@ package modules
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"go.uber.org/fx"
@@ -60,6 +62,10 @@ import (
 	"github.com/filecoin-project/lotus/chain/gen/slashfilter"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/lib/clockcheck"
+	"github.com/filecoin-project/lotus/markets/dealauth"
+	"github.com/filecoin-project/lotus/markets/dealfilter"
+	"github.com/filecoin-project/lotus/markets/dealsla"
 	marketevents "github.com/filecoin-project/lotus/markets/loggers"
 	"github.com/filecoin-project/lotus/markets/retrievaladapter"
 	"github.com/filecoin-project/lotus/miner"
@@ -254,6 +260,48 @@ func HandleDeals(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, h sto
 	})
 }
 
+// DealAuthorizations returns the deal authorizations imported by the miner,
+// verified against the chain state of the full node.
+func DealAuthorizations(ds dtypes.MetadataDS, full lapi.FullNode) *dealauth.Authorizations {
+	return dealauth.NewAuthorizations(ds, full)
+}
+
+// DealSLATracker returns the tracker measuring the service levels of the deals
+// of the miner, subscribed to the events of the storage and retrieval
+// providers.
+func DealSLATracker(cfg config.DealmakingConfig) func(lc fx.Lifecycle, ds dtypes.MetadataDS, sp storagemarket.StorageProvider, rp retrievalmarket.RetrievalProvider, full lapi.FullNode) (*dealsla.Tracker, error) {
+	return func(lc fx.Lifecycle, ds dtypes.MetadataDS, sp storagemarket.StorageProvider, rp retrievalmarket.RetrievalProvider, full lapi.FullNode) (*dealsla.Tracker, error) {
+		t, err := dealsla.NewTracker(ds, time.Duration(cfg.SLAReportPeriod), cfg.SLAReportsKept, func(ctx context.Context) (abi.ChainEpoch, error) {
+			head, err := full.ChainHead(ctx)
+			if err != nil {
+				return 0, err
+			}
+			return head.Height(), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var unsubs []func()
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				unsubs = append(unsubs,
+					sp.SubscribeToEvents(t.OnStorageEvent),
+					rp.SubscribeToEvents(t.OnRetrievalEvent))
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				for _, unsub := range unsubs {
+					unsub()
+				}
+				return t.Stop()
+			},
+		})
+
+		return t, nil
+	}
+}
+
 // NewProviderDAGServiceDataTransfer returns a data transfer manager that just
 // uses the provider's Staging DAG service for transfers
 func NewProviderDAGServiceDataTransfer(lc fx.Lifecycle, h host.Host, gs dtypes.StagingGraphsync, ds dtypes.MetadataDS) (dtypes.ProviderDataTransfer, error) {
@@ -357,13 +405,13 @@ func StagingGraphsync(mctx helpers.MetricsCtx, lc fx.Lifecycle, ibs dtypes.Stagi
 	return gs
 }
 
-func SetupBlockProducer(lc fx.Lifecycle, ds dtypes.MetadataDS, api lapi.FullNode, epp gen.WinningPoStProver, sf *slashfilter.SlashFilter, j journal.Journal) (*miner.Miner, error) {
+func SetupBlockProducer(lc fx.Lifecycle, ds dtypes.MetadataDS, api lapi.FullNode, epp gen.WinningPoStProver, sf *slashfilter.SlashFilter, j journal.Journal, clock *clockcheck.Monitor) (*miner.Miner, error) {
 	minerAddr, err := minerAddrFromDS(ds)
 	if err != nil {
 		return nil, err
 	}
 
-	m := miner.NewMiner(api, epp, minerAddr, sf, j)
+	m := miner.NewMiner(api, epp, minerAddr, sf, j, clock)
 
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
@@ -414,13 +462,23 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 	offlineOk dtypes.ConsiderOfflineStorageDealsConfigFunc,
 	blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
 	expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
+	clientLimitsFunc dtypes.GetStorageDealClientLimitsFunc,
+	fundersFunc dtypes.GetStorageDealAuthorizedFundersFunc,
+	auths *dealauth.Authorizations,
+	ds dtypes.MetadataDS,
 	spn storagemarket.StorageProviderNode) dtypes.StorageDealFilter {
 	return func(onlineOk dtypes.ConsiderOnlineStorageDealsConfigFunc,
 		offlineOk dtypes.ConsiderOfflineStorageDealsConfigFunc,
 		blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
 		expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
+		clientLimitsFunc dtypes.GetStorageDealClientLimitsFunc,
+		fundersFunc dtypes.GetStorageDealAuthorizedFundersFunc,
+		auths *dealauth.Authorizations,
+		ds dtypes.MetadataDS,
 		spn storagemarket.StorageProviderNode) dtypes.StorageDealFilter {
 
+		quota := dealfilter.NewClientQuota(ds)
+
 		return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
 			b, err := onlineOk()
 			if err != nil {
@@ -429,7 +487,7 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 
 			if deal.Ref != nil && deal.Ref.TransferType != storagemarket.TTManual && !b {
 				log.Warnf("online storage deal consideration disabled; rejecting storage deal proposal from client: %s", deal.Client.String())
-				return false, "miner is not considering online storage deals", nil
+				return false, lapi.FormatDealRejection(lapi.DealRejectedNotAccepting, "miner is not considering online storage deals"), nil
 			}
 
 			b, err = offlineOk()
@@ -439,7 +497,7 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 
 			if deal.Ref != nil && deal.Ref.TransferType == storagemarket.TTManual && !b {
 				log.Warnf("offline storage deal consideration disabled; rejecting storage deal proposal from client: %s", deal.Client.String())
-				return false, "miner is not accepting offline storage deals", nil
+				return false, lapi.FormatDealRejection(lapi.DealRejectedNotAccepting, "miner is not accepting offline storage deals"), nil
 			}
 
 			blocklist, err := blocklistFunc()
@@ -450,10 +508,24 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 			for idx := range blocklist {
 				if deal.Proposal.PieceCID.Equals(blocklist[idx]) {
 					log.Warnf("piece CID in proposal %s is blocklisted; rejecting storage deal proposal from client: %s", deal.Proposal.PieceCID, deal.Client.String())
-					return false, fmt.Sprintf("miner has blocklisted piece CID %s", deal.Proposal.PieceCID), nil
+					return false, lapi.FormatDealRejection(lapi.DealRejectedBlocklisted, fmt.Sprintf("miner has blocklisted piece CID %s", deal.Proposal.PieceCID)), nil
 				}
 			}
 
+			funders, err := fundersFunc()
+			if err != nil {
+				return false, "miner error", err
+			}
+
+			ok, reason, err := auths.Accept(ctx, deal, funders)
+			if err != nil {
+				return false, "miner error", err
+			}
+			if !ok {
+				log.Warnw("client is not authorized; rejecting storage deal proposal", "client", deal.Proposal.Client, "proposal", deal.ProposalCid, "reason", reason)
+				return false, lapi.FormatDealRejection(lapi.DealRejectedUnauthorized, reason), nil
+			}
+
 			sealDuration, err := expectedSealTimeFunc()
 			if err != nil {
 				return false, "miner error", err
@@ -467,20 +539,44 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 			earliest := abi.ChainEpoch(sealEpochs) + ht
 			if deal.Proposal.StartEpoch < earliest {
 				log.Warnw("proposed deal would start before sealing can be completed; rejecting storage deal proposal from client", "piece_cid", deal.Proposal.PieceCID, "client", deal.Client.String(), "seal_duration", sealDuration, "earliest", earliest, "curepoch", ht)
-				return false, fmt.Sprintf("cannot seal a sector before %s", deal.Proposal.StartEpoch), nil
+				return false, lapi.FormatDealRejection(lapi.DealRejectedBadStartEpoch, fmt.Sprintf("cannot seal a sector before %s", deal.Proposal.StartEpoch)), nil
 			}
 
 			// Reject if it's more than 7 days in the future
 			// TODO: read from cfg
 			maxStartEpoch := earliest + abi.ChainEpoch(7*builtin.SecondsInDay/build.BlockDelaySecs)
 			if deal.Proposal.StartEpoch > maxStartEpoch {
-				return false, fmt.Sprintf("deal start epoch is too far in the future: %s > %s", deal.Proposal.StartEpoch, maxStartEpoch), nil
+				return false, lapi.FormatDealRejection(lapi.DealRejectedBadStartEpoch, fmt.Sprintf("deal start epoch is too far in the future: %s > %s", deal.Proposal.StartEpoch, maxStartEpoch)), nil
 			}
 
 			if user != nil {
-				return user(ctx, deal)
+				ok, reason, err := user(ctx, deal)
+				if err != nil {
+					return false, reason, err
+				}
+				if !ok {
+					// keep codes set by the filter
+					if r := lapi.ParseDealRejection(reason); r == nil || r.Code == lapi.DealRejectedOther {
+						reason = lapi.FormatDealRejection(lapi.DealRejectedFilter, reason)
+					}
+					return false, reason, nil
+				}
 			}
 
+			// checked last, so that only accepted deals count towards the quota
+			limits, err := clientLimitsFunc()
+			if err != nil {
+				return false, "miner error", err
+			}
+
+			ok, reason, err = quota.Accept(deal, limits)
+			if err != nil {
+				return false, "miner error", err
+			}
+			if !ok {
+				log.Warnw("client deal quota exceeded; rejecting storage deal proposal", "client", deal.Proposal.Client, "proposal", deal.ProposalCid, "reason", reason)
+				return false, lapi.FormatDealRejection(lapi.DealRejectedClientQuota, reason), nil
+			}
 			return true, "", nil
 		}
 	}
@@ -711,6 +807,35 @@ func NewGetSealConfigFunc(r repo.LockedRepo) (dtypes.GetSealingConfigFunc, error
 	}, nil
 }
 
+func NewGetStorageDealClientLimitsFunc(r repo.LockedRepo) (dtypes.GetStorageDealClientLimitsFunc, error) {
+	return func() (out dtypes.StorageDealClientLimits, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out = dtypes.StorageDealClientLimits{
+				MaxDeals: cfg.Dealmaking.MaxDealsPerClient,
+				MaxBytes: cfg.Dealmaking.MaxDealBytesPerClient,
+				Period:   time.Duration(cfg.Dealmaking.ClientQuotaPeriod),
+			}
+		})
+		return
+	}, nil
+}
+
+func NewGetStorageDealAuthorizedFundersFunc(r repo.LockedRepo) (dtypes.GetStorageDealAuthorizedFundersFunc, error) {
+	return func() (out []address.Address, err error) {
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			for _, s := range cfg.Dealmaking.AuthorizedFunders {
+				addr, perr := address.NewFromString(s)
+				if perr != nil {
+					err = xerrors.Errorf("parsing authorized funder '%s': %w", s, perr)
+					return
+				}
+				out = append(out, addr)
+			}
+		})
+		return
+	}, nil
+}
+
 func NewSetExpectedSealDurationFunc(r repo.LockedRepo) (dtypes.SetExpectedSealDurationFunc, error) {
 	return func(delay time.Duration) (err error) {
 		err = mutateCfg(r, func(cfg *config.StorageMiner) {
@@ -729,6 +854,67 @@ func NewGetExpectedSealDurationFunc(r repo.LockedRepo) (dtypes.GetExpectedSealDu
 	}, nil
 }
 
+// minerLiveSettings are the settings of the miner config, or sections of it,
+// read from the config each time they are used. Changes to other settings only
+// apply after restarting the miner.
+var minerLiveSettings = []string{
+	"Dealmaking.ConsiderOnlineStorageDeals",
+	"Dealmaking.ConsiderOfflineStorageDeals",
+	"Dealmaking.ConsiderOnlineRetrievalDeals",
+	"Dealmaking.ConsiderOfflineRetrievalDeals",
+	"Dealmaking.PieceCidBlocklist",
+	"Dealmaking.ExpectedSealDuration",
+	"Dealmaking.MaxDealsPerClient",
+	"Dealmaking.MaxDealBytesPerClient",
+	"Dealmaking.ClientQuotaPeriod",
+	"Dealmaking.AuthorizedFunders",
+	"Sealing",
+}
+
+func minerSettingIsLive(setting string) bool {
+	for _, s := range minerLiveSettings {
+		if setting == s || strings.HasPrefix(setting, s+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func NewGetConfigSectionFunc(r repo.LockedRepo) (dtypes.GetConfigSectionFunc, error) {
+	return func(section string) (out json.RawMessage, err error) {
+		var secErr error
+		err = readCfg(r, func(cfg *config.StorageMiner) {
+			out, secErr = config.SectionJSON(cfg, section)
+		})
+		if err == nil {
+			err = secErr
+		}
+		return
+	}, nil
+}
+
+func NewSetConfigSectionFunc(r repo.LockedRepo) (dtypes.SetConfigSectionFunc, error) {
+	return func(section string, value json.RawMessage) (changed []string, restartRequired bool, err error) {
+		var secErr error
+		err = mutateCfg(r, func(cfg *config.StorageMiner) {
+			changed, secErr = config.SetSectionJSON(cfg, config.DefaultStorageMiner(), section, value)
+		})
+		if secErr != nil {
+			return nil, false, secErr
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, setting := range changed {
+			if !minerSettingIsLive(setting) {
+				restartRequired = true
+			}
+		}
+		return changed, restartRequired, nil
+	}, nil
+}
+
 func readCfg(r repo.LockedRepo, accessor func(*config.StorageMiner)) error {
 	raw, err := r.Config()
 	if err != nil {
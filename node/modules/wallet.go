@@ -0,0 +1,75 @@
+package modules
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/wallet"
+	"github.com/filecoin-project/lotus/node/config"
+)
+
+// WalletRoutes parses the signing routes of the wallet config.
+func WalletRoutes(cfg config.Wallet) func() (wallet.Routes, error) {
+	return func() (wallet.Routes, error) {
+		routes := make(wallet.Routes, 0, len(cfg.Routes))
+		for i, rc := range cfg.Routes {
+			rt := wallet.Route{
+				Backend:      rc.Backend,
+				OnlyMessages: rc.OnlyMessages,
+			}
+
+			if rc.MaxValue != "" {
+				v, err := types.ParseFIL(rc.MaxValue)
+				if err != nil {
+					return nil, xerrors.Errorf("wallet route %d: parsing max value %q: %w", i, rc.MaxValue, err)
+				}
+				rt.MaxValue = abi.TokenAmount(v)
+			}
+
+			switch rc.Backend {
+			case "", wallet.BackendLocal, wallet.BackendRemote, wallet.BackendLedger:
+			default:
+				return nil, xerrors.Errorf("wallet route %d: unknown backend %q", i, rc.Backend)
+			}
+
+			for _, s := range rc.Addresses {
+				switch s {
+				case "*":
+					rt.All = true
+				case "secp256k1":
+					rt.Protocols = append(rt.Protocols, address.SECP256K1)
+				case "bls":
+					rt.Protocols = append(rt.Protocols, address.BLS)
+				default:
+					a, err := address.NewFromString(s)
+					if err != nil {
+						return nil, xerrors.Errorf("wallet route %d: parsing address %q: %w", i, s, err)
+					}
+					if a.Protocol() != address.SECP256K1 && a.Protocol() != address.BLS {
+						// an ID or actor address would never match a signer
+						return nil, xerrors.Errorf("wallet route %d: %s isn't a key address, routes match the key addresses signing", i, s)
+					}
+					rt.Addresses = append(rt.Addresses, a)
+				}
+			}
+
+			for _, s := range rc.To {
+				a, err := address.NewFromString(s)
+				if err != nil {
+					return nil, xerrors.Errorf("wallet route %d: parsing destination %q: %w", i, s, err)
+				}
+				rt.To = append(rt.To, a)
+			}
+
+			for _, m := range rc.Methods {
+				rt.Methods = append(rt.Methods, abi.MethodNum(m))
+			}
+
+			routes = append(routes, rt)
+		}
+		return routes, nil
+	}
+}
@@ -161,3 +161,17 @@ func TestPaymentChannels(t *testing.T) {
 
 	test.TestPaymentChannels(t, builder.MockSbBuilder, 5*time.Millisecond)
 }
+
+// TestPaymentChannelsCBORRPC runs the payment channel test with the nodes used
+// over the cbor transport: the miner, and the events of the test, subscribe
+// to the head changes with ChainNotify.
+func TestPaymentChannelsCBORRPC(t *testing.T) {
+	logging.SetLogLevel("miner", "ERROR")
+	logging.SetLogLevel("chainstore", "ERROR")
+	logging.SetLogLevel("chain", "ERROR")
+	logging.SetLogLevel("sub", "ERROR")
+	logging.SetLogLevel("pubsub", "ERROR")
+	logging.SetLogLevel("storageminer", "ERROR")
+
+	test.TestPaymentChannels(t, builder.CBORRPCMockSbBuilder, 5*time.Millisecond)
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/blockstore"
+	"github.com/filecoin-project/lotus/node/config"
+	"github.com/filecoin-project/lotus/node/repo/remotebs"
+)
+
+const (
+	// BlockstoreBadger keeps blocks in the repo's local badger datastores
+	BlockstoreBadger = "badger"
+	// BlockstoreRemote keeps blocks in a remote key-value service, see the
+	// remotebs package for the protocol
+	BlockstoreRemote = "remote"
+)
+
+// BlockstoreBackendCtor opens the blockstore for a datastore domain of the
+// repo (e.g. "/chain") from its backend config.
+type BlockstoreBackendCtor func(lr LockedRepo, domain string, cfg config.BlockstoreBackend) (blockstore.Blockstore, error)
+
+var (
+	backendsLk sync.Mutex
+	backends   = map[string]BlockstoreBackendCtor{
+		BlockstoreBadger: localBlockstore,
+		BlockstoreRemote: remoteBlockstore,
+	}
+)
+
+// RegisterBlockstoreBackend makes a blockstore backend available for
+// selection in the config under the given type name.
+func RegisterBlockstoreBackend(typ string, ctor BlockstoreBackendCtor) {
+	backendsLk.Lock()
+	defer backendsLk.Unlock()
+
+	backends[typ] = ctor
+}
+
+// OpenBlockstore opens the blockstore for the given domain using the backend
+// selected in cfg. An empty backend type selects the local badger backend.
+func OpenBlockstore(lr LockedRepo, domain string, cfg config.BlockstoreBackend) (blockstore.Blockstore, error) {
+	typ := cfg.Type
+	if typ == "" {
+		typ = BlockstoreBadger
+	}
+
+	backendsLk.Lock()
+	ctor, ok := backends[typ]
+	backendsLk.Unlock()
+	if !ok {
+		return nil, xerrors.Errorf("unknown blockstore backend %q", typ)
+	}
+
+	bs, err := ctor(lr, domain, cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("opening %s blockstore for %s: %w", typ, domain, err)
+	}
+	return bs, nil
+}
+
+func localBlockstore(lr LockedRepo, domain string, _ config.BlockstoreBackend) (blockstore.Blockstore, error) {
+	ds, err := lr.Datastore(domain)
+	if err != nil {
+		return nil, err
+	}
+	return blockstore.NewBlockstore(ds), nil
+}
+
+func remoteBlockstore(_ LockedRepo, domain string, cfg config.BlockstoreBackend) (blockstore.Blockstore, error) {
+	if cfg.Remote.Endpoint == "" {
+		return nil, xerrors.Errorf("no remote blockstore endpoint configured")
+	}
+
+	bs, err := remotebs.New(cfg.Remote.Endpoint, cfg.Remote.Token, time.Duration(cfg.Remote.Timeout))
+	if err != nil {
+		return nil, err
+	}
+	return blockstore.WrapIDStore(bs), nil
+}
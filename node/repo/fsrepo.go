@@ -13,6 +13,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/ipfs/go-datastore"
+	badger "github.com/ipfs/go-ds-badger2"
 	fslock "github.com/ipfs/go-fs-lock"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/mitchellh/go-homedir"
@@ -256,6 +257,13 @@ type fsLockedRepo struct {
 	ds     map[string]datastore.Batching
 	dsErr  error
 	dsOnce sync.Once
+	// guards ds and badgerDs once opened, as the optional datastores are
+	// added when first requested
+	dsLk sync.Mutex
+
+	// unwrapped badger datastores, used for online GC
+	badgerDs  map[string]*badger.Datastore
+	gcRunning int32
 
 	storageLk sync.Mutex
 	configLk  sync.Mutex
@@ -3,6 +3,7 @@ package repo
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/ipfs/go-datastore"
 	"golang.org/x/xerrors"
@@ -26,6 +27,13 @@ var fsDatastores = map[string]dsCtor{
 	"client": badgerDs, // client specific
 }
 
+// fsOptionalDatastores are only opened once requested, by the nodes using
+// them.
+var fsOptionalDatastores = map[string]dsCtor{
+	// hotstore and tracking metadata for the splitstore
+	"splitstore": chainBadgerDs,
+}
+
 func chainBadgerDs(path string, readonly bool) (datastore.Batching, error) {
 	opts := badger.DefaultOptions
 	opts.GcInterval = 0 // disable GC for chain datastore
@@ -62,24 +70,35 @@ func (fsr *fsLockedRepo) openDatastores(readonly bool) (map[string]datastore.Bat
 	}
 
 	out := map[string]datastore.Batching{}
+	fsr.badgerDs = map[string]*badger.Datastore{}
 
 	for p, ctor := range fsDatastores {
-		prefix := datastore.NewKey(p)
-
-		// TODO: optimization: don't init datastores we don't need
-		ds, err := ctor(fsr.join(filepath.Join(fsDatastore, p)), readonly)
+		ds, err := fsr.openDatastore(p, ctor, readonly)
 		if err != nil {
-			return nil, xerrors.Errorf("opening datastore %s: %w", prefix, err)
+			return nil, err
 		}
 
-		ds = measure.New("fsrepo."+p, ds)
-
 		out[datastore.NewKey(p).String()] = ds
 	}
 
 	return out, nil
 }
 
+func (fsr *fsLockedRepo) openDatastore(p string, ctor dsCtor, readonly bool) (datastore.Batching, error) {
+	prefix := datastore.NewKey(p)
+
+	ds, err := ctor(fsr.join(filepath.Join(fsDatastore, p)), readonly)
+	if err != nil {
+		return nil, xerrors.Errorf("opening datastore %s: %w", prefix, err)
+	}
+
+	if bds, ok := ds.(*badger.Datastore); ok {
+		fsr.badgerDs[prefix.String()] = bds
+	}
+
+	return measure.New("fsrepo."+p, ds), nil
+}
+
 func (fsr *fsLockedRepo) Datastore(ns string) (datastore.Batching, error) {
 	fsr.dsOnce.Do(func() {
 		fsr.ds, fsr.dsErr = fsr.openDatastores(fsr.readonly)
@@ -88,9 +107,23 @@ func (fsr *fsLockedRepo) Datastore(ns string) (datastore.Batching, error) {
 	if fsr.dsErr != nil {
 		return nil, fsr.dsErr
 	}
+
+	fsr.dsLk.Lock()
+	defer fsr.dsLk.Unlock()
+
 	ds, ok := fsr.ds[ns]
 	if ok {
 		return ds, nil
 	}
+
+	p := strings.TrimPrefix(ns, "/")
+	if ctor, ok := fsOptionalDatastores[p]; ok {
+		ds, err := fsr.openDatastore(p, ctor, fsr.readonly)
+		if err != nil {
+			return nil, err
+		}
+		fsr.ds[ns] = ds
+		return ds, nil
+	}
 	return nil, xerrors.Errorf("no such datastore: %s", ns)
 }
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+
+	dgbadger "github.com/dgraph-io/badger/v2"
+	"golang.org/x/xerrors"
+)
+
+// DefaultGCDiscardRatio is the fraction of a badger value log file which must
+// be garbage before the file is rewritten.
+const DefaultGCDiscardRatio = 0.5
+
+var ErrGCRunning = errors.New("datastore garbage collection already running")
+
+// ChainDatastores returns the datastores which back the chain blockstore,
+// which include the hotstore when the splitstore is enabled.
+func ChainDatastores(splitstore bool) []string {
+	if splitstore {
+		return []string{"/chain", "/splitstore"}
+	}
+	return []string{"/chain"}
+}
+
+type DatastoreGCOpts struct {
+	// DiscardRatio is passed to badger value log GC, see DefaultGCDiscardRatio
+	DiscardRatio float64
+	// Compact flattens the LSM tree once value log GC is done
+	Compact bool
+}
+
+type DatastoreGCProgress struct {
+	Namespace string
+	// Stage is one of "start", "gc", "compact" or "done"
+	Stage string
+	// Rewrites is the number of value log files rewritten so far
+	Rewrites int
+
+	// Approximate on-disk sizes; badger only refreshes them periodically
+	LSMSize  int64
+	VlogSize int64
+}
+
+// DatastoreGC is implemented by locked repos whose datastores can be garbage
+// collected and compacted while the node is running.
+type DatastoreGC interface {
+	GCDatastores(ctx context.Context, namespaces []string, opts DatastoreGCOpts, progress func(DatastoreGCProgress)) error
+}
+
+var _ DatastoreGC = (*fsLockedRepo)(nil)
+
+func (fsr *fsLockedRepo) GCDatastores(ctx context.Context, namespaces []string, opts DatastoreGCOpts, progress func(DatastoreGCProgress)) error {
+	if !atomic.CompareAndSwapInt32(&fsr.gcRunning, 0, 1) {
+		return ErrGCRunning
+	}
+	defer atomic.StoreInt32(&fsr.gcRunning, 0)
+
+	if opts.DiscardRatio <= 0 || opts.DiscardRatio >= 1 {
+		opts.DiscardRatio = DefaultGCDiscardRatio
+	}
+	if progress == nil {
+		progress = func(DatastoreGCProgress) {}
+	}
+
+	for _, ns := range namespaces {
+		// make sure the datastores are open
+		if _, err := fsr.Datastore(ns); err != nil {
+			return err
+		}
+
+		fsr.dsLk.Lock()
+		bds, ok := fsr.badgerDs[ns]
+		fsr.dsLk.Unlock()
+		if !ok {
+			return xerrors.Errorf("datastore %s doesn't support online GC", ns)
+		}
+
+		if err := gcBadger(ctx, ns, bds.DB, opts, progress); err != nil {
+			return xerrors.Errorf("datastore %s: %w", ns, err)
+		}
+	}
+
+	return nil
+}
+
+func gcBadger(ctx context.Context, ns string, db *dgbadger.DB, opts DatastoreGCOpts, progress func(DatastoreGCProgress)) error {
+	var rewrites int
+	report := func(stage string) {
+		lsm, vlog := db.Size()
+		progress(DatastoreGCProgress{
+			Namespace: ns,
+			Stage:     stage,
+			Rewrites:  rewrites,
+			LSMSize:   lsm,
+			VlogSize:  vlog,
+		})
+	}
+
+	report("start")
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := db.RunValueLogGC(opts.DiscardRatio)
+		if err == dgbadger.ErrNoRewrite {
+			break
+		}
+		if err != nil {
+			return xerrors.Errorf("value log GC: %w", err)
+		}
+
+		rewrites++
+		report("gc")
+	}
+
+	if opts.Compact {
+		report("compact")
+		if err := db.Flatten(runtime.NumCPU()); err != nil {
+			return xerrors.Errorf("flattening LSM tree: %w", err)
+		}
+	}
+
+	report("done")
+	return nil
+}
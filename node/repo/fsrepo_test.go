@@ -1,8 +1,10 @@
 package repo
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -31,3 +33,63 @@ func TestFsBasic(t *testing.T) {
 	defer closer()
 	basicTest(t, repo)
 }
+
+func TestFsOptionalDatastores(t *testing.T) {
+	repo, closer := genFsRepo(t)
+	defer closer()
+
+	lrepo, err := repo.Lock(FullNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lrepo.Close() //nolint:errcheck
+
+	if _, err := lrepo.Datastore("/metadata"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(repo.path, fsDatastore, "splitstore")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the splitstore datastore to be opened once requested, stat: %v", err)
+	}
+
+	if _, err := lrepo.Datastore("/splitstore"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFsDatastoreGC(t *testing.T) {
+	repo, closer := genFsRepo(t)
+	defer closer()
+
+	lrepo, err := repo.Lock(FullNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lrepo.Close() //nolint:errcheck
+
+	gc, ok := lrepo.(DatastoreGC)
+	if !ok {
+		t.Fatal("fs repo should support datastore GC")
+	}
+
+	var done []string
+	err = gc.GCDatastores(context.TODO(), ChainDatastores(true), DatastoreGCOpts{Compact: true}, func(p DatastoreGCProgress) {
+		if p.Stage == "done" {
+			done = append(done, p.Namespace)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(ChainDatastores(true)) {
+		t.Fatalf("expected GC to finish on %v, finished on %v", ChainDatastores(true), done)
+	}
+
+	if err := gc.GCDatastores(context.TODO(), []string{"/metadata"}, DatastoreGCOpts{}, nil); err == nil {
+		t.Fatal("expected GC on a leveldb datastore to fail")
+	}
+}
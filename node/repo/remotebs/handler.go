@@ -0,0 +1,185 @@
+package remotebs
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+// MaxBlockSize bounds the size of blocks accepted by the handler.
+const MaxBlockSize = 4 << 20
+
+// MaxBatchSize bounds the size of the batches of blocks sent by PutMany,
+// unless they hold a single block.
+const MaxBatchSize = 16 << 20
+
+type handler struct {
+	bs    blockstore.Blockstore
+	token string
+}
+
+// NewHandler serves bs over the protocol spoken by Blockstore. If token is
+// set, requests must carry it as a bearer token.
+func NewHandler(bs blockstore.Blockstore, token string) http.Handler {
+	return &handler{bs: bs, token: token}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(h.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	key := strings.Trim(r.URL.Path, "/")
+	if key == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listKeys(w, r)
+		case http.MethodPost:
+			h.putBatch(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	c, err := cid.Decode(key)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad cid: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		size, err := h.bs.GetSize(c)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(size))
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet:
+		blk, err := h.bs.Get(c)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(blk.RawData())))
+		_, _ = w.Write(blk.RawData())
+
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxBlockSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		blk, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// only accept blocks which actually hash to their key
+		if rc, err := c.Prefix().Sum(data); err != nil || !rc.Equals(c) {
+			http.Error(w, "block data doesn't match cid", http.StatusBadRequest)
+			return
+		}
+		if err := h.bs.Put(blk); err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if err := h.bs.DeleteBlock(c); err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *handler) putBatch(w http.ResponseWriter, r *http.Request) {
+	// a batch under the limit may be followed by one more block
+	br := bufio.NewReader(http.MaxBytesReader(w, r.Body, MaxBatchSize+2*MaxBlockSize))
+
+	var blks []blocks.Block
+	for {
+		cb, err := readFrame(br, MaxBlockSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, c, err := cid.CidFromBytes(cb)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("bad cid: %s", err), http.StatusBadRequest)
+			return
+		}
+		data, err := readFrame(br, MaxBlockSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// only accept blocks which actually hash to their key
+		if rc, err := c.Prefix().Sum(data); err != nil || !rc.Equals(c) {
+			http.Error(w, fmt.Sprintf("block data doesn't match cid %s", c), http.StatusBadRequest)
+			return
+		}
+		blk, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		blks = append(blks, blk)
+	}
+
+	if err := h.bs.PutMany(blks); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.bs.AllKeysChan(r.Context())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	for c := range keys {
+		if _, err := fmt.Fprintln(w, c.String()); err != nil {
+			return
+		}
+	}
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	if err == blockstore.ErrNotFound {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	log.Warnf("remote blockstore request failed: %s", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
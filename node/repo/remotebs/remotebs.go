@@ -0,0 +1,316 @@
+// Package remotebs implements a blockstore backed by a remote key-value
+// service speaking a minimal HTTP protocol, so that several nodes can share a
+// single chain store.
+//
+// Blocks are addressed as {endpoint}/{cid}:
+//
+//	HEAD   returns 200 with Content-Length set to the block size, or 404
+//	GET    returns 200 with the raw block data, or 404
+//	PUT    stores the request body under the CID
+//	DELETE removes the block
+//
+// A GET on the endpoint itself streams all stored CIDs, one per line, and a
+// POST on it stores the batch of blocks of the request body, each framed as
+// the uvarint length of its CID bytes, the CID bytes, the uvarint length of
+// its data and the data.
+package remotebs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+var log = logging.Logger("remotebs")
+
+type Blockstore struct {
+	endpoint string
+	token    string
+	timeout  time.Duration
+	client   *http.Client
+
+	rehash bool
+}
+
+var _ blockstore.Blockstore = (*Blockstore)(nil)
+
+// New returns a blockstore talking to the service at endpoint. If token is
+// set, it is sent as a bearer token with every request. The requests on
+// blocks must complete within timeout; listing the keys, which streams them,
+// only has to connect and get the response headers within it.
+func New(endpoint, token string, timeout time.Duration) (*Blockstore, error) {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, xerrors.Errorf("remote blockstore endpoint must be an http(s) URL, got %q", endpoint)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if timeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = timeout
+		transport.ResponseHeaderTimeout = timeout
+	}
+
+	return &Blockstore{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		timeout:  timeout,
+		client:   &http.Client{Transport: transport},
+	}, nil
+}
+
+// requestContext returns the context of a request on blocks, bounded by the
+// timeout of the blockstore.
+func (b *Blockstore) requestContext() (context.Context, context.CancelFunc) {
+	if b.timeout <= 0 {
+		return context.WithCancel(context.TODO())
+	}
+	return context.WithTimeout(context.TODO(), b.timeout)
+}
+
+func (b *Blockstore) do(ctx context.Context, method string, c cid.Cid, body []byte) (*http.Response, error) {
+	url := b.endpoint + "/"
+	if c.Defined() {
+		url += c.String()
+	}
+
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, url, rd)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("%s %s: %w", method, url, err)
+	}
+	return resp, nil
+}
+
+func statusErr(resp *http.Response) error {
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	return xerrors.Errorf("remote blockstore returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func (b *Blockstore) Has(c cid.Cid) (bool, error) {
+	_, err := b.GetSize(c)
+	if err == blockstore.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *Blockstore) GetSize(c cid.Cid) (int, error) {
+	ctx, cancel := b.requestContext()
+	defer cancel()
+
+	resp, err := b.do(ctx, http.MethodHead, c, nil)
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if resp.ContentLength < 0 {
+			return -1, xerrors.Errorf("remote blockstore didn't report the size of %s", c)
+		}
+		return int(resp.ContentLength), nil
+	case http.StatusNotFound:
+		return -1, blockstore.ErrNotFound
+	default:
+		return -1, statusErr(resp)
+	}
+}
+
+func (b *Blockstore) Get(c cid.Cid) (blocks.Block, error) {
+	ctx, cancel := b.requestContext()
+	defer cancel()
+
+	resp, err := b.do(ctx, http.MethodGet, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, blockstore.ErrNotFound
+	default:
+		return nil, statusErr(resp)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("reading block %s: %w", c, err)
+	}
+
+	if b.rehash {
+		rc, err := c.Prefix().Sum(data)
+		if err != nil {
+			return nil, err
+		}
+		if !rc.Equals(c) {
+			return nil, xerrors.Errorf("remote block %s failed hash verification", c)
+		}
+	}
+
+	return blocks.NewBlockWithCid(data, c)
+}
+
+func (b *Blockstore) Put(blk blocks.Block) error {
+	ctx, cancel := b.requestContext()
+	defer cancel()
+
+	resp, err := b.do(ctx, http.MethodPut, blk.Cid(), blk.RawData())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 {
+		return statusErr(resp)
+	}
+	return nil
+}
+
+// PutMany stores the blocks in batches of at most MaxBatchSize bytes, one
+// request each.
+func (b *Blockstore) PutMany(blks []blocks.Block) error {
+	var batch bytes.Buffer
+	for _, blk := range blks {
+		var frame bytes.Buffer
+		writeFrame(&frame, blk.Cid().Bytes())
+		writeFrame(&frame, blk.RawData())
+
+		if batch.Len() > 0 && batch.Len()+frame.Len() > MaxBatchSize {
+			if err := b.putBatch(batch.Bytes()); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		batch.Write(frame.Bytes())
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	return b.putBatch(batch.Bytes())
+}
+
+func (b *Blockstore) putBatch(body []byte) error {
+	ctx, cancel := b.requestContext()
+	defer cancel()
+
+	resp, err := b.do(ctx, http.MethodPost, cid.Undef, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 {
+		return statusErr(resp)
+	}
+	return nil
+}
+
+func writeFrame(w *bytes.Buffer, data []byte) {
+	var lb [binary.MaxVarintLen64]byte
+	w.Write(lb[:binary.PutUvarint(lb[:], uint64(len(data)))])
+	w.Write(data)
+}
+
+// readFrame reads a frame written by writeFrame, of at most limit bytes; it
+// returns io.EOF if r has no frame left.
+func readFrame(r *bufio.Reader, limit uint64) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > limit {
+		return nil, xerrors.Errorf("frame of %d bytes over the limit of %d", l, limit)
+	}
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, xerrors.Errorf("reading frame: %w", err)
+	}
+	return data, nil
+}
+
+func (b *Blockstore) DeleteBlock(c cid.Cid) error {
+	ctx, cancel := b.requestContext()
+	defer cancel()
+
+	resp, err := b.do(ctx, http.MethodDelete, c, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return statusErr(resp)
+	}
+	return nil
+}
+
+func (b *Blockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	resp, err := b.do(ctx, http.MethodGet, cid.Undef, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close() //nolint:errcheck
+		return nil, statusErr(resp)
+	}
+
+	out := make(chan cid.Cid)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close() //nolint:errcheck
+
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			c, err := cid.Decode(sc.Text())
+			if err != nil {
+				log.Errorf("bad key from remote blockstore: %s", err)
+				return
+			}
+
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			log.Errorf("listing remote blockstore keys: %s", err)
+		}
+	}()
+
+	return out, nil
+}
+
+func (b *Blockstore) HashOnRead(enabled bool) {
+	b.rehash = enabled
+}
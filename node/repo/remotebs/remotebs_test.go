@@ -0,0 +1,120 @@
+package remotebs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/lotus/lib/blockstore"
+)
+
+func TestRemoteBlockstore(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(blockstore.NewTemporarySync(), "secret"))
+	defer srv.Close()
+
+	bs, err := New(srv.URL, "secret", 5*time.Second)
+	require.NoError(t, err)
+
+	blk := blocks.NewBlock([]byte("some block"))
+
+	has, err := bs.Has(blk.Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+
+	_, err = bs.Get(blk.Cid())
+	require.Equal(t, blockstore.ErrNotFound, err)
+
+	require.NoError(t, bs.Put(blk))
+
+	has, err = bs.Has(blk.Cid())
+	require.NoError(t, err)
+	require.True(t, has)
+
+	size, err := bs.GetSize(blk.Cid())
+	require.NoError(t, err)
+	require.Equal(t, len(blk.RawData()), size)
+
+	bs.HashOnRead(true)
+	got, err := bs.Get(blk.Cid())
+	require.NoError(t, err)
+	require.Equal(t, blk.RawData(), got.RawData())
+
+	keys, err := bs.AllKeysChan(context.TODO())
+	require.NoError(t, err)
+	var n int
+	for k := range keys {
+		require.Equal(t, blk.Cid(), k)
+		n++
+	}
+	require.Equal(t, 1, n)
+
+	require.NoError(t, bs.DeleteBlock(blk.Cid()))
+	has, err = bs.Has(blk.Cid())
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// requests without the right token are rejected
+	unauth, err := New(srv.URL, "wrong", 5*time.Second)
+	require.NoError(t, err)
+	_, err = unauth.Has(blk.Cid())
+	require.Error(t, err)
+}
+
+func TestRemoteBlockstorePutMany(t *testing.T) {
+	h := NewHandler(blockstore.NewTemporarySync(), "")
+	var puts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			puts++
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	bs, err := New(srv.URL, "", 5*time.Second)
+	require.NoError(t, err)
+
+	var blks []blocks.Block
+	for i := 0; i < 10; i++ {
+		blks = append(blks, blocks.NewBlock([]byte(fmt.Sprintf("block %d", i))))
+	}
+	require.NoError(t, bs.PutMany(blks))
+	require.Equal(t, 1, puts)
+
+	for _, blk := range blks {
+		got, err := bs.Get(blk.Cid())
+		require.NoError(t, err)
+		require.Equal(t, blk.RawData(), got.RawData())
+	}
+}
+
+func TestRemoteBlockstoreSlowListing(t *testing.T) {
+	blk := blocks.NewBlock([]byte("some block"))
+
+	// listing the keys takes longer than the timeout of the requests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		_, _ = fmt.Fprintln(w, blk.Cid().String())
+	}))
+	defer srv.Close()
+
+	bs, err := New(srv.URL, "", 100*time.Millisecond)
+	require.NoError(t, err)
+
+	keys, err := bs.AllKeysChan(context.TODO())
+	require.NoError(t, err)
+	var got []cid.Cid
+	for k := range keys {
+		got = append(got, k)
+	}
+	require.Equal(t, []cid.Cid{blk.Cid()}, got)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/filecoin-project/lotus/extern/sector-storage/ffiwrapper"
 	"github.com/filecoin-project/lotus/extern/sector-storage/mock"
 	"github.com/filecoin-project/lotus/genesis"
+	"github.com/filecoin-project/lotus/lib/cborrpc"
 	miner2 "github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/node"
 	"github.com/filecoin-project/lotus/node/modules"
@@ -46,6 +47,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
 	"github.com/stretchr/testify/require"
 )
 
@@ -141,23 +143,39 @@ func CreateTestStorageNode(ctx context.Context, t *testing.T, waddr address.Addr
 	return test.TestStorageNode{StorageMiner: minerapi, MineOne: mineOne}
 }
 
+// rpcTransport is the transport the APIs of the test nodes are used over.
+type rpcTransport int
+
+const (
+	noRPC rpcTransport = iota
+	jsonRPC
+	// cborRPC serves the full nodes over the cbor transport, before the
+	// storage nodes are created, so the miners use the cbor client; the
+	// storage nodes are served over json
+	cborRPC
+)
+
 func Builder(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner) ([]test.TestNode, []test.TestStorageNode) {
-	return mockBuilderOpts(t, fullOpts, storage, false)
+	return mockBuilderOpts(t, fullOpts, storage, noRPC)
 }
 
 func MockSbBuilder(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner) ([]test.TestNode, []test.TestStorageNode) {
-	return mockSbBuilderOpts(t, fullOpts, storage, false)
+	return mockSbBuilderOpts(t, fullOpts, storage, noRPC)
 }
 
 func RPCBuilder(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner) ([]test.TestNode, []test.TestStorageNode) {
-	return mockBuilderOpts(t, fullOpts, storage, true)
+	return mockBuilderOpts(t, fullOpts, storage, jsonRPC)
 }
 
 func RPCMockSbBuilder(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner) ([]test.TestNode, []test.TestStorageNode) {
-	return mockSbBuilderOpts(t, fullOpts, storage, true)
+	return mockSbBuilderOpts(t, fullOpts, storage, jsonRPC)
+}
+
+func CBORRPCMockSbBuilder(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner) ([]test.TestNode, []test.TestStorageNode) {
+	return mockSbBuilderOpts(t, fullOpts, storage, cborRPC)
 }
 
-func mockBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner, rpc bool) ([]test.TestNode, []test.TestStorageNode) {
+func mockBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner, rpc rpcTransport) ([]test.TestNode, []test.TestStorageNode) {
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
 
@@ -253,8 +271,11 @@ func mockBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.
 
 		t.Cleanup(func() { _ = stop(context.Background()) })
 
-		if rpc {
+		switch rpc {
+		case jsonRPC:
 			fulls[i] = fullRpc(t, fulls[i])
+		case cborRPC:
+			fulls[i] = fullCBORRpc(t, fulls[i])
 		}
 	}
 
@@ -287,7 +308,7 @@ func mockBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.
 
 			psd := presealDirs[i]
 		*/
-		if rpc {
+		if rpc != noRPC {
 			storers[i] = storerRpc(t, storers[i])
 		}
 	}
@@ -315,7 +336,7 @@ func mockBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.
 	return fulls, storers
 }
 
-func mockSbBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner, rpc bool) ([]test.TestNode, []test.TestStorageNode) {
+func mockSbBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []test.StorageMiner, rpc rpcTransport) ([]test.TestNode, []test.TestStorageNode) {
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
 
@@ -412,8 +433,11 @@ func mockSbBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []tes
 
 		t.Cleanup(func() { _ = stop(context.Background()) })
 
-		if rpc {
+		switch rpc {
+		case jsonRPC:
 			fulls[i] = fullRpc(t, fulls[i])
+		case cborRPC:
+			fulls[i] = fullCBORRpc(t, fulls[i])
 		}
 	}
 
@@ -450,7 +474,7 @@ func mockSbBuilderOpts(t *testing.T, fullOpts []test.FullNodeOpts, storage []tes
 			node.Unset(new(*sectorstorage.Manager)),
 		))
 
-		if rpc {
+		if rpc != noRPC {
 			storers[i] = storerRpc(t, storers[i])
 		}
 	}
@@ -489,6 +513,29 @@ func fullRpc(t *testing.T, nd test.TestNode) test.TestNode {
 	return full
 }
 
+func fullCBORRpc(t *testing.T, nd test.TestNode) test.TestNode {
+	srv := cborrpc.NewServer(nil)
+	srv.Register("Filecoin", nd)
+
+	lst, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lst.Close() })
+	go srv.Serve(lst) // nolint:errcheck
+
+	ma, err := manet.FromNetAddr(lst.Addr())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var full test.TestNode
+	full.FullNode, _, err = client.NewFullNodeCBORRPC(ctx, ma, "")
+	require.NoError(t, err)
+
+	full.ListenAddr = ma
+	return full
+}
+
 func storerRpc(t *testing.T, nd test.TestStorageNode) test.TestStorageNode {
 	ma, listenAddr, err := CreateRPCServer(nd)
 	require.NoError(t, err)
@@ -9,6 +9,7 @@ import (
 	"go.uber.org/fx"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 
 	"github.com/filecoin-project/go-address"
@@ -23,10 +24,15 @@ import (
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/node/impl/full"
 	payapi "github.com/filecoin-project/lotus/node/impl/paych"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
 var log = logging.Logger("payment-channel-settler")
 
+// cursorKey is the key of the events cursor of the settler, so settle messages
+// included while the node was down are still handled
+var cursorKey = datastore.NewKey("/paych/settler/events-cursor")
+
 // API are the dependencies need to run the payment channel settler
 type API struct {
 	fx.In
@@ -34,6 +40,8 @@ type API struct {
 	full.ChainAPI
 	full.StateAPI
 	payapi.PaychAPI
+
+	DS dtypes.MetadataDS
 }
 
 type settlerAPI interface {
@@ -56,8 +64,11 @@ func SettlePaymentChannels(lc fx.Lifecycle, api API) error {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			pcs := newPaymentChannelSettler(ctx, &api)
-			ev := events.NewEvents(ctx, &api)
-			return ev.Called(pcs.check, pcs.messageHandler, pcs.revertHandler, int(build.MessageConfidence+1), events.NoTimeout, pcs.matcher)
+			ev := events.NewEventsWithCursor(ctx, &api, api.DS, cursorKey)
+			if err := ev.Called(pcs.check, pcs.messageHandler, pcs.revertHandler, int(build.MessageConfidence+1), events.NoTimeout, pcs.matcher); err != nil {
+				return err
+			}
+			return ev.Resume()
 		},
 	})
 	return nil